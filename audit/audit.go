@@ -0,0 +1,98 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package audit
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Results
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+// Record describes a single mutating aws call
+type Record struct {
+	Time        time.Time   `json:"time"`
+	Subject     string      `json:"subject"`
+	ComponentID string      `json:"component_id,omitempty"`
+	Service     string      `json:"service"`
+	Operation   string      `json:"operation"`
+	Input       interface{} `json:"input,omitempty"`
+	Result      string      `json:"result"`
+	Error       string      `json:"error,omitempty"`
+	RequestID   string      `json:"request_id,omitempty"`
+}
+
+// Sink stores audit records
+type Sink interface {
+	Write(r Record) error
+}
+
+var (
+	mu    sync.RWMutex
+	once  sync.Once
+	sinks []Sink
+)
+
+// Register : sends a record of every mutating call made by the
+// components to the sink
+func Register(s Sink) {
+	once.Do(func() {
+		client.OnSession(track)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	sinks = append(sinks, s)
+}
+
+func track(sess *session.Session, scope client.Scope) {
+	sess.Handlers.Complete.PushBack(func(r *request.Request) {
+		if !client.Mutating(r.Operation.Name) {
+			return
+		}
+		write(newRecord(scope, r))
+	})
+}
+
+func newRecord(scope client.Scope, r *request.Request) Record {
+	rec := Record{
+		Time:        time.Now().UTC(),
+		Subject:     scope.Subject,
+		ComponentID: scope.ComponentID,
+		Service:     r.ClientInfo.ServiceName,
+		Operation:   r.Operation.Name,
+		Input:       redact(r.Params),
+		Result:      Success,
+		RequestID:   r.RequestID,
+	}
+
+	if r.Error != nil {
+		rec.Result = Failure
+		rec.Error = r.Error.Error()
+	}
+
+	return rec
+}
+
+func write(rec Record) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, s := range sinks {
+		if err := s.Write(rec); err != nil {
+			log.Println("Audit: " + err.Error())
+		}
+	}
+}
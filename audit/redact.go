@@ -0,0 +1,58 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package audit
+
+import (
+	"encoding/json"
+)
+
+// redacted is the value stored instead of a secret
+const redacted = "**REDACTED**"
+
+// secrets are input fields never written to a sink
+var secrets = map[string]bool{
+	"MasterUserPassword": true,
+	"NewPassword":        true,
+	"OldPassword":        true,
+	"Password":           true,
+	"PrivateKey":         true,
+	"SecretAccessKey":    true,
+	"SessionToken":       true,
+	"UserData":           true,
+}
+
+// redact : returns a copy of the input with its secrets masked
+func redact(input interface{}) interface{} {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+
+	return mask(v)
+}
+
+func mask(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, val := range x {
+			if secrets[k] && val != nil {
+				x[k] = redacted
+				continue
+			}
+			x[k] = mask(val)
+		}
+	case []interface{}:
+		for i := range x {
+			x[i] = mask(x[i])
+		}
+	}
+
+	return v
+}
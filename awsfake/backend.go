@@ -0,0 +1,261 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsfake
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Call stores an operation served by the backend
+type Call struct {
+	Service   string
+	Operation string
+	Input     interface{}
+	Error     error
+}
+
+// account is the id of the fake aws account owning every resource
+const account = "000000000000"
+
+type failure struct {
+	err   error
+	times int
+}
+
+// Backend is an in-memory replacement for the aws services used by the
+// components. Once installed, every client built through the client
+// package is served by the backend instead of aws
+type Backend struct {
+	mu       sync.Mutex
+	ids      int
+	services map[string]interface{}
+	failures map[string]*failure
+	calls    []Call
+
+	// Region is used to build arns and default availability zones
+	Region string
+
+	EC2     *EC2
+	ELB     *ELB
+	RDS     *RDS
+	IAM     *IAM
+	Route53 *Route53
+	S3      *S3
+}
+
+var (
+	mu     sync.RWMutex
+	once   sync.Once
+	active *Backend
+)
+
+// New : Constructor
+func New() *Backend {
+	b := &Backend{
+		failures: make(map[string]*failure),
+		Region:   "us-east-1",
+	}
+
+	b.EC2 = newEC2(b)
+	b.ELB = newELB(b)
+	b.RDS = newRDS(b)
+	b.IAM = newIAM(b)
+	b.Route53 = newRoute53(b)
+	b.S3 = newS3(b)
+
+	b.services = map[string]interface{}{
+		ec2.ServiceName:     b.EC2,
+		elb.ServiceName:     b.ELB,
+		rds.ServiceName:     b.RDS,
+		iam.ServiceName:     b.IAM,
+		route53.ServiceName: b.Route53,
+		s3.ServiceName:      b.S3,
+	}
+
+	return b
+}
+
+// Install : routes all sessions created from now on to this backend
+func (b *Backend) Install() {
+	once.Do(func() {
+		client.OnSession(wire)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	active = b
+}
+
+// Uninstall : stops routing new sessions to this backend
+func (b *Backend) Uninstall() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if active == b {
+		active = nil
+	}
+}
+
+// Fail : makes the next calls to an operation fail with the given aws
+// error code, times lower than one makes all calls fail
+func (b *Backend) Fail(service, operation, code string, times int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures[service+"."+operation] = &failure{
+		err:   awserr.New(code, "injected failure", nil),
+		times: times,
+	}
+}
+
+// Calls : returns all operations served by the backend
+func (b *Backend) Calls() []Call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Call{}, b.calls...)
+}
+
+// Called : returns how many times an operation was served
+func (b *Backend) Called(service, operation string) int {
+	var n int
+
+	for _, c := range b.Calls() {
+		if c.Service == service && c.Operation == operation {
+			n++
+		}
+	}
+
+	return n
+}
+
+func wire(sess *session.Session, _ client.Scope) {
+	mu.RLock()
+	b := active
+	mu.RUnlock()
+
+	if b == nil {
+		return
+	}
+
+	sess.Handlers.Send.Clear()
+	sess.Handlers.Send.PushBack(b.send)
+}
+
+func (b *Backend) send(r *request.Request) {
+	// the response is filled in place, so skip the protocol unmarshalers
+	r.Handlers.UnmarshalMeta.Clear()
+	r.Handlers.ValidateResponse.Clear()
+	r.Handlers.Unmarshal.Clear()
+	r.Handlers.UnmarshalError.Clear()
+
+	r.HTTPResponse = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+
+	r.Error = b.serve(r.ClientInfo.ServiceName, r.Operation.Name, r.Params, r.Data)
+	if r.Error != nil {
+		r.Retryable = aws.Bool(false)
+	}
+}
+
+func (b *Backend) serve(service, operation string, input, output interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.injected(service + "." + operation)
+
+	defer func() {
+		b.calls = append(b.calls, Call{
+			Service:   service,
+			Operation: operation,
+			Input:     input,
+			Error:     err,
+		})
+	}()
+
+	if err != nil {
+		return err
+	}
+
+	svc, ok := b.services[service]
+	if !ok {
+		err = notImplemented(service, operation)
+		return err
+	}
+
+	m := reflect.ValueOf(svc).MethodByName(operation)
+	if !m.IsValid() {
+		err = notImplemented(service, operation)
+		return err
+	}
+
+	res := m.Call([]reflect.Value{reflect.ValueOf(input), reflect.ValueOf(output)})
+	if !res[0].IsNil() {
+		err = res[0].Interface().(error)
+	}
+
+	return err
+}
+
+func (b *Backend) injected(key string) error {
+	f, ok := b.failures[key]
+	if !ok {
+		return nil
+	}
+
+	if f.times > 0 {
+		f.times--
+		if f.times == 0 {
+			delete(b.failures, key)
+		}
+	}
+
+	return f.err
+}
+
+// id : generates a unique aws like identifier
+func (b *Backend) id(prefix string) string {
+	b.ids++
+	return fmt.Sprintf("%s-%08x", prefix, b.ids)
+}
+
+// arn : builds the arn of a resource on the backend account
+func (b *Backend) arn(service, resource string) string {
+	region := b.Region
+	if service == "iam" {
+		region = ""
+	}
+
+	return fmt.Sprintf("arn:aws:%s:%s:%s:%s", service, region, account, resource)
+}
+
+func notImplemented(service, operation string) error {
+	return awserr.New("NotImplemented", fmt.Sprintf("%s.%s is not implemented by the fake backend", service, operation), nil)
+}
+
+func notFound(code, id string) error {
+	return awserr.New(code, fmt.Sprintf("The resource '%s' does not exist", id), nil)
+}
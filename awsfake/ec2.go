@@ -0,0 +1,2861 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsfake
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Instance state codes
+const (
+	instanceRunning    = 16
+	instanceTerminated = 48
+	instanceStopped    = 80
+)
+
+// EC2 stores the state of the fake ec2 service
+type EC2 struct {
+	b *Backend
+
+	Vpcs              map[string]*ec2.Vpc
+	Subnets           map[string]*ec2.Subnet
+	InternetGateways  map[string]*ec2.InternetGateway
+	RouteTables       map[string]*ec2.RouteTable
+	SecurityGroups    map[string]*ec2.SecurityGroup
+	Instances         map[string]*ec2.Instance
+	Volumes           map[string]*ec2.Volume
+	NatGateways       map[string]*ec2.NatGateway
+	Addresses         map[string]*ec2.Address
+	NetworkInterfaces map[string]*ec2.NetworkInterface
+	NetworkAcls       map[string]*ec2.NetworkAcl
+	VpcAttributes     map[string]*VpcAttributes
+
+	VpcPeeringConnections map[string]*ec2.VpcPeeringConnection
+	FlowLogs              map[string]*ec2.FlowLog
+	DhcpOptions           map[string]*ec2.DhcpOptions
+	VpcEndpoints          map[string]*ec2.VpcEndpoint
+
+	EgressOnlyInternetGateways map[string]*ec2.EgressOnlyInternetGateway
+}
+
+// VpcAttributes stores the dns attributes of a vpc
+type VpcAttributes struct {
+	EnableDNSSupport   bool
+	EnableDNSHostnames bool
+}
+
+func newEC2(b *Backend) *EC2 {
+	return &EC2{
+		b:                 b,
+		Vpcs:              make(map[string]*ec2.Vpc),
+		Subnets:           make(map[string]*ec2.Subnet),
+		InternetGateways:  make(map[string]*ec2.InternetGateway),
+		RouteTables:       make(map[string]*ec2.RouteTable),
+		SecurityGroups:    make(map[string]*ec2.SecurityGroup),
+		Instances:         make(map[string]*ec2.Instance),
+		Volumes:           make(map[string]*ec2.Volume),
+		NatGateways:       make(map[string]*ec2.NatGateway),
+		Addresses:         make(map[string]*ec2.Address),
+		NetworkInterfaces: make(map[string]*ec2.NetworkInterface),
+		NetworkAcls:       make(map[string]*ec2.NetworkAcl),
+		VpcAttributes:     make(map[string]*VpcAttributes),
+
+		VpcPeeringConnections: make(map[string]*ec2.VpcPeeringConnection),
+		FlowLogs:              make(map[string]*ec2.FlowLog),
+		DhcpOptions:           make(map[string]*ec2.DhcpOptions),
+		VpcEndpoints:          make(map[string]*ec2.VpcEndpoint),
+
+		EgressOnlyInternetGateways: make(map[string]*ec2.EgressOnlyInternetGateway),
+	}
+}
+
+// CreateVpc : creates a vpc with its main route table, default security
+// group and default network acl
+func (f *EC2) CreateVpc(in *ec2.CreateVpcInput, out *ec2.CreateVpcOutput) error {
+	vpc := &ec2.Vpc{
+		VpcId:           aws.String(f.b.id("vpc")),
+		CidrBlock:       in.CidrBlock,
+		State:           aws.String(ec2.VpcStateAvailable),
+		InstanceTenancy: aws.String(ec2.TenancyDefault),
+		IsDefault:       aws.Bool(false),
+	}
+
+	if in.InstanceTenancy != nil {
+		vpc.InstanceTenancy = in.InstanceTenancy
+	}
+
+	vpc.CidrBlockAssociationSet = append(vpc.CidrBlockAssociationSet, &ec2.VpcCidrBlockAssociation{
+		AssociationId:  aws.String(f.b.id("vpc-cidr-assoc")),
+		CidrBlock:      in.CidrBlock,
+		CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociated)},
+	})
+
+	if aws.BoolValue(in.AmazonProvidedIpv6CidrBlock) {
+		f.associateIpv6CidrBlock(vpc)
+	}
+
+	vpc.DhcpOptionsId = f.defaultDhcpOptions().DhcpOptionsId
+
+	f.Vpcs[*vpc.VpcId] = vpc
+	f.VpcAttributes[*vpc.VpcId] = &VpcAttributes{EnableDNSSupport: true}
+
+	rt := f.newRouteTable(vpc)
+	rt.Associations = append(rt.Associations, &ec2.RouteTableAssociation{
+		Main:                    aws.Bool(true),
+		RouteTableAssociationId: aws.String(f.b.id("rtbassoc")),
+		RouteTableId:            rt.RouteTableId,
+	})
+
+	f.newSecurityGroup(vpc.VpcId, aws.String("default"), aws.String("default VPC security group"))
+	f.newNetworkACL(vpc.VpcId, true)
+
+	out.Vpc = vpc
+
+	return nil
+}
+
+// DeleteVpc : deletes a vpc without dependencies
+func (f *EC2) DeleteVpc(in *ec2.DeleteVpcInput, out *ec2.DeleteVpcOutput) error {
+	id := aws.StringValue(in.VpcId)
+	if _, ok := f.Vpcs[id]; !ok {
+		return notFound("InvalidVpcID.NotFound", id)
+	}
+
+	for _, s := range f.Subnets {
+		if *s.VpcId == id {
+			return dependencyViolation(id)
+		}
+	}
+
+	for _, ig := range f.InternetGateways {
+		for _, a := range ig.Attachments {
+			if *a.VpcId == id {
+				return dependencyViolation(id)
+			}
+		}
+	}
+
+	for _, gw := range f.EgressOnlyInternetGateways {
+		for _, a := range gw.Attachments {
+			if *a.VpcId == id {
+				return dependencyViolation(id)
+			}
+		}
+	}
+
+	for _, e := range f.VpcEndpoints {
+		if *e.VpcId == id && *e.State != "deleted" {
+			return dependencyViolation(id)
+		}
+	}
+
+	for _, sg := range f.SecurityGroups {
+		if *sg.VpcId == id && *sg.GroupName != "default" {
+			return dependencyViolation(id)
+		}
+	}
+
+	for _, acl := range f.NetworkAcls {
+		if *acl.VpcId == id && !*acl.IsDefault {
+			return dependencyViolation(id)
+		}
+	}
+
+	for rid, rt := range f.RouteTables {
+		if *rt.VpcId != id {
+			continue
+		}
+		if !isMainRouteTable(rt) {
+			return dependencyViolation(id)
+		}
+		delete(f.RouteTables, rid)
+	}
+
+	for aid, acl := range f.NetworkAcls {
+		if *acl.VpcId == id {
+			delete(f.NetworkAcls, aid)
+		}
+	}
+
+	for gid, sg := range f.SecurityGroups {
+		if *sg.VpcId == id {
+			delete(f.SecurityGroups, gid)
+		}
+	}
+
+	delete(f.Vpcs, id)
+	delete(f.VpcAttributes, id)
+
+	return nil
+}
+
+// DescribeVpcs : lists vpcs
+func (f *EC2) DescribeVpcs(in *ec2.DescribeVpcsInput, out *ec2.DescribeVpcsOutput) error {
+	for _, id := range in.VpcIds {
+		if _, ok := f.Vpcs[*id]; !ok {
+			return notFound("InvalidVpcID.NotFound", *id)
+		}
+	}
+
+	for _, v := range f.Vpcs {
+		attrs := map[string][]string{
+			"vpc-id":          {*v.VpcId},
+			"cidr":            {aws.StringValue(v.CidrBlock)},
+			"cidr-block":      {aws.StringValue(v.CidrBlock)},
+			"state":           {aws.StringValue(v.State)},
+			"dhcp-options-id": {aws.StringValue(v.DhcpOptionsId)},
+		}
+
+		if selected(in.VpcIds, v.VpcId) && matches(in.Filters, v.Tags, attrs) {
+			out.Vpcs = append(out.Vpcs, v)
+		}
+	}
+
+	return nil
+}
+
+// ModifyVpcAttribute : sets one of the dns attributes of a vpc
+func (f *EC2) ModifyVpcAttribute(in *ec2.ModifyVpcAttributeInput, out *ec2.ModifyVpcAttributeOutput) error {
+	attrs, ok := f.VpcAttributes[aws.StringValue(in.VpcId)]
+	if !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	if (in.EnableDnsSupport == nil) == (in.EnableDnsHostnames == nil) {
+		return awserr.New("InvalidParameterCombination", "Exactly one attribute must be modified per request", nil)
+	}
+
+	if in.EnableDnsSupport != nil {
+		attrs.EnableDNSSupport = aws.BoolValue(in.EnableDnsSupport.Value)
+	}
+
+	if in.EnableDnsHostnames != nil {
+		attrs.EnableDNSHostnames = aws.BoolValue(in.EnableDnsHostnames.Value)
+	}
+
+	return nil
+}
+
+// DescribeVpcAttribute : returns one of the dns attributes of a vpc
+func (f *EC2) DescribeVpcAttribute(in *ec2.DescribeVpcAttributeInput, out *ec2.DescribeVpcAttributeOutput) error {
+	attrs, ok := f.VpcAttributes[aws.StringValue(in.VpcId)]
+	if !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	out.VpcId = in.VpcId
+
+	switch aws.StringValue(in.Attribute) {
+	case ec2.VpcAttributeNameEnableDnsSupport:
+		out.EnableDnsSupport = &ec2.AttributeBooleanValue{Value: aws.Bool(attrs.EnableDNSSupport)}
+	case ec2.VpcAttributeNameEnableDnsHostnames:
+		out.EnableDnsHostnames = &ec2.AttributeBooleanValue{Value: aws.Bool(attrs.EnableDNSHostnames)}
+	default:
+		return awserr.New("InvalidParameterValue", "Unsupported attribute "+aws.StringValue(in.Attribute), nil)
+	}
+
+	return nil
+}
+
+// ModifyVpcTenancy : only allows changing the tenancy to default
+func (f *EC2) ModifyVpcTenancy(in *ec2.ModifyVpcTenancyInput, out *ec2.ModifyVpcTenancyOutput) error {
+	vpc, ok := f.Vpcs[aws.StringValue(in.VpcId)]
+	if !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	if aws.StringValue(in.InstanceTenancy) != ec2.VpcTenancyDefault {
+		return awserr.New("InvalidParameterValue", "The tenancy can only be changed to default", nil)
+	}
+
+	vpc.InstanceTenancy = in.InstanceTenancy
+	out.ReturnValue = aws.Bool(true)
+
+	return nil
+}
+
+// AssociateVpcCidrBlock : adds a secondary ipv4 block or an amazon
+// provided ipv6 block to a vpc
+func (f *EC2) AssociateVpcCidrBlock(in *ec2.AssociateVpcCidrBlockInput, out *ec2.AssociateVpcCidrBlockOutput) error {
+	vpc, ok := f.Vpcs[aws.StringValue(in.VpcId)]
+	if !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	out.VpcId = vpc.VpcId
+
+	if aws.BoolValue(in.AmazonProvidedIpv6CidrBlock) {
+		for _, a := range vpc.Ipv6CidrBlockAssociationSet {
+			if aws.StringValue(a.Ipv6CidrBlockState.State) == ec2.VpcCidrBlockStateCodeAssociated {
+				return awserr.New("CidrLimitExceeded", "The vpc already has an ipv6 cidr block", nil)
+			}
+		}
+
+		out.Ipv6CidrBlockAssociation = f.associateIpv6CidrBlock(vpc)
+
+		return nil
+	}
+
+	for _, a := range vpc.CidrBlockAssociationSet {
+		if aws.StringValue(a.CidrBlock) == aws.StringValue(in.CidrBlock) && aws.StringValue(a.CidrBlockState.State) == ec2.VpcCidrBlockStateCodeAssociated {
+			return awserr.New("InvalidVpc.Range", "The CIDR '"+aws.StringValue(in.CidrBlock)+"' is already associated", nil)
+		}
+	}
+
+	a := &ec2.VpcCidrBlockAssociation{
+		AssociationId:  aws.String(f.b.id("vpc-cidr-assoc")),
+		CidrBlock:      in.CidrBlock,
+		CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociated)},
+	}
+
+	vpc.CidrBlockAssociationSet = append(vpc.CidrBlockAssociationSet, a)
+	out.CidrBlockAssociation = a
+
+	return nil
+}
+
+// DisassociateVpcCidrBlock : removes a secondary ipv4 block or the ipv6
+// block of a vpc
+func (f *EC2) DisassociateVpcCidrBlock(in *ec2.DisassociateVpcCidrBlockInput, out *ec2.DisassociateVpcCidrBlockOutput) error {
+	id := aws.StringValue(in.AssociationId)
+
+	for _, vpc := range f.Vpcs {
+		for _, a := range vpc.CidrBlockAssociationSet {
+			if aws.StringValue(a.AssociationId) != id {
+				continue
+			}
+
+			if aws.StringValue(a.CidrBlock) == aws.StringValue(vpc.CidrBlock) {
+				return awserr.New("OperationNotPermitted", "The primary cidr block can't be disassociated", nil)
+			}
+
+			a.CidrBlockState = &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeDisassociated)}
+			out.VpcId = vpc.VpcId
+			out.CidrBlockAssociation = a
+
+			return nil
+		}
+
+		for _, a := range vpc.Ipv6CidrBlockAssociationSet {
+			if aws.StringValue(a.AssociationId) != id {
+				continue
+			}
+
+			a.Ipv6CidrBlockState = &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeDisassociated)}
+			out.VpcId = vpc.VpcId
+			out.Ipv6CidrBlockAssociation = a
+
+			return nil
+		}
+	}
+
+	return notFound("InvalidVpcCidrBlockAssociationID.NotFound", id)
+}
+
+func (f *EC2) associateIpv6CidrBlock(vpc *ec2.Vpc) *ec2.VpcIpv6CidrBlockAssociation {
+	n := len(f.Vpcs) + len(vpc.Ipv6CidrBlockAssociationSet)
+
+	a := &ec2.VpcIpv6CidrBlockAssociation{
+		AssociationId:      aws.String(f.b.id("vpc-cidr-assoc")),
+		Ipv6CidrBlock:      aws.String(fmt.Sprintf("2600:1f18:%x::/56", 0x1000+n*0x100)),
+		Ipv6CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociated)},
+	}
+
+	vpc.Ipv6CidrBlockAssociationSet = append(vpc.Ipv6CidrBlockAssociationSet, a)
+
+	return a
+}
+
+// AssociateSubnetCidrBlock : adds an ipv6 block of the vpc to a subnet
+func (f *EC2) AssociateSubnetCidrBlock(in *ec2.AssociateSubnetCidrBlockInput, out *ec2.AssociateSubnetCidrBlockOutput) error {
+	s, ok := f.Subnets[aws.StringValue(in.SubnetId)]
+	if !ok {
+		return notFound("InvalidSubnetID.NotFound", aws.StringValue(in.SubnetId))
+	}
+
+	for _, a := range s.Ipv6CidrBlockAssociationSet {
+		if *a.Ipv6CidrBlockState.State == ec2.SubnetCidrBlockStateCodeAssociated {
+			return awserr.New("InvalidParameterValue", "The subnet already has an ipv6 cidr block", nil)
+		}
+	}
+
+	if err := f.associateSubnetIpv6CidrBlock(s, aws.StringValue(in.Ipv6CidrBlock)); err != nil {
+		return err
+	}
+
+	out.SubnetId = s.SubnetId
+	out.Ipv6CidrBlockAssociation = s.Ipv6CidrBlockAssociationSet[len(s.Ipv6CidrBlockAssociationSet)-1]
+
+	return nil
+}
+
+// DisassociateSubnetCidrBlock : removes the ipv6 block of a subnet
+func (f *EC2) DisassociateSubnetCidrBlock(in *ec2.DisassociateSubnetCidrBlockInput, out *ec2.DisassociateSubnetCidrBlockOutput) error {
+	id := aws.StringValue(in.AssociationId)
+
+	for _, s := range f.Subnets {
+		for i, a := range s.Ipv6CidrBlockAssociationSet {
+			if *a.AssociationId != id {
+				continue
+			}
+
+			for _, ni := range f.NetworkInterfaces {
+				if *ni.SubnetId == *s.SubnetId && len(ni.Ipv6Addresses) > 0 {
+					return awserr.New("InvalidCidrBlock.InUse", "The ipv6 cidr block of the subnet has addresses in use", nil)
+				}
+			}
+
+			s.Ipv6CidrBlockAssociationSet = append(s.Ipv6CidrBlockAssociationSet[:i], s.Ipv6CidrBlockAssociationSet[i+1:]...)
+			out.SubnetId = s.SubnetId
+			out.Ipv6CidrBlockAssociation = a
+
+			return nil
+		}
+	}
+
+	return notFound("InvalidSubnetCidrBlockAssociationID.NotFound", id)
+}
+
+// associateSubnetIpv6CidrBlock : checks the block is a /64 of the vpc
+// ipv6 block not used by other subnets, and associates it
+func (f *EC2) associateSubnetIpv6CidrBlock(s *ec2.Subnet, cidr string) error {
+	ip, n, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() != nil {
+		return awserr.New("InvalidSubnet.Range", "The ipv6 cidr '"+cidr+"' is invalid, it must be a /64", nil)
+	}
+
+	if ones, _ := n.Mask.Size(); ones != 64 {
+		return awserr.New("InvalidSubnet.Range", "The ipv6 cidr '"+cidr+"' is invalid, it must be a /64", nil)
+	}
+
+	inVpc := false
+	for _, a := range f.Vpcs[*s.VpcId].Ipv6CidrBlockAssociationSet {
+		_, block, _ := net.ParseCIDR(aws.StringValue(a.Ipv6CidrBlock))
+		inVpc = inVpc || (block != nil && block.Contains(ip))
+	}
+
+	if !inVpc {
+		return awserr.New("InvalidSubnet.Range", "The ipv6 cidr '"+cidr+"' is not within the vpc ipv6 block", nil)
+	}
+
+	for _, o := range f.Subnets {
+		for _, a := range o.Ipv6CidrBlockAssociationSet {
+			if *o.VpcId == *s.VpcId && *a.Ipv6CidrBlock == n.String() {
+				return awserr.New("InvalidSubnet.Conflict", "The ipv6 cidr '"+cidr+"' conflicts with another subnet", nil)
+			}
+		}
+	}
+
+	s.Ipv6CidrBlockAssociationSet = append(s.Ipv6CidrBlockAssociationSet, &ec2.SubnetIpv6CidrBlockAssociation{
+		AssociationId:      aws.String(f.b.id("subnet-cidr-assoc")),
+		Ipv6CidrBlock:      aws.String(n.String()),
+		Ipv6CidrBlockState: &ec2.SubnetCidrBlockState{State: aws.String(ec2.SubnetCidrBlockStateCodeAssociated)},
+	})
+
+	return nil
+}
+
+// CreateSubnet : creates a subnet on an existing vpc
+func (f *EC2) CreateSubnet(in *ec2.CreateSubnetInput, out *ec2.CreateSubnetOutput) error {
+	if _, ok := f.Vpcs[aws.StringValue(in.VpcId)]; !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	for _, s := range f.Subnets {
+		if *s.VpcId == *in.VpcId && aws.StringValue(s.CidrBlock) == aws.StringValue(in.CidrBlock) {
+			return awserr.New("InvalidSubnet.Conflict", "The CIDR '"+aws.StringValue(in.CidrBlock)+"' conflicts with another subnet", nil)
+		}
+	}
+
+	az := in.AvailabilityZone
+	if az == nil {
+		az = aws.String(f.b.Region + "a")
+	}
+
+	s := &ec2.Subnet{
+		SubnetId:                    aws.String(f.b.id("subnet")),
+		VpcId:                       in.VpcId,
+		CidrBlock:                   in.CidrBlock,
+		AvailabilityZone:            az,
+		MapPublicIpOnLaunch:         aws.Bool(false),
+		AssignIpv6AddressOnCreation: aws.Bool(false),
+		DefaultForAz:                aws.Bool(false),
+		State:                       aws.String(ec2.SubnetStateAvailable),
+	}
+
+	if in.Ipv6CidrBlock != nil {
+		if err := f.associateSubnetIpv6CidrBlock(s, *in.Ipv6CidrBlock); err != nil {
+			return err
+		}
+	}
+
+	f.Subnets[*s.SubnetId] = s
+	out.Subnet = s
+
+	for _, acl := range f.NetworkAcls {
+		if *acl.VpcId == *s.VpcId && *acl.IsDefault {
+			acl.Associations = append(acl.Associations, &ec2.NetworkAclAssociation{
+				NetworkAclAssociationId: aws.String(f.b.id("aclassoc")),
+				NetworkAclId:            acl.NetworkAclId,
+				SubnetId:                s.SubnetId,
+			})
+		}
+	}
+
+	return nil
+}
+
+// ModifySubnetAttribute : updates the attributes of a subnet
+func (f *EC2) ModifySubnetAttribute(in *ec2.ModifySubnetAttributeInput, out *ec2.ModifySubnetAttributeOutput) error {
+	s, ok := f.Subnets[aws.StringValue(in.SubnetId)]
+	if !ok {
+		return notFound("InvalidSubnetID.NotFound", aws.StringValue(in.SubnetId))
+	}
+
+	if in.MapPublicIpOnLaunch != nil {
+		s.MapPublicIpOnLaunch = in.MapPublicIpOnLaunch.Value
+	}
+
+	if in.AssignIpv6AddressOnCreation != nil {
+		s.AssignIpv6AddressOnCreation = in.AssignIpv6AddressOnCreation.Value
+	}
+
+	return nil
+}
+
+// DeleteSubnet : deletes a subnet without network interfaces
+func (f *EC2) DeleteSubnet(in *ec2.DeleteSubnetInput, out *ec2.DeleteSubnetOutput) error {
+	id := aws.StringValue(in.SubnetId)
+	if _, ok := f.Subnets[id]; !ok {
+		return notFound("InvalidSubnetID.NotFound", id)
+	}
+
+	for _, ni := range f.NetworkInterfaces {
+		if *ni.SubnetId == id {
+			return dependencyViolation(id)
+		}
+	}
+
+	for _, rt := range f.RouteTables {
+		for i := len(rt.Associations) - 1; i >= 0; i-- {
+			if aws.StringValue(rt.Associations[i].SubnetId) == id {
+				rt.Associations = append(rt.Associations[:i], rt.Associations[i+1:]...)
+			}
+		}
+	}
+
+	for _, acl := range f.NetworkAcls {
+		for i := len(acl.Associations) - 1; i >= 0; i-- {
+			if aws.StringValue(acl.Associations[i].SubnetId) == id {
+				acl.Associations = append(acl.Associations[:i], acl.Associations[i+1:]...)
+			}
+		}
+	}
+
+	delete(f.Subnets, id)
+
+	return nil
+}
+
+// DescribeSubnets : lists subnets
+func (f *EC2) DescribeSubnets(in *ec2.DescribeSubnetsInput, out *ec2.DescribeSubnetsOutput) error {
+	for _, id := range in.SubnetIds {
+		if _, ok := f.Subnets[*id]; !ok {
+			return notFound("InvalidSubnetID.NotFound", *id)
+		}
+	}
+
+	for _, s := range f.Subnets {
+		attrs := map[string][]string{
+			"subnet-id":         {*s.SubnetId},
+			"vpc-id":            {*s.VpcId},
+			"cidr":              {aws.StringValue(s.CidrBlock)},
+			"cidr-block":        {aws.StringValue(s.CidrBlock)},
+			"availability-zone": {aws.StringValue(s.AvailabilityZone)},
+		}
+
+		if selected(in.SubnetIds, s.SubnetId) && matches(in.Filters, s.Tags, attrs) {
+			out.Subnets = append(out.Subnets, s)
+		}
+	}
+
+	return nil
+}
+
+// CreateInternetGateway : creates a detached internet gateway
+func (f *EC2) CreateInternetGateway(in *ec2.CreateInternetGatewayInput, out *ec2.CreateInternetGatewayOutput) error {
+	ig := &ec2.InternetGateway{
+		InternetGatewayId: aws.String(f.b.id("igw")),
+	}
+
+	f.InternetGateways[*ig.InternetGatewayId] = ig
+	out.InternetGateway = ig
+
+	return nil
+}
+
+// AttachInternetGateway : attaches an internet gateway to a vpc
+func (f *EC2) AttachInternetGateway(in *ec2.AttachInternetGatewayInput, out *ec2.AttachInternetGatewayOutput) error {
+	ig, ok := f.InternetGateways[aws.StringValue(in.InternetGatewayId)]
+	if !ok {
+		return notFound("InvalidInternetGatewayID.NotFound", aws.StringValue(in.InternetGatewayId))
+	}
+
+	if _, ok := f.Vpcs[aws.StringValue(in.VpcId)]; !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	if len(ig.Attachments) > 0 {
+		return awserr.New("Resource.AlreadyAssociated", "Internet gateway is already attached", nil)
+	}
+
+	ig.Attachments = append(ig.Attachments, &ec2.InternetGatewayAttachment{
+		State: aws.String("available"),
+		VpcId: in.VpcId,
+	})
+
+	return nil
+}
+
+// DetachInternetGateway : detaches an internet gateway from a vpc
+func (f *EC2) DetachInternetGateway(in *ec2.DetachInternetGatewayInput, out *ec2.DetachInternetGatewayOutput) error {
+	ig, ok := f.InternetGateways[aws.StringValue(in.InternetGatewayId)]
+	if !ok {
+		return notFound("InvalidInternetGatewayID.NotFound", aws.StringValue(in.InternetGatewayId))
+	}
+
+	for i, a := range ig.Attachments {
+		if *a.VpcId == aws.StringValue(in.VpcId) {
+			ig.Attachments = append(ig.Attachments[:i], ig.Attachments[i+1:]...)
+			return nil
+		}
+	}
+
+	return awserr.New("Gateway.NotAttached", "Internet gateway is not attached to the vpc", nil)
+}
+
+// DeleteInternetGateway : deletes a detached internet gateway
+func (f *EC2) DeleteInternetGateway(in *ec2.DeleteInternetGatewayInput, out *ec2.DeleteInternetGatewayOutput) error {
+	id := aws.StringValue(in.InternetGatewayId)
+
+	ig, ok := f.InternetGateways[id]
+	if !ok {
+		return notFound("InvalidInternetGatewayID.NotFound", id)
+	}
+
+	if len(ig.Attachments) > 0 {
+		return dependencyViolation(id)
+	}
+
+	delete(f.InternetGateways, id)
+
+	return nil
+}
+
+// DescribeInternetGateways : lists internet gateways
+func (f *EC2) DescribeInternetGateways(in *ec2.DescribeInternetGatewaysInput, out *ec2.DescribeInternetGatewaysOutput) error {
+	for _, ig := range f.InternetGateways {
+		attrs := map[string][]string{
+			"internet-gateway-id": {*ig.InternetGatewayId},
+		}
+		for _, a := range ig.Attachments {
+			attrs["attachment.vpc-id"] = append(attrs["attachment.vpc-id"], *a.VpcId)
+		}
+
+		if selected(in.InternetGatewayIds, ig.InternetGatewayId) && matches(in.Filters, ig.Tags, attrs) {
+			out.InternetGateways = append(out.InternetGateways, ig)
+		}
+	}
+
+	return nil
+}
+
+// CreateRouteTable : creates a route table with the local route
+func (f *EC2) CreateRouteTable(in *ec2.CreateRouteTableInput, out *ec2.CreateRouteTableOutput) error {
+	vpc, ok := f.Vpcs[aws.StringValue(in.VpcId)]
+	if !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	out.RouteTable = f.newRouteTable(vpc)
+
+	return nil
+}
+
+// AssociateRouteTable : associates a route table with a subnet
+func (f *EC2) AssociateRouteTable(in *ec2.AssociateRouteTableInput, out *ec2.AssociateRouteTableOutput) error {
+	rt, ok := f.RouteTables[aws.StringValue(in.RouteTableId)]
+	if !ok {
+		return notFound("InvalidRouteTableID.NotFound", aws.StringValue(in.RouteTableId))
+	}
+
+	if _, ok := f.Subnets[aws.StringValue(in.SubnetId)]; !ok {
+		return notFound("InvalidSubnetID.NotFound", aws.StringValue(in.SubnetId))
+	}
+
+	for _, other := range f.RouteTables {
+		for _, a := range other.Associations {
+			if aws.StringValue(a.SubnetId) == *in.SubnetId {
+				return awserr.New("Resource.AlreadyAssociated", "The subnet is already associated with a route table", nil)
+			}
+		}
+	}
+
+	assoc := &ec2.RouteTableAssociation{
+		Main:                    aws.Bool(false),
+		RouteTableAssociationId: aws.String(f.b.id("rtbassoc")),
+		RouteTableId:            rt.RouteTableId,
+		SubnetId:                in.SubnetId,
+	}
+
+	rt.Associations = append(rt.Associations, assoc)
+	out.AssociationId = assoc.RouteTableAssociationId
+
+	return nil
+}
+
+// DisassociateRouteTable : removes a route table association
+func (f *EC2) DisassociateRouteTable(in *ec2.DisassociateRouteTableInput, out *ec2.DisassociateRouteTableOutput) error {
+	id := aws.StringValue(in.AssociationId)
+
+	for _, rt := range f.RouteTables {
+		for i, a := range rt.Associations {
+			if *a.RouteTableAssociationId == id {
+				rt.Associations = append(rt.Associations[:i], rt.Associations[i+1:]...)
+				return nil
+			}
+		}
+	}
+
+	return notFound("InvalidAssociationID.NotFound", id)
+}
+
+// CreateRoute : adds a route to a route table
+func (f *EC2) CreateRoute(in *ec2.CreateRouteInput, out *ec2.CreateRouteOutput) error {
+	rt, ok := f.RouteTables[aws.StringValue(in.RouteTableId)]
+	if !ok {
+		return notFound("InvalidRouteTableID.NotFound", aws.StringValue(in.RouteTableId))
+	}
+
+	for _, r := range rt.Routes {
+		if r.DestinationCidrBlock != nil && aws.StringValue(r.DestinationCidrBlock) == aws.StringValue(in.DestinationCidrBlock) {
+			return awserr.New("RouteAlreadyExists", "The route identified by "+aws.StringValue(in.DestinationCidrBlock)+" already exists", nil)
+		}
+		if r.DestinationIpv6CidrBlock != nil && aws.StringValue(r.DestinationIpv6CidrBlock) == aws.StringValue(in.DestinationIpv6CidrBlock) {
+			return awserr.New("RouteAlreadyExists", "The route identified by "+aws.StringValue(in.DestinationIpv6CidrBlock)+" already exists", nil)
+		}
+	}
+
+	if in.EgressOnlyInternetGatewayId != nil {
+		if _, ok := f.EgressOnlyInternetGateways[*in.EgressOnlyInternetGatewayId]; !ok {
+			return notFound("InvalidGatewayID.NotFound", *in.EgressOnlyInternetGatewayId)
+		}
+	}
+
+	if in.VpcPeeringConnectionId != nil {
+		pc, ok := f.VpcPeeringConnections[*in.VpcPeeringConnectionId]
+		if !ok || aws.StringValue(pc.Status.Code) != ec2.VpcPeeringConnectionStateReasonCodeActive {
+			return notFound("InvalidVpcPeeringConnectionID.NotFound", *in.VpcPeeringConnectionId)
+		}
+	}
+
+	rt.Routes = append(rt.Routes, &ec2.Route{
+		DestinationCidrBlock:        in.DestinationCidrBlock,
+		DestinationIpv6CidrBlock:    in.DestinationIpv6CidrBlock,
+		GatewayId:                   in.GatewayId,
+		EgressOnlyInternetGatewayId: in.EgressOnlyInternetGatewayId,
+		NatGatewayId:                in.NatGatewayId,
+		InstanceId:                  in.InstanceId,
+		VpcPeeringConnectionId:      in.VpcPeeringConnectionId,
+		Origin:                      aws.String(ec2.RouteOriginCreateRoute),
+		State:                       aws.String(ec2.RouteStateActive),
+	})
+	out.Return = aws.Bool(true)
+
+	return nil
+}
+
+// DeleteRoute : removes a route from a route table
+func (f *EC2) DeleteRoute(in *ec2.DeleteRouteInput, out *ec2.DeleteRouteOutput) error {
+	rt, ok := f.RouteTables[aws.StringValue(in.RouteTableId)]
+	if !ok {
+		return notFound("InvalidRouteTableID.NotFound", aws.StringValue(in.RouteTableId))
+	}
+
+	for i, r := range rt.Routes {
+		if (r.DestinationCidrBlock != nil && *r.DestinationCidrBlock == aws.StringValue(in.DestinationCidrBlock)) ||
+			(r.DestinationIpv6CidrBlock != nil && *r.DestinationIpv6CidrBlock == aws.StringValue(in.DestinationIpv6CidrBlock)) {
+			rt.Routes = append(rt.Routes[:i], rt.Routes[i+1:]...)
+			return nil
+		}
+	}
+
+	return notFound("InvalidRoute.NotFound", aws.StringValue(in.DestinationCidrBlock)+aws.StringValue(in.DestinationIpv6CidrBlock))
+}
+
+// DeleteRouteTable : deletes a route table without associations
+func (f *EC2) DeleteRouteTable(in *ec2.DeleteRouteTableInput, out *ec2.DeleteRouteTableOutput) error {
+	id := aws.StringValue(in.RouteTableId)
+
+	rt, ok := f.RouteTables[id]
+	if !ok {
+		return notFound("InvalidRouteTableID.NotFound", id)
+	}
+
+	if len(rt.Associations) > 0 {
+		return dependencyViolation(id)
+	}
+
+	delete(f.RouteTables, id)
+
+	return nil
+}
+
+// DescribeRouteTables : lists route tables
+func (f *EC2) DescribeRouteTables(in *ec2.DescribeRouteTablesInput, out *ec2.DescribeRouteTablesOutput) error {
+	for _, rt := range f.RouteTables {
+		attrs := map[string][]string{
+			"route-table-id": {*rt.RouteTableId},
+			"vpc-id":         {*rt.VpcId},
+			"association.main": {
+				fmt.Sprint(isMainRouteTable(rt)),
+			},
+		}
+
+		for _, a := range rt.Associations {
+			if a.SubnetId != nil {
+				attrs["association.subnet-id"] = append(attrs["association.subnet-id"], *a.SubnetId)
+			}
+			attrs["association.route-table-association-id"] = append(attrs["association.route-table-association-id"], *a.RouteTableAssociationId)
+		}
+
+		for _, r := range rt.Routes {
+			if r.GatewayId != nil {
+				attrs["route.gateway-id"] = append(attrs["route.gateway-id"], *r.GatewayId)
+			}
+			if r.NatGatewayId != nil {
+				attrs["route.nat-gateway-id"] = append(attrs["route.nat-gateway-id"], *r.NatGatewayId)
+			}
+			if r.VpcPeeringConnectionId != nil {
+				attrs["route.vpc-peering-connection-id"] = append(attrs["route.vpc-peering-connection-id"], *r.VpcPeeringConnectionId)
+			}
+			if r.DestinationCidrBlock != nil {
+				attrs["route.destination-cidr-block"] = append(attrs["route.destination-cidr-block"], *r.DestinationCidrBlock)
+			}
+		}
+
+		if selected(in.RouteTableIds, rt.RouteTableId) && matches(in.Filters, rt.Tags, attrs) {
+			out.RouteTables = append(out.RouteTables, rt)
+		}
+	}
+
+	return nil
+}
+
+// DescribeNetworkInterfaces : lists network interfaces
+func (f *EC2) DescribeNetworkInterfaces(in *ec2.DescribeNetworkInterfacesInput, out *ec2.DescribeNetworkInterfacesOutput) error {
+	for _, ni := range f.NetworkInterfaces {
+		attrs := map[string][]string{
+			"network-interface-id": {*ni.NetworkInterfaceId},
+			"subnet-id":            {*ni.SubnetId},
+			"vpc-id":               {aws.StringValue(ni.VpcId)},
+			"description":          {aws.StringValue(ni.Description)},
+		}
+
+		if selected(in.NetworkInterfaceIds, ni.NetworkInterfaceId) && matches(in.Filters, ni.TagSet, attrs) {
+			out.NetworkInterfaces = append(out.NetworkInterfaces, ni)
+		}
+	}
+
+	return nil
+}
+
+// DeleteNetworkInterface : deletes a detached network interface
+func (f *EC2) DeleteNetworkInterface(in *ec2.DeleteNetworkInterfaceInput, out *ec2.DeleteNetworkInterfaceOutput) error {
+	id := aws.StringValue(in.NetworkInterfaceId)
+
+	ni, ok := f.NetworkInterfaces[id]
+	if !ok {
+		return notFound("InvalidNetworkInterfaceID.NotFound", id)
+	}
+
+	if aws.StringValue(ni.Status) == ec2.NetworkInterfaceStatusInUse {
+		return awserr.New("InvalidNetworkInterface.InUse", "Interface: ["+id+"] in use", nil)
+	}
+
+	delete(f.NetworkInterfaces, id)
+
+	return nil
+}
+
+// CreateNetworkAcl : creates a network acl denying all traffic
+func (f *EC2) CreateNetworkAcl(in *ec2.CreateNetworkAclInput, out *ec2.CreateNetworkAclOutput) error {
+	if _, ok := f.Vpcs[aws.StringValue(in.VpcId)]; !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	out.NetworkAcl = f.newNetworkACL(in.VpcId, false)
+
+	return nil
+}
+
+// CreateNetworkAclEntry : adds an entry to a network acl
+func (f *EC2) CreateNetworkAclEntry(in *ec2.CreateNetworkAclEntryInput, out *ec2.CreateNetworkAclEntryOutput) error {
+	acl, ok := f.NetworkAcls[aws.StringValue(in.NetworkAclId)]
+	if !ok {
+		return notFound("InvalidNetworkAclID.NotFound", aws.StringValue(in.NetworkAclId))
+	}
+
+	if networkACLEntry(acl, in.Egress, in.RuleNumber) >= 0 {
+		return awserr.New("NetworkAclEntryAlreadyExists", fmt.Sprintf("The network acl entry identified by %d already exists", aws.Int64Value(in.RuleNumber)), nil)
+	}
+
+	acl.Entries = append(acl.Entries, &ec2.NetworkAclEntry{
+		RuleNumber:    in.RuleNumber,
+		Egress:        in.Egress,
+		Protocol:      in.Protocol,
+		RuleAction:    in.RuleAction,
+		CidrBlock:     in.CidrBlock,
+		Ipv6CidrBlock: in.Ipv6CidrBlock,
+		PortRange:     in.PortRange,
+		IcmpTypeCode:  in.IcmpTypeCode,
+	})
+
+	return nil
+}
+
+// ReplaceNetworkAclEntry : replaces an existing entry of a network acl
+func (f *EC2) ReplaceNetworkAclEntry(in *ec2.ReplaceNetworkAclEntryInput, out *ec2.ReplaceNetworkAclEntryOutput) error {
+	acl, ok := f.NetworkAcls[aws.StringValue(in.NetworkAclId)]
+	if !ok {
+		return notFound("InvalidNetworkAclID.NotFound", aws.StringValue(in.NetworkAclId))
+	}
+
+	i := networkACLEntry(acl, in.Egress, in.RuleNumber)
+	if i < 0 {
+		return notFound("InvalidNetworkAclEntry.NotFound", fmt.Sprint(aws.Int64Value(in.RuleNumber)))
+	}
+
+	acl.Entries[i] = &ec2.NetworkAclEntry{
+		RuleNumber:    in.RuleNumber,
+		Egress:        in.Egress,
+		Protocol:      in.Protocol,
+		RuleAction:    in.RuleAction,
+		CidrBlock:     in.CidrBlock,
+		Ipv6CidrBlock: in.Ipv6CidrBlock,
+		PortRange:     in.PortRange,
+		IcmpTypeCode:  in.IcmpTypeCode,
+	}
+
+	return nil
+}
+
+// DeleteNetworkAclEntry : removes an entry from a network acl
+func (f *EC2) DeleteNetworkAclEntry(in *ec2.DeleteNetworkAclEntryInput, out *ec2.DeleteNetworkAclEntryOutput) error {
+	acl, ok := f.NetworkAcls[aws.StringValue(in.NetworkAclId)]
+	if !ok {
+		return notFound("InvalidNetworkAclID.NotFound", aws.StringValue(in.NetworkAclId))
+	}
+
+	i := networkACLEntry(acl, in.Egress, in.RuleNumber)
+	if i < 0 {
+		return notFound("InvalidNetworkAclEntry.NotFound", fmt.Sprint(aws.Int64Value(in.RuleNumber)))
+	}
+
+	acl.Entries = append(acl.Entries[:i], acl.Entries[i+1:]...)
+
+	return nil
+}
+
+// ReplaceNetworkAclAssociation : moves a subnet to another network acl
+func (f *EC2) ReplaceNetworkAclAssociation(in *ec2.ReplaceNetworkAclAssociationInput, out *ec2.ReplaceNetworkAclAssociationOutput) error {
+	to, ok := f.NetworkAcls[aws.StringValue(in.NetworkAclId)]
+	if !ok {
+		return notFound("InvalidNetworkAclID.NotFound", aws.StringValue(in.NetworkAclId))
+	}
+
+	for _, acl := range f.NetworkAcls {
+		for i, a := range acl.Associations {
+			if *a.NetworkAclAssociationId != aws.StringValue(in.AssociationId) {
+				continue
+			}
+
+			if *acl.VpcId != *to.VpcId {
+				return awserr.New("InvalidParameterValue", "The network acl and the subnet belong to different vpcs", nil)
+			}
+
+			acl.Associations = append(acl.Associations[:i], acl.Associations[i+1:]...)
+
+			na := &ec2.NetworkAclAssociation{
+				NetworkAclAssociationId: aws.String(f.b.id("aclassoc")),
+				NetworkAclId:            to.NetworkAclId,
+				SubnetId:                a.SubnetId,
+			}
+			to.Associations = append(to.Associations, na)
+			out.NewAssociationId = na.NetworkAclAssociationId
+
+			return nil
+		}
+	}
+
+	return notFound("InvalidAssociationID.NotFound", aws.StringValue(in.AssociationId))
+}
+
+// networkACLEntry : returns the index of the entry with the direction and
+// rule number, or -1
+func networkACLEntry(acl *ec2.NetworkAcl, egress *bool, number *int64) int {
+	for i, e := range acl.Entries {
+		if aws.BoolValue(e.Egress) == aws.BoolValue(egress) && aws.Int64Value(e.RuleNumber) == aws.Int64Value(number) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// DescribeNetworkAcls : lists network acls
+func (f *EC2) DescribeNetworkAcls(in *ec2.DescribeNetworkAclsInput, out *ec2.DescribeNetworkAclsOutput) error {
+	for _, id := range in.NetworkAclIds {
+		if _, ok := f.NetworkAcls[*id]; !ok {
+			return notFound("InvalidNetworkAclID.NotFound", *id)
+		}
+	}
+
+	for _, acl := range f.NetworkAcls {
+		attrs := map[string][]string{
+			"network-acl-id": {*acl.NetworkAclId},
+			"vpc-id":         {*acl.VpcId},
+			"default":        {fmt.Sprint(*acl.IsDefault)},
+		}
+
+		for _, a := range acl.Associations {
+			attrs["association.subnet-id"] = append(attrs["association.subnet-id"], *a.SubnetId)
+		}
+
+		if selected(in.NetworkAclIds, acl.NetworkAclId) && matches(in.Filters, acl.Tags, attrs) {
+			out.NetworkAcls = append(out.NetworkAcls, acl)
+		}
+	}
+
+	return nil
+}
+
+// DeleteNetworkAcl : deletes a non default network acl without subnets
+func (f *EC2) DeleteNetworkAcl(in *ec2.DeleteNetworkAclInput, out *ec2.DeleteNetworkAclOutput) error {
+	id := aws.StringValue(in.NetworkAclId)
+
+	acl, ok := f.NetworkAcls[id]
+	if !ok {
+		return notFound("InvalidNetworkAclID.NotFound", id)
+	}
+
+	if *acl.IsDefault {
+		return awserr.New("InvalidParameterValue", "Cannot delete default network ACL "+id, nil)
+	}
+
+	if len(acl.Associations) > 0 {
+		return dependencyViolation(id)
+	}
+
+	delete(f.NetworkAcls, id)
+
+	return nil
+}
+
+func (f *EC2) newNetworkACL(vpc *string, isDefault bool) *ec2.NetworkAcl {
+	acl := &ec2.NetworkAcl{
+		NetworkAclId: aws.String(f.b.id("acl")),
+		VpcId:        vpc,
+		IsDefault:    aws.Bool(isDefault),
+	}
+
+	for _, egress := range []bool{false, true} {
+		acl.Entries = append(acl.Entries, &ec2.NetworkAclEntry{
+			RuleNumber: aws.Int64(32767),
+			Protocol:   aws.String("-1"),
+			RuleAction: aws.String(ec2.RuleActionDeny),
+			Egress:     aws.Bool(egress),
+			CidrBlock:  aws.String("0.0.0.0/0"),
+		})
+
+		if isDefault {
+			acl.Entries = append(acl.Entries, &ec2.NetworkAclEntry{
+				RuleNumber: aws.Int64(100),
+				Protocol:   aws.String("-1"),
+				RuleAction: aws.String(ec2.RuleActionAllow),
+				Egress:     aws.Bool(egress),
+				CidrBlock:  aws.String("0.0.0.0/0"),
+			})
+		}
+	}
+
+	f.NetworkAcls[*acl.NetworkAclId] = acl
+
+	return acl
+}
+
+// CreateVpcPeeringConnection : requests a peering connection between two
+// vpcs of the backend. Requests to another account stay pending until
+// they are accepted
+func (f *EC2) CreateVpcPeeringConnection(in *ec2.CreateVpcPeeringConnectionInput, out *ec2.CreateVpcPeeringConnectionOutput) error {
+	requester, ok := f.Vpcs[aws.StringValue(in.VpcId)]
+	if !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	accepter, ok := f.Vpcs[aws.StringValue(in.PeerVpcId)]
+	if !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.PeerVpcId))
+	}
+
+	owner := account
+	if in.PeerOwnerId != nil {
+		owner = *in.PeerOwnerId
+	}
+
+	region := f.b.Region
+	if in.PeerRegion != nil {
+		region = *in.PeerRegion
+	}
+
+	pc := &ec2.VpcPeeringConnection{
+		VpcPeeringConnectionId: aws.String(f.b.id("pcx")),
+		RequesterVpcInfo:       peeringVpcInfo(requester, account, f.b.Region),
+		AccepterVpcInfo:        peeringVpcInfo(accepter, owner, region),
+		Status: &ec2.VpcPeeringConnectionStateReason{
+			Code:    aws.String(ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance),
+			Message: aws.String("Pending Acceptance by " + owner),
+		},
+	}
+
+	f.VpcPeeringConnections[*pc.VpcPeeringConnectionId] = pc
+	out.VpcPeeringConnection = pc
+
+	return nil
+}
+
+// AcceptVpcPeeringConnection : activates a pending peering connection
+func (f *EC2) AcceptVpcPeeringConnection(in *ec2.AcceptVpcPeeringConnectionInput, out *ec2.AcceptVpcPeeringConnectionOutput) error {
+	id := aws.StringValue(in.VpcPeeringConnectionId)
+
+	pc, ok := f.VpcPeeringConnections[id]
+	if !ok {
+		return notFound("InvalidVpcPeeringConnectionID.NotFound", id)
+	}
+
+	if aws.StringValue(pc.Status.Code) != ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance {
+		return awserr.New("InvalidStateTransition", "Invalid state transition for "+id, nil)
+	}
+
+	pc.Status = &ec2.VpcPeeringConnectionStateReason{
+		Code:    aws.String(ec2.VpcPeeringConnectionStateReasonCodeActive),
+		Message: aws.String("Active"),
+	}
+	pc.AccepterVpcInfo.PeeringOptions = &ec2.VpcPeeringConnectionOptionsDescription{AllowDnsResolutionFromRemoteVpc: aws.Bool(false)}
+	pc.RequesterVpcInfo.PeeringOptions = &ec2.VpcPeeringConnectionOptionsDescription{AllowDnsResolutionFromRemoteVpc: aws.Bool(false)}
+
+	out.VpcPeeringConnection = pc
+
+	return nil
+}
+
+// ModifyVpcPeeringConnectionOptions : sets the dns resolution options of
+// an active peering connection
+func (f *EC2) ModifyVpcPeeringConnectionOptions(in *ec2.ModifyVpcPeeringConnectionOptionsInput, out *ec2.ModifyVpcPeeringConnectionOptionsOutput) error {
+	id := aws.StringValue(in.VpcPeeringConnectionId)
+
+	pc, ok := f.VpcPeeringConnections[id]
+	if !ok {
+		return notFound("InvalidVpcPeeringConnectionID.NotFound", id)
+	}
+
+	if aws.StringValue(pc.Status.Code) != ec2.VpcPeeringConnectionStateReasonCodeActive {
+		return awserr.New("OperationNotPermitted", "The peering connection "+id+" is not active", nil)
+	}
+
+	if o := in.RequesterPeeringConnectionOptions; o != nil && o.AllowDnsResolutionFromRemoteVpc != nil {
+		pc.RequesterVpcInfo.PeeringOptions.AllowDnsResolutionFromRemoteVpc = o.AllowDnsResolutionFromRemoteVpc
+	}
+
+	if o := in.AccepterPeeringConnectionOptions; o != nil && o.AllowDnsResolutionFromRemoteVpc != nil {
+		pc.AccepterVpcInfo.PeeringOptions.AllowDnsResolutionFromRemoteVpc = o.AllowDnsResolutionFromRemoteVpc
+	}
+
+	return nil
+}
+
+// DescribeVpcPeeringConnections : lists peering connections
+func (f *EC2) DescribeVpcPeeringConnections(in *ec2.DescribeVpcPeeringConnectionsInput, out *ec2.DescribeVpcPeeringConnectionsOutput) error {
+	for _, id := range in.VpcPeeringConnectionIds {
+		if _, ok := f.VpcPeeringConnections[*id]; !ok {
+			return notFound("InvalidVpcPeeringConnectionID.NotFound", *id)
+		}
+	}
+
+	for _, pc := range f.VpcPeeringConnections {
+		attrs := map[string][]string{
+			"vpc-peering-connection-id": {*pc.VpcPeeringConnectionId},
+			"requester-vpc-info.vpc-id": {*pc.RequesterVpcInfo.VpcId},
+			"accepter-vpc-info.vpc-id":  {*pc.AccepterVpcInfo.VpcId},
+			"status-code":               {*pc.Status.Code},
+		}
+
+		if selected(in.VpcPeeringConnectionIds, pc.VpcPeeringConnectionId) && matches(in.Filters, pc.Tags, attrs) {
+			out.VpcPeeringConnections = append(out.VpcPeeringConnections, pc)
+		}
+	}
+
+	return nil
+}
+
+// DeleteVpcPeeringConnection : marks a peering connection as deleted
+func (f *EC2) DeleteVpcPeeringConnection(in *ec2.DeleteVpcPeeringConnectionInput, out *ec2.DeleteVpcPeeringConnectionOutput) error {
+	id := aws.StringValue(in.VpcPeeringConnectionId)
+
+	pc, ok := f.VpcPeeringConnections[id]
+	if !ok || aws.StringValue(pc.Status.Code) == ec2.VpcPeeringConnectionStateReasonCodeDeleted {
+		return notFound("InvalidVpcPeeringConnectionID.NotFound", id)
+	}
+
+	pc.Status = &ec2.VpcPeeringConnectionStateReason{
+		Code:    aws.String(ec2.VpcPeeringConnectionStateReasonCodeDeleted),
+		Message: aws.String("Deleted by " + account),
+	}
+
+	for _, rt := range f.RouteTables {
+		for _, r := range rt.Routes {
+			if aws.StringValue(r.VpcPeeringConnectionId) == id {
+				r.State = aws.String(ec2.RouteStateBlackhole)
+			}
+		}
+	}
+
+	out.Return = aws.Bool(true)
+
+	return nil
+}
+
+func peeringVpcInfo(vpc *ec2.Vpc, owner, region string) *ec2.VpcPeeringConnectionVpcInfo {
+	info := &ec2.VpcPeeringConnectionVpcInfo{
+		VpcId:     vpc.VpcId,
+		OwnerId:   aws.String(owner),
+		Region:    aws.String(region),
+		CidrBlock: vpc.CidrBlock,
+	}
+
+	for _, a := range vpc.CidrBlockAssociationSet {
+		if aws.StringValue(a.CidrBlockState.State) == ec2.VpcCidrBlockStateCodeAssociated {
+			info.CidrBlockSet = append(info.CidrBlockSet, &ec2.CidrBlock{CidrBlock: a.CidrBlock})
+		}
+	}
+
+	return info
+}
+
+// CreateFlowLogs : creates a flow log for each of the resources. Resources
+// that don't exist are reported as unsuccessful
+func (f *EC2) CreateFlowLogs(in *ec2.CreateFlowLogsInput, out *ec2.CreateFlowLogsOutput) error {
+	destinationType := aws.StringValue(in.LogDestinationType)
+	if destinationType == "" {
+		destinationType = ec2.LogDestinationTypeCloudWatchLogs
+	}
+
+	switch destinationType {
+	case ec2.LogDestinationTypeS3:
+		if in.LogDestination == nil {
+			return awserr.New("InvalidParameter", "LogDestination is required for s3 flow logs", nil)
+		}
+	default:
+		if in.LogGroupName == nil || in.DeliverLogsPermissionArn == nil {
+			return awserr.New("InvalidParameter", "LogGroupName and DeliverLogsPermissionArn are required for cloud-watch-logs flow logs", nil)
+		}
+	}
+
+	format := aws.StringValue(in.LogFormat)
+	if format == "" {
+		format = "${version} ${account-id} ${interface-id} ${srcaddr} ${dstaddr} ${srcport} ${dstport} ${protocol} ${packets} ${bytes} ${start} ${end} ${action} ${log-status}"
+	}
+
+	for _, rid := range in.ResourceIds {
+		id := aws.StringValue(rid)
+
+		var exists bool
+		switch aws.StringValue(in.ResourceType) {
+		case ec2.FlowLogsResourceTypeVpc:
+			_, exists = f.Vpcs[id]
+		case ec2.FlowLogsResourceTypeSubnet:
+			_, exists = f.Subnets[id]
+		case ec2.FlowLogsResourceTypeNetworkInterface:
+			_, exists = f.NetworkInterfaces[id]
+		}
+
+		if !exists {
+			out.Unsuccessful = append(out.Unsuccessful, &ec2.UnsuccessfulItem{
+				ResourceId: rid,
+				Error: &ec2.UnsuccessfulItemError{
+					Code:    aws.String("InvalidParameter"),
+					Message: aws.String(fmt.Sprintf("The resource '%s' does not exist", id)),
+				},
+			})
+			continue
+		}
+
+		fl := &ec2.FlowLog{
+			FlowLogId:                aws.String(f.b.id("fl")),
+			ResourceId:               rid,
+			TrafficType:              in.TrafficType,
+			LogDestinationType:       aws.String(destinationType),
+			LogDestination:           in.LogDestination,
+			LogGroupName:             in.LogGroupName,
+			DeliverLogsPermissionArn: in.DeliverLogsPermissionArn,
+			LogFormat:                aws.String(format),
+			FlowLogStatus:            aws.String("ACTIVE"),
+			DeliverLogsStatus:        aws.String("SUCCESS"),
+		}
+
+		f.FlowLogs[*fl.FlowLogId] = fl
+		out.FlowLogIds = append(out.FlowLogIds, fl.FlowLogId)
+	}
+
+	return nil
+}
+
+// DescribeFlowLogs : lists flow logs
+func (f *EC2) DescribeFlowLogs(in *ec2.DescribeFlowLogsInput, out *ec2.DescribeFlowLogsOutput) error {
+	for _, fl := range f.FlowLogs {
+		attrs := map[string][]string{
+			"flow-log-id":          {*fl.FlowLogId},
+			"resource-id":          {*fl.ResourceId},
+			"traffic-type":         {aws.StringValue(fl.TrafficType)},
+			"log-destination-type": {*fl.LogDestinationType},
+			"log-group-name":       {aws.StringValue(fl.LogGroupName)},
+		}
+
+		if selected(in.FlowLogIds, fl.FlowLogId) && matches(in.Filter, fl.Tags, attrs) {
+			out.FlowLogs = append(out.FlowLogs, fl)
+		}
+	}
+
+	return nil
+}
+
+// DeleteFlowLogs : deletes flow logs, missing ones are reported as
+// unsuccessful
+func (f *EC2) DeleteFlowLogs(in *ec2.DeleteFlowLogsInput, out *ec2.DeleteFlowLogsOutput) error {
+	for _, id := range in.FlowLogIds {
+		if _, ok := f.FlowLogs[aws.StringValue(id)]; !ok {
+			out.Unsuccessful = append(out.Unsuccessful, &ec2.UnsuccessfulItem{
+				ResourceId: id,
+				Error: &ec2.UnsuccessfulItemError{
+					Code:    aws.String("InvalidFlowLogId.NotFound"),
+					Message: aws.String(fmt.Sprintf("The flow log '%s' does not exist", aws.StringValue(id))),
+				},
+			})
+			continue
+		}
+
+		delete(f.FlowLogs, aws.StringValue(id))
+	}
+
+	return nil
+}
+
+// CreateDhcpOptions : creates a dhcp options set
+func (f *EC2) CreateDhcpOptions(in *ec2.CreateDhcpOptionsInput, out *ec2.CreateDhcpOptionsOutput) error {
+	o := &ec2.DhcpOptions{
+		DhcpOptionsId: aws.String(f.b.id("dopt")),
+		OwnerId:       aws.String(account),
+	}
+
+	for _, c := range in.DhcpConfigurations {
+		cfg := &ec2.DhcpConfiguration{Key: c.Key}
+		for _, v := range c.Values {
+			cfg.Values = append(cfg.Values, &ec2.AttributeValue{Value: v})
+		}
+		o.DhcpConfigurations = append(o.DhcpConfigurations, cfg)
+	}
+
+	f.DhcpOptions[*o.DhcpOptionsId] = o
+	out.DhcpOptions = o
+
+	return nil
+}
+
+// AssociateDhcpOptions : sets the dhcp options of a vpc, "default" sets
+// the region default options
+func (f *EC2) AssociateDhcpOptions(in *ec2.AssociateDhcpOptionsInput, out *ec2.AssociateDhcpOptionsOutput) error {
+	vpc, ok := f.Vpcs[aws.StringValue(in.VpcId)]
+	if !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	id := aws.StringValue(in.DhcpOptionsId)
+	if id == "default" {
+		id = *f.defaultDhcpOptions().DhcpOptionsId
+	}
+
+	if _, ok := f.DhcpOptions[id]; !ok {
+		return notFound("InvalidDhcpOptionID.NotFound", id)
+	}
+
+	vpc.DhcpOptionsId = aws.String(id)
+
+	return nil
+}
+
+// DescribeDhcpOptions : lists dhcp options sets
+func (f *EC2) DescribeDhcpOptions(in *ec2.DescribeDhcpOptionsInput, out *ec2.DescribeDhcpOptionsOutput) error {
+	for _, id := range in.DhcpOptionsIds {
+		if _, ok := f.DhcpOptions[*id]; !ok {
+			return notFound("InvalidDhcpOptionID.NotFound", *id)
+		}
+	}
+
+	for _, o := range f.DhcpOptions {
+		attrs := map[string][]string{
+			"dhcp-options-id": {*o.DhcpOptionsId},
+		}
+
+		for _, c := range o.DhcpConfigurations {
+			attrs["key"] = append(attrs["key"], *c.Key)
+		}
+
+		if selected(in.DhcpOptionsIds, o.DhcpOptionsId) && matches(in.Filters, o.Tags, attrs) {
+			out.DhcpOptions = append(out.DhcpOptions, o)
+		}
+	}
+
+	return nil
+}
+
+// DeleteDhcpOptions : deletes a dhcp options set no vpc is using
+func (f *EC2) DeleteDhcpOptions(in *ec2.DeleteDhcpOptionsInput, out *ec2.DeleteDhcpOptionsOutput) error {
+	id := aws.StringValue(in.DhcpOptionsId)
+	if _, ok := f.DhcpOptions[id]; !ok {
+		return notFound("InvalidDhcpOptionID.NotFound", id)
+	}
+
+	for _, v := range f.Vpcs {
+		if aws.StringValue(v.DhcpOptionsId) == id {
+			return dependencyViolation(id)
+		}
+	}
+
+	delete(f.DhcpOptions, id)
+
+	return nil
+}
+
+// defaultDhcpOptions : returns the region default options set, created on
+// first use
+func (f *EC2) defaultDhcpOptions() *ec2.DhcpOptions {
+	for _, o := range f.DhcpOptions {
+		if aws.StringValue(o.OwnerId) == "amazon" {
+			return o
+		}
+	}
+
+	o := &ec2.DhcpOptions{
+		DhcpOptionsId: aws.String(f.b.id("dopt")),
+		OwnerId:       aws.String("amazon"),
+		DhcpConfigurations: []*ec2.DhcpConfiguration{
+			{Key: aws.String("domain-name"), Values: []*ec2.AttributeValue{{Value: aws.String(f.b.Region + ".compute.internal")}}},
+			{Key: aws.String("domain-name-servers"), Values: []*ec2.AttributeValue{{Value: aws.String("AmazonProvidedDNS")}}},
+		},
+	}
+
+	f.DhcpOptions[*o.DhcpOptionsId] = o
+
+	return o
+}
+
+// CreateVpcEndpoint : creates a vpc endpoint. Gateway endpoints are
+// available straight away and add a prefix list route to their route
+// tables, interface endpoints get a network interface on each subnet and
+// are pending until they are first described
+func (f *EC2) CreateVpcEndpoint(in *ec2.CreateVpcEndpointInput, out *ec2.CreateVpcEndpointOutput) error {
+	if _, ok := f.Vpcs[aws.StringValue(in.VpcId)]; !ok {
+		return notFound("InvalidVpcId.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	t := aws.StringValue(in.VpcEndpointType)
+	if t == "" {
+		t = ec2.VpcEndpointTypeGateway
+	}
+
+	policy := in.PolicyDocument
+	if policy == nil {
+		policy = aws.String(`{"Version":"2008-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"*","Resource":"*"}]}`)
+	}
+
+	e := &ec2.VpcEndpoint{
+		VpcEndpointId:     aws.String(f.b.id("vpce")),
+		VpcEndpointType:   aws.String(t),
+		VpcId:             in.VpcId,
+		ServiceName:       in.ServiceName,
+		PolicyDocument:    policy,
+		State:             aws.String("available"),
+		OwnerId:           aws.String(account),
+		PrivateDnsEnabled: aws.Bool(false),
+	}
+
+	if t == ec2.VpcEndpointTypeInterface {
+		e.State = aws.String("pending")
+		e.PrivateDnsEnabled = aws.Bool(in.PrivateDnsEnabled == nil || *in.PrivateDnsEnabled)
+		e.DnsEntries = []*ec2.DnsEntry{{
+			DnsName:      aws.String(*e.VpcEndpointId + "." + strings.TrimPrefix(*in.ServiceName, "com.amazonaws.") + ".vpce.amazonaws.com"),
+			HostedZoneId: aws.String("Z7HUB22UULQXV"),
+		}}
+
+		for _, id := range in.SecurityGroupIds {
+			if _, ok := f.SecurityGroups[*id]; !ok {
+				return notFound("InvalidGroup.NotFound", *id)
+			}
+			e.Groups = append(e.Groups, &ec2.SecurityGroupIdentifier{GroupId: id})
+		}
+
+		for _, id := range in.SubnetIds {
+			if err := f.addEndpointSubnet(e, *id); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, id := range in.RouteTableIds {
+		if err := f.addEndpointRouteTable(e, *id); err != nil {
+			return err
+		}
+	}
+
+	f.VpcEndpoints[*e.VpcEndpointId] = e
+	out.VpcEndpoint = e
+
+	return nil
+}
+
+// ModifyVpcEndpoint : adds or removes route tables, subnets and security
+// groups, and sets the policy or private dns of a vpc endpoint
+func (f *EC2) ModifyVpcEndpoint(in *ec2.ModifyVpcEndpointInput, out *ec2.ModifyVpcEndpointOutput) error {
+	id := aws.StringValue(in.VpcEndpointId)
+
+	e, ok := f.VpcEndpoints[id]
+	if !ok {
+		return notFound("InvalidVpcEndpointId.NotFound", id)
+	}
+
+	for _, rt := range in.AddRouteTableIds {
+		if err := f.addEndpointRouteTable(e, *rt); err != nil {
+			return err
+		}
+	}
+
+	for _, rt := range in.RemoveRouteTableIds {
+		f.removeEndpointRouteTable(e, *rt)
+	}
+
+	for _, s := range in.AddSubnetIds {
+		if err := f.addEndpointSubnet(e, *s); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range in.RemoveSubnetIds {
+		f.removeEndpointSubnet(e, *s)
+	}
+
+	for _, g := range in.AddSecurityGroupIds {
+		if _, ok := f.SecurityGroups[*g]; !ok {
+			return notFound("InvalidGroup.NotFound", *g)
+		}
+		e.Groups = append(e.Groups, &ec2.SecurityGroupIdentifier{GroupId: g})
+	}
+
+	for _, g := range in.RemoveSecurityGroupIds {
+		for i := len(e.Groups) - 1; i >= 0; i-- {
+			if *e.Groups[i].GroupId == *g {
+				e.Groups = append(e.Groups[:i], e.Groups[i+1:]...)
+			}
+		}
+	}
+
+	if in.PolicyDocument != nil {
+		e.PolicyDocument = in.PolicyDocument
+	}
+
+	if in.PrivateDnsEnabled != nil {
+		e.PrivateDnsEnabled = in.PrivateDnsEnabled
+	}
+
+	out.Return = aws.Bool(true)
+
+	return nil
+}
+
+// DescribeVpcEndpoints : lists vpc endpoints, pending ones become
+// available once they are described
+func (f *EC2) DescribeVpcEndpoints(in *ec2.DescribeVpcEndpointsInput, out *ec2.DescribeVpcEndpointsOutput) error {
+	for _, id := range in.VpcEndpointIds {
+		if _, ok := f.VpcEndpoints[*id]; !ok {
+			return notFound("InvalidVpcEndpointId.NotFound", *id)
+		}
+	}
+
+	for _, e := range f.VpcEndpoints {
+		attrs := map[string][]string{
+			"vpc-endpoint-id":    {*e.VpcEndpointId},
+			"vpc-id":             {*e.VpcId},
+			"service-name":       {aws.StringValue(e.ServiceName)},
+			"vpc-endpoint-type":  {*e.VpcEndpointType},
+			"vpc-endpoint-state": {*e.State},
+		}
+
+		if selected(in.VpcEndpointIds, e.VpcEndpointId) && matches(in.Filters, e.Tags, attrs) {
+			out.VpcEndpoints = append(out.VpcEndpoints, e)
+		}
+
+		if *e.State == "pending" {
+			e.State = aws.String("available")
+		}
+	}
+
+	return nil
+}
+
+// DeleteVpcEndpoints : deletes vpc endpoints with their routes and
+// network interfaces, missing ones are reported as unsuccessful
+func (f *EC2) DeleteVpcEndpoints(in *ec2.DeleteVpcEndpointsInput, out *ec2.DeleteVpcEndpointsOutput) error {
+	for _, id := range in.VpcEndpointIds {
+		e, ok := f.VpcEndpoints[aws.StringValue(id)]
+		if !ok {
+			out.Unsuccessful = append(out.Unsuccessful, &ec2.UnsuccessfulItem{
+				ResourceId: id,
+				Error: &ec2.UnsuccessfulItemError{
+					Code:    aws.String("InvalidVpcEndpoint.NotFound"),
+					Message: aws.String(fmt.Sprintf("The Vpc Endpoint Id '%s' does not exist", aws.StringValue(id))),
+				},
+			})
+			continue
+		}
+
+		for _, rt := range append([]*string{}, e.RouteTableIds...) {
+			f.removeEndpointRouteTable(e, *rt)
+		}
+
+		for _, s := range append([]*string{}, e.SubnetIds...) {
+			f.removeEndpointSubnet(e, *s)
+		}
+
+		delete(f.VpcEndpoints, *id)
+	}
+
+	return nil
+}
+
+func (f *EC2) addEndpointRouteTable(e *ec2.VpcEndpoint, id string) error {
+	rt, ok := f.RouteTables[id]
+	if !ok {
+		return notFound("InvalidRouteTableID.NotFound", id)
+	}
+
+	rt.Routes = append(rt.Routes, &ec2.Route{
+		DestinationPrefixListId: aws.String("pl-" + strings.TrimPrefix(*e.VpcEndpointId, "vpce-")),
+		GatewayId:               e.VpcEndpointId,
+		Origin:                  aws.String(ec2.RouteOriginCreateRoute),
+		State:                   aws.String(ec2.RouteStateActive),
+	})
+	e.RouteTableIds = append(e.RouteTableIds, aws.String(id))
+
+	return nil
+}
+
+func (f *EC2) removeEndpointRouteTable(e *ec2.VpcEndpoint, id string) {
+	if rt, ok := f.RouteTables[id]; ok {
+		for i := len(rt.Routes) - 1; i >= 0; i-- {
+			if aws.StringValue(rt.Routes[i].GatewayId) == *e.VpcEndpointId {
+				rt.Routes = append(rt.Routes[:i], rt.Routes[i+1:]...)
+			}
+		}
+	}
+
+	for i := len(e.RouteTableIds) - 1; i >= 0; i-- {
+		if *e.RouteTableIds[i] == id {
+			e.RouteTableIds = append(e.RouteTableIds[:i], e.RouteTableIds[i+1:]...)
+		}
+	}
+}
+
+func (f *EC2) addEndpointSubnet(e *ec2.VpcEndpoint, id string) error {
+	s, ok := f.Subnets[id]
+	if !ok {
+		return notFound("InvalidSubnetID.NotFound", id)
+	}
+
+	ni := &ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String(f.b.id("eni")),
+		SubnetId:           s.SubnetId,
+		VpcId:              s.VpcId,
+		Description:        aws.String("VPC Endpoint Interface " + *e.VpcEndpointId),
+		InterfaceType:      aws.String("vpc_endpoint"),
+		RequesterManaged:   aws.Bool(true),
+		Status:             aws.String(ec2.NetworkInterfaceStatusInUse),
+	}
+	f.NetworkInterfaces[*ni.NetworkInterfaceId] = ni
+
+	e.SubnetIds = append(e.SubnetIds, aws.String(id))
+	e.NetworkInterfaceIds = append(e.NetworkInterfaceIds, ni.NetworkInterfaceId)
+
+	return nil
+}
+
+func (f *EC2) removeEndpointSubnet(e *ec2.VpcEndpoint, id string) {
+	for i := len(e.NetworkInterfaceIds) - 1; i >= 0; i-- {
+		ni, ok := f.NetworkInterfaces[*e.NetworkInterfaceIds[i]]
+		if ok && *ni.SubnetId == id {
+			delete(f.NetworkInterfaces, *ni.NetworkInterfaceId)
+			e.NetworkInterfaceIds = append(e.NetworkInterfaceIds[:i], e.NetworkInterfaceIds[i+1:]...)
+		}
+	}
+
+	for i := len(e.SubnetIds) - 1; i >= 0; i-- {
+		if *e.SubnetIds[i] == id {
+			e.SubnetIds = append(e.SubnetIds[:i], e.SubnetIds[i+1:]...)
+		}
+	}
+}
+
+// CreateEgressOnlyInternetGateway : creates an egress only internet
+// gateway attached to a vpc
+func (f *EC2) CreateEgressOnlyInternetGateway(in *ec2.CreateEgressOnlyInternetGatewayInput, out *ec2.CreateEgressOnlyInternetGatewayOutput) error {
+	if _, ok := f.Vpcs[aws.StringValue(in.VpcId)]; !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	gw := &ec2.EgressOnlyInternetGateway{
+		EgressOnlyInternetGatewayId: aws.String(f.b.id("eigw")),
+		Attachments: []*ec2.InternetGatewayAttachment{{
+			VpcId: in.VpcId,
+			State: aws.String(ec2.AttachmentStatusAttached),
+		}},
+	}
+
+	f.EgressOnlyInternetGateways[*gw.EgressOnlyInternetGatewayId] = gw
+	out.EgressOnlyInternetGateway = gw
+
+	return nil
+}
+
+// DescribeEgressOnlyInternetGateways : lists egress only internet gateways
+func (f *EC2) DescribeEgressOnlyInternetGateways(in *ec2.DescribeEgressOnlyInternetGatewaysInput, out *ec2.DescribeEgressOnlyInternetGatewaysOutput) error {
+	for _, gw := range f.EgressOnlyInternetGateways {
+		if selected(in.EgressOnlyInternetGatewayIds, gw.EgressOnlyInternetGatewayId) && matches(in.Filters, gw.Tags, nil) {
+			out.EgressOnlyInternetGateways = append(out.EgressOnlyInternetGateways, gw)
+		}
+	}
+
+	return nil
+}
+
+// DeleteEgressOnlyInternetGateway : deletes an egress only internet gateway
+func (f *EC2) DeleteEgressOnlyInternetGateway(in *ec2.DeleteEgressOnlyInternetGatewayInput, out *ec2.DeleteEgressOnlyInternetGatewayOutput) error {
+	id := aws.StringValue(in.EgressOnlyInternetGatewayId)
+	if _, ok := f.EgressOnlyInternetGateways[id]; !ok {
+		return notFound("InvalidGatewayID.NotFound", id)
+	}
+
+	delete(f.EgressOnlyInternetGateways, id)
+	out.ReturnCode = aws.Bool(true)
+
+	return nil
+}
+
+// CreateTags : adds or overwrites tags on any ec2 resource
+func (f *EC2) CreateTags(in *ec2.CreateTagsInput, out *ec2.CreateTagsOutput) error {
+	for _, id := range in.Resources {
+		tags := f.tags(aws.StringValue(id))
+		if tags == nil {
+			return notFound("InvalidID", aws.StringValue(id))
+		}
+
+		for _, t := range in.Tags {
+			*tags = setTag(*tags, aws.StringValue(t.Key), aws.StringValue(t.Value))
+		}
+	}
+
+	return nil
+}
+
+// DeleteTags : removes tags from any ec2 resource, tags given with a
+// value are only removed when the value matches
+func (f *EC2) DeleteTags(in *ec2.DeleteTagsInput, out *ec2.DeleteTagsOutput) error {
+	for _, id := range in.Resources {
+		tags := f.tags(aws.StringValue(id))
+		if tags == nil {
+			return notFound("InvalidID", aws.StringValue(id))
+		}
+
+		for _, t := range in.Tags {
+			for i := len(*tags) - 1; i >= 0; i-- {
+				c := (*tags)[i]
+				if *c.Key == aws.StringValue(t.Key) && (t.Value == nil || *c.Value == *t.Value) {
+					*tags = append((*tags)[:i], (*tags)[i+1:]...)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunInstances : launches a single running instance
+func (f *EC2) RunInstances(in *ec2.RunInstancesInput, out *ec2.Reservation) error {
+	s, ok := f.Subnets[aws.StringValue(in.SubnetId)]
+	if !ok {
+		return notFound("InvalidSubnetID.NotFound", aws.StringValue(in.SubnetId))
+	}
+
+	i := &ec2.Instance{
+		InstanceId:       aws.String(f.b.id("i")),
+		ImageId:          in.ImageId,
+		InstanceType:     in.InstanceType,
+		KeyName:          in.KeyName,
+		SubnetId:         s.SubnetId,
+		VpcId:            s.VpcId,
+		PrivateIpAddress: in.PrivateIpAddress,
+		RootDeviceName:   aws.String("/dev/xvda"),
+		State:            instanceState(instanceRunning),
+		Placement:        &ec2.Placement{AvailabilityZone: s.AvailabilityZone},
+	}
+
+	if i.PrivateIpAddress == nil {
+		i.PrivateIpAddress = aws.String(fmt.Sprintf("10.0.%d.%d", f.b.ids/250%250, f.b.ids%250+4))
+	}
+
+	if aws.BoolValue(s.MapPublicIpOnLaunch) {
+		i.PublicIpAddress = aws.String(fmt.Sprintf("54.0.%d.%d", f.b.ids/250%250, f.b.ids%250+1))
+	}
+
+	for _, id := range in.SecurityGroupIds {
+		sg, ok := f.SecurityGroups[aws.StringValue(id)]
+		if !ok {
+			return notFound("InvalidGroup.NotFound", aws.StringValue(id))
+		}
+
+		i.SecurityGroups = append(i.SecurityGroups, &ec2.GroupIdentifier{
+			GroupId:   sg.GroupId,
+			GroupName: sg.GroupName,
+		})
+	}
+
+	if in.IamInstanceProfile != nil {
+		arn := in.IamInstanceProfile.Arn
+		if arn == nil {
+			arn = aws.String(f.b.arn("iam", "instance-profile/"+aws.StringValue(in.IamInstanceProfile.Name)))
+		}
+		i.IamInstanceProfile = &ec2.IamInstanceProfile{Arn: arn}
+	}
+
+	root := f.newVolume(s.AvailabilityZone, aws.Int64(8))
+	f.attach(root, i, i.RootDeviceName)
+
+	ni := &ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String(f.b.id("eni")),
+		SubnetId:           s.SubnetId,
+		VpcId:              s.VpcId,
+		PrivateIpAddress:   i.PrivateIpAddress,
+		Status:             aws.String(ec2.NetworkInterfaceStatusInUse),
+		Attachment:         &ec2.NetworkInterfaceAttachment{InstanceId: i.InstanceId, DeviceIndex: aws.Int64(0)},
+	}
+	f.NetworkInterfaces[*ni.NetworkInterfaceId] = ni
+
+	i.NetworkInterfaces = []*ec2.InstanceNetworkInterface{{
+		NetworkInterfaceId: ni.NetworkInterfaceId,
+		SubnetId:           ni.SubnetId,
+		VpcId:              ni.VpcId,
+		PrivateIpAddress:   ni.PrivateIpAddress,
+		Status:             ni.Status,
+		Attachment:         &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(0)},
+	}}
+
+	count := aws.Int64Value(in.Ipv6AddressCount)
+	if in.Ipv6AddressCount == nil && aws.BoolValue(s.AssignIpv6AddressOnCreation) {
+		count = 1
+	}
+
+	f.Instances[*i.InstanceId] = i
+
+	if _, err := f.assignIpv6Addresses(ni, count); err != nil {
+		delete(f.NetworkInterfaces, *ni.NetworkInterfaceId)
+		delete(f.Instances, *i.InstanceId)
+		return err
+	}
+
+	out.ReservationId = aws.String(f.b.id("r"))
+	out.OwnerId = aws.String(account)
+	out.Instances = []*ec2.Instance{i}
+
+	return nil
+}
+
+// AssignIpv6Addresses : assigns new ipv6 addresses of the subnet block
+// to a network interface
+func (f *EC2) AssignIpv6Addresses(in *ec2.AssignIpv6AddressesInput, out *ec2.AssignIpv6AddressesOutput) error {
+	ni, ok := f.NetworkInterfaces[aws.StringValue(in.NetworkInterfaceId)]
+	if !ok {
+		return notFound("InvalidNetworkInterfaceID.NotFound", aws.StringValue(in.NetworkInterfaceId))
+	}
+
+	addrs, err := f.assignIpv6Addresses(ni, aws.Int64Value(in.Ipv6AddressCount))
+	if err != nil {
+		return err
+	}
+
+	out.NetworkInterfaceId = ni.NetworkInterfaceId
+	out.AssignedIpv6Addresses = addrs
+
+	return nil
+}
+
+// UnassignIpv6Addresses : removes ipv6 addresses from a network interface
+func (f *EC2) UnassignIpv6Addresses(in *ec2.UnassignIpv6AddressesInput, out *ec2.UnassignIpv6AddressesOutput) error {
+	ni, ok := f.NetworkInterfaces[aws.StringValue(in.NetworkInterfaceId)]
+	if !ok {
+		return notFound("InvalidNetworkInterfaceID.NotFound", aws.StringValue(in.NetworkInterfaceId))
+	}
+
+	for _, addr := range in.Ipv6Addresses {
+		found := false
+		for i, a := range ni.Ipv6Addresses {
+			if *a.Ipv6Address == aws.StringValue(addr) {
+				ni.Ipv6Addresses = append(ni.Ipv6Addresses[:i], ni.Ipv6Addresses[i+1:]...)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return notFound("InvalidParameterValue", aws.StringValue(addr))
+		}
+
+		out.UnassignedIpv6Addresses = append(out.UnassignedIpv6Addresses, addr)
+	}
+
+	f.syncIpv6Addresses(ni)
+	out.NetworkInterfaceId = ni.NetworkInterfaceId
+
+	return nil
+}
+
+// assignIpv6Addresses : adds count addresses of the subnet ipv6 block to
+// the interface, keeping the instance view of the interface in sync
+func (f *EC2) assignIpv6Addresses(ni *ec2.NetworkInterface, count int64) ([]*string, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	var block string
+	for _, a := range f.Subnets[*ni.SubnetId].Ipv6CidrBlockAssociationSet {
+		block = aws.StringValue(a.Ipv6CidrBlock)
+	}
+
+	if block == "" {
+		return nil, awserr.New("InvalidParameterValue", "The subnet "+*ni.SubnetId+" has no ipv6 cidr block", nil)
+	}
+
+	var addrs []*string
+	for ; count > 0; count-- {
+		a := aws.String(fmt.Sprintf("%s%x", strings.TrimSuffix(block, "/64"), f.b.ids+0x100))
+		f.b.ids++
+
+		ni.Ipv6Addresses = append(ni.Ipv6Addresses, &ec2.NetworkInterfaceIpv6Address{Ipv6Address: a})
+		addrs = append(addrs, a)
+	}
+
+	f.syncIpv6Addresses(ni)
+
+	return addrs, nil
+}
+
+// syncIpv6Addresses : copies the ipv6 addresses of an interface to the
+// instance it is attached to
+func (f *EC2) syncIpv6Addresses(ni *ec2.NetworkInterface) {
+	if ni.Attachment == nil {
+		return
+	}
+
+	i, ok := f.Instances[aws.StringValue(ni.Attachment.InstanceId)]
+	if !ok {
+		return
+	}
+
+	for _, ini := range i.NetworkInterfaces {
+		if *ini.NetworkInterfaceId != *ni.NetworkInterfaceId {
+			continue
+		}
+
+		ini.Ipv6Addresses = nil
+		for _, a := range ni.Ipv6Addresses {
+			ini.Ipv6Addresses = append(ini.Ipv6Addresses, &ec2.InstanceIpv6Address{Ipv6Address: a.Ipv6Address})
+		}
+	}
+}
+
+// DescribeInstances : lists instances, one per reservation
+func (f *EC2) DescribeInstances(in *ec2.DescribeInstancesInput, out *ec2.DescribeInstancesOutput) error {
+	for _, id := range in.InstanceIds {
+		if _, ok := f.Instances[*id]; !ok {
+			return notFound("InvalidInstanceID.NotFound", *id)
+		}
+	}
+
+	for _, i := range f.Instances {
+		attrs := map[string][]string{
+			"instance-id":         {*i.InstanceId},
+			"instance-state-name": {*i.State.Name},
+			"subnet-id":           {aws.StringValue(i.SubnetId)},
+			"vpc-id":              {aws.StringValue(i.VpcId)},
+		}
+
+		if selected(in.InstanceIds, i.InstanceId) && matches(in.Filters, i.Tags, attrs) {
+			out.Reservations = append(out.Reservations, &ec2.Reservation{
+				ReservationId: aws.String("r-" + strings.TrimPrefix(*i.InstanceId, "i-")),
+				OwnerId:       aws.String(account),
+				Instances:     []*ec2.Instance{i},
+			})
+		}
+	}
+
+	return nil
+}
+
+// DescribeInstanceStatus : returns the status of the instances, always ok
+func (f *EC2) DescribeInstanceStatus(in *ec2.DescribeInstanceStatusInput, out *ec2.DescribeInstanceStatusOutput) error {
+	for _, i := range f.Instances {
+		if !selected(in.InstanceIds, i.InstanceId) {
+			continue
+		}
+
+		if *i.State.Code != instanceRunning && !aws.BoolValue(in.IncludeAllInstances) {
+			continue
+		}
+
+		out.InstanceStatuses = append(out.InstanceStatuses, &ec2.InstanceStatus{
+			InstanceId:     i.InstanceId,
+			InstanceState:  i.State,
+			InstanceStatus: &ec2.InstanceStatusSummary{Status: aws.String(ec2.SummaryStatusOk)},
+			SystemStatus:   &ec2.InstanceStatusSummary{Status: aws.String(ec2.SummaryStatusOk)},
+		})
+	}
+
+	return nil
+}
+
+// StopInstances : stops instances
+func (f *EC2) StopInstances(in *ec2.StopInstancesInput, out *ec2.StopInstancesOutput) error {
+	changes, err := f.setInstanceStates(in.InstanceIds, instanceStopped)
+	out.StoppingInstances = changes
+	return err
+}
+
+// StartInstances : starts instances
+func (f *EC2) StartInstances(in *ec2.StartInstancesInput, out *ec2.StartInstancesOutput) error {
+	changes, err := f.setInstanceStates(in.InstanceIds, instanceRunning)
+	out.StartingInstances = changes
+	return err
+}
+
+// TerminateInstances : terminates instances, releasing their interfaces
+// and detaching their volumes
+func (f *EC2) TerminateInstances(in *ec2.TerminateInstancesInput, out *ec2.TerminateInstancesOutput) error {
+	changes, err := f.setInstanceStates(in.InstanceIds, instanceTerminated)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range in.InstanceIds {
+		i := f.Instances[*id]
+
+		for _, bdm := range i.BlockDeviceMappings {
+			if v, ok := f.Volumes[*bdm.Ebs.VolumeId]; ok {
+				f.detach(v, i)
+				if *bdm.DeviceName == *i.RootDeviceName {
+					delete(f.Volumes, *v.VolumeId)
+				}
+			}
+		}
+		i.BlockDeviceMappings = nil
+
+		for nid, ni := range f.NetworkInterfaces {
+			if ni.Attachment != nil && aws.StringValue(ni.Attachment.InstanceId) == *id {
+				delete(f.NetworkInterfaces, nid)
+			}
+		}
+
+		for _, a := range f.Addresses {
+			if aws.StringValue(a.InstanceId) == *id {
+				a.InstanceId = nil
+				a.AssociationId = nil
+			}
+		}
+
+		i.PublicIpAddress = nil
+	}
+
+	out.TerminatingInstances = changes
+
+	return nil
+}
+
+// ModifyInstanceAttribute : updates the type or security groups of an instance
+func (f *EC2) ModifyInstanceAttribute(in *ec2.ModifyInstanceAttributeInput, out *ec2.ModifyInstanceAttributeOutput) error {
+	i, ok := f.Instances[aws.StringValue(in.InstanceId)]
+	if !ok {
+		return notFound("InvalidInstanceID.NotFound", aws.StringValue(in.InstanceId))
+	}
+
+	if in.InstanceType != nil {
+		if *i.State.Code != instanceStopped {
+			return awserr.New("IncorrectInstanceState", "The instance must be stopped to change its type", nil)
+		}
+		i.InstanceType = in.InstanceType.Value
+	}
+
+	if in.Groups != nil {
+		var groups []*ec2.GroupIdentifier
+
+		for _, id := range in.Groups {
+			sg, ok := f.SecurityGroups[aws.StringValue(id)]
+			if !ok {
+				return notFound("InvalidGroup.NotFound", aws.StringValue(id))
+			}
+			groups = append(groups, &ec2.GroupIdentifier{GroupId: sg.GroupId, GroupName: sg.GroupName})
+		}
+
+		i.SecurityGroups = groups
+	}
+
+	return nil
+}
+
+// AllocateAddress : allocates an elastic ip
+func (f *EC2) AllocateAddress(in *ec2.AllocateAddressInput, out *ec2.AllocateAddressOutput) error {
+	a := &ec2.Address{
+		AllocationId: aws.String(f.b.id("eipalloc")),
+		PublicIp:     aws.String(fmt.Sprintf("52.0.%d.%d", f.b.ids/250%250, f.b.ids%250+1)),
+		Domain:       aws.String(ec2.DomainTypeVpc),
+	}
+
+	f.Addresses[*a.AllocationId] = a
+
+	out.AllocationId = a.AllocationId
+	out.PublicIp = a.PublicIp
+	out.Domain = a.Domain
+
+	return nil
+}
+
+// AssociateAddress : associates an elastic ip with an instance
+func (f *EC2) AssociateAddress(in *ec2.AssociateAddressInput, out *ec2.AssociateAddressOutput) error {
+	a, ok := f.Addresses[aws.StringValue(in.AllocationId)]
+	if !ok {
+		return notFound("InvalidAllocationID.NotFound", aws.StringValue(in.AllocationId))
+	}
+
+	i, ok := f.Instances[aws.StringValue(in.InstanceId)]
+	if !ok {
+		return notFound("InvalidInstanceID.NotFound", aws.StringValue(in.InstanceId))
+	}
+
+	a.InstanceId = i.InstanceId
+	a.AssociationId = aws.String(f.b.id("eipassoc"))
+	i.PublicIpAddress = a.PublicIp
+
+	out.AssociationId = a.AssociationId
+
+	return nil
+}
+
+// ReleaseAddress : releases an elastic ip that is not in use
+func (f *EC2) ReleaseAddress(in *ec2.ReleaseAddressInput, out *ec2.ReleaseAddressOutput) error {
+	id := aws.StringValue(in.AllocationId)
+
+	a, ok := f.Addresses[id]
+	if !ok {
+		return notFound("InvalidAllocationID.NotFound", id)
+	}
+
+	if a.AssociationId != nil {
+		return awserr.New("InvalidIPAddress.InUse", "Address "+*a.PublicIp+" is in use", nil)
+	}
+
+	delete(f.Addresses, id)
+
+	return nil
+}
+
+// DescribeAddresses : lists the elastic ips, by allocation id
+func (f *EC2) DescribeAddresses(in *ec2.DescribeAddressesInput, out *ec2.DescribeAddressesOutput) error {
+	for _, id := range in.AllocationIds {
+		if _, ok := f.Addresses[aws.StringValue(id)]; !ok {
+			return notFound("InvalidAllocationID.NotFound", aws.StringValue(id))
+		}
+	}
+
+	for _, a := range f.Addresses {
+		if selected(in.AllocationIds, a.AllocationId) {
+			out.Addresses = append(out.Addresses, a)
+		}
+	}
+
+	return nil
+}
+
+// CreateVolume : creates an available ebs volume
+func (f *EC2) CreateVolume(in *ec2.CreateVolumeInput, out *ec2.Volume) error {
+	v := f.newVolume(in.AvailabilityZone, in.Size)
+	v.VolumeType = in.VolumeType
+	v.Iops = in.Iops
+	v.Encrypted = in.Encrypted
+	v.KmsKeyId = in.KmsKeyId
+
+	*out = *v
+
+	return nil
+}
+
+// DeleteVolume : deletes a detached volume
+func (f *EC2) DeleteVolume(in *ec2.DeleteVolumeInput, out *ec2.DeleteVolumeOutput) error {
+	id := aws.StringValue(in.VolumeId)
+
+	v, ok := f.Volumes[id]
+	if !ok {
+		return notFound("InvalidVolume.NotFound", id)
+	}
+
+	if len(v.Attachments) > 0 {
+		return awserr.New("VolumeInUse", "Volume "+id+" is currently attached", nil)
+	}
+
+	delete(f.Volumes, id)
+
+	return nil
+}
+
+// DescribeVolumes : lists volumes
+func (f *EC2) DescribeVolumes(in *ec2.DescribeVolumesInput, out *ec2.DescribeVolumesOutput) error {
+	for _, v := range f.Volumes {
+		attrs := map[string][]string{
+			"volume-id":         {*v.VolumeId},
+			"availability-zone": {aws.StringValue(v.AvailabilityZone)},
+			"status":            {aws.StringValue(v.State)},
+		}
+		for _, a := range v.Attachments {
+			attrs["attachment.instance-id"] = append(attrs["attachment.instance-id"], *a.InstanceId)
+		}
+
+		if selected(in.VolumeIds, v.VolumeId) && matches(in.Filters, v.Tags, attrs) {
+			out.Volumes = append(out.Volumes, v)
+		}
+	}
+
+	return nil
+}
+
+// AttachVolume : attaches a volume to an instance
+func (f *EC2) AttachVolume(in *ec2.AttachVolumeInput, out *ec2.VolumeAttachment) error {
+	v, ok := f.Volumes[aws.StringValue(in.VolumeId)]
+	if !ok {
+		return notFound("InvalidVolume.NotFound", aws.StringValue(in.VolumeId))
+	}
+
+	i, ok := f.Instances[aws.StringValue(in.InstanceId)]
+	if !ok {
+		return notFound("InvalidInstanceID.NotFound", aws.StringValue(in.InstanceId))
+	}
+
+	if len(v.Attachments) > 0 {
+		return awserr.New("VolumeInUse", "Volume "+*v.VolumeId+" is already attached", nil)
+	}
+
+	*out = *f.attach(v, i, in.Device)
+
+	return nil
+}
+
+// DetachVolume : detaches a volume from an instance
+func (f *EC2) DetachVolume(in *ec2.DetachVolumeInput, out *ec2.VolumeAttachment) error {
+	v, ok := f.Volumes[aws.StringValue(in.VolumeId)]
+	if !ok {
+		return notFound("InvalidVolume.NotFound", aws.StringValue(in.VolumeId))
+	}
+
+	if len(v.Attachments) < 1 {
+		return awserr.New("IncorrectState", "Volume "+*v.VolumeId+" is not attached", nil)
+	}
+
+	i := f.Instances[*v.Attachments[0].InstanceId]
+	*out = *v.Attachments[0]
+	out.State = aws.String(ec2.VolumeAttachmentStateDetached)
+
+	f.detach(v, i)
+
+	return nil
+}
+
+// CreateSecurityGroup : creates a security group with the default egress rule
+func (f *EC2) CreateSecurityGroup(in *ec2.CreateSecurityGroupInput, out *ec2.CreateSecurityGroupOutput) error {
+	if _, ok := f.Vpcs[aws.StringValue(in.VpcId)]; !ok {
+		return notFound("InvalidVpcID.NotFound", aws.StringValue(in.VpcId))
+	}
+
+	for _, sg := range f.SecurityGroups {
+		if *sg.VpcId == *in.VpcId && *sg.GroupName == aws.StringValue(in.GroupName) {
+			return awserr.New("InvalidGroup.Duplicate", "The security group '"+*sg.GroupName+"' already exists", nil)
+		}
+	}
+
+	sg := f.newSecurityGroup(in.VpcId, in.GroupName, in.Description)
+	out.GroupId = sg.GroupId
+
+	return nil
+}
+
+// DeleteSecurityGroup : deletes a security group that is not in use
+func (f *EC2) DeleteSecurityGroup(in *ec2.DeleteSecurityGroupInput, out *ec2.DeleteSecurityGroupOutput) error {
+	id := aws.StringValue(in.GroupId)
+
+	if _, ok := f.SecurityGroups[id]; !ok {
+		return notFound("InvalidGroup.NotFound", id)
+	}
+
+	for _, i := range f.Instances {
+		if *i.State.Code == instanceTerminated {
+			continue
+		}
+		for _, g := range i.SecurityGroups {
+			if *g.GroupId == id {
+				return dependencyViolation(id)
+			}
+		}
+	}
+
+	delete(f.SecurityGroups, id)
+
+	return nil
+}
+
+// DescribeSecurityGroups : lists security groups
+func (f *EC2) DescribeSecurityGroups(in *ec2.DescribeSecurityGroupsInput, out *ec2.DescribeSecurityGroupsOutput) error {
+	for _, sg := range f.SecurityGroups {
+		attrs := map[string][]string{
+			"group-id":   {*sg.GroupId},
+			"group-name": {*sg.GroupName},
+			"vpc-id":     {*sg.VpcId},
+		}
+
+		if selected(in.GroupIds, sg.GroupId) && matches(in.Filters, sg.Tags, attrs) {
+			out.SecurityGroups = append(out.SecurityGroups, sg)
+		}
+	}
+
+	return nil
+}
+
+// AuthorizeSecurityGroupIngress : adds ingress rules
+func (f *EC2) AuthorizeSecurityGroupIngress(in *ec2.AuthorizeSecurityGroupIngressInput, out *ec2.AuthorizeSecurityGroupIngressOutput) error {
+	sg, ok := f.SecurityGroups[aws.StringValue(in.GroupId)]
+	if !ok {
+		return notFound("InvalidGroup.NotFound", aws.StringValue(in.GroupId))
+	}
+
+	perms, err := authorize(sg.IpPermissions, in.IpPermissions)
+	if err != nil {
+		return err
+	}
+	sg.IpPermissions = perms
+
+	return nil
+}
+
+// AuthorizeSecurityGroupEgress : adds egress rules
+func (f *EC2) AuthorizeSecurityGroupEgress(in *ec2.AuthorizeSecurityGroupEgressInput, out *ec2.AuthorizeSecurityGroupEgressOutput) error {
+	sg, ok := f.SecurityGroups[aws.StringValue(in.GroupId)]
+	if !ok {
+		return notFound("InvalidGroup.NotFound", aws.StringValue(in.GroupId))
+	}
+
+	perms, err := authorize(sg.IpPermissionsEgress, in.IpPermissions)
+	if err != nil {
+		return err
+	}
+	sg.IpPermissionsEgress = perms
+
+	return nil
+}
+
+// RevokeSecurityGroupIngress : removes ingress rules
+func (f *EC2) RevokeSecurityGroupIngress(in *ec2.RevokeSecurityGroupIngressInput, out *ec2.RevokeSecurityGroupIngressOutput) error {
+	sg, ok := f.SecurityGroups[aws.StringValue(in.GroupId)]
+	if !ok {
+		return notFound("InvalidGroup.NotFound", aws.StringValue(in.GroupId))
+	}
+
+	perms, err := revoke(sg.IpPermissions, in.IpPermissions)
+	if err != nil {
+		return err
+	}
+	sg.IpPermissions = perms
+
+	return nil
+}
+
+// RevokeSecurityGroupEgress : removes egress rules
+func (f *EC2) RevokeSecurityGroupEgress(in *ec2.RevokeSecurityGroupEgressInput, out *ec2.RevokeSecurityGroupEgressOutput) error {
+	sg, ok := f.SecurityGroups[aws.StringValue(in.GroupId)]
+	if !ok {
+		return notFound("InvalidGroup.NotFound", aws.StringValue(in.GroupId))
+	}
+
+	perms, err := revoke(sg.IpPermissionsEgress, in.IpPermissions)
+	if err != nil {
+		return err
+	}
+	sg.IpPermissionsEgress = perms
+
+	return nil
+}
+
+// CreateNatGateway : creates an available nat gateway
+func (f *EC2) CreateNatGateway(in *ec2.CreateNatGatewayInput, out *ec2.CreateNatGatewayOutput) error {
+	s, ok := f.Subnets[aws.StringValue(in.SubnetId)]
+	if !ok {
+		return notFound("InvalidSubnetID.NotFound", aws.StringValue(in.SubnetId))
+	}
+
+	a, ok := f.Addresses[aws.StringValue(in.AllocationId)]
+	if !ok {
+		return notFound("InvalidAllocationID.NotFound", aws.StringValue(in.AllocationId))
+	}
+
+	ni := &ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String(f.b.id("eni")),
+		SubnetId:           s.SubnetId,
+		VpcId:              s.VpcId,
+		Description:        aws.String("Interface for NAT Gateway"),
+		Status:             aws.String(ec2.NetworkInterfaceStatusInUse),
+		RequesterManaged:   aws.Bool(true),
+	}
+	f.NetworkInterfaces[*ni.NetworkInterfaceId] = ni
+
+	a.AssociationId = aws.String(f.b.id("eipassoc"))
+	a.NetworkInterfaceId = ni.NetworkInterfaceId
+
+	ng := &ec2.NatGateway{
+		NatGatewayId: aws.String(f.b.id("nat")),
+		SubnetId:     s.SubnetId,
+		VpcId:        s.VpcId,
+		State:        aws.String(ec2.NatGatewayStateAvailable),
+		NatGatewayAddresses: []*ec2.NatGatewayAddress{
+			{
+				AllocationId:       a.AllocationId,
+				PublicIp:           a.PublicIp,
+				NetworkInterfaceId: ni.NetworkInterfaceId,
+			},
+		},
+	}
+
+	f.NatGateways[*ng.NatGatewayId] = ng
+	out.NatGateway = ng
+
+	return nil
+}
+
+// DeleteNatGateway : marks a nat gateway as deleted, releasing its interface
+func (f *EC2) DeleteNatGateway(in *ec2.DeleteNatGatewayInput, out *ec2.DeleteNatGatewayOutput) error {
+	id := aws.StringValue(in.NatGatewayId)
+
+	ng, ok := f.NatGateways[id]
+	if !ok || *ng.State == ec2.NatGatewayStateDeleted {
+		return notFound("NatGatewayNotFound", id)
+	}
+
+	ng.State = aws.String(ec2.NatGatewayStateDeleted)
+
+	for _, na := range ng.NatGatewayAddresses {
+		delete(f.NetworkInterfaces, aws.StringValue(na.NetworkInterfaceId))
+		if a, ok := f.Addresses[aws.StringValue(na.AllocationId)]; ok {
+			a.AssociationId = nil
+			a.NetworkInterfaceId = nil
+		}
+	}
+
+	out.NatGatewayId = ng.NatGatewayId
+
+	return nil
+}
+
+// DescribeNatGateways : lists nat gateways
+func (f *EC2) DescribeNatGateways(in *ec2.DescribeNatGatewaysInput, out *ec2.DescribeNatGatewaysOutput) error {
+	for _, id := range in.NatGatewayIds {
+		if _, ok := f.NatGateways[*id]; !ok {
+			return notFound("NatGatewayNotFound", *id)
+		}
+	}
+
+	for _, ng := range f.NatGateways {
+		attrs := map[string][]string{
+			"nat-gateway-id": {*ng.NatGatewayId},
+			"subnet-id":      {*ng.SubnetId},
+			"vpc-id":         {*ng.VpcId},
+			"state":          {*ng.State},
+		}
+
+		if selected(in.NatGatewayIds, ng.NatGatewayId) && matches(in.Filter, nil, attrs) {
+			out.NatGateways = append(out.NatGateways, ng)
+		}
+	}
+
+	return nil
+}
+
+func (f *EC2) newRouteTable(vpc *ec2.Vpc) *ec2.RouteTable {
+	rt := &ec2.RouteTable{
+		RouteTableId: aws.String(f.b.id("rtb")),
+		VpcId:        vpc.VpcId,
+		Routes: []*ec2.Route{
+			{
+				DestinationCidrBlock: vpc.CidrBlock,
+				GatewayId:            aws.String("local"),
+				Origin:               aws.String(ec2.RouteOriginCreateRouteTable),
+				State:                aws.String(ec2.RouteStateActive),
+			},
+		},
+	}
+
+	f.RouteTables[*rt.RouteTableId] = rt
+
+	return rt
+}
+
+func (f *EC2) newSecurityGroup(vpc, name, description *string) *ec2.SecurityGroup {
+	sg := &ec2.SecurityGroup{
+		GroupId:     aws.String(f.b.id("sg")),
+		GroupName:   name,
+		Description: description,
+		VpcId:       vpc,
+		OwnerId:     aws.String(account),
+		IpPermissionsEgress: []*ec2.IpPermission{
+			{
+				IpProtocol: aws.String("-1"),
+				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+			},
+		},
+	}
+
+	if v, ok := f.Vpcs[aws.StringValue(vpc)]; ok && len(v.Ipv6CidrBlockAssociationSet) > 0 {
+		sg.IpPermissionsEgress = append(sg.IpPermissionsEgress, &ec2.IpPermission{
+			IpProtocol: aws.String("-1"),
+			Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: aws.String("::/0")}},
+		})
+	}
+
+	f.SecurityGroups[*sg.GroupId] = sg
+
+	return sg
+}
+
+func (f *EC2) newVolume(az *string, size *int64) *ec2.Volume {
+	v := &ec2.Volume{
+		VolumeId:         aws.String(f.b.id("vol")),
+		AvailabilityZone: az,
+		Size:             size,
+		VolumeType:       aws.String(ec2.VolumeTypeGp2),
+		State:            aws.String(ec2.VolumeStateAvailable),
+		Encrypted:        aws.Bool(false),
+	}
+
+	f.Volumes[*v.VolumeId] = v
+
+	return v
+}
+
+func (f *EC2) attach(v *ec2.Volume, i *ec2.Instance, device *string) *ec2.VolumeAttachment {
+	a := &ec2.VolumeAttachment{
+		VolumeId:   v.VolumeId,
+		InstanceId: i.InstanceId,
+		Device:     device,
+		State:      aws.String(ec2.VolumeAttachmentStateAttached),
+	}
+
+	v.Attachments = []*ec2.VolumeAttachment{a}
+	v.State = aws.String(ec2.VolumeStateInUse)
+
+	i.BlockDeviceMappings = append(i.BlockDeviceMappings, &ec2.InstanceBlockDeviceMapping{
+		DeviceName: device,
+		Ebs: &ec2.EbsInstanceBlockDevice{
+			VolumeId: v.VolumeId,
+			Status:   aws.String(ec2.AttachmentStatusAttached),
+		},
+	})
+
+	return a
+}
+
+func (f *EC2) detach(v *ec2.Volume, i *ec2.Instance) {
+	v.Attachments = nil
+	v.State = aws.String(ec2.VolumeStateAvailable)
+
+	if i == nil {
+		return
+	}
+
+	for x, bdm := range i.BlockDeviceMappings {
+		if *bdm.Ebs.VolumeId == *v.VolumeId {
+			i.BlockDeviceMappings = append(i.BlockDeviceMappings[:x], i.BlockDeviceMappings[x+1:]...)
+			return
+		}
+	}
+}
+
+func (f *EC2) setInstanceStates(ids []*string, code int64) ([]*ec2.InstanceStateChange, error) {
+	var changes []*ec2.InstanceStateChange
+
+	for _, id := range ids {
+		i, ok := f.Instances[aws.StringValue(id)]
+		if !ok {
+			return nil, notFound("InvalidInstanceID.NotFound", aws.StringValue(id))
+		}
+
+		changes = append(changes, &ec2.InstanceStateChange{
+			InstanceId:    i.InstanceId,
+			PreviousState: i.State,
+			CurrentState:  instanceState(code),
+		})
+
+		i.State = instanceState(code)
+	}
+
+	return changes, nil
+}
+
+// tags : returns the tag list of any taggable ec2 resource
+func (f *EC2) tags(id string) *[]*ec2.Tag {
+	if r, ok := f.Vpcs[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.Subnets[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.InternetGateways[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.RouteTables[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.SecurityGroups[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.Instances[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.Volumes[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.NetworkInterfaces[id]; ok {
+		return &r.TagSet
+	}
+	if r, ok := f.NetworkAcls[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.VpcPeeringConnections[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.FlowLogs[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.DhcpOptions[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.VpcEndpoints[id]; ok {
+		return &r.Tags
+	}
+	if r, ok := f.EgressOnlyInternetGateways[id]; ok {
+		return &r.Tags
+	}
+	return nil
+}
+
+func instanceState(code int64) *ec2.InstanceState {
+	names := map[int64]string{
+		instanceRunning:    ec2.InstanceStateNameRunning,
+		instanceTerminated: ec2.InstanceStateNameTerminated,
+		instanceStopped:    ec2.InstanceStateNameStopped,
+	}
+
+	return &ec2.InstanceState{
+		Code: aws.Int64(code),
+		Name: aws.String(names[code]),
+	}
+}
+
+func isMainRouteTable(rt *ec2.RouteTable) bool {
+	for _, a := range rt.Associations {
+		if aws.BoolValue(a.Main) {
+			return true
+		}
+	}
+	return false
+}
+
+func setTag(tags []*ec2.Tag, key, value string) []*ec2.Tag {
+	for _, t := range tags {
+		if *t.Key == key {
+			t.Value = aws.String(value)
+			return tags
+		}
+	}
+
+	return append(tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+}
+
+// authorize : adds the permissions to the rule set, one ip range per rule
+func authorize(current, perms []*ec2.IpPermission) ([]*ec2.IpPermission, error) {
+	for _, p := range split(perms) {
+		if findPermission(current, p) >= 0 {
+			return nil, awserr.New("InvalidPermission.Duplicate", "The specified rule already exists", nil)
+		}
+		current = append(current, p)
+	}
+
+	return current, nil
+}
+
+// revoke : removes the permissions from the rule set
+func revoke(current, perms []*ec2.IpPermission) ([]*ec2.IpPermission, error) {
+	for _, p := range split(perms) {
+		i := findPermission(current, p)
+		if i < 0 {
+			return nil, awserr.New("InvalidPermission.NotFound", "The specified rule does not exist in this security group", nil)
+		}
+		current = append(current[:i], current[i+1:]...)
+	}
+
+	return current, nil
+}
+
+func split(perms []*ec2.IpPermission) []*ec2.IpPermission {
+	var split []*ec2.IpPermission
+
+	for _, p := range perms {
+		for _, r := range p.IpRanges {
+			split = append(split, &ec2.IpPermission{
+				IpProtocol: p.IpProtocol,
+				FromPort:   p.FromPort,
+				ToPort:     p.ToPort,
+				IpRanges:   []*ec2.IpRange{{CidrIp: r.CidrIp}},
+			})
+		}
+		for _, r := range p.Ipv6Ranges {
+			split = append(split, &ec2.IpPermission{
+				IpProtocol: p.IpProtocol,
+				FromPort:   p.FromPort,
+				ToPort:     p.ToPort,
+				Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: r.CidrIpv6}},
+			})
+		}
+	}
+
+	return split
+}
+
+func findPermission(perms []*ec2.IpPermission, p *ec2.IpPermission) int {
+	for i, c := range perms {
+		if aws.StringValue(c.IpProtocol) != aws.StringValue(p.IpProtocol) {
+			continue
+		}
+
+		if permissionCIDR(c) != permissionCIDR(p) {
+			continue
+		}
+
+		// ports are ignored for rules covering all protocols
+		if aws.StringValue(p.IpProtocol) != "-1" &&
+			(aws.Int64Value(c.FromPort) != aws.Int64Value(p.FromPort) || aws.Int64Value(c.ToPort) != aws.Int64Value(p.ToPort)) {
+			continue
+		}
+
+		return i
+	}
+
+	return -1
+}
+
+// permissionCIDR : returns the single ip range of a split permission
+func permissionCIDR(p *ec2.IpPermission) string {
+	if len(p.Ipv6Ranges) > 0 {
+		return aws.StringValue(p.Ipv6Ranges[0].CidrIpv6)
+	}
+
+	return aws.StringValue(p.IpRanges[0].CidrIp)
+}
+
+// selected : checks if the id is part of the requested ids, if any
+func selected(ids []*string, id *string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if aws.StringValue(i) == aws.StringValue(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches : checks a resource against ec2 filters, given its tags and
+// the values of the attributes that can be filtered on
+func matches(filters []*ec2.Filter, tags []*ec2.Tag, attrs map[string][]string) bool {
+	for _, f := range filters {
+		name := aws.StringValue(f.Name)
+		values := attrs[name]
+
+		if strings.HasPrefix(name, "tag:") {
+			values = nil
+			for _, t := range tags {
+				if *t.Key == strings.TrimPrefix(name, "tag:") {
+					values = append(values, aws.StringValue(t.Value))
+				}
+			}
+		}
+
+		if !intersects(values, aws.StringValueSlice(f.Values)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func dependencyViolation(id string) error {
+	return awserr.New("DependencyViolation", "The resource '"+id+"' has dependencies and cannot be deleted", nil)
+}
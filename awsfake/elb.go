@@ -0,0 +1,354 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsfake
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+)
+
+// ELB stores the state of the fake elb service
+type ELB struct {
+	b *Backend
+
+	LoadBalancers map[string]*elb.LoadBalancerDescription
+	Tags          map[string][]*elb.Tag
+}
+
+func newELB(b *Backend) *ELB {
+	return &ELB{
+		b:             b,
+		LoadBalancers: make(map[string]*elb.LoadBalancerDescription),
+		Tags:          make(map[string][]*elb.Tag),
+	}
+}
+
+// CreateLoadBalancer : creates a load balancer with an interface on
+// every subnet
+func (f *ELB) CreateLoadBalancer(in *elb.CreateLoadBalancerInput, out *elb.CreateLoadBalancerOutput) error {
+	name := aws.StringValue(in.LoadBalancerName)
+
+	if _, ok := f.LoadBalancers[name]; ok {
+		return awserr.New("DuplicateLoadBalancerName", "Load balancer '"+name+"' already exists", nil)
+	}
+
+	scheme := aws.String("internet-facing")
+	if in.Scheme != nil {
+		scheme = in.Scheme
+	}
+
+	lb := &elb.LoadBalancerDescription{
+		LoadBalancerName: in.LoadBalancerName,
+		DNSName:          aws.String(fmt.Sprintf("%s-%d.%s.elb.amazonaws.com", name, f.b.ids, f.b.Region)),
+		Scheme:           scheme,
+		SecurityGroups:   in.SecurityGroups,
+		CreatedTime:      aws.Time(time.Now()),
+	}
+
+	for _, l := range in.Listeners {
+		lb.ListenerDescriptions = append(lb.ListenerDescriptions, &elb.ListenerDescription{Listener: l})
+	}
+
+	f.LoadBalancers[name] = lb
+
+	if err := f.attach(lb, in.Subnets); err != nil {
+		delete(f.LoadBalancers, name)
+		return err
+	}
+
+	f.Tags[name] = in.Tags
+	out.DNSName = lb.DNSName
+
+	return nil
+}
+
+// DeleteLoadBalancer : deletes a load balancer and its interfaces
+func (f *ELB) DeleteLoadBalancer(in *elb.DeleteLoadBalancerInput, out *elb.DeleteLoadBalancerOutput) error {
+	name := aws.StringValue(in.LoadBalancerName)
+
+	lb, ok := f.LoadBalancers[name]
+	if !ok {
+		return nil
+	}
+
+	f.detach(lb, lb.Subnets)
+
+	delete(f.LoadBalancers, name)
+	delete(f.Tags, name)
+
+	return nil
+}
+
+// DescribeLoadBalancers : lists load balancers
+func (f *ELB) DescribeLoadBalancers(in *elb.DescribeLoadBalancersInput, out *elb.DescribeLoadBalancersOutput) error {
+	for _, name := range in.LoadBalancerNames {
+		if _, ok := f.LoadBalancers[aws.StringValue(name)]; !ok {
+			return notFound("LoadBalancerNotFound", aws.StringValue(name))
+		}
+	}
+
+	for _, lb := range f.LoadBalancers {
+		if selected(in.LoadBalancerNames, lb.LoadBalancerName) {
+			out.LoadBalancerDescriptions = append(out.LoadBalancerDescriptions, lb)
+		}
+	}
+
+	return nil
+}
+
+// RegisterInstancesWithLoadBalancer : adds instances to a load balancer
+func (f *ELB) RegisterInstancesWithLoadBalancer(in *elb.RegisterInstancesWithLoadBalancerInput, out *elb.RegisterInstancesWithLoadBalancerOutput) error {
+	lb, err := f.get(in.LoadBalancerName)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range in.Instances {
+		if _, ok := f.b.EC2.Instances[aws.StringValue(i.InstanceId)]; !ok {
+			return notFound("InvalidInstance", aws.StringValue(i.InstanceId))
+		}
+
+		if !hasInstance(lb.Instances, i.InstanceId) {
+			lb.Instances = append(lb.Instances, &elb.Instance{InstanceId: i.InstanceId})
+		}
+	}
+
+	out.Instances = lb.Instances
+
+	return nil
+}
+
+// DeregisterInstancesFromLoadBalancer : removes instances from a load balancer
+func (f *ELB) DeregisterInstancesFromLoadBalancer(in *elb.DeregisterInstancesFromLoadBalancerInput, out *elb.DeregisterInstancesFromLoadBalancerOutput) error {
+	lb, err := f.get(in.LoadBalancerName)
+	if err != nil {
+		return err
+	}
+
+	var instances []*elb.Instance
+	for _, i := range lb.Instances {
+		if !hasInstance(in.Instances, i.InstanceId) {
+			instances = append(instances, i)
+		}
+	}
+
+	lb.Instances = instances
+	out.Instances = instances
+
+	return nil
+}
+
+// CreateLoadBalancerListeners : adds listeners to a load balancer
+func (f *ELB) CreateLoadBalancerListeners(in *elb.CreateLoadBalancerListenersInput, out *elb.CreateLoadBalancerListenersOutput) error {
+	lb, err := f.get(in.LoadBalancerName)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range in.Listeners {
+		for _, ld := range lb.ListenerDescriptions {
+			if *ld.Listener.LoadBalancerPort == aws.Int64Value(l.LoadBalancerPort) {
+				return awserr.New("DuplicateListener", fmt.Sprintf("A listener already exists on port %d", *l.LoadBalancerPort), nil)
+			}
+		}
+
+		lb.ListenerDescriptions = append(lb.ListenerDescriptions, &elb.ListenerDescription{Listener: l})
+	}
+
+	return nil
+}
+
+// DeleteLoadBalancerListeners : removes the listeners on the given ports
+func (f *ELB) DeleteLoadBalancerListeners(in *elb.DeleteLoadBalancerListenersInput, out *elb.DeleteLoadBalancerListenersOutput) error {
+	lb, err := f.get(in.LoadBalancerName)
+	if err != nil {
+		return err
+	}
+
+	var listeners []*elb.ListenerDescription
+	for _, ld := range lb.ListenerDescriptions {
+		var found bool
+		for _, port := range in.LoadBalancerPorts {
+			if *ld.Listener.LoadBalancerPort == aws.Int64Value(port) {
+				found = true
+			}
+		}
+		if !found {
+			listeners = append(listeners, ld)
+		}
+	}
+
+	lb.ListenerDescriptions = listeners
+
+	return nil
+}
+
+// AttachLoadBalancerToSubnets : adds subnets to a load balancer
+func (f *ELB) AttachLoadBalancerToSubnets(in *elb.AttachLoadBalancerToSubnetsInput, out *elb.AttachLoadBalancerToSubnetsOutput) error {
+	lb, err := f.get(in.LoadBalancerName)
+	if err != nil {
+		return err
+	}
+
+	if err := f.attach(lb, in.Subnets); err != nil {
+		return err
+	}
+
+	out.Subnets = lb.Subnets
+
+	return nil
+}
+
+// DetachLoadBalancerFromSubnets : removes subnets from a load balancer
+func (f *ELB) DetachLoadBalancerFromSubnets(in *elb.DetachLoadBalancerFromSubnetsInput, out *elb.DetachLoadBalancerFromSubnetsOutput) error {
+	lb, err := f.get(in.LoadBalancerName)
+	if err != nil {
+		return err
+	}
+
+	f.detach(lb, in.Subnets)
+	out.Subnets = lb.Subnets
+
+	return nil
+}
+
+// ApplySecurityGroupsToLoadBalancer : replaces the security groups of a
+// load balancer
+func (f *ELB) ApplySecurityGroupsToLoadBalancer(in *elb.ApplySecurityGroupsToLoadBalancerInput, out *elb.ApplySecurityGroupsToLoadBalancerOutput) error {
+	lb, err := f.get(in.LoadBalancerName)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range in.SecurityGroups {
+		if _, ok := f.b.EC2.SecurityGroups[aws.StringValue(id)]; !ok {
+			return notFound("InvalidSecurityGroup", aws.StringValue(id))
+		}
+	}
+
+	lb.SecurityGroups = in.SecurityGroups
+	out.SecurityGroups = in.SecurityGroups
+
+	return nil
+}
+
+// AddTags : adds or overwrites tags on load balancers
+func (f *ELB) AddTags(in *elb.AddTagsInput, out *elb.AddTagsOutput) error {
+	for _, name := range in.LoadBalancerNames {
+		if _, err := f.get(name); err != nil {
+			return err
+		}
+
+		for _, t := range in.Tags {
+			f.Tags[*name] = setELBTag(f.Tags[*name], aws.StringValue(t.Key), aws.StringValue(t.Value))
+		}
+	}
+
+	return nil
+}
+
+// DescribeTags : lists the tags of load balancers
+func (f *ELB) DescribeTags(in *elb.DescribeTagsInput, out *elb.DescribeTagsOutput) error {
+	for _, name := range in.LoadBalancerNames {
+		if _, err := f.get(name); err != nil {
+			return err
+		}
+
+		out.TagDescriptions = append(out.TagDescriptions, &elb.TagDescription{
+			LoadBalancerName: name,
+			Tags:             f.Tags[*name],
+		})
+	}
+
+	return nil
+}
+
+func (f *ELB) get(name *string) (*elb.LoadBalancerDescription, error) {
+	lb, ok := f.LoadBalancers[aws.StringValue(name)]
+	if !ok {
+		return nil, notFound("LoadBalancerNotFound", aws.StringValue(name))
+	}
+
+	return lb, nil
+}
+
+// attach : adds the subnets to the load balancer, creating an interface
+// on each of them
+func (f *ELB) attach(lb *elb.LoadBalancerDescription, subnets []*string) error {
+	for _, id := range subnets {
+		s, ok := f.b.EC2.Subnets[aws.StringValue(id)]
+		if !ok {
+			return notFound("SubnetNotFound", aws.StringValue(id))
+		}
+
+		if selected(lb.Subnets, id) && len(lb.Subnets) > 0 {
+			continue
+		}
+
+		ni := &ec2.NetworkInterface{
+			NetworkInterfaceId: aws.String(f.b.id("eni")),
+			SubnetId:           s.SubnetId,
+			VpcId:              s.VpcId,
+			Description:        aws.String("ELB " + *lb.LoadBalancerName),
+			Status:             aws.String(ec2.NetworkInterfaceStatusInUse),
+		}
+		f.b.EC2.NetworkInterfaces[*ni.NetworkInterfaceId] = ni
+
+		lb.Subnets = append(lb.Subnets, s.SubnetId)
+		lb.AvailabilityZones = append(lb.AvailabilityZones, s.AvailabilityZone)
+		lb.VPCId = s.VpcId
+	}
+
+	return nil
+}
+
+// detach : removes the subnets from the load balancer with their interfaces
+func (f *ELB) detach(lb *elb.LoadBalancerDescription, subnets []*string) {
+	if len(subnets) == 0 {
+		return
+	}
+
+	desc := "ELB " + *lb.LoadBalancerName
+
+	for id, ni := range f.b.EC2.NetworkInterfaces {
+		if aws.StringValue(ni.Description) == desc && selected(subnets, ni.SubnetId) {
+			delete(f.b.EC2.NetworkInterfaces, id)
+		}
+	}
+
+	var remaining []*string
+	for _, s := range lb.Subnets {
+		if !selected(subnets, s) {
+			remaining = append(remaining, s)
+		}
+	}
+
+	lb.Subnets = remaining
+}
+
+func hasInstance(instances []*elb.Instance, id *string) bool {
+	for _, i := range instances {
+		if aws.StringValue(i.InstanceId) == aws.StringValue(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func setELBTag(tags []*elb.Tag, key, value string) []*elb.Tag {
+	for _, t := range tags {
+		if *t.Key == key {
+			t.Value = aws.String(value)
+			return tags
+		}
+	}
+
+	return append(tags, &elb.Tag{Key: aws.String(key), Value: aws.String(value)})
+}
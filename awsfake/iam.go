@@ -0,0 +1,416 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsfake
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+// IAM stores the state of the fake iam service
+type IAM struct {
+	b *Backend
+
+	Policies         map[string]*iam.Policy
+	PolicyDocuments  map[string]string
+	Roles            map[string]*iam.Role
+	RolePolicies     map[string][]string
+	InstanceProfiles map[string]*iam.InstanceProfile
+}
+
+func newIAM(b *Backend) *IAM {
+	return &IAM{
+		b:                b,
+		Policies:         make(map[string]*iam.Policy),
+		PolicyDocuments:  make(map[string]string),
+		Roles:            make(map[string]*iam.Role),
+		RolePolicies:     make(map[string][]string),
+		InstanceProfiles: make(map[string]*iam.InstanceProfile),
+	}
+}
+
+// CreatePolicy : creates a customer managed policy
+func (f *IAM) CreatePolicy(in *iam.CreatePolicyInput, out *iam.CreatePolicyOutput) error {
+	path := iamPath(in.Path)
+	arn := f.b.arn("iam", "policy"+path+aws.StringValue(in.PolicyName))
+
+	if _, ok := f.Policies[arn]; ok {
+		return entityExists("policy", aws.StringValue(in.PolicyName))
+	}
+
+	p := &iam.Policy{
+		PolicyName:       in.PolicyName,
+		PolicyId:         aws.String(iamID("ANPA", f.b.id("policy"))),
+		Arn:              aws.String(arn),
+		Path:             aws.String(path),
+		Description:      in.Description,
+		DefaultVersionId: aws.String("v1"),
+		AttachmentCount:  aws.Int64(0),
+		CreateDate:       aws.Time(time.Now()),
+	}
+
+	f.Policies[arn] = p
+	f.PolicyDocuments[arn] = aws.StringValue(in.PolicyDocument)
+	out.Policy = p
+
+	return nil
+}
+
+// DeletePolicy : deletes a policy that is not attached to any role
+func (f *IAM) DeletePolicy(in *iam.DeletePolicyInput, out *iam.DeletePolicyOutput) error {
+	arn := aws.StringValue(in.PolicyArn)
+
+	p, ok := f.Policies[arn]
+	if !ok {
+		return noSuchEntity("policy", arn)
+	}
+
+	if aws.Int64Value(p.AttachmentCount) > 0 {
+		return deleteConflict("policy", arn)
+	}
+
+	delete(f.Policies, arn)
+	delete(f.PolicyDocuments, arn)
+
+	return nil
+}
+
+// ListPolicies : lists policies, only customer managed policies are stored
+func (f *IAM) ListPolicies(in *iam.ListPoliciesInput, out *iam.ListPoliciesOutput) error {
+	for _, p := range f.Policies {
+		out.Policies = append(out.Policies, p)
+	}
+
+	out.IsTruncated = aws.Bool(false)
+
+	return nil
+}
+
+// ListPolicyTags : returns the tags of a policy
+func (f *IAM) ListPolicyTags(in *iam.ListPolicyTagsInput, out *iam.ListPolicyTagsOutput) error {
+	p, ok := f.Policies[aws.StringValue(in.PolicyArn)]
+	if !ok {
+		return noSuchEntity("policy", aws.StringValue(in.PolicyArn))
+	}
+
+	out.Tags = p.Tags
+	out.IsTruncated = aws.Bool(false)
+
+	return nil
+}
+
+// GetPolicyVersion : returns the url encoded document of a policy
+func (f *IAM) GetPolicyVersion(in *iam.GetPolicyVersionInput, out *iam.GetPolicyVersionOutput) error {
+	arn := aws.StringValue(in.PolicyArn)
+
+	p, ok := f.Policies[arn]
+	if !ok {
+		return noSuchEntity("policy", arn)
+	}
+
+	if aws.StringValue(in.VersionId) != *p.DefaultVersionId {
+		return noSuchEntity("policy version", aws.StringValue(in.VersionId))
+	}
+
+	out.PolicyVersion = &iam.PolicyVersion{
+		VersionId:        p.DefaultVersionId,
+		IsDefaultVersion: aws.Bool(true),
+		Document:         aws.String(url.QueryEscape(f.PolicyDocuments[arn])),
+		CreateDate:       p.CreateDate,
+	}
+
+	return nil
+}
+
+// CreateRole : creates a role
+func (f *IAM) CreateRole(in *iam.CreateRoleInput, out *iam.CreateRoleOutput) error {
+	name := aws.StringValue(in.RoleName)
+
+	if _, ok := f.Roles[name]; ok {
+		return entityExists("role", name)
+	}
+
+	path := iamPath(in.Path)
+
+	r := &iam.Role{
+		RoleName:                 in.RoleName,
+		RoleId:                   aws.String(iamID("AROA", f.b.id("role"))),
+		Arn:                      aws.String(f.b.arn("iam", "role"+path+name)),
+		Path:                     aws.String(path),
+		Description:              in.Description,
+		AssumeRolePolicyDocument: aws.String(url.QueryEscape(aws.StringValue(in.AssumeRolePolicyDocument))),
+		CreateDate:               aws.Time(time.Now()),
+	}
+
+	f.Roles[name] = r
+	out.Role = r
+
+	return nil
+}
+
+// DeleteRole : deletes a role without attached policies or instance profiles
+func (f *IAM) DeleteRole(in *iam.DeleteRoleInput, out *iam.DeleteRoleOutput) error {
+	name := aws.StringValue(in.RoleName)
+
+	if _, ok := f.Roles[name]; !ok {
+		return noSuchEntity("role", name)
+	}
+
+	if len(f.RolePolicies[name]) > 0 {
+		return deleteConflict("role", name)
+	}
+
+	for _, p := range f.InstanceProfiles {
+		for _, r := range p.Roles {
+			if *r.RoleName == name {
+				return deleteConflict("role", name)
+			}
+		}
+	}
+
+	delete(f.Roles, name)
+	delete(f.RolePolicies, name)
+
+	return nil
+}
+
+// ListRoles : lists roles
+func (f *IAM) ListRoles(in *iam.ListRolesInput, out *iam.ListRolesOutput) error {
+	for _, r := range f.Roles {
+		out.Roles = append(out.Roles, r)
+	}
+
+	out.IsTruncated = aws.Bool(false)
+
+	return nil
+}
+
+// ListRoleTags : returns the tags of a role
+func (f *IAM) ListRoleTags(in *iam.ListRoleTagsInput, out *iam.ListRoleTagsOutput) error {
+	r, ok := f.Roles[aws.StringValue(in.RoleName)]
+	if !ok {
+		return noSuchEntity("role", aws.StringValue(in.RoleName))
+	}
+
+	out.Tags = r.Tags
+	out.IsTruncated = aws.Bool(false)
+
+	return nil
+}
+
+// AttachRolePolicy : attaches a managed policy to a role
+func (f *IAM) AttachRolePolicy(in *iam.AttachRolePolicyInput, out *iam.AttachRolePolicyOutput) error {
+	name := aws.StringValue(in.RoleName)
+	arn := aws.StringValue(in.PolicyArn)
+
+	if _, ok := f.Roles[name]; !ok {
+		return noSuchEntity("role", name)
+	}
+
+	p, ok := f.Policies[arn]
+	if !ok {
+		return noSuchEntity("policy", arn)
+	}
+
+	for _, a := range f.RolePolicies[name] {
+		if a == arn {
+			return nil
+		}
+	}
+
+	f.RolePolicies[name] = append(f.RolePolicies[name], arn)
+	p.AttachmentCount = aws.Int64(aws.Int64Value(p.AttachmentCount) + 1)
+
+	return nil
+}
+
+// DetachRolePolicy : detaches a managed policy from a role
+func (f *IAM) DetachRolePolicy(in *iam.DetachRolePolicyInput, out *iam.DetachRolePolicyOutput) error {
+	name := aws.StringValue(in.RoleName)
+	arn := aws.StringValue(in.PolicyArn)
+
+	if _, ok := f.Roles[name]; !ok {
+		return noSuchEntity("role", name)
+	}
+
+	for i, a := range f.RolePolicies[name] {
+		if a == arn {
+			f.RolePolicies[name] = append(f.RolePolicies[name][:i], f.RolePolicies[name][i+1:]...)
+			if p, ok := f.Policies[arn]; ok {
+				p.AttachmentCount = aws.Int64(aws.Int64Value(p.AttachmentCount) - 1)
+			}
+			return nil
+		}
+	}
+
+	return noSuchEntity("policy", arn)
+}
+
+// ListAttachedRolePolicies : lists the managed policies attached to a role
+func (f *IAM) ListAttachedRolePolicies(in *iam.ListAttachedRolePoliciesInput, out *iam.ListAttachedRolePoliciesOutput) error {
+	name := aws.StringValue(in.RoleName)
+
+	if _, ok := f.Roles[name]; !ok {
+		return noSuchEntity("role", name)
+	}
+
+	for _, arn := range f.RolePolicies[name] {
+		out.AttachedPolicies = append(out.AttachedPolicies, &iam.AttachedPolicy{
+			PolicyArn:  aws.String(arn),
+			PolicyName: aws.String(arn[strings.LastIndex(arn, "/")+1:]),
+		})
+	}
+
+	out.IsTruncated = aws.Bool(false)
+
+	return nil
+}
+
+// CreateInstanceProfile : creates an instance profile without roles
+func (f *IAM) CreateInstanceProfile(in *iam.CreateInstanceProfileInput, out *iam.CreateInstanceProfileOutput) error {
+	name := aws.StringValue(in.InstanceProfileName)
+
+	if _, ok := f.InstanceProfiles[name]; ok {
+		return entityExists("instance profile", name)
+	}
+
+	path := iamPath(in.Path)
+
+	p := &iam.InstanceProfile{
+		InstanceProfileName: in.InstanceProfileName,
+		InstanceProfileId:   aws.String(iamID("AIPA", f.b.id("profile"))),
+		Arn:                 aws.String(f.b.arn("iam", "instance-profile"+path+name)),
+		Path:                aws.String(path),
+		Roles:               []*iam.Role{},
+		CreateDate:          aws.Time(time.Now()),
+	}
+
+	f.InstanceProfiles[name] = p
+	out.InstanceProfile = p
+
+	return nil
+}
+
+// GetInstanceProfile : returns an instance profile
+func (f *IAM) GetInstanceProfile(in *iam.GetInstanceProfileInput, out *iam.GetInstanceProfileOutput) error {
+	p, ok := f.InstanceProfiles[aws.StringValue(in.InstanceProfileName)]
+	if !ok {
+		return noSuchEntity("instance profile", aws.StringValue(in.InstanceProfileName))
+	}
+
+	out.InstanceProfile = p
+
+	return nil
+}
+
+// DeleteInstanceProfile : deletes an instance profile without roles
+func (f *IAM) DeleteInstanceProfile(in *iam.DeleteInstanceProfileInput, out *iam.DeleteInstanceProfileOutput) error {
+	name := aws.StringValue(in.InstanceProfileName)
+
+	p, ok := f.InstanceProfiles[name]
+	if !ok {
+		return noSuchEntity("instance profile", name)
+	}
+
+	if len(p.Roles) > 0 {
+		return deleteConflict("instance profile", name)
+	}
+
+	delete(f.InstanceProfiles, name)
+
+	return nil
+}
+
+// ListInstanceProfiles : lists instance profiles
+func (f *IAM) ListInstanceProfiles(in *iam.ListInstanceProfilesInput, out *iam.ListInstanceProfilesOutput) error {
+	for _, p := range f.InstanceProfiles {
+		out.InstanceProfiles = append(out.InstanceProfiles, p)
+	}
+
+	out.IsTruncated = aws.Bool(false)
+
+	return nil
+}
+
+// ListInstanceProfileTags : returns the tags of an instance profile
+func (f *IAM) ListInstanceProfileTags(in *iam.ListInstanceProfileTagsInput, out *iam.ListInstanceProfileTagsOutput) error {
+	p, ok := f.InstanceProfiles[aws.StringValue(in.InstanceProfileName)]
+	if !ok {
+		return noSuchEntity("instance profile", aws.StringValue(in.InstanceProfileName))
+	}
+
+	out.Tags = p.Tags
+	out.IsTruncated = aws.Bool(false)
+
+	return nil
+}
+
+// AddRoleToInstanceProfile : adds a role to an instance profile, which
+// can only hold one role
+func (f *IAM) AddRoleToInstanceProfile(in *iam.AddRoleToInstanceProfileInput, out *iam.AddRoleToInstanceProfileOutput) error {
+	p, ok := f.InstanceProfiles[aws.StringValue(in.InstanceProfileName)]
+	if !ok {
+		return noSuchEntity("instance profile", aws.StringValue(in.InstanceProfileName))
+	}
+
+	r, ok := f.Roles[aws.StringValue(in.RoleName)]
+	if !ok {
+		return noSuchEntity("role", aws.StringValue(in.RoleName))
+	}
+
+	if len(p.Roles) > 0 {
+		return awserr.New("LimitExceeded", "Cannot exceed quota for InstanceSessionsPerInstanceProfile: 1", nil)
+	}
+
+	p.Roles = append(p.Roles, r)
+
+	return nil
+}
+
+// RemoveRoleFromInstanceProfile : removes a role from an instance profile
+func (f *IAM) RemoveRoleFromInstanceProfile(in *iam.RemoveRoleFromInstanceProfileInput, out *iam.RemoveRoleFromInstanceProfileOutput) error {
+	p, ok := f.InstanceProfiles[aws.StringValue(in.InstanceProfileName)]
+	if !ok {
+		return noSuchEntity("instance profile", aws.StringValue(in.InstanceProfileName))
+	}
+
+	for i, r := range p.Roles {
+		if *r.RoleName == aws.StringValue(in.RoleName) {
+			p.Roles = append(p.Roles[:i], p.Roles[i+1:]...)
+			return nil
+		}
+	}
+
+	return noSuchEntity("role", aws.StringValue(in.RoleName))
+}
+
+func iamPath(path *string) string {
+	if path == nil || *path == "" {
+		return "/"
+	}
+	return *path
+}
+
+// iamID : builds an aws like unique id with the prefix of the entity type
+func iamID(prefix, id string) string {
+	return prefix + strings.ToUpper(strings.Replace(id, "-", "", -1))
+}
+
+func noSuchEntity(kind, name string) error {
+	return awserr.New("NoSuchEntity", "The "+kind+" with name "+name+" cannot be found", nil)
+}
+
+func entityExists(kind, name string) error {
+	return awserr.New("EntityAlreadyExists", "The "+kind+" with name "+name+" already exists", nil)
+}
+
+func deleteConflict(kind, name string) error {
+	return awserr.New("DeleteConflict", "Cannot delete the "+kind+" "+name+" while it is in use", nil)
+}
@@ -0,0 +1,545 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsfake
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// RDS stores the state of the fake rds service
+type RDS struct {
+	b *Backend
+
+	DBInstances    map[string]*rds.DBInstance
+	DBClusters     map[string]*rds.DBCluster
+	DBSubnetGroups map[string]*rds.DBSubnetGroup
+	Tags           map[string][]*rds.Tag
+}
+
+func newRDS(b *Backend) *RDS {
+	return &RDS{
+		b:              b,
+		DBInstances:    make(map[string]*rds.DBInstance),
+		DBClusters:     make(map[string]*rds.DBCluster),
+		DBSubnetGroups: make(map[string]*rds.DBSubnetGroup),
+		Tags:           make(map[string][]*rds.Tag),
+	}
+}
+
+// CreateDBInstance : creates an available database instance
+func (f *RDS) CreateDBInstance(in *rds.CreateDBInstanceInput, out *rds.CreateDBInstanceOutput) error {
+	name := aws.StringValue(in.DBInstanceIdentifier)
+
+	if _, ok := f.DBInstances[name]; ok {
+		return awserr.New("DBInstanceAlreadyExists", "DB Instance "+name+" already exists", nil)
+	}
+
+	if in.DBClusterIdentifier != nil {
+		if _, ok := f.DBClusters[*in.DBClusterIdentifier]; !ok {
+			return notFound("DBClusterNotFoundFault", *in.DBClusterIdentifier)
+		}
+	}
+
+	sg, err := f.subnetGroup(in.DBSubnetGroupName)
+	if err != nil {
+		return err
+	}
+
+	i := &rds.DBInstance{
+		DBInstanceIdentifier:       in.DBInstanceIdentifier,
+		DBInstanceArn:              aws.String(f.b.arn("rds", "db:"+name)),
+		DBInstanceClass:            in.DBInstanceClass,
+		DBInstanceStatus:           aws.String("available"),
+		Engine:                     in.Engine,
+		EngineVersion:              in.EngineVersion,
+		DBClusterIdentifier:        in.DBClusterIdentifier,
+		AllocatedStorage:           in.AllocatedStorage,
+		StorageType:                in.StorageType,
+		Iops:                       in.Iops,
+		MultiAZ:                    in.MultiAZ,
+		PromotionTier:              in.PromotionTier,
+		AvailabilityZone:           in.AvailabilityZone,
+		AutoMinorVersionUpgrade:    in.AutoMinorVersionUpgrade,
+		BackupRetentionPeriod:      in.BackupRetentionPeriod,
+		PreferredBackupWindow:      in.PreferredBackupWindow,
+		PreferredMaintenanceWindow: in.PreferredMaintenanceWindow,
+		VpcSecurityGroups:          securityGroupMemberships(in.VpcSecurityGroupIds),
+		DBName:                     in.DBName,
+		MasterUsername:             in.MasterUsername,
+		DBSubnetGroup:              sg,
+		LicenseModel:               in.LicenseModel,
+		PubliclyAccessible:         in.PubliclyAccessible,
+		Timezone:                   in.Timezone,
+		Endpoint:                   f.endpoint(name, in.Port),
+	}
+
+	f.DBInstances[name] = i
+	f.Tags[*i.DBInstanceArn] = in.Tags
+	out.DBInstance = i
+
+	return nil
+}
+
+// CreateDBInstanceReadReplica : creates an available replica of an instance
+func (f *RDS) CreateDBInstanceReadReplica(in *rds.CreateDBInstanceReadReplicaInput, out *rds.CreateDBInstanceReadReplicaOutput) error {
+	name := aws.StringValue(in.DBInstanceIdentifier)
+
+	source, ok := f.DBInstances[aws.StringValue(in.SourceDBInstanceIdentifier)]
+	if !ok {
+		return notFound("DBInstanceNotFound", aws.StringValue(in.SourceDBInstanceIdentifier))
+	}
+
+	if _, ok := f.DBInstances[name]; ok {
+		return awserr.New("DBInstanceAlreadyExists", "DB Instance "+name+" already exists", nil)
+	}
+
+	sg, err := f.subnetGroup(in.DBSubnetGroupName)
+	if err != nil {
+		return err
+	}
+
+	class := in.DBInstanceClass
+	if class == nil {
+		class = source.DBInstanceClass
+	}
+
+	i := &rds.DBInstance{
+		DBInstanceIdentifier:                  in.DBInstanceIdentifier,
+		DBInstanceArn:                         aws.String(f.b.arn("rds", "db:"+name)),
+		DBInstanceClass:                       class,
+		DBInstanceStatus:                      aws.String("available"),
+		Engine:                                source.Engine,
+		EngineVersion:                         source.EngineVersion,
+		AllocatedStorage:                      source.AllocatedStorage,
+		StorageType:                           in.StorageType,
+		Iops:                                  in.Iops,
+		AvailabilityZone:                      in.AvailabilityZone,
+		AutoMinorVersionUpgrade:               in.AutoMinorVersionUpgrade,
+		VpcSecurityGroups:                     source.VpcSecurityGroups,
+		DBName:                                source.DBName,
+		MasterUsername:                        source.MasterUsername,
+		DBSubnetGroup:                         sg,
+		LicenseModel:                          source.LicenseModel,
+		PubliclyAccessible:                    in.PubliclyAccessible,
+		ReadReplicaSourceDBInstanceIdentifier: source.DBInstanceIdentifier,
+		Endpoint:                              f.endpoint(name, in.Port),
+	}
+
+	source.ReadReplicaDBInstanceIdentifiers = append(source.ReadReplicaDBInstanceIdentifiers, i.DBInstanceIdentifier)
+
+	f.DBInstances[name] = i
+	f.Tags[*i.DBInstanceArn] = in.Tags
+	out.DBInstance = i
+
+	return nil
+}
+
+// ModifyDBInstance : updates a database instance, changes are always
+// applied immediately
+func (f *RDS) ModifyDBInstance(in *rds.ModifyDBInstanceInput, out *rds.ModifyDBInstanceOutput) error {
+	i, ok := f.DBInstances[aws.StringValue(in.DBInstanceIdentifier)]
+	if !ok {
+		return notFound("DBInstanceNotFound", aws.StringValue(in.DBInstanceIdentifier))
+	}
+
+	set(&i.DBInstanceClass, in.DBInstanceClass)
+	set(&i.EngineVersion, in.EngineVersion)
+	set(&i.StorageType, in.StorageType)
+	set(&i.PreferredBackupWindow, in.PreferredBackupWindow)
+	set(&i.PreferredMaintenanceWindow, in.PreferredMaintenanceWindow)
+	set(&i.LicenseModel, in.LicenseModel)
+	setInt(&i.AllocatedStorage, in.AllocatedStorage)
+	setInt(&i.Iops, in.Iops)
+	setInt(&i.PromotionTier, in.PromotionTier)
+	setInt(&i.BackupRetentionPeriod, in.BackupRetentionPeriod)
+	setBool(&i.MultiAZ, in.MultiAZ)
+	setBool(&i.AutoMinorVersionUpgrade, in.AutoMinorVersionUpgrade)
+	setBool(&i.PubliclyAccessible, in.PubliclyAccessible)
+
+	if in.DBPortNumber != nil {
+		i.Endpoint.Port = in.DBPortNumber
+	}
+
+	if in.VpcSecurityGroupIds != nil {
+		i.VpcSecurityGroups = securityGroupMemberships(in.VpcSecurityGroupIds)
+	}
+
+	out.DBInstance = i
+
+	return nil
+}
+
+// DeleteDBInstance : deletes a database instance straight away
+func (f *RDS) DeleteDBInstance(in *rds.DeleteDBInstanceInput, out *rds.DeleteDBInstanceOutput) error {
+	name := aws.StringValue(in.DBInstanceIdentifier)
+
+	i, ok := f.DBInstances[name]
+	if !ok {
+		return notFound("DBInstanceNotFound", name)
+	}
+
+	if !aws.BoolValue(in.SkipFinalSnapshot) && in.FinalDBSnapshotIdentifier == nil && i.DBClusterIdentifier == nil {
+		return awserr.New("InvalidParameterCombination", "FinalDBSnapshotIdentifier is required unless SkipFinalSnapshot is specified", nil)
+	}
+
+	i.DBInstanceStatus = aws.String("deleting")
+
+	delete(f.DBInstances, name)
+	delete(f.Tags, *i.DBInstanceArn)
+
+	out.DBInstance = i
+
+	return nil
+}
+
+// DescribeDBInstances : lists database instances
+func (f *RDS) DescribeDBInstances(in *rds.DescribeDBInstancesInput, out *rds.DescribeDBInstancesOutput) error {
+	if in.DBInstanceIdentifier != nil {
+		i, ok := f.DBInstances[*in.DBInstanceIdentifier]
+		if !ok {
+			return notFound("DBInstanceNotFound", *in.DBInstanceIdentifier)
+		}
+		out.DBInstances = []*rds.DBInstance{i}
+		return nil
+	}
+
+	for _, i := range f.DBInstances {
+		out.DBInstances = append(out.DBInstances, i)
+	}
+
+	return nil
+}
+
+// CreateDBCluster : creates an available database cluster
+func (f *RDS) CreateDBCluster(in *rds.CreateDBClusterInput, out *rds.CreateDBClusterOutput) error {
+	name := aws.StringValue(in.DBClusterIdentifier)
+
+	if _, ok := f.DBClusters[name]; ok {
+		return awserr.New("DBClusterAlreadyExistsFault", "DB Cluster "+name+" already exists", nil)
+	}
+
+	if _, err := f.subnetGroup(in.DBSubnetGroupName); err != nil {
+		return err
+	}
+
+	c := &rds.DBCluster{
+		DBClusterIdentifier:         in.DBClusterIdentifier,
+		DBClusterArn:                aws.String(f.b.arn("rds", "cluster:"+name)),
+		Status:                      aws.String("available"),
+		Engine:                      in.Engine,
+		EngineVersion:               in.EngineVersion,
+		Port:                        in.Port,
+		Endpoint:                    f.endpoint(name+".cluster", nil).Address,
+		AvailabilityZones:           in.AvailabilityZones,
+		DatabaseName:                in.DatabaseName,
+		MasterUsername:              in.MasterUsername,
+		DBSubnetGroup:               in.DBSubnetGroupName,
+		BackupRetentionPeriod:       in.BackupRetentionPeriod,
+		PreferredBackupWindow:       in.PreferredBackupWindow,
+		PreferredMaintenanceWindow:  in.PreferredMaintenanceWindow,
+		ReplicationSourceIdentifier: in.ReplicationSourceIdentifier,
+		VpcSecurityGroups:           securityGroupMemberships(in.VpcSecurityGroupIds),
+	}
+
+	f.DBClusters[name] = c
+	f.Tags[*c.DBClusterArn] = in.Tags
+	out.DBCluster = c
+
+	return nil
+}
+
+// ModifyDBCluster : updates a database cluster, changes are always
+// applied immediately
+func (f *RDS) ModifyDBCluster(in *rds.ModifyDBClusterInput, out *rds.ModifyDBClusterOutput) error {
+	c, ok := f.DBClusters[aws.StringValue(in.DBClusterIdentifier)]
+	if !ok {
+		return notFound("DBClusterNotFoundFault", aws.StringValue(in.DBClusterIdentifier))
+	}
+
+	set(&c.PreferredBackupWindow, in.PreferredBackupWindow)
+	set(&c.PreferredMaintenanceWindow, in.PreferredMaintenanceWindow)
+	setInt(&c.Port, in.Port)
+	setInt(&c.BackupRetentionPeriod, in.BackupRetentionPeriod)
+
+	if in.VpcSecurityGroupIds != nil {
+		c.VpcSecurityGroups = securityGroupMemberships(in.VpcSecurityGroupIds)
+	}
+
+	out.DBCluster = c
+
+	return nil
+}
+
+// DeleteDBCluster : deletes a database cluster without instances
+func (f *RDS) DeleteDBCluster(in *rds.DeleteDBClusterInput, out *rds.DeleteDBClusterOutput) error {
+	name := aws.StringValue(in.DBClusterIdentifier)
+
+	c, ok := f.DBClusters[name]
+	if !ok {
+		return notFound("DBClusterNotFoundFault", name)
+	}
+
+	for _, i := range f.DBInstances {
+		if aws.StringValue(i.DBClusterIdentifier) == name {
+			return awserr.New("InvalidDBClusterStateFault", "Cluster cannot be deleted, it still contains DB instances", nil)
+		}
+	}
+
+	if !aws.BoolValue(in.SkipFinalSnapshot) && in.FinalDBSnapshotIdentifier == nil {
+		return awserr.New("InvalidParameterCombination", "FinalDBSnapshotIdentifier is required unless SkipFinalSnapshot is specified", nil)
+	}
+
+	c.Status = aws.String("deleting")
+
+	delete(f.DBClusters, name)
+	delete(f.Tags, *c.DBClusterArn)
+
+	out.DBCluster = c
+
+	return nil
+}
+
+// DescribeDBClusters : lists database clusters
+func (f *RDS) DescribeDBClusters(in *rds.DescribeDBClustersInput, out *rds.DescribeDBClustersOutput) error {
+	if in.DBClusterIdentifier != nil {
+		c, ok := f.DBClusters[*in.DBClusterIdentifier]
+		if !ok {
+			return notFound("DBClusterNotFoundFault", *in.DBClusterIdentifier)
+		}
+		out.DBClusters = []*rds.DBCluster{c}
+		return nil
+	}
+
+	for _, c := range f.DBClusters {
+		out.DBClusters = append(out.DBClusters, c)
+	}
+
+	return nil
+}
+
+// CreateDBSubnetGroup : creates a subnet group from existing subnets
+func (f *RDS) CreateDBSubnetGroup(in *rds.CreateDBSubnetGroupInput, out *rds.CreateDBSubnetGroupOutput) error {
+	name := aws.StringValue(in.DBSubnetGroupName)
+
+	if _, ok := f.DBSubnetGroups[name]; ok {
+		return awserr.New("DBSubnetGroupAlreadyExists", "DB Subnet Group "+name+" already exists", nil)
+	}
+
+	sg := &rds.DBSubnetGroup{
+		DBSubnetGroupName:        in.DBSubnetGroupName,
+		DBSubnetGroupArn:         aws.String(f.b.arn("rds", "subgrp:"+name)),
+		DBSubnetGroupDescription: in.DBSubnetGroupDescription,
+		SubnetGroupStatus:        aws.String("Complete"),
+	}
+
+	if err := f.setSubnets(sg, in.SubnetIds); err != nil {
+		return err
+	}
+
+	f.DBSubnetGroups[name] = sg
+	out.DBSubnetGroup = sg
+
+	return nil
+}
+
+// ModifyDBSubnetGroup : replaces the subnets of a subnet group
+func (f *RDS) ModifyDBSubnetGroup(in *rds.ModifyDBSubnetGroupInput, out *rds.ModifyDBSubnetGroupOutput) error {
+	sg, err := f.subnetGroup(in.DBSubnetGroupName)
+	if err != nil {
+		return err
+	}
+
+	if sg == nil {
+		return notFound("DBSubnetGroupNotFoundFault", "")
+	}
+
+	if err := f.setSubnets(sg, in.SubnetIds); err != nil {
+		return err
+	}
+
+	set(&sg.DBSubnetGroupDescription, in.DBSubnetGroupDescription)
+	out.DBSubnetGroup = sg
+
+	return nil
+}
+
+// DeleteDBSubnetGroup : deletes a subnet group that is not in use
+func (f *RDS) DeleteDBSubnetGroup(in *rds.DeleteDBSubnetGroupInput, out *rds.DeleteDBSubnetGroupOutput) error {
+	name := aws.StringValue(in.DBSubnetGroupName)
+
+	if _, ok := f.DBSubnetGroups[name]; !ok {
+		return notFound("DBSubnetGroupNotFoundFault", name)
+	}
+
+	for _, i := range f.DBInstances {
+		if i.DBSubnetGroup != nil && *i.DBSubnetGroup.DBSubnetGroupName == name {
+			return awserr.New("InvalidDBSubnetGroupStateFault", "DB Subnet Group "+name+" is in use", nil)
+		}
+	}
+
+	for _, c := range f.DBClusters {
+		if aws.StringValue(c.DBSubnetGroup) == name {
+			return awserr.New("InvalidDBSubnetGroupStateFault", "DB Subnet Group "+name+" is in use", nil)
+		}
+	}
+
+	delete(f.DBSubnetGroups, name)
+
+	return nil
+}
+
+// DescribeDBSubnetGroups : lists subnet groups
+func (f *RDS) DescribeDBSubnetGroups(in *rds.DescribeDBSubnetGroupsInput, out *rds.DescribeDBSubnetGroupsOutput) error {
+	if in.DBSubnetGroupName != nil {
+		sg, ok := f.DBSubnetGroups[*in.DBSubnetGroupName]
+		if !ok {
+			return notFound("DBSubnetGroupNotFoundFault", *in.DBSubnetGroupName)
+		}
+		out.DBSubnetGroups = []*rds.DBSubnetGroup{sg}
+		return nil
+	}
+
+	for _, sg := range f.DBSubnetGroups {
+		out.DBSubnetGroups = append(out.DBSubnetGroups, sg)
+	}
+
+	return nil
+}
+
+// AddTagsToResource : adds or overwrites tags on an instance or cluster
+func (f *RDS) AddTagsToResource(in *rds.AddTagsToResourceInput, out *rds.AddTagsToResourceOutput) error {
+	arn := aws.StringValue(in.ResourceName)
+
+	tags, ok := f.Tags[arn]
+	if !ok && !f.exists(arn) {
+		return notFound("DBInstanceNotFound", arn)
+	}
+
+	for _, t := range in.Tags {
+		tags = setRDSTag(tags, aws.StringValue(t.Key), aws.StringValue(t.Value))
+	}
+
+	f.Tags[arn] = tags
+
+	return nil
+}
+
+// ListTagsForResource : lists the tags of an instance or cluster
+func (f *RDS) ListTagsForResource(in *rds.ListTagsForResourceInput, out *rds.ListTagsForResourceOutput) error {
+	arn := aws.StringValue(in.ResourceName)
+
+	if !f.exists(arn) {
+		return notFound("DBInstanceNotFound", arn)
+	}
+
+	out.TagList = f.Tags[arn]
+
+	return nil
+}
+
+func (f *RDS) exists(arn string) bool {
+	for _, i := range f.DBInstances {
+		if *i.DBInstanceArn == arn {
+			return true
+		}
+	}
+
+	for _, c := range f.DBClusters {
+		if *c.DBClusterArn == arn {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subnetGroup : returns the named subnet group, if any name is given
+func (f *RDS) subnetGroup(name *string) (*rds.DBSubnetGroup, error) {
+	if name == nil {
+		return nil, nil
+	}
+
+	sg, ok := f.DBSubnetGroups[*name]
+	if !ok {
+		return nil, notFound("DBSubnetGroupNotFoundFault", *name)
+	}
+
+	return sg, nil
+}
+
+func (f *RDS) setSubnets(sg *rds.DBSubnetGroup, ids []*string) error {
+	var subnets []*rds.Subnet
+
+	for _, id := range ids {
+		s, ok := f.b.EC2.Subnets[aws.StringValue(id)]
+		if !ok {
+			return notFound("InvalidSubnet", aws.StringValue(id))
+		}
+
+		sg.VpcId = s.VpcId
+		subnets = append(subnets, &rds.Subnet{
+			SubnetIdentifier:       s.SubnetId,
+			SubnetAvailabilityZone: &rds.AvailabilityZone{Name: s.AvailabilityZone},
+			SubnetStatus:           aws.String("Active"),
+		})
+	}
+
+	sg.Subnets = subnets
+
+	return nil
+}
+
+func (f *RDS) endpoint(name string, port *int64) *rds.Endpoint {
+	return &rds.Endpoint{
+		Address: aws.String(fmt.Sprintf("%s.fake.%s.rds.amazonaws.com", name, f.b.Region)),
+		Port:    port,
+	}
+}
+
+func securityGroupMemberships(ids []*string) []*rds.VpcSecurityGroupMembership {
+	var sgs []*rds.VpcSecurityGroupMembership
+
+	for _, id := range ids {
+		sgs = append(sgs, &rds.VpcSecurityGroupMembership{
+			VpcSecurityGroupId: id,
+			Status:             aws.String("active"),
+		})
+	}
+
+	return sgs
+}
+
+func setRDSTag(tags []*rds.Tag, key, value string) []*rds.Tag {
+	for _, t := range tags {
+		if *t.Key == key {
+			t.Value = aws.String(value)
+			return tags
+		}
+	}
+
+	return append(tags, &rds.Tag{Key: aws.String(key), Value: aws.String(value)})
+}
+
+func set(field **string, v *string) {
+	if v != nil {
+		*field = v
+	}
+}
+
+func setInt(field **int64, v *int64) {
+	if v != nil {
+		*field = v
+	}
+}
+
+func setBool(field **bool, v *bool) {
+	if v != nil {
+		*field = v
+	}
+}
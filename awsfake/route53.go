@@ -0,0 +1,278 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsfake
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Route53 stores the state of the fake route53 service
+type Route53 struct {
+	b *Backend
+
+	HostedZones map[string]*route53.HostedZone
+	Records     map[string][]*route53.ResourceRecordSet
+	Tags        map[string][]*route53.Tag
+}
+
+func newRoute53(b *Backend) *Route53 {
+	return &Route53{
+		b:           b,
+		HostedZones: make(map[string]*route53.HostedZone),
+		Records:     make(map[string][]*route53.ResourceRecordSet),
+		Tags:        make(map[string][]*route53.Tag),
+	}
+}
+
+// CreateHostedZone : creates a hosted zone with its default SOA and NS records
+func (f *Route53) CreateHostedZone(in *route53.CreateHostedZoneInput, out *route53.CreateHostedZoneOutput) error {
+	for _, z := range f.HostedZones {
+		if *z.CallerReference == aws.StringValue(in.CallerReference) {
+			return awserr.New("HostedZoneAlreadyExists", "A hosted zone has already been created with the specified caller reference", nil)
+		}
+	}
+
+	config := &route53.HostedZoneConfig{PrivateZone: aws.Bool(false)}
+	if in.HostedZoneConfig != nil {
+		config.Comment = in.HostedZoneConfig.Comment
+		config.PrivateZone = aws.Bool(aws.BoolValue(in.HostedZoneConfig.PrivateZone))
+	}
+
+	if *config.PrivateZone && in.VPC == nil {
+		return awserr.New("InvalidInput", "A private hosted zone requires a VPC", nil)
+	}
+
+	name := fqdn(aws.StringValue(in.Name))
+	id := "/hostedzone/" + strings.ToUpper(strings.Replace(f.b.id("Z"), "-", "", -1))
+
+	z := &route53.HostedZone{
+		Id:                     aws.String(id),
+		Name:                   aws.String(name),
+		CallerReference:        in.CallerReference,
+		Config:                 config,
+		ResourceRecordSetCount: aws.Int64(2),
+	}
+
+	f.HostedZones[id] = z
+	f.Records[id] = []*route53.ResourceRecordSet{
+		{
+			Name: aws.String(name),
+			Type: aws.String(route53.RRTypeNs),
+			TTL:  aws.Int64(172800),
+			ResourceRecords: []*route53.ResourceRecord{
+				{Value: aws.String("ns-1.awsdns-00.com.")},
+				{Value: aws.String("ns-2.awsdns-00.net.")},
+			},
+		},
+		{
+			Name: aws.String(name),
+			Type: aws.String(route53.RRTypeSoa),
+			TTL:  aws.Int64(900),
+			ResourceRecords: []*route53.ResourceRecord{
+				{Value: aws.String("ns-1.awsdns-00.com. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")},
+			},
+		},
+	}
+
+	out.HostedZone = z
+	out.VPC = in.VPC
+	out.Location = aws.String("https://route53.amazonaws.com/2013-04-01" + id)
+	out.ChangeInfo = f.changeInfo()
+
+	return nil
+}
+
+// DeleteHostedZone : deletes a hosted zone holding only its default records
+func (f *Route53) DeleteHostedZone(in *route53.DeleteHostedZoneInput, out *route53.DeleteHostedZoneOutput) error {
+	z, err := f.get(in.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range f.Records[*z.Id] {
+		if *r.Name != *z.Name || (*r.Type != route53.RRTypeNs && *r.Type != route53.RRTypeSoa) {
+			return awserr.New("HostedZoneNotEmpty", "The hosted zone contains resource records that are not SOA or NS records", nil)
+		}
+	}
+
+	delete(f.HostedZones, *z.Id)
+	delete(f.Records, *z.Id)
+	delete(f.Tags, *z.Id)
+
+	out.ChangeInfo = f.changeInfo()
+
+	return nil
+}
+
+// ListHostedZones : lists hosted zones
+func (f *Route53) ListHostedZones(in *route53.ListHostedZonesInput, out *route53.ListHostedZonesOutput) error {
+	for _, z := range f.HostedZones {
+		out.HostedZones = append(out.HostedZones, z)
+	}
+
+	out.IsTruncated = aws.Bool(false)
+	out.MaxItems = aws.String("100")
+
+	return nil
+}
+
+// ListResourceRecordSets : lists the records of a hosted zone
+func (f *Route53) ListResourceRecordSets(in *route53.ListResourceRecordSetsInput, out *route53.ListResourceRecordSetsOutput) error {
+	z, err := f.get(in.HostedZoneId)
+	if err != nil {
+		return err
+	}
+
+	out.ResourceRecordSets = f.Records[*z.Id]
+	out.IsTruncated = aws.Bool(false)
+	out.MaxItems = aws.String("100")
+
+	return nil
+}
+
+// ChangeResourceRecordSets : applies a batch of changes, either all of
+// them or none
+func (f *Route53) ChangeResourceRecordSets(in *route53.ChangeResourceRecordSetsInput, out *route53.ChangeResourceRecordSetsOutput) error {
+	z, err := f.get(in.HostedZoneId)
+	if err != nil {
+		return err
+	}
+
+	records := append([]*route53.ResourceRecordSet{}, f.Records[*z.Id]...)
+
+	for _, c := range in.ChangeBatch.Changes {
+		rs := *c.ResourceRecordSet
+		rs.Name = aws.String(fqdn(aws.StringValue(rs.Name)))
+
+		if !strings.HasSuffix(*rs.Name, *z.Name) {
+			return awserr.New("InvalidChangeBatch", "RRSet with DNS name "+*rs.Name+" is not permitted in zone "+*z.Name, nil)
+		}
+
+		i := findRecord(records, &rs)
+
+		switch aws.StringValue(c.Action) {
+		case route53.ChangeActionCreate:
+			if i >= 0 {
+				return awserr.New("InvalidChangeBatch", "Tried to create resource record set "+*rs.Name+" but it already exists", nil)
+			}
+			records = append(records, &rs)
+		case route53.ChangeActionUpsert:
+			if i >= 0 {
+				records[i] = &rs
+			} else {
+				records = append(records, &rs)
+			}
+		case route53.ChangeActionDelete:
+			if i < 0 {
+				return awserr.New("InvalidChangeBatch", "Tried to delete resource record set "+*rs.Name+" but it was not found", nil)
+			}
+			records = append(records[:i], records[i+1:]...)
+		}
+	}
+
+	f.Records[*z.Id] = records
+	z.ResourceRecordSetCount = aws.Int64(int64(len(records)))
+
+	out.ChangeInfo = f.changeInfo()
+
+	return nil
+}
+
+// ChangeTagsForResource : adds and removes tags on a hosted zone
+func (f *Route53) ChangeTagsForResource(in *route53.ChangeTagsForResourceInput, out *route53.ChangeTagsForResourceOutput) error {
+	z, err := f.get(in.ResourceId)
+	if err != nil {
+		return err
+	}
+
+	tags := f.Tags[*z.Id]
+
+	for _, key := range in.RemoveTagKeys {
+		for i, t := range tags {
+			if *t.Key == aws.StringValue(key) {
+				tags = append(tags[:i], tags[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for _, t := range in.AddTags {
+		tags = setRoute53Tag(tags, aws.StringValue(t.Key), aws.StringValue(t.Value))
+	}
+
+	f.Tags[*z.Id] = tags
+
+	return nil
+}
+
+// ListTagsForResource : lists the tags of a hosted zone
+func (f *Route53) ListTagsForResource(in *route53.ListTagsForResourceInput, out *route53.ListTagsForResourceOutput) error {
+	z, err := f.get(in.ResourceId)
+	if err != nil {
+		return err
+	}
+
+	out.ResourceTagSet = &route53.ResourceTagSet{
+		ResourceId:   aws.String(strings.TrimPrefix(*z.Id, "/hostedzone/")),
+		ResourceType: in.ResourceType,
+		Tags:         f.Tags[*z.Id],
+	}
+
+	return nil
+}
+
+// get : returns a hosted zone, accepting ids with or without the
+// /hostedzone/ prefix
+func (f *Route53) get(id *string) (*route53.HostedZone, error) {
+	key := "/hostedzone/" + strings.TrimPrefix(aws.StringValue(id), "/hostedzone/")
+
+	z, ok := f.HostedZones[key]
+	if !ok {
+		return nil, awserr.New("NoSuchHostedZone", "No hosted zone found with ID: "+aws.StringValue(id), nil)
+	}
+
+	return z, nil
+}
+
+func (f *Route53) changeInfo() *route53.ChangeInfo {
+	return &route53.ChangeInfo{
+		Id:          aws.String("/change/" + strings.ToUpper(strings.Replace(f.b.id("C"), "-", "", -1))),
+		Status:      aws.String(route53.ChangeStatusInsync),
+		SubmittedAt: aws.Time(time.Now()),
+	}
+}
+
+func findRecord(records []*route53.ResourceRecordSet, rs *route53.ResourceRecordSet) int {
+	for i, r := range records {
+		if *r.Name == *rs.Name && aws.StringValue(r.Type) == aws.StringValue(rs.Type) {
+			return i
+		}
+	}
+	return -1
+}
+
+func setRoute53Tag(tags []*route53.Tag, key, value string) []*route53.Tag {
+	for _, t := range tags {
+		if *t.Key == key {
+			t.Value = aws.String(value)
+			return tags
+		}
+	}
+
+	return append(tags, &route53.Tag{Key: aws.String(key), Value: aws.String(value)})
+}
+
+// fqdn : appends the trailing dot route53 adds to every name
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
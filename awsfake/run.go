@@ -0,0 +1,41 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsfake
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ernestio/ernestaws"
+)
+
+// Datacenter holds the credentials Run adds to every event body
+const Datacenter = `"datacenter_region":"us-east-1","aws_access_key_id":"key","aws_secret_access_key":"secret"`
+
+// Run : handles an event built by the component constructor, filling
+// the $placeholders of the body with the given ids and adding the
+// datacenter credentials. Returns the response subject and body
+func Run(t *testing.T, handler func(string, []byte, string) ernestaws.Event, subject, body string, ids map[string]string) (string, map[string]interface{}) {
+	t.Helper()
+
+	for k, v := range ids {
+		body = strings.Replace(body, "$"+k, v, -1)
+	}
+
+	if body = strings.TrimSpace(strings.TrimPrefix(body, "{")); body != "}" {
+		body = "," + body
+	}
+
+	ev := handler(subject, []byte(`{`+Datacenter+body), "")
+	subject, data := ernestaws.Handle(&ev)
+
+	var res map[string]interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	return subject, res
+}
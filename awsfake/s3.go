@@ -0,0 +1,216 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsfake
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	allUsers           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	authenticatedUsers = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// Bucket stores the state of a fake s3 bucket
+type Bucket struct {
+	Name         string
+	Location     string
+	CreationDate time.Time
+	Grants       []*s3.Grant
+	Tags         []*s3.Tag
+}
+
+// S3 stores the state of the fake s3 service
+type S3 struct {
+	b *Backend
+
+	Owner   *s3.Owner
+	Buckets map[string]*Bucket
+}
+
+func newS3(b *Backend) *S3 {
+	return &S3{
+		b: b,
+		Owner: &s3.Owner{
+			ID:          aws.String(account),
+			DisplayName: aws.String("fake"),
+		},
+		Buckets: make(map[string]*Bucket),
+	}
+}
+
+// CreateBucket : creates a bucket with a canned acl
+func (f *S3) CreateBucket(in *s3.CreateBucketInput, out *s3.CreateBucketOutput) error {
+	name := aws.StringValue(in.Bucket)
+
+	if _, ok := f.Buckets[name]; ok {
+		return awserr.New("BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it", nil)
+	}
+
+	location := f.b.Region
+	if in.CreateBucketConfiguration != nil && aws.StringValue(in.CreateBucketConfiguration.LocationConstraint) != "" {
+		location = *in.CreateBucketConfiguration.LocationConstraint
+	}
+
+	f.Buckets[name] = &Bucket{
+		Name:         name,
+		Location:     location,
+		CreationDate: time.Now(),
+		Grants:       f.cannedGrants(aws.StringValue(in.ACL)),
+	}
+
+	out.Location = aws.String("/" + name)
+
+	return nil
+}
+
+// DeleteBucket : deletes a bucket
+func (f *S3) DeleteBucket(in *s3.DeleteBucketInput, out *s3.DeleteBucketOutput) error {
+	if _, err := f.get(in.Bucket); err != nil {
+		return err
+	}
+
+	delete(f.Buckets, aws.StringValue(in.Bucket))
+
+	return nil
+}
+
+// ListBuckets : lists buckets
+func (f *S3) ListBuckets(in *s3.ListBucketsInput, out *s3.ListBucketsOutput) error {
+	for _, b := range f.Buckets {
+		out.Buckets = append(out.Buckets, &s3.Bucket{
+			Name:         aws.String(b.Name),
+			CreationDate: aws.Time(b.CreationDate),
+		})
+	}
+
+	out.Owner = f.Owner
+
+	return nil
+}
+
+// GetBucketLocation : returns the region of a bucket
+func (f *S3) GetBucketLocation(in *s3.GetBucketLocationInput, out *s3.GetBucketLocationOutput) error {
+	b, err := f.get(in.Bucket)
+	if err != nil {
+		return err
+	}
+
+	out.LocationConstraint = aws.String(b.Location)
+
+	return nil
+}
+
+// GetBucketAcl : returns the grants of a bucket
+func (f *S3) GetBucketAcl(in *s3.GetBucketAclInput, out *s3.GetBucketAclOutput) error {
+	b, err := f.get(in.Bucket)
+	if err != nil {
+		return err
+	}
+
+	out.Owner = f.Owner
+	out.Grants = b.Grants
+
+	return nil
+}
+
+// PutBucketAcl : replaces the grants of a bucket with a canned acl or
+// an explicit access control policy
+func (f *S3) PutBucketAcl(in *s3.PutBucketAclInput, out *s3.PutBucketAclOutput) error {
+	b, err := f.get(in.Bucket)
+	if err != nil {
+		return err
+	}
+
+	if in.ACL != nil && in.AccessControlPolicy != nil {
+		return awserr.New("UnexpectedContent", "This request does not support content", nil)
+	}
+
+	if in.AccessControlPolicy != nil {
+		b.Grants = in.AccessControlPolicy.Grants
+		return nil
+	}
+
+	b.Grants = f.cannedGrants(aws.StringValue(in.ACL))
+
+	return nil
+}
+
+// PutBucketTagging : replaces the tags of a bucket
+func (f *S3) PutBucketTagging(in *s3.PutBucketTaggingInput, out *s3.PutBucketTaggingOutput) error {
+	b, err := f.get(in.Bucket)
+	if err != nil {
+		return err
+	}
+
+	if in.Tagging == nil {
+		return awserr.New("MalformedXML", "The XML you provided was not well-formed", nil)
+	}
+
+	b.Tags = in.Tagging.TagSet
+
+	return nil
+}
+
+// GetBucketTagging : returns the tags of a bucket
+func (f *S3) GetBucketTagging(in *s3.GetBucketTaggingInput, out *s3.GetBucketTaggingOutput) error {
+	b, err := f.get(in.Bucket)
+	if err != nil {
+		return err
+	}
+
+	if len(b.Tags) == 0 {
+		return awserr.New("NoSuchTagSet", "The TagSet does not exist", nil)
+	}
+
+	out.TagSet = b.Tags
+
+	return nil
+}
+
+func (f *S3) get(name *string) (*Bucket, error) {
+	b, ok := f.Buckets[aws.StringValue(name)]
+	if !ok {
+		return nil, awserr.New("NoSuchBucket", "The specified bucket does not exist", nil)
+	}
+
+	return b, nil
+}
+
+// cannedGrants : returns the grants of a canned acl
+func (f *S3) cannedGrants(acl string) []*s3.Grant {
+	grants := []*s3.Grant{
+		{
+			Grantee: &s3.Grantee{
+				Type:        aws.String(s3.TypeCanonicalUser),
+				ID:          f.Owner.ID,
+				DisplayName: f.Owner.DisplayName,
+			},
+			Permission: aws.String(s3.PermissionFullControl),
+		},
+	}
+
+	group := func(uri, permission string) *s3.Grant {
+		return &s3.Grant{
+			Grantee:    &s3.Grantee{Type: aws.String(s3.TypeGroup), URI: aws.String(uri)},
+			Permission: aws.String(permission),
+		}
+	}
+
+	switch acl {
+	case s3.BucketCannedACLPublicRead:
+		grants = append(grants, group(allUsers, s3.PermissionRead))
+	case s3.BucketCannedACLPublicReadWrite:
+		grants = append(grants, group(allUsers, s3.PermissionRead), group(allUsers, s3.PermissionWrite))
+	case s3.BucketCannedACLAuthenticatedRead:
+		grants = append(grants, group(authenticatedUsers, s3.PermissionRead))
+	}
+
+	return grants
+}
@@ -0,0 +1,182 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsreplay
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Request stores a recorded aws request
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response stores a recorded aws response
+type Response struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Interaction is a request with its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Fixture stores all interactions of a run
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// redacted headers are never written to a fixture
+var redacted = []string{
+	"Authorization",
+	"X-Amz-Security-Token",
+	"User-Agent",
+}
+
+// redactedParams are removed from presigned urls and query bodies
+var redactedParams = []string{
+	"X-Amz-Credential",
+	"X-Amz-Signature",
+	"X-Amz-Security-Token",
+	"AWSAccessKeyId",
+	"Signature",
+}
+
+// volatileParams are query values that change on every run, like the
+// idempotency tokens the sdk generates, they are masked so a replayed run
+// matches its recording
+var volatileParams = []string{
+	"ClientToken",
+}
+
+// volatile matches request values that change on every run, they are
+// masked so a replayed run matches its recording
+var volatile = []*regexp.Regexp{
+	regexp.MustCompile(`<CallerReference>[^<]*</CallerReference>`),
+}
+
+func load(file string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+func (f *Fixture) save(file string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// scrubHeaders : copies the headers without credentials nor signatures
+func scrubHeaders(h http.Header) http.Header {
+	c := http.Header{}
+
+	for k, v := range h {
+		c[k] = v
+	}
+
+	for _, k := range redacted {
+		c.Del(k)
+	}
+
+	return c
+}
+
+// scrubURL : removes credentials and signatures from the query string
+func scrubURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	c.RawQuery = scrubValues(c.RawQuery)
+
+	return c.String()
+}
+
+// scrubBody : removes credentials and volatile values from a request body
+func scrubBody(body string, h http.Header) string {
+	if strings.HasPrefix(h.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		body = scrubValues(body)
+	}
+
+	for _, re := range volatile {
+		body = re.ReplaceAllStringFunc(body, func(m string) string {
+			open := m[:strings.Index(m, ">")+1]
+			return open + "scrubbed" + "</" + open[1:]
+		})
+	}
+
+	return body
+}
+
+func scrubValues(raw string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil || len(values) == 0 {
+		return raw
+	}
+
+	for _, k := range redactedParams {
+		values.Del(k)
+	}
+
+	for _, k := range volatileParams {
+		if _, ok := values[k]; ok {
+			values.Set(k, "scrubbed")
+		}
+	}
+
+	return values.Encode()
+}
+
+// key : identifies a request regardless of the order in which lists
+// built from maps are serialized
+func key(method, rawurl, body string) string {
+	return method + " " + rawurl + "\n" + canonical(body)
+}
+
+// canonical : returns an order independent form of a body. List indexes
+// are stripped from query bodies and xml elements are sorted, so tags
+// sent in map iteration order still match
+func canonical(body string) string {
+	var parts []string
+
+	if values, err := url.ParseQuery(body); err == nil && !strings.HasPrefix(body, "<") {
+		for k, vs := range values {
+			k = index.ReplaceAllString(k, ".")
+			for _, v := range vs {
+				parts = append(parts, k+"="+v)
+			}
+		}
+	} else {
+		parts = strings.Split(body, "<")
+	}
+
+	sort.Strings(parts)
+
+	return strings.Join(parts, "\n")
+}
+
+var index = regexp.MustCompile(`\.\d+(\.|$)`)
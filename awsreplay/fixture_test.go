@@ -0,0 +1,65 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsreplay
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestScrubBody(t *testing.T) {
+	form := http.Header{"Content-Type": []string{"application/x-www-form-urlencoded; charset=utf-8"}}
+	xml := http.Header{"Content-Type": []string{"application/xml"}}
+
+	tests := []struct {
+		name     string
+		body     string
+		header   http.Header
+		expected string
+	}{
+		{
+			name:     "masks client tokens",
+			body:     "Action=RunInstances&ClientToken=4d5c7f0e-0d2e-4a7e-9f3b-5d1c2e3f4a5b&ImageId=ami-1",
+			header:   form,
+			expected: "Action=RunInstances&ClientToken=scrubbed&ImageId=ami-1",
+		},
+		{
+			name:     "removes credentials",
+			body:     "AWSAccessKeyId=AKIAEXAMPLE&Action=DescribeLoadBalancers&Signature=abc",
+			header:   form,
+			expected: "Action=DescribeLoadBalancers",
+		},
+		{
+			name:     "masks caller references",
+			body:     "<CreateHostedZoneRequest><CallerReference>1510831244</CallerReference><Name>example.com</Name></CreateHostedZoneRequest>",
+			header:   xml,
+			expected: "<CreateHostedZoneRequest><CallerReference>scrubbed</CallerReference><Name>example.com</Name></CreateHostedZoneRequest>",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := scrubBody(tt.body, tt.header); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestKeyMatchesRecordedRequests(t *testing.T) {
+	form := http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}}
+
+	recorded := scrubBody("Action=CreateNatGateway&ClientToken=aaaa&SubnetId=subnet-1", form)
+	replayed := scrubBody("Action=CreateNatGateway&ClientToken=bbbb&SubnetId=subnet-1", form)
+
+	if key("POST", "https://ec2.eu-west-1.amazonaws.com/", recorded) != key("POST", "https://ec2.eu-west-1.amazonaws.com/", replayed) {
+		t.Error("requests only differing on the client token should match")
+	}
+
+	tags := scrubBody("Action=CreateTags&Tag.1.Key=a&Tag.1.Value=1&Tag.2.Key=b&Tag.2.Value=2", form)
+	reordered := scrubBody("Action=CreateTags&Tag.1.Key=b&Tag.1.Value=2&Tag.2.Key=a&Tag.2.Value=1", form)
+
+	if key("POST", "/", tags) != key("POST", "/", reordered) {
+		t.Error("requests only differing on the order of the tags should match")
+	}
+}
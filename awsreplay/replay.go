@@ -0,0 +1,227 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package awsreplay
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Mode defines if the traffic is recorded or replayed
+type Mode int
+
+// Modes
+const (
+	// Record sends the requests to aws and stores them on the fixture
+	Record Mode = iota
+	// Replay serves the requests from the fixture, without reaching aws
+	Replay
+	// Auto records when the fixture does not exist and replays otherwise
+	Auto
+)
+
+// ErrInteractionNotFound : The request was not recorded on the fixture
+var ErrInteractionNotFound = errors.New("No recorded interaction matches the request")
+
+// Recorder is an http transport recording or replaying the aws traffic
+// of the clients created through the client package
+type Recorder struct {
+	mu       sync.Mutex
+	file     string
+	mode     Mode
+	fixture  *Fixture
+	queues   map[string][]*Interaction
+	last     map[string]*Interaction
+	previous *http.Client
+	base     http.RoundTripper
+}
+
+// New : Constructor, the fixture is loaded straight away when replaying
+func New(file string, mode Mode) (*Recorder, error) {
+	if mode == Auto {
+		mode = Replay
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			mode = Record
+		}
+	}
+
+	r := &Recorder{
+		file:    file,
+		mode:    mode,
+		fixture: &Fixture{},
+		queues:  make(map[string][]*Interaction),
+		last:    make(map[string]*Interaction),
+	}
+
+	if mode == Record {
+		return r, nil
+	}
+
+	f, err := load(file)
+	if err != nil {
+		return nil, err
+	}
+
+	r.fixture = f
+	for i := range f.Interactions {
+		in := &f.Interactions[i]
+		k := key(in.Request.Method, in.Request.URL, in.Request.Body)
+		r.queues[k] = append(r.queues[k], in)
+	}
+
+	return r, nil
+}
+
+// Mode : returns the mode the recorder is running on
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Install : routes the aws traffic of every session created from now on
+// through the recorder
+func (r *Recorder) Install() {
+	r.previous = client.Config.HTTPClient
+
+	r.base = http.DefaultTransport
+	if r.previous != nil && r.previous.Transport != nil {
+		r.base = r.previous.Transport
+	}
+
+	client.Config.HTTPClient = &http.Client{Transport: r}
+}
+
+// Stop : restores the previous http client and writes the fixture when
+// recording
+func (r *Recorder) Stop() error {
+	client.Config.HTTPClient = r.previous
+
+	if r.mode != Record {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.fixture.save(r.file)
+}
+
+// RoundTrip : implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := Request{
+		Method:  req.Method,
+		URL:     scrubURL(req.URL),
+		Headers: scrubHeaders(req.Header),
+		Body:    scrubBody(body, req.Header),
+	}
+
+	if r.mode == Replay {
+		return r.replay(req, recorded)
+	}
+
+	return r.record(req, recorded)
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fixture.Interactions = append(r.fixture.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: scrubHeaders(resp.Header),
+			Body:    string(data),
+		},
+	})
+
+	return resp, nil
+}
+
+// replay : serves the recorded responses of a request in order, once
+// exhausted the last one is served again, so polling keeps working
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := key(recorded.Method, recorded.URL, recorded.Body)
+
+	in := r.last[k]
+	if q := r.queues[k]; len(q) > 0 {
+		in = q[0]
+		r.queues[k] = q[1:]
+		r.last[k] = in
+	}
+
+	if in == nil {
+		return nil, errors.New(ErrInteractionNotFound.Error() + ": " + recorded.Method + " " + recorded.URL)
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(in.Response.Status),
+		StatusCode:    in.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Response.Headers,
+		Body:          ioutil.NopCloser(bytes.NewBufferString(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unused : returns the recorded interactions that were never replayed
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Interaction
+
+	for _, q := range r.queues {
+		for _, in := range q {
+			unused = append(unused, *in)
+		}
+	}
+
+	return unused
+}
+
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	return string(data), nil
+}
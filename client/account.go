@@ -0,0 +1,32 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package client
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/ernestio/ernestaws/credentials"
+)
+
+// Account holds the region and credentials used by the typed component
+// clients. Keys are decrypted with the crypto key when one is set, and
+// the default aws credential chain is used when there are no keys
+type Account struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	CryptoKey       string
+}
+
+// Config : returns the aws config of the account
+func (a Account) Config() *aws.Config {
+	cfg := aws.NewConfig().WithRegion(a.Region)
+
+	if a.AccessKeyID != "" || a.SecretAccessKey != "" {
+		creds, _ := credentials.NewStaticCredentials(a.AccessKeyID, a.SecretAccessKey, a.CryptoKey)
+		cfg.Credentials = creds
+	}
+
+	return cfg
+}
@@ -0,0 +1,52 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package client
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Config is shared by the sessions of every component client, so the host
+// process can override settings like the endpoint or the http client
+var Config = aws.NewConfig()
+
+// Scope identifies the event a session makes calls for
+type Scope struct {
+	Subject     string
+	ComponentID string
+}
+
+var (
+	mu    sync.RWMutex
+	hooks []func(*session.Session, Scope)
+)
+
+// OnSession : registers a hook that is applied to every new session, used
+// to add request handlers to all the component clients
+func OnSession(fn func(*session.Session, Scope)) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	hooks = append(hooks, fn)
+}
+
+// Session : returns a new session built on top of the shared config,
+// for the calls made while handling the given event
+func Session(subject, componentID string) *session.Session {
+	sess := session.New(Config)
+	scope := Scope{Subject: subject, ComponentID: componentID}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, fn := range hooks {
+		fn(sess, scope)
+	}
+
+	return sess
+}
@@ -0,0 +1,246 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Rate configures a token bucket, a rate lower or equal to zero disables it
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Limits configures the rates of each api family
+type Limits struct {
+	Describe Rate
+	Mutate   Rate
+}
+
+// DefaultLimits are conservative rates, well below the aws account limits
+var DefaultLimits = Limits{
+	Describe: Rate{PerSecond: 20, Burst: 40},
+	Mutate:   Rate{PerSecond: 5, Burst: 10},
+}
+
+const (
+	// slowdown is applied to the rate of a bucket on every throttling error
+	slowdown = 0.5
+	// floor is the lowest fraction of the configured rate a bucket can reach
+	floor = 0.1
+	// recovery is the fraction of the configured rate regained on success
+	recovery = 0.05
+)
+
+// throttling error codes returned by the different aws services
+var throttling = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"RequestThrottledException":              true,
+	"TooManyRequestsException":               true,
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+	"RequestThrottled":                       true,
+	"SlowDown":                               true,
+	"PriorRequestNotComplete":                true,
+}
+
+type bucket struct {
+	rate   Rate
+	limit  float64
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket rate limiter shared by all the component
+// clients. Buckets are keyed by account, region, service and api family,
+// and their rate is reduced whenever aws throttles a call
+type Limiter struct {
+	mu      sync.Mutex
+	limits  Limits
+	buckets map[string]*bucket
+}
+
+// NewLimiter : Constructor
+func NewLimiter(l Limits) *Limiter {
+	return &Limiter{
+		limits:  l,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Install : rate limits the calls of every session created from now on
+func (l *Limiter) Install() {
+	OnSession(l.track)
+}
+
+func (l *Limiter) track(sess *session.Session, _ Scope) {
+	// sign runs before every attempt, including retries
+	sess.Handlers.Sign.PushFront(l.wait)
+	sess.Handlers.Retry.PushBack(l.throttled)
+	sess.Handlers.Complete.PushBack(l.succeeded)
+}
+
+// Wait : blocks until a call of the operation can be made on the key or
+// the context is done, in which case the reserved slot is given back
+func (l *Limiter) Wait(ctx context.Context, key, operation string) error {
+	d := l.reserve(key, operation)
+	if d <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		l.cancel(key, operation)
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) wait(r *request.Request) {
+	if err := l.Wait(r.Context(), requestKey(r), r.Operation.Name); err != nil {
+		r.Error = awserr.New(request.CanceledErrorCode, "request context canceled", err)
+	}
+}
+
+// throttled : slows a bucket down when aws throttles a call
+func (l *Limiter) throttled(r *request.Request) {
+	if isThrottle(r) {
+		l.adjust(requestKey(r), r.Operation.Name, slowdown, 0)
+	}
+}
+
+// succeeded : gradually brings a bucket back to its configured rate
+func (l *Limiter) succeeded(r *request.Request) {
+	if r.Error == nil {
+		l.adjust(requestKey(r), r.Operation.Name, 1, recovery)
+	}
+}
+
+func (l *Limiter) reserve(key, operation string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, operation)
+	if b == nil {
+		return 0
+	}
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.limit
+	if b.tokens > float64(b.rate.Burst) {
+		b.tokens = float64(b.rate.Burst)
+	}
+	b.last = now
+
+	// tokens can go negative, reserving a slot in the future
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.limit * float64(time.Second))
+}
+
+// cancel : returns the token of a reservation that wasn't used
+func (l *Limiter) cancel(key, operation string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b := l.bucket(key, operation); b != nil {
+		b.tokens++
+	}
+}
+
+func (l *Limiter) adjust(key, operation string, factor, increase float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, operation)
+	if b == nil {
+		return
+	}
+
+	b.limit = b.limit*factor + b.rate.PerSecond*increase
+
+	if b.limit > b.rate.PerSecond {
+		b.limit = b.rate.PerSecond
+	}
+
+	if b.limit < b.rate.PerSecond*floor {
+		b.limit = b.rate.PerSecond * floor
+	}
+}
+
+func (l *Limiter) bucket(key, operation string) *bucket {
+	rate := l.limits.Describe
+	family := "describe"
+
+	if Mutating(operation) {
+		rate = l.limits.Mutate
+		family = "mutate"
+	}
+
+	if rate.PerSecond <= 0 {
+		return nil
+	}
+
+	if rate.Burst < 1 {
+		rate.Burst = 1
+	}
+
+	key = key + "/" + family
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{
+			rate:   rate,
+			limit:  rate.PerSecond,
+			tokens: float64(rate.Burst),
+			last:   time.Now(),
+		}
+		l.buckets[key] = b
+	}
+
+	return b
+}
+
+// requestKey : identifies the account, region and service of a request.
+// The access key stands for the account, as resolving the account id
+// would need an extra call
+func requestKey(r *request.Request) string {
+	var account string
+
+	if r.Config.Credentials != nil {
+		if v, err := r.Config.Credentials.Get(); err == nil {
+			account = v.AccessKeyID
+		}
+	}
+
+	return account + "/" + aws.StringValue(r.Config.Region) + "/" + r.ClientInfo.ServiceName
+}
+
+func isThrottle(r *request.Request) bool {
+	if r.HTTPResponse != nil && r.HTTPResponse.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	if err, ok := r.Error.(awserr.Error); ok {
+		return throttling[err.Code()]
+	}
+
+	return false
+}
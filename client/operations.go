@@ -0,0 +1,20 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package client
+
+import "strings"
+
+// readOnly prefixes identify the operations that don't mutate resources
+var readOnly = []string{"Describe", "List", "Get", "Head"}
+
+// Mutating : checks if an aws operation changes any resource
+func Mutating(operation string) bool {
+	for _, p := range readOnly {
+		if strings.HasPrefix(operation, p) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Config stores the worker configuration
+type Config struct {
+	NatsURI      string
+	Queue        string
+	Concurrency  int
+	CryptoKey    string
+	DrainTimeout time.Duration
+	AuditFile    string
+	JournalDir   string
+	Preflight    bool
+	Verify       bool
+	DescribeRate float64
+	MutateRate   float64
+}
+
+// loadConfig : reads the configuration from flags, falling back to the
+// environment and then to the defaults
+func loadConfig(args []string) (*Config, error) {
+	var c Config
+
+	fs := flag.NewFlagSet("ernestaws-worker", flag.ContinueOnError)
+	fs.StringVar(&c.NatsURI, "nats", env("NATS_URI", "nats://127.0.0.1:4222"), "nats server uri")
+	fs.StringVar(&c.Queue, "queue", env("ERNESTAWS_QUEUE", "ernestaws"), "nats queue group")
+	fs.IntVar(&c.Concurrency, "concurrency", envInt("ERNESTAWS_CONCURRENCY", 10), "maximum number of events processed at once")
+	fs.StringVar(&c.CryptoKey, "crypto-key", env("ERNEST_CRYPTO_KEY", ""), "key used to decrypt the aws credentials")
+	fs.DurationVar(&c.DrainTimeout, "drain-timeout", envDuration("ERNESTAWS_DRAIN_TIMEOUT", time.Minute*5), "time to wait for in-flight events on shutdown")
+	fs.StringVar(&c.AuditFile, "audit-file", env("ERNESTAWS_AUDIT_FILE", ""), "append a record of every mutating aws call to this file")
+	fs.StringVar(&c.JournalDir, "journal-dir", env("ERNESTAWS_JOURNAL_DIR", ""), "directory to checkpoint multi step operations on, so re-delivered events resume them")
+	fs.BoolVar(&c.Preflight, "preflight", envBool("ERNESTAWS_PREFLIGHT", false), "check the aws account limits before creating resources")
+	fs.BoolVar(&c.Verify, "verify", envBool("ERNESTAWS_VERIFY", false), "validate the events against live aws metadata")
+	fs.Float64Var(&c.DescribeRate, "describe-rate", envFloat("ERNESTAWS_DESCRIBE_RATE", client.DefaultLimits.Describe.PerSecond), "read only aws calls per second, per account, region and service (0 disables the limit)")
+	fs.Float64Var(&c.MutateRate, "mutate-rate", envFloat("ERNESTAWS_MUTATE_RATE", client.DefaultLimits.Mutate.PerSecond), "mutating aws calls per second, per account, region and service (0 disables the limit)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if c.Concurrency < 1 {
+		c.Concurrency = 1
+	}
+
+	return &c, nil
+}
+
+func env(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func envBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func envFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
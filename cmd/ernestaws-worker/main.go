@@ -0,0 +1,75 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ernestio/ernestaws/audit"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/journal"
+	"github.com/ernestio/ernestaws/quota"
+	"github.com/ernestio/ernestaws/verify"
+	"github.com/nats-io/nats"
+)
+
+func main() {
+	c, err := loadConfig(os.Args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	if c.AuditFile != "" {
+		sink, err := audit.NewFileSink(c.AuditFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer sink.Close()
+
+		audit.Register(sink)
+	}
+
+	if c.JournalDir != "" {
+		store, err := journal.NewFileStore(c.JournalDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		journal.Use(store)
+	}
+
+	quota.Enable(c.Preflight)
+	verify.Enable(c.Verify)
+
+	limits := client.DefaultLimits
+	limits.Describe.PerSecond = c.DescribeRate
+	limits.Mutate.PerSecond = c.MutateRate
+	client.NewLimiter(limits).Install()
+
+	nc, err := nats.Connect(c.NatsURI, nats.MaxReconnects(-1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer nc.Close()
+
+	w := NewWorker(c, nc)
+	if err = w.Start(); err != nil {
+		log.Fatal(err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	s := <-sig
+
+	log.Printf("Received %s, draining in-flight events", s)
+
+	if err = w.Drain(); err != nil {
+		log.Println(err.Error())
+		os.Exit(1)
+	}
+}
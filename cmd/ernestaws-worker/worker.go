@@ -0,0 +1,146 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/components"
+	"github.com/nats-io/nats"
+)
+
+// ErrDrainTimeout ...
+var ErrDrainTimeout = errors.New("Timed out waiting for in-flight events")
+
+// Worker subscribes to all component subjects and processes their events
+type Worker struct {
+	config *Config
+	conn   *nats.Conn
+	subs   []*nats.Subscription
+	sem    chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewWorker : Constructor
+func NewWorker(c *Config, nc *nats.Conn) *Worker {
+	return &Worker{
+		config: c,
+		conn:   nc,
+		sem:    make(chan struct{}, c.Concurrency),
+	}
+}
+
+// Start : subscribes to every registered component subject
+func (w *Worker) Start() error {
+	for _, subject := range components.Subjects() {
+		sub, err := w.conn.QueueSubscribe(subject, w.config.Queue, w.dispatch)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Listening on %s (queue %s)", subject, w.config.Queue)
+		w.subs = append(w.subs, sub)
+	}
+
+	return w.conn.Flush()
+}
+
+// Drain : stops receiving new events and waits for the in-flight ones.
+// The subscriptions are drained instead of unsubscribed, so the events
+// already received by the client are still dispatched
+func (w *Worker) Drain() error {
+	for _, sub := range w.subs {
+		if err := sub.Drain(); err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	deadline := time.After(w.config.DrainTimeout)
+
+	// no event is dispatched once the subscriptions are closed, so waiting
+	// on the group can't race with the dispatch of a new one
+	if err := w.waitForSubscriptions(deadline); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-deadline:
+		return ErrDrainTimeout
+	}
+
+	return w.conn.Flush()
+}
+
+// waitForSubscriptions : waits until the drained subscriptions have
+// dispatched their pending events and are closed
+func (w *Worker) waitForSubscriptions(deadline <-chan time.Time) error {
+	tick := time.NewTicker(time.Millisecond * 50)
+	defer tick.Stop()
+
+	for {
+		drained := true
+		for _, sub := range w.subs {
+			if sub.IsValid() {
+				drained = false
+			}
+		}
+
+		if drained {
+			return nil
+		}
+
+		select {
+		case <-tick.C:
+		case <-deadline:
+			return ErrDrainTimeout
+		}
+	}
+}
+
+// dispatch is called from the subscription goroutine, so it blocks once
+// the concurrency limit is reached to apply back pressure on nats
+func (w *Worker) dispatch(msg *nats.Msg) {
+	w.wg.Add(1)
+	w.sem <- struct{}{}
+
+	go func() {
+		defer func() {
+			<-w.sem
+			w.wg.Done()
+		}()
+
+		w.process(msg)
+	}()
+}
+
+func (w *Worker) process(msg *nats.Msg) {
+	ev, err := components.New(msg.Subject, msg.Data, w.config.CryptoKey)
+	if err != nil {
+		log.Printf("Error: %s (%s)", err.Error(), msg.Subject)
+		if err = w.conn.Publish(msg.Subject+".error", msg.Data); err != nil {
+			log.Println(err.Error())
+		}
+		return
+	}
+
+	start := time.Now()
+	subject, data := ernestaws.Handle(&ev)
+	log.Printf("Processed %s -> %s in %s", msg.Subject, subject, time.Since(start))
+
+	if err := w.conn.Publish(subject, data); err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -0,0 +1,41 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"errors"
+
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/graph"
+)
+
+func runGraph(opts *options) error {
+	if opts.graph != "dot" && opts.graph != "json" {
+		return errors.New("unknown graph format " + opts.graph + ", expected dot or json")
+	}
+
+	s, err := scope(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.endpoint != "" {
+		client.Config.Endpoint = &opts.endpoint
+	}
+
+	g, err := graph.Discover(s)
+	if err != nil {
+		return err
+	}
+
+	output := g.DOT()
+	if opts.graph == "json" {
+		if output, err = g.JSON(); err != nil {
+			return err
+		}
+	}
+
+	return write(opts.out, "graph", output)
+}
@@ -0,0 +1,76 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/discovery"
+	"github.com/ernestio/ernestaws/importer"
+)
+
+// scope : builds the discovery scope from the options. Credentials are
+// read in plain text from the standard aws environment variables, so the
+// crypto key isn't used
+func scope(opts *options) (discovery.Scope, error) {
+	if opts.region == "" {
+		return discovery.Scope{}, errors.New("-region is required")
+	}
+
+	if opts.vpc == "" && opts.tags == "" {
+		return discovery.Scope{}, errors.New("-vpc or -tags are required")
+	}
+
+	tags := make(map[string]string)
+
+	for _, t := range strings.Split(opts.tags, ",") {
+		if t == "" {
+			continue
+		}
+
+		kv := strings.SplitN(t, "=", 2)
+		if len(kv) != 2 {
+			return discovery.Scope{}, errors.New("invalid tag " + t + ", expected key=value")
+		}
+
+		tags[kv[0]] = kv[1]
+	}
+
+	return discovery.Scope{
+		Account: client.Account{
+			Region:          opts.region,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		},
+		VpcID: opts.vpc,
+		Tags:  tags,
+	}, nil
+}
+
+func runImport(opts *options) error {
+	s, err := scope(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.endpoint != "" {
+		client.Config.Endpoint = &opts.endpoint
+	}
+
+	r, err := discovery.Discover(s)
+	if err != nil {
+		return err
+	}
+
+	output, err := importer.Build(opts.service, opts.datacenter, r).YAML()
+	if err != nil {
+		return err
+	}
+
+	return write(opts.out, "import", output)
+}
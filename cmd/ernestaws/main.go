@@ -0,0 +1,283 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/awsreplay"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/components"
+	"github.com/ernestio/ernestaws/quota"
+	"github.com/ernestio/ernestaws/schema"
+	"github.com/ernestio/ernestaws/verify"
+)
+
+const usage = `Usage: ernestaws [options] <subject> [file]
+       ernestaws -import -region <region> [-vpc <id>] [-tags k=v,...]
+       ernestaws -graph <dot|json> -region <region> [-vpc <id>] [-tags k=v,...]
+
+Runs the event stored on file (or read from stdin) through the component
+handling the given subject and prints its response. With -validate the
+event is only loaded and validated. With -import, the resources found on
+aws are printed as an ernest service definition, and with -graph as a
+graph of their dependencies.
+
+Example:
+  ernestaws -redact instance.update.aws failed.json
+  ernestaws -region eu-west-1 -format yaml network.find.aws < query.json
+  ernestaws -record elb-listeners.json elb.update.aws event.json
+  ernestaws -validate instance.create.aws event.json
+  ernestaws -import -region eu-west-1 -vpc vpc-0a1b2c3d -service web
+  ernestaws -graph dot -region eu-west-1 -vpc vpc-0a1b2c3d | dot -Tsvg
+
+Options:
+`
+
+type options struct {
+	cryptoKey string
+	region    string
+	endpoint  string
+	format    string
+	out       string
+	redact    bool
+	timing    bool
+	validate  bool
+	preflight bool
+	verify    bool
+	schemas   bool
+	record    string
+	replay    string
+
+	importing  bool
+	vpc        string
+	tags       string
+	service    string
+	datacenter string
+	graph      string
+}
+
+func main() {
+	var opts options
+
+	flag.StringVar(&opts.cryptoKey, "crypto-key", os.Getenv("ERNEST_CRYPTO_KEY"), "key used to decrypt the aws credentials")
+	flag.StringVar(&opts.region, "region", "", "override the datacenter region of the event")
+	flag.StringVar(&opts.endpoint, "endpoint", "", "override the aws endpoint")
+	flag.StringVar(&opts.format, "format", "json", "output format (json or yaml)")
+	flag.StringVar(&opts.out, "out", "", "write the response to a file instead of stdout")
+	flag.BoolVar(&opts.redact, "redact", false, "mask credentials and passwords on the response")
+	flag.BoolVar(&opts.timing, "timing", false, "print a timing breakdown to stderr")
+	flag.BoolVar(&opts.validate, "validate", false, "only load and validate the event, without calling aws")
+	flag.BoolVar(&opts.preflight, "preflight", false, "check the aws account limits before creating resources")
+	flag.BoolVar(&opts.verify, "verify", false, "validate the event against live aws metadata")
+	flag.StringVar(&opts.record, "record", "", "record the aws traffic on the given fixture file")
+	flag.StringVar(&opts.replay, "replay", "", "serve the aws traffic from the given fixture file")
+	flag.BoolVar(&opts.schemas, "schemas", false, "print the json schema of every component event and exit")
+	flag.BoolVar(&opts.importing, "import", false, "print the resources found on aws as a service definition and exit")
+	flag.StringVar(&opts.vpc, "vpc", "", "limit the import to the resources of the given vpc id")
+	flag.StringVar(&opts.tags, "tags", "", "limit the import to the resources with the given tags (key=value,...)")
+	flag.StringVar(&opts.service, "service", "imported", "name of the imported service")
+	flag.StringVar(&opts.datacenter, "datacenter", "", "datacenter of the imported service")
+	flag.StringVar(&opts.graph, "graph", "", "print the dependency graph of the resources found on aws (dot or json) and exit")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if opts.schemas {
+		if err := printSchemas(opts.format); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if opts.importing {
+		if err := runImport(&opts); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if opts.graph != "" {
+		if err := runGraph(&opts); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), flag.Arg(1), &opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(subject, file string, opts *options) error {
+	body, err := readBody(file)
+	if err != nil {
+		return err
+	}
+
+	if opts.region != "" {
+		body, err = overrideRegion(body, opts.region)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.endpoint != "" {
+		client.Config.Endpoint = &opts.endpoint
+	}
+
+	quota.Enable(opts.preflight)
+	verify.Enable(opts.verify)
+
+	rec, err := recorder(opts)
+	if err != nil {
+		return err
+	}
+
+	t := newTimings()
+	if opts.timing {
+		client.OnSession(t.track)
+	}
+
+	ev, err := components.New(subject, body, opts.cryptoKey)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	var rsubject string
+	var rbody []byte
+
+	if opts.validate {
+		rsubject = subject + ".done"
+		if err = ev.Process(); err != nil {
+			rsubject = subject + ".error"
+		}
+		rbody = ev.GetBody()
+	} else {
+		rsubject, rbody = ernestaws.Handle(&ev)
+	}
+
+	elapsed := time.Since(start)
+
+	if rec != nil {
+		if err = rec.Stop(); err != nil {
+			return err
+		}
+	}
+
+	output, err := format(rbody, opts.format, opts.redact)
+	if err != nil {
+		return err
+	}
+
+	if err = write(opts.out, rsubject, output); err != nil {
+		return err
+	}
+
+	if opts.timing {
+		t.print(os.Stderr, elapsed)
+	}
+
+	if strings.HasSuffix(rsubject, ".error") {
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func recorder(opts *options) (*awsreplay.Recorder, error) {
+	if opts.record != "" && opts.replay != "" {
+		return nil, errors.New("-record and -replay can't be used together")
+	}
+
+	var rec *awsreplay.Recorder
+	var err error
+
+	switch {
+	case opts.record != "":
+		rec, err = awsreplay.New(opts.record, awsreplay.Record)
+	case opts.replay != "":
+		rec, err = awsreplay.New(opts.replay, awsreplay.Replay)
+	default:
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rec.Install()
+
+	return rec, nil
+}
+
+func printSchemas(f string) error {
+	body, err := json.Marshal(schema.Export())
+	if err != nil {
+		return err
+	}
+
+	output, err := format(body, f, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(output)
+
+	return err
+}
+
+func readBody(file string) ([]byte, error) {
+	if file == "" || file == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+
+	return ioutil.ReadFile(file)
+}
+
+func overrideRegion(body []byte, region string) ([]byte, error) {
+	var m map[string]interface{}
+
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, err
+	}
+
+	m["datacenter_region"] = region
+
+	return json.Marshal(m)
+}
+
+func write(file, subject string, output []byte) error {
+	if file == "" {
+		fmt.Fprintln(os.Stderr, subject)
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, subject+" -> "+file)
+
+	return ioutil.WriteFile(file, output, 0644)
+}
@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// secrets are the body fields masked when redacting a response
+var secrets = map[string]bool{
+	"aws_access_key_id":          true,
+	"aws_secret_access_key":      true,
+	"peer_aws_access_key_id":     true,
+	"peer_aws_secret_access_key": true,
+	"database_password":          true,
+	"password":                   true,
+	"user_data":                  true,
+}
+
+// ErrFormatInvalid ...
+var ErrFormatInvalid = errors.New("Output format invalid, must be json or yaml")
+
+func format(body []byte, f string, redacted bool) ([]byte, error) {
+	var v interface{}
+
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+
+	if redacted {
+		v = redact(v)
+	}
+
+	switch f {
+	case "json":
+		out, err := json.MarshalIndent(v, "", "  ")
+		return append(out, '\n'), err
+	case "yaml":
+		return yaml.Marshal(v)
+	}
+
+	return nil, ErrFormatInvalid
+}
+
+func redact(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for key, val := range x {
+			if secrets[key] && val != nil && val != "" {
+				x[key] = "********"
+				continue
+			}
+			x[key] = redact(val)
+		}
+	case []interface{}:
+		for i := range x {
+			x[i] = redact(x[i])
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,84 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/ernestio/ernestaws/client"
+)
+
+type call struct {
+	name     string
+	duration time.Duration
+	err      bool
+}
+
+// timings records the duration of every aws call made while handling
+// an event
+type timings struct {
+	mu      sync.Mutex
+	started map[*request.Request]time.Time
+	calls   []call
+}
+
+func newTimings() *timings {
+	return &timings{started: make(map[*request.Request]time.Time)}
+}
+
+func (t *timings) track(sess *session.Session, _ client.Scope) {
+	sess.Handlers.Build.PushFront(t.start)
+	sess.Handlers.Complete.PushBack(t.stop)
+}
+
+func (t *timings) start(r *request.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.started[r] = time.Now()
+}
+
+func (t *timings) stop(r *request.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, ok := t.started[r]
+	if !ok {
+		return
+	}
+	delete(t.started, r)
+
+	t.calls = append(t.calls, call{
+		name:     r.ClientInfo.ServiceName + "." + r.Operation.Name,
+		duration: time.Since(start),
+		err:      r.Error != nil,
+	})
+}
+
+func (t *timings) print(w io.Writer, total time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var spent time.Duration
+
+	fmt.Fprintln(w, "Timing:")
+	for _, c := range t.calls {
+		status := "ok"
+		if c.err {
+			status = "error"
+		}
+		fmt.Fprintf(w, "  %-50s %12s  %s\n", c.name, c.duration, status)
+		spent += c.duration
+	}
+
+	fmt.Fprintf(w, "  %-50s %12s\n", fmt.Sprintf("aws (%d calls)", len(t.calls)), spent)
+	fmt.Fprintf(w, "  %-50s %12s\n", "waiting / local", total-spent)
+	fmt.Fprintf(w, "  %-50s %12s\n", "total", total)
+}
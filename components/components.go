@@ -0,0 +1,110 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package components
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/dhcpoptions"
+	"github.com/ernestio/ernestaws/ebs"
+	"github.com/ernestio/ernestaws/elb"
+	"github.com/ernestio/ernestaws/firewall"
+	"github.com/ernestio/ernestaws/flowlog"
+	"github.com/ernestio/ernestaws/iaminstanceprofile"
+	"github.com/ernestio/ernestaws/iampolicy"
+	"github.com/ernestio/ernestaws/iamrole"
+	"github.com/ernestio/ernestaws/instance"
+	"github.com/ernestio/ernestaws/internetgateway"
+	"github.com/ernestio/ernestaws/nat"
+	"github.com/ernestio/ernestaws/network"
+	"github.com/ernestio/ernestaws/networkacl"
+	"github.com/ernestio/ernestaws/rdscluster"
+	"github.com/ernestio/ernestaws/rdsinstance"
+	"github.com/ernestio/ernestaws/route53"
+	"github.com/ernestio/ernestaws/s3"
+	"github.com/ernestio/ernestaws/vpc"
+	"github.com/ernestio/ernestaws/vpcendpoint"
+	"github.com/ernestio/ernestaws/vpcpeering"
+)
+
+var (
+	// ErrSubjectInvalid ...
+	ErrSubjectInvalid = errors.New("Subject invalid")
+	// ErrComponentNotSupported ...
+	ErrComponentNotSupported = errors.New("Component not supported")
+)
+
+// Constructor : builds an event for a subject, body and crypto key
+type Constructor func(subject string, body []byte, cryptoKey string) ernestaws.Event
+
+var registry = map[string]Constructor{
+	"dhcp_options":         dhcpoptions.New,
+	"ebs_volume":           ebs.New,
+	"elb":                  elb.New,
+	"firewall":             firewall.New,
+	"flow_log":             flowlog.New,
+	"iam_instance_profile": iaminstanceprofile.New,
+	"iam_policy":           iampolicy.New,
+	"iam_role":             iamrole.New,
+	"instance":             instance.New,
+	"internet_gateway":     internetgateway.New,
+	"nat":                  nat.New,
+	"network":              network.New,
+	"network_acl":          networkacl.New,
+	"rds_cluster":          rdscluster.New,
+	"rds_instance":         rdsinstance.New,
+	"route53":              route53.New,
+	"s3":                   s3.New,
+	"vpc":                  vpc.New,
+	"vpc_endpoint":         vpcendpoint.New,
+	"vpc_peering":          vpcpeering.New,
+}
+
+// Register : adds a component constructor to the registry
+func Register(name string, c Constructor) {
+	registry[name] = c
+}
+
+// Names : returns the names of all registered components
+func Names() []string {
+	var names []string
+
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Subjects : returns the subjects all registered components listen on
+func Subjects() []string {
+	var subjects []string
+
+	for _, name := range Names() {
+		subjects = append(subjects, name+".*.aws")
+	}
+
+	return subjects
+}
+
+// New : builds the event for the component referenced on the subject
+func New(subject string, body []byte, cryptoKey string) (ernestaws.Event, error) {
+	parts := strings.Split(subject, ".")
+	if len(parts) != 3 || parts[2] != "aws" {
+		return nil, ErrSubjectInvalid
+	}
+
+	c, ok := registry[parts[0]]
+	if !ok {
+		return nil, ErrComponentNotSupported
+	}
+
+	return c(subject, body, cryptoKey), nil
+}
@@ -0,0 +1,316 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package dhcpoptions
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of a dhcp options set. Options left
+// empty are not set, and the netbios node type is only set when not zero
+type Spec struct {
+	Name               string
+	VpcID              string
+	DomainName         string
+	DomainNameServers  []string
+	NTPServers         []string
+	NetbiosNameServers []string
+	NetbiosNodeType    int64
+	Tags               map[string]string
+}
+
+// Status describes a dhcp options set as it is on aws. The vpc id is set
+// when a vpc is using it
+type Status struct {
+	ID                 string
+	Name               string
+	VpcID              string
+	DomainName         string
+	DomainNameServers  []string
+	NTPServers         []string
+	NetbiosNameServers []string
+	NetbiosNodeType    int64
+	Tags               map[string]string
+}
+
+// Client manages dhcp options sets through a typed api, the json events
+// are an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a dhcp options set and associates it with the vpc
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	req := ec2.CreateDhcpOptionsInput{
+		DhcpConfigurations: configurations(s),
+	}
+
+	resp, err := svc.CreateDhcpOptionsWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(resp.DhcpOptions)
+	st.Name = s.Name
+	st.VpcID = s.VpcID
+	st.Tags = s.Tags
+
+	err = c.setTags(ctx, svc, st.ID, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return st, c.associate(ctx, svc, st.ID, s.VpcID)
+}
+
+// Update : updates a dhcp options set. Options sets can't be modified, so
+// changed options are swapped in as a new set and the old one is deleted
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	opts, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if equal(mapConfigurations(opts.DhcpConfigurations), s.options()) {
+		err = c.associate(ctx, svc, id, s.VpcID)
+		if err != nil {
+			return Status{}, err
+		}
+
+		st := toStatus(opts)
+		st.Name = s.Name
+		st.VpcID = s.VpcID
+		st.Tags = s.Tags
+
+		return st, c.setTags(ctx, svc, id, s.Tags)
+	}
+
+	st, err := c.Create(ctx, s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	// the vpc already uses the new set, so failing to remove the old one,
+	// as when another vpc still uses it, doesn't fail the update
+	req := ec2.DeleteDhcpOptionsInput{
+		DhcpOptionsId: aws.String(id),
+	}
+
+	_, err = svc.DeleteDhcpOptionsWithContext(ctx, &req)
+	if err != nil {
+		log.Println("[ERROR]: Deleting the replaced dhcp options set " + id + ": " + err.Error())
+	}
+
+	return st, nil
+}
+
+// Delete : deletes a dhcp options set. The vpc is set back to the default
+// options if it is still using the set
+func (c Client) Delete(ctx context.Context, id, vpcID string) error {
+	svc := c.getEC2Client()
+
+	vpc, err := c.vpc(ctx, svc, vpcID)
+	if err != nil {
+		return err
+	}
+
+	if vpc != nil && aws.StringValue(vpc.DhcpOptionsId) == id {
+		err = c.associate(ctx, svc, "default", vpcID)
+		if err != nil {
+			return err
+		}
+	}
+
+	req := ec2.DeleteDhcpOptionsInput{
+		DhcpOptionsId: aws.String(id),
+	}
+
+	_, err = svc.DeleteDhcpOptionsWithContext(ctx, &req)
+
+	return err
+}
+
+// Find : returns the dhcp options sets matching all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	req := &ec2.DescribeDhcpOptionsInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := svc.DescribeDhcpOptionsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	vpcs, err := svc.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var sets []Status
+
+	for _, o := range resp.DhcpOptions {
+		st := toStatus(o)
+
+		for _, v := range vpcs.Vpcs {
+			if aws.StringValue(v.DhcpOptionsId) == st.ID {
+				st.VpcID = aws.StringValue(v.VpcId)
+			}
+		}
+
+		sets = append(sets, st)
+	}
+
+	return sets, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) associate(ctx context.Context, svc *ec2.EC2, id, vpcID string) error {
+	req := ec2.AssociateDhcpOptionsInput{
+		DhcpOptionsId: aws.String(id),
+		VpcId:         aws.String(vpcID),
+	}
+
+	_, err := svc.AssociateDhcpOptionsWithContext(ctx, &req)
+
+	return err
+}
+
+func (c Client) describe(ctx context.Context, svc *ec2.EC2, id string) (*ec2.DhcpOptions, error) {
+	req := ec2.DescribeDhcpOptionsInput{
+		DhcpOptionsIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeDhcpOptionsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.DhcpOptions) == 0 {
+		return nil, ErrDHCPOptionsNotFound
+	}
+
+	return resp.DhcpOptions[0], nil
+}
+
+func (c Client) vpc(ctx context.Context, svc *ec2.EC2, id string) (*ec2.Vpc, error) {
+	req := ec2.DescribeVpcsInput{
+		VpcIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeVpcsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Vpcs) == 0 {
+		return nil, nil
+	}
+
+	return resp.Vpcs[0], nil
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// options : returns the values of each dhcp configuration key set on the
+// spec
+func (s Spec) options() map[string][]string {
+	o := make(map[string][]string)
+
+	if s.DomainName != "" {
+		o[keyDomainName] = []string{s.DomainName}
+	}
+
+	if len(s.DomainNameServers) > 0 {
+		o[keyDomainNameServers] = s.DomainNameServers
+	}
+
+	if len(s.NTPServers) > 0 {
+		o[keyNTPServers] = s.NTPServers
+	}
+
+	if len(s.NetbiosNameServers) > 0 {
+		o[keyNetbiosNameServers] = s.NetbiosNameServers
+	}
+
+	if s.NetbiosNodeType != 0 {
+		o[keyNetbiosNodeType] = []string{strconv.FormatInt(s.NetbiosNodeType, 10)}
+	}
+
+	return o
+}
+
+func configurations(s Spec) []*ec2.NewDhcpConfiguration {
+	var c []*ec2.NewDhcpConfiguration
+
+	o := s.options()
+
+	for _, key := range keys(o) {
+		c = append(c, &ec2.NewDhcpConfiguration{
+			Key:    aws.String(key),
+			Values: aws.StringSlice(o[key]),
+		})
+	}
+
+	return c
+}
+
+func toStatus(o *ec2.DhcpOptions) Status {
+	tags := mapEC2Tags(o.Tags)
+	opts := mapConfigurations(o.DhcpConfigurations)
+
+	st := Status{
+		ID:                 aws.StringValue(o.DhcpOptionsId),
+		Name:               tags["Name"],
+		DomainNameServers:  opts[keyDomainNameServers],
+		NTPServers:         opts[keyNTPServers],
+		NetbiosNameServers: opts[keyNetbiosNameServers],
+		Tags:               tags,
+	}
+
+	if v := opts[keyDomainName]; len(v) > 0 {
+		st.DomainName = v[0]
+	}
+
+	if v := opts[keyNetbiosNodeType]; len(v) > 0 {
+		st.NetbiosNodeType, _ = strconv.ParseInt(v[0], 10, 64)
+	}
+
+	return st
+}
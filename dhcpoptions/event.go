@@ -0,0 +1,287 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package dhcpoptions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
+)
+
+var (
+	// ErrDatacenterIDInvalid ...
+	ErrDatacenterIDInvalid = errors.New("Datacenter VPC ID invalid")
+	// ErrDatacenterRegionInvalid ...
+	ErrDatacenterRegionInvalid = errors.New("Datacenter Region invalid")
+	// ErrDatacenterCredentialsInvalid ...
+	ErrDatacenterCredentialsInvalid = errors.New("Datacenter credentials invalid")
+	// ErrDHCPOptionsAWSIDInvalid ...
+	ErrDHCPOptionsAWSIDInvalid = errors.New("DHCP options aws id invalid")
+	// ErrDHCPOptionsEmpty ...
+	ErrDHCPOptionsEmpty = errors.New("DHCP options must set at least one option")
+	// ErrNetbiosNodeTypeInvalid ...
+	ErrNetbiosNodeTypeInvalid = errors.New("DHCP options netbios node type invalid, must be one of 1, 2, 4 or 8")
+	// ErrDHCPOptionsNotFound ...
+	ErrDHCPOptionsNotFound = errors.New("DHCP options not found")
+)
+
+// the dhcp configuration keys of an options set
+const (
+	keyDomainName         = "domain-name"
+	keyDomainNameServers  = "domain-name-servers"
+	keyNTPServers         = "ntp-servers"
+	keyNetbiosNameServers = "netbios-name-servers"
+	keyNetbiosNodeType    = "netbios-node-type"
+)
+
+// Event stores the dhcp options data
+type Event struct {
+	ProviderType       string            `json:"_provider"`
+	ComponentType      string            `json:"_component"`
+	ComponentID        string            `json:"_component_id"`
+	State              string            `json:"_state"`
+	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
+	DHCPOptionsAWSID   *string           `json:"dhcp_options_aws_id"`
+	Name               *string           `json:"name"`
+	DomainName         *string           `json:"domain_name"`
+	DomainNameServers  []string          `json:"domain_name_servers"`
+	NTPServers         []string          `json:"ntp_servers"`
+	NetbiosNameServers []string          `json:"netbios_name_servers"`
+	NetbiosNodeType    *int64            `json:"netbios_node_type"`
+	Tags               map[string]string `json:"tags"`
+	DatacenterType     string            `json:"datacenter_type"`
+	DatacenterName     string            `json:"datacenter_name"`
+	DatacenterRegion   string            `json:"datacenter_region"`
+	AccessKeyID        string            `json:"aws_access_key_id"`
+	SecretAccessKey    string            `json:"aws_secret_access_key"`
+	Vpc                string            `json:"vpc"`
+	VpcID              string            `json:"vpc_id"`
+	Service            string            `json:"service"`
+	ErrorMessage       string            `json:"error,omitempty"`
+	Subject            string            `json:"-"`
+	Body               []byte            `json:"-"`
+	CryptoKey          string            `json:"-"`
+}
+
+// New : Constructor
+func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
+	if strings.Split(subject, ".")[1] == "find" {
+		return &Collection{Subject: subject, Body: body, CryptoKey: cryptoKey}
+	}
+
+	return &Event{Subject: subject, Body: body, CryptoKey: cryptoKey}
+}
+
+// Validate checks if all criteria are met
+func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
+	if ev.VpcID == "" {
+		return ErrDatacenterIDInvalid
+	}
+
+	if ev.DatacenterRegion == "" {
+		return ErrDatacenterRegionInvalid
+	}
+
+	if ev.AccessKeyID == "" || ev.SecretAccessKey == "" {
+		return ErrDatacenterCredentialsInvalid
+	}
+
+	if ev.Subject != "dhcp_options.create.aws" && ev.DHCPOptionsAWSID == nil {
+		return ErrDHCPOptionsAWSIDInvalid
+	}
+
+	if ev.Subject == "dhcp_options.delete.aws" {
+		return nil
+	}
+
+	if len(ev.spec().options()) == 0 {
+		return ErrDHCPOptionsEmpty
+	}
+
+	if ev.NetbiosNodeType != nil {
+		switch *ev.NetbiosNodeType {
+		case 1, 2, 4, 8:
+		default:
+			return ErrNetbiosNodeTypeInvalid
+		}
+	}
+
+	return nil
+}
+
+// Process : starts processing the current message
+func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := json.Unmarshal(ev.Body, &ev); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := ev.Validate(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Error : Will respond the current event with an error
+func (ev *Event) Error(err error) {
+	log.Printf("Error: %s", err.Error())
+	ev.ErrorMessage = err.Error()
+	ev.State = "errored"
+
+	ev.Body, err = json.Marshal(ev)
+}
+
+// Complete : sets the state of the event to completed
+func (ev *Event) Complete() {
+	ev.State = "completed"
+}
+
+// Find : Find an object on aws
+func (ev *Event) Find() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// Create : Creates a dhcp options set on aws and associates it with the vpc
+func (ev *Event) Create() error {
+	st, err := ev.client().Create(context.Background(), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.DHCPOptionsAWSID = aws.String(st.ID)
+
+	return nil
+}
+
+// Update : Updates a dhcp options set on aws. Options sets can't be
+// modified, so changed options are swapped in as a new set and the old
+// one is deleted
+func (ev *Event) Update() error {
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.DHCPOptionsAWSID), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.DHCPOptionsAWSID = aws.String(st.ID)
+
+	return nil
+}
+
+// Delete : Deletes a dhcp options set on aws. The vpc is set back to the
+// default options if it is still using the set
+func (ev *Event) Delete() error {
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.DHCPOptionsAWSID), ev.VpcID)
+}
+
+// Get : Gets a object on aws
+func (ev *Event) Get() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// GetBody : Gets the body for this event
+func (ev *Event) GetBody() []byte {
+	var err error
+	if ev.Body, err = json.Marshal(ev); err != nil {
+		log.Println(err.Error())
+	}
+	return ev.Body
+}
+
+// GetSubject : Gets the subject for this event
+func (ev *Event) GetSubject() string {
+	return ev.Subject
+}
+
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+}
+
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:               aws.StringValue(ev.Name),
+		VpcID:              ev.VpcID,
+		DomainName:         aws.StringValue(ev.DomainName),
+		DomainNameServers:  ev.DomainNameServers,
+		NTPServers:         ev.NTPServers,
+		NetbiosNameServers: ev.NetbiosNameServers,
+		NetbiosNodeType:    aws.Int64Value(ev.NetbiosNodeType),
+		Tags:               ev.Tags,
+	}
+}
+
+// mapConfigurations : returns the values of each configuration key, the
+// order of the servers is kept as it is significant
+func mapConfigurations(input []*ec2.DhcpConfiguration) map[string][]string {
+	m := make(map[string][]string)
+
+	for _, cfg := range input {
+		for _, v := range cfg.Values {
+			m[*cfg.Key] = append(m[*cfg.Key], aws.StringValue(v.Value))
+		}
+	}
+
+	return m
+}
+
+func equal(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for key, av := range a {
+		bv, ok := b[key]
+		if !ok || strings.Join(av, ",") != strings.Join(bv, ",") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// keys : returns the sorted configuration keys
+func keys(m map[string][]string) []string {
+	var k []string
+
+	for key := range m {
+		k = append(k, key)
+	}
+
+	sort.Strings(k)
+
+	return k
+}
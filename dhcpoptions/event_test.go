@@ -0,0 +1,183 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package dhcpoptions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "dhcp_options.create.aws",
+			body:     `{"vpc_id":"$vpc","name":"dns","domain_name":"example.com","domain_name_servers":["10.0.0.2"],"tags":{"Name":"dns"}}`,
+			expected: "dhcp_options.create.aws.done",
+			save:     map[string]string{"id": "dhcp_options_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				return *b.EC2.Vpcs[ids["vpc"]].DhcpOptionsId == ids["id"]
+			},
+		},
+		{
+			name:     "update keeps an unchanged set",
+			subject:  "dhcp_options.update.aws",
+			body:     `{"vpc_id":"$vpc","dhcp_options_aws_id":"$id","domain_name":"example.com","domain_name_servers":["10.0.0.2"],"tags":{"Name":"dns"}}`,
+			expected: "dhcp_options.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return res["dhcp_options_aws_id"] == ids["id"]
+			},
+		},
+		{
+			name:     "update replaces a changed set",
+			subject:  "dhcp_options.update.aws",
+			body:     `{"vpc_id":"$vpc","dhcp_options_aws_id":"$id","domain_name":"example.org","ntp_servers":["10.0.0.3"],"tags":{"Name":"dns"}}`,
+			expected: "dhcp_options.update.aws.done",
+			save:     map[string]string{"id": "dhcp_options_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				return *b.EC2.Vpcs[ids["vpc"]].DhcpOptionsId == ids["id"] && len(b.EC2.DhcpOptions) == 2
+			},
+		},
+		{
+			name:     "find",
+			subject:  "dhcp_options.find.aws",
+			body:     `{"tags":{"Name":"dns"}}`,
+			expected: "dhcp_options.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				o := found[0].(map[string]interface{})
+				return o["vpc_id"] == ids["vpc"] && o["domain_name"] == "example.org" && len(o["ntp_servers"].([]interface{})) == 1
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "dhcp_options.delete.aws",
+			body:     `{"vpc_id":"$vpc","dhcp_options_aws_id":"$id"}`,
+			expected: "dhcp_options.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return *b.EC2.Vpcs[ids["vpc"]].DhcpOptionsId != ids["id"] && len(b.EC2.DhcpOptions) == 1
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails associating the set",
+			operation: "AssociateDhcpOptions",
+			subject:   "dhcp_options.create.aws",
+			body:      `{"vpc_id":"$vpc","domain_name":"example.com"}`,
+		},
+		{
+			name:      "update fails describing the set",
+			operation: "DescribeDhcpOptions",
+			subject:   "dhcp_options.update.aws",
+			body:      `{"vpc_id":"$vpc","dhcp_options_aws_id":"dopt-00000001","domain_name":"example.com"}`,
+		},
+		{
+			name:      "delete fails deleting the set",
+			operation: "DeleteDhcpOptions",
+			subject:   "dhcp_options.delete.aws",
+			body:      `{"vpc_id":"$vpc","dhcp_options_aws_id":"dopt-00000001"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, map[string]string{"vpc": createVpc(t, b)})
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestUpdateIgnoresFailedCleanup(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	_, res := awsfake.Run(t, New, "dhcp_options.create.aws", `{"vpc_id":"$vpc","name":"dns","domain_name":"example.com"}`, ids)
+	ids["id"], _ = res["dhcp_options_aws_id"].(string)
+
+	b.Fail("ec2", "DeleteDhcpOptions", "DependencyViolation", 1)
+
+	subject, res := awsfake.Run(t, New, "dhcp_options.update.aws", `{"vpc_id":"$vpc","dhcp_options_aws_id":"$id","name":"dns","domain_name":"example.org"}`, ids)
+	if subject != "dhcp_options.update.aws.done" {
+		t.Fatalf("expected dhcp_options.update.aws.done, got %s: %v", subject, res["error"])
+	}
+
+	id, _ := res["dhcp_options_aws_id"].(string)
+	if id == ids["id"] || *b.EC2.Vpcs[ids["vpc"]].DhcpOptionsId != id {
+		t.Errorf("expected the vpc to use the new set, got %v", res)
+	}
+
+	if b.EC2.DhcpOptions[ids["id"]] == nil {
+		t.Errorf("expected the old set to be kept")
+	}
+}
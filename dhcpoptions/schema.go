@@ -0,0 +1,37 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package dhcpoptions
+
+import "github.com/ernestio/ernestaws/schema"
+
+var options = []schema.Field{
+	{Name: "vpc_id", Type: schema.String, Required: true},
+	{Name: "domain_name", Type: schema.String},
+	{Name: "domain_name_servers", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "ntp_servers", Type: schema.Array, Items: &schema.Field{Type: schema.String, Format: schema.IP}},
+	{Name: "netbios_name_servers", Type: schema.Array, Items: &schema.Field{Type: schema.String, Format: schema.IP}},
+	{Name: "netbios_node_type", Type: schema.Integer, Minimum: schema.Int(1), Maximum: schema.Int(8)},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("dhcp_options", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), options, []schema.Field{
+			{Name: "name", Type: schema.String},
+		}),
+		"update": schema.Fields(schema.Datacenter(), options, []schema.Field{
+			{Name: "dhcp_options_aws_id", Type: schema.String, Required: true},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "dhcp_options_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("dhcp_options", 1, schema.VpcReference)
+}
@@ -0,0 +1,299 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/components"
+	"github.com/ernestio/ernestaws/dhcpoptions"
+	"github.com/ernestio/ernestaws/ebs"
+	"github.com/ernestio/ernestaws/elb"
+	"github.com/ernestio/ernestaws/firewall"
+	"github.com/ernestio/ernestaws/flowlog"
+	"github.com/ernestio/ernestaws/iaminstanceprofile"
+	"github.com/ernestio/ernestaws/iampolicy"
+	"github.com/ernestio/ernestaws/iamrole"
+	"github.com/ernestio/ernestaws/instance"
+	"github.com/ernestio/ernestaws/internetgateway"
+	"github.com/ernestio/ernestaws/nat"
+	"github.com/ernestio/ernestaws/network"
+	"github.com/ernestio/ernestaws/networkacl"
+	"github.com/ernestio/ernestaws/rdscluster"
+	"github.com/ernestio/ernestaws/rdsinstance"
+	"github.com/ernestio/ernestaws/route53"
+	"github.com/ernestio/ernestaws/s3"
+	"github.com/ernestio/ernestaws/vpc"
+	"github.com/ernestio/ernestaws/vpcendpoint"
+	"github.com/ernestio/ernestaws/vpcpeering"
+)
+
+// Scope selects the resources to discover. Resources are matched by
+// tags and, when a vpc is set, limited to the ones belonging to it.
+// Buckets, zones and iam resources aren't bound to a vpc, so they are
+// only discovered when no vpc is set
+type Scope struct {
+	client.Account
+	VpcID string
+	Tags  map[string]string
+}
+
+// Resources stores the events returned by the find of every component
+type Resources struct {
+	VPCs             []*vpc.Event
+	Networks         []*network.Event
+	InternetGateways []*internetgateway.Event
+	NatGateways      []*nat.Event
+	Firewalls        []*firewall.Event
+	Instances        []*instance.Event
+	Volumes          []*ebs.Event
+	ELBs             []*elb.Event
+	RDSClusters      []*rdscluster.Event
+	RDSInstances     []*rdsinstance.Event
+	Buckets          []*s3.Event
+	Zones            []*route53.Event
+	Roles            []*iamrole.Event
+	Policies         []*iampolicy.Event
+	InstanceProfiles []*iaminstanceprofile.Event
+	VpcPeerings      []*vpcpeering.Event
+	FlowLogs         []*flowlog.Event
+	DHCPOptions      []*dhcpoptions.Event
+	VpcEndpoints     []*vpcendpoint.Event
+	NetworkACLs      []*networkacl.Event
+}
+
+// Discover : runs the find of every component on the scope
+func Discover(s Scope) (*Resources, error) {
+	var r Resources
+
+	finds := []struct {
+		component string
+		out       interface{}
+		global    bool
+	}{
+		{"vpc", &r.VPCs, false},
+		{"network", &r.Networks, false},
+		{"internet_gateway", &r.InternetGateways, false},
+		{"nat", &r.NatGateways, false},
+		{"firewall", &r.Firewalls, false},
+		{"instance", &r.Instances, false},
+		{"ebs_volume", &r.Volumes, false},
+		{"elb", &r.ELBs, false},
+		{"rds_cluster", &r.RDSClusters, false},
+		{"rds_instance", &r.RDSInstances, false},
+		{"s3", &r.Buckets, true},
+		{"route53", &r.Zones, true},
+		{"iam_role", &r.Roles, true},
+		{"iam_policy", &r.Policies, true},
+		{"iam_instance_profile", &r.InstanceProfiles, true},
+		{"vpc_peering", &r.VpcPeerings, false},
+		{"flow_log", &r.FlowLogs, false},
+		{"dhcp_options", &r.DHCPOptions, false},
+		{"vpc_endpoint", &r.VpcEndpoints, false},
+		{"network_acl", &r.NetworkACLs, false},
+	}
+
+	for _, f := range finds {
+		if f.global && s.VpcID != "" {
+			continue
+		}
+
+		if err := find(s, f.component, f.out); err != nil {
+			return nil, errors.New(f.component + ": " + err.Error())
+		}
+	}
+
+	if s.VpcID != "" {
+		r.filter(s.VpcID)
+	}
+
+	return &r, nil
+}
+
+// find : handles the find event of a component, decoding the results
+func find(s Scope, component string, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"datacenter_region":     s.Region,
+		"aws_access_key_id":     s.AccessKeyID,
+		"aws_secret_access_key": s.SecretAccessKey,
+		"tags":                  s.Tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	ev, err := components.New(component+".find.aws", body, s.CryptoKey)
+	if err != nil {
+		return err
+	}
+
+	subject, body := ernestaws.Handle(&ev)
+
+	var resp struct {
+		Components json.RawMessage `json:"components"`
+		Error      string          `json:"error"`
+	}
+
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(subject, ".error") {
+		return errors.New(resp.Error)
+	}
+
+	if len(resp.Components) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(resp.Components, out)
+}
+
+// filter : drops the resources that don't belong to the vpc
+func (r *Resources) filter(vpcID string) {
+	var vpcs []*vpc.Event
+	for _, v := range r.VPCs {
+		if aws.StringValue(v.VpcID) == vpcID {
+			vpcs = append(vpcs, v)
+		}
+	}
+	r.VPCs = vpcs
+
+	subnets := make(map[string]bool)
+
+	var networks []*network.Event
+	for _, n := range r.Networks {
+		if n.VpcID == vpcID {
+			networks = append(networks, n)
+			subnets[aws.StringValue(n.NetworkAWSID)] = true
+		}
+	}
+	r.Networks = networks
+
+	var gateways []*internetgateway.Event
+	for _, g := range r.InternetGateways {
+		if g.VpcID == vpcID {
+			gateways = append(gateways, g)
+		}
+	}
+	r.InternetGateways = gateways
+
+	var nats []*nat.Event
+	for _, n := range r.NatGateways {
+		if n.VpcID == vpcID {
+			nats = append(nats, n)
+		}
+	}
+	r.NatGateways = nats
+
+	var firewalls []*firewall.Event
+	for _, f := range r.Firewalls {
+		if f.VpcID == vpcID {
+			firewalls = append(firewalls, f)
+		}
+	}
+	r.Firewalls = firewalls
+
+	volumes := make(map[string]bool)
+
+	var instances []*instance.Event
+	for _, i := range r.Instances {
+		if subnets[aws.StringValue(i.NetworkAWSID)] {
+			instances = append(instances, i)
+			for _, v := range i.Volumes {
+				volumes[aws.StringValue(v.VolumeAWSID)] = true
+			}
+		}
+	}
+	r.Instances = instances
+
+	var attached []*ebs.Event
+	for _, v := range r.Volumes {
+		if volumes[aws.StringValue(v.VolumeAWSID)] {
+			attached = append(attached, v)
+		}
+	}
+	r.Volumes = attached
+
+	var elbs []*elb.Event
+	for _, e := range r.ELBs {
+		if includes(subnets, e.NetworkAWSIDs) {
+			elbs = append(elbs, e)
+		}
+	}
+	r.ELBs = elbs
+
+	var clusters []*rdscluster.Event
+	for _, c := range r.RDSClusters {
+		if includes(subnets, c.NetworkAWSIDs) {
+			clusters = append(clusters, c)
+		}
+	}
+	r.RDSClusters = clusters
+
+	var dbs []*rdsinstance.Event
+	for _, i := range r.RDSInstances {
+		if includes(subnets, i.NetworkAWSIDs) {
+			dbs = append(dbs, i)
+		}
+	}
+	r.RDSInstances = dbs
+
+	var peerings []*vpcpeering.Event
+	for _, p := range r.VpcPeerings {
+		if p.VpcID == vpcID || p.PeerVpcID == vpcID {
+			peerings = append(peerings, p)
+		}
+	}
+	r.VpcPeerings = peerings
+
+	// flow logs of network interfaces can't be matched to a subnet, so
+	// only the ones on the vpc and its subnets are kept
+	var logs []*flowlog.Event
+	for _, l := range r.FlowLogs {
+		if l.ResourceID == vpcID || subnets[l.ResourceID] {
+			logs = append(logs, l)
+		}
+	}
+	r.FlowLogs = logs
+
+	var options []*dhcpoptions.Event
+	for _, o := range r.DHCPOptions {
+		if o.VpcID == vpcID {
+			options = append(options, o)
+		}
+	}
+	r.DHCPOptions = options
+
+	var endpoints []*vpcendpoint.Event
+	for _, e := range r.VpcEndpoints {
+		if e.VpcID == vpcID {
+			endpoints = append(endpoints, e)
+		}
+	}
+	r.VpcEndpoints = endpoints
+
+	var acls []*networkacl.Event
+	for _, a := range r.NetworkACLs {
+		if a.VpcID == vpcID {
+			acls = append(acls, a)
+		}
+	}
+	r.NetworkACLs = acls
+}
+
+func includes(set map[string]bool, ids []*string) bool {
+	for _, id := range ids {
+		if set[aws.StringValue(id)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ebs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of an ebs volume. Size and iops are
+// left to the aws defaults when zero
+type Spec struct {
+	Name             string
+	AvailabilityZone string
+	VolumeType       string
+	Size             int64
+	Iops             int64
+	Encrypted        bool
+	EncryptionKeyID  string
+	Tags             map[string]string
+}
+
+// Status describes an ebs volume as it is on aws
+type Status struct {
+	ID               string
+	Name             string
+	AvailabilityZone string
+	VolumeType       string
+	Size             int64
+	Iops             int64
+	Encrypted        bool
+	EncryptionKeyID  string
+	Tags             map[string]string
+}
+
+// Client manages ebs volumes through a typed api, the json events are an
+// adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates an ebs volume
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	req := &ec2.CreateVolumeInput{
+		AvailabilityZone: aws.String(s.AvailabilityZone),
+		VolumeType:       aws.String(s.VolumeType),
+	}
+
+	if s.Encrypted {
+		req.Encrypted = aws.Bool(true)
+	}
+
+	if s.Size > 0 {
+		req.Size = aws.Int64(s.Size)
+	}
+
+	if s.Iops > 0 {
+		req.Iops = aws.Int64(s.Iops)
+	}
+
+	if s.EncryptionKeyID != "" {
+		req.KmsKeyId = aws.String(s.EncryptionKeyID)
+	}
+
+	resp, err := svc.CreateVolumeWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(resp)
+	st.Name = s.Name
+	st.Tags = s.Tags
+
+	return st, c.setTags(ctx, svc, st.ID, s.Tags)
+}
+
+// Delete : deletes an ebs volume
+func (c Client) Delete(ctx context.Context, id string) error {
+	svc := c.getEC2Client()
+
+	req := &ec2.DeleteVolumeInput{
+		VolumeId: aws.String(id),
+	}
+
+	_, err := svc.DeleteVolumeWithContext(ctx, req)
+
+	return err
+}
+
+// Find : returns the ebs volumes matching all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	req := &ec2.DescribeVolumesInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := svc.DescribeVolumesWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var volumes []Status
+
+	for _, v := range resp.Volumes {
+		volumes = append(volumes, toStatus(v))
+	}
+
+	return volumes, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toStatus(v *ec2.Volume) Status {
+	tags := mapEC2Tags(v.Tags)
+
+	return Status{
+		ID:               aws.StringValue(v.VolumeId),
+		Name:             tags["Name"],
+		AvailabilityZone: aws.StringValue(v.AvailabilityZone),
+		VolumeType:       aws.StringValue(v.VolumeType),
+		Size:             aws.Int64Value(v.Size),
+		Iops:             aws.Int64Value(v.Iops),
+		Encrypted:        aws.BoolValue(v.Encrypted),
+		EncryptionKeyID:  aws.StringValue(v.KmsKeyId),
+		Tags:             tags,
+	}
+}
@@ -5,16 +5,17 @@
 package ebs
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -41,6 +42,7 @@ type Event struct {
 	ComponentID      string            `json:"_component_id"`
 	State            string            `json:"_state"`
 	Action           string            `json:"_action"`
+	SchemaVersion    int               `json:"_schema_version"`
 	VolumeAWSID      *string           `json:"volume_aws_id"`
 	Name             *string           `json:"name"`
 	AvailabilityZone *string           `json:"availability_zone"`
@@ -87,6 +89,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -97,6 +104,11 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.verify(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -116,6 +128,9 @@ func (ev *Event) Complete() {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
 
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
@@ -153,25 +168,14 @@ func (ev *Event) Find() error {
 
 // Create : Creates a instance object on aws
 func (ev *Event) Create() error {
-	svc := ev.getEC2Client()
-
-	req := &ec2.CreateVolumeInput{
-		AvailabilityZone: ev.AvailabilityZone,
-		VolumeType:       ev.VolumeType,
-		Size:             ev.Size,
-		Iops:             ev.Iops,
-		Encrypted:        ev.Encrypted,
-		KmsKeyId:         ev.EncryptionKeyID,
-	}
-
-	resp, err := svc.CreateVolume(req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	ev.VolumeAWSID = resp.VolumeId
+	ev.VolumeAWSID = aws.String(st.ID)
 
-	return ev.setTags()
+	return nil
 }
 
 // Update : Updates a instance object on aws
@@ -181,18 +185,7 @@ func (ev *Event) Update() error {
 
 // Delete : Deletes a instance object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getEC2Client()
-
-	req := &ec2.DeleteVolumeInput{
-		VolumeId: ev.VolumeAWSID,
-	}
-
-	_, err := svc.DeleteVolume(req)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.VolumeAWSID))
 }
 
 // Get : Gets a instance object on aws
@@ -201,31 +194,34 @@ func (ev *Event) Get() error {
 }
 
 func (ev *Event) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
+	return ev.client().getEC2Client()
 }
 
-func (ev *Event) setTags() error {
-	svc := ev.getEC2Client()
-
-	for key, val := range ev.Tags {
-		req := &ec2.CreateTagsInput{
-			Resources: []*string{ev.VolumeAWSID},
-		}
-
-		req.Tags = append(req.Tags, &ec2.Tag{
-			Key:   &key,
-			Value: &val,
-		})
-
-		_, err := svc.CreateTags(req)
-		if err != nil {
-			return err
-		}
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
 	}
+}
 
-	return nil
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:             aws.StringValue(ev.Name),
+		AvailabilityZone: aws.StringValue(ev.AvailabilityZone),
+		VolumeType:       aws.StringValue(ev.VolumeType),
+		Size:             aws.Int64Value(ev.Size),
+		Iops:             aws.Int64Value(ev.Iops),
+		Encrypted:        aws.BoolValue(ev.Encrypted),
+		EncryptionKeyID:  aws.StringValue(ev.EncryptionKeyID),
+		Tags:             ev.Tags,
+	}
 }
@@ -0,0 +1,138 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ebs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "ebs_volume.create.aws",
+			body:     `{"name":"data","availability_zone":"us-east-1a","volume_type":"io1","size":20,"iops":1000,"tags":{"Name":"data"}}`,
+			expected: "ebs_volume.create.aws.done",
+			save:     map[string]string{"id": "volume_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				v := b.EC2.Volumes[ids["id"]]
+				return v != nil && *v.Size == 20 && *v.Iops == 1000 && len(v.Tags) == 1
+			},
+		},
+		{
+			name:     "update is not supported",
+			subject:  "ebs_volume.update.aws",
+			body:     `{"name":"data","availability_zone":"us-east-1a","volume_type":"io1","volume_aws_id":"$id"}`,
+			expected: "ebs_volume.update.aws.error",
+		},
+		{
+			name:     "find",
+			subject:  "ebs_volume.find.aws",
+			body:     `{"tags":{"Name":"data"}}`,
+			expected: "ebs_volume.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				v := found[0].(map[string]interface{})
+				return v["volume_aws_id"] == ids["id"] && v["volume_type"] == "io1" && v["size"] == 20.0
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "ebs_volume.delete.aws",
+			body:     `{"name":"data","availability_zone":"us-east-1a","volume_type":"io1","volume_aws_id":"$id"}`,
+			expected: "ebs_volume.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.EC2.Volumes) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+		volumes   int
+	}{
+		{
+			name:      "create fails",
+			operation: "CreateVolume",
+			subject:   "ebs_volume.create.aws",
+			body:      `{"name":"data","availability_zone":"us-east-1a","volume_type":"gp2","size":20}`,
+		},
+		{
+			name:      "create fails tagging the volume",
+			operation: "CreateTags",
+			subject:   "ebs_volume.create.aws",
+			body:      `{"name":"data","availability_zone":"us-east-1a","volume_type":"gp2","size":20,"tags":{"Name":"data"}}`,
+			volumes:   1,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteVolume",
+			subject:   "ebs_volume.delete.aws",
+			body:      `{"name":"data","availability_zone":"us-east-1a","volume_type":"gp2","volume_aws_id":"vol-00000001"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, nil)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+
+			if len(b.EC2.Volumes) != tt.volumes {
+				t.Errorf("expected %d volumes, got %d", tt.volumes, len(b.EC2.Volumes))
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ebs
+
+import "github.com/ernestio/ernestaws/schema"
+
+var volume = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "availability_zone", Type: schema.String, Required: true},
+	{Name: "volume_type", Type: schema.String, Required: true, Enum: []string{"standard", "io1", "gp2", "sc1", "st1"}},
+	{Name: "size", Type: schema.Integer, Minimum: schema.Int(1), Maximum: schema.Int(16384)},
+	{Name: "iops", Type: schema.Integer, Minimum: schema.Int(100), Maximum: schema.Int(20000)},
+	{Name: "encrypted", Type: schema.Boolean},
+	{Name: "encryption_key_id", Type: schema.String},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("ebs_volume", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), volume),
+		"delete": schema.Fields(schema.Datacenter(), volume, []schema.Field{
+			{Name: "volume_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
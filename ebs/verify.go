@@ -0,0 +1,23 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ebs
+
+import (
+	"strings"
+
+	"github.com/ernestio/ernestaws/verify"
+)
+
+// verify : checks the event against the live aws metadata
+func (ev *Event) verify() error {
+	if !verify.Enabled() || strings.Split(ev.Subject, ".")[1] != "create" {
+		return nil
+	}
+
+	var c verify.Check
+	c.AvailabilityZone(ev.getEC2Client(), "$.availability_zone", ev.AvailabilityZone)
+
+	return c.Err()
+}
@@ -0,0 +1,599 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package elb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Port describes a listener, forwarding a port of the load balancer to
+// a port of the instances
+type Port struct {
+	FromPort  int64
+	ToPort    int64
+	Protocol  string
+	SSLCertID string
+}
+
+// Spec describes the desired state of a load balancer
+type Spec struct {
+	Name             string
+	Private          bool
+	Ports            []Port
+	InstanceIDs      []string
+	NetworkIDs       []string
+	SecurityGroupIDs []string
+	Tags             map[string]string
+}
+
+// Status describes a load balancer. Create and Update report it as set
+// from the spec, without describing it again
+type Status struct {
+	Name             string
+	DNSName          string
+	Private          bool
+	Ports            []Port
+	InstanceIDs      []string
+	NetworkIDs       []string
+	SecurityGroupIDs []string
+	Tags             map[string]string
+}
+
+// Client manages classic load balancers through a typed api, the json
+// events are an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a load balancer and registers its instances
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getELBClient()
+
+	// Create Loadbalancer
+	req := elb.CreateLoadBalancerInput{
+		LoadBalancerName: aws.String(s.Name),
+		Listeners:        mapListeners(s.Ports),
+		Subnets:          aws.StringSlice(s.NetworkIDs),
+		SecurityGroups:   aws.StringSlice(s.SecurityGroupIDs),
+	}
+
+	if s.Private {
+		req.Scheme = aws.String("internal")
+	}
+
+	resp, err := svc.CreateLoadBalancerWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	// Add instances
+	ireq := elb.RegisterInstancesWithLoadBalancerInput{
+		LoadBalancerName: aws.String(s.Name),
+		Instances:        instancesToRegister(s.InstanceIDs, nil),
+	}
+
+	if len(ireq.Instances) > 0 {
+		_, err = svc.RegisterInstancesWithLoadBalancerWithContext(ctx, &ireq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.setTags(ctx, svc, s.Name, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return s.status(aws.StringValue(resp.DNSName)), nil
+}
+
+// Update : updates the security groups, networks, instances and
+// listeners of a load balancer
+func (c Client) Update(ctx context.Context, name string, s Spec) (Status, error) {
+	svc := c.getELBClient()
+
+	lb, err := c.describe(ctx, svc, name)
+	if err != nil {
+		return Status{}, err
+	}
+
+	// Update ports, certs and security groups & networks
+	err = c.updateELBSecurityGroups(ctx, svc, lb, s.SecurityGroupIDs)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.updateELBNetworks(ctx, svc, lb, s.NetworkIDs)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.updateELBInstances(ctx, svc, lb, s.InstanceIDs)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.updateELBListeners(ctx, svc, lb, s.Ports)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.setTags(ctx, svc, name, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return s.status(aws.StringValue(lb.DNSName)), nil
+}
+
+// Delete : deletes a load balancer and waits for its interfaces on the
+// networks to be removed
+func (c Client) Delete(ctx context.Context, name string, networkIDs []string) error {
+	svc := c.getELBClient()
+
+	// Delete Loadbalancer
+	req := elb.DeleteLoadBalancerInput{
+		LoadBalancerName: aws.String(name),
+	}
+
+	_, err := svc.DeleteLoadBalancerWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	err = c.waitForELBRemoval(ctx, svc, name)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range networkIDs {
+		err = c.waitForInterfaceRemoval(ctx, id, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find : returns the load balancers matching all the tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getELBClient()
+
+	resp, err := svc.DescribeLoadBalancersWithContext(ctx, &elb.DescribeLoadBalancersInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var lbs []Status
+
+	for _, e := range resp.LoadBalancerDescriptions {
+		req := &elb.DescribeTagsInput{
+			LoadBalancerNames: []*string{e.LoadBalancerName},
+		}
+
+		resp, err := svc.DescribeTagsWithContext(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		st := toStatus(e, resp.TagDescriptions[0].Tags)
+
+		if tagsMatch(tags, st.Tags) {
+			lbs = append(lbs, st)
+		}
+	}
+
+	return lbs, nil
+}
+
+func (c Client) getELBClient() *elb.ELB {
+	return elb.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) describe(ctx context.Context, svc *elb.ELB, name string) (*elb.LoadBalancerDescription, error) {
+	req := elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: []*string{aws.String(name)},
+	}
+
+	resp, err := svc.DescribeLoadBalancersWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.LoadBalancerDescriptions) != 1 {
+		return nil, errors.New("Could not find ELB")
+	}
+
+	return resp.LoadBalancerDescriptions[0], nil
+}
+
+func (c Client) updateELBInstances(ctx context.Context, svc *elb.ELB, lb *elb.LoadBalancerDescription, ni []string) error {
+	var err error
+
+	// Instances to remove
+	drreq := elb.DeregisterInstancesFromLoadBalancerInput{
+		LoadBalancerName: lb.LoadBalancerName,
+		Instances:        instancesToDeregister(ni, lb.Instances),
+	}
+	if len(drreq.Instances) > 0 {
+		_, err = svc.DeregisterInstancesFromLoadBalancerWithContext(ctx, &drreq)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Instances to add
+	rreq := elb.RegisterInstancesWithLoadBalancerInput{
+		LoadBalancerName: lb.LoadBalancerName,
+		Instances:        instancesToRegister(ni, lb.Instances),
+	}
+
+	if len(rreq.Instances) > 0 {
+		_, err = svc.RegisterInstancesWithLoadBalancerWithContext(ctx, &rreq)
+	}
+
+	return err
+}
+
+func (c Client) updateELBListeners(ctx context.Context, svc *elb.ELB, lb *elb.LoadBalancerDescription, nl []Port) error {
+	var err error
+
+	dlreq := elb.DeleteLoadBalancerListenersInput{
+		LoadBalancerName:  lb.LoadBalancerName,
+		LoadBalancerPorts: listenersToDelete(nl, lb.ListenerDescriptions),
+	}
+
+	if len(dlreq.LoadBalancerPorts) > 0 {
+		_, err = svc.DeleteLoadBalancerListenersWithContext(ctx, &dlreq)
+		if err != nil {
+			return err
+		}
+	}
+
+	clreq := elb.CreateLoadBalancerListenersInput{
+		LoadBalancerName: lb.LoadBalancerName,
+		Listeners:        listenersToCreate(nl, lb.ListenerDescriptions),
+	}
+
+	if len(clreq.Listeners) > 0 {
+		_, err = svc.CreateLoadBalancerListenersWithContext(ctx, &clreq)
+	}
+
+	return err
+}
+
+func (c Client) updateELBNetworks(ctx context.Context, svc *elb.ELB, lb *elb.LoadBalancerDescription, nl []string) error {
+	var err error
+
+	dsreq := elb.DetachLoadBalancerFromSubnetsInput{
+		LoadBalancerName: lb.LoadBalancerName,
+		Subnets:          subnetsToDetach(nl, lb.Subnets),
+	}
+
+	if len(dsreq.Subnets) > 0 {
+		_, err = svc.DetachLoadBalancerFromSubnetsWithContext(ctx, &dsreq)
+		if err != nil {
+			return err
+		}
+	}
+
+	csreq := elb.AttachLoadBalancerToSubnetsInput{
+		LoadBalancerName: lb.LoadBalancerName,
+		Subnets:          subnetsToAttach(nl, lb.Subnets),
+	}
+
+	if len(csreq.Subnets) > 0 {
+		_, err = svc.AttachLoadBalancerToSubnetsWithContext(ctx, &csreq)
+	}
+
+	return err
+}
+
+func (c Client) updateELBSecurityGroups(ctx context.Context, svc *elb.ELB, lb *elb.LoadBalancerDescription, nsg []string) error {
+	var err error
+
+	req := elb.ApplySecurityGroupsToLoadBalancerInput{
+		LoadBalancerName: lb.LoadBalancerName,
+		SecurityGroups:   aws.StringSlice(nsg),
+	}
+
+	if len(req.SecurityGroups) > 0 {
+		_, err = svc.ApplySecurityGroupsToLoadBalancerWithContext(ctx, &req)
+	}
+
+	return err
+}
+
+func portInUse(listeners []*elb.ListenerDescription, port int64) bool {
+	for _, l := range listeners {
+		if *l.Listener.LoadBalancerPort == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+func portRemoved(ports []Port, listener *elb.ListenerDescription) bool {
+	for _, p := range ports {
+		if p.FromPort == *listener.Listener.LoadBalancerPort {
+			return false
+		}
+	}
+
+	return true
+}
+
+func instancesToRegister(newInstances []string, currentInstances []*elb.Instance) []*elb.Instance {
+	var i []*elb.Instance
+
+	for _, instance := range newInstances {
+		exists := false
+		for _, ci := range currentInstances {
+			if instance == *ci.InstanceId {
+				exists = true
+			}
+		}
+		if exists != true {
+			i = append(i, &elb.Instance{InstanceId: aws.String(instance)})
+		}
+	}
+
+	return i
+}
+
+func instancesToDeregister(newInstances []string, currentInstances []*elb.Instance) []*elb.Instance {
+	var i []*elb.Instance
+
+	for _, ci := range currentInstances {
+		exists := false
+		for _, instance := range newInstances {
+			if *ci.InstanceId == instance {
+				exists = true
+			}
+		}
+		if exists != true {
+			i = append(i, &elb.Instance{InstanceId: ci.InstanceId})
+		}
+	}
+
+	return i
+}
+
+func listenersToDelete(newListeners []Port, currentListeners []*elb.ListenerDescription) []*int64 {
+	var l []*int64
+
+	for _, cl := range currentListeners {
+		if portRemoved(newListeners, cl) {
+			l = append(l, cl.Listener.LoadBalancerPort)
+		}
+	}
+
+	return l
+}
+
+func listenersToCreate(newListeners []Port, currentListeners []*elb.ListenerDescription) []*elb.Listener {
+	var l []*elb.Listener
+
+	for _, listener := range newListeners {
+		if portInUse(currentListeners, listener.FromPort) != true {
+			l = append(l, listener.listener())
+		}
+	}
+
+	return l
+}
+
+func subnetsToAttach(newSubnets []string, currentSubnets []*string) []*string {
+	var s []*string
+
+	for _, subnet := range newSubnets {
+		exists := false
+		for _, cs := range currentSubnets {
+			if subnet == *cs {
+				exists = true
+			}
+		}
+		if exists != true {
+			s = append(s, aws.String(subnet))
+		}
+	}
+
+	return s
+}
+
+func subnetsToDetach(newSubnets []string, currentSubnets []*string) []*string {
+	var s []*string
+
+	for _, cs := range currentSubnets {
+		exists := false
+		for _, subnet := range newSubnets {
+			if *cs == subnet {
+				exists = true
+			}
+		}
+		if exists != true {
+			s = append(s, cs)
+		}
+	}
+
+	return s
+}
+
+func (c Client) waitForELBRemoval(ctx context.Context, svc *elb.ELB, name string) error {
+	for {
+		req := elb.DescribeLoadBalancersInput{
+			LoadBalancerNames: []*string{aws.String(name)},
+		}
+
+		resp, err := svc.DescribeLoadBalancersWithContext(ctx, &req)
+		if err != nil {
+			return nil
+		}
+
+		if len(resp.LoadBalancerDescriptions) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c Client) waitForInterfaceRemoval(ctx context.Context, networkID, name string) error {
+	svc := c.getEC2Client()
+
+	for {
+		f := []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("subnet-id"),
+				Values: []*string{aws.String(networkID)},
+			},
+		}
+
+		req := ec2.DescribeNetworkInterfacesInput{
+			Filters: f,
+		}
+
+		resp, err := svc.DescribeNetworkInterfacesWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+
+		if hasELBAttachment(resp.NetworkInterfaces, name) != true {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func hasELBAttachment(ifaces []*ec2.NetworkInterface, name string) bool {
+	for _, iface := range ifaces {
+		if iface.Description != nil {
+			if *iface.Description == "ELB "+name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c Client) setTags(ctx context.Context, svc *elb.ELB, name string, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	req := &elb.AddTagsInput{
+		LoadBalancerNames: []*string{aws.String(name)},
+	}
+
+	for key, val := range tags {
+		req.Tags = append(req.Tags, &elb.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+	}
+
+	_, err := svc.AddTagsWithContext(ctx, req)
+
+	return err
+}
+
+func (s Spec) status(dnsName string) Status {
+	return Status{
+		Name:             s.Name,
+		DNSName:          dnsName,
+		Private:          s.Private,
+		Ports:            s.Ports,
+		InstanceIDs:      s.InstanceIDs,
+		NetworkIDs:       s.NetworkIDs,
+		SecurityGroupIDs: s.SecurityGroupIDs,
+		Tags:             s.Tags,
+	}
+}
+
+func (p Port) listener() *elb.Listener {
+	l := &elb.Listener{
+		Protocol:         aws.String(p.Protocol),
+		LoadBalancerPort: aws.Int64(p.FromPort),
+		InstancePort:     aws.Int64(p.ToPort),
+		InstanceProtocol: aws.String(p.Protocol),
+	}
+
+	if p.SSLCertID != "" {
+		l.SSLCertificateId = aws.String(p.SSLCertID)
+	}
+
+	return l
+}
+
+func mapListeners(ports []Port) []*elb.Listener {
+	var l []*elb.Listener
+
+	for _, port := range ports {
+		l = append(l, port.listener())
+	}
+
+	return l
+}
+
+func tagsMatch(qt, rt map[string]string) bool {
+	for k, v := range qt {
+		if rt[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func toStatus(e *elb.LoadBalancerDescription, tags []*elb.Tag) Status {
+	st := Status{
+		Name:             aws.StringValue(e.LoadBalancerName),
+		DNSName:          aws.StringValue(e.DNSName),
+		Private:          aws.StringValue(e.Scheme) == "internal",
+		NetworkIDs:       aws.StringValueSlice(e.Subnets),
+		SecurityGroupIDs: aws.StringValueSlice(e.SecurityGroups),
+		Tags:             mapELBTags(tags),
+	}
+
+	for _, ld := range e.ListenerDescriptions {
+		st.Ports = append(st.Ports, Port{
+			FromPort:  aws.Int64Value(ld.Listener.LoadBalancerPort),
+			ToPort:    aws.Int64Value(ld.Listener.InstancePort),
+			Protocol:  aws.StringValue(ld.Listener.Protocol),
+			SSLCertID: aws.StringValue(ld.Listener.SSLCertificateId),
+		})
+	}
+
+	for _, i := range e.Instances {
+		st.InstanceIDs = append(st.InstanceIDs, aws.StringValue(i.InstanceId))
+	}
+
+	return st
+}
@@ -5,18 +5,16 @@
 package elb
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -51,6 +49,7 @@ type Event struct {
 	ComponentID         string            `json:"_component_id"`
 	State               string            `json:"_state"`
 	Action              string            `json:"_action"`
+	SchemaVersion       int               `json:"_schema_version"`
 	Name                *string           `json:"name"`
 	IsPrivate           *bool             `json:"is_private"`
 	Listeners           []Listener        `json:"listeners"`
@@ -100,6 +99,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -110,6 +114,11 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.preflight(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -129,6 +138,10 @@ func (ev *Event) Complete() {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -173,118 +186,25 @@ func (ev *Event) Find() error {
 
 // Create : Creates a elb object on aws
 func (ev *Event) Create() error {
-	svc := ev.getELBClient()
-
-	// Create Loadbalancer
-	req := elb.CreateLoadBalancerInput{
-		LoadBalancerName: ev.Name,
-		Listeners:        ev.mapListeners(),
-		Subnets:          ev.NetworkAWSIDs,
-		SecurityGroups:   ev.SecurityGroupAWSIDs,
-	}
-
-	if ev.IsPrivate != nil {
-		if *ev.IsPrivate {
-			req.Scheme = aws.String("internal")
-		}
-	}
-
-	resp, err := svc.CreateLoadBalancer(&req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	ev.DNSName = resp.DNSName
-
-	// Add instances
-	ireq := elb.RegisterInstancesWithLoadBalancerInput{
-		LoadBalancerName: ev.Name,
-	}
-
-	for _, instance := range ev.InstanceAWSIDs {
-		ireq.Instances = append(ireq.Instances, &elb.Instance{
-			InstanceId: instance,
-		})
-	}
-
-	_, err = svc.RegisterInstancesWithLoadBalancer(&ireq)
-	if err != nil {
-		return err
-	}
+	ev.DNSName = aws.String(st.DNSName)
 
-	return ev.setTags()
+	return nil
 }
 
 // Update : Updates a elb object on aws
 func (ev *Event) Update() error {
-	svc := ev.getELBClient()
-
-	req := elb.DescribeLoadBalancersInput{
-		LoadBalancerNames: []*string{ev.Name},
-	}
-
-	resp, err := svc.DescribeLoadBalancers(&req)
-	if err != nil {
-		return err
-	}
-
-	if len(resp.LoadBalancerDescriptions) != 1 {
-		return errors.New("Could not find ELB")
-	}
-
-	lb := resp.LoadBalancerDescriptions[0]
-
-	// Update ports, certs and security groups & networks
-	err = ev.updateELBSecurityGroups(svc, lb, ev.SecurityGroupAWSIDs)
-	if err != nil {
-		return err
-	}
-
-	err = ev.updateELBNetworks(svc, lb, ev.NetworkAWSIDs)
-	if err != nil {
-		return err
-	}
-
-	err = ev.updateELBInstances(svc, lb, ev.InstanceAWSIDs)
-	if err != nil {
-		return err
-	}
-
-	err = ev.updateELBListeners(svc, lb, ev.Listeners)
-	if err != nil {
-		return err
-	}
-
-	return ev.setTags()
+	_, err := ev.client().Update(context.Background(), aws.StringValue(ev.Name), ev.spec())
+	return err
 }
 
 // Delete : Deletes a elb object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getELBClient()
-
-	// Delete Loadbalancer
-	req := elb.DeleteLoadBalancerInput{
-		LoadBalancerName: ev.Name,
-	}
-
-	_, err := svc.DeleteLoadBalancer(&req)
-	if err != nil {
-		return err
-	}
-
-	err = ev.waitForELBRemoval(ev.Name)
-	if err != nil {
-		return err
-	}
-
-	for _, id := range ev.NetworkAWSIDs {
-		err = ev.waitForInterfaceRemoval(id)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.Name), aws.StringValueSlice(ev.NetworkAWSIDs))
 }
 
 // Get : Gets a elb object on aws
@@ -292,341 +212,40 @@ func (ev *Event) Get() error {
 	return errors.New(ev.Subject + " not supported")
 }
 
-func (ev *Event) mapListeners() []*elb.Listener {
-	var l []*elb.Listener
-
-	for _, port := range ev.Listeners {
-		l = append(l, &elb.Listener{
-			Protocol:         port.Protocol,
-			LoadBalancerPort: port.FromPort,
-			InstancePort:     port.ToPort,
-			InstanceProtocol: port.Protocol,
-			SSLCertificateId: port.SSLCertID,
-		})
-	}
-
-	return l
-}
-
-func (ev *Event) getELBClient() *elb.ELB {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return elb.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func (ev *Event) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func (ev *Event) updateELBInstances(svc *elb.ELB, lb *elb.LoadBalancerDescription, ni []*string) error {
-	var err error
-
-	// Instances to remove
-	drreq := elb.DeregisterInstancesFromLoadBalancerInput{
-		LoadBalancerName: lb.LoadBalancerName,
-		Instances:        ev.instancesToDeregister(ni, lb.Instances),
-	}
-	if len(drreq.Instances) > 0 {
-		_, err = svc.DeregisterInstancesFromLoadBalancer(&drreq)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Instances to add
-	rreq := elb.RegisterInstancesWithLoadBalancerInput{
-		LoadBalancerName: lb.LoadBalancerName,
-		Instances:        ev.instancesToRegister(ni, lb.Instances),
-	}
-
-	if len(rreq.Instances) > 0 {
-		_, err = svc.RegisterInstancesWithLoadBalancer(&rreq)
-	}
-
-	return err
-}
-
-func (ev *Event) updateELBListeners(svc *elb.ELB, lb *elb.LoadBalancerDescription, nl []Listener) error {
-	var err error
-
-	dlreq := elb.DeleteLoadBalancerListenersInput{
-		LoadBalancerName:  lb.LoadBalancerName,
-		LoadBalancerPorts: ev.listenersToDelete(nl, lb.ListenerDescriptions),
-	}
-
-	if len(dlreq.LoadBalancerPorts) > 0 {
-		_, err = svc.DeleteLoadBalancerListeners(&dlreq)
-		if err != nil {
-			return err
-		}
-	}
-
-	clreq := elb.CreateLoadBalancerListenersInput{
-		LoadBalancerName: lb.LoadBalancerName,
-		Listeners:        ev.listenersToCreate(nl, lb.ListenerDescriptions),
-	}
-
-	if len(clreq.Listeners) > 0 {
-		_, err = svc.CreateLoadBalancerListeners(&clreq)
-	}
-
-	return err
-}
-
-func (ev *Event) updateELBNetworks(svc *elb.ELB, lb *elb.LoadBalancerDescription, nl []*string) error {
-	var err error
-
-	dsreq := elb.DetachLoadBalancerFromSubnetsInput{
-		LoadBalancerName: lb.LoadBalancerName,
-		Subnets:          ev.subnetsToDetach(nl, lb.Subnets),
-	}
-
-	if len(dsreq.Subnets) > 0 {
-		_, err = svc.DetachLoadBalancerFromSubnets(&dsreq)
-		if err != nil {
-			return err
-		}
-	}
-
-	csreq := elb.AttachLoadBalancerToSubnetsInput{
-		LoadBalancerName: lb.LoadBalancerName,
-		Subnets:          ev.subnetsToAttach(nl, lb.Subnets),
-	}
-
-	if len(csreq.Subnets) > 0 {
-		_, err = svc.AttachLoadBalancerToSubnets(&csreq)
-	}
-
-	return err
-}
-
-func (ev *Event) updateELBSecurityGroups(svc *elb.ELB, lb *elb.LoadBalancerDescription, nsg []*string) error {
-	var err error
-
-	req := elb.ApplySecurityGroupsToLoadBalancerInput{
-		LoadBalancerName: lb.LoadBalancerName,
-		SecurityGroups:   nsg,
-	}
-
-	if len(req.SecurityGroups) > 0 {
-		_, err = svc.ApplySecurityGroupsToLoadBalancer(&req)
-	}
-
-	return err
-}
-
-func (ev *Event) portInUse(listeners []*elb.ListenerDescription, port *int64) bool {
-	for _, l := range listeners {
-		if *l.Listener.LoadBalancerPort == *port {
-			return true
-		}
-	}
-
-	return false
-}
-
-func (ev *Event) portRemoved(ports []Listener, listener *elb.ListenerDescription) bool {
-	for _, p := range ports {
-		if *p.FromPort == *listener.Listener.LoadBalancerPort {
-			return false
-		}
-	}
-
-	return true
-}
-
-func (ev *Event) instancesToRegister(newInstances []*string, currentInstances []*elb.Instance) []*elb.Instance {
-	var i []*elb.Instance
-
-	for _, instance := range newInstances {
-		exists := false
-		for _, ci := range currentInstances {
-			if *instance == *ci.InstanceId {
-				exists = true
-			}
-		}
-		if exists != true {
-			i = append(i, &elb.Instance{InstanceId: instance})
-		}
-	}
-
-	return i
-}
-
-func (ev *Event) instancesToDeregister(newInstances []*string, currentInstances []*elb.Instance) []*elb.Instance {
-	var i []*elb.Instance
-
-	for _, ci := range currentInstances {
-		exists := false
-		for _, instance := range newInstances {
-			if *ci.InstanceId == *instance {
-				exists = true
-			}
-		}
-		if exists != true {
-			i = append(i, &elb.Instance{InstanceId: ci.InstanceId})
-		}
-	}
-
-	return i
-}
-
-func (ev *Event) listenersToDelete(newListeners []Listener, currentListeners []*elb.ListenerDescription) []*int64 {
-	var l []*int64
-
-	for _, cl := range currentListeners {
-		if ev.portRemoved(newListeners, cl) {
-			l = append(l, cl.Listener.LoadBalancerPort)
-		}
-	}
-
-	return l
-}
-
-func (ev *Event) listenersToCreate(newListeners []Listener, currentListeners []*elb.ListenerDescription) []*elb.Listener {
-	var l []*elb.Listener
-
-	for _, listener := range newListeners {
-
-		if ev.portInUse(currentListeners, listener.FromPort) != true {
-			l = append(l, &elb.Listener{
-				Protocol:         listener.Protocol,
-				LoadBalancerPort: listener.FromPort,
-				InstancePort:     listener.ToPort,
-				InstanceProtocol: listener.Protocol,
-				SSLCertificateId: listener.SSLCertID,
-			})
-		}
-	}
-
-	return l
-}
-
-func (ev *Event) subnetsToAttach(newSubnets []*string, currentSubnets []*string) []*string {
-	var s []*string
-
-	for _, subnet := range newSubnets {
-		exists := false
-		for _, cs := range currentSubnets {
-			if *subnet == *cs {
-				exists = true
-			}
-		}
-		if exists != true {
-			s = append(s, subnet)
-		}
-	}
-
-	return s
-}
-
-func (ev *Event) subnetsToDetach(newSubnets []*string, currentSubnets []*string) []*string {
-	var s []*string
-
-	for _, cs := range currentSubnets {
-		exists := false
-		for _, subnet := range newSubnets {
-			if *cs == *subnet {
-				exists = true
-			}
-		}
-		if exists != true {
-			s = append(s, cs)
-		}
-	}
-
-	return s
-}
-
-func (ev *Event) waitForELBRemoval(name *string) error {
-	for {
-		resp, err := ev.getELBs(name)
-		if err != nil {
-			return nil
-		}
-
-		if len(resp.LoadBalancerDescriptions) == 0 {
-			return nil
-		}
-
-		time.Sleep(time.Second)
-	}
-}
-
-func (ev *Event) getELBs(name *string) (*elb.DescribeLoadBalancersOutput, error) {
-	svc := ev.getELBClient()
-
-	req := elb.DescribeLoadBalancersInput{
-		LoadBalancerNames: []*string{name},
-	}
-
-	return svc.DescribeLoadBalancers(&req)
-}
-
-func (ev *Event) waitForInterfaceRemoval(networkID *string) error {
-	for {
-		resp, err := ev.getNetworkInterfaces(networkID)
-		if err != nil {
-			return err
-		}
-
-		if hasELBAttachment(resp.NetworkInterfaces, ev.Name) != true {
-			return nil
-		}
-
-		time.Sleep(time.Second)
-	}
-}
-
-func hasELBAttachment(ifaces []*ec2.NetworkInterface, name *string) bool {
-	for _, iface := range ifaces {
-		if iface.Description != nil {
-			if *iface.Description == "ELB "+*name {
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
-func (ev *Event) getNetworkInterfaces(networkID *string) (*ec2.DescribeNetworkInterfacesOutput, error) {
-	svc := ev.getEC2Client()
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("subnet-id"),
-			Values: []*string{networkID},
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
 		},
 	}
-
-	req := ec2.DescribeNetworkInterfacesInput{
-		Filters: f,
-	}
-
-	return svc.DescribeNetworkInterfaces(&req)
 }
 
-func (ev *Event) setTags() error {
-	svc := ev.getELBClient()
-
-	req := &elb.AddTagsInput{
-		LoadBalancerNames: []*string{ev.Name},
+func (ev *Event) spec() Spec {
+	s := Spec{
+		Name:             aws.StringValue(ev.Name),
+		Private:          aws.BoolValue(ev.IsPrivate),
+		InstanceIDs:      aws.StringValueSlice(ev.InstanceAWSIDs),
+		NetworkIDs:       aws.StringValueSlice(ev.NetworkAWSIDs),
+		SecurityGroupIDs: aws.StringValueSlice(ev.SecurityGroupAWSIDs),
+		Tags:             ev.Tags,
 	}
 
-	for key, val := range ev.Tags {
-		req.Tags = append(req.Tags, &elb.Tag{
-			Key:   &key,
-			Value: &val,
+	for _, l := range ev.Listeners {
+		s.Ports = append(s.Ports, Port{
+			FromPort:  aws.Int64Value(l.FromPort),
+			ToPort:    aws.Int64Value(l.ToPort),
+			Protocol:  aws.StringValue(l.Protocol),
+			SSLCertID: aws.StringValue(l.SSLCertID),
 		})
 	}
 
-	_, err := svc.AddTags(req)
-
-	return err
+	return s
 }
@@ -0,0 +1,203 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package elb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+// setup : creates a vpc with two networks, a security group and an
+// instance on each network
+func setup(t *testing.T, b *awsfake.Backend) map[string]string {
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	for i, name := range []string{"neta", "netb"} {
+		var out ec2.CreateSubnetOutput
+		if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(ids["vpc"]), CidrBlock: aws.String(fmt.Sprintf("10.0.%d.0/24", i))}, &out); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = *out.Subnet.SubnetId
+	}
+
+	var sg ec2.CreateSecurityGroupOutput
+	if err := b.EC2.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{VpcId: aws.String(ids["vpc"]), GroupName: aws.String("web"), Description: aws.String("web")}, &sg); err != nil {
+		t.Fatal(err)
+	}
+	ids["sg"] = *sg.GroupId
+
+	for name, net := range map[string]string{"ia": ids["neta"], "ib": ids["netb"]} {
+		var out ec2.Reservation
+		if err := b.EC2.RunInstances(&ec2.RunInstancesInput{ImageId: aws.String("ami-1"), SubnetId: aws.String(net), MinCount: aws.Int64(1), MaxCount: aws.Int64(1)}, &out); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = *out.Instances[0].InstanceId
+	}
+
+	return ids
+}
+
+func ports(lb *elb.LoadBalancerDescription) []int64 {
+	var p []int64
+
+	for _, l := range lb.ListenerDescriptions {
+		p = append(p, *l.Listener.LoadBalancerPort)
+	}
+
+	sort.Slice(p, func(i, j int) bool { return p[i] < p[j] })
+
+	return p
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "elb.create.aws",
+			body:     `{"name":"web","is_private":true,"instance_aws_ids":["$ia"],"network_aws_ids":["$neta"],"security_group_aws_ids":["$sg"],"tags":{"Name":"web"},"listeners":[{"from_port":80,"to_port":8080,"protocol":"HTTP"}]}`,
+			expected: "elb.create.aws.done",
+			check: func(res map[string]interface{}) bool {
+				lb := b.ELB.LoadBalancers["web"]
+				return res["dns_name"] == *lb.DNSName && *lb.Scheme == "internal" &&
+					len(lb.Instances) == 1 && *lb.Instances[0].InstanceId == ids["ia"] &&
+					reflect.DeepEqual(ports(lb), []int64{80}) && len(b.ELB.Tags["web"]) == 1
+			},
+		},
+		{
+			name:     "update moves the instances, networks and listeners",
+			subject:  "elb.update.aws",
+			body:     `{"name":"web","instance_aws_ids":["$ib"],"network_aws_ids":["$netb"],"security_group_aws_ids":["$sg"],"tags":{"Name":"web"},"listeners":[{"from_port":443,"to_port":8080,"protocol":"TCP"}]}`,
+			expected: "elb.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				lb := b.ELB.LoadBalancers["web"]
+				return len(lb.Instances) == 1 && *lb.Instances[0].InstanceId == ids["ib"] &&
+					len(lb.Subnets) == 1 && *lb.Subnets[0] == ids["netb"] &&
+					reflect.DeepEqual(ports(lb), []int64{443})
+			},
+		},
+		{
+			name:     "find",
+			subject:  "elb.find.aws",
+			body:     `{"tags":{"Name":"web"}}`,
+			expected: "elb.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				lb := found[0].(map[string]interface{})
+				listeners := lb["listeners"].([]interface{})
+				return lb["name"] == "web" && len(listeners) == 1 && listeners[0].(map[string]interface{})["from_port"] == 443.0
+			},
+		},
+		{
+			name:     "delete waits for the interfaces to be removed",
+			subject:  "elb.delete.aws",
+			body:     `{"name":"web","network_aws_ids":["$netb"]}`,
+			expected: "elb.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return b.ELB.LoadBalancers["web"] == nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails",
+			operation: "CreateLoadBalancer",
+			subject:   "elb.create.aws",
+			body:      `{"name":"db","instance_aws_ids":["$ia"],"tags":{"Name":"db"},"network_aws_ids":["$neta"],"listeners":[{"from_port":5432,"to_port":5432,"protocol":"TCP"}]}`,
+		},
+		{
+			name:      "create fails registering the instances",
+			operation: "RegisterInstancesWithLoadBalancer",
+			subject:   "elb.create.aws",
+			body:      `{"name":"db","instance_aws_ids":["$ia"],"tags":{"Name":"db"},"network_aws_ids":["$neta"],"listeners":[{"from_port":5432,"to_port":5432,"protocol":"TCP"}]}`,
+		},
+		{
+			name:      "update fails replacing the listeners",
+			operation: "DeleteLoadBalancerListeners",
+			subject:   "elb.update.aws",
+			body:      `{"name":"web","instance_aws_ids":["$ia"],"tags":{"Name":"web"},"network_aws_ids":["$neta"],"listeners":[{"from_port":443,"to_port":80,"protocol":"TCP"}]}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteLoadBalancer",
+			subject:   "elb.delete.aws",
+			body:      `{"name":"web","network_aws_ids":["$neta"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := setup(t, b)
+
+			awsfake.Run(t, New, "elb.create.aws", `{"name":"web","instance_aws_ids":["$ia"],"tags":{"Name":"web"},"network_aws_ids":["$neta"],"listeners":[{"from_port":80,"to_port":80,"protocol":"HTTP"}]}`, ids)
+
+			b.Fail("elasticloadbalancing", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
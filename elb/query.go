@@ -5,14 +5,15 @@
 package elb
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/elb"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -50,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -108,49 +119,30 @@ func (col *Collection) Delete() error {
 
 // Find : Find elbs on aws
 func (col *Collection) Find() error {
-	svc := col.getELBClient()
-
-	resp, err := svc.DescribeLoadBalancers(nil)
+	lbs, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, e := range resp.LoadBalancerDescriptions {
-		req := &elb.DescribeTagsInput{
-			LoadBalancerNames: []*string{e.LoadBalancerName},
-		}
-
-		resp, err := svc.DescribeTags(req)
-		if err != nil {
-			return err
-		}
-
-		event := toEvent(e, resp.TagDescriptions[0].Tags)
-
-		if tagsMatch(col.Tags, event.Tags) {
-			col.Results = append(col.Results, event)
-		}
+	for _, st := range lbs {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getELBClient() *elb.ELB {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return elb.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func tagsMatch(qt, rt map[string]string) bool {
-	for k, v := range qt {
-		if rt[k] != v {
-			return false
-		}
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
 	}
-
-	return true
 }
 
 func mapELBTags(input []*elb.Tag) map[string]string {
@@ -163,64 +155,34 @@ func mapELBTags(input []*elb.Tag) map[string]string {
 	return t
 }
 
-func mapELBListeners(input []*elb.ListenerDescription) []Listener {
-	var listeners []Listener
-
-	for _, ld := range input {
-		listeners = append(listeners, Listener{
-			FromPort:  ld.Listener.LoadBalancerPort,
-			ToPort:    ld.Listener.InstancePort,
-			Protocol:  ld.Listener.Protocol,
-			SSLCertID: ld.Listener.SSLCertificateId,
-		})
-	}
-
-	return listeners
-}
-
-func mapELBSecurityGroups(input []*string) []*string {
-	var sgs []*string
-
-	for _, sg := range input {
-		sgs = append(sgs, sg)
-	}
-
-	return sgs
-}
-
-func mapELBInstances(input []*elb.Instance) []*string {
-	var instances []*string
-
-	for _, i := range input {
-		instances = append(instances, i.InstanceId)
+// toEvent converts a load balancer to an ernest event
+func toEvent(st Status) *Event {
+	e := &Event{
+		ProviderType:        "aws",
+		ComponentType:       "elb",
+		ComponentID:         "elb::" + st.Name,
+		Name:                aws.String(st.Name),
+		DNSName:             aws.String(st.DNSName),
+		IsPrivate:           aws.Bool(st.Private),
+		InstanceAWSIDs:      aws.StringSlice(st.InstanceIDs),
+		NetworkAWSIDs:       aws.StringSlice(st.NetworkIDs),
+		SecurityGroupAWSIDs: aws.StringSlice(st.SecurityGroupIDs),
+		Tags:                st.Tags,
 	}
 
-	return instances
-}
+	for _, p := range st.Ports {
+		l := Listener{
+			FromPort: aws.Int64(p.FromPort),
+			ToPort:   aws.Int64(p.ToPort),
+			Protocol: aws.String(p.Protocol),
+		}
 
-func mapELBSubnets(input []*string) []*string {
-	var subnets []*string
+		if p.SSLCertID != "" {
+			l.SSLCertID = aws.String(p.SSLCertID)
+		}
 
-	for _, s := range input {
-		subnets = append(subnets, s)
+		e.Listeners = append(e.Listeners, l)
 	}
 
-	return subnets
-}
-
-// toEvent converts an ec2 subnet object to an ernest event
-func toEvent(e *elb.LoadBalancerDescription, tags []*elb.Tag) *Event {
-	return &Event{
-		ProviderType:        "aws",
-		ComponentType:       "elb",
-		ComponentID:         "elb::" + *e.LoadBalancerName,
-		Name:                e.LoadBalancerName,
-		DNSName:             e.DNSName,
-		Listeners:           mapELBListeners(e.ListenerDescriptions),
-		InstanceAWSIDs:      mapELBInstances(e.Instances),
-		NetworkAWSIDs:       mapELBSubnets(e.Subnets),
-		SecurityGroupAWSIDs: mapELBSecurityGroups(e.SecurityGroups),
-		Tags:                mapELBTags(tags),
-		//ELBIsPrivate: *e.,
-	}
+	return e
 }
@@ -0,0 +1,56 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package elb
+
+import (
+	"testing"
+
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/awsreplay"
+)
+
+const datacenter = `"datacenter_region":"eu-west-1","aws_access_key_id":"AKIAEXAMPLE","aws_secret_access_key":"secret"`
+
+// TestReplayListenerChanges : replays the listener changes of an update.
+// The fixture is synthetic, recorded against the awsfake backend rather
+// than a real aws account, so its ids are sequential
+func TestReplayListenerChanges(t *testing.T) {
+	rec, err := awsreplay.New("testdata/elb-listeners.json", awsreplay.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Install()
+	defer rec.Stop()
+
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+	}{
+		{
+			name:    "replaces the removed listener",
+			subject: "elb.update.aws",
+			body:    `{` + datacenter + `,"name":"web","instance_aws_ids":["i-0000000e"],"network_aws_ids":["subnet-00000008"],"security_group_aws_ids":["sg-0000000d"],"tags":{"Name":"web"},"listeners":[{"from_port":80,"to_port":80,"protocol":"HTTP"},{"from_port":8080,"to_port":8080,"protocol":"TCP"}]}`,
+		},
+		{
+			name:    "keeps unchanged listeners",
+			subject: "elb.update.aws",
+			body:    `{` + datacenter + `,"name":"web","instance_aws_ids":["i-0000000e"],"network_aws_ids":["subnet-00000008"],"security_group_aws_ids":["sg-0000000d"],"tags":{"Name":"web"},"listeners":[{"from_port":80,"to_port":80,"protocol":"HTTP"},{"from_port":8080,"to_port":8080,"protocol":"TCP"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		ev := New(tt.subject, []byte(tt.body), "")
+
+		subject, body := ernestaws.Handle(&ev)
+		if subject != tt.subject+".done" {
+			t.Fatalf("%s: expected %s.done, got %s: %s", tt.name, tt.subject, subject, body)
+		}
+	}
+
+	if unused := rec.Unused(); len(unused) > 0 {
+		t.Errorf("%d recorded requests were not sent, first: %s", len(unused), unused[0].Request.Body)
+	}
+}
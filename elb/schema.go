@@ -0,0 +1,40 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package elb
+
+import "github.com/ernestio/ernestaws/schema"
+
+var listener = &schema.Field{
+	Type: schema.Object,
+	Fields: []schema.Field{
+		{Name: "from_port", Type: schema.Integer, Required: true, Minimum: schema.Int(1), Maximum: schema.Int(65535)},
+		{Name: "to_port", Type: schema.Integer, Required: true, Minimum: schema.Int(1), Maximum: schema.Int(65535)},
+		{Name: "protocol", Type: schema.String, Required: true, Enum: []string{"HTTP", "HTTPS", "TCP", "SSL"}},
+		{Name: "ssl_cert", Type: schema.String, Format: schema.ARN},
+	},
+}
+
+var loadbalancer = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "is_private", Type: schema.Boolean},
+	{Name: "listeners", Type: schema.Array, Required: true, MinItems: 1, Items: listener},
+	{Name: "instance_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "network_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "security_group_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("elb", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), loadbalancer),
+		"update": schema.Fields(schema.Datacenter(), loadbalancer),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "name", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
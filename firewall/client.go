@@ -0,0 +1,426 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package firewall
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Rule describes an ingress or egress rule of a security group
+type Rule struct {
+	IP       string
+	Protocol string
+	FromPort int64
+	ToPort   int64
+}
+
+// Spec describes the desired state of a security group
+type Spec struct {
+	Name    string
+	VpcID   string
+	Ingress []Rule
+	Egress  []Rule
+	Tags    map[string]string
+}
+
+// Status describes a security group. Create and Update report it as set
+// from the spec, without describing it again
+type Status struct {
+	ID      string
+	Name    string
+	VpcID   string
+	Ingress []Rule
+	Egress  []Rule
+	Tags    map[string]string
+}
+
+// Client manages security groups through a typed api, the json events
+// are an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a security group with its rules, replacing the
+// default egress rule aws adds
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	// Create SecurityGroup
+	req := ec2.CreateSecurityGroupInput{
+		VpcId:       aws.String(s.VpcID),
+		GroupName:   aws.String(s.Name),
+		Description: aws.String(s.Name),
+	}
+
+	resp, err := svc.CreateSecurityGroupWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	id := aws.StringValue(resp.GroupId)
+
+	// Remove default rule
+	err = c.removeDefaultRule(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	// Authorize Ingress
+	if len(s.Ingress) > 0 {
+		iReq := ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       aws.String(id),
+			IpPermissions: buildPermissions(s.Ingress),
+		}
+
+		_, err = svc.AuthorizeSecurityGroupIngressWithContext(ctx, &iReq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	// Authorize Egress
+	if len(s.Egress) > 0 {
+		eReq := ec2.AuthorizeSecurityGroupEgressInput{
+			GroupId:       aws.String(id),
+			IpPermissions: buildPermissions(s.Egress),
+		}
+
+		_, err = svc.AuthorizeSecurityGroupEgressWithContext(ctx, &eReq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return s.status(id), nil
+}
+
+// Update : revokes the rules that are no longer defined and authorizes
+// the new ones
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	sg, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	// generate the new rulesets
+	newIngressRules := buildPermissions(s.Ingress)
+	newEgressRules := buildPermissions(s.Egress)
+
+	// aws groups the ranges of rules sharing protocol and ports
+	ingress := splitPermissions(sg.IpPermissions)
+	egress := splitPermissions(sg.IpPermissionsEgress)
+
+	// generate the rules to remove
+	revokeIngressRules := buildRevokePermissions(ingress, newIngressRules)
+	revokeEgressRules := buildRevokePermissions(egress, newEgressRules)
+
+	// remove already existing rules from the new ruleset
+	newIngressRules = deduplicateRules(newIngressRules, ingress)
+	newEgressRules = deduplicateRules(newEgressRules, egress)
+
+	// Revoke Ingress
+	if len(revokeIngressRules) > 0 {
+		iReq := ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       aws.String(id),
+			IpPermissions: revokeIngressRules,
+		}
+
+		_, err := svc.RevokeSecurityGroupIngressWithContext(ctx, &iReq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	// Revoke Egress
+	if len(revokeEgressRules) > 0 {
+		eReq := ec2.RevokeSecurityGroupEgressInput{
+			GroupId:       aws.String(id),
+			IpPermissions: revokeEgressRules,
+		}
+		_, err := svc.RevokeSecurityGroupEgressWithContext(ctx, &eReq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	// Authorize Ingress
+	if len(newIngressRules) > 0 {
+		iReq := ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       aws.String(id),
+			IpPermissions: newIngressRules,
+		}
+
+		_, err := svc.AuthorizeSecurityGroupIngressWithContext(ctx, &iReq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	// Authorize Egress
+	if len(newEgressRules) > 0 {
+		eReq := ec2.AuthorizeSecurityGroupEgressInput{
+			GroupId:       aws.String(id),
+			IpPermissions: newEgressRules,
+		}
+
+		_, err := svc.AuthorizeSecurityGroupEgressWithContext(ctx, &eReq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return s.status(id), nil
+}
+
+// Delete : deletes a security group
+func (c Client) Delete(ctx context.Context, id string) error {
+	req := ec2.DeleteSecurityGroupInput{
+		GroupId: aws.String(id),
+	}
+
+	_, err := c.getEC2Client().DeleteSecurityGroupWithContext(ctx, &req)
+
+	return err
+}
+
+// Find : returns the security groups matching all the tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	req := &ec2.DescribeSecurityGroupsInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := c.getEC2Client().DescribeSecurityGroupsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Status
+
+	for _, sg := range resp.SecurityGroups {
+		groups = append(groups, toStatus(sg))
+	}
+
+	return groups, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) removeDefaultRule(ctx context.Context, svc *ec2.EC2, id string) error {
+	perms := []*ec2.IpPermission{
+		&ec2.IpPermission{
+			FromPort:   aws.Int64(0),
+			ToPort:     aws.Int64(65535),
+			IpProtocol: aws.String("-1"),
+			IpRanges: []*ec2.IpRange{
+				&ec2.IpRange{CidrIp: aws.String("0.0.0.0/0")},
+			},
+		},
+	}
+
+	// groups on vpcs with an ipv6 block also allow all ipv6 egress
+	sg, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range splitPermissions(sg.IpPermissionsEgress) {
+		if aws.StringValue(p.IpProtocol) == "-1" && len(p.Ipv6Ranges) > 0 && aws.StringValue(p.Ipv6Ranges[0].CidrIpv6) == "::/0" {
+			perms = append(perms, p)
+		}
+	}
+
+	eReq := ec2.RevokeSecurityGroupEgressInput{
+		GroupId:       aws.String(id),
+		IpPermissions: perms,
+	}
+	_, err = svc.RevokeSecurityGroupEgressWithContext(ctx, &eReq)
+	return err
+}
+
+func (c Client) describe(ctx context.Context, svc *ec2.EC2, id string) (*ec2.SecurityGroup, error) {
+	f := []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String("group-id"),
+			Values: []*string{aws.String(id)},
+		},
+	}
+
+	req := ec2.DescribeSecurityGroupsInput{Filters: f}
+	resp, err := svc.DescribeSecurityGroupsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.SecurityGroups) != 1 {
+		return nil, errors.New("Could not find security group")
+	}
+
+	return resp.SecurityGroups[0], nil
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s Spec) status(id string) Status {
+	return Status{
+		ID:      id,
+		Name:    s.Name,
+		VpcID:   s.VpcID,
+		Ingress: s.Ingress,
+		Egress:  s.Egress,
+		Tags:    s.Tags,
+	}
+}
+
+func buildPermissions(rules []Rule) []*ec2.IpPermission {
+	var perms []*ec2.IpPermission
+	for _, rule := range rules {
+		p := ec2.IpPermission{
+			FromPort:   aws.Int64(rule.FromPort),
+			ToPort:     aws.Int64(rule.ToPort),
+			IpProtocol: aws.String(rule.Protocol),
+		}
+		if isIPv6(rule.IP) {
+			p.Ipv6Ranges = append(p.Ipv6Ranges, &ec2.Ipv6Range{CidrIpv6: aws.String(rule.IP)})
+		} else {
+			p.IpRanges = append(p.IpRanges, &ec2.IpRange{CidrIp: aws.String(rule.IP)})
+		}
+		perms = append(perms, &p)
+	}
+	return perms
+}
+
+func buildRevokePermissions(old, new []*ec2.IpPermission) []*ec2.IpPermission {
+	var revoked []*ec2.IpPermission
+	for _, rule := range old {
+		if ruleExists(rule, new) != true {
+			revoked = append(revoked, rule)
+		}
+	}
+	return revoked
+}
+
+func deduplicateRules(rules, old []*ec2.IpPermission) []*ec2.IpPermission {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if ruleExists(rules[i], old) {
+			rules = append(rules[:i], rules[i+1:]...)
+		}
+	}
+	return rules
+}
+
+func ruleExists(rule *ec2.IpPermission, ruleset []*ec2.IpPermission) bool {
+	for _, r := range ruleset {
+		if reflect.DeepEqual(*r, *rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPermissions : returns a permission for each ip range, as the
+// rules are built
+func splitPermissions(perms []*ec2.IpPermission) []*ec2.IpPermission {
+	var split []*ec2.IpPermission
+
+	for _, p := range perms {
+		for _, r := range p.IpRanges {
+			split = append(split, &ec2.IpPermission{
+				FromPort:   p.FromPort,
+				ToPort:     p.ToPort,
+				IpProtocol: p.IpProtocol,
+				IpRanges:   []*ec2.IpRange{&ec2.IpRange{CidrIp: r.CidrIp}},
+			})
+		}
+
+		for _, r := range p.Ipv6Ranges {
+			split = append(split, &ec2.IpPermission{
+				FromPort:   p.FromPort,
+				ToPort:     p.ToPort,
+				IpProtocol: p.IpProtocol,
+				Ipv6Ranges: []*ec2.Ipv6Range{&ec2.Ipv6Range{CidrIpv6: r.CidrIpv6}},
+			})
+		}
+	}
+
+	return split
+}
+
+func isIPv6(cidr string) bool {
+	return strings.Contains(cidr, ":")
+}
+
+func toStatus(sg *ec2.SecurityGroup) Status {
+	return Status{
+		ID:      aws.StringValue(sg.GroupId),
+		Name:    aws.StringValue(sg.GroupName),
+		VpcID:   aws.StringValue(sg.VpcId),
+		Ingress: mapSecurityGroupRules(sg.IpPermissions),
+		Egress:  mapSecurityGroupRules(sg.IpPermissionsEgress),
+		Tags:    mapEC2Tags(sg.Tags),
+	}
+}
+
+func mapSecurityGroupRules(perms []*ec2.IpPermission) []Rule {
+	var rules []Rule
+
+	for _, p := range splitPermissions(perms) {
+		r := Rule{
+			Protocol: aws.StringValue(p.IpProtocol),
+			FromPort: aws.Int64Value(p.FromPort),
+			ToPort:   aws.Int64Value(p.ToPort),
+		}
+
+		if len(p.IpRanges) > 0 {
+			r.IP = aws.StringValue(p.IpRanges[0].CidrIp)
+		} else {
+			r.IP = aws.StringValue(p.Ipv6Ranges[0].CidrIpv6)
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules
+}
@@ -5,17 +5,16 @@
 package firewall
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
-	"reflect"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -55,6 +54,7 @@ type Event struct {
 	ComponentID        string  `json:"_component_id"`
 	State              string  `json:"_state"`
 	Action             string  `json:"_action"`
+	SchemaVersion      int     `json:"_schema_version"`
 	SecurityGroupAWSID *string `json:"security_group_aws_id,omitempty"`
 	Name               *string `json:"name"`
 	NetworkAWSID       *string `json:"network_aws_id"`
@@ -102,6 +102,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -112,6 +117,11 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.preflight(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -131,6 +141,10 @@ func (ev *Event) Complete() {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.VpcID == "" {
 		return ErrDatacenterIDInvalid
 	}
@@ -153,7 +167,7 @@ func (ev *Event) Validate() error {
 			return ErrSGNameInvalid
 		}
 
-		if len(ev.Rules.Egress) < 1 && len(ev.Rules.Egress) < 1 {
+		if len(ev.Rules.Ingress) < 1 && len(ev.Rules.Egress) < 1 {
 			return ErrSGRulesInvalid
 		}
 		for _, rule := range ev.Rules.Ingress {
@@ -195,148 +209,27 @@ func (ev *Event) Find() error {
 	return errors.New(ev.Subject + " not supported")
 }
 
-// Create : Creates a nat object on aws
+// Create : Creates a security group on aws
 func (ev *Event) Create() error {
-	svc := ev.getEC2Client()
-
-	// Create SecurityGroup
-	req := ec2.CreateSecurityGroupInput{
-		VpcId:       aws.String(ev.VpcID),
-		GroupName:   ev.Name,
-		Description: ev.Name,
-	}
-
-	resp, err := svc.CreateSecurityGroup(&req)
-	if err != nil {
-		return err
-	}
-
-	ev.SecurityGroupAWSID = resp.GroupId
-
-	// Remove default rule
-	err = ev.removeDefaultRule(svc, resp.GroupId)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	// Authorize Ingress
-	if len(ev.Rules.Ingress) > 0 {
-		iReq := ec2.AuthorizeSecurityGroupIngressInput{
-			GroupId:       ev.SecurityGroupAWSID,
-			IpPermissions: ev.buildPermissions(ev.Rules.Ingress),
-		}
-
-		_, err = svc.AuthorizeSecurityGroupIngress(&iReq)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Authorize Egress
-	if len(ev.Rules.Egress) > 0 {
-		eReq := ec2.AuthorizeSecurityGroupEgressInput{
-			GroupId:       ev.SecurityGroupAWSID,
-			IpPermissions: ev.buildPermissions(ev.Rules.Egress),
-		}
+	ev.SecurityGroupAWSID = aws.String(st.ID)
 
-		_, err = svc.AuthorizeSecurityGroupEgress(&eReq)
-		if err != nil {
-			return err
-		}
-	}
-
-	return ev.setTags()
+	return nil
 }
 
-// Update : Updates a nat object on aws
+// Update : Updates the rules of a security group on aws
 func (ev *Event) Update() error {
-	svc := ev.getEC2Client()
-
-	sg, err := ev.securityGroupByID(svc, ev.SecurityGroupAWSID)
-	if err != nil {
-		return err
-	}
-
-	// generate the new rulesets
-	newIngressRules := ev.buildPermissions(ev.Rules.Ingress)
-	newEgressRules := ev.buildPermissions(ev.Rules.Egress)
-
-	// generate the rules to remove
-	revokeIngressRules := ev.buildRevokePermissions(sg.IpPermissions, newIngressRules)
-	revokeEgressRules := ev.buildRevokePermissions(sg.IpPermissionsEgress, newEgressRules)
-
-	// remove already existing rules from the new ruleset
-	newIngressRules = ev.deduplicateRules(newIngressRules, sg.IpPermissions)
-	newEgressRules = ev.deduplicateRules(newEgressRules, sg.IpPermissionsEgress)
-
-	// Revoke Ingress
-	if len(revokeIngressRules) > 0 {
-		iReq := ec2.RevokeSecurityGroupIngressInput{
-			GroupId:       ev.SecurityGroupAWSID,
-			IpPermissions: revokeIngressRules,
-		}
-
-		_, err := svc.RevokeSecurityGroupIngress(&iReq)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Revoke Egress
-	if len(revokeEgressRules) > 0 {
-		eReq := ec2.RevokeSecurityGroupEgressInput{
-			GroupId:       ev.SecurityGroupAWSID,
-			IpPermissions: revokeEgressRules,
-		}
-		_, err := svc.RevokeSecurityGroupEgress(&eReq)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Authorize Ingress
-	if len(newIngressRules) > 0 {
-		iReq := ec2.AuthorizeSecurityGroupIngressInput{
-			GroupId:       ev.SecurityGroupAWSID,
-			IpPermissions: newIngressRules,
-		}
-
-		_, err := svc.AuthorizeSecurityGroupIngress(&iReq)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Authorize Egress
-	if len(newEgressRules) > 0 {
-		eReq := ec2.AuthorizeSecurityGroupEgressInput{
-			GroupId:       ev.SecurityGroupAWSID,
-			IpPermissions: newEgressRules,
-		}
-
-		_, err := svc.AuthorizeSecurityGroupEgress(&eReq)
-		if err != nil {
-			return err
-		}
-	}
-
-	return ev.setTags()
+	_, err := ev.client().Update(context.Background(), aws.StringValue(ev.SecurityGroupAWSID), ev.spec())
+	return err
 }
 
-// Delete : Deletes a nat object on aws
+// Delete : Deletes a security group on aws
 func (ev *Event) Delete() error {
-	svc := ev.getEC2Client()
-
-	req := ec2.DeleteSecurityGroupInput{
-		GroupId: ev.SecurityGroupAWSID,
-	}
-
-	_, err := svc.DeleteSecurityGroup(&req)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.SecurityGroupAWSID))
 }
 
 // Get : Gets a nat object on aws
@@ -344,116 +237,43 @@ func (ev *Event) Get() error {
 	return errors.New(ev.Subject + " not supported")
 }
 
-func (ev *Event) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func (ev *Event) removeDefaultRule(svc *ec2.EC2, sgID *string) error {
-	perms := []*ec2.IpPermission{
-		&ec2.IpPermission{
-			FromPort:   aws.Int64(0),
-			ToPort:     aws.Int64(65535),
-			IpProtocol: aws.String("-1"),
-			IpRanges: []*ec2.IpRange{
-				&ec2.IpRange{CidrIp: aws.String("0.0.0.0/0")},
-			},
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
 		},
-	}
-
-	eReq := ec2.RevokeSecurityGroupEgressInput{
-		GroupId:       sgID,
-		IpPermissions: perms,
-	}
-	_, err := svc.RevokeSecurityGroupEgress(&eReq)
-	return err
-}
-
-func (ev *Event) securityGroupByID(svc *ec2.EC2, id *string) (*ec2.SecurityGroup, error) {
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("group-id"),
-			Values: []*string{id},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
 		},
 	}
-
-	req := ec2.DescribeSecurityGroupsInput{Filters: f}
-	resp, err := svc.DescribeSecurityGroups(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.SecurityGroups) != 1 {
-		return nil, errors.New("Could not find security group")
-	}
-
-	return resp.SecurityGroups[0], nil
-}
-
-func (ev *Event) buildPermissions(rules []rule) []*ec2.IpPermission {
-	var perms []*ec2.IpPermission
-	for _, rule := range rules {
-		p := ec2.IpPermission{
-			FromPort:   rule.FromPort,
-			ToPort:     rule.ToPort,
-			IpProtocol: rule.Protocol,
-		}
-		ip := ec2.IpRange{CidrIp: rule.IP}
-		p.IpRanges = append(p.IpRanges, &ip)
-		perms = append(perms, &p)
-	}
-	return perms
-}
-
-func (ev *Event) buildRevokePermissions(old, new []*ec2.IpPermission) []*ec2.IpPermission {
-	var revoked []*ec2.IpPermission
-	for _, rule := range old {
-		if ev.ruleExists(rule, new) != true {
-			revoked = append(revoked, rule)
-		}
-	}
-	return revoked
-}
-
-func (ev *Event) deduplicateRules(rules, old []*ec2.IpPermission) []*ec2.IpPermission {
-	for i := len(rules) - 1; i >= 0; i-- {
-		if ev.ruleExists(rules[i], old) {
-			rules = append(rules[:i], rules[i+1:]...)
-		}
-	}
-	return rules
 }
 
-func (ev *Event) ruleExists(rule *ec2.IpPermission, ruleset []*ec2.IpPermission) bool {
-	for _, r := range ruleset {
-		if reflect.DeepEqual(*r, *rule) {
-			return true
-		}
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:    aws.StringValue(ev.Name),
+		VpcID:   ev.VpcID,
+		Ingress: toRules(ev.Rules.Ingress),
+		Egress:  toRules(ev.Rules.Egress),
+		Tags:    ev.Tags,
 	}
-	return false
 }
 
-func (ev *Event) setTags() error {
-	svc := ev.getEC2Client()
-
-	for key, val := range ev.Tags {
-		req := &ec2.CreateTagsInput{
-			Resources: []*string{ev.SecurityGroupAWSID},
-		}
+func toRules(rules []rule) []Rule {
+	var r []Rule
 
-		req.Tags = append(req.Tags, &ec2.Tag{
-			Key:   &key,
-			Value: &val,
+	for _, ru := range rules {
+		r = append(r, Rule{
+			IP:       aws.StringValue(ru.IP),
+			Protocol: aws.StringValue(ru.Protocol),
+			FromPort: aws.Int64Value(ru.FromPort),
+			ToPort:   aws.Int64Value(ru.ToPort),
 		})
-
-		_, err := svc.CreateTags(req)
-		if err != nil {
-			return err
-		}
 	}
 
-	return nil
+	return r
 }
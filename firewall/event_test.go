@@ -0,0 +1,195 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package firewall
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+// rules : returns the rules of a direction of the security group as
+// protocol/from-to/ip
+func rules(perms []*ec2.IpPermission) []string {
+	var r []string
+
+	for _, p := range perms {
+		for _, ip := range p.IpRanges {
+			r = append(r, fmt.Sprintf("%s/%d-%d/%s", *p.IpProtocol, aws.Int64Value(p.FromPort), aws.Int64Value(p.ToPort), *ip.CidrIp))
+		}
+		for _, ip := range p.Ipv6Ranges {
+			r = append(r, fmt.Sprintf("%s/%d-%d/%s", *p.IpProtocol, aws.Int64Value(p.FromPort), aws.Int64Value(p.ToPort), *ip.CidrIpv6))
+		}
+	}
+
+	sort.Strings(r)
+
+	return r
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create replaces the default egress rule",
+			subject:  "firewall.create.aws",
+			body:     `{"vpc_id":"$vpc","name":"web","tags":{"Name":"web"},"rules":{"ingress":[{"ip":"0.0.0.0/0","protocol":"tcp","from_port":80,"to_port":80},{"ip":"::/0","protocol":"tcp","from_port":80,"to_port":80}],"egress":[{"ip":"10.0.0.0/16","protocol":"tcp","from_port":5432,"to_port":5432}]}}`,
+			expected: "firewall.create.aws.done",
+			save:     map[string]string{"id": "security_group_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				sg := b.EC2.SecurityGroups[ids["id"]]
+				return reflect.DeepEqual(rules(sg.IpPermissions), []string{"tcp/80-80/0.0.0.0/0", "tcp/80-80/::/0"}) &&
+					reflect.DeepEqual(rules(sg.IpPermissionsEgress), []string{"tcp/5432-5432/10.0.0.0/16"})
+			},
+		},
+		{
+			name:     "update revokes and authorizes the changed rules",
+			subject:  "firewall.update.aws",
+			body:     `{"vpc_id":"$vpc","name":"web","security_group_aws_id":"$id","rules":{"ingress":[{"ip":"0.0.0.0/0","protocol":"tcp","from_port":443,"to_port":443},{"ip":"::/0","protocol":"tcp","from_port":80,"to_port":80}],"egress":[{"ip":"10.0.0.0/16","protocol":"tcp","from_port":5432,"to_port":5432}]}}`,
+			expected: "firewall.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				sg := b.EC2.SecurityGroups[ids["id"]]
+				return reflect.DeepEqual(rules(sg.IpPermissions), []string{"tcp/443-443/0.0.0.0/0", "tcp/80-80/::/0"}) &&
+					reflect.DeepEqual(rules(sg.IpPermissionsEgress), []string{"tcp/5432-5432/10.0.0.0/16"})
+			},
+		},
+		{
+			name:     "find",
+			subject:  "firewall.find.aws",
+			body:     `{"tags":{"Name":"web"}}`,
+			expected: "firewall.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				sg := found[0].(map[string]interface{})
+				r := sg["rules"].(map[string]interface{})
+				return sg["security_group_aws_id"] == ids["id"] && sg["name"] == "web" && len(r["ingress"].([]interface{})) == 2
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "firewall.delete.aws",
+			body:     `{"vpc_id":"$vpc","security_group_aws_id":"$id"}`,
+			expected: "firewall.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return b.EC2.SecurityGroups[ids["id"]] == nil
+			},
+		},
+		{
+			name:     "create with ingress rules only",
+			subject:  "firewall.create.aws",
+			body:     `{"vpc_id":"$vpc","name":"api","rules":{"ingress":[{"ip":"10.0.0.0/16","protocol":"tcp","from_port":8080,"to_port":8080}]}}`,
+			expected: "firewall.create.aws.done",
+			save:     map[string]string{"api": "security_group_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				sg := b.EC2.SecurityGroups[ids["api"]]
+				return reflect.DeepEqual(rules(sg.IpPermissions), []string{"tcp/8080-8080/10.0.0.0/16"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails removing the default rule",
+			operation: "RevokeSecurityGroupEgress",
+			subject:   "firewall.create.aws",
+			body:      `{"vpc_id":"$vpc","name":"db","rules":{"ingress":[{"ip":"10.0.0.0/16","protocol":"tcp","from_port":5432,"to_port":5432}],"egress":[{"ip":"0.0.0.0/0","protocol":"-1","from_port":0,"to_port":65535}]}}`,
+		},
+		{
+			name:      "create fails authorizing the rules",
+			operation: "AuthorizeSecurityGroupIngress",
+			subject:   "firewall.create.aws",
+			body:      `{"vpc_id":"$vpc","name":"db","rules":{"ingress":[{"ip":"10.0.0.0/16","protocol":"tcp","from_port":5432,"to_port":5432}],"egress":[{"ip":"0.0.0.0/0","protocol":"-1","from_port":0,"to_port":65535}]}}`,
+		},
+		{
+			name:      "update fails revoking the rules",
+			operation: "RevokeSecurityGroupIngress",
+			subject:   "firewall.update.aws",
+			body:      `{"vpc_id":"$vpc","name":"web","security_group_aws_id":"$id","rules":{"ingress":[{"ip":"10.0.0.0/16","protocol":"tcp","from_port":22,"to_port":22}],"egress":[{"ip":"0.0.0.0/0","protocol":"-1","from_port":0,"to_port":65535}]}}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteSecurityGroup",
+			subject:   "firewall.delete.aws",
+			body:      `{"vpc_id":"$vpc","security_group_aws_id":"$id"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := map[string]string{"vpc": createVpc(t, b)}
+
+			_, res := awsfake.Run(t, New, "firewall.create.aws", `{"vpc_id":"$vpc","name":"web","rules":{"ingress":[{"ip":"0.0.0.0/0","protocol":"tcp","from_port":80,"to_port":80}],"egress":[{"ip":"0.0.0.0/0","protocol":"-1","from_port":0,"to_port":65535}]}}`, ids)
+			ids["id"], _ = res["security_group_aws_id"].(string)
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
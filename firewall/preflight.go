@@ -0,0 +1,26 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package firewall
+
+import (
+	"strings"
+
+	"github.com/ernestio/ernestaws/quota"
+)
+
+// preflight : checks the rules fit on the security group before
+// creating or updating it
+func (ev *Event) preflight() error {
+	if !quota.Enabled() {
+		return nil
+	}
+
+	switch strings.Split(ev.Subject, ".")[1] {
+	case "create", "update":
+		return quota.Rules(int64(len(ev.Rules.Ingress)), int64(len(ev.Rules.Egress)))
+	}
+
+	return nil
+}
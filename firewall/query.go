@@ -5,14 +5,15 @@
 package firewall
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -50,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -108,30 +119,30 @@ func (col *Collection) Delete() error {
 
 // Find : Find security groups on aws
 func (col *Collection) Find() error {
-	svc := col.getEC2Client()
-
-	req := &ec2.DescribeSecurityGroupsInput{
-		Filters: mapFilters(col.Tags),
-	}
-
-	resp, err := svc.DescribeSecurityGroups(req)
+	groups, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, sg := range resp.SecurityGroups {
-		col.Results = append(col.Results, toEvent(sg))
+	for _, st := range groups {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
 func mapFilters(tags map[string]string) []*ec2.Filter {
@@ -157,36 +168,34 @@ func mapEC2Tags(input []*ec2.Tag) map[string]string {
 	return t
 }
 
-// toEvent converts an ec2 security group object to an ernest event
-func toEvent(sg *ec2.SecurityGroup) *Event {
+// toEvent converts a security group to an ernest event
+func toEvent(st Status) *Event {
 	e := &Event{
 		ProviderType:       "aws",
 		ComponentType:      "firewall",
-		ComponentID:        "firewall::" + *sg.GroupName,
-		VpcID:              *sg.VpcId,
-		SecurityGroupAWSID: sg.GroupId,
-		Name:               sg.GroupName,
-		Tags:               mapEC2Tags(sg.Tags),
+		ComponentID:        "firewall::" + st.Name,
+		VpcID:              st.VpcID,
+		SecurityGroupAWSID: aws.String(st.ID),
+		Name:               aws.String(st.Name),
+		Tags:               st.Tags,
 	}
 
-	e.Rules.Ingress = mapSecurityGroupRules(sg.IpPermissions)
-	e.Rules.Egress = mapSecurityGroupRules(sg.IpPermissionsEgress)
+	e.Rules.Ingress = mapRules(st.Ingress)
+	e.Rules.Egress = mapRules(st.Egress)
 	return e
 }
 
-func mapSecurityGroupRules(perms []*ec2.IpPermission) []rule {
-	var rules []rule
+func mapRules(rules []Rule) []rule {
+	var r []rule
 
-	for _, p := range perms {
-		for _, r := range p.IpRanges {
-			rules = append(rules, rule{
-				IP:       r.CidrIp,
-				Protocol: p.IpProtocol,
-				FromPort: p.FromPort,
-				ToPort:   p.ToPort,
-			})
-		}
+	for _, ru := range rules {
+		r = append(r, rule{
+			IP:       aws.String(ru.IP),
+			Protocol: aws.String(ru.Protocol),
+			FromPort: aws.Int64(ru.FromPort),
+			ToPort:   aws.Int64(ru.ToPort),
+		})
 	}
 
-	return rules
+	return r
 }
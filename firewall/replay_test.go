@@ -0,0 +1,56 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package firewall
+
+import (
+	"testing"
+
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/awsreplay"
+)
+
+const datacenter = `"datacenter_region":"eu-west-1","aws_access_key_id":"AKIAEXAMPLE","aws_secret_access_key":"secret"`
+
+// TestReplayRuleChanges : replays the rule changes of an update. The
+// fixture is synthetic, recorded against the awsfake backend rather than
+// a real aws account, so its ids are sequential
+func TestReplayRuleChanges(t *testing.T) {
+	rec, err := awsreplay.New("testdata/firewall-rules.json", awsreplay.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Install()
+	defer rec.Stop()
+
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+	}{
+		{
+			name:    "revokes and authorizes the changed rules",
+			subject: "firewall.update.aws",
+			body:    `{` + datacenter + `,"vpc_id":"vpc-00000001","name":"web","tags":{"Name":"web"},"security_group_aws_id":"sg-00000008","rules":{"ingress":[{"ip":"0.0.0.0/0","protocol":"tcp","from_port":443,"to_port":443},{"ip":"10.0.0.0/16","protocol":"tcp","from_port":22,"to_port":22}],"egress":[{"ip":"10.0.0.0/16","protocol":"tcp","from_port":5432,"to_port":5432}]}}`,
+		},
+		{
+			name:    "deletes the security group",
+			subject: "firewall.delete.aws",
+			body:    `{` + datacenter + `,"vpc_id":"vpc-00000001","security_group_aws_id":"sg-00000008"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		ev := New(tt.subject, []byte(tt.body), "")
+
+		subject, body := ernestaws.Handle(&ev)
+		if subject != tt.subject+".done" {
+			t.Fatalf("%s: expected %s.done, got %s: %s", tt.name, tt.subject, subject, body)
+		}
+	}
+
+	if unused := rec.Unused(); len(unused) > 0 {
+		t.Errorf("%d recorded requests were not sent, first: %s", len(unused), unused[0].Request.Body)
+	}
+}
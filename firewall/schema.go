@@ -0,0 +1,52 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package firewall
+
+import "github.com/ernestio/ernestaws/schema"
+
+var port = schema.Field{Type: schema.Integer, Required: true, Minimum: schema.Int(0), Maximum: schema.Int(65535)}
+
+var ruleset = &schema.Field{
+	Type: schema.Object,
+	Fields: []schema.Field{
+		{Name: "ip", Type: schema.String, Required: true, Format: schema.CIDR},
+		{Name: "protocol", Type: schema.String, Required: true},
+		withName(port, "from_port"),
+		withName(port, "to_port"),
+	},
+}
+
+var firewall = []schema.Field{
+	{Name: "vpc_id", Type: schema.String, Required: true},
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "rules", Type: schema.Object, Required: true, Fields: []schema.Field{
+		{Name: "ingress", Type: schema.Array, Items: ruleset},
+		{Name: "egress", Type: schema.Array, Items: ruleset},
+	}},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("firewall", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), firewall),
+		"update": schema.Fields(schema.Datacenter(), firewall, []schema.Field{
+			{Name: "security_group_aws_id", Type: schema.String, Required: true},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "security_group_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("firewall", 1, schema.VpcReference)
+}
+
+func withName(f schema.Field, name string) schema.Field {
+	f.Name = name
+	return f
+}
@@ -0,0 +1,359 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package flowlog
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of a flow log. Values left empty are
+// not set, so the destination type defaults to cloud watch logs
+type Spec struct {
+	Name            string
+	ResourceID      string
+	ResourceType    string
+	TrafficType     string
+	DestinationType string
+	LogGroupName    string
+	IAMRoleARN      string
+	LogDestination  string
+	LogFormat       string
+	Tags            map[string]string
+}
+
+// Status describes a flow log as it is on aws
+type Status struct {
+	ID              string
+	Name            string
+	ResourceID      string
+	ResourceType    string
+	TrafficType     string
+	DestinationType string
+	LogGroupName    string
+	IAMRoleARN      string
+	LogDestination  string
+	LogFormat       string
+	Tags            map[string]string
+}
+
+// Client manages flow logs through a typed api, the json events are an
+// adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a flow log. Flow logs on instances are created on
+// their primary network interface
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	resourceID, resourceType, err := c.resource(ctx, svc, s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	req := ec2.CreateFlowLogsInput{
+		ResourceIds:              []*string{aws.String(resourceID)},
+		ResourceType:             aws.String(resourceType),
+		TrafficType:              aws.String(s.TrafficType),
+		LogDestinationType:       optional(s.DestinationType),
+		LogGroupName:             optional(s.LogGroupName),
+		DeliverLogsPermissionArn: optional(s.IAMRoleARN),
+		LogDestination:           optional(s.LogDestination),
+		LogFormat:                optional(s.LogFormat),
+	}
+
+	resp, err := svc.CreateFlowLogsWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	for _, u := range resp.Unsuccessful {
+		if u.Error != nil {
+			return Status{}, errors.New(aws.StringValue(u.Error.Code) + ": " + aws.StringValue(u.Error.Message))
+		}
+	}
+
+	if len(resp.FlowLogIds) == 0 {
+		return Status{}, ErrFlowLogNotFound
+	}
+
+	st := Status{
+		ID:              aws.StringValue(resp.FlowLogIds[0]),
+		Name:            s.Name,
+		ResourceID:      s.ResourceID,
+		ResourceType:    s.ResourceType,
+		TrafficType:     s.TrafficType,
+		DestinationType: s.DestinationType,
+		LogGroupName:    s.LogGroupName,
+		IAMRoleARN:      s.IAMRoleARN,
+		LogDestination:  s.LogDestination,
+		LogFormat:       s.LogFormat,
+		Tags:            s.Tags,
+	}
+
+	return st, c.setTags(ctx, svc, st.ID, s.Tags)
+}
+
+// Update : updates a flow log. Flow logs can't be modified, so a change
+// on the traffic type, destination or format replaces it
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	fl, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if changed(s, fl) {
+		st, err := c.Create(ctx, s)
+		if err != nil {
+			return Status{}, err
+		}
+
+		return st, c.delete(ctx, svc, id)
+	}
+
+	st := toStatus(fl)
+	st.Name = s.Name
+	st.ResourceID = s.ResourceID
+	st.ResourceType = s.ResourceType
+	st.Tags = s.Tags
+
+	return st, c.setTags(ctx, svc, id, s.Tags)
+}
+
+// Delete : deletes a flow log
+func (c Client) Delete(ctx context.Context, id string) error {
+	return c.delete(ctx, c.getEC2Client(), id)
+}
+
+// Find : returns the flow logs matching all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	return c.FindByResource(ctx, "", tags)
+}
+
+// FindByResource : returns the flow logs matching all the given tags,
+// created on the resource when its id is not empty. Flow logs of
+// instances are looked up on their primary network interface, as they
+// are created there
+func (c Client) FindByResource(ctx context.Context, resourceID string, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	f := mapFilters(tags)
+
+	instance := resourceTypes[strings.Split(resourceID, "-")[0]] == "Instance"
+
+	if instance {
+		id, err := primaryNetworkInterface(ctx, svc, resourceID)
+		if err != nil {
+			return nil, err
+		}
+
+		f = append(f, &ec2.Filter{
+			Name:   aws.String("resource-id"),
+			Values: []*string{aws.String(id)},
+		})
+	} else if resourceID != "" {
+		f = append(f, &ec2.Filter{
+			Name:   aws.String("resource-id"),
+			Values: []*string{aws.String(resourceID)},
+		})
+	}
+
+	req := &ec2.DescribeFlowLogsInput{
+		Filter: f,
+	}
+
+	resp, err := svc.DescribeFlowLogsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []Status
+
+	for _, fl := range resp.FlowLogs {
+		st := toStatus(fl)
+
+		if instance {
+			st.ResourceID = resourceID
+			st.ResourceType = "Instance"
+		}
+
+		logs = append(logs, st)
+	}
+
+	return logs, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+// resource : returns the id and type of the resource the flow log is
+// created on, resolving instances to their primary network interface
+func (c Client) resource(ctx context.Context, svc *ec2.EC2, s Spec) (string, string, error) {
+	if s.ResourceType != "Instance" {
+		return s.ResourceID, s.ResourceType, nil
+	}
+
+	id, err := primaryNetworkInterface(ctx, svc, s.ResourceID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return id, ec2.FlowLogsResourceTypeNetworkInterface, nil
+}
+
+func (c Client) describe(ctx context.Context, svc *ec2.EC2, id string) (*ec2.FlowLog, error) {
+	req := ec2.DescribeFlowLogsInput{
+		FlowLogIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeFlowLogsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.FlowLogs) == 0 {
+		return nil, ErrFlowLogNotFound
+	}
+
+	return resp.FlowLogs[0], nil
+}
+
+func (c Client) delete(ctx context.Context, svc *ec2.EC2, id string) error {
+	req := ec2.DeleteFlowLogsInput{
+		FlowLogIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DeleteFlowLogsWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range resp.Unsuccessful {
+		if u.Error != nil {
+			return errors.New(aws.StringValue(u.Error.Code) + ": " + aws.StringValue(u.Error.Message))
+		}
+	}
+
+	return nil
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// primaryNetworkInterface : returns the id of the network interface on
+// device index 0 of an instance
+func primaryNetworkInterface(ctx context.Context, svc *ec2.EC2, instanceID string) (string, error) {
+	req := ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(instanceID)},
+	}
+
+	resp, err := svc.DescribeInstancesWithContext(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+
+	for _, r := range resp.Reservations {
+		for _, i := range r.Instances {
+			for _, ni := range i.NetworkInterfaces {
+				if ni.Attachment == nil || aws.Int64Value(ni.Attachment.DeviceIndex) == 0 {
+					return aws.StringValue(ni.NetworkInterfaceId), nil
+				}
+			}
+		}
+	}
+
+	return "", ErrNetworkInterfaceNotFound
+}
+
+// changed : checks if the flow log differs from the spec on any of the
+// values that can only be set on creation
+func changed(s Spec, fl *ec2.FlowLog) bool {
+	destinationType := s.DestinationType
+	if destinationType == "" {
+		destinationType = ec2.LogDestinationTypeCloudWatchLogs
+	}
+
+	if destinationType != aws.StringValue(fl.LogDestinationType) {
+		return true
+	}
+
+	if s.TrafficType != aws.StringValue(fl.TrafficType) {
+		return true
+	}
+
+	if s.LogFormat != "" && s.LogFormat != aws.StringValue(fl.LogFormat) {
+		return true
+	}
+
+	if destinationType == ec2.LogDestinationTypeS3 {
+		return s.LogDestination != aws.StringValue(fl.LogDestination)
+	}
+
+	return s.LogGroupName != aws.StringValue(fl.LogGroupName) ||
+		s.IAMRoleARN != aws.StringValue(fl.DeliverLogsPermissionArn)
+}
+
+// optional : returns nil for empty values, so aws applies its defaults
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return aws.String(s)
+}
+
+func toStatus(fl *ec2.FlowLog) Status {
+	tags := mapEC2Tags(fl.Tags)
+
+	st := Status{
+		ID:              aws.StringValue(fl.FlowLogId),
+		Name:            tags["Name"],
+		ResourceID:      aws.StringValue(fl.ResourceId),
+		TrafficType:     aws.StringValue(fl.TrafficType),
+		DestinationType: aws.StringValue(fl.LogDestinationType),
+		LogFormat:       aws.StringValue(fl.LogFormat),
+		Tags:            tags,
+	}
+
+	st.ResourceType = resourceTypes[strings.Split(st.ResourceID, "-")[0]]
+
+	if st.DestinationType == ec2.LogDestinationTypeS3 {
+		st.LogDestination = aws.StringValue(fl.LogDestination)
+	} else {
+		st.LogGroupName = aws.StringValue(fl.LogGroupName)
+		st.IAMRoleARN = aws.StringValue(fl.DeliverLogsPermissionArn)
+	}
+
+	return st
+}
@@ -0,0 +1,250 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package flowlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
+)
+
+var (
+	// ErrDatacenterRegionInvalid ...
+	ErrDatacenterRegionInvalid = errors.New("Datacenter Region invalid")
+	// ErrDatacenterCredentialsInvalid ...
+	ErrDatacenterCredentialsInvalid = errors.New("Datacenter credentials invalid")
+	// ErrResourceIDInvalid ...
+	ErrResourceIDInvalid = errors.New("Flow log resource id invalid, must be a vpc, subnet, network interface or instance id")
+	// ErrFlowLogAWSIDInvalid ...
+	ErrFlowLogAWSIDInvalid = errors.New("Flow log aws id invalid")
+	// ErrCloudWatchDestinationInvalid ...
+	ErrCloudWatchDestinationInvalid = errors.New("Flow log log_group_name and iam_role_arn are required for cloud-watch-logs destinations")
+	// ErrS3DestinationInvalid ...
+	ErrS3DestinationInvalid = errors.New("Flow log log_destination is required for s3 destinations")
+	// ErrFlowLogNotFound ...
+	ErrFlowLogNotFound = errors.New("Flow log not found")
+	// ErrNetworkInterfaceNotFound ...
+	ErrNetworkInterfaceNotFound = errors.New("Instance network interface not found")
+)
+
+// resource types of the flow logs, keyed by the prefix of the ids
+var resourceTypes = map[string]string{
+	"vpc":    ec2.FlowLogsResourceTypeVpc,
+	"subnet": ec2.FlowLogsResourceTypeSubnet,
+	"eni":    ec2.FlowLogsResourceTypeNetworkInterface,
+	"i":      "Instance",
+}
+
+// Event stores the flow log data
+type Event struct {
+	ProviderType     string            `json:"_provider"`
+	ComponentType    string            `json:"_component"`
+	ComponentID      string            `json:"_component_id"`
+	State            string            `json:"_state"`
+	Action           string            `json:"_action"`
+	SchemaVersion    int               `json:"_schema_version"`
+	FlowLogAWSID     *string           `json:"flow_log_aws_id"`
+	Name             *string           `json:"name"`
+	ResourceID       string            `json:"resource_id"`
+	ResourceType     string            `json:"resource_type"`
+	TrafficType      *string           `json:"traffic_type"`
+	DestinationType  *string           `json:"destination_type"`
+	LogGroupName     *string           `json:"log_group_name"`
+	IAMRoleARN       *string           `json:"iam_role_arn"`
+	LogDestination   *string           `json:"log_destination"`
+	LogFormat        *string           `json:"log_format"`
+	Tags             map[string]string `json:"tags"`
+	DatacenterType   string            `json:"datacenter_type"`
+	DatacenterName   string            `json:"datacenter_name"`
+	DatacenterRegion string            `json:"datacenter_region"`
+	AccessKeyID      string            `json:"aws_access_key_id"`
+	SecretAccessKey  string            `json:"aws_secret_access_key"`
+	Service          string            `json:"service"`
+	ErrorMessage     string            `json:"error,omitempty"`
+	Subject          string            `json:"-"`
+	Body             []byte            `json:"-"`
+	CryptoKey        string            `json:"-"`
+}
+
+// New : Constructor
+func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
+	if strings.Split(subject, ".")[1] == "find" {
+		return &Collection{Subject: subject, Body: body, CryptoKey: cryptoKey}
+	}
+
+	return &Event{Subject: subject, Body: body, CryptoKey: cryptoKey}
+}
+
+// Validate checks if all criteria are met
+func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
+	if ev.DatacenterRegion == "" {
+		return ErrDatacenterRegionInvalid
+	}
+
+	if ev.AccessKeyID == "" || ev.SecretAccessKey == "" {
+		return ErrDatacenterCredentialsInvalid
+	}
+
+	if ev.Subject != "flow_log.create.aws" && ev.FlowLogAWSID == nil {
+		return ErrFlowLogAWSIDInvalid
+	}
+
+	if ev.Subject == "flow_log.delete.aws" {
+		return nil
+	}
+
+	if ev.ResourceType == "" {
+		ev.ResourceType = resourceTypes[strings.Split(ev.ResourceID, "-")[0]]
+	}
+
+	if ev.ResourceType == "" {
+		return ErrResourceIDInvalid
+	}
+
+	switch aws.StringValue(ev.DestinationType) {
+	case ec2.LogDestinationTypeS3:
+		if ev.LogDestination == nil {
+			return ErrS3DestinationInvalid
+		}
+	default:
+		if ev.LogGroupName == nil || ev.IAMRoleARN == nil {
+			return ErrCloudWatchDestinationInvalid
+		}
+	}
+
+	return nil
+}
+
+// Process : starts processing the current message
+func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := json.Unmarshal(ev.Body, &ev); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := ev.Validate(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Error : Will respond the current event with an error
+func (ev *Event) Error(err error) {
+	log.Printf("Error: %s", err.Error())
+	ev.ErrorMessage = err.Error()
+	ev.State = "errored"
+
+	ev.Body, err = json.Marshal(ev)
+}
+
+// Complete : sets the state of the event to completed
+func (ev *Event) Complete() {
+	ev.State = "completed"
+}
+
+// Find : Find an object on aws
+func (ev *Event) Find() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// Create : Creates a flow log on aws. Flow logs on instances are created
+// on their primary network interface
+func (ev *Event) Create() error {
+	st, err := ev.client().Create(context.Background(), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.FlowLogAWSID = aws.String(st.ID)
+
+	return nil
+}
+
+// Update : Updates a flow log on aws. Flow logs can't be modified, so a
+// change on the traffic type, destination or format replaces it
+func (ev *Event) Update() error {
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.FlowLogAWSID), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.FlowLogAWSID = aws.String(st.ID)
+
+	return nil
+}
+
+// Delete : Deletes a flow log on aws
+func (ev *Event) Delete() error {
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.FlowLogAWSID))
+}
+
+// Get : Gets a object on aws
+func (ev *Event) Get() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// GetBody : Gets the body for this event
+func (ev *Event) GetBody() []byte {
+	var err error
+	if ev.Body, err = json.Marshal(ev); err != nil {
+		log.Println(err.Error())
+	}
+	return ev.Body
+}
+
+// GetSubject : Gets the subject for this event
+func (ev *Event) GetSubject() string {
+	return ev.Subject
+}
+
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+}
+
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:            aws.StringValue(ev.Name),
+		ResourceID:      ev.ResourceID,
+		ResourceType:    ev.ResourceType,
+		TrafficType:     aws.StringValue(ev.TrafficType),
+		DestinationType: aws.StringValue(ev.DestinationType),
+		LogGroupName:    aws.StringValue(ev.LogGroupName),
+		IAMRoleARN:      aws.StringValue(ev.IAMRoleARN),
+		LogDestination:  aws.StringValue(ev.LogDestination),
+		LogFormat:       aws.StringValue(ev.LogFormat),
+		Tags:            ev.Tags,
+	}
+}
@@ -0,0 +1,196 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package flowlog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "flow_log.create.aws",
+			body:     `{"name":"logs","resource_id":"$vpc","traffic_type":"ALL","log_group_name":"logs","iam_role_arn":"arn:aws:iam::000000000000:role/logs","tags":{"Name":"logs"}}`,
+			expected: "flow_log.create.aws.done",
+			save:     map[string]string{"id": "flow_log_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				fl := b.EC2.FlowLogs[ids["id"]]
+				return fl != nil && *fl.ResourceId == ids["vpc"] && len(fl.Tags) == 1
+			},
+		},
+		{
+			name:     "update keeps an unchanged flow log",
+			subject:  "flow_log.update.aws",
+			body:     `{"flow_log_aws_id":"$id","resource_id":"$vpc","traffic_type":"ALL","log_group_name":"logs","iam_role_arn":"arn:aws:iam::000000000000:role/logs","tags":{"Name":"logs"}}`,
+			expected: "flow_log.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return res["flow_log_aws_id"] == ids["id"] && len(b.EC2.FlowLogs) == 1
+			},
+		},
+		{
+			name:     "update replaces a changed flow log",
+			subject:  "flow_log.update.aws",
+			body:     `{"flow_log_aws_id":"$id","resource_id":"$vpc","traffic_type":"REJECT","log_group_name":"logs","iam_role_arn":"arn:aws:iam::000000000000:role/logs","tags":{"Name":"logs"}}`,
+			expected: "flow_log.update.aws.done",
+			save:     map[string]string{"id": "flow_log_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				fl := b.EC2.FlowLogs[ids["id"]]
+				return fl != nil && *fl.TrafficType == "REJECT" && len(b.EC2.FlowLogs) == 1
+			},
+		},
+		{
+			name:     "find",
+			subject:  "flow_log.find.aws",
+			body:     `{"resource_id":"$vpc"}`,
+			expected: "flow_log.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				fl := found[0].(map[string]interface{})
+				return fl["flow_log_aws_id"] == ids["id"] && fl["resource_type"] == "VPC" && fl["log_group_name"] == "logs"
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "flow_log.delete.aws",
+			body:     `{"flow_log_aws_id":"$id"}`,
+			expected: "flow_log.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.EC2.FlowLogs) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails",
+			operation: "CreateFlowLogs",
+			subject:   "flow_log.create.aws",
+			body:      `{"resource_id":"$vpc","traffic_type":"ALL","destination_type":"s3","log_destination":"arn:aws:s3:::logs"}`,
+		},
+		{
+			name:      "update fails describing the flow log",
+			operation: "DescribeFlowLogs",
+			subject:   "flow_log.update.aws",
+			body:      `{"flow_log_aws_id":"fl-00000001","resource_id":"$vpc","traffic_type":"ALL","destination_type":"s3","log_destination":"arn:aws:s3:::logs"}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteFlowLogs",
+			subject:   "flow_log.delete.aws",
+			body:      `{"flow_log_aws_id":"fl-00000001"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, map[string]string{"vpc": createVpc(t, b)})
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestFindInstanceFlowLogs(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	var subnet ec2.CreateSubnetOutput
+	if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(createVpc(t, b)), CidrBlock: aws.String("10.0.1.0/24")}, &subnet); err != nil {
+		t.Fatal(err)
+	}
+
+	var r ec2.Reservation
+	if err := b.EC2.RunInstances(&ec2.RunInstancesInput{SubnetId: subnet.Subnet.SubnetId, ImageId: aws.String("ami-0a1b2c3d")}, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]string{"instance": *r.Instances[0].InstanceId}
+
+	subject, res := awsfake.Run(t, New, "flow_log.create.aws", `{"name":"logs","resource_id":"$instance","traffic_type":"ALL","log_group_name":"logs","iam_role_arn":"arn:aws:iam::000000000000:role/logs"}`, ids)
+	if subject != "flow_log.create.aws.done" {
+		t.Fatalf("expected flow_log.create.aws.done, got %s: %v", subject, res["error"])
+	}
+
+	subject, res = awsfake.Run(t, New, "flow_log.find.aws", `{"resource_id":"$instance"}`, ids)
+	if subject != "flow_log.find.aws.done" {
+		t.Fatalf("expected flow_log.find.aws.done, got %s: %v", subject, res["error"])
+	}
+
+	found, _ := res["components"].([]interface{})
+	if len(found) != 1 {
+		t.Fatalf("expected the flow log of the instance interface, got %v", found)
+	}
+
+	fl := found[0].(map[string]interface{})
+	if fl["resource_id"] != ids["instance"] || fl["resource_type"] != "Instance" {
+		t.Errorf("expected the flow log on the instance, got %v", fl)
+	}
+}
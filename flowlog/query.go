@@ -0,0 +1,192 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package flowlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
+)
+
+// Collection ....
+type Collection struct {
+	ProviderType       string            `json:"_provider"`
+	ComponentType      string            `json:"_component"`
+	ComponentID        string            `json:"_component_id"`
+	State              string            `json:"_state"`
+	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
+	Service            string            `json:"service"`
+	AWSAccessKeyID     string            `json:"aws_access_key_id"`
+	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
+	DatacenterRegion   string            `json:"datacenter_region"`
+	ResourceID         string            `json:"resource_id"`
+	Tags               map[string]string `json:"tags"`
+	Results            []interface{}     `json:"components"`
+	ErrorMessage       string            `json:"error,omitempty"`
+	Subject            string            `json:"-"`
+	Body               []byte            `json:"-"`
+	CryptoKey          string            `json:"-"`
+}
+
+// GetBody : Gets the body for this event
+func (col *Collection) GetBody() []byte {
+	var err error
+	if col.Body, err = json.Marshal(col); err != nil {
+		log.Println(err.Error())
+	}
+	return col.Body
+}
+
+// GetSubject : Gets the subject for this event
+func (col *Collection) GetSubject() string {
+	return col.Subject
+}
+
+// Process : starts processing the current message
+func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
+	if err := json.Unmarshal(col.Body, &col); err != nil {
+		col.Error(err)
+		return err
+	}
+
+	if err := col.Validate(); err != nil {
+		col.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Error : Will respond the current event with an error
+func (col *Collection) Error(err error) {
+	log.Printf("Error: %s", err.Error())
+	col.ErrorMessage = err.Error()
+	col.State = "errored"
+
+	col.Body, err = json.Marshal(col)
+}
+
+// Complete : sets the state of the event to completed
+func (col *Collection) Complete() {
+	col.State = "completed"
+}
+
+// Validate checks if all criteria are met
+func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
+	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
+		return ErrDatacenterCredentialsInvalid
+	}
+
+	return nil
+}
+
+// Get : Gets a object on aws
+func (col *Collection) Get() error {
+	return errors.New(col.Subject + " not supported")
+}
+
+// Create : Creates an object on aws
+func (col *Collection) Create() error {
+	return errors.New(col.Subject + " not supported")
+}
+
+// Update : Updates an object on aws
+func (col *Collection) Update() error {
+	return errors.New(col.Subject + " not supported")
+}
+
+// Delete : Delete an object on aws
+func (col *Collection) Delete() error {
+	return errors.New(col.Subject + " not supported")
+}
+
+// Find : Find flow logs on aws, by the id of the resource they are
+// created on or by tags. Flow logs of instances are looked up on their
+// primary network interface, as they are created there
+func (col *Collection) Find() error {
+	logs, err := col.client().FindByResource(context.Background(), col.ResourceID, col.Tags)
+	if err != nil {
+		return err
+	}
+
+	for _, st := range logs {
+		col.Results = append(col.Results, toEvent(st))
+	}
+
+	return nil
+}
+
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
+}
+
+func mapFilters(tags map[string]string) []*ec2.Filter {
+	var f []*ec2.Filter
+
+	for key, val := range tags {
+		f = append(f, &ec2.Filter{
+			Name:   aws.String("tag:" + key),
+			Values: []*string{aws.String(val)},
+		})
+	}
+
+	return f
+}
+
+// toEvent converts a flow log status to an ernest event
+func toEvent(st Status) *Event {
+	return &Event{
+		ProviderType:    "aws",
+		ComponentType:   "flow_log",
+		ComponentID:     "flow_log::" + st.Name,
+		FlowLogAWSID:    aws.String(st.ID),
+		Name:            aws.String(st.Name),
+		ResourceID:      st.ResourceID,
+		ResourceType:    st.ResourceType,
+		TrafficType:     optional(st.TrafficType),
+		DestinationType: optional(st.DestinationType),
+		LogGroupName:    optional(st.LogGroupName),
+		IAMRoleARN:      optional(st.IAMRoleARN),
+		LogDestination:  optional(st.LogDestination),
+		LogFormat:       optional(st.LogFormat),
+		Tags:            st.Tags,
+	}
+}
+
+func mapEC2Tags(input []*ec2.Tag) map[string]string {
+	t := make(map[string]string)
+
+	for _, tag := range input {
+		t[*tag.Key] = *tag.Value
+	}
+
+	return t
+}
@@ -0,0 +1,37 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package flowlog
+
+import "github.com/ernestio/ernestaws/schema"
+
+var options = []schema.Field{
+	{Name: "resource_id", Type: schema.String, Required: true},
+	{Name: "resource_type", Type: schema.String, Enum: []string{"VPC", "Subnet", "NetworkInterface", "Instance"}},
+	{Name: "traffic_type", Type: schema.String, Required: true, Enum: []string{"ACCEPT", "REJECT", "ALL"}},
+	{Name: "destination_type", Type: schema.String, Enum: []string{"cloud-watch-logs", "s3"}},
+	{Name: "log_group_name", Type: schema.String},
+	{Name: "iam_role_arn", Type: schema.String, Format: schema.ARN},
+	{Name: "log_destination", Type: schema.String, Format: schema.ARN},
+	{Name: "log_format", Type: schema.String},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("flow_log", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), options, []schema.Field{
+			{Name: "name", Type: schema.String},
+		}),
+		"update": schema.Fields(schema.Datacenter(), options, []schema.Field{
+			{Name: "flow_log_aws_id", Type: schema.String, Required: true},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "flow_log_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "resource_id", Type: schema.String},
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
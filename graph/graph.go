@@ -0,0 +1,296 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/discovery"
+)
+
+// Node kinds
+const (
+	VPC              = "vpc"
+	Subnet           = "subnet"
+	RouteTable       = "route_table"
+	InternetGateway  = "internet_gateway"
+	NatGateway       = "nat_gateway"
+	SecurityGroup    = "security_group"
+	Instance         = "instance"
+	Volume           = "volume"
+	ELB              = "elb"
+	RDSCluster       = "rds_cluster"
+	RDSInstance      = "rds_instance"
+	AvailabilityZone = "availability_zone"
+	Zone             = "route53_zone"
+	VpcPeering       = "vpc_peering"
+	FlowLog          = "flow_log"
+	DHCPOptions      = "dhcp_options"
+	VpcEndpoint      = "vpc_endpoint"
+	NetworkACL       = "network_acl"
+)
+
+// kinds of the resources flow logs are created on, keyed by the prefix
+// of their ids
+var resourceKinds = map[string]string{
+	"vpc":    VPC,
+	"subnet": Subnet,
+	"i":      Instance,
+}
+
+// Node is a resource on the graph, identified by its aws id
+type Node struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Graph stores the resources and their dependencies. An edge from a to b
+// means a depends on b, like an instance on its subnet
+type Graph struct {
+	nodes map[string]*Node
+	edges map[string]map[string]bool
+}
+
+// New : Constructor
+func New() *Graph {
+	return &Graph{
+		nodes: make(map[string]*Node),
+		edges: make(map[string]map[string]bool),
+	}
+}
+
+// Build : creates the graph of the discovered resources and the route
+// tables of their vpcs
+func Build(r *discovery.Resources, tables []*ec2.RouteTable) *Graph {
+	g := New()
+
+	for _, v := range r.VPCs {
+		g.Add(aws.StringValue(v.VpcID), VPC, v.Name)
+	}
+
+	for _, v := range r.Networks {
+		id := aws.StringValue(v.NetworkAWSID)
+		g.Add(id, Subnet, aws.StringValue(v.Name))
+		g.Link(id, VPC, v.VpcID)
+		g.Link(id, AvailabilityZone, aws.StringValue(v.AvailabilityZone))
+	}
+
+	for _, v := range r.InternetGateways {
+		id := aws.StringValue(v.InternetGatewayAWSID)
+		g.Add(id, InternetGateway, aws.StringValue(v.Name))
+		g.Link(id, VPC, v.VpcID)
+	}
+
+	for _, v := range r.NatGateways {
+		id := aws.StringValue(v.NatGatewayAWSID)
+		g.Add(id, NatGateway, aws.StringValue(v.Name))
+		g.Link(id, Subnet, aws.StringValue(v.PublicNetworkAWSID))
+	}
+
+	for _, t := range tables {
+		id := aws.StringValue(t.RouteTableId)
+		g.Add(id, RouteTable, tagValue(t.Tags, "Name"))
+		g.Link(id, VPC, aws.StringValue(t.VpcId))
+
+		for _, a := range t.Associations {
+			g.Link(aws.StringValue(a.SubnetId), RouteTable, id)
+		}
+
+		for _, rt := range t.Routes {
+			if aws.StringValue(rt.NatGatewayId) != "" {
+				g.Link(id, NatGateway, aws.StringValue(rt.NatGatewayId))
+			}
+
+			if gw := aws.StringValue(rt.GatewayId); gw != "" && gw != "local" {
+				g.Link(id, InternetGateway, gw)
+			}
+		}
+	}
+
+	for _, v := range r.Firewalls {
+		id := aws.StringValue(v.SecurityGroupAWSID)
+		g.Add(id, SecurityGroup, aws.StringValue(v.Name))
+		g.Link(id, VPC, v.VpcID)
+	}
+
+	for _, v := range r.Volumes {
+		id := aws.StringValue(v.VolumeAWSID)
+		g.Add(id, Volume, aws.StringValue(v.Name))
+		g.Link(id, AvailabilityZone, aws.StringValue(v.AvailabilityZone))
+	}
+
+	for _, v := range r.Instances {
+		id := aws.StringValue(v.InstanceAWSID)
+		g.Add(id, Instance, aws.StringValue(v.Name))
+		g.Link(id, Subnet, aws.StringValue(v.NetworkAWSID))
+		g.LinkAll(id, SecurityGroup, v.SecurityGroupAWSIDs)
+
+		for _, vol := range v.Volumes {
+			g.Link(id, Volume, aws.StringValue(vol.VolumeAWSID))
+		}
+	}
+
+	for _, v := range r.ELBs {
+		id := "elb:" + aws.StringValue(v.Name)
+		g.Add(id, ELB, aws.StringValue(v.Name))
+		g.LinkAll(id, Subnet, v.NetworkAWSIDs)
+		g.LinkAll(id, SecurityGroup, v.SecurityGroupAWSIDs)
+		g.LinkAll(id, Instance, v.InstanceAWSIDs)
+	}
+
+	for _, v := range r.RDSClusters {
+		id := aws.StringValue(v.ARN)
+		g.Add(id, RDSCluster, aws.StringValue(v.Name))
+		g.LinkAll(id, Subnet, v.NetworkAWSIDs)
+		g.LinkAll(id, SecurityGroup, v.SecurityGroupAWSIDs)
+		g.LinkAll(id, AvailabilityZone, v.AvailabilityZones)
+	}
+
+	clusters := make(map[string]string)
+	for _, v := range r.RDSClusters {
+		clusters[aws.StringValue(v.Name)] = aws.StringValue(v.ARN)
+	}
+
+	for _, v := range r.RDSInstances {
+		id := aws.StringValue(v.ARN)
+		g.Add(id, RDSInstance, aws.StringValue(v.Name))
+		g.LinkAll(id, Subnet, v.NetworkAWSIDs)
+		g.LinkAll(id, SecurityGroup, v.SecurityGroupAWSIDs)
+		g.Link(id, AvailabilityZone, aws.StringValue(v.AvailabilityZone))
+
+		if arn, ok := clusters[aws.StringValue(v.Cluster)]; ok {
+			g.Link(id, RDSCluster, arn)
+		}
+	}
+
+	for _, v := range r.Zones {
+		id := aws.StringValue(v.HostedZoneID)
+		g.Add(id, Zone, aws.StringValue(v.Name))
+		g.Link(id, VPC, aws.StringValue(v.VpcID))
+	}
+
+	for _, v := range r.VpcPeerings {
+		id := aws.StringValue(v.VpcPeeringAWSID)
+		g.Add(id, VpcPeering, aws.StringValue(v.Name))
+		g.Link(id, VPC, v.VpcID)
+		g.Link(id, VPC, v.PeerVpcID)
+		g.LinkAll(id, RouteTable, v.RouteTableAWSIDs)
+	}
+
+	for _, v := range r.FlowLogs {
+		id := aws.StringValue(v.FlowLogAWSID)
+		g.Add(id, FlowLog, aws.StringValue(v.Name))
+
+		if kind, ok := resourceKinds[strings.Split(v.ResourceID, "-")[0]]; ok {
+			g.Link(id, kind, v.ResourceID)
+		}
+	}
+
+	for _, v := range r.DHCPOptions {
+		id := aws.StringValue(v.DHCPOptionsAWSID)
+		g.Add(id, DHCPOptions, aws.StringValue(v.Name))
+		g.Link(id, VPC, v.VpcID)
+	}
+
+	for _, v := range r.VpcEndpoints {
+		id := aws.StringValue(v.VpcEndpointAWSID)
+		g.Add(id, VpcEndpoint, aws.StringValue(v.Name))
+		g.Link(id, VPC, v.VpcID)
+		g.LinkAll(id, RouteTable, v.RouteTableAWSIDs)
+		g.LinkAll(id, Subnet, v.NetworkAWSIDs)
+		g.LinkAll(id, SecurityGroup, v.SecurityGroupAWSIDs)
+	}
+
+	for _, v := range r.NetworkACLs {
+		id := aws.StringValue(v.NetworkACLAWSID)
+		g.Add(id, NetworkACL, aws.StringValue(v.Name))
+		g.Link(id, VPC, v.VpcID)
+		g.LinkAll(id, Subnet, aws.StringSlice(v.NetworkAWSIDs))
+	}
+
+	return g
+}
+
+// Add : adds a resource, updating it if it was already linked
+func (g *Graph) Add(id, kind, name string) {
+	if id == "" {
+		return
+	}
+
+	if name == "" {
+		name = id
+	}
+
+	g.nodes[id] = &Node{ID: id, Kind: kind, Name: name}
+}
+
+// Link : adds a dependency of the resource from on the resource to. Not
+// discovered dependencies are added as nodes named after their id
+func (g *Graph) Link(from, kind, to string) {
+	if from == "" || to == "" {
+		return
+	}
+
+	if _, ok := g.nodes[to]; !ok {
+		g.Add(to, kind, to)
+	}
+
+	if g.edges[from] == nil {
+		g.edges[from] = make(map[string]bool)
+	}
+
+	g.edges[from][to] = true
+}
+
+// LinkAll : adds a dependency on each of the given resources
+func (g *Graph) LinkAll(from, kind string, to []*string) {
+	for _, id := range to {
+		g.Link(from, kind, aws.StringValue(id))
+	}
+}
+
+// Nodes : returns the resources sorted by kind and id
+func (g *Graph) Nodes() []*Node {
+	var nodes []*Node
+
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Kind != nodes[j].Kind {
+			return nodes[i].Kind < nodes[j].Kind
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+
+	return nodes
+}
+
+// Dependencies : returns the sorted ids the resource depends on
+func (g *Graph) Dependencies(id string) []string {
+	var ids []string
+
+	for to := range g.edges[id] {
+		ids = append(ids, to)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+func tagValue(tags []*ec2.Tag, key string) string {
+	for _, t := range tags {
+		if aws.StringValue(t.Key) == key {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// shapes of each kind of node on dot
+var shapes = map[string]string{
+	VPC:              "box3d",
+	Subnet:           "box",
+	RouteTable:       "note",
+	InternetGateway:  "invhouse",
+	NatGateway:       "house",
+	SecurityGroup:    "octagon",
+	Instance:         "component",
+	Volume:           "cylinder",
+	ELB:              "diamond",
+	RDSCluster:       "folder",
+	RDSInstance:      "cylinder",
+	AvailabilityZone: "ellipse",
+	Zone:             "tab",
+	VpcPeering:       "cds",
+	FlowLog:          "note",
+	DHCPOptions:      "hexagon",
+	VpcEndpoint:      "circle",
+	NetworkACL:       "septagon",
+}
+
+// DOT : renders the graph on the graphviz dot format
+func (g *Graph) DOT() []byte {
+	var b bytes.Buffer
+
+	b.WriteString("digraph aws {\n")
+	b.WriteString("\trankdir=LR;\n")
+
+	nodes := g.Nodes()
+
+	for _, n := range nodes {
+		shape, ok := shapes[n.Kind]
+		if !ok {
+			shape = "box"
+		}
+
+		fmt.Fprintf(&b, "\t%q [label=%q, shape=%s];\n", n.ID, n.Name+"\n"+n.Kind, shape)
+	}
+
+	for _, n := range nodes {
+		for _, to := range g.Dependencies(n.ID) {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", n.ID, to)
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
+
+// adjacency is the json representation of the graph
+type adjacency struct {
+	Nodes     []*Node             `json:"nodes"`
+	Adjacency map[string][]string `json:"adjacency"`
+}
+
+// JSON : renders the graph as the list of nodes and the ids each of them
+// depends on
+func (g *Graph) JSON() ([]byte, error) {
+	a := adjacency{
+		Nodes:     g.Nodes(),
+		Adjacency: make(map[string][]string),
+	}
+
+	for _, n := range a.Nodes {
+		a.Adjacency[n.ID] = []string{}
+		if deps := g.Dependencies(n.ID); len(deps) > 0 {
+			a.Adjacency[n.ID] = deps
+		}
+	}
+
+	return json.MarshalIndent(a, "", "  ")
+}
@@ -0,0 +1,59 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package graph
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/discovery"
+)
+
+// Discover : discovers the resources on the scope and builds their graph.
+// Route tables don't have a component, so they are described directly
+func Discover(s discovery.Scope) (*Graph, error) {
+	r, err := discovery.Discover(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var vpcs []*string
+	for _, v := range r.VPCs {
+		vpcs = append(vpcs, v.VpcID)
+	}
+
+	tables, err := routeTables(s.Account, vpcs)
+	if err != nil {
+		return nil, err
+	}
+
+	return Build(r, tables), nil
+}
+
+func routeTables(a client.Account, vpcs []*string) ([]*ec2.RouteTable, error) {
+	if len(vpcs) == 0 {
+		return nil, nil
+	}
+
+	svc := ec2.New(client.Session("graph", ""), a.Config())
+
+	req := ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("vpc-id"),
+				Values: vpcs,
+			},
+		},
+	}
+
+	var tables []*ec2.RouteTable
+
+	err := svc.DescribeRouteTablesPages(&req, func(resp *ec2.DescribeRouteTablesOutput, last bool) bool {
+		tables = append(tables, resp.RouteTables...)
+		return true
+	})
+
+	return tables, err
+}
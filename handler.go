@@ -9,10 +9,17 @@ import (
 )
 
 // Handle : Handles the given event
-func Handle(ev *Event) (string, []byte) {
+func Handle(ev *Event) (subject string, body []byte) {
 	var err error
 
 	n := *ev
+
+	defer func() {
+		if r := recover(); r != nil {
+			subject, body = recovered(n, r)
+		}
+	}()
+
 	if err = n.Process(); err != nil {
 		return n.GetSubject() + ".error", n.GetBody()
 	}
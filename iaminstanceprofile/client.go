@@ -0,0 +1,185 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iaminstanceprofile
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// propagationDelay is how long a new instance profile is given before
+// instances can use it, as iam is eventually consistent
+var propagationDelay = 10 * time.Second
+
+// Spec describes the desired state of an iam instance profile. The path
+// is left to the aws default when empty
+type Spec struct {
+	Name  string
+	Roles []string
+	Path  string
+}
+
+// Status describes an iam instance profile as it is on aws
+type Status struct {
+	ID    string
+	ARN   string
+	Name  string
+	Roles []string
+	Path  string
+}
+
+// Client manages iam instance profiles through a typed api, the json
+// events are an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates an iam instance profile with the roles of the spec
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getIAMClient()
+
+	req := &iam.CreateInstanceProfileInput{
+		InstanceProfileName: aws.String(s.Name),
+	}
+
+	if s.Path != "" {
+		req.Path = aws.String(s.Path)
+	}
+
+	resp, err := svc.CreateInstanceProfileWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	wreq := &iam.GetInstanceProfileInput{
+		InstanceProfileName: aws.String(s.Name),
+	}
+
+	err = svc.WaitUntilInstanceProfileExistsWithContext(ctx, wreq)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(resp.InstanceProfile)
+	st.Roles = s.Roles
+
+	for _, role := range s.Roles {
+		areq := &iam.AddRoleToInstanceProfileInput{
+			InstanceProfileName: aws.String(s.Name),
+			RoleName:            aws.String(role),
+		}
+
+		_, err = svc.AddRoleToInstanceProfileWithContext(ctx, areq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	// because eventual consistency sucks
+	select {
+	case <-ctx.Done():
+		return Status{}, ctx.Err()
+	case <-time.After(propagationDelay):
+	}
+
+	return st, nil
+}
+
+// Delete : removes the given roles from an iam instance profile and
+// deletes it
+func (c Client) Delete(ctx context.Context, name string, roles []string) error {
+	svc := c.getIAMClient()
+
+	for _, role := range roles {
+		dreq := &iam.RemoveRoleFromInstanceProfileInput{
+			InstanceProfileName: aws.String(name),
+			RoleName:            aws.String(role),
+		}
+
+		_, err := svc.RemoveRoleFromInstanceProfileWithContext(ctx, dreq)
+		if err != nil {
+			return err
+		}
+	}
+
+	req := &iam.DeleteInstanceProfileInput{
+		InstanceProfileName: aws.String(name),
+	}
+
+	_, err := svc.DeleteInstanceProfileWithContext(ctx, req)
+
+	return err
+}
+
+// Find : returns the iam instance profiles having all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getIAMClient()
+
+	resp, err := svc.ListInstanceProfilesWithContext(ctx, &iam.ListInstanceProfilesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []Status
+
+	for _, p := range resp.InstanceProfiles {
+		if len(tags) > 0 {
+			resp, err := svc.ListInstanceProfileTagsWithContext(ctx, &iam.ListInstanceProfileTagsInput{InstanceProfileName: p.InstanceProfileName})
+			if err != nil {
+				return nil, err
+			}
+
+			if !hasTags(resp.Tags, tags) {
+				continue
+			}
+		}
+
+		profiles = append(profiles, toStatus(p))
+	}
+
+	return profiles, nil
+}
+
+func (c Client) getIAMClient() *iam.IAM {
+	return iam.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func toStatus(p *iam.InstanceProfile) Status {
+	st := Status{
+		ID:   aws.StringValue(p.InstanceProfileId),
+		ARN:  aws.StringValue(p.Arn),
+		Name: aws.StringValue(p.InstanceProfileName),
+		Path: aws.StringValue(p.Path),
+	}
+
+	for _, role := range p.Roles {
+		st.Roles = append(st.Roles, aws.StringValue(role.RoleName))
+	}
+
+	return st
+}
+
+// hasTags : checks the tags of a resource include all the given ones
+func hasTags(current []*iam.Tag, tags map[string]string) bool {
+	t := make(map[string]string)
+
+	for _, tag := range current {
+		t[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	for k, v := range tags {
+		if t[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
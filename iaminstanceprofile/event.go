@@ -5,17 +5,16 @@
 package iaminstanceprofile
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -38,6 +37,7 @@ type Event struct {
 	ComponentID             string    `json:"_component_id"`
 	State                   string    `json:"_state"`
 	Action                  string    `json:"_action"`
+	SchemaVersion           int       `json:"_schema_version"`
 	IAMInstanceProfileAWSID *string   `json:"iam_instance_profile_aws_id"`
 	IAMInstanceProfileARN   *string   `json:"iam_instance_profile_arn"`
 	Name                    *string   `json:"name"`
@@ -64,6 +64,10 @@ func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -83,6 +87,11 @@ func (ev *Event) Validate() error {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -117,44 +126,13 @@ func (ev *Event) Find() error {
 
 // Create : Creates a role object on aws
 func (ev *Event) Create() error {
-	svc := ev.getIAMClient()
-
-	req := &iam.CreateInstanceProfileInput{
-		InstanceProfileName: ev.Name,
-		Path:                ev.Path,
-	}
-
-	resp, err := svc.CreateInstanceProfile(req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	wreq := &iam.GetInstanceProfileInput{
-		InstanceProfileName: ev.Name,
-	}
-
-	err = svc.WaitUntilInstanceProfileExists(wreq)
-	if err != nil {
-		return err
-	}
-
-	ev.IAMInstanceProfileAWSID = resp.InstanceProfile.InstanceProfileId
-	ev.IAMInstanceProfileARN = resp.InstanceProfile.Arn
-
-	for _, role := range ev.Roles {
-		areq := &iam.AddRoleToInstanceProfileInput{
-			InstanceProfileName: ev.Name,
-			RoleName:            role,
-		}
-
-		_, err = svc.AddRoleToInstanceProfile(areq)
-		if err != nil {
-			return err
-		}
-	}
-
-	// because eventual consistency sucks
-	time.Sleep(time.Second * 10)
+	ev.IAMInstanceProfileAWSID = aws.String(st.ID)
+	ev.IAMInstanceProfileARN = aws.String(st.ARN)
 
 	return nil
 }
@@ -166,27 +144,7 @@ func (ev *Event) Update() error {
 
 // Delete : Deletes a role object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getIAMClient()
-
-	for _, role := range ev.Roles {
-		dreq := &iam.RemoveRoleFromInstanceProfileInput{
-			InstanceProfileName: ev.Name,
-			RoleName:            role,
-		}
-
-		_, err := svc.RemoveRoleFromInstanceProfile(dreq)
-		if err != nil {
-			return err
-		}
-	}
-
-	req := &iam.DeleteInstanceProfileInput{
-		InstanceProfileName: ev.Name,
-	}
-
-	_, err := svc.DeleteInstanceProfile(req)
-
-	return err
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.Name), aws.StringValueSlice(ev.Roles))
 }
 
 // Get : Gets a role object on aws
@@ -208,10 +166,26 @@ func (ev *Event) GetSubject() string {
 	return ev.Subject
 }
 
-func (ev *Event) getIAMClient() *iam.IAM {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return iam.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+}
+
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:  aws.StringValue(ev.Name),
+		Roles: aws.StringValueSlice(ev.Roles),
+		Path:  aws.StringValue(ev.Path),
+	}
 }
@@ -0,0 +1,177 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iaminstanceprofile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createRole(t *testing.T, b *awsfake.Backend) {
+	var out iam.CreateRoleOutput
+
+	in := &iam.CreateRoleInput{
+		RoleName:                 aws.String("app"),
+		AssumeRolePolicyDocument: aws.String("{}"),
+	}
+
+	if err := b.IAM.CreateRole(in, &out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	propagationDelay = 0
+
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	createRole(t, b)
+
+	ids := map[string]string{}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "iam_instance_profile.create.aws",
+			body:     `{"name":"app","path":"/app/","roles":["app"]}`,
+			expected: "iam_instance_profile.create.aws.done",
+			save:     map[string]string{"id": "iam_instance_profile_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				return res["iam_instance_profile_arn"] != nil && len(b.IAM.InstanceProfiles["app"].Roles) == 1
+			},
+		},
+		{
+			name:     "update is not supported",
+			subject:  "iam_instance_profile.update.aws",
+			body:     `{"name":"app","iam_instance_profile_aws_id":"$id"}`,
+			expected: "iam_instance_profile.update.aws.error",
+		},
+		{
+			name:     "find",
+			subject:  "iam_instance_profile.find.aws",
+			body:     `{}`,
+			expected: "iam_instance_profile.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				p := found[0].(map[string]interface{})
+				roles, _ := p["roles"].([]interface{})
+				return p["iam_instance_profile_aws_id"] == ids["id"] && p["path"] == "/app/" && len(roles) == 1 && roles[0] == "app"
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "iam_instance_profile.delete.aws",
+			body:     `{"name":"app","iam_instance_profile_aws_id":"$id","roles":["app"]}`,
+			expected: "iam_instance_profile.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.IAM.InstanceProfiles) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	propagationDelay = 0
+
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails adding the roles",
+			operation: "AddRoleToInstanceProfile",
+			subject:   "iam_instance_profile.create.aws",
+			body:      `{"name":"app","roles":["app"]}`,
+		},
+		{
+			name:      "find fails",
+			operation: "ListInstanceProfiles",
+			subject:   "iam_instance_profile.find.aws",
+			body:      `{}`,
+		},
+		{
+			name:      "delete fails removing the roles",
+			operation: "RemoveRoleFromInstanceProfile",
+			subject:   "iam_instance_profile.delete.aws",
+			body:      `{"name":"app","iam_instance_profile_aws_id":"AIPA1","roles":["app"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			b.Fail("iam", tt.operation, "InternalError", 1)
+
+			createRole(t, b)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, nil)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestFindByTags(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{}
+
+	for _, name := range []string{"app", "web"} {
+		_, res := awsfake.Run(t, New, "iam_instance_profile.create.aws", `{"name":"`+name+`"}`, nil)
+		ids[name], _ = res["name"].(string)
+	}
+
+	b.IAM.InstanceProfiles[ids["web"]].Tags = []*iam.Tag{{Key: aws.String("team"), Value: aws.String("web")}}
+
+	_, res := awsfake.Run(t, New, "iam_instance_profile.find.aws", `{"tags":{"team":"web"}}`, nil)
+
+	found, _ := res["components"].([]interface{})
+	if len(found) != 1 || found[0].(map[string]interface{})["name"] != "web" {
+		t.Errorf("expected the tagged instance profile, got %v", found)
+	}
+}
@@ -5,14 +5,14 @@
 package iaminstanceprofile
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/iam"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +22,7 @@ type Collection struct {
 	ComponentID      string            `json:"_component_id"`
 	State            string            `json:"_state"`
 	Action           string            `json:"_action"`
+	SchemaVersion    int               `json:"_schema_version"`
 	Service          string            `json:"service"`
 	AccessKeyID      string            `json:"aws_access_key_id"`
 	SecretAccessKey  string            `json:"aws_secret_access_key"`
@@ -50,6 +51,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +85,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AccessKeyID == "" || col.SecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -108,43 +118,47 @@ func (col *Collection) Delete() error {
 
 // Find : Find networks on aws
 func (col *Collection) Find() error {
-	svc := col.getIAMClient()
-
-	resp, err := svc.ListInstanceProfiles(&iam.ListInstanceProfilesInput{})
+	profiles, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, p := range resp.InstanceProfiles {
-		col.Results = append(col.Results, toEvent(p))
+	for _, st := range profiles {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getIAMClient() *iam.IAM {
-	creds, _ := credentials.NewStaticCredentials(col.AccessKeyID, col.SecretAccessKey, col.CryptoKey)
-	return iam.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-// ToEvent converts an ec2 subnet object to an ernest event
-func toEvent(r *iam.InstanceProfile) *Event {
-	var roles []*string
-	for _, role := range r.Roles {
-		roles = append(roles, role.RoleName)
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AccessKeyID,
+			SecretAccessKey: col.SecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
 	}
+}
 
-	return &Event{
+// toEvent converts an iam instance profile status to an ernest event
+func toEvent(st Status) *Event {
+	e := &Event{
 		ProviderType:            "aws",
 		ComponentType:           "iam_instance_profile",
-		ComponentID:             "iam_instance_profile::" + *r.InstanceProfileName,
-		IAMInstanceProfileAWSID: r.InstanceProfileId,
-		IAMInstanceProfileARN:   r.Arn,
-		Name:  r.InstanceProfileName,
-		Path:  r.Path,
-		Roles: roles,
+		ComponentID:             "iam_instance_profile::" + st.Name,
+		IAMInstanceProfileAWSID: aws.String(st.ID),
+		IAMInstanceProfileARN:   aws.String(st.ARN),
+		Name:                    aws.String(st.Name),
+		Path:                    aws.String(st.Path),
 	}
+
+	if len(st.Roles) > 0 {
+		e.Roles = aws.StringSlice(st.Roles)
+	}
+
+	return e
 }
@@ -0,0 +1,26 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iaminstanceprofile
+
+import "github.com/ernestio/ernestaws/schema"
+
+var profile = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "roles", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "path", Type: schema.String},
+}
+
+func init() {
+	schema.Register("iam_instance_profile", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), profile),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "name", Type: schema.String, Required: true},
+			{Name: "iam_instance_profile_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
@@ -0,0 +1,163 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iampolicy
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of an iam policy. The path and the
+// description are left to the aws defaults when empty
+type Spec struct {
+	Name           string
+	PolicyDocument string
+	Description    string
+	Path           string
+}
+
+// Status describes an iam policy as it is on aws
+type Status struct {
+	ID             string
+	ARN            string
+	Name           string
+	PolicyDocument string
+	Description    string
+	Path           string
+}
+
+// Client manages iam policies through a typed api, the json events are
+// an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates an iam policy
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getIAMClient()
+
+	req := &iam.CreatePolicyInput{
+		PolicyName:     aws.String(s.Name),
+		PolicyDocument: aws.String(s.PolicyDocument),
+	}
+
+	if s.Path != "" {
+		req.Path = aws.String(s.Path)
+	}
+
+	if s.Description != "" {
+		req.Description = aws.String(s.Description)
+	}
+
+	resp, err := svc.CreatePolicyWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(resp.Policy)
+	st.PolicyDocument = s.PolicyDocument
+
+	return st, nil
+}
+
+// Delete : deletes an iam policy
+func (c Client) Delete(ctx context.Context, arn string) error {
+	svc := c.getIAMClient()
+
+	req := &iam.DeletePolicyInput{
+		PolicyArn: aws.String(arn),
+	}
+
+	_, err := svc.DeletePolicyWithContext(ctx, req)
+
+	return err
+}
+
+// Find : returns the customer managed policies having all the given
+// tags, along with the document of their default version
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getIAMClient()
+
+	req := &iam.ListPoliciesInput{
+		Scope: aws.String("Local"),
+	}
+
+	resp, err := svc.ListPoliciesWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []Status
+
+	for _, p := range resp.Policies {
+		if len(tags) > 0 {
+			resp, err := svc.ListPolicyTagsWithContext(ctx, &iam.ListPolicyTagsInput{PolicyArn: p.Arn})
+			if err != nil {
+				return nil, err
+			}
+
+			if !hasTags(resp.Tags, tags) {
+				continue
+			}
+		}
+
+		st := toStatus(p)
+
+		req := &iam.GetPolicyVersionInput{
+			PolicyArn: p.Arn,
+			VersionId: p.DefaultVersionId,
+		}
+
+		resp, err := svc.GetPolicyVersionWithContext(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.PolicyVersion != nil && resp.PolicyVersion.Document != nil {
+			st.PolicyDocument, _ = url.QueryUnescape(*resp.PolicyVersion.Document)
+		}
+
+		policies = append(policies, st)
+	}
+
+	return policies, nil
+}
+
+func (c Client) getIAMClient() *iam.IAM {
+	return iam.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func toStatus(p *iam.Policy) Status {
+	return Status{
+		ID:          aws.StringValue(p.PolicyId),
+		ARN:         aws.StringValue(p.Arn),
+		Name:        aws.StringValue(p.PolicyName),
+		Description: aws.StringValue(p.Description),
+		Path:        aws.StringValue(p.Path),
+	}
+}
+
+// hasTags : checks the tags of a resource include all the given ones
+func hasTags(current []*iam.Tag, tags map[string]string) bool {
+	t := make(map[string]string)
+
+	for _, tag := range current {
+		t[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	for k, v := range tags {
+		if t[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
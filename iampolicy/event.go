@@ -5,16 +5,16 @@
 package iampolicy
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -37,6 +37,7 @@ type Event struct {
 	ComponentID      string  `json:"_component_id"`
 	State            string  `json:"_state"`
 	Action           string  `json:"_action"`
+	SchemaVersion    int     `json:"_schema_version"`
 	IAMPolicyAWSID   *string `json:"iam_policy_aws_id"`
 	IAMPolicyARN     *string `json:"iam_policy_arn"`
 	Name             *string `json:"name"`
@@ -64,6 +65,10 @@ func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -83,6 +88,11 @@ func (ev *Event) Validate() error {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -117,22 +127,13 @@ func (ev *Event) Find() error {
 
 // Create : Creates a role object on aws
 func (ev *Event) Create() error {
-	svc := ev.getIAMClient()
-
-	req := &iam.CreatePolicyInput{
-		PolicyName:     ev.Name,
-		PolicyDocument: ev.PolicyDocument,
-		Path:           ev.Path,
-		Description:    ev.Description,
-	}
-
-	resp, err := svc.CreatePolicy(req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	ev.IAMPolicyAWSID = resp.Policy.PolicyId
-	ev.IAMPolicyARN = resp.Policy.Arn
+	ev.IAMPolicyAWSID = aws.String(st.ID)
+	ev.IAMPolicyARN = aws.String(st.ARN)
 
 	return nil
 }
@@ -144,15 +145,7 @@ func (ev *Event) Update() error {
 
 // Delete : Deletes a role object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getIAMClient()
-
-	req := &iam.DeletePolicyInput{
-		PolicyArn: ev.IAMPolicyARN,
-	}
-
-	_, err := svc.DeletePolicy(req)
-
-	return err
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.IAMPolicyARN))
 }
 
 // Get : Gets a role object on aws
@@ -174,10 +167,27 @@ func (ev *Event) GetSubject() string {
 	return ev.Subject
 }
 
-func (ev *Event) getIAMClient() *iam.IAM {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return iam.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+}
+
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:           aws.StringValue(ev.Name),
+		PolicyDocument: aws.StringValue(ev.PolicyDocument),
+		Description:    aws.StringValue(ev.Description),
+		Path:           aws.StringValue(ev.Path),
+	}
 }
@@ -0,0 +1,156 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iampolicy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "iam_policy.create.aws",
+			body:     `{"name":"read","path":"/app/","description":"reads","policy_document":"{\"Version\":\"2012-10-17\"}"}`,
+			expected: "iam_policy.create.aws.done",
+			save:     map[string]string{"id": "iam_policy_aws_id", "arn": "iam_policy_arn"},
+			check: func(res map[string]interface{}) bool {
+				return b.IAM.PolicyDocuments[ids["arn"]] == `{"Version":"2012-10-17"}`
+			},
+		},
+		{
+			name:     "update is not supported",
+			subject:  "iam_policy.update.aws",
+			body:     `{"iam_policy_aws_id":"$id","iam_policy_arn":"$arn"}`,
+			expected: "iam_policy.update.aws.error",
+		},
+		{
+			name:     "find",
+			subject:  "iam_policy.find.aws",
+			body:     `{}`,
+			expected: "iam_policy.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				p := found[0].(map[string]interface{})
+				return p["iam_policy_arn"] == ids["arn"] && p["path"] == "/app/" && p["policy_document"] == `{"Version":"2012-10-17"}`
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "iam_policy.delete.aws",
+			body:     `{"iam_policy_aws_id":"$id","iam_policy_arn":"$arn"}`,
+			expected: "iam_policy.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.IAM.Policies) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails",
+			operation: "CreatePolicy",
+			subject:   "iam_policy.create.aws",
+			body:      `{"name":"read","policy_document":"{}"}`,
+		},
+		{
+			name:      "find fails reading the documents",
+			operation: "GetPolicyVersion",
+			subject:   "iam_policy.find.aws",
+			body:      `{}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeletePolicy",
+			subject:   "iam_policy.delete.aws",
+			body:      `{"iam_policy_aws_id":"ANPA1","iam_policy_arn":"arn:aws:iam::000000000000:policy/read"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			awsfake.Run(t, New, "iam_policy.create.aws", `{"name":"seed","policy_document":"{}"}`, nil)
+			b.Fail("iam", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, nil)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestFindByTags(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{}
+
+	for _, name := range []string{"app", "web"} {
+		_, res := awsfake.Run(t, New, "iam_policy.create.aws", `{"name":"`+name+`","policy_document":"{}"}`, nil)
+		ids[name], _ = res["iam_policy_arn"].(string)
+	}
+
+	b.IAM.Policies[ids["web"]].Tags = []*iam.Tag{{Key: aws.String("team"), Value: aws.String("web")}}
+
+	_, res := awsfake.Run(t, New, "iam_policy.find.aws", `{"tags":{"team":"web"}}`, nil)
+
+	found, _ := res["components"].([]interface{})
+	if len(found) != 1 || found[0].(map[string]interface{})["name"] != "web" {
+		t.Errorf("expected the tagged policy, got %v", found)
+	}
+}
@@ -5,15 +5,14 @@
 package iampolicy
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
-	"net/url"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/iam"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -23,6 +22,7 @@ type Collection struct {
 	ComponentID      string            `json:"_component_id"`
 	State            string            `json:"_state"`
 	Action           string            `json:"_action"`
+	SchemaVersion    int               `json:"_schema_version"`
 	Service          string            `json:"service"`
 	AccessKeyID      string            `json:"aws_access_key_id"`
 	SecretAccessKey  string            `json:"aws_secret_access_key"`
@@ -51,6 +51,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -80,6 +85,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AccessKeyID == "" || col.SecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -109,62 +118,48 @@ func (col *Collection) Delete() error {
 
 // Find : Find networks on aws
 func (col *Collection) Find() error {
-	svc := col.getIAMClient()
-
-	req := &iam.ListPoliciesInput{
-		Scope: aws.String("Local"),
-	}
-
-	resp, err := svc.ListPolicies(req)
+	policies, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, p := range resp.Policies {
-		var document *string
-
-		req := &iam.GetPolicyVersionInput{
-			PolicyArn: p.Arn,
-			VersionId: p.DefaultVersionId,
-		}
-
-		resp, err := svc.GetPolicyVersion(req)
-		if err != nil {
-			return err
-		}
-
-		if resp.PolicyVersion != nil {
-			if resp.PolicyVersion.Document != nil {
-				escaped, _ := url.QueryUnescape(*resp.PolicyVersion.Document)
-				document = &escaped
-			}
-		}
-
-		col.Results = append(col.Results, toEvent(p, document))
+	for _, st := range policies {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getIAMClient() *iam.IAM {
-	creds, _ := credentials.NewStaticCredentials(col.AccessKeyID, col.SecretAccessKey, col.CryptoKey)
-	return iam.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AccessKeyID,
+			SecretAccessKey: col.SecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
-// ToEvent converts an ec2 subnet object to an ernest event
-func toEvent(r *iam.Policy, document *string) *Event {
-	return &Event{
+// toEvent converts an iam policy status to an ernest event
+func toEvent(st Status) *Event {
+	e := &Event{
 		ProviderType:   "aws",
 		ComponentType:  "iam_policy",
-		ComponentID:    "iam_policy::" + *r.PolicyName,
-		IAMPolicyAWSID: r.PolicyId,
-		IAMPolicyARN:   r.Arn,
-		Name:           r.PolicyName,
-		Description:    r.Description,
-		Path:           r.Path,
-		PolicyDocument: document,
+		ComponentID:    "iam_policy::" + st.Name,
+		IAMPolicyAWSID: aws.String(st.ID),
+		IAMPolicyARN:   aws.String(st.ARN),
+		Name:           aws.String(st.Name),
+		Path:           aws.String(st.Path),
+		PolicyDocument: aws.String(st.PolicyDocument),
+	}
+
+	if st.Description != "" {
+		e.Description = aws.String(st.Description)
 	}
+
+	return e
 }
@@ -0,0 +1,25 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iampolicy
+
+import "github.com/ernestio/ernestaws/schema"
+
+func init() {
+	schema.Register("iam_policy", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "name", Type: schema.String, Required: true},
+			{Name: "policy_document", Type: schema.String, Required: true},
+			{Name: "description", Type: schema.String},
+			{Name: "path", Type: schema.String},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "iam_policy_aws_id", Type: schema.String, Required: true},
+			{Name: "iam_policy_arn", Type: schema.String, Required: true, Format: schema.ARN},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
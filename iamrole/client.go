@@ -0,0 +1,194 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iamrole
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of an iam role. The path and the
+// description are left to the aws defaults when empty
+type Spec struct {
+	Name                 string
+	AssumePolicyDocument string
+	PolicyARNs           []string
+	Description          string
+	Path                 string
+}
+
+// Status describes an iam role as it is on aws, along with the names
+// and arns of the policies attached to it
+type Status struct {
+	ID                   string
+	ARN                  string
+	Name                 string
+	AssumePolicyDocument string
+	Policies             []string
+	PolicyARNs           []string
+	Description          string
+	Path                 string
+}
+
+// Client manages iam roles through a typed api, the json events are an
+// adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates an iam role and attaches the policies of the spec
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getIAMClient()
+
+	req := &iam.CreateRoleInput{
+		RoleName:                 aws.String(s.Name),
+		AssumeRolePolicyDocument: aws.String(s.AssumePolicyDocument),
+	}
+
+	if s.Path != "" {
+		req.Path = aws.String(s.Path)
+	}
+
+	if s.Description != "" {
+		req.Description = aws.String(s.Description)
+	}
+
+	resp, err := svc.CreateRoleWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(resp.Role)
+	st.AssumePolicyDocument = s.AssumePolicyDocument
+	st.PolicyARNs = s.PolicyARNs
+
+	for _, arn := range s.PolicyARNs {
+		areq := &iam.AttachRolePolicyInput{
+			RoleName:  aws.String(s.Name),
+			PolicyArn: aws.String(arn),
+		}
+
+		_, err := svc.AttachRolePolicyWithContext(ctx, areq)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	return st, nil
+}
+
+// Delete : detaches the given policies from an iam role and deletes it
+func (c Client) Delete(ctx context.Context, name string, policyARNs []string) error {
+	svc := c.getIAMClient()
+
+	for _, arn := range policyARNs {
+		dreq := &iam.DetachRolePolicyInput{
+			RoleName:  aws.String(name),
+			PolicyArn: aws.String(arn),
+		}
+
+		_, err := svc.DetachRolePolicyWithContext(ctx, dreq)
+		if err != nil {
+			return err
+		}
+	}
+
+	req := &iam.DeleteRoleInput{
+		RoleName: aws.String(name),
+	}
+
+	_, err := svc.DeleteRoleWithContext(ctx, req)
+
+	return err
+}
+
+// Find : returns the iam roles having all the given tags, along with the
+// policies attached to them
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getIAMClient()
+
+	resp, err := svc.ListRolesWithContext(ctx, &iam.ListRolesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []Status
+
+	for _, r := range resp.Roles {
+		if len(tags) > 0 {
+			resp, err := svc.ListRoleTagsWithContext(ctx, &iam.ListRoleTagsInput{RoleName: r.RoleName})
+			if err != nil {
+				return nil, err
+			}
+
+			if !hasTags(resp.Tags, tags) {
+				continue
+			}
+		}
+
+		st := toStatus(r)
+
+		req := &iam.ListAttachedRolePoliciesInput{
+			RoleName: r.RoleName,
+		}
+
+		resp, err := svc.ListAttachedRolePoliciesWithContext(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range resp.AttachedPolicies {
+			st.Policies = append(st.Policies, aws.StringValue(p.PolicyName))
+			st.PolicyARNs = append(st.PolicyARNs, aws.StringValue(p.PolicyArn))
+		}
+
+		roles = append(roles, st)
+	}
+
+	return roles, nil
+}
+
+func (c Client) getIAMClient() *iam.IAM {
+	return iam.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func toStatus(r *iam.Role) Status {
+	st := Status{
+		ID:          aws.StringValue(r.RoleId),
+		ARN:         aws.StringValue(r.Arn),
+		Name:        aws.StringValue(r.RoleName),
+		Description: aws.StringValue(r.Description),
+		Path:        aws.StringValue(r.Path),
+	}
+
+	if r.AssumeRolePolicyDocument != nil {
+		st.AssumePolicyDocument, _ = url.QueryUnescape(*r.AssumeRolePolicyDocument)
+	}
+
+	return st
+}
+
+// hasTags : checks the tags of a resource include all the given ones
+func hasTags(current []*iam.Tag, tags map[string]string) bool {
+	t := make(map[string]string)
+
+	for _, tag := range current {
+		t[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	for k, v := range tags {
+		if t[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
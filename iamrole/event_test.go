@@ -0,0 +1,173 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iamrole
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createPolicy(t *testing.T, b *awsfake.Backend) string {
+	var out iam.CreatePolicyOutput
+
+	in := &iam.CreatePolicyInput{
+		PolicyName:     aws.String("read"),
+		PolicyDocument: aws.String("{}"),
+	}
+
+	if err := b.IAM.CreatePolicy(in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Policy.Arn
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"policy": createPolicy(t, b)}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "iam_role.create.aws",
+			body:     `{"name":"app","path":"/app/","assume_policy_document":"{\"Version\":\"2012-10-17\"}","policy_arns":["$policy"]}`,
+			expected: "iam_role.create.aws.done",
+			save:     map[string]string{"id": "iam_role_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				return res["iam_role_arn"] != nil && len(b.IAM.RolePolicies["app"]) == 1
+			},
+		},
+		{
+			name:     "update is not supported",
+			subject:  "iam_role.update.aws",
+			body:     `{"name":"app","iam_role_aws_id":"$id"}`,
+			expected: "iam_role.update.aws.error",
+		},
+		{
+			name:     "find",
+			subject:  "iam_role.find.aws",
+			body:     `{}`,
+			expected: "iam_role.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				r := found[0].(map[string]interface{})
+				arns, _ := r["policy_arns"].([]interface{})
+				return r["iam_role_aws_id"] == ids["id"] && r["assume_policy_document"] == `{"Version":"2012-10-17"}` && len(arns) == 1 && arns[0] == ids["policy"]
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "iam_role.delete.aws",
+			body:     `{"name":"app","iam_role_aws_id":"$id","policy_arns":["$policy"]}`,
+			expected: "iam_role.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.IAM.Roles) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails attaching the policies",
+			operation: "AttachRolePolicy",
+			subject:   "iam_role.create.aws",
+			body:      `{"name":"app","assume_policy_document":"{}","policy_arns":["$policy"]}`,
+		},
+		{
+			name:      "find fails listing the policies",
+			operation: "ListAttachedRolePolicies",
+			subject:   "iam_role.find.aws",
+			body:      `{}`,
+		},
+		{
+			name:      "delete fails detaching the policies",
+			operation: "DetachRolePolicy",
+			subject:   "iam_role.delete.aws",
+			body:      `{"name":"seed","iam_role_aws_id":"AROA1","policy_arns":["$policy"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := map[string]string{"policy": createPolicy(t, b)}
+			awsfake.Run(t, New, "iam_role.create.aws", `{"name":"seed","assume_policy_document":"{}","policy_arns":["$policy"]}`, ids)
+			b.Fail("iam", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestFindByTags(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{}
+
+	for _, name := range []string{"app", "web"} {
+		_, res := awsfake.Run(t, New, "iam_role.create.aws", `{"name":"`+name+`","assume_policy_document":"{}"}`, nil)
+		ids[name], _ = res["name"].(string)
+	}
+
+	b.IAM.Roles[ids["web"]].Tags = []*iam.Tag{{Key: aws.String("team"), Value: aws.String("web")}}
+
+	_, res := awsfake.Run(t, New, "iam_role.find.aws", `{"tags":{"team":"web"}}`, nil)
+
+	found, _ := res["components"].([]interface{})
+	if len(found) != 1 || found[0].(map[string]interface{})["name"] != "web" {
+		t.Errorf("expected the tagged role, got %v", found)
+	}
+}
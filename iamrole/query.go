@@ -5,15 +5,14 @@
 package iamrole
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
-	"net/url"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/iam"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -23,6 +22,7 @@ type Collection struct {
 	ComponentID      string            `json:"_component_id"`
 	State            string            `json:"_state"`
 	Action           string            `json:"_action"`
+	SchemaVersion    int               `json:"_schema_version"`
 	Service          string            `json:"service"`
 	AccessKeyID      string            `json:"aws_access_key_id"`
 	SecretAccessKey  string            `json:"aws_secret_access_key"`
@@ -51,6 +51,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -80,6 +85,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AccessKeyID == "" || col.SecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -109,64 +118,53 @@ func (col *Collection) Delete() error {
 
 // Find : Find networks on aws
 func (col *Collection) Find() error {
-	svc := col.getIAMClient()
-
-	resp, err := svc.ListRoles(&iam.ListRolesInput{})
+	roles, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, r := range resp.Roles {
-		var arns []*string
-		var policies []*string
-
-		req := &iam.ListAttachedRolePoliciesInput{
-			RoleName: r.RoleName,
-		}
-
-		resp, err := svc.ListAttachedRolePolicies(req)
-		if err != nil {
-			return err
-		}
-
-		for _, p := range resp.AttachedPolicies {
-			policies = append(policies, p.PolicyName)
-			arns = append(arns, p.PolicyArn)
-		}
-
-		col.Results = append(col.Results, toEvent(r, policies, arns))
+	for _, st := range roles {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getIAMClient() *iam.IAM {
-	creds, _ := credentials.NewStaticCredentials(col.AccessKeyID, col.SecretAccessKey, col.CryptoKey)
-	return iam.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AccessKeyID,
+			SecretAccessKey: col.SecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
-// ToEvent converts an ec2 subnet object to an ernest event
-func toEvent(r *iam.Role, policies, arns []*string) *Event {
-	var document *string
+// toEvent converts an iam role status to an ernest event
+func toEvent(st Status) *Event {
+	e := &Event{
+		ProviderType:         "aws",
+		ComponentType:        "iam_role",
+		ComponentID:          "iam_role::" + st.Name,
+		IAMRoleAWSID:         aws.String(st.ID),
+		IAMRoleARN:           aws.String(st.ARN),
+		Name:                 aws.String(st.Name),
+		AssumePolicyDocument: aws.String(st.AssumePolicyDocument),
+		Path:                 aws.String(st.Path),
+	}
 
-	if r.AssumeRolePolicyDocument != nil {
-		escaped, _ := url.QueryUnescape(*r.AssumeRolePolicyDocument)
-		document = &escaped
+	if st.Description != "" {
+		e.Description = aws.String(st.Description)
 	}
 
-	return &Event{
-		ProviderType:         "aws",
-		ComponentType:        "iam_role",
-		ComponentID:          "iam_role::" + *r.RoleName,
-		IAMRoleAWSID:         r.RoleId,
-		Name:                 r.RoleName,
-		Description:          r.Description,
-		Policies:             policies,
-		PolicyARNs:           arns,
-		AssumePolicyDocument: document,
-		Path:                 r.Path,
+	if len(st.PolicyARNs) > 0 {
+		e.Policies = aws.StringSlice(st.Policies)
+		e.PolicyARNs = aws.StringSlice(st.PolicyARNs)
 	}
+
+	return e
 }
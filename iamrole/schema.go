@@ -0,0 +1,28 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package iamrole
+
+import "github.com/ernestio/ernestaws/schema"
+
+var role = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "assume_policy_document", Type: schema.String, Required: true},
+	{Name: "policy_arns", Type: schema.Array, Items: &schema.Field{Type: schema.String, Format: schema.ARN}},
+	{Name: "description", Type: schema.String},
+	{Name: "path", Type: schema.String},
+}
+
+func init() {
+	schema.Register("iam_role", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), role),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "name", Type: schema.String, Required: true},
+			{Name: "iam_role_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
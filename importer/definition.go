@@ -0,0 +1,318 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package importer
+
+// Definition is an ernest service definition
+type Definition struct {
+	Name                string               `yaml:"name"`
+	Datacenter          string               `yaml:"datacenter"`
+	VPCs                []VPC                `yaml:"vpcs,omitempty"`
+	Networks            []Network            `yaml:"networks,omitempty"`
+	SecurityGroups      []SecurityGroup      `yaml:"security_groups,omitempty"`
+	NatGateways         []NatGateway         `yaml:"nat_gateways,omitempty"`
+	Instances           []Instance           `yaml:"instances,omitempty"`
+	EBSVolumes          []EBSVolume          `yaml:"ebs_volumes,omitempty"`
+	ELBs                []ELB                `yaml:"elbs,omitempty"`
+	RDSClusters         []RDSCluster         `yaml:"rds_clusters,omitempty"`
+	RDSInstances        []RDSInstance        `yaml:"rds_instances,omitempty"`
+	S3Buckets           []S3Bucket           `yaml:"s3_buckets,omitempty"`
+	Route53Zones        []Route53Zone        `yaml:"route53_zones,omitempty"`
+	IAMPolicies         []IAMPolicy          `yaml:"iam_policies,omitempty"`
+	IAMRoles            []IAMRole            `yaml:"iam_roles,omitempty"`
+	IAMInstanceProfiles []IAMInstanceProfile `yaml:"iam_instance_profiles,omitempty"`
+	VpcPeerings         []VpcPeering         `yaml:"vpc_peerings,omitempty"`
+	FlowLogs            []FlowLog            `yaml:"flow_logs,omitempty"`
+	DHCPOptions         []DHCPOptions        `yaml:"dhcp_options,omitempty"`
+	VpcEndpoints        []VpcEndpoint        `yaml:"vpc_endpoints,omitempty"`
+	NetworkACLs         []NetworkACL         `yaml:"network_acls,omitempty"`
+}
+
+// VPC ...
+type VPC struct {
+	Name               string            `yaml:"name"`
+	VpcID              string            `yaml:"vpc_id,omitempty"`
+	Subnet             string            `yaml:"subnet"`
+	SecondarySubnets   []string          `yaml:"secondary_subnets,omitempty"`
+	AssignIPv6Subnet   bool              `yaml:"assign_ipv6_subnet,omitempty"`
+	EnableDNSSupport   bool              `yaml:"enable_dns_support"`
+	EnableDNSHostnames bool              `yaml:"enable_dns_hostnames"`
+	InstanceTenancy    string            `yaml:"instance_tenancy,omitempty"`
+	AutoRemove         bool              `yaml:"auto_remove"`
+	Tags               map[string]string `yaml:"tags,omitempty"`
+}
+
+// Network ...
+type Network struct {
+	Name             string            `yaml:"name"`
+	Subnet           string            `yaml:"subnet"`
+	Public           bool              `yaml:"public"`
+	AvailabilityZone string            `yaml:"availability_zone,omitempty"`
+	VPC              string            `yaml:"vpc,omitempty"`
+	Tags             map[string]string `yaml:"tags,omitempty"`
+}
+
+// Rule ...
+type Rule struct {
+	IP       string `yaml:"ip"`
+	FromPort int64  `yaml:"from_port"`
+	ToPort   int64  `yaml:"to_port"`
+	Protocol string `yaml:"protocol"`
+}
+
+// SecurityGroup ...
+type SecurityGroup struct {
+	Name    string            `yaml:"name"`
+	VPC     string            `yaml:"vpc,omitempty"`
+	Ingress []Rule            `yaml:"ingress,omitempty"`
+	Egress  []Rule            `yaml:"egress,omitempty"`
+	Tags    map[string]string `yaml:"tags,omitempty"`
+}
+
+// NatGateway ...
+type NatGateway struct {
+	Name          string `yaml:"name"`
+	PublicNetwork string `yaml:"public_network"`
+}
+
+// Volume ...
+type Volume struct {
+	Volume string `yaml:"volume"`
+	Device string `yaml:"device"`
+}
+
+// Instance ...
+type Instance struct {
+	Name               string            `yaml:"name"`
+	Type               string            `yaml:"type"`
+	Image              string            `yaml:"image"`
+	Count              int               `yaml:"count"`
+	Network            string            `yaml:"network"`
+	StartIP            string            `yaml:"start_ip,omitempty"`
+	KeyPair            string            `yaml:"key_pair,omitempty"`
+	ElasticIP          bool              `yaml:"elastic_ip,omitempty"`
+	SecurityGroups     []string          `yaml:"security_groups,omitempty"`
+	IAMInstanceProfile string            `yaml:"iam_instance_profile,omitempty"`
+	Volumes            []Volume          `yaml:"volumes,omitempty"`
+	Tags               map[string]string `yaml:"tags,omitempty"`
+}
+
+// EBSVolume ...
+type EBSVolume struct {
+	Name             string            `yaml:"name"`
+	Type             string            `yaml:"type"`
+	Size             int64             `yaml:"size,omitempty"`
+	Iops             int64             `yaml:"iops,omitempty"`
+	AvailabilityZone string            `yaml:"availability_zone"`
+	Encrypted        bool              `yaml:"encrypted,omitempty"`
+	EncryptionKeyID  string            `yaml:"encryption_key_id,omitempty"`
+	Count            int               `yaml:"count"`
+	Tags             map[string]string `yaml:"tags,omitempty"`
+}
+
+// Listener ...
+type Listener struct {
+	FromPort int64  `yaml:"from_port"`
+	ToPort   int64  `yaml:"to_port"`
+	Protocol string `yaml:"protocol"`
+	SSLCert  string `yaml:"ssl_cert,omitempty"`
+}
+
+// ELB ...
+type ELB struct {
+	Name           string            `yaml:"name"`
+	Private        bool              `yaml:"private"`
+	Subnets        []string          `yaml:"subnets,omitempty"`
+	Instances      []string          `yaml:"instances,omitempty"`
+	SecurityGroups []string          `yaml:"security_groups,omitempty"`
+	Listeners      []Listener        `yaml:"listeners,omitempty"`
+	Tags           map[string]string `yaml:"tags,omitempty"`
+}
+
+// Backups ...
+type Backups struct {
+	Window    string `yaml:"window,omitempty"`
+	Retention int64  `yaml:"retention,omitempty"`
+}
+
+// RDSCluster ...
+type RDSCluster struct {
+	Name              string            `yaml:"name"`
+	Engine            string            `yaml:"engine"`
+	EngineVersion     string            `yaml:"engine_version,omitempty"`
+	Port              int64             `yaml:"port,omitempty"`
+	AvailabilityZones []string          `yaml:"availability_zones,omitempty"`
+	SecurityGroups    []string          `yaml:"security_groups,omitempty"`
+	Networks          []string          `yaml:"networks,omitempty"`
+	DatabaseName      string            `yaml:"database_name,omitempty"`
+	DatabaseUsername  string            `yaml:"database_username,omitempty"`
+	Backups           Backups           `yaml:"backups,omitempty"`
+	MaintenanceWindow string            `yaml:"maintenance_window,omitempty"`
+	ReplicationSource string            `yaml:"replication_source,omitempty"`
+	Tags              map[string]string `yaml:"tags,omitempty"`
+}
+
+// Storage ...
+type Storage struct {
+	Type string `yaml:"type,omitempty"`
+	Size int64  `yaml:"size,omitempty"`
+	Iops int64  `yaml:"iops,omitempty"`
+}
+
+// RDSInstance ...
+type RDSInstance struct {
+	Name              string            `yaml:"name"`
+	Size              string            `yaml:"size"`
+	Engine            string            `yaml:"engine"`
+	EngineVersion     string            `yaml:"engine_version,omitempty"`
+	Port              int64             `yaml:"port,omitempty"`
+	Cluster           string            `yaml:"cluster,omitempty"`
+	Public            bool              `yaml:"public"`
+	MultiAZ           bool              `yaml:"multi_az"`
+	PromotionTier     int64             `yaml:"promotion_tier,omitempty"`
+	Storage           Storage           `yaml:"storage,omitempty"`
+	AvailabilityZone  string            `yaml:"availability_zone,omitempty"`
+	SecurityGroups    []string          `yaml:"security_groups,omitempty"`
+	Networks          []string          `yaml:"networks,omitempty"`
+	DatabaseName      string            `yaml:"database_name,omitempty"`
+	DatabaseUsername  string            `yaml:"database_username,omitempty"`
+	AutoUpgrade       bool              `yaml:"auto_upgrade"`
+	Backups           Backups           `yaml:"backups,omitempty"`
+	MaintenanceWindow string            `yaml:"maintenance_window,omitempty"`
+	ReplicationSource string            `yaml:"replication_source,omitempty"`
+	License           string            `yaml:"license,omitempty"`
+	Timezone          string            `yaml:"timezone,omitempty"`
+	Tags              map[string]string `yaml:"tags,omitempty"`
+}
+
+// Grantee ...
+type Grantee struct {
+	ID          string `yaml:"id"`
+	Type        string `yaml:"type"`
+	Permissions string `yaml:"permissions"`
+}
+
+// S3Bucket ...
+type S3Bucket struct {
+	Name           string            `yaml:"name"`
+	ACL            string            `yaml:"acl,omitempty"`
+	BucketLocation string            `yaml:"bucket_location,omitempty"`
+	Grantees       []Grantee         `yaml:"grantees,omitempty"`
+	Tags           map[string]string `yaml:"tags,omitempty"`
+}
+
+// Record ...
+type Record struct {
+	Entry  string   `yaml:"entry"`
+	Type   string   `yaml:"type"`
+	Values []string `yaml:"values,omitempty"`
+	TTL    int64    `yaml:"ttl,omitempty"`
+}
+
+// Route53Zone ...
+type Route53Zone struct {
+	Name    string            `yaml:"name"`
+	Private bool              `yaml:"private"`
+	Records []Record          `yaml:"records,omitempty"`
+	Tags    map[string]string `yaml:"tags,omitempty"`
+}
+
+// IAMPolicy ...
+type IAMPolicy struct {
+	Name        string `yaml:"name"`
+	Path        string `yaml:"path,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Document    string `yaml:"document"`
+}
+
+// IAMRole ...
+type IAMRole struct {
+	Name                 string   `yaml:"name"`
+	Path                 string   `yaml:"path,omitempty"`
+	Description          string   `yaml:"description,omitempty"`
+	AssumePolicyDocument string   `yaml:"assume_policy_document"`
+	Policies             []string `yaml:"policies,omitempty"`
+}
+
+// IAMInstanceProfile ...
+type IAMInstanceProfile struct {
+	Name  string   `yaml:"name"`
+	Path  string   `yaml:"path,omitempty"`
+	Roles []string `yaml:"roles,omitempty"`
+}
+
+// VpcPeering ...
+type VpcPeering struct {
+	Name                         string            `yaml:"name"`
+	VPC                          string            `yaml:"vpc"`
+	PeerVPC                      string            `yaml:"peer_vpc"`
+	PeerOwnerID                  string            `yaml:"peer_owner_id,omitempty"`
+	PeerRegion                   string            `yaml:"peer_region,omitempty"`
+	AllowRemoteDNSResolution     bool              `yaml:"allow_remote_vpc_dns_resolution,omitempty"`
+	PeerAllowRemoteDNSResolution bool              `yaml:"peer_allow_remote_vpc_dns_resolution,omitempty"`
+	RouteTables                  []string          `yaml:"route_tables,omitempty"`
+	PeerRouteTables              []string          `yaml:"peer_route_tables,omitempty"`
+	Subnets                      []string          `yaml:"subnets,omitempty"`
+	PeerSubnets                  []string          `yaml:"peer_subnets,omitempty"`
+	Tags                         map[string]string `yaml:"tags,omitempty"`
+}
+
+// FlowLog ...
+type FlowLog struct {
+	Name            string            `yaml:"name"`
+	Resource        string            `yaml:"resource"`
+	TrafficType     string            `yaml:"traffic_type,omitempty"`
+	DestinationType string            `yaml:"destination_type,omitempty"`
+	LogGroupName    string            `yaml:"log_group_name,omitempty"`
+	IAMRoleARN      string            `yaml:"iam_role_arn,omitempty"`
+	LogDestination  string            `yaml:"log_destination,omitempty"`
+	LogFormat       string            `yaml:"log_format,omitempty"`
+	Tags            map[string]string `yaml:"tags,omitempty"`
+}
+
+// DHCPOptions ...
+type DHCPOptions struct {
+	Name               string            `yaml:"name"`
+	VPC                string            `yaml:"vpc"`
+	DomainName         string            `yaml:"domain_name,omitempty"`
+	DomainNameServers  []string          `yaml:"domain_name_servers,omitempty"`
+	NTPServers         []string          `yaml:"ntp_servers,omitempty"`
+	NetbiosNameServers []string          `yaml:"netbios_name_servers,omitempty"`
+	NetbiosNodeType    int64             `yaml:"netbios_node_type,omitempty"`
+	Tags               map[string]string `yaml:"tags,omitempty"`
+}
+
+// VpcEndpoint ...
+type VpcEndpoint struct {
+	Name              string            `yaml:"name"`
+	VPC               string            `yaml:"vpc"`
+	Service           string            `yaml:"service"`
+	Type              string            `yaml:"type"`
+	RouteTables       []string          `yaml:"route_tables,omitempty"`
+	Networks          []string          `yaml:"networks,omitempty"`
+	SecurityGroups    []string          `yaml:"security_groups,omitempty"`
+	PrivateDNSEnabled bool              `yaml:"private_dns_enabled,omitempty"`
+	PolicyDocument    string            `yaml:"policy_document,omitempty"`
+	Tags              map[string]string `yaml:"tags,omitempty"`
+}
+
+// Entry ...
+type Entry struct {
+	RuleNumber int64  `yaml:"rule_number"`
+	IP         string `yaml:"ip"`
+	Protocol   string `yaml:"protocol"`
+	FromPort   int64  `yaml:"from_port"`
+	ToPort     int64  `yaml:"to_port"`
+	Action     string `yaml:"action"`
+}
+
+// NetworkACL ...
+type NetworkACL struct {
+	Name     string            `yaml:"name"`
+	VPC      string            `yaml:"vpc"`
+	Networks []string          `yaml:"networks,omitempty"`
+	Ingress  []Entry           `yaml:"ingress,omitempty"`
+	Egress   []Entry           `yaml:"egress,omitempty"`
+	Tags     map[string]string `yaml:"tags,omitempty"`
+}
@@ -0,0 +1,423 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package importer
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/ernestio/ernestaws/discovery"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// names maps the aws ids of the discovered resources to their names
+type names map[string]string
+
+// name : returns the name of the resource with the given id, or the id
+// itself if the resource wasn't discovered
+func (n names) name(id *string) string {
+	if v, ok := n[aws.StringValue(id)]; ok {
+		return v
+	}
+	return aws.StringValue(id)
+}
+
+func (n names) list(ids []*string) []string {
+	var l []string
+	for _, id := range ids {
+		l = append(l, n.name(id))
+	}
+	return l
+}
+
+func (n names) add(id, name *string) {
+	if aws.StringValue(id) == "" {
+		return
+	}
+
+	if aws.StringValue(name) == "" {
+		n[*id] = *id
+		return
+	}
+
+	n[*id] = *name
+}
+
+// Build : converts the discovered resources into a service definition,
+// replacing the aws ids that link them with the names of the resources
+func Build(name, datacenter string, r *discovery.Resources) *Definition {
+	d := Definition{
+		Name:       name,
+		Datacenter: datacenter,
+	}
+
+	n := make(names)
+
+	for _, v := range r.VPCs {
+		n.add(v.VpcID, &v.Name)
+	}
+	for _, v := range r.Networks {
+		n.add(v.NetworkAWSID, v.Name)
+	}
+	for _, v := range r.Firewalls {
+		n.add(v.SecurityGroupAWSID, v.Name)
+	}
+	for _, v := range r.Instances {
+		n.add(v.InstanceAWSID, v.Name)
+	}
+	for _, v := range r.Volumes {
+		n.add(v.VolumeAWSID, v.Name)
+	}
+	for _, v := range r.Policies {
+		n.add(v.IAMPolicyARN, v.Name)
+	}
+	for _, v := range r.InstanceProfiles {
+		n.add(v.IAMInstanceProfileARN, v.Name)
+	}
+
+	for _, v := range r.VPCs {
+		d.VPCs = append(d.VPCs, VPC{
+			Name:               n.name(v.VpcID),
+			VpcID:              aws.StringValue(v.VpcID),
+			Subnet:             aws.StringValue(v.Subnet),
+			SecondarySubnets:   v.SecondarySubnets,
+			AssignIPv6Subnet:   aws.BoolValue(v.AssignIPv6Subnet),
+			EnableDNSSupport:   aws.BoolValue(v.EnableDNSSupport),
+			EnableDNSHostnames: aws.BoolValue(v.EnableDNSHostnames),
+			InstanceTenancy:    aws.StringValue(v.InstanceTenancy),
+			Tags:               v.Tags,
+		})
+	}
+
+	for _, v := range r.Networks {
+		d.Networks = append(d.Networks, Network{
+			Name:             n.name(v.NetworkAWSID),
+			Subnet:           aws.StringValue(v.Subnet),
+			Public:           aws.BoolValue(v.IsPublic),
+			AvailabilityZone: aws.StringValue(v.AvailabilityZone),
+			VPC:              n.name(&v.VpcID),
+			Tags:             v.Tags,
+		})
+	}
+
+	for _, v := range r.Firewalls {
+		sg := SecurityGroup{
+			Name: n.name(v.SecurityGroupAWSID),
+			VPC:  n.name(&v.VpcID),
+			Tags: v.Tags,
+		}
+
+		for _, rl := range v.Rules.Ingress {
+			sg.Ingress = append(sg.Ingress, Rule{
+				IP:       aws.StringValue(rl.IP),
+				FromPort: aws.Int64Value(rl.FromPort),
+				ToPort:   aws.Int64Value(rl.ToPort),
+				Protocol: aws.StringValue(rl.Protocol),
+			})
+		}
+
+		for _, rl := range v.Rules.Egress {
+			sg.Egress = append(sg.Egress, Rule{
+				IP:       aws.StringValue(rl.IP),
+				FromPort: aws.Int64Value(rl.FromPort),
+				ToPort:   aws.Int64Value(rl.ToPort),
+				Protocol: aws.StringValue(rl.Protocol),
+			})
+		}
+
+		d.SecurityGroups = append(d.SecurityGroups, sg)
+	}
+
+	for _, v := range r.NatGateways {
+		d.NatGateways = append(d.NatGateways, NatGateway{
+			Name:          n.name(v.NatGatewayAWSID),
+			PublicNetwork: n.name(v.PublicNetworkAWSID),
+		})
+	}
+
+	for _, v := range r.Instances {
+		i := Instance{
+			Name:               n.name(v.InstanceAWSID),
+			Type:               aws.StringValue(v.Type),
+			Image:              aws.StringValue(v.Image),
+			Count:              1,
+			Network:            n.name(v.NetworkAWSID),
+			StartIP:            aws.StringValue(v.IP),
+			KeyPair:            aws.StringValue(v.KeyPair),
+			ElasticIP:          aws.StringValue(v.ElasticIP) != "",
+			SecurityGroups:     n.list(v.SecurityGroupAWSIDs),
+			IAMInstanceProfile: n.name(v.IAMInstanceProfileARN),
+			Tags:               v.Tags,
+		}
+
+		for _, vol := range v.Volumes {
+			i.Volumes = append(i.Volumes, Volume{
+				Volume: n.name(vol.VolumeAWSID),
+				Device: aws.StringValue(vol.Device),
+			})
+		}
+
+		d.Instances = append(d.Instances, i)
+	}
+
+	for _, v := range r.Volumes {
+		d.EBSVolumes = append(d.EBSVolumes, EBSVolume{
+			Name:             n.name(v.VolumeAWSID),
+			Type:             aws.StringValue(v.VolumeType),
+			Size:             aws.Int64Value(v.Size),
+			Iops:             aws.Int64Value(v.Iops),
+			AvailabilityZone: aws.StringValue(v.AvailabilityZone),
+			Encrypted:        aws.BoolValue(v.Encrypted),
+			EncryptionKeyID:  aws.StringValue(v.EncryptionKeyID),
+			Count:            1,
+			Tags:             v.Tags,
+		})
+	}
+
+	for _, v := range r.ELBs {
+		e := ELB{
+			Name:           aws.StringValue(v.Name),
+			Private:        aws.BoolValue(v.IsPrivate),
+			Subnets:        n.list(v.NetworkAWSIDs),
+			Instances:      n.list(v.InstanceAWSIDs),
+			SecurityGroups: n.list(v.SecurityGroupAWSIDs),
+			Tags:           v.Tags,
+		}
+
+		for _, l := range v.Listeners {
+			e.Listeners = append(e.Listeners, Listener{
+				FromPort: aws.Int64Value(l.FromPort),
+				ToPort:   aws.Int64Value(l.ToPort),
+				Protocol: aws.StringValue(l.Protocol),
+				SSLCert:  aws.StringValue(l.SSLCertID),
+			})
+		}
+
+		d.ELBs = append(d.ELBs, e)
+	}
+
+	for _, v := range r.RDSClusters {
+		d.RDSClusters = append(d.RDSClusters, RDSCluster{
+			Name:              aws.StringValue(v.Name),
+			Engine:            aws.StringValue(v.Engine),
+			EngineVersion:     aws.StringValue(v.EngineVersion),
+			Port:              aws.Int64Value(v.Port),
+			AvailabilityZones: aws.StringValueSlice(v.AvailabilityZones),
+			SecurityGroups:    n.list(v.SecurityGroupAWSIDs),
+			Networks:          n.list(v.NetworkAWSIDs),
+			DatabaseName:      aws.StringValue(v.DatabaseName),
+			DatabaseUsername:  aws.StringValue(v.DatabaseUsername),
+			Backups: Backups{
+				Window:    aws.StringValue(v.BackupWindow),
+				Retention: aws.Int64Value(v.BackupRetention),
+			},
+			MaintenanceWindow: aws.StringValue(v.MaintenanceWindow),
+			ReplicationSource: aws.StringValue(v.ReplicationSource),
+			Tags:              v.Tags,
+		})
+	}
+
+	for _, v := range r.RDSInstances {
+		d.RDSInstances = append(d.RDSInstances, RDSInstance{
+			Name:          aws.StringValue(v.Name),
+			Size:          aws.StringValue(v.Size),
+			Engine:        aws.StringValue(v.Engine),
+			EngineVersion: aws.StringValue(v.EngineVersion),
+			Port:          aws.Int64Value(v.Port),
+			Cluster:       aws.StringValue(v.Cluster),
+			Public:        aws.BoolValue(v.Public),
+			MultiAZ:       aws.BoolValue(v.MultiAZ),
+			PromotionTier: aws.Int64Value(v.PromotionTier),
+			Storage: Storage{
+				Type: aws.StringValue(v.StorageType),
+				Size: aws.Int64Value(v.StorageSize),
+				Iops: aws.Int64Value(v.StorageIops),
+			},
+			AvailabilityZone: aws.StringValue(v.AvailabilityZone),
+			SecurityGroups:   n.list(v.SecurityGroupAWSIDs),
+			Networks:         n.list(v.NetworkAWSIDs),
+			DatabaseName:     aws.StringValue(v.DatabaseName),
+			DatabaseUsername: aws.StringValue(v.DatabaseUsername),
+			AutoUpgrade:      aws.BoolValue(v.AutoUpgrade),
+			Backups: Backups{
+				Window:    aws.StringValue(v.BackupWindow),
+				Retention: aws.Int64Value(v.BackupRetention),
+			},
+			MaintenanceWindow: aws.StringValue(v.MaintenanceWindow),
+			ReplicationSource: aws.StringValue(v.ReplicationSource),
+			License:           aws.StringValue(v.License),
+			Timezone:          aws.StringValue(v.Timezone),
+			Tags:              v.Tags,
+		})
+	}
+
+	for _, v := range r.Buckets {
+		b := S3Bucket{
+			Name:           aws.StringValue(v.Name),
+			ACL:            aws.StringValue(v.ACL),
+			BucketLocation: aws.StringValue(v.BucketLocation),
+			Tags:           v.Tags,
+		}
+
+		for _, g := range v.Grantees {
+			b.Grantees = append(b.Grantees, Grantee{
+				ID:          aws.StringValue(g.ID),
+				Type:        aws.StringValue(g.Type),
+				Permissions: aws.StringValue(g.Permissions),
+			})
+		}
+
+		d.S3Buckets = append(d.S3Buckets, b)
+	}
+
+	for _, v := range r.Zones {
+		z := Route53Zone{
+			Name:    aws.StringValue(v.Name),
+			Private: aws.BoolValue(v.Private),
+			Tags:    v.Tags,
+		}
+
+		for _, rc := range v.Records {
+			z.Records = append(z.Records, Record{
+				Entry:  aws.StringValue(rc.Entry),
+				Type:   aws.StringValue(rc.Type),
+				Values: aws.StringValueSlice(rc.Values),
+				TTL:    aws.Int64Value(rc.TTL),
+			})
+		}
+
+		d.Route53Zones = append(d.Route53Zones, z)
+	}
+
+	for _, v := range r.Policies {
+		d.IAMPolicies = append(d.IAMPolicies, IAMPolicy{
+			Name:        aws.StringValue(v.Name),
+			Path:        aws.StringValue(v.Path),
+			Description: aws.StringValue(v.Description),
+			Document:    aws.StringValue(v.PolicyDocument),
+		})
+	}
+
+	for _, v := range r.Roles {
+		role := IAMRole{
+			Name:                 aws.StringValue(v.Name),
+			Path:                 aws.StringValue(v.Path),
+			Description:          aws.StringValue(v.Description),
+			AssumePolicyDocument: aws.StringValue(v.AssumePolicyDocument),
+			Policies:             aws.StringValueSlice(v.Policies),
+		}
+
+		if len(role.Policies) == 0 {
+			role.Policies = n.list(v.PolicyARNs)
+		}
+
+		d.IAMRoles = append(d.IAMRoles, role)
+	}
+
+	for _, v := range r.InstanceProfiles {
+		d.IAMInstanceProfiles = append(d.IAMInstanceProfiles, IAMInstanceProfile{
+			Name:  aws.StringValue(v.Name),
+			Path:  aws.StringValue(v.Path),
+			Roles: aws.StringValueSlice(v.Roles),
+		})
+	}
+
+	for _, v := range r.VpcPeerings {
+		d.VpcPeerings = append(d.VpcPeerings, VpcPeering{
+			Name:                         aws.StringValue(v.Name),
+			VPC:                          n.name(&v.VpcID),
+			PeerVPC:                      n.name(&v.PeerVpcID),
+			PeerOwnerID:                  aws.StringValue(v.PeerOwnerID),
+			PeerRegion:                   aws.StringValue(v.PeerRegion),
+			AllowRemoteDNSResolution:     aws.BoolValue(v.AllowRemoteDNSResolution),
+			PeerAllowRemoteDNSResolution: aws.BoolValue(v.PeerAllowRemoteDNSResolution),
+			RouteTables:                  aws.StringValueSlice(v.RouteTableAWSIDs),
+			PeerRouteTables:              aws.StringValueSlice(v.PeerRouteTableAWSIDs),
+			Subnets:                      aws.StringValueSlice(v.Subnets),
+			PeerSubnets:                  aws.StringValueSlice(v.PeerSubnets),
+			Tags:                         v.Tags,
+		})
+	}
+
+	for _, v := range r.FlowLogs {
+		d.FlowLogs = append(d.FlowLogs, FlowLog{
+			Name:            aws.StringValue(v.Name),
+			Resource:        n.name(&v.ResourceID),
+			TrafficType:     aws.StringValue(v.TrafficType),
+			DestinationType: aws.StringValue(v.DestinationType),
+			LogGroupName:    aws.StringValue(v.LogGroupName),
+			IAMRoleARN:      aws.StringValue(v.IAMRoleARN),
+			LogDestination:  aws.StringValue(v.LogDestination),
+			LogFormat:       aws.StringValue(v.LogFormat),
+			Tags:            v.Tags,
+		})
+	}
+
+	for _, v := range r.DHCPOptions {
+		d.DHCPOptions = append(d.DHCPOptions, DHCPOptions{
+			Name:               aws.StringValue(v.Name),
+			VPC:                n.name(&v.VpcID),
+			DomainName:         aws.StringValue(v.DomainName),
+			DomainNameServers:  v.DomainNameServers,
+			NTPServers:         v.NTPServers,
+			NetbiosNameServers: v.NetbiosNameServers,
+			NetbiosNodeType:    aws.Int64Value(v.NetbiosNodeType),
+			Tags:               v.Tags,
+		})
+	}
+
+	for _, v := range r.VpcEndpoints {
+		d.VpcEndpoints = append(d.VpcEndpoints, VpcEndpoint{
+			Name:              aws.StringValue(v.Name),
+			VPC:               n.name(&v.VpcID),
+			Service:           v.ServiceName,
+			Type:              v.EndpointType,
+			RouteTables:       aws.StringValueSlice(v.RouteTableAWSIDs),
+			Networks:          n.list(v.NetworkAWSIDs),
+			SecurityGroups:    n.list(v.SecurityGroupAWSIDs),
+			PrivateDNSEnabled: aws.BoolValue(v.PrivateDNSEnabled),
+			PolicyDocument:    aws.StringValue(v.PolicyDocument),
+			Tags:              v.Tags,
+		})
+	}
+
+	for _, v := range r.NetworkACLs {
+		acl := NetworkACL{
+			Name:     aws.StringValue(v.Name),
+			VPC:      n.name(&v.VpcID),
+			Networks: n.list(aws.StringSlice(v.NetworkAWSIDs)),
+			Tags:     v.Tags,
+		}
+
+		for _, e := range v.Entries.Ingress {
+			acl.Ingress = append(acl.Ingress, Entry{
+				RuleNumber: aws.Int64Value(e.RuleNumber),
+				IP:         aws.StringValue(e.IP),
+				Protocol:   aws.StringValue(e.Protocol),
+				FromPort:   aws.Int64Value(e.FromPort),
+				ToPort:     aws.Int64Value(e.ToPort),
+				Action:     aws.StringValue(e.Action),
+			})
+		}
+
+		for _, e := range v.Entries.Egress {
+			acl.Egress = append(acl.Egress, Entry{
+				RuleNumber: aws.Int64Value(e.RuleNumber),
+				IP:         aws.StringValue(e.IP),
+				Protocol:   aws.StringValue(e.Protocol),
+				FromPort:   aws.Int64Value(e.FromPort),
+				ToPort:     aws.Int64Value(e.ToPort),
+				Action:     aws.StringValue(e.Action),
+			})
+		}
+
+		d.NetworkACLs = append(d.NetworkACLs, acl)
+	}
+
+	return &d
+}
+
+// YAML : renders the definition
+func (d *Definition) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
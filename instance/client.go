@@ -0,0 +1,642 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package instance
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// instance state codes
+const (
+	stateRunning = 16
+	stateStopped = 80
+)
+
+// Attachment is an ebs volume attached to an instance on a device
+type Attachment struct {
+	Device   string
+	VolumeID string
+}
+
+// Spec describes the desired state of an instance. A nil ipv6 address
+// count leaves the addresses of the instance as they are
+type Spec struct {
+	Name               string
+	Type               string
+	Image              string
+	NetworkID          string
+	IP                 string
+	IPv6AddressCount   *int64
+	KeyPair            string
+	UserData           string
+	SecurityGroupIDs   []string
+	IAMInstanceProfile string
+	AssignElasticIP    bool
+	Volumes            []Attachment
+	Powered            bool
+	Tags               map[string]string
+}
+
+// Status describes an instance as it is on aws. The elastic ip is only
+// reported on create, as it is allocated with the instance
+type Status struct {
+	ID                    string
+	Name                  string
+	Type                  string
+	Image                 string
+	NetworkID             string
+	IP                    string
+	IPv6Addresses         []string
+	PublicIP              string
+	ElasticIP             string
+	ElasticIPID           string
+	KeyPair               string
+	SecurityGroupIDs      []string
+	IAMInstanceProfile    string
+	IAMInstanceProfileARN string
+	Volumes               []Attachment
+	Powered               bool
+	Tags                  map[string]string
+}
+
+// Client manages instances through a typed api, the json events are an
+// adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : launches an instance and waits for it to be running
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	req := ec2.RunInstancesInput{
+		SubnetId:         aws.String(s.NetworkID),
+		ImageId:          aws.String(s.Image),
+		InstanceType:     aws.String(s.Type),
+		MaxCount:         aws.Int64(1),
+		MinCount:         aws.Int64(1),
+		Ipv6AddressCount: s.IPv6AddressCount,
+		SecurityGroupIds: aws.StringSlice(s.SecurityGroupIDs),
+	}
+
+	if s.IP != "" {
+		req.PrivateIpAddress = aws.String(s.IP)
+	}
+
+	if s.KeyPair != "" {
+		req.KeyName = aws.String(s.KeyPair)
+	}
+
+	if s.UserData != "" {
+		req.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(s.UserData)))
+	}
+
+	if s.IAMInstanceProfile != "" {
+		req.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{
+			Name: aws.String(s.IAMInstanceProfile),
+		}
+	}
+
+	resp, err := svc.RunInstancesWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	id := aws.StringValue(resp.Instances[0].InstanceId)
+
+	builtInstance := ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	}
+
+	err = svc.WaitUntilInstanceRunningWithContext(ctx, &builtInstance)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var eip, eipID string
+
+	if s.AssignElasticIP {
+		eip, eipID, err = c.assignElasticIP(ctx, svc, id)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	instance, err := c.getInstanceByID(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(instance, "", stateRunning)
+	st.Name = s.Name
+	st.Tags = s.Tags
+	st.ElasticIP = eip
+	st.ElasticIPID = eipID
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return st, err
+	}
+
+	return st, c.attachVolumes(ctx, svc, id, s.Volumes)
+}
+
+// Update : resizes an instance and sets its security groups, ipv6
+// addresses and volumes. The instance is stopped while it is updated and
+// only started again when it should be powered
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	builtInstance := ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	}
+
+	okInstance := ec2.DescribeInstanceStatusInput{
+		InstanceIds: []*string{aws.String(id)},
+	}
+
+	state, err := c.getInstanceState(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if state != stateStopped {
+		err := svc.WaitUntilInstanceStatusOkWithContext(ctx, &okInstance)
+		if err != nil {
+			log.Println("[ERROR]: Waiting for instance to be in status OK")
+			return Status{}, err
+		}
+
+		stopreq := ec2.StopInstancesInput{
+			InstanceIds: []*string{aws.String(id)},
+		}
+
+		// power off the instance
+		_, err = svc.StopInstancesWithContext(ctx, &stopreq)
+		if err != nil {
+			log.Println("[ERROR]: While stopping the instance")
+			return Status{}, err
+		}
+
+		err = svc.WaitUntilInstanceStoppedWithContext(ctx, &builtInstance)
+		if err != nil {
+			log.Println("[ERROR]: Waiting until instance is stopped")
+			return Status{}, err
+		}
+	}
+
+	// resize the instance
+	req := ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(id),
+		InstanceType: &ec2.AttributeValue{
+			Value: aws.String(s.Type),
+		},
+	}
+
+	_, err = svc.ModifyInstanceAttributeWithContext(ctx, &req)
+	if err != nil {
+		log.Println("[ERROR]: Modifying instance attributes (I)")
+		return Status{}, err
+	}
+
+	// update instance security groups
+	req = ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(id),
+		Groups:     aws.StringSlice(s.SecurityGroupIDs),
+	}
+
+	if req.Groups == nil {
+		req.Groups = []*string{}
+	}
+
+	_, err = svc.ModifyInstanceAttributeWithContext(ctx, &req)
+	if err != nil {
+		log.Println("[ERROR]: Modifying instance attributes (II)")
+		return Status{}, err
+	}
+
+	err = c.setIPv6Addresses(ctx, svc, id, s.IPv6AddressCount)
+	if err != nil {
+		log.Println("[ERROR]: Setting instance ipv6 addresses")
+		return Status{}, err
+	}
+
+	err = c.attachVolumes(ctx, svc, id, s.Volumes)
+	if err != nil {
+		log.Println("[ERROR]: Attaching instance volumes")
+		return Status{}, err
+	}
+
+	state = stateStopped
+
+	if s.Powered {
+		// power the instance back on
+		startreq := ec2.StartInstancesInput{
+			InstanceIds: []*string{aws.String(id)},
+		}
+
+		_, err = svc.StartInstancesWithContext(ctx, &startreq)
+		if err != nil {
+			log.Println("[ERROR] While starting the instance")
+			return Status{}, err
+		}
+
+		err = svc.WaitUntilInstanceRunningWithContext(ctx, &builtInstance)
+		if err != nil {
+			log.Println("[ERROR] While waiting for instance to be running")
+			return Status{}, err
+		}
+
+		state = stateRunning
+	}
+
+	instance, err := c.getInstanceByID(ctx, svc, id)
+	if err != nil {
+		log.Println("[ERROR]: Getting instance by id")
+		return Status{}, err
+	}
+
+	st := toStatus(instance, s.IAMInstanceProfile, state)
+	st.Name = s.Name
+	st.Tags = s.Tags
+
+	return st, c.setTags(ctx, svc, id, s.Tags)
+}
+
+// Delete : terminates an instance and releases its elastic ip, if any
+func (c Client) Delete(ctx context.Context, id, elasticIPID string) error {
+	svc := c.getEC2Client()
+
+	req := ec2.TerminateInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	}
+
+	_, err := svc.TerminateInstancesWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	termreq := ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	}
+
+	err = svc.WaitUntilInstanceTerminatedWithContext(ctx, &termreq)
+	if err != nil {
+		return err
+	}
+
+	if elasticIPID != "" {
+		rreq := &ec2.ReleaseAddressInput{
+			AllocationId: aws.String(elasticIPID),
+		}
+
+		_, err = svc.ReleaseAddressWithContext(ctx, rreq)
+	}
+
+	return err
+}
+
+// Find : returns the running or stopped instances matching all the
+// given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	req := &ec2.DescribeInstancesInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := svc.DescribeInstancesWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []Status
+
+	for _, r := range resp.Reservations {
+		for _, i := range r.Instances {
+			var profile string
+
+			if i.IamInstanceProfile != nil {
+				profile, err = c.getInstanceProfileName(ctx, aws.StringValue(i.IamInstanceProfile.Arn))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			state, err := c.getInstanceState(ctx, svc, aws.StringValue(i.InstanceId))
+			if err != nil {
+				return nil, err
+			}
+
+			instances = append(instances, toStatus(i, profile, state))
+		}
+	}
+
+	return instances, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) getIAMClient() *iam.IAM {
+	return iam.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) getInstanceProfileName(ctx context.Context, arn string) (string, error) {
+	resp, err := c.getIAMClient().ListInstanceProfilesWithContext(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range resp.InstanceProfiles {
+		if aws.StringValue(p.Arn) == arn {
+			return aws.StringValue(p.InstanceProfileName), nil
+		}
+	}
+
+	return "", nil
+}
+
+func (c Client) getInstanceState(ctx context.Context, svc *ec2.EC2, id string) (int64, error) {
+	input := ec2.DescribeInstanceStatusInput{
+		InstanceIds:         []*string{aws.String(id)},
+		IncludeAllInstances: aws.Bool(true),
+	}
+
+	output, err := svc.DescribeInstanceStatusWithContext(ctx, &input)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(output.InstanceStatuses) != 1 {
+		return 0, errors.New("Could not find an instance status with that ID")
+	}
+
+	return aws.Int64Value(output.InstanceStatuses[0].InstanceState.Code), nil
+}
+
+func (c Client) getInstanceByID(ctx context.Context, svc *ec2.EC2, id string) (*ec2.Instance, error) {
+	req := ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeInstancesWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Reservations) != 1 {
+		return nil, errors.New("Could not find any instance reservations")
+	}
+
+	if len(resp.Reservations[0].Instances) != 1 {
+		return nil, errors.New("Could not find an instance with that ID")
+	}
+
+	return resp.Reservations[0].Instances[0], nil
+}
+
+func (c Client) assignElasticIP(ctx context.Context, svc *ec2.EC2, id string) (string, string, error) {
+	// Create Elastic IP
+	resp, err := svc.AllocateAddressWithContext(ctx, &ec2.AllocateAddressInput{})
+	if err != nil {
+		return "", "", err
+	}
+
+	req := ec2.AssociateAddressInput{
+		InstanceId:   aws.String(id),
+		AllocationId: resp.AllocationId,
+	}
+	_, err = svc.AssociateAddressWithContext(ctx, &req)
+	if err != nil {
+		return "", "", err
+	}
+
+	return aws.StringValue(resp.PublicIp), aws.StringValue(resp.AllocationId), nil
+}
+
+// setIPv6Addresses : assigns or unassigns ipv6 addresses on the primary
+// network interface until it has the requested count
+func (c Client) setIPv6Addresses(ctx context.Context, svc *ec2.EC2, id string, count *int64) error {
+	if count == nil {
+		return nil
+	}
+
+	instance, err := c.getInstanceByID(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+
+	for _, ni := range instance.NetworkInterfaces {
+		if ni.Attachment != nil && aws.Int64Value(ni.Attachment.DeviceIndex) != 0 {
+			continue
+		}
+
+		current := int64(len(ni.Ipv6Addresses))
+
+		switch {
+		case *count > current:
+			req := ec2.AssignIpv6AddressesInput{
+				NetworkInterfaceId: ni.NetworkInterfaceId,
+				Ipv6AddressCount:   aws.Int64(*count - current),
+			}
+
+			_, err = svc.AssignIpv6AddressesWithContext(ctx, &req)
+		case *count < current:
+			req := ec2.UnassignIpv6AddressesInput{
+				NetworkInterfaceId: ni.NetworkInterfaceId,
+			}
+
+			for _, a := range ni.Ipv6Addresses[*count:] {
+				req.Ipv6Addresses = append(req.Ipv6Addresses, a.Ipv6Address)
+			}
+
+			_, err = svc.UnassignIpv6AddressesWithContext(ctx, &req)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachVolumes : detaches the volumes that are not attached on the spec
+// and attaches the missing ones, the root device is left as it is
+func (c Client) attachVolumes(ctx context.Context, svc *ec2.EC2, id string, volumes []Attachment) error {
+	instance, err := c.getInstanceByID(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+
+	for _, bdm := range instance.BlockDeviceMappings {
+		if hasBlockDevice(volumes, bdm) || *bdm.DeviceName == *instance.RootDeviceName {
+			continue
+		}
+
+		req := &ec2.DetachVolumeInput{
+			InstanceId: aws.String(id),
+			VolumeId:   bdm.Ebs.VolumeId,
+		}
+
+		_, err = svc.DetachVolumeWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, vol := range volumes {
+		// check volume doesn't exist
+		if hasVolumeAttached(instance.BlockDeviceMappings, vol) {
+			continue
+		}
+
+		req := &ec2.AttachVolumeInput{
+			Device:     aws.String(vol.Device),
+			VolumeId:   aws.String(vol.VolumeID),
+			InstanceId: aws.String(id),
+		}
+
+		_, err = svc.AttachVolumeWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasVolumeAttached(bdms []*ec2.InstanceBlockDeviceMapping, vol Attachment) bool {
+	for _, bdm := range bdms {
+		if *bdm.Ebs.VolumeId == vol.VolumeID || *bdm.DeviceName == vol.Device {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasBlockDevice(volumes []Attachment, bdm *ec2.InstanceBlockDeviceMapping) bool {
+	for _, vol := range volumes {
+		if vol.VolumeID == *bdm.Ebs.VolumeId || vol.Device == *bdm.DeviceName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mapIPv6Addresses : returns the ipv6 addresses of the primary network
+// interface of an instance
+func mapIPv6Addresses(i *ec2.Instance) []string {
+	var addrs []string
+
+	for _, ni := range i.NetworkInterfaces {
+		if ni.Attachment != nil && aws.Int64Value(ni.Attachment.DeviceIndex) != 0 {
+			continue
+		}
+
+		for _, a := range ni.Ipv6Addresses {
+			addrs = append(addrs, aws.StringValue(a.Ipv6Address))
+		}
+	}
+
+	return addrs
+}
+
+func mapAttachments(vs []*ec2.InstanceBlockDeviceMapping, rootDevice *string) []Attachment {
+	var vols []Attachment
+
+	for _, v := range vs {
+		// omit root disk!
+		if *v.DeviceName != *rootDevice {
+			vols = append(vols, Attachment{
+				Device:   aws.StringValue(v.DeviceName),
+				VolumeID: aws.StringValue(v.Ebs.VolumeId),
+			})
+		}
+	}
+
+	return vols
+}
+
+func mapSecurityGroupIDs(gi []*ec2.GroupIdentifier) []string {
+	var sgs []string
+
+	for _, sg := range gi {
+		sgs = append(sgs, aws.StringValue(sg.GroupId))
+	}
+
+	return sgs
+}
+
+func toStatus(i *ec2.Instance, profile string, state int64) Status {
+	tags := mapEC2Tags(i.Tags)
+
+	st := Status{
+		ID:               aws.StringValue(i.InstanceId),
+		Name:             tags["Name"],
+		Type:             aws.StringValue(i.InstanceType),
+		Image:            aws.StringValue(i.ImageId),
+		NetworkID:        aws.StringValue(i.SubnetId),
+		IP:               aws.StringValue(i.PrivateIpAddress),
+		IPv6Addresses:    mapIPv6Addresses(i),
+		PublicIP:         aws.StringValue(i.PublicIpAddress),
+		KeyPair:          aws.StringValue(i.KeyName),
+		SecurityGroupIDs: mapSecurityGroupIDs(i.SecurityGroups),
+		Volumes:          mapAttachments(i.BlockDeviceMappings, i.RootDeviceName),
+		Powered:          state == stateRunning,
+		Tags:             tags,
+	}
+
+	if i.IamInstanceProfile != nil {
+		st.IAMInstanceProfileARN = aws.StringValue(i.IamInstanceProfile.Arn)
+		st.IAMInstanceProfile = profile
+	}
+
+	return st
+}
+
+func mapEC2Tags(input []*ec2.Tag) map[string]string {
+	t := make(map[string]string)
+
+	for _, tag := range input {
+		t[*tag.Key] = *tag.Value
+	}
+
+	return t
+}
@@ -5,17 +5,17 @@
 package instance
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -51,11 +51,14 @@ type Event struct {
 	ComponentID           string            `json:"_component_id"`
 	State                 string            `json:"_state"`
 	Action                string            `json:"_action"`
+	SchemaVersion         int               `json:"_schema_version"`
 	InstanceAWSID         *string           `json:"instance_aws_id"`
 	Name                  *string           `json:"name"`
 	Type                  *string           `json:"instance_type"`
 	Image                 *string           `json:"image"`
 	IP                    *string           `json:"ip"`
+	IPv6AddressCount      *int64            `json:"ipv6_address_count"`
+	IPv6Addresses         []*string         `json:"ipv6_addresses"`
 	PublicIP              *string           `json:"public_ip"`
 	ElasticIP             *string           `json:"elastic_ip"`
 	ElasticIPAWSID        *string           `json:"elastic_ip_aws_id,omitempty"`
@@ -109,6 +112,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -119,6 +127,16 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.verify(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := ev.preflight(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -138,6 +156,10 @@ func (ev *Event) Complete() {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -180,212 +202,44 @@ func (ev *Event) Find() error {
 
 // Create : Creates a instance object on aws
 func (ev *Event) Create() error {
-	svc := ev.getEC2Client()
-
-	req := ec2.RunInstancesInput{
-		SubnetId:         ev.NetworkAWSID,
-		ImageId:          ev.Image,
-		InstanceType:     ev.Type,
-		PrivateIpAddress: ev.IP,
-		KeyName:          ev.KeyPair,
-		MaxCount:         aws.Int64(1),
-		MinCount:         aws.Int64(1),
-	}
-
-	for _, sg := range ev.SecurityGroupAWSIDs {
-		req.SecurityGroupIds = append(req.SecurityGroupIds, sg)
-	}
-
-	if ev.UserData != nil {
-		req.UserData = ev.encodeUserData(ev.UserData)
-	}
-
-	if ev.IAMInstanceProfile != nil {
-		req.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{
-			Name: ev.IAMInstanceProfile,
-		}
-	}
-
-	resp, err := svc.RunInstances(&req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	builtInstance := ec2.DescribeInstancesInput{
-		InstanceIds: []*string{resp.Instances[0].InstanceId},
-	}
-
-	err = svc.WaitUntilInstanceRunning(&builtInstance)
-	if err != nil {
-		return err
-	}
+	ev.InstanceAWSID = aws.String(st.ID)
+	ev.PublicIP = optional(st.PublicIP)
+	ev.IPv6Addresses = aws.StringSlice(st.IPv6Addresses)
 
-	if *ev.AssignElasticIP {
-		ev.ElasticIP, ev.ElasticIPAWSID, err = ev.assignElasticIP(svc, resp.Instances[0].InstanceId)
-		if err != nil {
-			return err
-		}
-	}
-
-	ev.InstanceAWSID = resp.Instances[0].InstanceId
-
-	instance, err := ev.getInstanceByID(resp.Instances[0].InstanceId)
-	if err != nil {
-		return err
-	}
-
-	ev.PublicIP = instance.PublicIpAddress
-
-	err = ev.setTags()
-	if err != nil {
-		return err
+	if st.ElasticIPID != "" {
+		ev.ElasticIP = aws.String(st.ElasticIP)
+		ev.ElasticIPAWSID = aws.String(st.ElasticIPID)
 	}
 
-	return ev.attachVolumes()
+	return nil
 }
 
 // Update : Updates a instance object on aws
 func (ev *Event) Update() error {
-	var err error
-	svc := ev.getEC2Client()
-
-	builtInstance := ec2.DescribeInstancesInput{
-		InstanceIds: []*string{ev.InstanceAWSID},
-	}
-
-	okInstance := ec2.DescribeInstanceStatusInput{
-		InstanceIds: []*string{ev.InstanceAWSID},
-	}
-
-	input := ec2.DescribeInstanceStatusInput{
-		InstanceIds:         append([]*string{}, ev.InstanceAWSID),
-		IncludeAllInstances: aws.Bool(true),
-	}
-	output, _ := svc.DescribeInstanceStatus(&input)
-	status := output.InstanceStatuses[0].InstanceState.Code
-
-	if *status != 80 {
-		err := svc.WaitUntilInstanceStatusOk(&okInstance)
-		if err != nil {
-			log.Println("[ERROR]: Waiting for instance to be in status OK")
-			return err
-		}
-
-		stopreq := ec2.StopInstancesInput{
-			InstanceIds: []*string{ev.InstanceAWSID},
-		}
-
-		// power off the instance
-		_, err = svc.StopInstances(&stopreq)
-		if err != nil {
-			log.Println("[ERROR]: While stopping the instance")
-			return err
-		}
-
-		err = svc.WaitUntilInstanceStopped(&builtInstance)
-		if err != nil {
-			log.Println("[ERROR]: Waiting until instance is stopped")
-			return err
-		}
-	}
-
-	// resize the instance
-	req := ec2.ModifyInstanceAttributeInput{
-		InstanceId: ev.InstanceAWSID,
-		InstanceType: &ec2.AttributeValue{
-			Value: ev.Type,
-		},
-	}
-
-	_, err = svc.ModifyInstanceAttribute(&req)
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.InstanceAWSID), ev.spec())
 	if err != nil {
-		log.Println("[ERROR]: Modifying instance attributes (I)")
 		return err
 	}
 
-	// update instance security groups
-	req = ec2.ModifyInstanceAttributeInput{
-		InstanceId: ev.InstanceAWSID,
-		Groups:     []*string{},
-	}
-
-	for _, sg := range ev.SecurityGroupAWSIDs {
-		req.Groups = append(req.Groups, sg)
-	}
-
-	_, err = svc.ModifyInstanceAttribute(&req)
-	if err != nil {
-		log.Println("[ERROR]: Modifying instance attributes (II)")
-		return err
+	if ev.IPv6AddressCount != nil {
+		ev.IPv6Addresses = aws.StringSlice(st.IPv6Addresses)
 	}
 
-	err = ev.attachVolumes()
-	if err != nil {
-		log.Println("[ERROR]: Attaching instance volumes")
-		return err
-	}
-
-	if ev.Powered == true {
-		// power the instance back on
-		startreq := ec2.StartInstancesInput{
-			InstanceIds: []*string{ev.InstanceAWSID},
-		}
-
-		_, err = svc.StartInstances(&startreq)
-		if err != nil {
-			log.Println("[ERROR] While starting the instance")
-			return err
-		}
-
-		err = svc.WaitUntilInstanceRunning(&builtInstance)
-		if err != nil {
-			log.Println("[ERROR] While waiting for instance to be running")
-			return err
-		}
-
-		instance, err := ev.getInstanceByID(ev.InstanceAWSID)
-		if err != nil {
-			log.Println("[ERROR]: Getting instance by id")
-			return err
-		}
-
-		ev.PublicIP = instance.PublicIpAddress
+	if ev.Powered {
+		ev.PublicIP = optional(st.PublicIP)
 	}
 
-	return ev.setTags()
+	return nil
 }
 
 // Delete : Deletes a instance object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getEC2Client()
-
-	req := ec2.TerminateInstancesInput{
-		InstanceIds: []*string{ev.InstanceAWSID},
-	}
-
-	_, err := svc.TerminateInstances(&req)
-	if err != nil {
-		return err
-	}
-
-	termreq := ec2.DescribeInstancesInput{
-		InstanceIds: []*string{ev.InstanceAWSID},
-	}
-
-	err = svc.WaitUntilInstanceTerminated(&termreq)
-	if err != nil {
-		return err
-	}
-
-	if ev.ElasticIPAWSID != nil {
-		rreq := &ec2.ReleaseAddressInput{
-			AllocationId: ev.ElasticIPAWSID,
-		}
-
-		_, err = svc.ReleaseAddress(rreq)
-	}
-
-	return err
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.InstanceAWSID), aws.StringValue(ev.ElasticIPAWSID))
 }
 
 // Get : Gets a instance object on aws
@@ -394,153 +248,56 @@ func (ev *Event) Get() error {
 }
 
 func (ev *Event) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
+	return ev.client().getEC2Client()
 }
 
-func (ev *Event) assignElasticIP(svc *ec2.EC2, instanceID *string) (*string, *string, error) {
-	// Create Elastic IP
-	resp, err := svc.AllocateAddress(nil)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	req := ec2.AssociateAddressInput{
-		InstanceId:   instanceID,
-		AllocationId: resp.AllocationId,
-	}
-	_, err = svc.AssociateAddress(&req)
-	if err != nil {
-		return nil, nil, err
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
 	}
-
-	return resp.PublicIp, resp.AllocationId, nil
 }
 
-func (ev *Event) getInstanceByID(id *string) (*ec2.Instance, error) {
-	svc := ev.getEC2Client()
-
-	req := ec2.DescribeInstancesInput{
-		InstanceIds: []*string{id},
-	}
-
-	resp, err := svc.DescribeInstances(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.Reservations) != 1 {
-		return nil, errors.New("Could not find any instance reservations")
-	}
-
-	if len(resp.Reservations[0].Instances) != 1 {
-		return nil, errors.New("Could not find an instance with that ID")
-	}
-
-	return resp.Reservations[0].Instances[0], nil
-}
-
-func (ev *Event) encodeUserData(data *string) *string {
-	value := base64.StdEncoding.EncodeToString([]byte(*data))
-	return &value
-}
-
-func (ev *Event) attachVolumes() error {
-	svc := ev.getEC2Client()
-
-	instance, err := ev.getInstanceByID(ev.InstanceAWSID)
-	if err != nil {
-		return err
-	}
-
-	for _, bdm := range instance.BlockDeviceMappings {
-		if hasBlockDevice(ev.Volumes, bdm) || *bdm.DeviceName == *instance.RootDeviceName {
-			continue
-		}
-
-		req := &ec2.DetachVolumeInput{
-			InstanceId: ev.InstanceAWSID,
-			VolumeId:   bdm.Ebs.VolumeId,
-		}
-
-		_, err = svc.DetachVolume(req)
-		if err != nil {
-			return err
-		}
+func (ev *Event) spec() Spec {
+	s := Spec{
+		Name:               aws.StringValue(ev.Name),
+		Type:               aws.StringValue(ev.Type),
+		Image:              aws.StringValue(ev.Image),
+		NetworkID:          aws.StringValue(ev.NetworkAWSID),
+		IP:                 aws.StringValue(ev.IP),
+		IPv6AddressCount:   ev.IPv6AddressCount,
+		KeyPair:            aws.StringValue(ev.KeyPair),
+		UserData:           aws.StringValue(ev.UserData),
+		SecurityGroupIDs:   aws.StringValueSlice(ev.SecurityGroupAWSIDs),
+		IAMInstanceProfile: aws.StringValue(ev.IAMInstanceProfile),
+		AssignElasticIP:    aws.BoolValue(ev.AssignElasticIP),
+		Powered:            ev.Powered,
+		Tags:               ev.Tags,
 	}
 
 	for _, vol := range ev.Volumes {
-		// check volume doesn't exist
-		if hasVolumeAttached(instance.BlockDeviceMappings, vol) {
-			continue
-		}
-
-		req := &ec2.AttachVolumeInput{
-			Device:     vol.Device,
-			VolumeId:   vol.VolumeAWSID,
-			InstanceId: ev.InstanceAWSID,
-		}
-
-		_, err = svc.AttachVolume(req)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (ev *Event) setTags() error {
-	svc := ev.getEC2Client()
-
-	for key, val := range ev.Tags {
-		req := &ec2.CreateTagsInput{
-			Resources: []*string{ev.InstanceAWSID},
-		}
-
-		req.Tags = append(req.Tags, &ec2.Tag{
-			Key:   &key,
-			Value: &val,
+		s.Volumes = append(s.Volumes, Attachment{
+			Device:   aws.StringValue(vol.Device),
+			VolumeID: aws.StringValue(vol.VolumeAWSID),
 		})
-
-		_, err := svc.CreateTags(req)
-		if err != nil {
-			return err
-		}
 	}
 
-	return nil
+	return s
 }
 
-func hasVolumeAttached(bdms []*ec2.InstanceBlockDeviceMapping, vol Volume) bool {
-	for _, bdm := range bdms {
-		if *bdm.Ebs.VolumeId == *vol.VolumeAWSID || *bdm.DeviceName == *vol.Device {
-			return true
-		}
-	}
-
-	return false
-}
-
-func hasBlockDevice(volumes []Volume, bdm *ec2.InstanceBlockDeviceMapping) bool {
-	for _, vol := range volumes {
-		if *vol.VolumeAWSID == *bdm.Ebs.VolumeId || *vol.Device == *bdm.DeviceName {
-			return true
-		}
-	}
-
-	return false
-}
-
-func mapEC2Tags(input []*ec2.Tag) map[string]string {
-	t := make(map[string]string)
-
-	for _, tag := range input {
-		t[*tag.Key] = *tag.Value
+func optional(s string) *string {
+	if s == "" {
+		return nil
 	}
 
-	return t
+	return aws.String(s)
 }
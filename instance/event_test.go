@@ -0,0 +1,208 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package instance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+// setup : creates a vpc with a network, a security group and a volume
+func setup(t *testing.T, b *awsfake.Backend) map[string]string {
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	var net ec2.CreateSubnetOutput
+	if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(ids["vpc"]), CidrBlock: aws.String("10.0.0.0/24"), AvailabilityZone: aws.String("us-east-1a")}, &net); err != nil {
+		t.Fatal(err)
+	}
+	ids["net"] = *net.Subnet.SubnetId
+
+	var sg ec2.CreateSecurityGroupOutput
+	if err := b.EC2.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{VpcId: aws.String(ids["vpc"]), GroupName: aws.String("web"), Description: aws.String("web")}, &sg); err != nil {
+		t.Fatal(err)
+	}
+	ids["sg"] = *sg.GroupId
+
+	var vol ec2.Volume
+	if err := b.EC2.CreateVolume(&ec2.CreateVolumeInput{AvailabilityZone: aws.String("us-east-1a"), Size: aws.Int64(20)}, &vol); err != nil {
+		t.Fatal(err)
+	}
+	ids["vol"] = *vol.VolumeId
+
+	return ids
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "instance.create.aws",
+			body:     `{"name":"web-1","instance_type":"t2.micro","image":"ami-1","network_aws_id":"$net","security_group_aws_ids":["$sg"],"assign_elastic_ip":true,"volumes":[{"volume":"data","device":"/dev/sdf","volume_aws_id":"$vol"}],"tags":{"Name":"web-1"}}`,
+			expected: "instance.create.aws.done",
+			save:     map[string]string{"id": "instance_aws_id", "eip": "elastic_ip_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				i := b.EC2.Instances[ids["id"]]
+				return i != nil && len(i.Tags) == 1 && len(i.SecurityGroups) == 1 && len(i.BlockDeviceMappings) == 2 && len(b.EC2.Addresses) == 1 && res["elastic_ip"] != nil
+			},
+		},
+		{
+			name:     "update",
+			subject:  "instance.update.aws",
+			body:     `{"name":"web-1","instance_type":"t2.large","image":"ami-1","network_aws_id":"$net","instance_aws_id":"$id","security_group_aws_ids":[],"powered":true,"tags":{"Name":"web-1"}}`,
+			expected: "instance.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				i := b.EC2.Instances[ids["id"]]
+				return *i.InstanceType == "t2.large" && *i.State.Name == "running" && len(i.SecurityGroups) == 0 && len(i.BlockDeviceMappings) == 1
+			},
+		},
+		{
+			name:     "update powered off",
+			subject:  "instance.update.aws",
+			body:     `{"name":"web-1","instance_type":"t2.large","image":"ami-1","network_aws_id":"$net","instance_aws_id":"$id","powered":false,"tags":{"Name":"web-1"}}`,
+			expected: "instance.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return *b.EC2.Instances[ids["id"]].State.Name == "stopped"
+			},
+		},
+		{
+			name:     "find",
+			subject:  "instance.find.aws",
+			body:     `{"tags":{"Name":"web-1"}}`,
+			expected: "instance.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				i := found[0].(map[string]interface{})
+				return i["instance_aws_id"] == ids["id"] && i["instance_type"] == "t2.large" && i["network_aws_id"] == ids["net"] && i["powered"] == false
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "instance.delete.aws",
+			body:     `{"name":"web-1","instance_type":"t2.large","image":"ami-1","instance_aws_id":"$id","elastic_ip_aws_id":"$eip"}`,
+			expected: "instance.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return *b.EC2.Instances[ids["id"]].State.Name == "terminated" && len(b.EC2.Addresses) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails",
+			operation: "RunInstances",
+			subject:   "instance.create.aws",
+			body:      `{"name":"web-1","instance_type":"t2.micro","image":"ami-1","network_aws_id":"$net","assign_elastic_ip":false}`,
+		},
+		{
+			name:      "create fails assigning the elastic ip",
+			operation: "AllocateAddress",
+			subject:   "instance.create.aws",
+			body:      `{"name":"web-1","instance_type":"t2.micro","image":"ami-1","network_aws_id":"$net","assign_elastic_ip":true}`,
+		},
+		{
+			name:      "create fails attaching the volumes",
+			operation: "AttachVolume",
+			subject:   "instance.create.aws",
+			body:      `{"name":"web-1","instance_type":"t2.micro","image":"ami-1","network_aws_id":"$net","assign_elastic_ip":false,"volumes":[{"device":"/dev/sdf","volume_aws_id":"$vol"}]}`,
+		},
+		{
+			name:      "update fails stopping the instance",
+			operation: "StopInstances",
+			subject:   "instance.update.aws",
+			body:      `{"name":"web-1","instance_type":"t2.large","image":"ami-1","network_aws_id":"$net","instance_aws_id":"$id","powered":true}`,
+		},
+		{
+			name:      "update fails starting the instance",
+			operation: "StartInstances",
+			subject:   "instance.update.aws",
+			body:      `{"name":"web-1","instance_type":"t2.large","image":"ami-1","network_aws_id":"$net","instance_aws_id":"$id","powered":true}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "TerminateInstances",
+			subject:   "instance.delete.aws",
+			body:      `{"name":"web-1","instance_type":"t2.micro","image":"ami-1","instance_aws_id":"$id"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := setup(t, b)
+
+			var out ec2.Reservation
+			if err := b.EC2.RunInstances(&ec2.RunInstancesInput{ImageId: aws.String("ami-1"), SubnetId: aws.String(ids["net"]), MinCount: aws.Int64(1), MaxCount: aws.Int64(1)}, &out); err != nil {
+				t.Fatal(err)
+			}
+			ids["id"] = *out.Instances[0].InstanceId
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
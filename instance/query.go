@@ -5,15 +5,15 @@
 package instance
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/iam"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -23,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -51,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -80,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -109,64 +119,30 @@ func (col *Collection) Delete() error {
 
 // Find : Find instances on aws
 func (col *Collection) Find() error {
-	svc := col.getEC2Client()
-
-	req := &ec2.DescribeInstancesInput{
-		Filters: mapFilters(col.Tags),
-	}
-
-	resp, err := svc.DescribeInstances(req)
+	instances, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, r := range resp.Reservations {
-		for _, i := range r.Instances {
-			var profile *string
-
-			if i.IamInstanceProfile != nil {
-				isvc := col.getIAMClient()
-
-				resp, err := isvc.ListInstanceProfiles(nil)
-				if err != nil {
-					return err
-				}
-
-				for _, p := range resp.InstanceProfiles {
-					if *p.Arn == *i.IamInstanceProfile.Arn {
-						profile = p.InstanceProfileName
-					}
-				}
-			}
-
-			input := ec2.DescribeInstanceStatusInput{
-				InstanceIds:         append([]*string{}, i.InstanceId),
-				IncludeAllInstances: aws.Bool(true),
-			}
-			output, _ := svc.DescribeInstanceStatus(&input)
-			status := output.InstanceStatuses[0].InstanceState.Code
-
-			col.Results = append(col.Results, toEvent(i, profile, *status))
-		}
+	for _, st := range instances {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func (col *Collection) getIAMClient() *iam.IAM {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return iam.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
 func mapFilters(tags map[string]string) []*ec2.Filter {
@@ -187,62 +163,40 @@ func mapFilters(tags map[string]string) []*ec2.Filter {
 	return f
 }
 
-func mapAWSSecurityGroupIDs(gi []*ec2.GroupIdentifier) []*string {
-	var sgs []*string
-
-	for _, sg := range gi {
-		sgs = append(sgs, sg.GroupId)
-	}
-
-	return sgs
-}
-
-func mapAWSVolumes(vs []*ec2.InstanceBlockDeviceMapping, rootDevice *string) []Volume {
-	var vols []Volume
-
-	for _, v := range vs {
-		// omit root disk!
-		if *v.DeviceName != *rootDevice {
-			vols = append(vols, Volume{
-				Device:      v.DeviceName,
-				VolumeAWSID: v.Ebs.VolumeId,
-			})
-		}
+// toEvent converts an instance status to an ernest event
+func toEvent(st Status) *Event {
+	e := &Event{
+		ProviderType:        "aws",
+		ComponentType:       "instance",
+		ComponentID:         "instance::" + st.Name,
+		InstanceAWSID:       aws.String(st.ID),
+		Name:                aws.String(st.Name),
+		Type:                aws.String(st.Type),
+		Image:               aws.String(st.Image),
+		NetworkAWSID:        aws.String(st.NetworkID),
+		SecurityGroupAWSIDs: aws.StringSlice(st.SecurityGroupIDs),
+		IP:                  optional(st.IP),
+		IPv6Addresses:       aws.StringSlice(st.IPv6Addresses),
+		KeyPair:             optional(st.KeyPair),
+		PublicIP:            optional(st.PublicIP),
+		Tags:                st.Tags,
+		Powered:             st.Powered,
 	}
 
-	return vols
-}
-
-// ToEvent converts an ec2 instance object to an ernest event
-func toEvent(i *ec2.Instance, profile *string, status int64) *Event {
-	powered := true
-	if status != 16 {
-		powered = false
+	for _, vol := range st.Volumes {
+		e.Volumes = append(e.Volumes, Volume{
+			Device:      aws.String(vol.Device),
+			VolumeAWSID: aws.String(vol.VolumeID),
+		})
 	}
-	tags := mapEC2Tags(i.Tags)
-	name := tags["Name"]
 
-	e := &Event{
-		ProviderType:        "aws",
-		ComponentType:       "instance",
-		ComponentID:         "instance::" + name,
-		InstanceAWSID:       i.InstanceId,
-		Name:                aws.String(name),
-		Type:                i.InstanceType,
-		Image:               i.ImageId,
-		NetworkAWSID:        i.SubnetId,
-		SecurityGroupAWSIDs: mapAWSSecurityGroupIDs(i.SecurityGroups),
-		IP:                  i.PrivateIpAddress,
-		KeyPair:             i.KeyName,
-		PublicIP:            i.PublicIpAddress,
-		Volumes:             mapAWSVolumes(i.BlockDeviceMappings, i.RootDeviceName),
-		Tags:                tags,
-		Powered:             powered,
+	if len(e.IPv6Addresses) > 0 {
+		e.IPv6AddressCount = aws.Int64(int64(len(e.IPv6Addresses)))
 	}
 
-	if i.IamInstanceProfile != nil {
-		e.IAMInstanceProfileARN = i.IamInstanceProfile.Arn
-		e.IAMInstanceProfile = profile
+	if st.IAMInstanceProfileARN != "" {
+		e.IAMInstanceProfileARN = aws.String(st.IAMInstanceProfileARN)
+		e.IAMInstanceProfile = optional(st.IAMInstanceProfile)
 	}
 
 	return e
@@ -0,0 +1,50 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package instance
+
+import "github.com/ernestio/ernestaws/schema"
+
+var volume = schema.Field{
+	Type: schema.Object,
+	Fields: []schema.Field{
+		{Name: "volume", Type: schema.String},
+		{Name: "device", Type: schema.String, Required: true},
+		{Name: "volume_aws_id", Type: schema.String, Required: true},
+	},
+}
+
+var instance = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "instance_type", Type: schema.String, Required: true},
+	{Name: "image", Type: schema.String, Required: true},
+	{Name: "network_aws_id", Type: schema.String, Required: true},
+	{Name: "ip", Type: schema.String, Format: schema.IP},
+	{Name: "ipv6_address_count", Type: schema.Integer, Minimum: schema.Int(0)},
+	{Name: "key_pair", Type: schema.String},
+	{Name: "user_data", Type: schema.String},
+	{Name: "security_group_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "iam_instance_profile", Type: schema.String},
+	{Name: "volumes", Type: schema.Array, Items: &volume},
+	{Name: "powered", Type: schema.Boolean},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("instance", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), instance, []schema.Field{
+			{Name: "assign_elastic_ip", Type: schema.Boolean, Required: true},
+		}),
+		"update": schema.Fields(schema.Datacenter(), instance, []schema.Field{
+			{Name: "instance_aws_id", Type: schema.String, Required: true},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "instance_aws_id", Type: schema.String, Required: true},
+			{Name: "elastic_ip_aws_id", Type: schema.String},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
@@ -0,0 +1,33 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package instance
+
+import (
+	"strings"
+
+	"github.com/ernestio/ernestaws/verify"
+)
+
+// verify : checks the event against the live aws metadata
+func (ev *Event) verify() error {
+	if !verify.Enabled() {
+		return nil
+	}
+
+	switch strings.Split(ev.Subject, ".")[1] {
+	case "create", "update":
+	default:
+		return nil
+	}
+
+	svc := ev.getEC2Client()
+
+	var c verify.Check
+	c.Image(svc, "$.image", ev.Image)
+	c.KeyPair(svc, "$.key_pair", ev.KeyPair)
+	c.InstanceType(svc, "$.instance_type", "$.network_aws_id", ev.Type, ev.NetworkAWSID)
+
+	return c.Err()
+}
@@ -0,0 +1,237 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package internetgateway
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of an internet gateway
+type Spec struct {
+	Name  string
+	VpcID string
+	Tags  map[string]string
+}
+
+// Status describes an internet gateway as it is on aws
+type Status struct {
+	ID    string
+	Name  string
+	VpcID string
+	Tags  map[string]string
+}
+
+// Client manages internet gateways through a typed api, the json events
+// are an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates an internet gateway attached to the vpc, the gateway
+// already attached to it is returned when there is one
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	st := Status{
+		Name:  s.Name,
+		VpcID: s.VpcID,
+		Tags:  s.Tags,
+	}
+
+	ig, err := c.internetGatewayByVPCID(ctx, svc, s.VpcID)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if ig != nil {
+		st.ID = aws.StringValue(ig.InternetGatewayId)
+		return st, nil
+	}
+
+	resp, err := svc.CreateInternetGatewayWithContext(ctx, &ec2.CreateInternetGatewayInput{})
+	if err != nil {
+		return Status{}, err
+	}
+
+	req := ec2.AttachInternetGatewayInput{
+		InternetGatewayId: resp.InternetGateway.InternetGatewayId,
+		VpcId:             aws.String(s.VpcID),
+	}
+
+	_, err = svc.AttachInternetGatewayWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st.ID = aws.StringValue(resp.InternetGateway.InternetGatewayId)
+
+	return st, c.setTags(ctx, svc, st.ID, s.Tags)
+}
+
+// Delete : detaches an internet gateway from the vpc and deletes it,
+// along with the route tables routing through it
+func (c Client) Delete(ctx context.Context, id, vpcID string) error {
+	svc := c.getEC2Client()
+
+	err := c.deleteRouteTables(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+
+	dreq := &ec2.DetachInternetGatewayInput{
+		InternetGatewayId: aws.String(id),
+		VpcId:             aws.String(vpcID),
+	}
+
+	_, err = svc.DetachInternetGatewayWithContext(ctx, dreq)
+	if err != nil {
+		return err
+	}
+
+	req := &ec2.DeleteInternetGatewayInput{
+		InternetGatewayId: aws.String(id),
+	}
+
+	_, err = svc.DeleteInternetGatewayWithContext(ctx, req)
+
+	return err
+}
+
+// Find : returns the internet gateways matching all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	req := &ec2.DescribeInternetGatewaysInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := svc.DescribeInternetGatewaysWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var gateways []Status
+
+	for _, ig := range resp.InternetGateways {
+		gateways = append(gateways, toStatus(ig))
+	}
+
+	return gateways, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) internetGatewayByVPCID(ctx context.Context, svc *ec2.EC2, vpc string) (*ec2.InternetGateway, error) {
+	f := []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String("attachment.vpc-id"),
+			Values: []*string{aws.String(vpc)},
+		},
+	}
+
+	req := ec2.DescribeInternetGatewaysInput{
+		Filters: f,
+	}
+
+	resp, err := svc.DescribeInternetGatewaysWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.InternetGateways) == 0 {
+		return nil, nil
+	}
+
+	return resp.InternetGateways[0], nil
+}
+
+func (c Client) deleteRouteTables(ctx context.Context, svc *ec2.EC2, id string) error {
+	f := []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String("route.gateway-id"),
+			Values: []*string{aws.String(id)},
+		},
+	}
+
+	req := &ec2.DescribeRouteTablesInput{
+		Filters: f,
+	}
+
+	resp, err := svc.DescribeRouteTablesWithContext(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for _, rt := range resp.RouteTables {
+		for _, assoc := range rt.Associations {
+			ddreq := &ec2.DisassociateRouteTableInput{
+				AssociationId: assoc.RouteTableAssociationId,
+			}
+
+			_, err = svc.DisassociateRouteTableWithContext(ctx, ddreq)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+		}
+
+		dreq := &ec2.DeleteRouteTableInput{
+			RouteTableId: rt.RouteTableId,
+		}
+
+		_, err = svc.DeleteRouteTableWithContext(ctx, dreq)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+	}
+
+	return nil
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toStatus(ig *ec2.InternetGateway) Status {
+	tags := mapEC2Tags(ig.Tags)
+
+	st := Status{
+		ID:   aws.StringValue(ig.InternetGatewayId),
+		Name: tags["Name"],
+		Tags: tags,
+	}
+
+	for _, a := range ig.Attachments {
+		st.VpcID = aws.StringValue(a.VpcId)
+	}
+
+	return st
+}
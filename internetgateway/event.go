@@ -5,16 +5,16 @@
 package internetgateway
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -35,6 +35,7 @@ type Event struct {
 	ComponentID          string            `json:"_component_id"`
 	State                string            `json:"_state"`
 	Action               string            `json:"_action"`
+	SchemaVersion        int               `json:"_schema_version"`
 	InternetGatewayAWSID *string           `json:"internet_gateway_aws_id"`
 	Name                 *string           `json:"name"`
 	Tags                 map[string]string `json:"tags"`
@@ -63,6 +64,10 @@ func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.VpcID == "" {
 		return ErrDatacenterIDInvalid
 	}
@@ -86,6 +91,11 @@ func (ev *Event) Validate() error {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -120,36 +130,14 @@ func (ev *Event) Find() error {
 
 // Create : Creates a nat object on aws
 func (ev *Event) Create() error {
-	svc := ev.getEC2Client()
-
-	ig, err := ev.internetGatewayByVPCID(svc, ev.VpcID)
-	if err != nil {
-		return err
-	}
-
-	if ig != nil {
-		ev.InternetGatewayAWSID = ig.InternetGatewayId
-		return nil
-	}
-
-	resp, err := svc.CreateInternetGateway(nil)
-	if err != nil {
-		return err
-	}
-
-	req := ec2.AttachInternetGatewayInput{
-		InternetGatewayId: resp.InternetGateway.InternetGatewayId,
-		VpcId:             aws.String(ev.VpcID),
-	}
-
-	_, err = svc.AttachInternetGateway(&req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	ev.InternetGatewayAWSID = resp.InternetGateway.InternetGatewayId
+	ev.InternetGatewayAWSID = aws.String(st.ID)
 
-	return ev.setTags()
+	return nil
 }
 
 // Update : Updates a nat object on aws
@@ -159,30 +147,7 @@ func (ev *Event) Update() error {
 
 // Delete : Deletes a nat object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getEC2Client()
-
-	err := ev.deleteRouteTables()
-	if err != nil {
-		return err
-	}
-
-	dreq := &ec2.DetachInternetGatewayInput{
-		InternetGatewayId: ev.InternetGatewayAWSID,
-		VpcId:             aws.String(ev.VpcID),
-	}
-
-	_, err = svc.DetachInternetGateway(dreq)
-	if err != nil {
-		return err
-	}
-
-	req := &ec2.DeleteInternetGatewayInput{
-		InternetGatewayId: ev.InternetGatewayAWSID,
-	}
-
-	_, err = svc.DeleteInternetGateway(req)
-
-	return err
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.InternetGatewayAWSID), ev.VpcID)
 }
 
 // Get : Gets a nat object on aws
@@ -204,102 +169,26 @@ func (ev *Event) GetSubject() string {
 	return ev.Subject
 }
 
-func (ev *Event) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func (ev *Event) internetGatewayByVPCID(svc *ec2.EC2, vpc string) (*ec2.InternetGateway, error) {
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("attachment.vpc-id"),
-			Values: []*string{aws.String(vpc)},
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
 		},
-	}
-
-	req := ec2.DescribeInternetGatewaysInput{
-		Filters: f,
-	}
-
-	resp, err := svc.DescribeInternetGateways(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.InternetGateways) == 0 {
-		return nil, nil
-	}
-
-	return resp.InternetGateways[0], nil
-}
-
-func (ev *Event) deleteRouteTables() error {
-	svc := ev.getEC2Client()
-
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("route.gateway-id"),
-			Values: []*string{ev.InternetGatewayAWSID},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
 		},
 	}
-
-	req := &ec2.DescribeRouteTablesInput{
-		Filters: f,
-	}
-
-	resp, err := svc.DescribeRouteTables(req)
-	if err != nil {
-		return err
-	}
-
-	for _, rt := range resp.RouteTables {
-		for _, assoc := range rt.Associations {
-			ddreq := &ec2.DisassociateRouteTableInput{
-				AssociationId: assoc.RouteTableAssociationId,
-			}
-
-			_, err = svc.DisassociateRouteTable(ddreq)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-		}
-
-		dreq := &ec2.DeleteRouteTableInput{
-			RouteTableId: rt.RouteTableId,
-		}
-
-		_, err = svc.DeleteRouteTable(dreq)
-		if err != nil {
-			log.Println(err)
-			continue
-		}
-	}
-
-	return nil
 }
 
-func (ev *Event) setTags() error {
-	svc := ev.getEC2Client()
-
-	for key, val := range ev.Tags {
-		req := &ec2.CreateTagsInput{
-			Resources: []*string{ev.InternetGatewayAWSID},
-		}
-
-		req.Tags = append(req.Tags, &ec2.Tag{
-			Key:   &key,
-			Value: &val,
-		})
-
-		_, err := svc.CreateTags(req)
-		if err != nil {
-			return err
-		}
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:  aws.StringValue(ev.Name),
+		VpcID: ev.VpcID,
+		Tags:  ev.Tags,
 	}
-
-	return nil
 }
@@ -0,0 +1,142 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package internetgateway
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "internet_gateway.create.aws",
+			body:     `{"vpc_id":"$vpc","name":"gw","tags":{"Name":"gw"}}`,
+			expected: "internet_gateway.create.aws.done",
+			save:     map[string]string{"id": "internet_gateway_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				return len(b.EC2.InternetGateways[ids["id"]].Attachments) == 1
+			},
+		},
+		{
+			name:     "create reuses the attached gateway",
+			subject:  "internet_gateway.create.aws",
+			body:     `{"vpc_id":"$vpc","name":"gw"}`,
+			expected: "internet_gateway.create.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return res["internet_gateway_aws_id"] == ids["id"] && len(b.EC2.InternetGateways) == 1
+			},
+		},
+		{
+			name:     "update is not supported",
+			subject:  "internet_gateway.update.aws",
+			body:     `{"vpc_id":"$vpc","internet_gateway_aws_id":"$id"}`,
+			expected: "internet_gateway.update.aws.error",
+		},
+		{
+			name:     "find",
+			subject:  "internet_gateway.find.aws",
+			body:     `{"tags":{"Name":"gw"}}`,
+			expected: "internet_gateway.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				return len(found) == 1 && found[0].(map[string]interface{})["vpc_id"] == ids["vpc"]
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "internet_gateway.delete.aws",
+			body:     `{"vpc_id":"$vpc","internet_gateway_aws_id":"$id"}`,
+			expected: "internet_gateway.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.EC2.InternetGateways) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails attaching the gateway",
+			operation: "AttachInternetGateway",
+			subject:   "internet_gateway.create.aws",
+			body:      `{"vpc_id":"$vpc"}`,
+		},
+		{
+			name:      "delete fails detaching the gateway",
+			operation: "DetachInternetGateway",
+			subject:   "internet_gateway.delete.aws",
+			body:      `{"vpc_id":"$vpc","internet_gateway_aws_id":"igw-00000009"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, map[string]string{"vpc": createVpc(t, b)})
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
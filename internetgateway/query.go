@@ -5,14 +5,15 @@
 package internetgateway
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -50,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -108,30 +119,30 @@ func (col *Collection) Delete() error {
 
 // Find : Find instances on aws
 func (col *Collection) Find() error {
-	svc := col.getEC2Client()
-
-	req := &ec2.DescribeInternetGatewaysInput{
-		Filters: mapFilters(col.Tags),
-	}
-
-	resp, err := svc.DescribeInternetGateways(req)
+	gateways, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, i := range resp.InternetGateways {
-		col.Results = append(col.Results, toEvent(i))
+	for _, st := range gateways {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
 func mapFilters(tags map[string]string) []*ec2.Filter {
@@ -147,25 +158,16 @@ func mapFilters(tags map[string]string) []*ec2.Filter {
 	return f
 }
 
-// ToEvent converts an ec2 instance object to an ernest event
-func toEvent(i *ec2.InternetGateway) *Event {
-	var vpcid string
-
-	tags := mapEC2Tags(i.Tags)
-	name := tags["Name"]
-
-	for _, attachments := range i.Attachments {
-		vpcid = *attachments.VpcId
-	}
-
+// ToEvent converts an internet gateway status to an ernest event
+func toEvent(st Status) *Event {
 	return &Event{
 		ProviderType:         "aws",
 		ComponentType:        "internet_gateway",
-		ComponentID:          "internet_gateway::" + name,
-		InternetGatewayAWSID: i.InternetGatewayId,
-		VpcID:                vpcid,
-		Name:                 aws.String(name),
-		Tags:                 tags,
+		ComponentID:          "internet_gateway::" + st.Name,
+		InternetGatewayAWSID: aws.String(st.ID),
+		VpcID:                st.VpcID,
+		Name:                 aws.String(st.Name),
+		Tags:                 st.Tags,
 	}
 }
 
@@ -0,0 +1,26 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package internetgateway
+
+import "github.com/ernestio/ernestaws/schema"
+
+func init() {
+	schema.Register("internet_gateway", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "name", Type: schema.String},
+			{Name: "tags", Type: schema.Map},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "internet_gateway_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("internet_gateway", 1, schema.VpcReference)
+}
@@ -0,0 +1,196 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Store persists the progress of the operations
+type Store interface {
+	// Load returns the entry stored under key, or nil if there is none
+	Load(key string) (*Entry, error)
+	Save(key string, e *Entry) error
+	Delete(key string) error
+}
+
+// Entry stores the completed steps of an operation, with the ids each
+// of them created. The digest identifies the event body the steps were
+// run for
+type Entry struct {
+	Digest  string               `json:"digest"`
+	Updated time.Time            `json:"updated"`
+	Steps   map[string][]*string `json:"steps"`
+}
+
+// Check reports whether the resources created by a completed step still
+// exist, the step is only skipped if they do
+type Check func() (bool, error)
+
+// MaxAge is the time after which an entry is discarded, as the event it
+// belongs to won't be re-delivered anymore
+var MaxAge = 24 * time.Hour
+
+var (
+	mu    sync.RWMutex
+	store Store
+)
+
+// Use : sets the store for the operation journals, journaling is
+// disabled until a store is set
+func Use(s Store) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	store = s
+}
+
+// Journal tracks the progress of a single multi step operation, so a
+// re-delivered event resumes from the last completed step
+type Journal struct {
+	store Store
+	key   string
+	entry *Entry
+}
+
+// Open : loads the journal of the operation identified by the event
+// subject and component id. Entries stored for a different body or
+// older than MaxAge are discarded. Operations without a component id
+// can't be told apart, so they aren't journaled
+func Open(subject, componentID string, body []byte) (*Journal, error) {
+	mu.RLock()
+	s := store
+	mu.RUnlock()
+
+	if componentID == "" {
+		s = nil
+	}
+
+	sum := sha256.Sum256(body)
+
+	j := &Journal{
+		store: s,
+		key:   subject + "/" + componentID,
+		entry: &Entry{
+			Digest: hex.EncodeToString(sum[:]),
+			Steps:  make(map[string][]*string),
+		},
+	}
+
+	if s == nil {
+		return j, nil
+	}
+
+	e, err := s.Load(j.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil || e.Steps == nil {
+		return j, nil
+	}
+
+	if e.Digest != j.entry.Digest || time.Since(e.Updated) > MaxAge {
+		return j, s.Delete(j.key)
+	}
+
+	j.entry = e
+
+	return j, nil
+}
+
+// Done : checks if a step was completed by a previous attempt
+func (j *Journal) Done(step string) bool {
+	_, ok := j.entry.Steps[step]
+	return ok
+}
+
+// Step : runs fn and checkpoints the ids it sets. If a previous attempt
+// already completed the step the ids are restored, and fn is skipped
+// unless check reports its resources are gone. When fn fails with an
+// error a re-delivered event won't get past, the journal is removed
+func (j *Journal) Step(step string, fn func() error, check Check, ids ...**string) error {
+	if stored, ok := j.entry.Steps[step]; ok {
+		for i, id := range ids {
+			if i < len(stored) {
+				*id = stored[i]
+			}
+		}
+
+		if check == nil {
+			return nil
+		}
+
+		exists, err := check()
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return nil
+		}
+
+		delete(j.entry.Steps, step)
+
+		if err := j.save(); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(); err != nil {
+		if !resumable(err) {
+			_ = j.Close()
+		}
+		return err
+	}
+
+	var values []*string
+	for _, id := range ids {
+		values = append(values, *id)
+	}
+
+	j.entry.Steps[step] = values
+
+	return j.save()
+}
+
+func (j *Journal) save() error {
+	if j.store == nil {
+		return nil
+	}
+
+	j.entry.Updated = time.Now()
+
+	return j.store.Save(j.key, j.entry)
+}
+
+// Close : removes the journal once the operation has completed
+func (j *Journal) Close() error {
+	if j.store == nil {
+		return nil
+	}
+
+	return j.store.Delete(j.key)
+}
+
+// resumable : checks if a re-delivered event could get past the error,
+// like throttling, timeouts or aws server errors
+func resumable(err error) bool {
+	if request.IsErrorRetryable(err) || request.IsErrorThrottle(err) {
+		return true
+	}
+
+	if rf, ok := err.(awserr.RequestFailure); ok {
+		return rf.StatusCode() >= 500
+	}
+
+	return false
+}
@@ -0,0 +1,557 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package nat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/journal"
+)
+
+// Spec describes the desired state of a nat gateway, the routed networks
+// get their default route through it
+type Spec struct {
+	Name             string
+	VpcID            string
+	PublicNetworkID  string
+	RoutedNetworkIDs []string
+}
+
+// Status describes a nat gateway as it is on aws
+type Status struct {
+	ID                string
+	Name              string
+	VpcID             string
+	PublicNetworkID   string
+	RoutedNetworkIDs  []string
+	AllocationID      string
+	AllocationIP      string
+	InternetGatewayID string
+}
+
+// Client manages nat gateways through a typed api, the json events are an
+// adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit.
+	// Creates are journaled under it, so a retried create resumes
+	Scope client.Scope
+}
+
+// Create : allocates an elastic ip and creates a nat gateway with it,
+// attaching an internet gateway to the vpc if it has none. The steps
+// are journaled, so a retry of a failed create resumes where it stopped
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	body, err := json.Marshal(s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	j, err := journal.Open(c.Scope.Subject, c.Scope.ComponentID, body)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var allocationID, allocationIP, igID, natID *string
+
+	// Create Elastic IP
+	err = j.Step("address", func() error {
+		resp, err := svc.AllocateAddressWithContext(ctx, &ec2.AllocateAddressInput{})
+		if err != nil {
+			return err
+		}
+
+		allocationID = resp.AllocationId
+		allocationIP = resp.PublicIp
+
+		return nil
+	}, func() (bool, error) {
+		return c.addressExists(ctx, svc, allocationID)
+	}, &allocationID, &allocationIP)
+	if err != nil {
+		return Status{}, err
+	}
+
+	// Create Internet Gateway
+	err = j.Step("internet_gateway", func() error {
+		var err error
+		igID, err = c.createInternetGateway(ctx, svc, s.VpcID)
+		return err
+	}, func() (bool, error) {
+		ig, err := c.internetGatewayByVPCID(ctx, svc, s.VpcID)
+		return ig != nil, err
+	}, &igID)
+	if err != nil {
+		return Status{}, err
+	}
+
+	// Create Nat Gateway
+	err = j.Step("nat_gateway", func() error {
+		req := ec2.CreateNatGatewayInput{
+			AllocationId: allocationID,
+			SubnetId:     aws.String(s.PublicNetworkID),
+		}
+
+		gwresp, err := svc.CreateNatGatewayWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+
+		natID = gwresp.NatGateway.NatGatewayId
+
+		return nil
+	}, func() (bool, error) {
+		return c.natGatewayExists(ctx, svc, natID)
+	}, &natID)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := Status{
+		ID:                aws.StringValue(natID),
+		Name:              s.Name,
+		VpcID:             s.VpcID,
+		PublicNetworkID:   s.PublicNetworkID,
+		RoutedNetworkIDs:  s.RoutedNetworkIDs,
+		AllocationID:      aws.StringValue(allocationID),
+		AllocationIP:      aws.StringValue(allocationIP),
+		InternetGatewayID: aws.StringValue(igID),
+	}
+
+	waitnat := ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: []*string{natID},
+	}
+
+	err = svc.WaitUntilNatGatewayAvailableWithContext(ctx, &waitnat)
+	if err != nil {
+		return st, err
+	}
+
+	for _, networkID := range s.RoutedNetworkIDs {
+		networkID := networkID
+
+		err = j.Step("routes/"+networkID, func() error {
+			rt, err := c.createRouteTable(ctx, svc, s.VpcID, networkID)
+			if err != nil {
+				return err
+			}
+
+			return c.createNatGatewayRoutes(ctx, svc, rt, st.ID)
+		}, func() (bool, error) {
+			rt, err := c.routingTableBySubnetID(ctx, svc, networkID)
+			return rt != nil && routeTableIsConfigured(rt, st.ID), err
+		})
+		if err != nil {
+			return st, err
+		}
+	}
+
+	return st, j.Close()
+}
+
+// Update : routes the networks that are not routed through the nat
+// gateway yet
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	for _, networkID := range s.RoutedNetworkIDs {
+		rt, err := c.createRouteTable(ctx, svc, s.VpcID, networkID)
+		if err != nil {
+			return Status{}, err
+		}
+
+		if routeTableIsConfigured(rt, id) {
+			continue
+		}
+
+		err = c.createNatGatewayRoutes(ctx, svc, rt, id)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	ng, err := c.natGatewayByID(ctx, svc, aws.String(id))
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(ng)
+	st.Name = s.Name
+	st.RoutedNetworkIDs = s.RoutedNetworkIDs
+
+	return st, nil
+}
+
+// Delete : deletes a nat gateway and releases its elastic ip, if any
+func (c Client) Delete(ctx context.Context, id, allocationID string) error {
+	svc := c.getEC2Client()
+
+	req := ec2.DeleteNatGatewayInput{
+		NatGatewayId: aws.String(id),
+	}
+
+	_, err := svc.DeleteNatGatewayWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	for !c.isNatGatewayDeleted(ctx, svc, id) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second * 3):
+		}
+	}
+
+	if allocationID == "" {
+		return nil
+	}
+
+	rreq := &ec2.ReleaseAddressInput{
+		AllocationId: aws.String(allocationID),
+	}
+
+	_, err = svc.ReleaseAddressWithContext(ctx, rreq)
+
+	return err
+}
+
+// Find : returns the nat gateways named by the ernest.nat_gateway tag
+// of one of their routed networks, as nat gateways can't be tagged. The
+// given tags are matched against the tags of that network
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	resp, err := svc.DescribeNatGatewaysWithContext(ctx, &ec2.DescribeNatGatewaysInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var gateways []Status
+
+	for _, ng := range resp.NatGateways {
+		var name string
+
+		networks, err := c.getRoutedNetworks(ctx, svc, aws.StringValue(ng.NatGatewayId))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, network := range networks {
+			t, err := c.getNetworkTags(ctx, svc, network)
+			if err != nil {
+				return nil, err
+			}
+
+			if t["ernest.nat_gateway"] != "" && hasTags(t, tags) {
+				name = t["ernest.nat_gateway"]
+				break
+			}
+		}
+
+		if name == "" {
+			continue
+		}
+
+		st := toStatus(ng)
+		st.Name = name
+		st.RoutedNetworkIDs = networks
+
+		gateways = append(gateways, st)
+	}
+
+	return gateways, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) internetGatewayByVPCID(ctx context.Context, svc *ec2.EC2, vpc string) (*ec2.InternetGateway, error) {
+	f := []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String("attachment.vpc-id"),
+			Values: []*string{aws.String(vpc)},
+		},
+	}
+
+	req := ec2.DescribeInternetGatewaysInput{
+		Filters: f,
+	}
+
+	resp, err := svc.DescribeInternetGatewaysWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.InternetGateways) == 0 {
+		return nil, nil
+	}
+
+	return resp.InternetGateways[0], nil
+}
+
+func (c Client) routingTableBySubnetID(ctx context.Context, svc *ec2.EC2, subnet string) (*ec2.RouteTable, error) {
+	f := []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String("association.subnet-id"),
+			Values: []*string{aws.String(subnet)},
+		},
+	}
+
+	req := ec2.DescribeRouteTablesInput{
+		Filters: f,
+	}
+
+	resp, err := svc.DescribeRouteTablesWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.RouteTables) == 0 {
+		return nil, nil
+	}
+
+	return resp.RouteTables[0], nil
+}
+
+func (c Client) createInternetGateway(ctx context.Context, svc *ec2.EC2, vpc string) (*string, error) {
+	ig, err := c.internetGatewayByVPCID(ctx, svc, vpc)
+	if err != nil {
+		return nil, err
+	}
+
+	if ig != nil {
+		return ig.InternetGatewayId, nil
+	}
+
+	resp, err := svc.CreateInternetGatewayWithContext(ctx, &ec2.CreateInternetGatewayInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	req := ec2.AttachInternetGatewayInput{
+		InternetGatewayId: resp.InternetGateway.InternetGatewayId,
+		VpcId:             aws.String(vpc),
+	}
+
+	_, err = svc.AttachInternetGatewayWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.InternetGateway.InternetGatewayId, nil
+}
+
+func (c Client) createRouteTable(ctx context.Context, svc *ec2.EC2, vpc, subnet string) (*ec2.RouteTable, error) {
+	rt, err := c.routingTableBySubnetID(ctx, svc, subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	if rt != nil {
+		return rt, nil
+	}
+
+	req := ec2.CreateRouteTableInput{
+		VpcId: aws.String(vpc),
+	}
+
+	resp, err := svc.CreateRouteTableWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	acreq := ec2.AssociateRouteTableInput{
+		RouteTableId: resp.RouteTable.RouteTableId,
+		SubnetId:     aws.String(subnet),
+	}
+
+	_, err = svc.AssociateRouteTableWithContext(ctx, &acreq)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.RouteTable, nil
+}
+
+func (c Client) createNatGatewayRoutes(ctx context.Context, svc *ec2.EC2, rt *ec2.RouteTable, gwID string) error {
+	req := ec2.CreateRouteInput{
+		RouteTableId:         rt.RouteTableId,
+		DestinationCidrBlock: aws.String("0.0.0.0/0"),
+		NatGatewayId:         aws.String(gwID),
+	}
+
+	_, err := svc.CreateRouteWithContext(ctx, &req)
+
+	return err
+}
+
+func (c Client) isNatGatewayDeleted(ctx context.Context, svc *ec2.EC2, id string) bool {
+	gw, _ := c.natGatewayByID(ctx, svc, aws.String(id))
+	if gw == nil {
+		return true
+	}
+
+	return *gw.State == ec2.NatGatewayStateDeleted
+}
+
+func (c Client) natGatewayByID(ctx context.Context, svc *ec2.EC2, id *string) (*ec2.NatGateway, error) {
+	req := ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: []*string{id},
+	}
+	resp, err := svc.DescribeNatGatewaysWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.NatGateways) != 1 {
+		return nil, errors.New("Could not find nat gateway")
+	}
+
+	return resp.NatGateways[0], nil
+}
+
+// addressExists : checks the elastic ip of a previous attempt was not
+// released
+func (c Client) addressExists(ctx context.Context, svc *ec2.EC2, id *string) (bool, error) {
+	req := ec2.DescribeAddressesInput{
+		AllocationIds: []*string{id},
+	}
+
+	_, err := svc.DescribeAddressesWithContext(ctx, &req)
+	if isNotFound(err, "InvalidAllocationID.NotFound") {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// natGatewayExists : checks the nat gateway of a previous attempt was
+// not deleted or failed
+func (c Client) natGatewayExists(ctx context.Context, svc *ec2.EC2, id *string) (bool, error) {
+	gw, err := c.natGatewayByID(ctx, svc, id)
+	if isNotFound(err, "NatGatewayNotFound") {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	switch aws.StringValue(gw.State) {
+	case ec2.NatGatewayStateDeleting, ec2.NatGatewayStateDeleted, ec2.NatGatewayStateFailed:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (c Client) getRoutedNetworks(ctx context.Context, svc *ec2.EC2, gatewayID string) ([]string, error) {
+	var ids []string
+
+	req := &ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("route.nat-gateway-id"),
+				Values: []*string{aws.String(gatewayID)},
+			},
+		},
+	}
+
+	resp, err := svc.DescribeRouteTablesWithContext(ctx, req)
+	if err != nil {
+		return ids, err
+	}
+
+	for _, rt := range resp.RouteTables {
+		for _, assoc := range rt.Associations {
+			if assoc.SubnetId != nil {
+				ids = append(ids, *assoc.SubnetId)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func (c Client) getNetworkTags(ctx context.Context, svc *ec2.EC2, networkID string) (map[string]string, error) {
+	req := &ec2.DescribeSubnetsInput{
+		SubnetIds: []*string{aws.String(networkID)},
+	}
+
+	resp, err := svc.DescribeSubnetsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Subnets) != 1 {
+		return nil, errors.New("could not find referenced nat gateway subnet")
+	}
+
+	return mapEC2Tags(resp.Subnets[0].Tags), nil
+}
+
+func routeTableIsConfigured(rt *ec2.RouteTable, gwID string) bool {
+	for _, route := range rt.Routes {
+		if aws.StringValue(route.DestinationCidrBlock) == "0.0.0.0/0" && aws.StringValue(route.NatGatewayId) == gwID {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTags : checks the tags of a network include all the given ones
+func hasTags(current, tags map[string]string) bool {
+	for k, v := range tags {
+		if current[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isNotFound(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
+
+func mapEC2Tags(input []*ec2.Tag) map[string]string {
+	t := make(map[string]string)
+
+	for _, tag := range input {
+		t[*tag.Key] = *tag.Value
+	}
+
+	return t
+}
+
+func toStatus(ng *ec2.NatGateway) Status {
+	st := Status{
+		ID:              aws.StringValue(ng.NatGatewayId),
+		VpcID:           aws.StringValue(ng.VpcId),
+		PublicNetworkID: aws.StringValue(ng.SubnetId),
+	}
+
+	for _, a := range ng.NatGatewayAddresses {
+		if a.PublicIp != nil {
+			st.AllocationID = aws.StringValue(a.AllocationId)
+			st.AllocationIP = aws.StringValue(a.PublicIp)
+			break
+		}
+	}
+
+	return st
+}
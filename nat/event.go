@@ -5,17 +5,17 @@
 package nat
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -40,6 +40,7 @@ type Event struct {
 	ComponentID            string            `json:"_component_id"`
 	State                  string            `json:"_state"`
 	Action                 string            `json:"_action"`
+	SchemaVersion          int               `json:"_schema_version"`
 	NatGatewayAWSID        *string           `json:"nat_gateway_aws_id"`
 	Name                   *string           `json:"name"`
 	PublicNetwork          string            `json:"public_network"`
@@ -88,6 +89,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -98,6 +104,11 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.preflight(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -117,6 +128,10 @@ func (ev *Event) Complete() {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -149,107 +164,32 @@ func (ev *Event) Find() error {
 
 // Create : Creates a nat object on aws
 func (ev *Event) Create() error {
-	svc := ev.getEC2Client()
-
-	// Create Elastic IP
-	resp, err := svc.AllocateAddress(nil)
-	if err != nil {
-		return err
-	}
-
-	ev.NatGatewayAllocationID = resp.AllocationId
-	ev.NatGatewayAllocationIP = resp.PublicIp
-
-	// Create Internet Gateway
-	ev.InternetGatewayID, err = ev.createInternetGateway(svc)
-	if err != nil {
-		return err
-	}
-
-	// Create Nat Gateway
-	req := ec2.CreateNatGatewayInput{
-		AllocationId: ev.NatGatewayAllocationID,
-		SubnetId:     ev.PublicNetworkAWSID,
-	}
-
-	gwresp, err := svc.CreateNatGateway(&req)
-	if err != nil {
-		return err
-	}
-
-	ev.NatGatewayAWSID = gwresp.NatGateway.NatGatewayId
-
-	waitnat := ec2.DescribeNatGatewaysInput{
-		NatGatewayIds: []*string{gwresp.NatGateway.NatGatewayId},
-	}
-
-	err = svc.WaitUntilNatGatewayAvailable(&waitnat)
-	if err != nil {
-		return err
-	}
+	st, err := ev.client().Create(context.Background(), ev.spec())
 
-	for _, networkID := range ev.RoutedNetworkAWSIDs {
-		rt, err := ev.createRouteTable(svc, networkID)
-		if err != nil {
-			return err
-		}
+	ev.NatGatewayAWSID = optional(st.ID)
+	ev.NatGatewayAllocationID = optional(st.AllocationID)
+	ev.NatGatewayAllocationIP = optional(st.AllocationIP)
+	ev.InternetGatewayID = optional(st.InternetGatewayID)
 
-		err = ev.createNatGatewayRoutes(svc, rt, gwresp.NatGateway.NatGatewayId)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return err
 }
 
 // Update : Updates a nat object on aws
 func (ev *Event) Update() error {
-	svc := ev.getEC2Client()
-
-	for _, networkID := range ev.RoutedNetworkAWSIDs {
-		rt, err := ev.createRouteTable(svc, networkID)
-		if err != nil {
-			return err
-		}
-
-		if ev.routeTableIsConfigured(rt) {
-			continue
-		}
-
-		err = ev.createNatGatewayRoutes(svc, rt, ev.NatGatewayAWSID)
-		if err != nil {
-			return err
-		}
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.NatGatewayAWSID), ev.spec())
+	if err != nil {
+		return err
 	}
 
+	ev.NatGatewayAllocationID = optional(st.AllocationID)
+	ev.NatGatewayAllocationIP = optional(st.AllocationIP)
+
 	return nil
 }
 
 // Delete : Deletes a nat object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getEC2Client()
-
-	req := ec2.DeleteNatGatewayInput{
-		NatGatewayId: ev.NatGatewayAWSID,
-	}
-
-	_, err := svc.DeleteNatGateway(&req)
-	if err != nil {
-		return err
-	}
-
-	for ev.isNatGatewayDeleted(svc, ev.NatGatewayAWSID) == false {
-		time.Sleep(time.Second * 3)
-	}
-
-	rreq := &ec2.ReleaseAddressInput{
-		AllocationId: ev.NatGatewayAllocationID,
-	}
-
-	_, err = svc.ReleaseAddress(rreq)
-
-	return err
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.NatGatewayAWSID), aws.StringValue(ev.NatGatewayAllocationID))
 }
 
 // Get : Gets a nat object on aws
@@ -258,171 +198,38 @@ func (ev *Event) Get() error {
 }
 
 func (ev *Event) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
+	return ev.client().getEC2Client()
 }
 
-func (ev *Event) internetGatewayByVPCID(svc *ec2.EC2, vpc string) (*ec2.InternetGateway, error) {
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("attachment.vpc-id"),
-			Values: []*string{aws.String(vpc)},
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
 		},
-	}
-
-	req := ec2.DescribeInternetGatewaysInput{
-		Filters: f,
-	}
-
-	resp, err := svc.DescribeInternetGateways(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.InternetGateways) == 0 {
-		return nil, nil
-	}
-
-	return resp.InternetGateways[0], nil
-}
-
-func (ev *Event) routingTableBySubnetID(svc *ec2.EC2, subnet *string) (*ec2.RouteTable, error) {
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("association.subnet-id"),
-			Values: []*string{subnet},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
 		},
 	}
-
-	req := ec2.DescribeRouteTablesInput{
-		Filters: f,
-	}
-
-	resp, err := svc.DescribeRouteTables(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.RouteTables) == 0 {
-		return nil, nil
-	}
-
-	return resp.RouteTables[0], nil
-}
-
-func (ev *Event) createInternetGateway(svc *ec2.EC2) (*string, error) {
-	ig, err := ev.internetGatewayByVPCID(svc, ev.VpcID)
-	if err != nil {
-		return nil, err
-	}
-
-	if ig != nil {
-		return ig.InternetGatewayId, nil
-	}
-
-	resp, err := svc.CreateInternetGateway(nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req := ec2.AttachInternetGatewayInput{
-		InternetGatewayId: resp.InternetGateway.InternetGatewayId,
-		VpcId:             aws.String(ev.VpcID),
-	}
-
-	_, err = svc.AttachInternetGateway(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp.InternetGateway.InternetGatewayId, nil
-}
-
-func (ev *Event) createRouteTable(svc *ec2.EC2, subnet *string) (*ec2.RouteTable, error) {
-	rt, err := ev.routingTableBySubnetID(svc, subnet)
-	if err != nil {
-		return nil, err
-	}
-
-	if rt != nil {
-		return rt, nil
-	}
-
-	req := ec2.CreateRouteTableInput{
-		VpcId: aws.String(ev.VpcID),
-	}
-
-	resp, err := svc.CreateRouteTable(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	acreq := ec2.AssociateRouteTableInput{
-		RouteTableId: resp.RouteTable.RouteTableId,
-		SubnetId:     subnet,
-	}
-
-	_, err = svc.AssociateRouteTable(&acreq)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp.RouteTable, nil
 }
 
-func (ev *Event) createNatGatewayRoutes(svc *ec2.EC2, rt *ec2.RouteTable, gwID *string) error {
-	req := ec2.CreateRouteInput{
-		RouteTableId:         rt.RouteTableId,
-		DestinationCidrBlock: aws.String("0.0.0.0/0"),
-		NatGatewayId:         gwID,
-	}
-
-	_, err := svc.CreateRoute(&req)
-	if err != nil {
-		return err
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:             aws.StringValue(ev.Name),
+		VpcID:            ev.VpcID,
+		PublicNetworkID:  aws.StringValue(ev.PublicNetworkAWSID),
+		RoutedNetworkIDs: aws.StringValueSlice(ev.RoutedNetworkAWSIDs),
 	}
-
-	return nil
-}
-
-func (ev *Event) isNatGatewayDeleted(svc *ec2.EC2, id *string) bool {
-	gw, _ := ev.natGatewayByID(svc, id)
-	if gw == nil {
-		return true
-	}
-
-	if *gw.State == ec2.NatGatewayStateDeleted {
-		return true
-	}
-
-	return false
 }
 
-func (ev *Event) routeTableIsConfigured(rt *ec2.RouteTable) bool {
-	gwID := ev.NatGatewayAWSID
-	for _, route := range rt.Routes {
-		if *route.DestinationCidrBlock == "0.0.0.0/0" && *route.NatGatewayId == *gwID {
-			return true
-		}
-	}
-	return false
-}
-
-func (ev *Event) natGatewayByID(svc *ec2.EC2, id *string) (*ec2.NatGateway, error) {
-	req := ec2.DescribeNatGatewaysInput{
-		NatGatewayIds: []*string{id},
-	}
-	resp, err := svc.DescribeNatGateways(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.NatGateways) != 1 {
-		return nil, errors.New("Could not find nat gateway")
+func optional(s string) *string {
+	if s == "" {
+		return nil
 	}
 
-	return resp.NatGateways[0], nil
+	return aws.String(s)
 }
@@ -0,0 +1,235 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package nat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+	"github.com/ernestio/ernestaws/journal"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+// setup : creates a vpc with a public network and two routed networks
+// tagged with the nat gateway name
+func setup(t *testing.T, b *awsfake.Backend) map[string]string {
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	for i, name := range []string{"pub", "neta", "netb"} {
+		var out ec2.CreateSubnetOutput
+		if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(ids["vpc"]), CidrBlock: aws.String(fmt.Sprintf("10.0.%d.0/24", i))}, &out); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = *out.Subnet.SubnetId
+	}
+
+	tags := &ec2.CreateTagsInput{
+		Resources: []*string{aws.String(ids["neta"]), aws.String(ids["netb"])},
+		Tags:      []*ec2.Tag{{Key: aws.String("ernest.nat_gateway"), Value: aws.String("nat")}},
+	}
+	if err := b.EC2.CreateTags(tags, &ec2.CreateTagsOutput{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return ids
+}
+
+// routesThrough : checks the default route of a network goes through the nat
+// gateway
+func routesThrough(b *awsfake.Backend, network, gateway string) bool {
+	for _, rt := range b.EC2.RouteTables {
+		for _, a := range rt.Associations {
+			if aws.StringValue(a.SubnetId) != network {
+				continue
+			}
+
+			for _, r := range rt.Routes {
+				if aws.StringValue(r.DestinationCidrBlock) == "0.0.0.0/0" && aws.StringValue(r.NatGatewayId) == gateway {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "nat.create.aws",
+			body:     `{"name":"nat","vpc_id":"$vpc","public_network_aws_id":"$pub","routed_networks_aws_ids":["$neta"]}`,
+			expected: "nat.create.aws.done",
+			save:     map[string]string{"id": "nat_gateway_aws_id", "alloc": "nat_gateway_allocation_id"},
+			check: func(res map[string]interface{}) bool {
+				return b.EC2.NatGateways[ids["id"]] != nil && b.EC2.Addresses[ids["alloc"]] != nil && len(b.EC2.InternetGateways) == 1 && routesThrough(b, ids["neta"], ids["id"])
+			},
+		},
+		{
+			name:     "update",
+			subject:  "nat.update.aws",
+			body:     `{"name":"nat","vpc_id":"$vpc","nat_gateway_aws_id":"$id","public_network_aws_id":"$pub","routed_networks_aws_ids":["$neta","$netb"]}`,
+			expected: "nat.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return routesThrough(b, ids["neta"], ids["id"]) && routesThrough(b, ids["netb"], ids["id"])
+			},
+		},
+		{
+			name:     "find",
+			subject:  "nat.find.aws",
+			body:     `{"service":"test"}`,
+			expected: "nat.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				n := found[0].(map[string]interface{})
+				return n["name"] == "nat" && n["nat_gateway_aws_id"] == ids["id"] && n["nat_gateway_allocation_id"] == ids["alloc"] && len(n["routed_networks_aws_ids"].([]interface{})) == 2
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "nat.delete.aws",
+			body:     `{"nat_gateway_aws_id":"$id","nat_gateway_allocation_id":"$alloc"}`,
+			expected: "nat.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return *b.EC2.NatGateways[ids["id"]].State == ec2.NatGatewayStateDeleted && len(b.EC2.Addresses) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails allocating the address",
+			operation: "AllocateAddress",
+			subject:   "nat.create.aws",
+			body:      `{"name":"nat","vpc_id":"$vpc","public_network_aws_id":"$pub","routed_networks_aws_ids":["$neta"]}`,
+		},
+		{
+			name:      "create fails",
+			operation: "CreateNatGateway",
+			subject:   "nat.create.aws",
+			body:      `{"name":"nat","vpc_id":"$vpc","public_network_aws_id":"$pub","routed_networks_aws_ids":["$neta"]}`,
+		},
+		{
+			name:      "create fails routing the networks",
+			operation: "CreateRoute",
+			subject:   "nat.create.aws",
+			body:      `{"name":"nat","vpc_id":"$vpc","public_network_aws_id":"$pub","routed_networks_aws_ids":["$neta"]}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteNatGateway",
+			subject:   "nat.delete.aws",
+			body:      `{"nat_gateway_aws_id":"nat-00000001"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := setup(t, b)
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestCreateResumes(t *testing.T) {
+	store, err := journal.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal.Use(store)
+	defer journal.Use(nil)
+
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+	body := `{"_component_id":"nat::nat","name":"nat","vpc_id":"$vpc","public_network_aws_id":"$pub","routed_networks_aws_ids":["$neta"]}`
+
+	b.Fail("ec2", "CreateNatGateway", "RequestTimeout", 1)
+
+	if subject, _ := awsfake.Run(t, New, "nat.create.aws", body, ids); subject != "nat.create.aws.error" {
+		t.Fatalf("expected nat.create.aws.error, got %s", subject)
+	}
+
+	subject, res := awsfake.Run(t, New, "nat.create.aws", body, ids)
+	if subject != "nat.create.aws.done" {
+		t.Fatalf("expected nat.create.aws.done, got %s: %v", subject, res["error"])
+	}
+
+	if n := b.Called("ec2", "AllocateAddress"); n != 1 {
+		t.Errorf("expected the address to be allocated once, got %d", n)
+	}
+
+	if len(b.EC2.Addresses) != 1 || len(b.EC2.InternetGateways) != 1 {
+		t.Errorf("expected the resources of the first attempt to be reused")
+	}
+}
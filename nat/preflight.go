@@ -0,0 +1,27 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package nat
+
+import (
+	"strings"
+
+	"github.com/ernestio/ernestaws/quota"
+)
+
+// preflight : checks the account limits before creating the nat gateway
+// and its elastic ip
+func (ev *Event) preflight() error {
+	if !quota.Enabled() || strings.Split(ev.Subject, ".")[1] != "create" {
+		return nil
+	}
+
+	svc := ev.getEC2Client()
+
+	if err := quota.ElasticIPs(svc, 1); err != nil {
+		return err
+	}
+
+	return quota.NatGateways(svc, ev.PublicNetworkAWSID)
+}
@@ -5,14 +5,15 @@
 package nat
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -50,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -108,50 +119,30 @@ func (col *Collection) Delete() error {
 
 // Find : Find nat gateways on aws
 func (col *Collection) Find() error {
-	svc := col.getEC2Client()
-
-	resp, err := svc.DescribeNatGateways(nil)
+	gateways, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	// As tags aren't supported on nat gw's, we append all items to be filtered later.
-	for _, ng := range resp.NatGateways {
-		var name string
-
-		networks, err := col.getRoutedNetworks(ng.NatGatewayId)
-		if err != nil {
-			return err
-		}
-
-		for _, network := range networks {
-			name, err = col.getGatewayName(network)
-			if err != nil {
-				return err
-			}
-
-			if name != "" {
-				break
-			}
-		}
-
-		e := toEvent(ng, name)
-		e.RoutedNetworkAWSIDs = networks
-
-		if name != "" {
-			col.Results = append(col.Results, e)
-		}
+	for _, st := range gateways {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
 func mapFilters(tags map[string]string) []*ec2.Filter {
@@ -167,103 +158,18 @@ func mapFilters(tags map[string]string) []*ec2.Filter {
 	return f
 }
 
-func getPublicAllocation(addresses []*ec2.NatGatewayAddress) (*string, *string) {
-	for _, a := range addresses {
-		if a.PublicIp != nil {
-			return a.AllocationId, a.PublicIp
-		}
-	}
-	return nil, nil
-}
-
-func (col *Collection) getRoutedNetworks(gatewayID *string) ([]*string, error) {
-	var f []*ec2.Filter
-	var ids []*string
-
-	svc := col.getEC2Client()
-
-	f = append(f, &ec2.Filter{
-		Name:   aws.String("route.nat-gateway-id"),
-		Values: []*string{gatewayID},
-	})
-
-	req := &ec2.DescribeRouteTablesInput{
-		Filters: f,
-	}
-
-	resp, err := svc.DescribeRouteTables(req)
-	if err != nil {
-		return ids, err
-	}
-
-	for _, rt := range resp.RouteTables {
-		for _, assoc := range rt.Associations {
-			if assoc.SubnetId != nil {
-				ids = append(ids, assoc.SubnetId)
-			}
-		}
-	}
-
-	return ids, err
-}
-
-func (col *Collection) getGatewayName(networkID *string) (string, error) {
-	nw, err := col.getNetwork(networkID)
-	if err != nil {
-		return "", err
-	}
-
-	tags := mapEC2Tags(nw.Tags)
-	name := tags["ernest.nat_gateway"]
-
-	return name, nil
-}
-
-func (col *Collection) getNetwork(id *string) (*ec2.Subnet, error) {
-	svc := col.getEC2Client()
-
-	req := &ec2.DescribeSubnetsInput{
-		SubnetIds: []*string{id},
-	}
-
-	resp, err := svc.DescribeSubnets(req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.Subnets) != 1 {
-		return nil, errors.New("could not find referenced nat gateway subnet")
-	}
-
-	return resp.Subnets[0], nil
-}
-
-func mapEC2Tags(input []*ec2.Tag) map[string]string {
-	t := make(map[string]string)
-
-	for _, tag := range input {
-		t[*tag.Key] = *tag.Value
-	}
-
-	return t
-}
-
-// ToEvent converts an ec2 nat gateway object to an ernest event
-func toEvent(ng *ec2.NatGateway, name string) *Event {
-	id, ip := getPublicAllocation(ng.NatGatewayAddresses)
-
-	e := &Event{
+// toEvent converts a nat gateway status to an ernest event
+func toEvent(st Status) *Event {
+	return &Event{
 		ProviderType:           "aws",
 		ComponentType:          "nat",
-		ComponentID:            "nat::" + name,
-		Name:                   aws.String(name),
-		VpcID:                  *ng.VpcId,
-		NatGatewayAWSID:        ng.NatGatewayId,
-		PublicNetworkAWSID:     ng.SubnetId,
-		NatGatewayAllocationID: id,
-		NatGatewayAllocationIP: ip,
-		//RoutedNetworksAWSIDs
-		//InternetGatewayID
+		ComponentID:            "nat::" + st.Name,
+		Name:                   aws.String(st.Name),
+		VpcID:                  st.VpcID,
+		NatGatewayAWSID:        aws.String(st.ID),
+		PublicNetworkAWSID:     aws.String(st.PublicNetworkID),
+		RoutedNetworkAWSIDs:    aws.StringSlice(st.RoutedNetworkIDs),
+		NatGatewayAllocationID: optional(st.AllocationID),
+		NatGatewayAllocationIP: optional(st.AllocationIP),
 	}
-	return e
 }
@@ -0,0 +1,35 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package nat
+
+import "github.com/ernestio/ernestaws/schema"
+
+var routed = schema.Field{Name: "routed_networks_aws_ids", Type: schema.Array, Required: true, MinItems: 1, Items: &schema.Field{Type: schema.String}}
+
+func init() {
+	schema.Register("nat", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "name", Type: schema.String},
+			{Name: "public_network_aws_id", Type: schema.String, Required: true},
+			routed,
+		}),
+		"update": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "nat_gateway_aws_id", Type: schema.String, Required: true},
+			{Name: "public_network_aws_id", Type: schema.String, Required: true},
+			routed,
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "nat_gateway_aws_id", Type: schema.String, Required: true},
+			{Name: "nat_gateway_allocation_id", Type: schema.String},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("nat", 1, schema.VpcReference)
+}
@@ -0,0 +1,917 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of a subnet
+type Spec struct {
+	Name             string
+	VpcID            string
+	CIDR             string
+	Public           bool
+	AvailabilityZone string
+	// IPv6CIDR is the /64 block of the subnet. When it is empty and
+	// AssignIPv6 is set, the first free /64 of the vpc block is used
+	IPv6CIDR   string
+	AssignIPv6 bool
+	// AssignIPv6OnCreation gives an ipv6 address to the network
+	// interfaces created on the subnet
+	AssignIPv6OnCreation bool
+	Tags                 map[string]string
+}
+
+// Status describes a subnet as it is on aws
+type Status struct {
+	ID                   string
+	Name                 string
+	VpcID                string
+	CIDR                 string
+	Public               bool
+	AvailabilityZone     string
+	IPv6CIDR             string
+	AssignIPv6OnCreation bool
+	Tags                 map[string]string
+}
+
+// Client manages subnets through a typed api, the json events are an
+// adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a subnet. Public subnets are routed through the vpc
+// internet gateway, which is created if the vpc has none. Private subnets
+// with ipv6 are routed through the vpc egress only internet gateway
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	req := ec2.CreateSubnetInput{
+		VpcId:     aws.String(s.VpcID),
+		CidrBlock: aws.String(s.CIDR),
+	}
+
+	if s.AvailabilityZone != "" {
+		req.AvailabilityZone = aws.String(s.AvailabilityZone)
+	}
+
+	ipv6, err := c.ipv6CIDR(ctx, svc, s, "")
+	if err != nil {
+		return Status{}, err
+	}
+
+	if ipv6 != "" {
+		req.Ipv6CidrBlock = aws.String(ipv6)
+	}
+
+	resp, err := svc.CreateSubnetWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if s.Public {
+		// Create Internet Gateway
+		gateway, err := c.createInternetGateway(ctx, svc, s.VpcID)
+		if err != nil {
+			return Status{}, err
+		}
+
+		// Create Route Table and direct traffic to Internet Gateway
+		rt, err := c.createRouteTable(ctx, svc, s.VpcID, *resp.Subnet.SubnetId)
+		if err != nil {
+			return Status{}, err
+		}
+
+		err = c.createGatewayRoutes(ctx, svc, rt, gateway)
+		if err != nil {
+			return Status{}, err
+		}
+
+		if ipv6 != "" {
+			err = c.setIPv6Route(ctx, svc, rt, &ec2.CreateRouteInput{GatewayId: gateway.InternetGatewayId})
+			if err != nil {
+				return Status{}, err
+			}
+		}
+
+		// Modify subnet to assign public IP's on launch
+		mod := ec2.ModifySubnetAttributeInput{
+			SubnetId:            resp.Subnet.SubnetId,
+			MapPublicIpOnLaunch: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
+		}
+
+		_, err = svc.ModifySubnetAttributeWithContext(ctx, &mod)
+		if err != nil {
+			return Status{}, err
+		}
+	} else if ipv6 != "" {
+		err = c.setPrivate(ctx, svc, s.VpcID, *resp.Subnet.SubnetId, true)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	if s.AssignIPv6OnCreation {
+		mod := ec2.ModifySubnetAttributeInput{
+			SubnetId:                    resp.Subnet.SubnetId,
+			AssignIpv6AddressOnCreation: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
+		}
+
+		_, err = svc.ModifySubnetAttributeWithContext(ctx, &mod)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	st := Status{
+		ID:                   aws.StringValue(resp.Subnet.SubnetId),
+		Name:                 s.Name,
+		VpcID:                s.VpcID,
+		CIDR:                 s.CIDR,
+		Public:               s.Public,
+		AvailabilityZone:     aws.StringValue(resp.Subnet.AvailabilityZone),
+		IPv6CIDR:             ipv6,
+		AssignIPv6OnCreation: s.AssignIPv6OnCreation,
+		Tags:                 s.Tags,
+	}
+
+	return st, c.setTags(ctx, svc, st.ID, s.Tags)
+}
+
+// Update : updates a subnet. Changing the cidr or the availability zone
+// replaces the subnet, which is only possible while nothing is using it.
+// Public subnets are routed through the vpc internet gateway, private
+// ones have the route to it removed and their route table disassociated,
+// or are routed through the egress only internet gateway with ipv6
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	n, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if s.CIDR != aws.StringValue(n.CidrBlock) || (s.AvailabilityZone != "" && s.AvailabilityZone != aws.StringValue(n.AvailabilityZone)) {
+		return c.replace(ctx, svc, id, s)
+	}
+
+	current := subnetIPv6(n)
+
+	ipv6, err := c.ipv6CIDR(ctx, svc, s, current)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if ipv6 != current {
+		err = c.setSubnetIPv6(ctx, svc, n, ipv6)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	if s.Public {
+		err = c.setPublic(ctx, svc, s.VpcID, id, ipv6 != "")
+	} else {
+		err = c.setPrivate(ctx, svc, s.VpcID, id, ipv6 != "")
+	}
+
+	if err != nil {
+		return Status{}, err
+	}
+
+	if s.AssignIPv6OnCreation != aws.BoolValue(n.AssignIpv6AddressOnCreation) {
+		mod := ec2.ModifySubnetAttributeInput{
+			SubnetId:                    aws.String(id),
+			AssignIpv6AddressOnCreation: &ec2.AttributeBooleanValue{Value: aws.Bool(s.AssignIPv6OnCreation)},
+		}
+
+		_, err = svc.ModifySubnetAttributeWithContext(ctx, &mod)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	if s.Public != aws.BoolValue(n.MapPublicIpOnLaunch) {
+		mod := ec2.ModifySubnetAttributeInput{
+			SubnetId:            aws.String(id),
+			MapPublicIpOnLaunch: &ec2.AttributeBooleanValue{Value: aws.Bool(s.Public)},
+		}
+
+		_, err = svc.ModifySubnetAttributeWithContext(ctx, &mod)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.removeTags(ctx, svc, id, n.Tags, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	n, err = c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(n)
+	st.Name = s.Name
+	st.Public = s.Public
+
+	return st, nil
+}
+
+// Delete : deletes a subnet, once the network interfaces using it are
+// gone. The route table of the subnet, like the ones routing public
+// subnets to the internet gateway or private ones to a nat gateway, is
+// removed with it unless it is the main table or other subnets use it
+func (c Client) Delete(ctx context.Context, id string) error {
+	svc := c.getEC2Client()
+
+	err := c.waitForInterfaceRemoval(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+
+	rt, err := c.routingTableBySubnetID(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+
+	req := ec2.DeleteSubnetInput{
+		SubnetId: aws.String(id),
+	}
+
+	_, err = svc.DeleteSubnetWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	if rt == nil {
+		return nil
+	}
+
+	return c.removeRouteTable(ctx, svc, aws.StringValue(rt.RouteTableId), id)
+}
+
+// Find : returns the subnets matching all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	req := &ec2.DescribeSubnetsInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := svc.DescribeSubnetsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var subnets []Status
+
+	for _, n := range resp.Subnets {
+		subnets = append(subnets, toStatus(n))
+	}
+
+	return subnets, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) internetGatewayByVPCID(ctx context.Context, svc *ec2.EC2, vpc string) (*ec2.InternetGateway, error) {
+	f := []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String("attachment.vpc-id"),
+			Values: []*string{aws.String(vpc)},
+		},
+	}
+
+	req := ec2.DescribeInternetGatewaysInput{
+		Filters: f,
+	}
+
+	resp, err := svc.DescribeInternetGatewaysWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.InternetGateways) == 0 {
+		return nil, nil
+	}
+
+	return resp.InternetGateways[0], nil
+}
+
+func (c Client) routingTableBySubnetID(ctx context.Context, svc *ec2.EC2, subnet string) (*ec2.RouteTable, error) {
+	f := []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String("association.subnet-id"),
+			Values: []*string{aws.String(subnet)},
+		},
+	}
+
+	req := ec2.DescribeRouteTablesInput{
+		Filters: f,
+	}
+
+	resp, err := svc.DescribeRouteTablesWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.RouteTables) == 0 {
+		return nil, nil
+	}
+
+	return resp.RouteTables[0], nil
+}
+
+func (c Client) createInternetGateway(ctx context.Context, svc *ec2.EC2, vpc string) (*ec2.InternetGateway, error) {
+	ig, err := c.internetGatewayByVPCID(ctx, svc, vpc)
+	if err != nil {
+		return nil, err
+	}
+
+	if ig != nil {
+		return ig, nil
+	}
+
+	resp, err := svc.CreateInternetGatewayWithContext(ctx, &ec2.CreateInternetGatewayInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	req := ec2.AttachInternetGatewayInput{
+		InternetGatewayId: resp.InternetGateway.InternetGatewayId,
+		VpcId:             aws.String(vpc),
+	}
+
+	_, err = svc.AttachInternetGatewayWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.InternetGateway, nil
+}
+
+func (c Client) createRouteTable(ctx context.Context, svc *ec2.EC2, vpc, subnet string) (*ec2.RouteTable, error) {
+	rt, err := c.routingTableBySubnetID(ctx, svc, subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	if rt != nil {
+		return rt, nil
+	}
+
+	req := ec2.CreateRouteTableInput{
+		VpcId: aws.String(vpc),
+	}
+
+	resp, err := svc.CreateRouteTableWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	acreq := ec2.AssociateRouteTableInput{
+		RouteTableId: resp.RouteTable.RouteTableId,
+		SubnetId:     aws.String(subnet),
+	}
+
+	_, err = svc.AssociateRouteTableWithContext(ctx, &acreq)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.RouteTable, nil
+}
+
+func (c Client) createGatewayRoutes(ctx context.Context, svc *ec2.EC2, rt *ec2.RouteTable, gw *ec2.InternetGateway) error {
+	req := ec2.CreateRouteInput{
+		RouteTableId:         rt.RouteTableId,
+		DestinationCidrBlock: aws.String("0.0.0.0/0"),
+		GatewayId:            gw.InternetGatewayId,
+	}
+
+	_, err := svc.CreateRouteWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// replace : deletes the subnet and creates it again with the new cidr or
+// availability zone. Subnets with network interfaces are not replaced, as
+// that would wait until whatever uses them is removed
+func (c Client) replace(ctx context.Context, svc *ec2.EC2, id string, s Spec) (Status, error) {
+	resp, err := c.getNetworkInterfaces(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if len(resp.NetworkInterfaces) > 0 {
+		return Status{}, ErrNetworkInUse
+	}
+
+	err = c.Delete(ctx, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return c.Create(ctx, s)
+}
+
+// setPublic : routes the subnet through the vpc internet gateway. Subnets
+// on a route table with a default route to anything else are moved to a
+// route table of their own, so shared tables are left untouched, and the
+// old table is removed once no other subnet uses it
+func (c Client) setPublic(ctx context.Context, svc *ec2.EC2, vpc, subnet string, ipv6 bool) error {
+	gateway, err := c.createInternetGateway(ctx, svc, vpc)
+	if err != nil {
+		return err
+	}
+
+	rt, err := c.routingTableBySubnetID(ctx, svc, subnet)
+	if err != nil {
+		return err
+	}
+
+	if rt != nil {
+		r := defaultRoute(rt)
+
+		if r != nil && aws.StringValue(r.GatewayId) != aws.StringValue(gateway.InternetGatewayId) {
+			err = c.removeRouteTable(ctx, svc, aws.StringValue(rt.RouteTableId), subnet)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	rt, err = c.createRouteTable(ctx, svc, vpc, subnet)
+	if err != nil {
+		return err
+	}
+
+	if defaultRoute(rt) == nil {
+		err = c.createGatewayRoutes(ctx, svc, rt, gateway)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !ipv6 {
+		return nil
+	}
+
+	return c.setIPv6Route(ctx, svc, rt, &ec2.CreateRouteInput{GatewayId: gateway.InternetGatewayId})
+}
+
+// setPrivate : removes the route to the internet gateway from the subnet
+// route table and disassociates it. The route is kept on tables shared
+// with other subnets, and tables left without subnets are removed. Ipv6
+// traffic is routed through the egress only internet gateway
+func (c Client) setPrivate(ctx context.Context, svc *ec2.EC2, vpc, subnet string, ipv6 bool) error {
+	rt, err := c.routingTableBySubnetID(ctx, svc, subnet)
+	if err != nil {
+		return err
+	}
+
+	if r := defaultRoute(rt); r != nil && strings.HasPrefix(aws.StringValue(r.GatewayId), "igw-") {
+		if len(rt.Associations) == 1 {
+			req := ec2.DeleteRouteInput{
+				RouteTableId:         rt.RouteTableId,
+				DestinationCidrBlock: r.DestinationCidrBlock,
+			}
+
+			_, err = svc.DeleteRouteWithContext(ctx, &req)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = c.removeRouteTable(ctx, svc, aws.StringValue(rt.RouteTableId), subnet)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !ipv6 {
+		return nil
+	}
+
+	gateway, err := c.createEgressOnlyInternetGateway(ctx, svc, vpc)
+	if err != nil {
+		return err
+	}
+
+	rt, err = c.createRouteTable(ctx, svc, vpc, subnet)
+	if err != nil {
+		return err
+	}
+
+	return c.setIPv6Route(ctx, svc, rt, &ec2.CreateRouteInput{EgressOnlyInternetGatewayId: gateway.EgressOnlyInternetGatewayId})
+}
+
+// removeRouteTable : disassociates the subnet from a route table, and
+// deletes the table if it is not the main one and no other subnet uses
+// it. The routes on it, to internet or nat gateways, go with it
+func (c Client) removeRouteTable(ctx context.Context, svc *ec2.EC2, id, subnet string) error {
+	req := ec2.DescribeRouteTablesInput{
+		RouteTableIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeRouteTablesWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.RouteTables) == 0 {
+		return nil
+	}
+
+	rt := resp.RouteTables[0]
+
+	err = c.disassociateRouteTable(ctx, svc, rt, subnet)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range rt.Associations {
+		if aws.BoolValue(a.Main) || aws.StringValue(a.SubnetId) != subnet {
+			return nil
+		}
+	}
+
+	dreq := ec2.DeleteRouteTableInput{
+		RouteTableId: rt.RouteTableId,
+	}
+
+	_, err = svc.DeleteRouteTableWithContext(ctx, &dreq)
+
+	return err
+}
+
+// setIPv6Route : points the ipv6 default route of the route table to the
+// gateway set on the route input, replacing any previous one
+func (c Client) setIPv6Route(ctx context.Context, svc *ec2.EC2, rt *ec2.RouteTable, route *ec2.CreateRouteInput) error {
+	if r := defaultIPv6Route(rt); r != nil {
+		if aws.StringValue(r.GatewayId) == aws.StringValue(route.GatewayId) &&
+			aws.StringValue(r.EgressOnlyInternetGatewayId) == aws.StringValue(route.EgressOnlyInternetGatewayId) {
+			return nil
+		}
+
+		req := ec2.DeleteRouteInput{
+			RouteTableId:             rt.RouteTableId,
+			DestinationIpv6CidrBlock: r.DestinationIpv6CidrBlock,
+		}
+
+		_, err := svc.DeleteRouteWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+	}
+
+	route.RouteTableId = rt.RouteTableId
+	route.DestinationIpv6CidrBlock = aws.String("::/0")
+
+	_, err := svc.CreateRouteWithContext(ctx, route)
+
+	return err
+}
+
+func (c Client) createEgressOnlyInternetGateway(ctx context.Context, svc *ec2.EC2, vpc string) (*ec2.EgressOnlyInternetGateway, error) {
+	resp, err := svc.DescribeEgressOnlyInternetGatewaysWithContext(ctx, &ec2.DescribeEgressOnlyInternetGatewaysInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gw := range resp.EgressOnlyInternetGateways {
+		for _, a := range gw.Attachments {
+			if aws.StringValue(a.VpcId) == vpc {
+				return gw, nil
+			}
+		}
+	}
+
+	req := ec2.CreateEgressOnlyInternetGatewayInput{
+		VpcId: aws.String(vpc),
+	}
+
+	cresp, err := svc.CreateEgressOnlyInternetGatewayWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return cresp.EgressOnlyInternetGateway, nil
+}
+
+// ipv6CIDR : returns the ipv6 block the subnet should have. Subnets that
+// already have one keep it when it is derived from the vpc block
+func (c Client) ipv6CIDR(ctx context.Context, svc *ec2.EC2, s Spec, current string) (string, error) {
+	if s.IPv6CIDR != "" || !s.AssignIPv6 {
+		return s.IPv6CIDR, nil
+	}
+
+	if current != "" {
+		return current, nil
+	}
+
+	req := ec2.DescribeVpcsInput{
+		VpcIds: []*string{aws.String(s.VpcID)},
+	}
+
+	resp, err := svc.DescribeVpcsWithContext(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+
+	var block string
+
+	for _, v := range resp.Vpcs {
+		for _, a := range v.Ipv6CidrBlockAssociationSet {
+			if aws.StringValue(a.Ipv6CidrBlockState.State) == ec2.VpcCidrBlockStateCodeAssociated {
+				block = aws.StringValue(a.Ipv6CidrBlock)
+			}
+		}
+	}
+
+	if block == "" {
+		return "", ErrVpcIPv6Invalid
+	}
+
+	sreq := ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(s.VpcID)},
+			},
+		},
+	}
+
+	sresp, err := svc.DescribeSubnetsWithContext(ctx, &sreq)
+	if err != nil {
+		return "", err
+	}
+
+	used := make(map[string]bool)
+	for _, n := range sresp.Subnets {
+		used[subnetIPv6(n)] = true
+	}
+
+	_, network, err := net.ParseCIDR(block)
+	if err != nil {
+		return "", err
+	}
+
+	for i := uint64(0); ; i++ {
+		cidr, err := subnetIPv6Block(network, i)
+		if err != nil {
+			return "", err
+		}
+
+		if !used[cidr] {
+			return cidr, nil
+		}
+	}
+}
+
+// subnetIPv6Block : returns the i-th /64 block of the vpc block. The
+// index takes the bits between the vpc prefix and the /64 boundary, so
+// it must be lower than 2^(64-prefix)
+func subnetIPv6Block(block *net.IPNet, i uint64) (string, error) {
+	ones, _ := block.Mask.Size()
+	if ones < 1 || ones > 64 || i >= 1<<uint(64-ones) {
+		return "", ErrVpcIPv6Exhausted
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, block.IP.To16())
+
+	binary.BigEndian.PutUint64(ip[:8], binary.BigEndian.Uint64(ip[:8])|i)
+
+	return (&net.IPNet{IP: ip, Mask: net.CIDRMask(64, 128)}).String(), nil
+}
+
+// setSubnetIPv6 : replaces the ipv6 block of the subnet, removing it when
+// the new one is empty
+func (c Client) setSubnetIPv6(ctx context.Context, svc *ec2.EC2, n *ec2.Subnet, cidr string) error {
+	for _, a := range n.Ipv6CidrBlockAssociationSet {
+		if aws.StringValue(a.Ipv6CidrBlockState.State) != ec2.SubnetCidrBlockStateCodeAssociated {
+			continue
+		}
+
+		req := ec2.DisassociateSubnetCidrBlockInput{
+			AssociationId: a.AssociationId,
+		}
+
+		_, err := svc.DisassociateSubnetCidrBlockWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cidr == "" {
+		return nil
+	}
+
+	req := ec2.AssociateSubnetCidrBlockInput{
+		SubnetId:      n.SubnetId,
+		Ipv6CidrBlock: aws.String(cidr),
+	}
+
+	_, err := svc.AssociateSubnetCidrBlockWithContext(ctx, &req)
+
+	return err
+}
+
+func (c Client) disassociateRouteTable(ctx context.Context, svc *ec2.EC2, rt *ec2.RouteTable, subnet string) error {
+	for _, a := range rt.Associations {
+		if aws.StringValue(a.SubnetId) != subnet {
+			continue
+		}
+
+		req := ec2.DisassociateRouteTableInput{
+			AssociationId: a.RouteTableAssociationId,
+		}
+
+		_, err := svc.DisassociateRouteTableWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c Client) describe(ctx context.Context, svc *ec2.EC2, id string) (*ec2.Subnet, error) {
+	req := ec2.DescribeSubnetsInput{
+		SubnetIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeSubnetsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Subnets) == 0 {
+		return nil, ErrNetworkNotFound
+	}
+
+	return resp.Subnets[0], nil
+}
+
+func (c Client) waitForInterfaceRemoval(ctx context.Context, svc *ec2.EC2, networkID string) error {
+	for {
+		resp, err := c.getNetworkInterfaces(ctx, svc, networkID)
+		if err != nil {
+			return err
+		}
+
+		if len(resp.NetworkInterfaces) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c Client) getNetworkInterfaces(ctx context.Context, svc *ec2.EC2, networkID string) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	f := []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String("subnet-id"),
+			Values: []*string{aws.String(networkID)},
+		},
+	}
+
+	req := ec2.DescribeNetworkInterfacesInput{
+		Filters: f,
+	}
+
+	return svc.DescribeNetworkInterfacesWithContext(ctx, &req)
+}
+
+// removeTags : deletes the tags of the subnet that are no longer on the
+// spec. Tags reserved by aws can't be removed, so they are kept
+func (c Client) removeTags(ctx context.Context, svc *ec2.EC2, id string, current []*ec2.Tag, tags map[string]string) error {
+	var removed []*ec2.Tag
+
+	for _, t := range current {
+		key := aws.StringValue(t.Key)
+		if _, ok := tags[key]; ok || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+
+		removed = append(removed, &ec2.Tag{Key: t.Key})
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	req := ec2.DeleteTagsInput{
+		Resources: []*string{aws.String(id)},
+		Tags:      removed,
+	}
+
+	_, err := svc.DeleteTagsWithContext(ctx, &req)
+
+	return err
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// defaultRoute : returns the ipv4 default route of a route table
+func defaultRoute(rt *ec2.RouteTable) *ec2.Route {
+	if rt == nil {
+		return nil
+	}
+
+	for _, r := range rt.Routes {
+		if aws.StringValue(r.DestinationCidrBlock) == "0.0.0.0/0" {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// defaultIPv6Route : returns the ipv6 default route of a route table
+func defaultIPv6Route(rt *ec2.RouteTable) *ec2.Route {
+	for _, r := range rt.Routes {
+		if aws.StringValue(r.DestinationIpv6CidrBlock) == "::/0" {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// subnetIPv6 : returns the associated ipv6 block of a subnet
+func subnetIPv6(n *ec2.Subnet) string {
+	for _, a := range n.Ipv6CidrBlockAssociationSet {
+		if aws.StringValue(a.Ipv6CidrBlockState.State) == ec2.SubnetCidrBlockStateCodeAssociated {
+			return aws.StringValue(a.Ipv6CidrBlock)
+		}
+	}
+
+	return ""
+}
+
+func toStatus(n *ec2.Subnet) Status {
+	tags := mapEC2Tags(n.Tags)
+
+	return Status{
+		ID:                   aws.StringValue(n.SubnetId),
+		Name:                 tags["Name"],
+		VpcID:                aws.StringValue(n.VpcId),
+		CIDR:                 aws.StringValue(n.CidrBlock),
+		Public:               aws.BoolValue(n.MapPublicIpOnLaunch),
+		AvailabilityZone:     aws.StringValue(n.AvailabilityZone),
+		IPv6CIDR:             subnetIPv6(n),
+		AssignIPv6OnCreation: aws.BoolValue(n.AssignIpv6AddressOnCreation),
+		Tags:                 tags,
+	}
+}
@@ -5,17 +5,17 @@
 package network
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -29,6 +29,14 @@ var (
 	ErrNetworkSubnetInvalid = errors.New("Network subnet invalid")
 	// ErrNetworkAWSIDInvalid ...
 	ErrNetworkAWSIDInvalid = errors.New("Network aws id invalid")
+	// ErrNetworkNotFound ...
+	ErrNetworkNotFound = errors.New("Network not found")
+	// ErrVpcIPv6Invalid ...
+	ErrVpcIPv6Invalid = errors.New("Network ipv6 subnet can't be assigned, the vpc has no ipv6 block")
+	// ErrVpcIPv6Exhausted ...
+	ErrVpcIPv6Exhausted = errors.New("Network ipv6 subnet can't be assigned, the vpc ipv6 block is full")
+	// ErrNetworkInUse ...
+	ErrNetworkInUse = errors.New("Network can't be replaced to change its range or availability zone while it has network interfaces")
 )
 
 // Event stores the network data
@@ -38,6 +46,7 @@ type Event struct {
 	ComponentID          string            `json:"_component_id"`
 	State                string            `json:"_state"`
 	Action               string            `json:"_action"`
+	SchemaVersion        int               `json:"_schema_version"`
 	NetworkAWSID         *string           `json:"network_aws_id"`
 	Name                 *string           `json:"name"`
 	Subnet               *string           `json:"range"`
@@ -45,6 +54,9 @@ type Event struct {
 	InternetGateway      string            `json:"internet_gateway"`
 	InternetGatewayAWSID string            `json:"internet_gateway_aws_id"`
 	AvailabilityZone     *string           `json:"availability_zone"`
+	IPv6Subnet           *string           `json:"ipv6_cidr"`
+	AssignIPv6Subnet     bool              `json:"assign_ipv6_subnet"`
+	AssignIPv6OnCreation bool              `json:"assign_ipv6_address_on_creation"`
 	Tags                 map[string]string `json:"tags"`
 	DatacenterType       string            `json:"datacenter_type"`
 	DatacenterName       string            `json:"datacenter_name"`
@@ -71,6 +83,10 @@ func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.VpcID == "" {
 		return ErrDatacenterIDInvalid
 	}
@@ -83,14 +99,12 @@ func (ev *Event) Validate() error {
 		return ErrDatacenterCredentialsInvalid
 	}
 
-	if ev.Subject == "network.delete.aws" {
-		if ev.NetworkAWSID == nil {
-			return ErrNetworkAWSIDInvalid
-		}
-	} else {
-		if ev.Subnet == nil {
-			return ErrNetworkSubnetInvalid
-		}
+	if ev.Subject != "network.create.aws" && ev.NetworkAWSID == nil {
+		return ErrNetworkAWSIDInvalid
+	}
+
+	if ev.Subject != "network.delete.aws" && ev.Subnet == nil {
+		return ErrNetworkSubnetInvalid
 	}
 
 	return nil
@@ -98,6 +112,11 @@ func (ev *Event) Validate() error {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -108,6 +127,11 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.verify(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -132,76 +156,31 @@ func (ev *Event) Find() error {
 
 // Create : Creates a nat object on aws
 func (ev *Event) Create() error {
-	svc := ev.getEC2Client()
-
-	req := ec2.CreateSubnetInput{
-		VpcId:            aws.String(ev.VpcID),
-		CidrBlock:        ev.Subnet,
-		AvailabilityZone: ev.AvailabilityZone,
-	}
-
-	resp, err := svc.CreateSubnet(&req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	if *ev.IsPublic {
-		// Create Internet Gateway
-		gateway, err := ev.createInternetGateway(svc, ev.VpcID)
-		if err != nil {
-			return err
-		}
-
-		// Create Route Table and direct traffic to Internet Gateway
-		rt, err := ev.createRouteTable(svc, ev.VpcID, *resp.Subnet.SubnetId)
-		if err != nil {
-			return err
-		}
-
-		err = ev.createGatewayRoutes(svc, rt, gateway)
-		if err != nil {
-			return err
-		}
-
-		// Modify subnet to assign public IP's on launch
-		mod := ec2.ModifySubnetAttributeInput{
-			SubnetId:            resp.Subnet.SubnetId,
-			MapPublicIpOnLaunch: &ec2.AttributeBooleanValue{Value: aws.Bool(true)},
-		}
-
-		_, err = svc.ModifySubnetAttribute(&mod)
-		if err != nil {
-			return err
-		}
-	}
+	ev.setStatus(st)
 
-	ev.NetworkAWSID = resp.Subnet.SubnetId
-	ev.AvailabilityZone = resp.Subnet.AvailabilityZone
-
-	return ev.setTags()
+	return nil
 }
 
 // Update : Updates a nat object on aws
 func (ev *Event) Update() error {
-	return errors.New(ev.Subject + " not supported")
-}
-
-// Delete : Deletes a nat object on aws
-func (ev *Event) Delete() error {
-	svc := ev.getEC2Client()
-
-	err := ev.waitForInterfaceRemoval(svc, ev.NetworkAWSID)
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.NetworkAWSID), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	req := ec2.DeleteSubnetInput{
-		SubnetId: ev.NetworkAWSID,
-	}
+	ev.setStatus(st)
 
-	_, err = svc.DeleteSubnet(&req)
+	return nil
+}
 
-	return err
+// Delete : Deletes a nat object on aws
+func (ev *Event) Delete() error {
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.NetworkAWSID))
 }
 
 // Get : Gets a nat object on aws
@@ -224,184 +203,45 @@ func (ev *Event) GetSubject() string {
 }
 
 func (ev *Event) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
+	return ev.client().getEC2Client()
 }
 
-func (ev *Event) internetGatewayByVPCID(svc *ec2.EC2, vpc string) (*ec2.InternetGateway, error) {
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("attachment.vpc-id"),
-			Values: []*string{aws.String(vpc)},
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
 		},
-	}
-
-	req := ec2.DescribeInternetGatewaysInput{
-		Filters: f,
-	}
-
-	resp, err := svc.DescribeInternetGateways(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.InternetGateways) == 0 {
-		return nil, nil
-	}
-
-	return resp.InternetGateways[0], nil
-}
-
-func (ev *Event) routingTableBySubnetID(svc *ec2.EC2, subnet string) (*ec2.RouteTable, error) {
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("association.subnet-id"),
-			Values: []*string{aws.String(subnet)},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
 		},
 	}
-
-	req := ec2.DescribeRouteTablesInput{
-		Filters: f,
-	}
-
-	resp, err := svc.DescribeRouteTables(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.RouteTables) == 0 {
-		return nil, nil
-	}
-
-	return resp.RouteTables[0], nil
 }
 
-func (ev *Event) createInternetGateway(svc *ec2.EC2, vpc string) (*ec2.InternetGateway, error) {
-	ig, err := ev.internetGatewayByVPCID(svc, vpc)
-	if err != nil {
-		return nil, err
-	}
-
-	if ig != nil {
-		return ig, nil
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:                 aws.StringValue(ev.Name),
+		VpcID:                ev.VpcID,
+		CIDR:                 aws.StringValue(ev.Subnet),
+		Public:               aws.BoolValue(ev.IsPublic),
+		AvailabilityZone:     aws.StringValue(ev.AvailabilityZone),
+		IPv6CIDR:             aws.StringValue(ev.IPv6Subnet),
+		AssignIPv6:           ev.AssignIPv6Subnet,
+		AssignIPv6OnCreation: ev.AssignIPv6OnCreation,
+		Tags:                 ev.Tags,
 	}
-
-	resp, err := svc.CreateInternetGateway(nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req := ec2.AttachInternetGatewayInput{
-		InternetGatewayId: resp.InternetGateway.InternetGatewayId,
-		VpcId:             aws.String(vpc),
-	}
-
-	_, err = svc.AttachInternetGateway(&req)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp.InternetGateway, nil
 }
 
-func (ev *Event) createRouteTable(svc *ec2.EC2, vpc, subnet string) (*ec2.RouteTable, error) {
-	rt, err := ev.routingTableBySubnetID(svc, subnet)
-	if err != nil {
-		return nil, err
-	}
-
-	if rt != nil {
-		return rt, nil
-	}
-
-	req := ec2.CreateRouteTableInput{
-		VpcId: aws.String(vpc),
-	}
+// setStatus : maps back the ids and the values aws assigned
+func (ev *Event) setStatus(st Status) {
+	ev.NetworkAWSID = aws.String(st.ID)
+	ev.AvailabilityZone = aws.String(st.AvailabilityZone)
 
-	resp, err := svc.CreateRouteTable(&req)
-	if err != nil {
-		return nil, err
+	if st.IPv6CIDR != "" {
+		ev.IPv6Subnet = aws.String(st.IPv6CIDR)
 	}
-
-	acreq := ec2.AssociateRouteTableInput{
-		RouteTableId: resp.RouteTable.RouteTableId,
-		SubnetId:     aws.String(subnet),
-	}
-
-	_, err = svc.AssociateRouteTable(&acreq)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp.RouteTable, nil
-}
-
-func (ev *Event) createGatewayRoutes(svc *ec2.EC2, rt *ec2.RouteTable, gw *ec2.InternetGateway) error {
-	req := ec2.CreateRouteInput{
-		RouteTableId:         rt.RouteTableId,
-		DestinationCidrBlock: aws.String("0.0.0.0/0"),
-		GatewayId:            gw.InternetGatewayId,
-	}
-
-	_, err := svc.CreateRoute(&req)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (ev *Event) waitForInterfaceRemoval(svc *ec2.EC2, networkID *string) error {
-	for {
-		resp, err := ev.getNetworkInterfaces(svc, networkID)
-		if err != nil {
-			return err
-		}
-
-		if len(resp.NetworkInterfaces) == 0 {
-			return nil
-		}
-
-		time.Sleep(time.Second)
-	}
-}
-
-func (ev *Event) getNetworkInterfaces(svc *ec2.EC2, networkID *string) (*ec2.DescribeNetworkInterfacesOutput, error) {
-	f := []*ec2.Filter{
-		&ec2.Filter{
-			Name:   aws.String("subnet-id"),
-			Values: []*string{networkID},
-		},
-	}
-
-	req := ec2.DescribeNetworkInterfacesInput{
-		Filters: f,
-	}
-
-	return svc.DescribeNetworkInterfaces(&req)
-}
-
-func (ev *Event) setTags() error {
-	svc := ev.getEC2Client()
-
-	for key, val := range ev.Tags {
-		req := &ec2.CreateTagsInput{
-			Resources: []*string{ev.NetworkAWSID},
-		}
-
-		req.Tags = append(req.Tags, &ec2.Tag{
-			Key:   &key,
-			Value: &val,
-		})
-
-		_, err := svc.CreateTags(req)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
 }
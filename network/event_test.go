@@ -0,0 +1,330 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package network
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create a public network",
+			subject:  "network.create.aws",
+			body:     `{"vpc_id":"$vpc","name":"web","range":"10.0.1.0/24","is_public":true,"tags":{"Name":"web"}}`,
+			expected: "network.create.aws.done",
+			save:     map[string]string{"id": "network_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				return aws.BoolValue(b.EC2.Subnets[ids["id"]].MapPublicIpOnLaunch) && len(b.EC2.InternetGateways) == 1
+			},
+		},
+		{
+			name:     "update makes the network private",
+			subject:  "network.update.aws",
+			body:     `{"vpc_id":"$vpc","network_aws_id":"$id","name":"web","range":"10.0.1.0/24","is_public":false,"tags":{"Name":"web"}}`,
+			expected: "network.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return res["network_aws_id"] == ids["id"] && !aws.BoolValue(b.EC2.Subnets[ids["id"]].MapPublicIpOnLaunch)
+			},
+		},
+		{
+			name:     "update replaces the network when the range changes",
+			subject:  "network.update.aws",
+			body:     `{"vpc_id":"$vpc","network_aws_id":"$id","name":"web","range":"10.0.2.0/24","is_public":false,"tags":{"Name":"web"}}`,
+			expected: "network.update.aws.done",
+			save:     map[string]string{"id": "network_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				n, ok := b.EC2.Subnets[ids["id"]]
+				return ok && len(b.EC2.Subnets) == 1 && aws.StringValue(n.CidrBlock) == "10.0.2.0/24"
+			},
+		},
+		{
+			name:     "find",
+			subject:  "network.find.aws",
+			body:     `{"tags":{"Name":"web"}}`,
+			expected: "network.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				return len(found) == 1 && found[0].(map[string]interface{})["network_aws_id"] == ids["id"]
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "network.delete.aws",
+			body:     `{"vpc_id":"$vpc","network_aws_id":"$id"}`,
+			expected: "network.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.EC2.Subnets) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails creating the subnet",
+			operation: "CreateSubnet",
+			subject:   "network.create.aws",
+			body:      `{"vpc_id":"$vpc","range":"10.0.1.0/24","is_public":false}`,
+		},
+		{
+			name:      "create fails routing the public subnet",
+			operation: "CreateRoute",
+			subject:   "network.create.aws",
+			body:      `{"vpc_id":"$vpc","range":"10.0.1.0/24","is_public":true}`,
+		},
+		{
+			name:      "delete fails deleting the subnet",
+			operation: "DeleteSubnet",
+			subject:   "network.delete.aws",
+			body:      `{"vpc_id":"$vpc","network_aws_id":"$subnet"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			vpc := createVpc(t, b)
+
+			var out ec2.CreateSubnetOutput
+			if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(vpc), CidrBlock: aws.String("10.0.9.0/24")}, &out); err != nil {
+				t.Fatal(err)
+			}
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, map[string]string{"vpc": vpc, "subnet": *out.Subnet.SubnetId})
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestDeleteRemovesRouteTable(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	_, res := awsfake.Run(t, New, "network.create.aws", `{"vpc_id":"$vpc","name":"web","range":"10.0.1.0/24","is_public":true}`, ids)
+	ids["id"], _ = res["network_aws_id"].(string)
+
+	table := routeTable(b, ids["id"])
+	if table == "" {
+		t.Fatalf("expected the network to have a route table")
+	}
+
+	subject, res := awsfake.Run(t, New, "network.delete.aws", `{"vpc_id":"$vpc","network_aws_id":"$id"}`, ids)
+	if subject != "network.delete.aws.done" {
+		t.Fatalf("expected network.delete.aws.done, got %s: %v", subject, res["error"])
+	}
+
+	if _, ok := b.EC2.RouteTables[table]; ok {
+		t.Errorf("expected the route table of the network to be removed")
+	}
+}
+
+// routeTable : returns the id of the route table associated with the
+// subnet
+func routeTable(b *awsfake.Backend, subnet string) string {
+	for id, rt := range b.EC2.RouteTables {
+		for _, a := range rt.Associations {
+			if aws.StringValue(a.SubnetId) == subnet {
+				return id
+			}
+		}
+	}
+
+	return ""
+}
+
+func TestUpdateRemovesTags(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	_, res := awsfake.Run(t, New, "network.create.aws", `{"vpc_id":"$vpc","name":"web","range":"10.0.1.0/24","is_public":false,"tags":{"Name":"web","Team":"ops"}}`, ids)
+	ids["id"], _ = res["network_aws_id"].(string)
+
+	subject, res := awsfake.Run(t, New, "network.update.aws", `{"vpc_id":"$vpc","network_aws_id":"$id","name":"web","range":"10.0.1.0/24","is_public":false,"tags":{"Name":"web"}}`, ids)
+	if subject != "network.update.aws.done" {
+		t.Fatalf("expected network.update.aws.done, got %s: %v", subject, res["error"])
+	}
+
+	tags := b.EC2.Subnets[ids["id"]].Tags
+	if len(tags) != 1 || aws.StringValue(tags[0].Key) != "Name" {
+		t.Errorf("expected only the Name tag to be kept, got %v", tags)
+	}
+}
+
+func TestCreateAssignsIPv6(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	var out ec2.CreateVpcOutput
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16"), AmazonProvidedIpv6CidrBlock: aws.Bool(true)}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	_, vpcBlock, err := net.ParseCIDR(aws.StringValue(out.Vpc.Ipv6CidrBlockAssociationSet[0].Ipv6CidrBlock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]string{"vpc": *out.Vpc.VpcId}
+	used := map[string]bool{}
+
+	for i, name := range []string{"web", "db"} {
+		body := `{"vpc_id":"$vpc","name":"` + name + `","range":"10.0.` + strconv.Itoa(i+1) + `.0/24","is_public":false,"assign_ipv6_subnet":true}`
+
+		subject, res := awsfake.Run(t, New, "network.create.aws", body, ids)
+		if subject != "network.create.aws.done" {
+			t.Fatalf("%s: expected network.create.aws.done, got %s: %v", name, subject, res["error"])
+		}
+
+		cidr, _ := res["ipv6_cidr"].(string)
+
+		ip, block, err := net.ParseCIDR(cidr)
+		if err != nil || !vpcBlock.Contains(ip) || used[cidr] {
+			t.Fatalf("%s: expected a free block of %s, got %q", name, vpcBlock, cidr)
+		}
+
+		if ones, _ := block.Mask.Size(); ones != 64 {
+			t.Errorf("%s: expected a /64, got %s", name, cidr)
+		}
+
+		used[cidr] = true
+	}
+}
+
+func TestUpdatePublicRemovesRouteTable(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	_, res := awsfake.Run(t, New, "network.create.aws", `{"vpc_id":"$vpc","name":"db","range":"10.0.1.0/24","is_public":false}`, ids)
+	ids["id"], _ = res["network_aws_id"].(string)
+
+	var rt ec2.CreateRouteTableOutput
+	if err := b.EC2.CreateRouteTable(&ec2.CreateRouteTableInput{VpcId: aws.String(ids["vpc"])}, &rt); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.EC2.CreateRoute(&ec2.CreateRouteInput{RouteTableId: rt.RouteTable.RouteTableId, DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-0a1b2c3d")}, &ec2.CreateRouteOutput{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.EC2.AssociateRouteTable(&ec2.AssociateRouteTableInput{RouteTableId: rt.RouteTable.RouteTableId, SubnetId: aws.String(ids["id"])}, &ec2.AssociateRouteTableOutput{}); err != nil {
+		t.Fatal(err)
+	}
+
+	subject, res := awsfake.Run(t, New, "network.update.aws", `{"vpc_id":"$vpc","network_aws_id":"$id","name":"db","range":"10.0.1.0/24","is_public":true}`, ids)
+	if subject != "network.update.aws.done" {
+		t.Fatalf("expected network.update.aws.done, got %s: %v", subject, res["error"])
+	}
+
+	if _, ok := b.EC2.RouteTables[*rt.RouteTable.RouteTableId]; ok {
+		t.Errorf("expected the nat routed table to be removed")
+	}
+}
+
+func TestSubnetIPv6Block(t *testing.T) {
+	tests := []struct {
+		block    string
+		index    uint64
+		expected string
+	}{
+		{"2600:1f18:1234:5600::/56", 0, "2600:1f18:1234:5600::/64"},
+		{"2600:1f18:1234:5600::/56", 255, "2600:1f18:1234:56ff::/64"},
+		{"2600:1f18:1234::/48", 0x0102, "2600:1f18:1234:102::/64"},
+		{"2600:1f18:1230::/44", 0xabcde, "2600:1f18:123a:bcde::/64"},
+		{"2600:1f18:1234:5600::/56", 256, ""},
+		{"2600:1f18:1234::/48", 1 << 16, ""},
+	}
+
+	for _, tt := range tests {
+		_, block, err := net.ParseCIDR(tt.block)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cidr, err := subnetIPv6Block(block, tt.index)
+		if tt.expected == "" {
+			if err != ErrVpcIPv6Exhausted {
+				t.Errorf("%s: expected index %d to be rejected, got %s", tt.block, tt.index, cidr)
+			}
+			continue
+		}
+
+		if err != nil || cidr != tt.expected {
+			t.Errorf("%s: expected block %d to be %s, got %s (%v)", tt.block, tt.index, tt.expected, cidr, err)
+		}
+	}
+}
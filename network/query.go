@@ -5,14 +5,15 @@
 package network
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -50,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -108,30 +119,30 @@ func (col *Collection) Delete() error {
 
 // Find : Find networks on aws
 func (col *Collection) Find() error {
-	svc := col.getEC2Client()
-
-	req := &ec2.DescribeSubnetsInput{
-		Filters: mapFilters(col.Tags),
-	}
-
-	resp, err := svc.DescribeSubnets(req)
+	subnets, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, n := range resp.Subnets {
-		col.Results = append(col.Results, toEvent(n))
+	for _, st := range subnets {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
 func mapFilters(tags map[string]string) []*ec2.Filter {
@@ -157,21 +168,25 @@ func mapEC2Tags(input []*ec2.Tag) map[string]string {
 	return t
 }
 
-// ToEvent converts an ec2 subnet object to an ernest event
-func toEvent(n *ec2.Subnet) *Event {
-	tags := mapEC2Tags(n.Tags)
-	name := tags["Name"]
-
-	return &Event{
-		ProviderType:     "aws",
-		ComponentType:    "network",
-		ComponentID:      "network::" + name,
-		VpcID:            *n.VpcId,
-		NetworkAWSID:     n.SubnetId,
-		Name:             aws.String(name),
-		Subnet:           n.CidrBlock,
-		AvailabilityZone: n.AvailabilityZone,
-		IsPublic:         n.MapPublicIpOnLaunch,
-		Tags:             mapEC2Tags(n.Tags),
+// ToEvent converts a subnet status to an ernest event
+func toEvent(st Status) *Event {
+	e := &Event{
+		ProviderType:         "aws",
+		ComponentType:        "network",
+		ComponentID:          "network::" + st.Name,
+		VpcID:                st.VpcID,
+		NetworkAWSID:         aws.String(st.ID),
+		Name:                 aws.String(st.Name),
+		Subnet:               aws.String(st.CIDR),
+		AvailabilityZone:     aws.String(st.AvailabilityZone),
+		IsPublic:             aws.Bool(st.Public),
+		AssignIPv6OnCreation: st.AssignIPv6OnCreation,
+		Tags:                 st.Tags,
 	}
+
+	if st.IPv6CIDR != "" {
+		e.IPv6Subnet = aws.String(st.IPv6CIDR)
+	}
+
+	return e
 }
@@ -0,0 +1,37 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package network
+
+import "github.com/ernestio/ernestaws/schema"
+
+var attributes = []schema.Field{
+	{Name: "vpc_id", Type: schema.String, Required: true},
+	{Name: "name", Type: schema.String},
+	{Name: "range", Type: schema.String, Required: true, Format: schema.CIDR},
+	{Name: "is_public", Type: schema.Boolean, Required: true},
+	{Name: "availability_zone", Type: schema.String},
+	{Name: "ipv6_cidr", Type: schema.String, Format: schema.CIDR},
+	{Name: "assign_ipv6_subnet", Type: schema.Boolean},
+	{Name: "assign_ipv6_address_on_creation", Type: schema.Boolean},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("network", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), attributes),
+		"update": schema.Fields(schema.Datacenter(), attributes, []schema.Field{
+			{Name: "network_aws_id", Type: schema.String, Required: true},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "network_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("network", 1, schema.VpcReference)
+}
@@ -0,0 +1,32 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package network
+
+import (
+	"strings"
+
+	"github.com/ernestio/ernestaws/verify"
+)
+
+// verify : checks the event against the live aws metadata
+func (ev *Event) verify() error {
+	action := strings.Split(ev.Subject, ".")[1]
+
+	if !verify.Enabled() || (action != "create" && action != "update") {
+		return nil
+	}
+
+	svc := ev.getEC2Client()
+
+	var c verify.Check
+	c.AvailabilityZone(svc, "$.availability_zone", ev.AvailabilityZone)
+
+	// on updates the range overlaps the subnet being updated
+	if action == "create" {
+		c.SubnetCIDR(svc, "$.range", ev.VpcID, ev.Subnet)
+	}
+
+	return c.Err()
+}
@@ -0,0 +1,554 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package networkacl
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// lastRuleNumber is the highest rule number that can be set, the rules
+// after it are the default deny entries aws adds to every acl
+const lastRuleNumber = 32766
+
+// protocols maps the protocol names of the entries to the numbers aws uses
+var protocols = map[string]string{
+	"-1":   "-1",
+	"icmp": "1",
+	"tcp":  "6",
+	"udp":  "17",
+}
+
+// Entry describes a rule of a network acl. A zero rule number numbers
+// the entry by its position, the ports are only used by tcp and udp
+type Entry struct {
+	RuleNumber int64
+	IP         string
+	Protocol   string
+	FromPort   *int64
+	ToPort     *int64
+	Action     string
+}
+
+// Spec describes the desired state of a network acl
+type Spec struct {
+	Name       string
+	VpcID      string
+	NetworkIDs []string
+	Ingress    []Entry
+	Egress     []Entry
+	Tags       map[string]string
+}
+
+// Status describes a network acl as it is on aws, without the default
+// deny entries
+type Status struct {
+	ID         string
+	Name       string
+	VpcID      string
+	NetworkIDs []string
+	Ingress    []Entry
+	Egress     []Entry
+	Tags       map[string]string
+}
+
+// Client manages network acls through a typed api, the json events are
+// an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a network acl with its entries, and associates it
+// with the networks
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	req := ec2.CreateNetworkAclInput{
+		VpcId: aws.String(s.VpcID),
+	}
+
+	resp, err := svc.CreateNetworkAclWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	id := aws.StringValue(resp.NetworkAcl.NetworkAclId)
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	for _, e := range s.entries() {
+		err = c.createEntry(ctx, svc, id, e)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.associate(ctx, svc, id, s.NetworkIDs)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return c.status(ctx, svc, id)
+}
+
+// Update : updates a network acl. Only the entries that changed are
+// replaced, entries that are no longer defined are removed, and networks
+// no longer listed are moved back to the default acl of the vpc
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	acl, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	current := customEntries(acl.Entries)
+	entries := s.entries()
+
+	// Delete removed entries
+	for _, e := range current {
+		if findEntry(entries, e) == nil {
+			req := ec2.DeleteNetworkAclEntryInput{
+				NetworkAclId: aws.String(id),
+				RuleNumber:   e.RuleNumber,
+				Egress:       e.Egress,
+			}
+
+			_, err = svc.DeleteNetworkAclEntryWithContext(ctx, &req)
+			if err != nil {
+				return Status{}, err
+			}
+		}
+	}
+
+	// Create new entries and replace the changed ones
+	for _, e := range entries {
+		cur := findEntry(current, e)
+
+		switch {
+		case cur == nil:
+			err = c.createEntry(ctx, svc, id, e)
+		case !equalEntries(cur, e):
+			err = c.replaceEntry(ctx, svc, id, e)
+		}
+
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	// Move removed networks to the default acl
+	var removed []string
+	for _, a := range acl.Associations {
+		if !contains(s.NetworkIDs, aws.StringValue(a.SubnetId)) {
+			removed = append(removed, aws.StringValue(a.SubnetId))
+		}
+	}
+
+	err = c.disassociate(ctx, svc, s.VpcID, removed)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.associate(ctx, svc, id, s.NetworkIDs)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return c.status(ctx, svc, id)
+}
+
+// Delete : deletes a network acl, the networks still associated with it
+// are moved back to the default acl of the vpc
+func (c Client) Delete(ctx context.Context, id, vpcID string) error {
+	svc := c.getEC2Client()
+
+	acl, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+
+	var subnets []string
+	for _, a := range acl.Associations {
+		subnets = append(subnets, aws.StringValue(a.SubnetId))
+	}
+
+	err = c.disassociate(ctx, svc, vpcID, subnets)
+	if err != nil {
+		return err
+	}
+
+	req := ec2.DeleteNetworkAclInput{
+		NetworkAclId: aws.String(id),
+	}
+
+	_, err = svc.DeleteNetworkAclWithContext(ctx, &req)
+
+	return err
+}
+
+// Find : returns the network acls matching all the tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	req := &ec2.DescribeNetworkAclsInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := c.getEC2Client().DescribeNetworkAclsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var acls []Status
+
+	for _, acl := range resp.NetworkAcls {
+		acls = append(acls, toStatus(acl))
+	}
+
+	return acls, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) status(ctx context.Context, svc *ec2.EC2, id string) (Status, error) {
+	acl, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return toStatus(acl), nil
+}
+
+// entries : returns the ingress and egress entries of the spec
+func (s Spec) entries() []*ec2.NetworkAclEntry {
+	return append(buildEntries(s.Ingress, false), buildEntries(s.Egress, true)...)
+}
+
+func (c Client) createEntry(ctx context.Context, svc *ec2.EC2, id string, e *ec2.NetworkAclEntry) error {
+	req := ec2.CreateNetworkAclEntryInput{
+		NetworkAclId:  aws.String(id),
+		RuleNumber:    e.RuleNumber,
+		Egress:        e.Egress,
+		Protocol:      e.Protocol,
+		RuleAction:    e.RuleAction,
+		CidrBlock:     e.CidrBlock,
+		Ipv6CidrBlock: e.Ipv6CidrBlock,
+		PortRange:     e.PortRange,
+		IcmpTypeCode:  e.IcmpTypeCode,
+	}
+
+	_, err := svc.CreateNetworkAclEntryWithContext(ctx, &req)
+
+	return err
+}
+
+func (c Client) replaceEntry(ctx context.Context, svc *ec2.EC2, id string, e *ec2.NetworkAclEntry) error {
+	req := ec2.ReplaceNetworkAclEntryInput{
+		NetworkAclId:  aws.String(id),
+		RuleNumber:    e.RuleNumber,
+		Egress:        e.Egress,
+		Protocol:      e.Protocol,
+		RuleAction:    e.RuleAction,
+		CidrBlock:     e.CidrBlock,
+		Ipv6CidrBlock: e.Ipv6CidrBlock,
+		PortRange:     e.PortRange,
+		IcmpTypeCode:  e.IcmpTypeCode,
+	}
+
+	_, err := svc.ReplaceNetworkAclEntryWithContext(ctx, &req)
+
+	return err
+}
+
+// associate : moves the networks to this acl. Networks are always
+// associated with one acl, so their current association is replaced
+func (c Client) associate(ctx context.Context, svc *ec2.EC2, id string, subnets []string) error {
+	return c.replaceAssociations(ctx, svc, subnets, id)
+}
+
+// disassociate : moves the networks back to the default acl of the vpc
+func (c Client) disassociate(ctx context.Context, svc *ec2.EC2, vpc string, subnets []string) error {
+	if len(subnets) == 0 {
+		return nil
+	}
+
+	acl, err := c.defaultNetworkACL(ctx, svc, vpc)
+	if err != nil {
+		return err
+	}
+
+	return c.replaceAssociations(ctx, svc, subnets, aws.StringValue(acl.NetworkAclId))
+}
+
+func (c Client) replaceAssociations(ctx context.Context, svc *ec2.EC2, subnets []string, id string) error {
+	if len(subnets) == 0 {
+		return nil
+	}
+
+	req := ec2.DescribeNetworkAclsInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("association.subnet-id"),
+				Values: aws.StringSlice(subnets),
+			},
+		},
+	}
+
+	resp, err := svc.DescribeNetworkAclsWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	for _, acl := range resp.NetworkAcls {
+		if aws.StringValue(acl.NetworkAclId) == id {
+			continue
+		}
+
+		for _, a := range acl.Associations {
+			if !contains(subnets, aws.StringValue(a.SubnetId)) {
+				continue
+			}
+
+			req := ec2.ReplaceNetworkAclAssociationInput{
+				AssociationId: a.NetworkAclAssociationId,
+				NetworkAclId:  aws.String(id),
+			}
+
+			_, err = svc.ReplaceNetworkAclAssociationWithContext(ctx, &req)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c Client) describe(ctx context.Context, svc *ec2.EC2, id string) (*ec2.NetworkAcl, error) {
+	req := ec2.DescribeNetworkAclsInput{
+		NetworkAclIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeNetworkAclsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.NetworkAcls) == 0 {
+		return nil, ErrNetworkACLNotFound
+	}
+
+	return resp.NetworkAcls[0], nil
+}
+
+func (c Client) defaultNetworkACL(ctx context.Context, svc *ec2.EC2, vpc string) (*ec2.NetworkAcl, error) {
+	req := ec2.DescribeNetworkAclsInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpc)},
+			},
+			&ec2.Filter{
+				Name:   aws.String("default"),
+				Values: []*string{aws.String("true")},
+			},
+		},
+	}
+
+	resp, err := svc.DescribeNetworkAclsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.NetworkAcls) == 0 {
+		return nil, ErrDefaultNetworkACLNotFound
+	}
+
+	return resp.NetworkAcls[0], nil
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildEntries : converts the spec entries to acl entries. Entries
+// without a rule number are numbered by their position, in steps of 100
+func buildEntries(entries []Entry, egress bool) []*ec2.NetworkAclEntry {
+	var built []*ec2.NetworkAclEntry
+
+	for i, e := range entries {
+		en := &ec2.NetworkAclEntry{
+			RuleNumber: aws.Int64(e.RuleNumber),
+			Egress:     aws.Bool(egress),
+			Protocol:   aws.String(protocols[e.Protocol]),
+			RuleAction: aws.String(e.Action),
+		}
+
+		if e.RuleNumber == 0 {
+			en.RuleNumber = aws.Int64(int64(i+1) * 100)
+		}
+
+		if strings.Contains(e.IP, ":") {
+			en.Ipv6CidrBlock = aws.String(e.IP)
+		} else {
+			en.CidrBlock = aws.String(e.IP)
+		}
+
+		switch e.Protocol {
+		case "tcp", "udp":
+			en.PortRange = &ec2.PortRange{From: e.FromPort, To: e.ToPort}
+		case "icmp":
+			en.IcmpTypeCode = &ec2.IcmpTypeCode{Type: aws.Int64(-1), Code: aws.Int64(-1)}
+		}
+
+		built = append(built, en)
+	}
+
+	return built
+}
+
+// customEntries : returns the entries that can be managed, without the
+// default deny entries, ordered by rule number
+func customEntries(entries []*ec2.NetworkAclEntry) []*ec2.NetworkAclEntry {
+	var custom []*ec2.NetworkAclEntry
+
+	for _, e := range entries {
+		if aws.Int64Value(e.RuleNumber) <= lastRuleNumber {
+			custom = append(custom, e)
+		}
+	}
+
+	sort.Slice(custom, func(i, j int) bool {
+		return aws.Int64Value(custom[i].RuleNumber) < aws.Int64Value(custom[j].RuleNumber)
+	})
+
+	return custom
+}
+
+// findEntry : returns the entry with the same direction and rule number
+func findEntry(entries []*ec2.NetworkAclEntry, e *ec2.NetworkAclEntry) *ec2.NetworkAclEntry {
+	for _, c := range entries {
+		if aws.BoolValue(c.Egress) == aws.BoolValue(e.Egress) && aws.Int64Value(c.RuleNumber) == aws.Int64Value(e.RuleNumber) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func equalEntries(a, b *ec2.NetworkAclEntry) bool {
+	if aws.StringValue(a.Protocol) != aws.StringValue(b.Protocol) ||
+		aws.StringValue(a.RuleAction) != aws.StringValue(b.RuleAction) ||
+		aws.StringValue(a.CidrBlock) != aws.StringValue(b.CidrBlock) ||
+		aws.StringValue(a.Ipv6CidrBlock) != aws.StringValue(b.Ipv6CidrBlock) {
+		return false
+	}
+
+	if (a.PortRange == nil) != (b.PortRange == nil) {
+		return false
+	}
+
+	if a.PortRange != nil {
+		return aws.Int64Value(a.PortRange.From) == aws.Int64Value(b.PortRange.From) &&
+			aws.Int64Value(a.PortRange.To) == aws.Int64Value(b.PortRange.To)
+	}
+
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toStatus(acl *ec2.NetworkAcl) Status {
+	tags := mapEC2Tags(acl.Tags)
+
+	st := Status{
+		ID:    aws.StringValue(acl.NetworkAclId),
+		Name:  tags["Name"],
+		VpcID: aws.StringValue(acl.VpcId),
+		Tags:  tags,
+	}
+
+	for _, a := range acl.Associations {
+		st.NetworkIDs = append(st.NetworkIDs, aws.StringValue(a.SubnetId))
+	}
+
+	for _, en := range customEntries(acl.Entries) {
+		if aws.BoolValue(en.Egress) {
+			st.Egress = append(st.Egress, toEntry(en))
+		} else {
+			st.Ingress = append(st.Ingress, toEntry(en))
+		}
+	}
+
+	return st
+}
+
+// toEntry : converts an acl entry back to an entry, protocols without a
+// name are kept as their number
+func toEntry(en *ec2.NetworkAclEntry) Entry {
+	e := Entry{
+		RuleNumber: aws.Int64Value(en.RuleNumber),
+		IP:         aws.StringValue(en.CidrBlock),
+		Protocol:   aws.StringValue(en.Protocol),
+		Action:     aws.StringValue(en.RuleAction),
+	}
+
+	if en.Ipv6CidrBlock != nil {
+		e.IP = *en.Ipv6CidrBlock
+	}
+
+	for name, number := range protocols {
+		if number == aws.StringValue(en.Protocol) {
+			e.Protocol = name
+		}
+	}
+
+	if en.PortRange != nil {
+		e.FromPort = en.PortRange.From
+		e.ToPort = en.PortRange.To
+	}
+
+	return e
+}
@@ -0,0 +1,260 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package networkacl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
+)
+
+var (
+	// ErrDatacenterIDInvalid ...
+	ErrDatacenterIDInvalid = errors.New("Datacenter VPC ID invalid")
+	// ErrDatacenterRegionInvalid ...
+	ErrDatacenterRegionInvalid = errors.New("Datacenter Region invalid")
+	// ErrDatacenterCredentialsInvalid ...
+	ErrDatacenterCredentialsInvalid = errors.New("Datacenter credentials invalid")
+	// ErrNetworkACLAWSIDInvalid ...
+	ErrNetworkACLAWSIDInvalid = errors.New("Network ACL aws id invalid")
+	// ErrEntryRuleNumberInvalid ...
+	ErrEntryRuleNumberInvalid = errors.New("Network ACL entry rule numbers must be unique on ingress and egress entries")
+	// ErrEntryPortsInvalid ...
+	ErrEntryPortsInvalid = errors.New("Network ACL tcp and udp entries must set from port and to port")
+	// ErrNetworkACLNotFound ...
+	ErrNetworkACLNotFound = errors.New("Network ACL not found")
+	// ErrDefaultNetworkACLNotFound ...
+	ErrDefaultNetworkACLNotFound = errors.New("Default network ACL of the vpc not found")
+)
+
+type entry struct {
+	RuleNumber *int64  `json:"rule_number"`
+	IP         *string `json:"ip"`
+	Protocol   *string `json:"protocol"`
+	FromPort   *int64  `json:"from_port"`
+	ToPort     *int64  `json:"to_port"`
+	Action     *string `json:"action"`
+}
+
+// Event stores the network acl data
+type Event struct {
+	ProviderType    string   `json:"_provider"`
+	ComponentType   string   `json:"_component"`
+	ComponentID     string   `json:"_component_id"`
+	State           string   `json:"_state"`
+	Action          string   `json:"_action"`
+	SchemaVersion   int      `json:"_schema_version"`
+	NetworkACLAWSID *string  `json:"network_acl_aws_id"`
+	Name            *string  `json:"name"`
+	NetworkAWSIDs   []string `json:"network_aws_ids"`
+	Entries         struct {
+		Ingress []entry `json:"ingress"`
+		Egress  []entry `json:"egress"`
+	} `json:"entries"`
+	Tags             map[string]string `json:"tags"`
+	DatacenterType   string            `json:"datacenter_type"`
+	DatacenterName   string            `json:"datacenter_name"`
+	DatacenterRegion string            `json:"datacenter_region"`
+	AccessKeyID      string            `json:"aws_access_key_id"`
+	SecretAccessKey  string            `json:"aws_secret_access_key"`
+	Vpc              string            `json:"vpc"`
+	VpcID            string            `json:"vpc_id"`
+	Service          string            `json:"service"`
+	ErrorMessage     string            `json:"error,omitempty"`
+	Subject          string            `json:"-"`
+	Body             []byte            `json:"-"`
+	CryptoKey        string            `json:"-"`
+}
+
+// New : Constructor
+func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
+	if strings.Split(subject, ".")[1] == "find" {
+		return &Collection{Subject: subject, Body: body, CryptoKey: cryptoKey}
+	}
+
+	return &Event{Subject: subject, Body: body, CryptoKey: cryptoKey}
+}
+
+// Validate checks if all criteria are met
+func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
+	if ev.VpcID == "" {
+		return ErrDatacenterIDInvalid
+	}
+
+	if ev.DatacenterRegion == "" {
+		return ErrDatacenterRegionInvalid
+	}
+
+	if ev.AccessKeyID == "" || ev.SecretAccessKey == "" {
+		return ErrDatacenterCredentialsInvalid
+	}
+
+	if ev.Subject != "network_acl.create.aws" && ev.NetworkACLAWSID == nil {
+		return ErrNetworkACLAWSIDInvalid
+	}
+
+	if ev.Subject == "network_acl.delete.aws" {
+		return nil
+	}
+
+	s := ev.spec()
+
+	for _, entries := range [][]Entry{s.Ingress, s.Egress} {
+		numbers := make(map[int64]bool)
+
+		for _, e := range buildEntries(entries, false) {
+			if numbers[*e.RuleNumber] {
+				return ErrEntryRuleNumberInvalid
+			}
+			numbers[*e.RuleNumber] = true
+
+			if e.PortRange != nil && (e.PortRange.From == nil || e.PortRange.To == nil) {
+				return ErrEntryPortsInvalid
+			}
+		}
+	}
+
+	return nil
+}
+
+// Process : starts processing the current message
+func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := json.Unmarshal(ev.Body, &ev); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := ev.Validate(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Error : Will respond the current event with an error
+func (ev *Event) Error(err error) {
+	log.Printf("Error: %s", err.Error())
+	ev.ErrorMessage = err.Error()
+	ev.State = "errored"
+
+	ev.Body, err = json.Marshal(ev)
+}
+
+// Complete : sets the state of the event to completed
+func (ev *Event) Complete() {
+	ev.State = "completed"
+}
+
+// Find : Find an object on aws
+func (ev *Event) Find() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// Create : Creates a network acl on aws with its entries, and associates
+// it with the networks
+func (ev *Event) Create() error {
+	st, err := ev.client().Create(context.Background(), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.NetworkACLAWSID = aws.String(st.ID)
+
+	return nil
+}
+
+// Update : Updates a network acl on aws. Only the entries that changed
+// are replaced, entries that are no longer defined are removed, and
+// networks no longer listed are moved back to the default acl of the vpc
+func (ev *Event) Update() error {
+	_, err := ev.client().Update(context.Background(), aws.StringValue(ev.NetworkACLAWSID), ev.spec())
+	return err
+}
+
+// Delete : Deletes a network acl on aws, the networks still associated
+// with it are moved back to the default acl of the vpc
+func (ev *Event) Delete() error {
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.NetworkACLAWSID), ev.VpcID)
+}
+
+// Get : Gets a object on aws
+func (ev *Event) Get() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// GetBody : Gets the body for this event
+func (ev *Event) GetBody() []byte {
+	var err error
+	if ev.Body, err = json.Marshal(ev); err != nil {
+		log.Println(err.Error())
+	}
+	return ev.Body
+}
+
+// GetSubject : Gets the subject for this event
+func (ev *Event) GetSubject() string {
+	return ev.Subject
+}
+
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+}
+
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:       aws.StringValue(ev.Name),
+		VpcID:      ev.VpcID,
+		NetworkIDs: ev.NetworkAWSIDs,
+		Ingress:    toEntries(ev.Entries.Ingress),
+		Egress:     toEntries(ev.Entries.Egress),
+		Tags:       ev.Tags,
+	}
+}
+
+func toEntries(entries []entry) []Entry {
+	var e []Entry
+
+	for _, en := range entries {
+		e = append(e, Entry{
+			RuleNumber: aws.Int64Value(en.RuleNumber),
+			IP:         aws.StringValue(en.IP),
+			Protocol:   aws.StringValue(en.Protocol),
+			FromPort:   en.FromPort,
+			ToPort:     en.ToPort,
+			Action:     aws.StringValue(en.Action),
+		})
+	}
+
+	return e
+}
@@ -0,0 +1,213 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package networkacl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+func createSubnet(t *testing.T, b *awsfake.Backend, vpc, cidr string) string {
+	var out ec2.CreateSubnetOutput
+
+	if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(vpc), CidrBlock: aws.String(cidr)}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Subnet.SubnetId
+}
+
+// setup : creates a vpc with two networks
+func setup(t *testing.T, b *awsfake.Backend) map[string]string {
+	vpc := createVpc(t, b)
+
+	return map[string]string{
+		"vpc": vpc,
+		"web": createSubnet(t, b, vpc, "10.0.1.0/24"),
+		"db":  createSubnet(t, b, vpc, "10.0.2.0/24"),
+	}
+}
+
+// entries : returns the entries set on the acl, by direction and
+// rule number
+func entries(b *awsfake.Backend, id string) map[string]*ec2.NetworkAclEntry {
+	e := make(map[string]*ec2.NetworkAclEntry)
+
+	for _, en := range b.EC2.NetworkAcls[id].Entries {
+		if *en.RuleNumber <= lastRuleNumber {
+			e[fmt.Sprintf("%t/%d", *en.Egress, *en.RuleNumber)] = en
+		}
+	}
+
+	return e
+}
+
+// associated : returns the acl a network is associated with
+func associated(b *awsfake.Backend, subnet string) string {
+	for id, acl := range b.EC2.NetworkAcls {
+		for _, a := range acl.Associations {
+			if *a.SubnetId == subnet {
+				return id
+			}
+		}
+	}
+
+	return ""
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "network_acl.create.aws",
+			body:     `{"vpc_id":"$vpc","name":"acl","network_aws_ids":["$web","$db"],"entries":{"ingress":[{"ip":"0.0.0.0/0","protocol":"tcp","from_port":80,"to_port":80,"action":"allow"},{"rule_number":150,"ip":"::/0","protocol":"icmp","action":"deny"}],"egress":[{"ip":"0.0.0.0/0","protocol":"-1","action":"allow"}]},"tags":{"Name":"acl"}}`,
+			expected: "network_acl.create.aws.done",
+			save:     map[string]string{"id": "network_acl_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				e := entries(b, ids["id"])
+				return len(e) == 3 && *e["false/100"].Protocol == "6" && *e["false/100"].PortRange.From == 80 &&
+					*e["false/150"].Ipv6CidrBlock == "::/0" && *e["true/100"].Protocol == "-1" &&
+					associated(b, ids["web"]) == ids["id"] && associated(b, ids["db"]) == ids["id"]
+			},
+		},
+		{
+			name:     "update replaces changed entries and releases dropped networks",
+			subject:  "network_acl.update.aws",
+			body:     `{"vpc_id":"$vpc","network_acl_aws_id":"$id","network_aws_ids":["$web"],"entries":{"ingress":[{"ip":"0.0.0.0/0","protocol":"tcp","from_port":443,"to_port":443,"action":"allow"}],"egress":[{"ip":"0.0.0.0/0","protocol":"-1","action":"allow"}]}}`,
+			expected: "network_acl.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				e := entries(b, ids["id"])
+				return len(e) == 2 && *e["false/100"].PortRange.From == 443 && e["false/150"] == nil &&
+					associated(b, ids["web"]) == ids["id"] && associated(b, ids["db"]) != ids["id"] && associated(b, ids["db"]) != ""
+			},
+		},
+		{
+			name:     "find",
+			subject:  "network_acl.find.aws",
+			body:     `{"tags":{"Name":"acl"}}`,
+			expected: "network_acl.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				acl := found[0].(map[string]interface{})
+				e := acl["entries"].(map[string]interface{})
+				ingress := e["ingress"].([]interface{})
+				return acl["network_acl_aws_id"] == ids["id"] && len(ingress) == 1 && ingress[0].(map[string]interface{})["protocol"] == "tcp"
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "network_acl.delete.aws",
+			body:     `{"vpc_id":"$vpc","network_acl_aws_id":"$id"}`,
+			expected: "network_acl.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return b.EC2.NetworkAcls[ids["id"]] == nil && associated(b, ids["web"]) != ""
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails adding an entry",
+			operation: "CreateNetworkAclEntry",
+			subject:   "network_acl.create.aws",
+			body:      `{"vpc_id":"$vpc","entries":{"ingress":[{"ip":"0.0.0.0/0","protocol":"-1","action":"allow"}]}}`,
+		},
+		{
+			name:      "update fails replacing an entry",
+			operation: "ReplaceNetworkAclEntry",
+			subject:   "network_acl.update.aws",
+			body:      `{"vpc_id":"$vpc","network_acl_aws_id":"$id","entries":{"ingress":[{"ip":"0.0.0.0/0","protocol":"-1","action":"deny"}]}}`,
+		},
+		{
+			name:      "update fails associating a network",
+			operation: "ReplaceNetworkAclAssociation",
+			subject:   "network_acl.update.aws",
+			body:      `{"vpc_id":"$vpc","network_acl_aws_id":"$id","network_aws_ids":["$web"]}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteNetworkAcl",
+			subject:   "network_acl.delete.aws",
+			body:      `{"vpc_id":"$vpc","network_acl_aws_id":"$id"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := setup(t, b)
+
+			_, res := awsfake.Run(t, New, "network_acl.create.aws", `{"vpc_id":"$vpc","entries":{"ingress":[{"ip":"0.0.0.0/0","protocol":"-1","action":"allow"}]}}`, ids)
+			ids["id"], _ = res["network_acl_aws_id"].(string)
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package networkacl
+
+import "github.com/ernestio/ernestaws/schema"
+
+var port = schema.Field{Type: schema.Integer, Minimum: schema.Int(0), Maximum: schema.Int(65535)}
+
+var entryset = &schema.Field{
+	Type: schema.Object,
+	Fields: []schema.Field{
+		{Name: "rule_number", Type: schema.Integer, Minimum: schema.Int(1), Maximum: schema.Int(32766)},
+		{Name: "ip", Type: schema.String, Required: true, Format: schema.CIDR},
+		{Name: "protocol", Type: schema.String, Required: true, Enum: []string{"-1", "tcp", "udp", "icmp"}},
+		withName(port, "from_port"),
+		withName(port, "to_port"),
+		{Name: "action", Type: schema.String, Required: true, Enum: []string{"allow", "deny"}},
+	},
+}
+
+var acl = []schema.Field{
+	{Name: "vpc_id", Type: schema.String, Required: true},
+	{Name: "network_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "entries", Type: schema.Object, Fields: []schema.Field{
+		{Name: "ingress", Type: schema.Array, Items: entryset},
+		{Name: "egress", Type: schema.Array, Items: entryset},
+	}},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("network_acl", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), acl, []schema.Field{
+			{Name: "name", Type: schema.String},
+		}),
+		"update": schema.Fields(schema.Datacenter(), acl, []schema.Field{
+			{Name: "network_acl_aws_id", Type: schema.String, Required: true},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "network_acl_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("network_acl", 1, schema.VpcReference)
+}
+
+func withName(f schema.Field, name string) schema.Field {
+	f.Name = name
+	return f
+}
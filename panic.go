@@ -0,0 +1,104 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ernestaws
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Panic stores the details of a panic recovered while handling an event
+type Panic struct {
+	ID      string
+	Subject string
+	Value   interface{}
+	Stack   []byte
+}
+
+var (
+	mu           sync.RWMutex
+	panicHandler func(*Panic)
+)
+
+// OnPanic : registers a function called with every panic recovered by
+// Handle, so the host process can report them to its crash tracker
+func OnPanic(fn func(*Panic)) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	panicHandler = fn
+}
+
+// recovered : builds the error response for an event that panicked
+func recovered(ev Event, r interface{}) (string, []byte) {
+	p := &Panic{
+		ID:      stackID(),
+		Subject: ev.GetSubject(),
+		Value:   r,
+		Stack:   debug.Stack(),
+	}
+
+	log.Printf("Panic: %v (%s)\n%s", r, p.ID, p.Stack)
+
+	mu.RLock()
+	fn := panicHandler
+	mu.RUnlock()
+
+	if fn != nil {
+		fn(p)
+	}
+
+	msg := "Internal error"
+	if rerr, ok := r.(runtime.Error); ok {
+		msg = msg + ": " + rerr.Error()
+	}
+	msg = fmt.Sprintf("%s (ref %s)", msg, p.ID)
+
+	return p.Subject + ".error", errorBody(ev, errors.New(msg))
+}
+
+// errorBody : sets the error on the event, falling back to a minimal
+// body when the event can't be marshalled anymore
+func errorBody(ev Event, err error) (body []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			body, _ = json.Marshal(map[string]string{
+				"_state": "errored",
+				"error":  err.Error(),
+			})
+		}
+	}()
+
+	ev.Error(err)
+
+	return ev.GetBody()
+}
+
+// stackID : identifies the code path that panicked by hashing the
+// function names and lines of the stack, so the same crash gets the
+// same id across processes
+func stackID() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	h := sha1.New()
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(h, "%s:%d\n", f.Function, f.Line)
+		if !more {
+			break
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
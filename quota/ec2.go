@@ -0,0 +1,209 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package quota
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// VPCs : checks there is room for more vpcs on the region
+func VPCs(svc *ec2.EC2, requested int64) error {
+	resp, err := svc.DescribeVpcs(&ec2.DescribeVpcsInput{})
+	if err != nil {
+		return err
+	}
+
+	return check("vpcs", Defaults.VPCs, int64(len(resp.Vpcs)), requested)
+}
+
+// ElasticIPs : checks there is room for more vpc elastic ips on the region
+func ElasticIPs(svc *ec2.EC2, requested int64) error {
+	limit, ok, err := attribute(svc, "vpc-max-elastic-ips")
+	if err != nil || !ok {
+		return err
+	}
+
+	req := ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("domain"),
+				Values: []*string{aws.String("vpc")},
+			},
+		},
+	}
+
+	resp, err := svc.DescribeAddresses(&req)
+	if err != nil {
+		return err
+	}
+
+	return check("elastic ips", limit, int64(len(resp.Addresses)), requested)
+}
+
+// Instances : checks there is room for more on-demand instances on the region
+func Instances(svc *ec2.EC2, requested int64) error {
+	limit, ok, err := attribute(svc, "max-instances")
+	if err != nil || !ok {
+		return err
+	}
+
+	req := ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: []*string{aws.String("pending"), aws.String("running")},
+			},
+		},
+	}
+
+	var used int64
+
+	err = svc.DescribeInstancesPages(&req, func(page *ec2.DescribeInstancesOutput, last bool) bool {
+		for _, r := range page.Reservations {
+			used += int64(len(r.Instances))
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return check("instances", limit, used, requested)
+}
+
+// NatGateways : checks there is room for another nat gateway on the
+// availability zone of the subnet
+func NatGateways(svc *ec2.EC2, subnetID *string) error {
+	zone, err := availabilityZone(svc, subnetID)
+	if err != nil || zone == nil {
+		return err
+	}
+
+	req := ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{
+			{
+				Name:   aws.String("state"),
+				Values: []*string{aws.String("pending"), aws.String("available")},
+			},
+		},
+	}
+
+	var subnets []*string
+
+	err = svc.DescribeNatGatewaysPages(&req, func(page *ec2.DescribeNatGatewaysOutput, last bool) bool {
+		for _, gw := range page.NatGateways {
+			subnets = append(subnets, gw.SubnetId)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	zones, err := availabilityZones(svc, subnets)
+	if err != nil {
+		return err
+	}
+
+	var used int64
+
+	for _, id := range subnets {
+		if zones[aws.StringValue(id)] == aws.StringValue(zone) {
+			used++
+		}
+	}
+
+	return check("nat gateways on "+aws.StringValue(zone), Defaults.NatGatewaysPerZone, used, 1)
+}
+
+// Rules : checks the rules fit on a single security group. The rules
+// replace the existing ones, so they are checked against an empty group
+func Rules(ingress, egress int64) error {
+	if err := check("ingress rules per security group", Defaults.RulesPerSecurityGroup, 0, ingress); err != nil {
+		return err
+	}
+
+	return check("egress rules per security group", Defaults.RulesPerSecurityGroup, 0, egress)
+}
+
+// attribute : reads a numeric account attribute, ok is false if aws
+// doesn't report it
+func attribute(svc *ec2.EC2, name string) (limit int64, ok bool, err error) {
+	req := ec2.DescribeAccountAttributesInput{
+		AttributeNames: []*string{aws.String(name)},
+	}
+
+	resp, err := svc.DescribeAccountAttributes(&req)
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, a := range resp.AccountAttributes {
+		for _, v := range a.AttributeValues {
+			limit, err = strconv.ParseInt(aws.StringValue(v.AttributeValue), 10, 64)
+			return limit, err == nil, err
+		}
+	}
+
+	return 0, false, nil
+}
+
+func availabilityZone(svc *ec2.EC2, subnetID *string) (*string, error) {
+	req := ec2.DescribeSubnetsInput{
+		SubnetIds: []*string{subnetID},
+	}
+
+	resp, err := svc.DescribeSubnets(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Subnets) < 1 {
+		return nil, nil
+	}
+
+	return resp.Subnets[0].AvailabilityZone, nil
+}
+
+// availabilityZones : returns the availability zone of each subnet,
+// described on a single call
+func availabilityZones(svc *ec2.EC2, subnetIDs []*string) (map[string]string, error) {
+	zones := make(map[string]string)
+
+	var ids []*string
+	for _, id := range subnetIDs {
+		if _, ok := zones[aws.StringValue(id)]; !ok {
+			zones[aws.StringValue(id)] = ""
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return zones, nil
+	}
+
+	req := ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("subnet-id"),
+				Values: ids,
+			},
+		},
+	}
+
+	resp, err := svc.DescribeSubnets(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range resp.Subnets {
+		zones[aws.StringValue(s.SubnetId)] = aws.StringValue(s.AvailabilityZone)
+	}
+
+	return zones, nil
+}
@@ -0,0 +1,51 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package quota
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+)
+
+// LoadBalancer : checks there is room for another classic load balancer
+// with the given number of listeners
+func LoadBalancer(svc *elb.ELB, listeners int64) error {
+	resp, err := svc.DescribeAccountLimits(&elb.DescribeAccountLimitsInput{})
+	if err != nil {
+		return err
+	}
+
+	limits := make(map[string]int64)
+	for _, l := range resp.Limits {
+		if v, err := strconv.ParseInt(aws.StringValue(l.Max), 10, 64); err == nil {
+			limits[aws.StringValue(l.Name)] = v
+		}
+	}
+
+	if limit, ok := limits["classic-listeners"]; ok {
+		if err = check("listeners per load balancer", limit, 0, listeners); err != nil {
+			return err
+		}
+	}
+
+	limit, ok := limits["classic-load-balancers"]
+	if !ok {
+		return nil
+	}
+
+	var used int64
+
+	err = svc.DescribeLoadBalancersPages(&elb.DescribeLoadBalancersInput{}, func(page *elb.DescribeLoadBalancersOutput, last bool) bool {
+		used += int64(len(page.LoadBalancerDescriptions))
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return check("load balancers", limit, used, 1)
+}
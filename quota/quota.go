@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package quota
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Limits are the account limits aws doesn't expose through an api
+type Limits struct {
+	VPCs                  int64
+	NatGatewaysPerZone    int64
+	RulesPerSecurityGroup int64
+}
+
+// Defaults are the aws default limits, override them for accounts
+// with raised limits
+var Defaults = Limits{
+	VPCs:                  5,
+	NatGatewaysPerZone:    5,
+	RulesPerSecurityGroup: 60,
+}
+
+var (
+	mu      sync.RWMutex
+	enabled bool
+)
+
+// Enable : turns the pre-flight checks on or off, they are off by default
+func Enable(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	enabled = on
+}
+
+// Enabled : checks if the components should run the pre-flight checks
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return enabled
+}
+
+// Error is returned when an operation would go over an account limit
+type Error struct {
+	Quota     string
+	Limit     int64
+	Used      int64
+	Requested int64
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Quota exceeded: %s limit is %d, %d in use and %d requested", e.Quota, e.Limit, e.Used, e.Requested)
+}
+
+// check : fails if the requested amount doesn't fit in the limit
+func check(quota string, limit, used, requested int64) error {
+	if used+requested <= limit {
+		return nil
+	}
+
+	return &Error{Quota: quota, Limit: limit, Used: used, Requested: requested}
+}
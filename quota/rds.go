@@ -0,0 +1,26 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package quota
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// DBInstances : checks there is room for more rds instances on the region
+func DBInstances(svc *rds.RDS, requested int64) error {
+	resp, err := svc.DescribeAccountAttributes(&rds.DescribeAccountAttributesInput{})
+	if err != nil {
+		return err
+	}
+
+	for _, q := range resp.AccountQuotas {
+		if aws.StringValue(q.AccountQuotaName) == "DBInstances" {
+			return check("db instances", aws.Int64Value(q.Max), aws.Int64Value(q.Used), requested)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,484 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdscluster
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/journal"
+)
+
+// Spec describes the desired state of a database cluster. Empty strings
+// and zero values are left to the aws defaults
+type Spec struct {
+	Name              string
+	Engine            string
+	EngineVersion     string
+	Port              int64
+	AvailabilityZones []string
+	SecurityGroupIDs  []string
+	NetworkIDs        []string
+	DatabaseName      string
+	DatabaseUsername  string
+	DatabasePassword  string
+	BackupRetention   int64
+	BackupWindow      string
+	MaintenanceWindow string
+	ReplicationSource string
+	Tags              map[string]string
+}
+
+// Status describes a database cluster as it is on aws
+type Status struct {
+	ARN               string
+	Name              string
+	Engine            string
+	EngineVersion     string
+	Port              int64
+	Endpoint          string
+	AvailabilityZones []string
+	SecurityGroupIDs  []string
+	NetworkIDs        []string
+	DatabaseName      string
+	DatabaseUsername  string
+	BackupRetention   int64
+	BackupWindow      string
+	MaintenanceWindow string
+	ReplicationSource string
+	Tags              map[string]string
+}
+
+// Client manages database clusters through a typed api, the json events
+// are an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit.
+	// Creates are journaled under it, so a retried create resumes
+	Scope client.Scope
+}
+
+// Create : creates a database cluster and its subnet group. The steps are
+// journaled, so a retry of a failed create resumes where it stopped
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getRDSClient()
+
+	body, err := json.Marshal(s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	j, err := journal.Open(c.Scope.Subject, c.Scope.ComponentID, body)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var subnetGroup, arn, endpoint *string
+
+	err = j.Step("subnet_group", func() error {
+		var err error
+		subnetGroup, err = c.createSubnetGroup(ctx, svc, s)
+		return err
+	}, func() (bool, error) {
+		return c.subnetGroupExists(ctx, svc, subnetGroup)
+	}, &subnetGroup)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = j.Step("cluster", func() error {
+		req := &rds.CreateDBClusterInput{
+			DBClusterIdentifier:         aws.String(s.Name),
+			Engine:                      aws.String(s.Engine),
+			EngineVersion:               optional(s.EngineVersion),
+			Port:                        optionalInt(s.Port),
+			DatabaseName:                optional(s.DatabaseName),
+			MasterUsername:              optional(s.DatabaseUsername),
+			MasterUserPassword:          optional(s.DatabasePassword),
+			DBSubnetGroupName:           subnetGroup,
+			BackupRetentionPeriod:       optionalInt(s.BackupRetention),
+			PreferredBackupWindow:       optional(s.BackupWindow),
+			PreferredMaintenanceWindow:  optional(s.MaintenanceWindow),
+			ReplicationSourceIdentifier: optional(s.ReplicationSource),
+		}
+
+		if len(s.AvailabilityZones) > 0 {
+			req.AvailabilityZones = aws.StringSlice(s.AvailabilityZones)
+		}
+
+		if len(s.SecurityGroupIDs) > 0 {
+			req.VpcSecurityGroupIds = aws.StringSlice(s.SecurityGroupIDs)
+		}
+
+		resp, err := svc.CreateDBClusterWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		arn = resp.DBCluster.DBClusterArn
+		endpoint = resp.DBCluster.Endpoint
+
+		return nil
+	}, func() (bool, error) {
+		return c.clusterExists(ctx, svc, s.Name)
+	}, &arn, &endpoint)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := Status{
+		ARN:               aws.StringValue(arn),
+		Name:              s.Name,
+		Engine:            s.Engine,
+		EngineVersion:     s.EngineVersion,
+		Port:              s.Port,
+		Endpoint:          aws.StringValue(endpoint),
+		AvailabilityZones: s.AvailabilityZones,
+		SecurityGroupIDs:  s.SecurityGroupIDs,
+		NetworkIDs:        s.NetworkIDs,
+		DatabaseName:      s.DatabaseName,
+		DatabaseUsername:  s.DatabaseUsername,
+		BackupRetention:   s.BackupRetention,
+		BackupWindow:      s.BackupWindow,
+		MaintenanceWindow: s.MaintenanceWindow,
+		ReplicationSource: s.ReplicationSource,
+		Tags:              s.Tags,
+	}
+
+	err = j.Step("tags", func() error {
+		return c.setTags(ctx, svc, st.ARN, s.Tags)
+	}, nil)
+	if err != nil {
+		return st, err
+	}
+
+	return st, j.Close()
+}
+
+// Update : updates the subnets and settings of a database cluster, the
+// changes are applied immediately
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getRDSClient()
+
+	err := c.updateSubnetGroup(ctx, svc, s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	req := &rds.ModifyDBClusterInput{
+		DBClusterIdentifier:        aws.String(id),
+		Port:                       optionalInt(s.Port),
+		MasterUserPassword:         optional(s.DatabasePassword),
+		BackupRetentionPeriod:      optionalInt(s.BackupRetention),
+		PreferredBackupWindow:      optional(s.BackupWindow),
+		PreferredMaintenanceWindow: optional(s.MaintenanceWindow),
+		ApplyImmediately:           aws.Bool(true),
+	}
+
+	if len(s.SecurityGroupIDs) > 0 {
+		req.VpcSecurityGroupIds = aws.StringSlice(s.SecurityGroupIDs)
+	}
+
+	resp, err := svc.ModifyDBClusterWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(resp.DBCluster, s.Tags)
+
+	return st, c.setTags(ctx, svc, st.ARN, s.Tags)
+}
+
+// Delete : deletes a database cluster and its subnet group, taking a final
+// snapshot if asked to
+func (c Client) Delete(ctx context.Context, name string, finalSnapshot bool) error {
+	svc := c.getRDSClient()
+
+	req := &rds.DeleteDBClusterInput{
+		DBClusterIdentifier: aws.String(name),
+	}
+
+	if finalSnapshot {
+		req.FinalDBSnapshotIdentifier = aws.String(name + "-Final-Snapshot")
+	} else {
+		req.SkipFinalSnapshot = aws.Bool(true)
+	}
+
+	_, err := svc.DeleteDBClusterWithContext(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	err = c.waitUntilClusterDeleted(ctx, svc, name)
+	if err != nil {
+		return err
+	}
+
+	return c.deleteSubnetGroup(ctx, svc, name)
+}
+
+// Find : returns the database clusters matching all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getRDSClient()
+
+	resp, err := svc.DescribeDBClustersWithContext(ctx, &rds.DescribeDBClustersInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var clusters []Status
+
+	for _, cl := range resp.DBClusters {
+		treq := &rds.ListTagsForResourceInput{
+			ResourceName: cl.DBClusterArn,
+		}
+
+		tresp, err := svc.ListTagsForResourceWithContext(ctx, treq)
+		if err != nil {
+			return nil, err
+		}
+
+		st := toStatus(cl, mapRDSTags(tresp.TagList))
+		if !tagsMatch(tags, st.Tags) {
+			continue
+		}
+
+		st.NetworkIDs, err = c.getSubnetGroupNetworks(ctx, svc, cl.DBSubnetGroup)
+		if err != nil {
+			return nil, err
+		}
+
+		clusters = append(clusters, st)
+	}
+
+	return clusters, nil
+}
+
+func (c Client) getRDSClient() *rds.RDS {
+	return rds.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) setTags(ctx context.Context, svc *rds.RDS, arn string, tags map[string]string) error {
+	req := &rds.AddTagsToResourceInput{
+		ResourceName: aws.String(arn),
+	}
+
+	for key, val := range tags {
+		req.Tags = append(req.Tags, &rds.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+	}
+
+	_, err := svc.AddTagsToResourceWithContext(ctx, req)
+
+	return err
+}
+
+func (c Client) createSubnetGroup(ctx context.Context, svc *rds.RDS, s Spec) (*string, error) {
+	if len(s.NetworkIDs) < 1 {
+		return nil, nil
+	}
+
+	req := &rds.CreateDBSubnetGroupInput{
+		DBSubnetGroupDescription: aws.String(s.Name + "-sg"),
+		DBSubnetGroupName:        aws.String(s.Name + "-sg"),
+		SubnetIds:                aws.StringSlice(s.NetworkIDs),
+	}
+
+	_, err := svc.CreateDBSubnetGroupWithContext(ctx, req)
+
+	return req.DBSubnetGroupName, err
+}
+
+// subnetGroupExists : checks the subnet group of a previous attempt was
+// not removed
+func (c Client) subnetGroupExists(ctx context.Context, svc *rds.RDS, name *string) (bool, error) {
+	if name == nil {
+		return true, nil
+	}
+
+	req := &rds.DescribeDBSubnetGroupsInput{
+		DBSubnetGroupName: name,
+	}
+
+	_, err := svc.DescribeDBSubnetGroupsWithContext(ctx, req)
+	if isNotFound(err, rds.ErrCodeDBSubnetGroupNotFoundFault) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (c Client) updateSubnetGroup(ctx context.Context, svc *rds.RDS, s Spec) error {
+	if len(s.NetworkIDs) < 1 {
+		return nil
+	}
+
+	req := &rds.ModifyDBSubnetGroupInput{
+		DBSubnetGroupName:        aws.String(s.Name + "-sg"),
+		DBSubnetGroupDescription: aws.String(s.Name + "-sg"),
+		SubnetIds:                aws.StringSlice(s.NetworkIDs),
+	}
+
+	_, err := svc.ModifyDBSubnetGroupWithContext(ctx, req)
+
+	return err
+}
+
+func (c Client) deleteSubnetGroup(ctx context.Context, svc *rds.RDS, name string) error {
+	req := &rds.DeleteDBSubnetGroupInput{
+		DBSubnetGroupName: aws.String(name + "-sg"),
+	}
+
+	_, err := svc.DeleteDBSubnetGroupWithContext(ctx, req)
+
+	return err
+}
+
+func (c Client) getSubnetGroupNetworks(ctx context.Context, svc *rds.RDS, name *string) ([]string, error) {
+	if name == nil {
+		return nil, nil
+	}
+
+	req := &rds.DescribeDBSubnetGroupsInput{
+		DBSubnetGroupName: name,
+	}
+
+	resp, err := svc.DescribeDBSubnetGroupsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var sids []string
+
+	for _, sg := range resp.DBSubnetGroups {
+		for _, s := range sg.Subnets {
+			sids = append(sids, aws.StringValue(s.SubnetIdentifier))
+		}
+	}
+
+	return sids, nil
+}
+
+func (c Client) waitUntilClusterDeleted(ctx context.Context, svc *rds.RDS, name string) error {
+	req := &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(name),
+	}
+
+	for {
+		_, err := svc.DescribeDBClustersWithContext(ctx, req)
+		if err != nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second * 2):
+		}
+	}
+}
+
+// clusterExists : checks the cluster of a previous attempt is not being
+// or was not deleted
+func (c Client) clusterExists(ctx context.Context, svc *rds.RDS, name string) (bool, error) {
+	req := &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(name),
+	}
+
+	resp, err := svc.DescribeDBClustersWithContext(ctx, req)
+	if isNotFound(err, rds.ErrCodeDBClusterNotFoundFault) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, cl := range resp.DBClusters {
+		if aws.StringValue(cl.Status) != "deleting" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func isNotFound(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
+
+func tagsMatch(qt, rt map[string]string) bool {
+	for k, v := range qt {
+		if rt[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mapRDSTags(input []*rds.Tag) map[string]string {
+	t := make(map[string]string)
+
+	for _, tag := range input {
+		t[*tag.Key] = *tag.Value
+	}
+
+	return t
+}
+
+func mapRDSSecurityGroups(sgroups []*rds.VpcSecurityGroupMembership) []string {
+	var sgs []string
+
+	for _, s := range sgroups {
+		sgs = append(sgs, aws.StringValue(s.VpcSecurityGroupId))
+	}
+
+	return sgs
+}
+
+func toStatus(cl *rds.DBCluster, tags map[string]string) Status {
+	return Status{
+		ARN:               aws.StringValue(cl.DBClusterArn),
+		Name:              aws.StringValue(cl.DBClusterIdentifier),
+		Engine:            aws.StringValue(cl.Engine),
+		EngineVersion:     aws.StringValue(cl.EngineVersion),
+		Port:              aws.Int64Value(cl.Port),
+		Endpoint:          aws.StringValue(cl.Endpoint),
+		AvailabilityZones: aws.StringValueSlice(cl.AvailabilityZones),
+		SecurityGroupIDs:  mapRDSSecurityGroups(cl.VpcSecurityGroups),
+		DatabaseName:      aws.StringValue(cl.DatabaseName),
+		DatabaseUsername:  aws.StringValue(cl.MasterUsername),
+		BackupRetention:   aws.Int64Value(cl.BackupRetentionPeriod),
+		BackupWindow:      aws.StringValue(cl.PreferredBackupWindow),
+		MaintenanceWindow: aws.StringValue(cl.PreferredMaintenanceWindow),
+		ReplicationSource: aws.StringValue(cl.ReplicationSourceIdentifier),
+		Tags:              tags,
+	}
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return aws.String(s)
+}
+
+func optionalInt(i int64) *int64 {
+	if i == 0 {
+		return nil
+	}
+
+	return aws.Int64(i)
+}
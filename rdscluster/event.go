@@ -5,17 +5,18 @@
 package rdscluster
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -38,6 +39,7 @@ type Event struct {
 	ComponentID         string            `json:"_component_id"`
 	State               string            `json:"_state"`
 	Action              string            `json:"_action"`
+	SchemaVersion       int               `json:"_schema_version"`
 	ARN                 *string           `json:"arn"`
 	Name                *string           `json:"name"`
 	Engine              *string           `json:"engine"`
@@ -81,6 +83,10 @@ func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -102,6 +108,11 @@ func (ev *Event) Validate() error {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -112,6 +123,11 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.verify(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -136,91 +152,27 @@ func (ev *Event) Find() error {
 
 // Create : Creates a nat object on aws
 func (ev *Event) Create() error {
-	svc := ev.getRDSClient()
-
-	subnetGroup, err := createSubnetGroup(ev)
-	if err != nil {
-		return err
-	}
-
-	req := &rds.CreateDBClusterInput{
-		DBClusterIdentifier:         ev.Name,
-		Engine:                      ev.Engine,
-		EngineVersion:               ev.EngineVersion,
-		Port:                        ev.Port,
-		AvailabilityZones:           ev.AvailabilityZones,
-		DatabaseName:                ev.DatabaseName,
-		MasterUsername:              ev.DatabaseUsername,
-		MasterUserPassword:          ev.DatabasePassword,
-		VpcSecurityGroupIds:         ev.SecurityGroupAWSIDs,
-		DBSubnetGroupName:           subnetGroup,
-		BackupRetentionPeriod:       ev.BackupRetention,
-		PreferredBackupWindow:       ev.BackupWindow,
-		PreferredMaintenanceWindow:  ev.MaintenanceWindow,
-		ReplicationSourceIdentifier: ev.ReplicationSource,
-	}
-
-	resp, err := svc.CreateDBCluster(req)
-	if err != nil {
-		return err
-	}
+	st, err := ev.client().Create(context.Background(), ev.spec())
 
-	ev.ARN = resp.DBCluster.DBClusterArn
-	ev.Endpoint = resp.DBCluster.Endpoint
+	ev.ARN = optional(st.ARN)
+	ev.Endpoint = optional(st.Endpoint)
 
-	return ev.setTags()
+	return err
 }
 
 // Update : Updates a nat object on aws
 func (ev *Event) Update() error {
-	svc := ev.getRDSClient()
-
-	_, err := updateSubnetGroup(ev)
-	if err != nil {
-		return err
-	}
-
-	req := &rds.ModifyDBClusterInput{
-		DBClusterIdentifier:        ev.Name,
-		Port:                       ev.Port,
-		MasterUserPassword:         ev.DatabasePassword,
-		BackupRetentionPeriod:      ev.BackupRetention,
-		PreferredBackupWindow:      ev.BackupWindow,
-		PreferredMaintenanceWindow: ev.MaintenanceWindow,
-		VpcSecurityGroupIds:        ev.SecurityGroupAWSIDs,
-		ApplyImmediately:           aws.Bool(true),
-	}
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.Name), ev.spec())
 
-	_, err = svc.ModifyDBCluster(req)
-	if err != nil {
-		return err
-	}
+	ev.ARN = optional(st.ARN)
+	ev.Endpoint = optional(st.Endpoint)
 
-	return ev.setTags()
+	return err
 }
 
 // Delete : Deletes a nat object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getRDSClient()
-
-	req := &rds.DeleteDBClusterInput{
-		DBClusterIdentifier: ev.Name,
-	}
-
-	if *ev.FinalSnapshot {
-		req.FinalDBSnapshotIdentifier = aws.String(*ev.Name + "-Final-Snapshot")
-	} else {
-		req.SkipFinalSnapshot = aws.Bool(true)
-	}
-
-	_, err := svc.DeleteDBCluster(req)
-	if err != nil {
-		return err
-	}
-
-	waitUntilClusterDeleted(ev)
-
-	return deleteSubnetGroup(ev)
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.Name), aws.BoolValue(ev.FinalSnapshot))
 }
 
 // Get : Gets a nat object on aws
@@ -243,93 +195,45 @@ func (ev *Event) GetSubject() string {
 }
 
 func (ev *Event) getRDSClient() *rds.RDS {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return rds.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func (ev *Event) setTags() error {
-	svc := ev.getRDSClient()
-
-	req := &rds.AddTagsToResourceInput{
-		ResourceName: ev.ARN,
-	}
-
-	for key, val := range ev.Tags {
-		req.Tags = append(req.Tags, &rds.Tag{
-			Key:   &key,
-			Value: &val,
-		})
-	}
-
-	_, err := svc.AddTagsToResource(req)
-
-	return err
-}
-
-func createSubnetGroup(ev *Event) (*string, error) {
-	svc := ev.getRDSClient()
-
-	if len(ev.NetworkAWSIDs) < 1 {
-		return nil, nil
-	}
-
-	req := &rds.CreateDBSubnetGroupInput{
-		DBSubnetGroupDescription: aws.String(*ev.Name + "-sg"),
-		DBSubnetGroupName:        aws.String(*ev.Name + "-sg"),
-		SubnetIds:                ev.NetworkAWSIDs,
-	}
-
-	_, err := svc.CreateDBSubnetGroup(req)
-
-	return req.DBSubnetGroupName, err
-}
-
-func updateSubnetGroup(ev *Event) (*string, error) {
-	svc := ev.getRDSClient()
-
-	if len(ev.NetworkAWSIDs) < 1 {
-		return nil, nil
-	}
-
-	req := &rds.ModifyDBSubnetGroupInput{
-		DBSubnetGroupName:        aws.String(*ev.Name + "-sg"),
-		DBSubnetGroupDescription: aws.String(*ev.Name + "-sg"),
-		SubnetIds:                ev.NetworkAWSIDs,
-	}
-
-	_, err := svc.ModifyDBSubnetGroup(req)
-
-	return req.DBSubnetGroupName, err
-}
-
-func deleteSubnetGroup(ev *Event) error {
-	svc := ev.getRDSClient()
-
-	req := &rds.DeleteDBSubnetGroupInput{
-		DBSubnetGroupName: aws.String(*ev.Name + "-sg"),
-	}
-
-	_, err := svc.DeleteDBSubnetGroup(req)
-
-	return err
-}
-
-func waitUntilClusterDeleted(ev *Event) {
-	svc := ev.getRDSClient()
-
-	req := &rds.DescribeDBClustersInput{
-		DBClusterIdentifier: ev.Name,
-	}
-
-	for {
-		_, err := svc.DescribeDBClusters(req)
-		if err != nil {
-			return
-		}
-
-		time.Sleep(time.Second * 2)
+	return ev.client().getRDSClient()
+}
+
+func (ev *Event) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(ev.Subject, ev.ComponentID), ev.client().Config())
+}
+
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+}
+
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:              aws.StringValue(ev.Name),
+		Engine:            aws.StringValue(ev.Engine),
+		EngineVersion:     aws.StringValue(ev.EngineVersion),
+		Port:              aws.Int64Value(ev.Port),
+		AvailabilityZones: aws.StringValueSlice(ev.AvailabilityZones),
+		SecurityGroupIDs:  aws.StringValueSlice(ev.SecurityGroupAWSIDs),
+		NetworkIDs:        aws.StringValueSlice(ev.NetworkAWSIDs),
+		DatabaseName:      aws.StringValue(ev.DatabaseName),
+		DatabaseUsername:  aws.StringValue(ev.DatabaseUsername),
+		DatabasePassword:  aws.StringValue(ev.DatabasePassword),
+		BackupRetention:   aws.Int64Value(ev.BackupRetention),
+		BackupWindow:      aws.StringValue(ev.BackupWindow),
+		MaintenanceWindow: aws.StringValue(ev.MaintenanceWindow),
+		ReplicationSource: aws.StringValue(ev.ReplicationSource),
+		Tags:              ev.Tags,
 	}
 }
@@ -0,0 +1,213 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdscluster
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+	"github.com/ernestio/ernestaws/journal"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+// setup : creates a vpc with two networks and a security group
+func setup(t *testing.T, b *awsfake.Backend) map[string]string {
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	for i, name := range []string{"neta", "netb"} {
+		var out ec2.CreateSubnetOutput
+		if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(ids["vpc"]), CidrBlock: aws.String(fmt.Sprintf("10.0.%d.0/24", i))}, &out); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = *out.Subnet.SubnetId
+	}
+
+	var sg ec2.CreateSecurityGroupOutput
+	if err := b.EC2.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{VpcId: aws.String(ids["vpc"]), GroupName: aws.String("db"), Description: aws.String("db")}, &sg); err != nil {
+		t.Fatal(err)
+	}
+	ids["sg"] = *sg.GroupId
+
+	return ids
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "rds_cluster.create.aws",
+			body:     `{"name":"aurora","engine":"aurora","database_username":"root","database_password":"secret","network_aws_ids":["$neta","$netb"],"security_group_aws_ids":["$sg"],"final_snapshot":false,"tags":{"Name":"aurora","Env":"test"}}`,
+			expected: "rds_cluster.create.aws.done",
+			check: func(res map[string]interface{}) bool {
+				c := b.RDS.DBClusters["aurora"]
+				return c != nil && res["arn"] == *c.DBClusterArn && res["endpoint"] != nil && len(b.RDS.Tags[*c.DBClusterArn]) == 2 && len(b.RDS.DBSubnetGroups["aurora-sg"].Subnets) == 2
+			},
+		},
+		{
+			name:     "update",
+			subject:  "rds_cluster.update.aws",
+			body:     `{"name":"aurora","engine":"aurora","backup_retention":7,"network_aws_ids":["$netb"],"security_group_aws_ids":["$sg"],"final_snapshot":false,"tags":{"Name":"aurora","Env":"test"}}`,
+			expected: "rds_cluster.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return *b.RDS.DBClusters["aurora"].BackupRetentionPeriod == 7 && len(b.RDS.DBSubnetGroups["aurora-sg"].Subnets) == 1
+			},
+		},
+		{
+			name:     "find",
+			subject:  "rds_cluster.find.aws",
+			body:     `{"tags":{"Env":"test"}}`,
+			expected: "rds_cluster.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				c := found[0].(map[string]interface{})
+				return c["name"] == "aurora" && c["backup_retention"] == 7.0 && len(c["network_aws_ids"].([]interface{})) == 1
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "rds_cluster.delete.aws",
+			body:     `{"name":"aurora","engine":"aurora","final_snapshot":true}`,
+			expected: "rds_cluster.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.RDS.DBClusters) == 0 && len(b.RDS.DBSubnetGroups) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails creating the subnet group",
+			operation: "CreateDBSubnetGroup",
+			subject:   "rds_cluster.create.aws",
+			body:      `{"name":"aurora","engine":"aurora","network_aws_ids":["$neta"],"final_snapshot":false}`,
+		},
+		{
+			name:      "create fails",
+			operation: "CreateDBCluster",
+			subject:   "rds_cluster.create.aws",
+			body:      `{"name":"aurora","engine":"aurora","network_aws_ids":["$neta"],"final_snapshot":false}`,
+		},
+		{
+			name:      "create fails tagging the cluster",
+			operation: "AddTagsToResource",
+			subject:   "rds_cluster.create.aws",
+			body:      `{"name":"aurora","engine":"aurora","final_snapshot":false,"tags":{"Name":"aurora"}}`,
+		},
+		{
+			name:      "update fails",
+			operation: "ModifyDBCluster",
+			subject:   "rds_cluster.update.aws",
+			body:      `{"name":"aurora","engine":"aurora","final_snapshot":false}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteDBCluster",
+			subject:   "rds_cluster.delete.aws",
+			body:      `{"name":"aurora","engine":"aurora","final_snapshot":false}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := setup(t, b)
+			b.Fail("rds", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestCreateResumes(t *testing.T) {
+	store, err := journal.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal.Use(store)
+	defer journal.Use(nil)
+
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+	body := `{"_component_id":"rds_cluster::aurora","name":"aurora","engine":"aurora","network_aws_ids":["$neta","$netb"],"final_snapshot":false,"tags":{"Name":"aurora"}}`
+
+	b.Fail("rds", "CreateDBCluster", "RequestTimeout", 1)
+
+	if subject, _ := awsfake.Run(t, New, "rds_cluster.create.aws", body, ids); subject != "rds_cluster.create.aws.error" {
+		t.Fatalf("expected rds_cluster.create.aws.error, got %s", subject)
+	}
+
+	subject, res := awsfake.Run(t, New, "rds_cluster.create.aws", body, ids)
+	if subject != "rds_cluster.create.aws.done" {
+		t.Fatalf("expected rds_cluster.create.aws.done, got %s: %v", subject, res["error"])
+	}
+
+	if n := b.Called("rds", "CreateDBSubnetGroup"); n != 1 {
+		t.Errorf("expected the subnet group to be created once, got %d", n)
+	}
+
+	if b.RDS.DBClusters["aurora"] == nil {
+		t.Errorf("expected the cluster to be created on the second attempt")
+	}
+}
@@ -5,14 +5,15 @@
 package rdscluster
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/rds"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -50,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -108,50 +119,30 @@ func (col *Collection) Delete() error {
 
 // Find : Find rds clusters on aws
 func (col *Collection) Find() error {
-	svc := col.getRDSClient()
-
-	resp, err := svc.DescribeDBClusters(nil)
+	clusters, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, c := range resp.DBClusters {
-		tags, err := getClusterTagDescriptions(svc, c.DBClusterArn)
-		if err != nil {
-			return err
-		}
-
-		sg, err := getSubnetGroup(svc, c.DBSubnetGroup)
-		if err != nil {
-			return err
-		}
-
-		e := toEvent(c, sg, tags)
-
-		if tagsMatch(col.Tags, e.Tags) {
-			col.Results = append(col.Results, e)
-		}
+	for _, st := range clusters {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getRDSClient() *rds.RDS {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return rds.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func tagsMatch(qt, rt map[string]string) bool {
-	for k, v := range qt {
-		if rt[k] != v {
-			return false
-		}
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
 	}
-
-	return true
 }
 
 func mapFilters(tags map[string]string) []*rds.Filter {
@@ -167,78 +158,27 @@ func mapFilters(tags map[string]string) []*rds.Filter {
 	return f
 }
 
-func mapRDSTags(input []*rds.Tag) map[string]string {
-	t := make(map[string]string)
-
-	for _, tag := range input {
-		t[*tag.Key] = *tag.Value
-	}
-
-	return t
-}
-
-func getClusterTagDescriptions(svc *rds.RDS, name *string) ([]*rds.Tag, error) {
-	treq := &rds.ListTagsForResourceInput{
-		ResourceName: name,
-	}
-
-	resp, err := svc.ListTagsForResource(treq)
-
-	return resp.TagList, err
-}
-
-func getSubnetGroup(svc *rds.RDS, name *string) (*rds.DBSubnetGroup, error) {
-	req := &rds.DescribeDBSubnetGroupsInput{
-		DBSubnetGroupName: name,
-	}
-
-	resp, err := svc.DescribeDBSubnetGroups(req)
-
-	return resp.DBSubnetGroups[0], err
-}
-
-func mapSubnetGroups(subnetgroup *rds.DBSubnetGroup) []*string {
-	var sids []*string
-
-	for _, s := range subnetgroup.Subnets {
-		sids = append(sids, s.SubnetIdentifier)
-	}
-
-	return sids
-}
-
-func mapRDSSecurityGroups(sgroups []*rds.VpcSecurityGroupMembership) []*string {
-	var sgs []*string
-
-	for _, s := range sgroups {
-		sgs = append(sgs, s.VpcSecurityGroupId)
-	}
-
-	return sgs
-}
-
-// ToEvent converts an rds cluster object to an ernest event
-func toEvent(c *rds.DBCluster, sg *rds.DBSubnetGroup, tags []*rds.Tag) *Event {
-	e := &Event{
+// toEvent converts a database cluster status to an ernest event
+func toEvent(st Status) *Event {
+	return &Event{
 		ProviderType:        "aws",
 		ComponentType:       "rds_cluster",
-		ComponentID:         "rds_cluster::" + *c.DBClusterIdentifier,
-		ARN:                 c.DBClusterArn,
-		Name:                c.DBClusterIdentifier,
-		Engine:              c.Engine,
-		EngineVersion:       c.EngineVersion,
-		Port:                c.Port,
-		Endpoint:            c.Endpoint,
-		AvailabilityZones:   c.AvailabilityZones,
-		DatabaseName:        c.DatabaseName,
-		DatabaseUsername:    c.MasterUsername,
-		BackupRetention:     c.BackupRetentionPeriod,
-		BackupWindow:        c.PreferredBackupWindow,
-		MaintenanceWindow:   c.PreferredMaintenanceWindow,
-		ReplicationSource:   c.ReplicationSourceIdentifier,
-		NetworkAWSIDs:       mapSubnetGroups(sg),
-		SecurityGroupAWSIDs: mapRDSSecurityGroups(c.VpcSecurityGroups),
-		Tags:                mapRDSTags(tags),
+		ComponentID:         "rds_cluster::" + st.Name,
+		ARN:                 aws.String(st.ARN),
+		Name:                aws.String(st.Name),
+		Engine:              aws.String(st.Engine),
+		EngineVersion:       optional(st.EngineVersion),
+		Port:                optionalInt(st.Port),
+		Endpoint:            optional(st.Endpoint),
+		AvailabilityZones:   aws.StringSlice(st.AvailabilityZones),
+		DatabaseName:        optional(st.DatabaseName),
+		DatabaseUsername:    optional(st.DatabaseUsername),
+		BackupRetention:     optionalInt(st.BackupRetention),
+		BackupWindow:        optional(st.BackupWindow),
+		MaintenanceWindow:   optional(st.MaintenanceWindow),
+		ReplicationSource:   optional(st.ReplicationSource),
+		NetworkAWSIDs:       aws.StringSlice(st.NetworkIDs),
+		SecurityGroupAWSIDs: aws.StringSlice(st.SecurityGroupIDs),
+		Tags:                st.Tags,
 	}
-	return e
 }
@@ -0,0 +1,41 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdscluster
+
+import "github.com/ernestio/ernestaws/schema"
+
+var cluster = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "engine", Type: schema.String, Required: true},
+	{Name: "engine_version", Type: schema.String},
+	{Name: "port", Type: schema.Integer, Minimum: schema.Int(1150), Maximum: schema.Int(65535)},
+	{Name: "availability_zones", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "security_group_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "network_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "database_name", Type: schema.String},
+	{Name: "database_username", Type: schema.String},
+	{Name: "database_password", Type: schema.String},
+	{Name: "backup_retention", Type: schema.Integer, Minimum: schema.Int(1), Maximum: schema.Int(35)},
+	{Name: "backup_window", Type: schema.String},
+	{Name: "maintenance_window", Type: schema.String},
+	{Name: "replication_source", Type: schema.String, Format: schema.ARN},
+	{Name: "final_snapshot", Type: schema.Boolean},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("rds_cluster", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), cluster),
+		"update": schema.Fields(schema.Datacenter(), cluster),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "name", Type: schema.String, Required: true},
+			{Name: "engine", Type: schema.String, Required: true},
+			{Name: "final_snapshot", Type: schema.Boolean, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
@@ -0,0 +1,29 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdscluster
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ernestio/ernestaws/verify"
+)
+
+// verify : checks the event against the live aws metadata
+func (ev *Event) verify() error {
+	if !verify.Enabled() || strings.Split(ev.Subject, ".")[1] != "create" {
+		return nil
+	}
+
+	svc := ev.getEC2Client()
+
+	var c verify.Check
+	for i, zone := range ev.AvailabilityZones {
+		c.AvailabilityZone(svc, "$.availability_zones["+strconv.Itoa(i)+"]", zone)
+	}
+	c.EngineVersion(ev.getRDSClient(), "$.engine_version", ev.Engine, ev.EngineVersion)
+
+	return c.Err()
+}
@@ -0,0 +1,490 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdsinstance
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of a database instance. Empty strings
+// and zero sizes are left to the aws defaults, as are the nil options
+type Spec struct {
+	Name              string
+	Size              string
+	Engine            string
+	EngineVersion     string
+	Port              int64
+	Cluster           string
+	Public            *bool
+	MultiAZ           *bool
+	PromotionTier     *int64
+	StorageType       string
+	StorageSize       int64
+	StorageIops       int64
+	AvailabilityZone  string
+	SecurityGroupIDs  []string
+	NetworkIDs        []string
+	DatabaseName      string
+	DatabaseUsername  string
+	DatabasePassword  string
+	AutoUpgrade       *bool
+	BackupRetention   *int64
+	BackupWindow      string
+	MaintenanceWindow string
+	ReplicationSource string
+	License           string
+	Timezone          string
+	Tags              map[string]string
+}
+
+// Status describes a database instance as it is on aws
+type Status struct {
+	ARN               string
+	Name              string
+	Size              string
+	Engine            string
+	EngineVersion     string
+	Endpoint          string
+	Port              int64
+	Cluster           string
+	Public            bool
+	MultiAZ           bool
+	PromotionTier     int64
+	StorageType       string
+	StorageSize       int64
+	StorageIops       int64
+	AvailabilityZone  string
+	SecurityGroupIDs  []string
+	NetworkIDs        []string
+	DatabaseName      string
+	DatabaseUsername  string
+	AutoUpgrade       bool
+	BackupRetention   int64
+	BackupWindow      string
+	MaintenanceWindow string
+	ReplicationSource string
+	License           string
+	Timezone          string
+	Tags              map[string]string
+}
+
+// Client manages database instances through a typed api, the json events
+// are an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a database instance, or a read replica when it has a
+// replication source, and waits for it to be available
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getRDSClient()
+
+	subnetGroup, err := c.createSubnetGroup(ctx, svc, s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if s.ReplicationSource != "" {
+		err = c.createReplicaDB(ctx, svc, s, subnetGroup)
+	} else {
+		err = c.createPrimaryDB(ctx, svc, s, subnetGroup)
+	}
+
+	if err != nil {
+		return Status{}, err
+	}
+
+	waitreq := &rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(s.Name),
+	}
+
+	err = svc.WaitUntilDBInstanceAvailableWithContext(ctx, waitreq)
+	if err != nil {
+		return Status{}, err
+	}
+
+	resp, err := svc.DescribeDBInstancesWithContext(ctx, waitreq)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(resp.DBInstances[0], s.Tags)
+
+	return st, c.setTags(ctx, svc, st.ARN, s.Tags)
+}
+
+// Update : updates the subnets and settings of a database instance, the
+// changes are applied immediately
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getRDSClient()
+
+	err := c.updateSubnetGroup(ctx, svc, s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	req := &rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier:       aws.String(id),
+		DBInstanceClass:            aws.String(s.Size),
+		EngineVersion:              optional(s.EngineVersion),
+		DBPortNumber:               optionalInt(s.Port),
+		AllocatedStorage:           optionalInt(s.StorageSize),
+		StorageType:                optional(s.StorageType),
+		Iops:                       optionalInt(s.StorageIops),
+		MultiAZ:                    s.MultiAZ,
+		PromotionTier:              s.PromotionTier,
+		AutoMinorVersionUpgrade:    s.AutoUpgrade,
+		BackupRetentionPeriod:      s.BackupRetention,
+		PreferredBackupWindow:      optional(s.BackupWindow),
+		PreferredMaintenanceWindow: optional(s.MaintenanceWindow),
+		MasterUserPassword:         optional(s.DatabasePassword),
+		LicenseModel:               optional(s.License),
+		PubliclyAccessible:         s.Public,
+		ApplyImmediately:           aws.Bool(true),
+	}
+
+	if len(s.SecurityGroupIDs) > 0 {
+		req.VpcSecurityGroupIds = aws.StringSlice(s.SecurityGroupIDs)
+	}
+
+	resp, err := svc.ModifyDBInstanceWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return toStatus(resp.DBInstance, s.Tags), nil
+}
+
+// Delete : deletes a database instance, taking a final snapshot if asked
+// to. The subnet group is kept for the instances of a cluster
+func (c Client) Delete(ctx context.Context, name, cluster string, finalSnapshot bool) error {
+	svc := c.getRDSClient()
+
+	req := &rds.DeleteDBInstanceInput{
+		DBInstanceIdentifier: aws.String(name),
+	}
+
+	if finalSnapshot {
+		req.FinalDBSnapshotIdentifier = aws.String(name + "-Final-Snapshot")
+	} else {
+		req.SkipFinalSnapshot = aws.Bool(true)
+	}
+
+	_, err := svc.DeleteDBInstanceWithContext(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for !c.isRDSInstanceDeleted(ctx, svc, name) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second * 3):
+		}
+	}
+
+	if cluster != "" {
+		return nil
+	}
+
+	return c.deleteSubnetGroup(ctx, svc, name)
+}
+
+// Find : returns the database instances matching all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getRDSClient()
+
+	resp, err := svc.DescribeDBInstancesWithContext(ctx, &rds.DescribeDBInstancesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []Status
+
+	for _, i := range resp.DBInstances {
+		treq := &rds.ListTagsForResourceInput{
+			ResourceName: i.DBInstanceArn,
+		}
+
+		tresp, err := svc.ListTagsForResourceWithContext(ctx, treq)
+		if err != nil {
+			return nil, err
+		}
+
+		st := toStatus(i, mapRDSTags(tresp.TagList))
+		if tagsMatch(tags, st.Tags) {
+			instances = append(instances, st)
+		}
+	}
+
+	return instances, nil
+}
+
+func (c Client) getRDSClient() *rds.RDS {
+	return rds.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) createPrimaryDB(ctx context.Context, svc *rds.RDS, s Spec, subnetGroup *string) error {
+	req := &rds.CreateDBInstanceInput{
+		DBInstanceIdentifier:       aws.String(s.Name),
+		DBInstanceClass:            aws.String(s.Size),
+		Engine:                     aws.String(s.Engine),
+		EngineVersion:              optional(s.EngineVersion),
+		Port:                       optionalInt(s.Port),
+		DBClusterIdentifier:        optional(s.Cluster),
+		AllocatedStorage:           optionalInt(s.StorageSize),
+		StorageType:                optional(s.StorageType),
+		Iops:                       optionalInt(s.StorageIops),
+		MultiAZ:                    s.MultiAZ,
+		PromotionTier:              s.PromotionTier,
+		AvailabilityZone:           optional(s.AvailabilityZone),
+		AutoMinorVersionUpgrade:    s.AutoUpgrade,
+		BackupRetentionPeriod:      s.BackupRetention,
+		PreferredBackupWindow:      optional(s.BackupWindow),
+		PreferredMaintenanceWindow: optional(s.MaintenanceWindow),
+		DBName:                     optional(s.DatabaseName),
+		MasterUsername:             optional(s.DatabaseUsername),
+		MasterUserPassword:         optional(s.DatabasePassword),
+		DBSubnetGroupName:          subnetGroup,
+		LicenseModel:               optional(s.License),
+		PubliclyAccessible:         s.Public,
+		Timezone:                   optional(s.Timezone),
+	}
+
+	if len(s.SecurityGroupIDs) > 0 {
+		req.VpcSecurityGroupIds = aws.StringSlice(s.SecurityGroupIDs)
+	}
+
+	_, err := svc.CreateDBInstanceWithContext(ctx, req)
+
+	return err
+}
+
+func (c Client) createReplicaDB(ctx context.Context, svc *rds.RDS, s Spec, subnetGroup *string) error {
+	req := &rds.CreateDBInstanceReadReplicaInput{
+		AutoMinorVersionUpgrade:    s.AutoUpgrade,
+		AvailabilityZone:           optional(s.AvailabilityZone),
+		DBInstanceIdentifier:       aws.String(s.Name),
+		DBInstanceClass:            aws.String(s.Size),
+		DBSubnetGroupName:          subnetGroup,
+		StorageType:                optional(s.StorageType),
+		Iops:                       optionalInt(s.StorageIops),
+		Port:                       optionalInt(s.Port),
+		PubliclyAccessible:         s.Public,
+		SourceDBInstanceIdentifier: aws.String(s.ReplicationSource),
+	}
+
+	_, err := svc.CreateDBInstanceReadReplicaWithContext(ctx, req)
+
+	return err
+}
+
+func (c Client) isRDSInstanceDeleted(ctx context.Context, svc *rds.RDS, name string) bool {
+	req := &rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(name),
+	}
+
+	resp, err := svc.DescribeDBInstancesWithContext(ctx, req)
+	if err != nil {
+		return true
+	}
+
+	return len(resp.DBInstances) != 1
+}
+
+func (c Client) createSubnetGroup(ctx context.Context, svc *rds.RDS, s Spec) (*string, error) {
+	if len(s.NetworkIDs) < 1 {
+		return nil, nil
+	}
+
+	req := &rds.CreateDBSubnetGroupInput{
+		DBSubnetGroupDescription: aws.String(s.Name + "-sg"),
+		DBSubnetGroupName:        aws.String(s.Name + "-sg"),
+		SubnetIds:                aws.StringSlice(s.NetworkIDs),
+	}
+
+	_, err := svc.CreateDBSubnetGroupWithContext(ctx, req)
+
+	return req.DBSubnetGroupName, err
+}
+
+func (c Client) updateSubnetGroup(ctx context.Context, svc *rds.RDS, s Spec) error {
+	if len(s.NetworkIDs) < 1 {
+		return nil
+	}
+
+	req := &rds.DescribeDBSubnetGroupsInput{
+		DBSubnetGroupName: aws.String(s.Name + "-sg"),
+	}
+
+	resp, err := svc.DescribeDBSubnetGroupsWithContext(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.DBSubnetGroups) < 1 || !subnetsHaveChanged(s.NetworkIDs, resp.DBSubnetGroups[0].Subnets) {
+		return nil
+	}
+
+	mreq := &rds.ModifyDBSubnetGroupInput{
+		DBSubnetGroupName:        aws.String(s.Name + "-sg"),
+		DBSubnetGroupDescription: aws.String(s.Name + "-sg"),
+		SubnetIds:                aws.StringSlice(s.NetworkIDs),
+	}
+
+	_, err = svc.ModifyDBSubnetGroupWithContext(ctx, mreq)
+
+	return err
+}
+
+func (c Client) deleteSubnetGroup(ctx context.Context, svc *rds.RDS, name string) error {
+	req := &rds.DeleteDBSubnetGroupInput{
+		DBSubnetGroupName: aws.String(name + "-sg"),
+	}
+
+	_, err := svc.DeleteDBSubnetGroupWithContext(ctx, req)
+
+	return err
+}
+
+func (c Client) setTags(ctx context.Context, svc *rds.RDS, arn string, tags map[string]string) error {
+	req := &rds.AddTagsToResourceInput{
+		ResourceName: aws.String(arn),
+	}
+
+	for key, val := range tags {
+		req.Tags = append(req.Tags, &rds.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+	}
+
+	_, err := svc.AddTagsToResourceWithContext(ctx, req)
+
+	return err
+}
+
+func subnetsHaveChanged(ids []string, subnets []*rds.Subnet) bool {
+	var sids sort.StringSlice
+	for _, s := range subnets {
+		sids = append(sids, aws.StringValue(s.SubnetIdentifier))
+	}
+
+	if len(ids) != len(sids) {
+		return true
+	}
+
+	idsx := append(sort.StringSlice{}, ids...)
+	idsx.Sort()
+	sids.Sort()
+
+	return !reflect.DeepEqual(idsx, sids)
+}
+
+func tagsMatch(qt, rt map[string]string) bool {
+	for k, v := range qt {
+		if rt[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mapRDSTags(input []*rds.Tag) map[string]string {
+	t := make(map[string]string)
+
+	for _, tag := range input {
+		t[*tag.Key] = *tag.Value
+	}
+
+	return t
+}
+
+func mapSubnetGroups(subnetgroup *rds.DBSubnetGroup) []string {
+	var sids []string
+
+	if subnetgroup == nil {
+		return sids
+	}
+
+	for _, s := range subnetgroup.Subnets {
+		sids = append(sids, aws.StringValue(s.SubnetIdentifier))
+	}
+
+	return sids
+}
+
+func mapRDSSecurityGroups(sgroups []*rds.VpcSecurityGroupMembership) []string {
+	var sgs []string
+
+	for _, s := range sgroups {
+		sgs = append(sgs, aws.StringValue(s.VpcSecurityGroupId))
+	}
+
+	return sgs
+}
+
+func toStatus(i *rds.DBInstance, tags map[string]string) Status {
+	st := Status{
+		ARN:               aws.StringValue(i.DBInstanceArn),
+		Name:              aws.StringValue(i.DBInstanceIdentifier),
+		Size:              aws.StringValue(i.DBInstanceClass),
+		Engine:            aws.StringValue(i.Engine),
+		EngineVersion:     aws.StringValue(i.EngineVersion),
+		Cluster:           aws.StringValue(i.DBClusterIdentifier),
+		Public:            aws.BoolValue(i.PubliclyAccessible),
+		MultiAZ:           aws.BoolValue(i.MultiAZ),
+		PromotionTier:     aws.Int64Value(i.PromotionTier),
+		StorageType:       aws.StringValue(i.StorageType),
+		StorageSize:       aws.Int64Value(i.AllocatedStorage),
+		StorageIops:       aws.Int64Value(i.Iops),
+		AvailabilityZone:  aws.StringValue(i.AvailabilityZone),
+		SecurityGroupIDs:  mapRDSSecurityGroups(i.VpcSecurityGroups),
+		NetworkIDs:        mapSubnetGroups(i.DBSubnetGroup),
+		DatabaseName:      aws.StringValue(i.DBName),
+		DatabaseUsername:  aws.StringValue(i.MasterUsername),
+		AutoUpgrade:       aws.BoolValue(i.AutoMinorVersionUpgrade),
+		BackupRetention:   aws.Int64Value(i.BackupRetentionPeriod),
+		BackupWindow:      aws.StringValue(i.PreferredBackupWindow),
+		MaintenanceWindow: aws.StringValue(i.PreferredMaintenanceWindow),
+		ReplicationSource: aws.StringValue(i.ReadReplicaSourceDBInstanceIdentifier),
+		License:           aws.StringValue(i.LicenseModel),
+		Timezone:          aws.StringValue(i.Timezone),
+		Tags:              tags,
+	}
+
+	if i.Endpoint != nil {
+		st.Endpoint = aws.StringValue(i.Endpoint.Address)
+		st.Port = aws.Int64Value(i.Endpoint.Port)
+	}
+
+	return st
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return aws.String(s)
+}
+
+func optionalInt(i int64) *int64 {
+	if i == 0 {
+		return nil
+	}
+
+	return aws.Int64(i)
+}
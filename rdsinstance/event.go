@@ -5,19 +5,18 @@
 package rdsinstance
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
-	"reflect"
-	"sort"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -42,6 +41,7 @@ type Event struct {
 	ComponentID         string            `json:"_component_id"`
 	State               string            `json:"_state"`
 	Action              string            `json:"_action"`
+	SchemaVersion       int               `json:"_schema_version"`
 	ARN                 *string           `json:"arn"`
 	Name                *string           `json:"name"`
 	Size                *string           `json:"size"`
@@ -96,6 +96,10 @@ func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -121,6 +125,11 @@ func (ev *Event) Validate() error {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -131,6 +140,16 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.verify(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := ev.preflight(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -155,83 +174,33 @@ func (ev *Event) Find() error {
 
 // Create : Creates a nat object on aws
 func (ev *Event) Create() error {
-	svc := ev.getRDSClient()
-
-	subnetGroup, err := createSubnetGroup(ev)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	if ev.ReplicationSource != nil {
-		return ev.createReplicaDB(svc, subnetGroup)
-	}
+	ev.ARN = aws.String(st.ARN)
+	ev.Endpoint = optional(st.Endpoint)
 
-	return ev.createPrimaryDB(svc, subnetGroup)
+	return nil
 }
 
 // Update : Updates a nat object on aws
 func (ev *Event) Update() error {
-	svc := ev.getRDSClient()
-
-	err := updateSubnetGroup(ev)
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.Name), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	req := &rds.ModifyDBInstanceInput{
-		DBInstanceIdentifier:       ev.Name,
-		DBInstanceClass:            ev.Size,
-		EngineVersion:              ev.EngineVersion,
-		DBPortNumber:               ev.Port,
-		AllocatedStorage:           ev.StorageSize,
-		StorageType:                ev.StorageType,
-		Iops:                       ev.StorageIops,
-		MultiAZ:                    ev.MultiAZ,
-		PromotionTier:              ev.PromotionTier,
-		AutoMinorVersionUpgrade:    ev.AutoUpgrade,
-		BackupRetentionPeriod:      ev.BackupRetention,
-		PreferredBackupWindow:      ev.BackupWindow,
-		PreferredMaintenanceWindow: ev.MaintenanceWindow,
-		VpcSecurityGroupIds:        ev.SecurityGroupAWSIDs,
-		MasterUserPassword:         ev.DatabasePassword,
-		LicenseModel:               ev.License,
-		PubliclyAccessible:         ev.Public,
-		ApplyImmediately:           aws.Bool(true),
-	}
+	ev.ARN = aws.String(st.ARN)
+	ev.Endpoint = optional(st.Endpoint)
 
-	_, err = svc.ModifyDBInstance(req)
-
-	return err
+	return nil
 }
 
 // Delete : Deletes a nat object on aws
 func (ev *Event) Delete() error {
-	svc := ev.getRDSClient()
-
-	req := &rds.DeleteDBInstanceInput{
-		DBInstanceIdentifier: ev.Name,
-	}
-
-	if *ev.FinalSnapshot {
-		req.FinalDBSnapshotIdentifier = aws.String(*ev.Name + "-Final-Snapshot")
-	} else {
-		req.SkipFinalSnapshot = aws.Bool(true)
-	}
-
-	_, err := svc.DeleteDBInstance(req)
-	if err != nil {
-		return err
-	}
-
-	for ev.isRDSInstanceDeleted(ev.Name) == false {
-		time.Sleep(time.Second * 3)
-	}
-
-	if ev.Cluster != nil {
-		return nil
-	}
-
-	return deleteSubnetGroup(ev)
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.Name), aws.StringValue(ev.Cluster), aws.BoolValue(ev.FinalSnapshot))
 }
 
 // Get : Gets a nat object on aws
@@ -253,250 +222,57 @@ func (ev *Event) GetSubject() string {
 	return ev.Subject
 }
 
-func (ev *Event) createPrimaryDB(svc *rds.RDS, subnetGroup *string) error {
-	req := &rds.CreateDBInstanceInput{
-		DBInstanceIdentifier:       ev.Name,
-		DBInstanceClass:            ev.Size,
-		Engine:                     ev.Engine,
-		EngineVersion:              ev.EngineVersion,
-		Port:                       ev.Port,
-		DBClusterIdentifier:        ev.Cluster,
-		AllocatedStorage:           ev.StorageSize,
-		StorageType:                ev.StorageType,
-		Iops:                       ev.StorageIops,
-		MultiAZ:                    ev.MultiAZ,
-		PromotionTier:              ev.PromotionTier,
-		AvailabilityZone:           ev.AvailabilityZone,
-		AutoMinorVersionUpgrade:    ev.AutoUpgrade,
-		BackupRetentionPeriod:      ev.BackupRetention,
-		PreferredBackupWindow:      ev.BackupWindow,
-		PreferredMaintenanceWindow: ev.MaintenanceWindow,
-		VpcSecurityGroupIds:        ev.SecurityGroupAWSIDs,
-		DBName:                     ev.DatabaseName,
-		MasterUsername:             ev.DatabaseUsername,
-		MasterUserPassword:         ev.DatabasePassword,
-		DBSubnetGroupName:          subnetGroup,
-		LicenseModel:               ev.License,
-		PubliclyAccessible:         ev.Public,
-		Timezone:                   ev.Timezone,
-	}
-
-	_, err := svc.CreateDBInstance(req)
-	if err != nil {
-		return err
-	}
-
-	waitreq := &rds.DescribeDBInstancesInput{
-		DBInstanceIdentifier: ev.Name,
-	}
-
-	err = svc.WaitUntilDBInstanceAvailable(waitreq)
-	if err != nil {
-		return err
-	}
-
-	resp, err := svc.DescribeDBInstances(waitreq)
-	if err != nil {
-		return err
-	}
-
-	ev.ARN = resp.DBInstances[0].DBInstanceArn
-
-	if resp.DBInstances[0].Endpoint != nil {
-		if resp.DBInstances[0].Endpoint.Address != nil {
-			ev.Endpoint = resp.DBInstances[0].Endpoint.Address
-		}
-	}
-
-	return ev.setTags()
-}
-
-func (ev *Event) createReplicaDB(svc *rds.RDS, subnetGroup *string) error {
-	req := &rds.CreateDBInstanceReadReplicaInput{
-		AutoMinorVersionUpgrade:    ev.AutoUpgrade,
-		AvailabilityZone:           ev.AvailabilityZone,
-		DBInstanceIdentifier:       ev.Name,
-		DBInstanceClass:            ev.Size,
-		DBSubnetGroupName:          subnetGroup,
-		StorageType:                ev.StorageType,
-		Iops:                       ev.StorageIops,
-		Port:                       ev.Port,
-		PubliclyAccessible:         ev.Public,
-		SourceDBInstanceIdentifier: ev.ReplicationSource,
-	}
-
-	_, err := svc.CreateDBInstanceReadReplica(req)
-	if err != nil {
-		return err
-	}
-
-	waitreq := &rds.DescribeDBInstancesInput{
-		DBInstanceIdentifier: ev.Name,
-	}
-
-	err = svc.WaitUntilDBInstanceAvailable(waitreq)
-	if err != nil {
-		return err
-	}
-
-	resp, err := svc.DescribeDBInstances(waitreq)
-	if err != nil {
-		return err
-	}
-
-	ev.ARN = resp.DBInstances[0].DBInstanceArn
-
-	if resp.DBInstances[0].Endpoint != nil {
-		ev.Endpoint = resp.DBInstances[0].Endpoint.Address
-	}
-
-	return ev.setTags()
-}
-
-func (ev *Event) isRDSInstanceDeleted(name *string) bool {
-	svc := ev.getRDSClient()
-
-	req := &rds.DescribeDBInstancesInput{
-		DBInstanceIdentifier: name,
-	}
-
-	resp, err := svc.DescribeDBInstances(req)
-	if err != nil {
-		return true
-	}
-
-	if len(resp.DBInstances) != 1 {
-		return true
-	}
-
-	return false
-}
-
 func (ev *Event) getRDSClient() *rds.RDS {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return rds.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
+	return ev.client().getRDSClient()
 }
 
-func createSubnetGroup(ev *Event) (*string, error) {
-	svc := ev.getRDSClient()
-
-	if len(ev.NetworkAWSIDs) < 1 {
-		return nil, nil
-	}
-
-	req := &rds.CreateDBSubnetGroupInput{
-		DBSubnetGroupDescription: aws.String(*ev.Name + "-sg"),
-		DBSubnetGroupName:        aws.String(*ev.Name + "-sg"),
-		SubnetIds:                ev.NetworkAWSIDs,
-	}
-
-	_, err := svc.CreateDBSubnetGroup(req)
-
-	return req.DBSubnetGroupName, err
+func (ev *Event) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(ev.Subject, ev.ComponentID), ev.client().Config())
 }
 
-func updateSubnetGroup(ev *Event) error {
-	svc := ev.getRDSClient()
-
-	if len(ev.NetworkAWSIDs) < 1 {
-		return nil
-	}
-
-	sg, err := getSubnetGroup(ev)
-	if err != nil {
-		return err
-	}
-
-	if subnetsHaveChanged(ev.NetworkAWSIDs, sg.Subnets) != true {
-		return nil
-	}
-
-	req := &rds.ModifyDBSubnetGroupInput{
-		DBSubnetGroupName:        aws.String(*ev.Name + "-sg"),
-		DBSubnetGroupDescription: aws.String(*ev.Name + "-sg"),
-		SubnetIds:                ev.NetworkAWSIDs,
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
 	}
-
-	_, err = svc.ModifyDBSubnetGroup(req)
-
-	return err
-}
-
-func subnetsHaveChanged(ids []*string, subnets []*rds.Subnet) bool {
-	var sids []*string
-	for _, s := range subnets {
-		sids = append(sids, s.SubnetIdentifier)
-	}
-
-	if len(ids) != len(sids) {
-		return true
-	}
-
-	idsx := ptrSliceToStrSlice(ids)
-	sidsx := ptrSliceToStrSlice(sids)
-	idsx.Sort()
-	sidsx.Sort()
-
-	return !reflect.DeepEqual(idsx, sidsx)
-}
-
-func getSubnetGroup(ev *Event) (*rds.DBSubnetGroup, error) {
-	svc := ev.getRDSClient()
-
-	req := &rds.DescribeDBSubnetGroupsInput{
-		DBSubnetGroupName: aws.String(*ev.Name + "-sg"),
-	}
-
-	resp, err := svc.DescribeDBSubnetGroups(req)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.DBSubnetGroups) < 1 {
-		return nil, err
-	}
-
-	return resp.DBSubnetGroups[0], nil
-}
-
-func deleteSubnetGroup(ev *Event) error {
-	svc := ev.getRDSClient()
-
-	req := &rds.DeleteDBSubnetGroupInput{
-		DBSubnetGroupName: aws.String(*ev.Name + "-sg"),
-	}
-
-	_, err := svc.DeleteDBSubnetGroup(req)
-
-	return err
-}
-
-func (ev *Event) setTags() error {
-	svc := ev.getRDSClient()
-
-	req := &rds.AddTagsToResourceInput{
-		ResourceName: ev.ARN,
-	}
-
-	for key, val := range ev.Tags {
-		req.Tags = append(req.Tags, &rds.Tag{
-			Key:   &key,
-			Value: &val,
-		})
-	}
-
-	_, err := svc.AddTagsToResource(req)
-
-	return err
 }
 
-func ptrSliceToStrSlice(s []*string) sort.StringSlice {
-	var ds sort.StringSlice
-	for _, str := range s {
-		ds = append(ds, *str)
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:              aws.StringValue(ev.Name),
+		Size:              aws.StringValue(ev.Size),
+		Engine:            aws.StringValue(ev.Engine),
+		EngineVersion:     aws.StringValue(ev.EngineVersion),
+		Port:              aws.Int64Value(ev.Port),
+		Cluster:           aws.StringValue(ev.Cluster),
+		Public:            ev.Public,
+		MultiAZ:           ev.MultiAZ,
+		PromotionTier:     ev.PromotionTier,
+		StorageType:       aws.StringValue(ev.StorageType),
+		StorageSize:       aws.Int64Value(ev.StorageSize),
+		StorageIops:       aws.Int64Value(ev.StorageIops),
+		AvailabilityZone:  aws.StringValue(ev.AvailabilityZone),
+		SecurityGroupIDs:  aws.StringValueSlice(ev.SecurityGroupAWSIDs),
+		NetworkIDs:        aws.StringValueSlice(ev.NetworkAWSIDs),
+		DatabaseName:      aws.StringValue(ev.DatabaseName),
+		DatabaseUsername:  aws.StringValue(ev.DatabaseUsername),
+		DatabasePassword:  aws.StringValue(ev.DatabasePassword),
+		AutoUpgrade:       ev.AutoUpgrade,
+		BackupRetention:   ev.BackupRetention,
+		BackupWindow:      aws.StringValue(ev.BackupWindow),
+		MaintenanceWindow: aws.StringValue(ev.MaintenanceWindow),
+		ReplicationSource: aws.StringValue(ev.ReplicationSource),
+		License:           aws.StringValue(ev.License),
+		Timezone:          aws.StringValue(ev.Timezone),
+		Tags:              ev.Tags,
 	}
-	return ds
 }
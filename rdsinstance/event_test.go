@@ -0,0 +1,196 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdsinstance
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+// setup : creates a vpc with two networks and a security group
+func setup(t *testing.T, b *awsfake.Backend) map[string]string {
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	for i, name := range []string{"neta", "netb"} {
+		var out ec2.CreateSubnetOutput
+		if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(ids["vpc"]), CidrBlock: aws.String(fmt.Sprintf("10.0.%d.0/24", i))}, &out); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = *out.Subnet.SubnetId
+	}
+
+	var sg ec2.CreateSecurityGroupOutput
+	if err := b.EC2.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{VpcId: aws.String(ids["vpc"]), GroupName: aws.String("db"), Description: aws.String("db")}, &sg); err != nil {
+		t.Fatal(err)
+	}
+	ids["sg"] = *sg.GroupId
+
+	return ids
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "rds_instance.create.aws",
+			body:     `{"name":"db","size":"db.t2.micro","engine":"mysql","storage_size":20,"database_username":"root","database_password":"secret","network_aws_ids":["$neta","$netb"],"security_group_aws_ids":["$sg"],"final_snapshot":false,"tags":{"Name":"db","Env":"test"}}`,
+			expected: "rds_instance.create.aws.done",
+			check: func(res map[string]interface{}) bool {
+				i := b.RDS.DBInstances["db"]
+				return i != nil && res["arn"] == *i.DBInstanceArn && res["endpoint"] != nil && len(b.RDS.Tags[*i.DBInstanceArn]) == 2 && len(b.RDS.DBSubnetGroups["db-sg"].Subnets) == 2
+			},
+		},
+		{
+			name:     "create a replica",
+			subject:  "rds_instance.create.aws",
+			body:     `{"name":"db-replica","size":"db.t2.micro","engine":"mysql","replication_source":"db","network_aws_ids":["$neta","$netb"],"final_snapshot":false,"tags":{"Name":"db-replica"}}`,
+			expected: "rds_instance.create.aws.done",
+			check: func(res map[string]interface{}) bool {
+				i := b.RDS.DBInstances["db-replica"]
+				return i != nil && *i.ReadReplicaSourceDBInstanceIdentifier == "db"
+			},
+		},
+		{
+			name:     "update",
+			subject:  "rds_instance.update.aws",
+			body:     `{"name":"db","size":"db.t2.large","engine":"mysql","storage_size":40,"network_aws_ids":["$netb"],"security_group_aws_ids":["$sg"],"final_snapshot":false,"tags":{"Name":"db"}}`,
+			expected: "rds_instance.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				i := b.RDS.DBInstances["db"]
+				return *i.DBInstanceClass == "db.t2.large" && *i.AllocatedStorage == 40 && len(b.RDS.DBSubnetGroups["db-sg"].Subnets) == 1
+			},
+		},
+		{
+			name:     "find",
+			subject:  "rds_instance.find.aws",
+			body:     `{"tags":{"Env":"test"}}`,
+			expected: "rds_instance.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				i := found[0].(map[string]interface{})
+				return i["name"] == "db" && i["size"] == "db.t2.large" && len(i["network_aws_ids"].([]interface{})) == 1
+			},
+		},
+		{
+			name:     "delete the replica",
+			subject:  "rds_instance.delete.aws",
+			body:     `{"name":"db-replica","size":"db.t2.micro","engine":"mysql","final_snapshot":false}`,
+			expected: "rds_instance.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return b.RDS.DBInstances["db-replica"] == nil && b.RDS.DBSubnetGroups["db-replica-sg"] == nil
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "rds_instance.delete.aws",
+			body:     `{"name":"db","size":"db.t2.large","engine":"mysql","final_snapshot":true}`,
+			expected: "rds_instance.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.RDS.DBInstances) == 0 && len(b.RDS.DBSubnetGroups) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails creating the subnet group",
+			operation: "CreateDBSubnetGroup",
+			subject:   "rds_instance.create.aws",
+			body:      `{"name":"db","size":"db.t2.micro","engine":"mysql","network_aws_ids":["$neta"],"final_snapshot":false}`,
+		},
+		{
+			name:      "create fails",
+			operation: "CreateDBInstance",
+			subject:   "rds_instance.create.aws",
+			body:      `{"name":"db","size":"db.t2.micro","engine":"mysql","network_aws_ids":["$neta"],"final_snapshot":false}`,
+		},
+		{
+			name:      "create fails tagging the instance",
+			operation: "AddTagsToResource",
+			subject:   "rds_instance.create.aws",
+			body:      `{"name":"db","size":"db.t2.micro","engine":"mysql","final_snapshot":false,"tags":{"Name":"db"}}`,
+		},
+		{
+			name:      "update fails",
+			operation: "ModifyDBInstance",
+			subject:   "rds_instance.update.aws",
+			body:      `{"name":"db","size":"db.t2.large","engine":"mysql","final_snapshot":false}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteDBInstance",
+			subject:   "rds_instance.delete.aws",
+			body:      `{"name":"db","size":"db.t2.micro","engine":"mysql","final_snapshot":false}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := setup(t, b)
+			b.Fail("rds", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdsinstance
+
+import (
+	"strings"
+
+	"github.com/ernestio/ernestaws/quota"
+)
+
+// preflight : checks the account limits before creating the rds instance
+func (ev *Event) preflight() error {
+	if !quota.Enabled() || strings.Split(ev.Subject, ".")[1] != "create" {
+		return nil
+	}
+
+	return quota.DBInstances(ev.getRDSClient(), 1)
+}
@@ -5,14 +5,15 @@
 package rdsinstance
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/rds"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -50,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -106,46 +117,32 @@ func (col *Collection) Delete() error {
 	return errors.New(col.Subject + " not supported")
 }
 
-// Find : Find rds clusters on aws
+// Find : Find rds instances on aws
 func (col *Collection) Find() error {
-	svc := col.getRDSClient()
-
-	resp, err := svc.DescribeDBInstances(nil)
+	instances, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, i := range resp.DBInstances {
-		tags, err := getInstanceTagDescriptions(svc, i.DBInstanceArn)
-		if err != nil {
-			return err
-		}
-
-		e := toEvent(i, tags)
-		if tagsMatch(col.Tags, e.Tags) {
-			col.Results = append(col.Results, e)
-		}
+	for _, st := range instances {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getRDSClient() *rds.RDS {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return rds.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func tagsMatch(qt, rt map[string]string) bool {
-	for k, v := range qt {
-		if rt[k] != v {
-			return false
-		}
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
 	}
-
-	return true
 }
 
 func mapFilters(tags map[string]string) []*rds.Filter {
@@ -161,81 +158,40 @@ func mapFilters(tags map[string]string) []*rds.Filter {
 	return f
 }
 
-func mapRDSTags(input []*rds.Tag) map[string]string {
-	t := make(map[string]string)
-
-	for _, tag := range input {
-		t[*tag.Key] = *tag.Value
-	}
-
-	return t
-}
-
-func getInstanceTagDescriptions(svc *rds.RDS, name *string) ([]*rds.Tag, error) {
-	treq := &rds.ListTagsForResourceInput{
-		ResourceName: name,
-	}
-
-	resp, err := svc.ListTagsForResource(treq)
-
-	return resp.TagList, err
-}
-
-func mapSubnetGroups(subnetgroup *rds.DBSubnetGroup) []*string {
-	var sids []*string
-
-	for _, s := range subnetgroup.Subnets {
-		sids = append(sids, s.SubnetIdentifier)
-	}
-
-	return sids
-}
-
-func mapRDSSecurityGroups(sgroups []*rds.VpcSecurityGroupMembership) []*string {
-	var sgs []*string
-
-	for _, s := range sgroups {
-		sgs = append(sgs, s.VpcSecurityGroupId)
-	}
-
-	return sgs
-}
-
-// ToEvent converts an rds instance object to an ernest event
-func toEvent(i *rds.DBInstance, tags []*rds.Tag) *Event {
-	e := &Event{
+// toEvent converts a database instance status to an ernest event
+func toEvent(st Status) *Event {
+	return &Event{
 		ProviderType:        "aws",
 		ComponentType:       "rds_instance",
-		ComponentID:         "rds_instance::" + *i.DBInstanceIdentifier,
-		ARN:                 i.DBInstanceArn,
-		Name:                i.DBInstanceIdentifier,
-		Size:                i.DBInstanceClass,
-		Endpoint:            i.Endpoint.Address,
-		Port:                i.Endpoint.Port,
-		Engine:              i.Engine,
-		EngineVersion:       i.EngineVersion,
-		Public:              i.PubliclyAccessible,
-		MultiAZ:             i.MultiAZ,
-		PromotionTier:       i.PromotionTier,
-		AutoUpgrade:         i.AutoMinorVersionUpgrade,
-		Cluster:             i.DBClusterIdentifier,
-		DatabaseName:        i.DBName,
-		DatabaseUsername:    i.MasterUsername,
-		StorageIops:         i.Iops,
-		StorageType:         i.StorageType,
-		StorageSize:         i.AllocatedStorage,
-		BackupRetention:     i.BackupRetentionPeriod,
-		BackupWindow:        i.PreferredBackupWindow,
-		MaintenanceWindow:   i.PreferredMaintenanceWindow,
-		ReplicationSource:   i.ReadReplicaSourceDBInstanceIdentifier,
-		License:             i.LicenseModel,
-		Timezone:            i.Timezone,
-		SecurityGroupAWSIDs: mapRDSSecurityGroups(i.VpcSecurityGroups),
-		NetworkAWSIDs:       mapSubnetGroups(i.DBSubnetGroup),
-		AvailabilityZone:    i.AvailabilityZone,
+		ComponentID:         "rds_instance::" + st.Name,
+		ARN:                 aws.String(st.ARN),
+		Name:                aws.String(st.Name),
+		Size:                aws.String(st.Size),
+		Endpoint:            optional(st.Endpoint),
+		Port:                optionalInt(st.Port),
+		Engine:              aws.String(st.Engine),
+		EngineVersion:       optional(st.EngineVersion),
+		Public:              aws.Bool(st.Public),
+		MultiAZ:             aws.Bool(st.MultiAZ),
+		PromotionTier:       aws.Int64(st.PromotionTier),
+		AutoUpgrade:         aws.Bool(st.AutoUpgrade),
+		Cluster:             optional(st.Cluster),
+		DatabaseName:        optional(st.DatabaseName),
+		DatabaseUsername:    optional(st.DatabaseUsername),
+		StorageIops:         optionalInt(st.StorageIops),
+		StorageType:         optional(st.StorageType),
+		StorageSize:         optionalInt(st.StorageSize),
+		BackupRetention:     aws.Int64(st.BackupRetention),
+		BackupWindow:        optional(st.BackupWindow),
+		MaintenanceWindow:   optional(st.MaintenanceWindow),
+		ReplicationSource:   optional(st.ReplicationSource),
+		License:             optional(st.License),
+		Timezone:            optional(st.Timezone),
+		SecurityGroupAWSIDs: aws.StringSlice(st.SecurityGroupIDs),
+		NetworkAWSIDs:       aws.StringSlice(st.NetworkIDs),
+		AvailabilityZone:    optional(st.AvailabilityZone),
 		// FinalSnapshot: i. Cannot be inferred ....
 
-		Tags: mapRDSTags(tags),
+		Tags: st.Tags,
 	}
-	return e
 }
@@ -0,0 +1,54 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdsinstance
+
+import "github.com/ernestio/ernestaws/schema"
+
+var database = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "size", Type: schema.String, Required: true},
+	{Name: "engine", Type: schema.String, Required: true},
+	{Name: "engine_version", Type: schema.String},
+	{Name: "port", Type: schema.Integer, Minimum: schema.Int(1150), Maximum: schema.Int(65535)},
+	{Name: "cluster", Type: schema.String},
+	{Name: "public", Type: schema.Boolean},
+	{Name: "multi_az", Type: schema.Boolean},
+	{Name: "promotion_tier", Type: schema.Integer, Minimum: schema.Int(0), Maximum: schema.Int(15)},
+	{Name: "storage_type", Type: schema.String, Enum: []string{"standard", "gp2", "io1"}},
+	{Name: "storage_size", Type: schema.Integer, Minimum: schema.Int(5), Maximum: schema.Int(16384)},
+	{Name: "storage_iops", Type: schema.Integer, Minimum: schema.Int(1000)},
+	{Name: "availability_zone", Type: schema.String},
+	{Name: "security_group_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "network_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "database_name", Type: schema.String},
+	{Name: "database_username", Type: schema.String},
+	{Name: "database_password", Type: schema.String},
+	{Name: "auto_upgrade", Type: schema.Boolean},
+	{Name: "backup_retention", Type: schema.Integer, Minimum: schema.Int(0), Maximum: schema.Int(35)},
+	{Name: "backup_window", Type: schema.String},
+	{Name: "maintenance_window", Type: schema.String},
+	{Name: "final_snapshot", Type: schema.Boolean},
+	{Name: "replication_source", Type: schema.String},
+	{Name: "license", Type: schema.String},
+	{Name: "timezone", Type: schema.String},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("rds_instance", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), database),
+		"update": schema.Fields(schema.Datacenter(), database),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "name", Type: schema.String, Required: true},
+			{Name: "size", Type: schema.String, Required: true},
+			{Name: "engine", Type: schema.String, Required: true},
+			{Name: "cluster", Type: schema.String},
+			{Name: "final_snapshot", Type: schema.Boolean, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
@@ -0,0 +1,30 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package rdsinstance
+
+import (
+	"strings"
+
+	"github.com/ernestio/ernestaws/verify"
+)
+
+// verify : checks the event against the live aws metadata
+func (ev *Event) verify() error {
+	if !verify.Enabled() {
+		return nil
+	}
+
+	var c verify.Check
+
+	switch strings.Split(ev.Subject, ".")[1] {
+	case "create":
+		c.AvailabilityZone(ev.getEC2Client(), "$.availability_zone", ev.AvailabilityZone)
+		c.EngineVersion(ev.getRDSClient(), "$.engine_version", ev.Engine, ev.EngineVersion)
+	case "update":
+		c.EngineVersion(ev.getRDSClient(), "$.engine_version", ev.Engine, ev.EngineVersion)
+	}
+
+	return c.Err()
+}
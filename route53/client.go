@@ -0,0 +1,349 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package route53
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/ernestio/ernestaws/client"
+	uuid "github.com/satori/go.uuid"
+)
+
+// RecordSet describes the values of a record of a zone. A zero ttl
+// leaves it unset
+type RecordSet struct {
+	Entry  string
+	Type   string
+	Values []string
+	TTL    int64
+}
+
+// Spec describes the desired state of a hosted zone. Private zones are
+// associated with the vpc on the region of the account
+type Spec struct {
+	Name    string
+	Private bool
+	VpcID   string
+	Records []RecordSet
+	Tags    map[string]string
+}
+
+// Status describes a hosted zone, without its default SOA and NS
+// records. Create and Update report it as set from the spec, without
+// describing it again
+type Status struct {
+	ID      string
+	Name    string
+	Private bool
+	Records []RecordSet
+	Tags    map[string]string
+}
+
+// Client manages hosted zones through a typed api, the json events are
+// an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a hosted zone with its records
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getRoute53Client()
+
+	uid, _ := uuid.NewV4()
+	req := &route53.CreateHostedZoneInput{
+		CallerReference: aws.String(uid.String()),
+		Name:            aws.String(s.Name),
+	}
+
+	if s.Private {
+		req.HostedZoneConfig = &route53.HostedZoneConfig{
+			PrivateZone: aws.Bool(true),
+		}
+		req.VPC = &route53.VPC{
+			VPCId:     aws.String(s.VpcID),
+			VPCRegion: aws.String(c.Region),
+		}
+	}
+
+	resp, err := svc.CreateHostedZoneWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return c.Update(ctx, aws.StringValue(resp.HostedZone.Id), s)
+}
+
+// Update : upserts the records of the spec and removes the ones no
+// longer defined, keeping the default SOA and NS records
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getRoute53Client()
+
+	zr, err := getZoneRecords(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	changes := buildChanges(s, zr)
+
+	if len(changes) > 0 {
+		req := &route53.ChangeResourceRecordSetsInput{
+			ChangeBatch: &route53.ChangeBatch{
+				Changes: changes,
+			},
+			HostedZoneId: aws.String(id),
+		}
+
+		_, err = svc.ChangeResourceRecordSetsWithContext(ctx, req)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return Status{
+		ID:      id,
+		Name:    s.Name,
+		Private: s.Private,
+		Records: s.Records,
+		Tags:    s.Tags,
+	}, nil
+}
+
+// Delete : removes the records of a hosted zone and deletes it
+func (c Client) Delete(ctx context.Context, id, name string) error {
+	// clear ruleset before delete
+	_, err := c.Update(ctx, id, Spec{Name: name})
+	if err != nil {
+		return err
+	}
+
+	req := &route53.DeleteHostedZoneInput{
+		Id: aws.String(id),
+	}
+
+	_, err = c.getRoute53Client().DeleteHostedZoneWithContext(ctx, req)
+
+	return err
+}
+
+// Find : returns the hosted zones matching all the tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getRoute53Client()
+
+	resp, err := svc.ListHostedZonesWithContext(ctx, &route53.ListHostedZonesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []Status
+
+	for _, z := range resp.HostedZones {
+		zt, err := getZoneTagDescriptions(ctx, svc, aws.StringValue(z.Id))
+		if err != nil {
+			return nil, err
+		}
+
+		records, err := getZoneRecords(ctx, svc, aws.StringValue(z.Id))
+		if err != nil {
+			return nil, err
+		}
+
+		st := toStatus(z, records, zt)
+
+		if tagsMatch(tags, st.Tags) {
+			zones = append(zones, st)
+		}
+	}
+
+	return zones, nil
+}
+
+func (c Client) getRoute53Client() *route53.Route53 {
+	return route53.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) setTags(ctx context.Context, svc *route53.Route53, id string, tags map[string]string) error {
+	req := &route53.ChangeTagsForResourceInput{
+		ResourceId:   aws.String(id),
+		ResourceType: aws.String("hostedzone"),
+	}
+
+	for key, val := range tags {
+		req.AddTags = append(req.AddTags, &route53.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+	}
+
+	_, err := svc.ChangeTagsForResourceWithContext(ctx, req)
+
+	return err
+}
+
+func getZoneRecords(ctx context.Context, svc *route53.Route53, id string) ([]*route53.ResourceRecordSet, error) {
+	zreq := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(id),
+	}
+
+	resp, err := svc.ListResourceRecordSetsWithContext(ctx, zreq)
+	if err != nil {
+		return []*route53.ResourceRecordSet{}, err
+	}
+
+	return resp.ResourceRecordSets, nil
+}
+
+func getZoneTagDescriptions(ctx context.Context, svc *route53.Route53, id string) ([]*route53.Tag, error) {
+	req := &route53.ListTagsForResourceInput{
+		ResourceId:   aws.String(id),
+		ResourceType: aws.String("hostedzone"),
+	}
+
+	resp, err := svc.ListTagsForResourceWithContext(ctx, req)
+	if err != nil {
+		return []*route53.Tag{}, err
+	}
+
+	return resp.ResourceTagSet.Tags, nil
+}
+
+func buildResourceRecords(values []string) []*route53.ResourceRecord {
+	var records []*route53.ResourceRecord
+
+	for _, v := range values {
+		records = append(records, &route53.ResourceRecord{
+			Value: aws.String(v),
+		})
+	}
+
+	return records
+}
+
+func isDefaultRule(name string, record *route53.ResourceRecordSet) bool {
+	return entryName(*record.Name) == entryName(name) && *record.Type == "SOA" ||
+		entryName(*record.Name) == entryName(name) && *record.Type == "NS"
+}
+
+// hasRecord : checks if the spec defines a record for the entry, with or
+// without the trailing dot
+func (s Spec) hasRecord(entry string) bool {
+	for _, record := range s.Records {
+		if entryName(record.Entry) == entryName(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildRecordsToRemove(s Spec, existing []*route53.ResourceRecordSet) []*route53.Change {
+	// Dont delete the default NS and SOA rules
+	// May conflict with non-default rules, needs testing
+
+	var missing []*route53.Change
+
+	for _, recordSet := range existing {
+
+		if s.hasRecord(*recordSet.Name) != true && isDefaultRule(s.Name, recordSet) != true {
+			missing = append(missing, &route53.Change{
+				Action:            aws.String("DELETE"),
+				ResourceRecordSet: recordSet,
+			})
+		}
+	}
+
+	return missing
+}
+
+func buildChanges(s Spec, existing []*route53.ResourceRecordSet) []*route53.Change {
+	var changes []*route53.Change
+
+	for _, record := range s.Records {
+		rs := &route53.ResourceRecordSet{
+			Name:            aws.String(record.Entry),
+			Type:            aws.String(record.Type),
+			ResourceRecords: buildResourceRecords(record.Values),
+		}
+
+		if record.TTL > 0 {
+			rs.TTL = aws.Int64(record.TTL)
+		}
+
+		changes = append(changes, &route53.Change{
+			Action:            aws.String("UPSERT"),
+			ResourceRecordSet: rs,
+		})
+	}
+
+	changes = append(changes, buildRecordsToRemove(s, existing)...)
+
+	return changes
+}
+
+func entryName(entry string) string {
+	if string(entry[len(entry)-1]) == "." {
+		return entry[:len(entry)-1]
+	}
+	return entry
+}
+
+func tagsMatch(qt, rt map[string]string) bool {
+	for k, v := range qt {
+		if rt[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mapRoute53Tags(input []*route53.Tag) map[string]string {
+	t := make(map[string]string)
+
+	for _, tag := range input {
+		t[*tag.Key] = *tag.Value
+	}
+
+	return t
+}
+
+func toStatus(z *route53.HostedZone, records []*route53.ResourceRecordSet, tags []*route53.Tag) Status {
+	st := Status{
+		ID:   aws.StringValue(z.Id),
+		Name: aws.StringValue(z.Name),
+		Tags: mapRoute53Tags(tags),
+	}
+
+	if z.Config != nil {
+		st.Private = aws.BoolValue(z.Config.PrivateZone)
+	}
+
+	for _, r := range records {
+		if isDefaultRule(st.Name, r) {
+			continue
+		}
+
+		rs := RecordSet{
+			Entry: aws.StringValue(r.Name),
+			Type:  aws.StringValue(r.Type),
+			TTL:   aws.Int64Value(r.TTL),
+		}
+
+		for _, v := range r.ResourceRecords {
+			rs.Values = append(rs.Values, aws.StringValue(v.Value))
+		}
+
+		st.Records = append(st.Records, rs)
+	}
+
+	return st
+}
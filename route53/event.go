@@ -5,17 +5,16 @@
 package route53
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
-	uuid "github.com/satori/go.uuid"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -58,6 +57,7 @@ type Event struct {
 	ComponentID      string            `json:"_component_id"`
 	State            string            `json:"_state"`
 	Action           string            `json:"_action"`
+	SchemaVersion    int               `json:"_schema_version"`
 	HostedZoneID     *string           `json:"hosted_zone_id"`
 	Name             *string           `json:"name"`
 	Private          *bool             `json:"private"`
@@ -101,6 +101,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -130,6 +135,10 @@ func (ev *Event) Complete() {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -152,76 +161,25 @@ func (ev *Event) Find() error {
 
 // Create : Creates a route53 object on aws
 func (ev *Event) Create() error {
-	svc := ev.getRoute53Client()
-
-	uid, _ := uuid.NewV4()
-	req := &route53.CreateHostedZoneInput{
-		CallerReference: aws.String(uid.String()),
-		Name:            ev.Name,
-	}
-
-	if *ev.Private == true {
-		req.HostedZoneConfig = &route53.HostedZoneConfig{
-			PrivateZone: ev.Private,
-		}
-		req.VPC = &route53.VPC{
-			VPCId:     ev.VpcID,
-			VPCRegion: aws.String(ev.DatacenterRegion),
-		}
-	}
-
-	resp, err := svc.CreateHostedZone(req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	ev.HostedZoneID = resp.HostedZone.Id
+	ev.HostedZoneID = aws.String(st.ID)
 
-	return ev.Update()
+	return nil
 }
 
 // Update : Updates a route53 object on aws
 func (ev *Event) Update() error {
-	svc := ev.getRoute53Client()
-
-	zr, err := ev.getZoneRecords()
-	if err != nil {
-		return err
-	}
-
-	req := &route53.ChangeResourceRecordSetsInput{
-		ChangeBatch: &route53.ChangeBatch{
-			Changes: ev.buildChanges(zr),
-		},
-		HostedZoneId: ev.HostedZoneID,
-	}
-
-	_, err = svc.ChangeResourceRecordSets(req)
-	if err != nil {
-		return err
-	}
-
-	return ev.setTags()
+	_, err := ev.client().Update(context.Background(), aws.StringValue(ev.HostedZoneID), ev.spec())
+	return err
 }
 
 // Delete : Deletes a route53 object on aws
 func (ev *Event) Delete() error {
-	// clear ruleset before delete
-	ev.Records = nil
-	err := ev.Update()
-	if err != nil {
-		return err
-	}
-
-	svc := ev.getRoute53Client()
-
-	req := &route53.DeleteHostedZoneInput{
-		Id: ev.HostedZoneID,
-	}
-
-	_, err = svc.DeleteHostedZone(req)
-
-	return err
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.HostedZoneID), aws.StringValue(ev.Name))
 }
 
 // Get : Gets a route53 object on aws
@@ -229,108 +187,38 @@ func (ev *Event) Get() error {
 	return errors.New(ev.Subject + " not supported")
 }
 
-func (ev *Event) getRoute53Client() *route53.Route53 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return route53.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
-}
-
-func (ev *Event) getZoneRecords() ([]*route53.ResourceRecordSet, error) {
-	svc := ev.getRoute53Client()
-
-	req := &route53.ListResourceRecordSetsInput{
-		HostedZoneId: ev.HostedZoneID,
-	}
-
-	resp, err := svc.ListResourceRecordSets(req)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp.ResourceRecordSets, nil
-}
-
-func (ev *Event) buildResourceRecords(values []*string) []*route53.ResourceRecord {
-	var records []*route53.ResourceRecord
-
-	for _, v := range values {
-		records = append(records, &route53.ResourceRecord{
-			Value: v,
-		})
-	}
-
-	return records
-}
-
-func isDefaultRule(name string, record *route53.ResourceRecordSet) bool {
-	return entryName(*record.Name) == entryName(name) && *record.Type == "SOA" ||
-		entryName(*record.Name) == entryName(name) && *record.Type == "NS"
-}
-
-func (ev *Event) buildRecordsToRemove(existing []*route53.ResourceRecordSet) []*route53.Change {
-	// Dont delete the default NS and SOA rules
-	// May conflict with non-default rules, needs testing
-
-	var missing []*route53.Change
-
-	for _, recordSet := range existing {
-
-		if ev.Records.HasRecord(*recordSet.Name) != true && isDefaultRule(*ev.Name, recordSet) != true {
-			missing = append(missing, &route53.Change{
-				Action:            aws.String("DELETE"),
-				ResourceRecordSet: recordSet,
-			})
-		}
-	}
-
-	return missing
-}
-
-func (ev *Event) buildChanges(existing []*route53.ResourceRecordSet) []*route53.Change {
-	var changes []*route53.Change
-
-	for _, record := range ev.Records {
-		changes = append(changes, &route53.Change{
-			Action: aws.String("UPSERT"),
-			ResourceRecordSet: &route53.ResourceRecordSet{
-				Name:            record.Entry,
-				Type:            record.Type,
-				TTL:             record.TTL,
-				ResourceRecords: ev.buildResourceRecords(record.Values),
-			},
-		})
-	}
-
-	changes = append(changes, ev.buildRecordsToRemove(existing)...)
-
-	return changes
-}
-
-func entryName(entry string) string {
-	if string(entry[len(entry)-1]) == "." {
-		return entry[:len(entry)-1]
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
 	}
-	return entry
 }
 
-func (ev *Event) setTags() error {
-	svc := ev.getRoute53Client()
-
-	req := &route53.ChangeTagsForResourceInput{
-		ResourceId:   ev.HostedZoneID,
-		ResourceType: aws.String("hostedzone"),
+func (ev *Event) spec() Spec {
+	s := Spec{
+		Name:    aws.StringValue(ev.Name),
+		Private: aws.BoolValue(ev.Private),
+		VpcID:   aws.StringValue(ev.VpcID),
+		Tags:    ev.Tags,
 	}
 
-	for key, val := range ev.Tags {
-		req.AddTags = append(req.AddTags, &route53.Tag{
-			Key:   &key,
-			Value: &val,
+	for _, r := range ev.Records {
+		s.Records = append(s.Records, RecordSet{
+			Entry:  aws.StringValue(r.Entry),
+			Type:   aws.StringValue(r.Type),
+			Values: aws.StringValueSlice(r.Values),
+			TTL:    aws.Int64Value(r.TTL),
 		})
 	}
 
-	_, err := svc.ChangeTagsForResource(req)
-
-	return err
+	return s
 }
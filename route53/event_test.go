@@ -0,0 +1,180 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package route53
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+// records : returns the record sets of a zone as name/type/values
+func records(b *awsfake.Backend, id string) []string {
+	var r []string
+
+	for _, rs := range b.Route53.Records[id] {
+		var values []string
+		for _, v := range rs.ResourceRecords {
+			values = append(values, *v.Value)
+		}
+		r = append(r, *rs.Name+"/"+*rs.Type+"/"+strings.Join(values, ","))
+	}
+
+	sort.Strings(r)
+
+	return r
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create a private zone with its records",
+			subject:  "route53.create.aws",
+			body:     `{"name":"example.com","private":true,"vpc_id":"$vpc","records":[{"entry":"www.example.com","type":"A","values":["10.0.1.11"],"ttl":60},{"entry":"db.example.com","type":"A","values":["10.0.1.12"],"ttl":60}],"tags":{"Name":"example"}}`,
+			expected: "route53.create.aws.done",
+			save:     map[string]string{"id": "hosted_zone_id"},
+			check: func(res map[string]interface{}) bool {
+				z := b.Route53.HostedZones[ids["id"]]
+				r := records(b, ids["id"])
+				return z != nil && *z.Config.PrivateZone && len(r) == 4 &&
+					r[0] == "db.example.com./A/10.0.1.12" && r[3] == "www.example.com./A/10.0.1.11"
+			},
+		},
+		{
+			name:     "update upserts and removes records",
+			subject:  "route53.update.aws",
+			body:     `{"name":"example.com","hosted_zone_id":"$id","records":[{"entry":"www.example.com","type":"A","values":["10.0.1.21","10.0.1.22"],"ttl":60}],"tags":{"Name":"example"}}`,
+			expected: "route53.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				r := records(b, ids["id"])
+				return len(r) == 3 && r[2] == "www.example.com./A/10.0.1.21,10.0.1.22"
+			},
+		},
+		{
+			name:     "find",
+			subject:  "route53.find.aws",
+			body:     `{"tags":{"Name":"example"}}`,
+			expected: "route53.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				z := found[0].(map[string]interface{})
+				r := z["records"].([]interface{})
+				return z["hosted_zone_id"] == ids["id"] && z["private"] == true && len(r) == 1
+			},
+		},
+		{
+			name:     "delete removes the records and the zone",
+			subject:  "route53.delete.aws",
+			body:     `{"name":"example.com","hosted_zone_id":"$id"}`,
+			expected: "route53.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return b.Route53.HostedZones[ids["id"]] == nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails",
+			operation: "CreateHostedZone",
+			subject:   "route53.create.aws",
+			body:      `{"name":"example.org","private":false,"records":[{"entry":"www.example.org","type":"A","values":["10.0.1.11"],"ttl":60}]}`,
+		},
+		{
+			name:      "create fails adding the records",
+			operation: "ChangeResourceRecordSets",
+			subject:   "route53.create.aws",
+			body:      `{"name":"example.org","private":false,"records":[{"entry":"www.example.org","type":"A","values":["10.0.1.11"],"ttl":60}]}`,
+		},
+		{
+			name:      "update fails tagging the zone",
+			operation: "ChangeTagsForResource",
+			subject:   "route53.update.aws",
+			body:      `{"name":"example.com","hosted_zone_id":"$id","records":[{"entry":"www.example.com","type":"A","values":["10.0.1.11"],"ttl":60}],"tags":{"Name":"example"}}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteHostedZone",
+			subject:   "route53.delete.aws",
+			body:      `{"name":"example.com","hosted_zone_id":"$id"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := map[string]string{}
+
+			_, res := awsfake.Run(t, New, "route53.create.aws", `{"name":"example.com","private":false,"records":[{"entry":"www.example.com","type":"A","values":["10.0.1.11"],"ttl":60}]}`, ids)
+			ids["id"], _ = res["hosted_zone_id"].(string)
+
+			b.Fail("route53", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
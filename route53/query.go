@@ -5,14 +5,14 @@
 package route53
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/route53"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -22,6 +22,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -50,6 +51,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -79,6 +85,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -106,129 +116,59 @@ func (col *Collection) Delete() error {
 	return errors.New(col.Subject + " not supported")
 }
 
-func (col *Collection) getRoute53Client() *route53.Route53 {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return route53.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
 // Find : Find route53 zones on aws
 func (col *Collection) Find() error {
-	svc := col.getRoute53Client()
-
-	resp, err := svc.ListHostedZones(nil)
+	zones, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, z := range resp.HostedZones {
-		tags, err := getZoneTagDescriptions(svc, z.Id)
-		if err != nil {
-			return err
-		}
-
-		records, err := getZoneRecords(svc, z.Id)
-		if err != nil {
-			return err
-		}
-
-		event := toEvent(z, records, tags)
-
-		if tagsMatch(col.Tags, event.Tags) {
-			col.Results = append(col.Results, event)
-		}
+	for _, st := range zones {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func tagsMatch(qt, rt map[string]string) bool {
-	for k, v := range qt {
-		if rt[k] != v {
-			return false
-		}
-	}
-
-	return true
-}
-
-func getZoneRecords(svc *route53.Route53, id *string) ([]*route53.ResourceRecordSet, error) {
-	zreq := &route53.ListResourceRecordSetsInput{
-		HostedZoneId: id,
-	}
-
-	resp, err := svc.ListResourceRecordSets(zreq)
-	if err != nil {
-		return []*route53.ResourceRecordSet{}, err
-	}
-
-	return resp.ResourceRecordSets, nil
-}
-
-func getZoneTagDescriptions(svc *route53.Route53, id *string) ([]*route53.Tag, error) {
-	req := &route53.ListTagsForResourceInput{
-		ResourceId:   id,
-		ResourceType: aws.String("hostedzone"),
-	}
-
-	resp, err := svc.ListTagsForResource(req)
-	if err != nil {
-		return []*route53.Tag{}, err
-	}
-
-	return resp.ResourceTagSet.Tags, nil
-}
-
-func mapRoute53Tags(input []*route53.Tag) map[string]string {
-	t := make(map[string]string)
-
-	for _, tag := range input {
-		t[*tag.Key] = *tag.Value
-	}
-
-	return t
-}
-
-func mapRecordValues(rv []*route53.ResourceRecord) []*string {
-	var values []*string
-
-	for _, v := range rv {
-		values = append(values, v.Value)
+// toEvent converts a hosted zone to an ernest event
+func toEvent(st Status) *Event {
+	e := &Event{
+		ProviderType:  "aws",
+		ComponentType: "route53",
+		ComponentID:   "route53::" + st.Name,
+		HostedZoneID:  aws.String(st.ID),
+		Name:          aws.String(st.Name),
+		Private:       aws.Bool(st.Private),
+		Tags:          st.Tags,
 	}
 
-	return values
-}
-
-func mapRoute53Records(name *string, records []*route53.ResourceRecordSet) []Record {
-	var zr []Record
-
-	for _, r := range records {
-		if isDefaultRule(*name, r) != true {
-			zr = append(zr, Record{
-				Entry:  r.Name,
-				Type:   r.Type,
-				TTL:    r.TTL,
-				Values: mapRecordValues(r.ResourceRecords),
-			})
+	for _, r := range st.Records {
+		record := Record{
+			Entry:  aws.String(r.Entry),
+			Type:   aws.String(r.Type),
+			Values: aws.StringSlice(r.Values),
 		}
-	}
 
-	return zr
-}
+		if r.TTL > 0 {
+			record.TTL = aws.Int64(r.TTL)
+		}
 
-// ToEvent converts an route53 instance object to an ernest event
-func toEvent(z *route53.HostedZone, records []*route53.ResourceRecordSet, tags []*route53.Tag) *Event {
-	e := &Event{
-		ProviderType:  "aws",
-		ComponentType: "route53",
-		ComponentID:   "route53::" + *z.Name,
-		HostedZoneID:  z.Id,
-		Name:          z.Name,
-		Private:       z.Config.PrivateZone,
-		Records:       mapRoute53Records(z.Name, records),
-		Tags:          mapRoute53Tags(tags),
+		e.Records = append(e.Records, record)
 	}
+
 	return e
 }
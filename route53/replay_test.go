@@ -0,0 +1,57 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package route53
+
+import (
+	"testing"
+
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/awsreplay"
+)
+
+const datacenter = `"datacenter_region":"eu-west-1","aws_access_key_id":"AKIAEXAMPLE","aws_secret_access_key":"secret"`
+
+// TestReplayRecordDeletions : replays the deletion of the records
+// missing on an update. The fixture is synthetic, recorded against the
+// awsfake backend rather than a real aws account, so its ids are
+// sequential
+func TestReplayRecordDeletions(t *testing.T) {
+	rec, err := awsreplay.New("testdata/route53-records.json", awsreplay.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Install()
+	defer rec.Stop()
+
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+	}{
+		{
+			name:    "deletes records missing on the zone",
+			subject: "route53.update.aws",
+			body:    `{` + datacenter + `,"name":"example.com","hosted_zone_id":"/hostedzone/Z00000001","records":[{"entry":"www.example.com","type":"A","values":["10.0.1.11"],"ttl":60}],"tags":{"Name":"example"}}`,
+		},
+		{
+			name:    "deletes all records before the zone",
+			subject: "route53.delete.aws",
+			body:    `{` + datacenter + `,"name":"example.com","hosted_zone_id":"/hostedzone/Z00000001"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		ev := New(tt.subject, []byte(tt.body), "")
+
+		subject, body := ernestaws.Handle(&ev)
+		if subject != tt.subject+".done" {
+			t.Fatalf("%s: expected %s.done, got %s: %s", tt.name, tt.subject, subject, body)
+		}
+	}
+
+	if unused := rec.Unused(); len(unused) > 0 {
+		t.Errorf("%d recorded requests were not sent, first: %s", len(unused), unused[0].Request.Body)
+	}
+}
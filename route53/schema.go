@@ -0,0 +1,44 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package route53
+
+import "github.com/ernestio/ernestaws/schema"
+
+var record = &schema.Field{
+	Type: schema.Object,
+	Fields: []schema.Field{
+		{Name: "entry", Type: schema.String, Required: true},
+		{Name: "type", Type: schema.String, Required: true, Enum: []string{"A", "AAAA", "CAA", "CNAME", "MX", "NAPTR", "NS", "PTR", "SOA", "SPF", "SRV", "TXT"}},
+		{Name: "values", Type: schema.Array, Required: true, MinItems: 1, Items: &schema.Field{Type: schema.String}},
+		{Name: "ttl", Type: schema.Integer, Minimum: schema.Int(0), Maximum: schema.Int(2147483647)},
+	},
+}
+
+var zone = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "vpc_id", Type: schema.String},
+	{Name: "records", Type: schema.Array, Items: record},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("route53", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), zone, []schema.Field{
+			{Name: "private", Type: schema.Boolean, Required: true},
+		}),
+		"update": schema.Fields(schema.Datacenter(), zone, []schema.Field{
+			{Name: "hosted_zone_id", Type: schema.String, Required: true},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "name", Type: schema.String, Required: true},
+			{Name: "hosted_zone_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("route53", 1, schema.VpcReference)
+}
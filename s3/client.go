@@ -0,0 +1,245 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Grant gives a permission on a bucket to a grantee, identified by its
+// canonical user id, email address or group uri depending on the type
+type Grant struct {
+	ID         string
+	Type       string
+	Permission string
+}
+
+// Spec describes the desired state of an s3 bucket. Grants are only
+// applied when no canned acl is set
+type Spec struct {
+	Name     string
+	ACL      string
+	Location string
+	Grants   []Grant
+	Tags     map[string]string
+}
+
+// Status describes an s3 bucket as it is on aws
+type Status struct {
+	Name     string
+	Location string
+	URI      string
+	Grants   []Grant
+	Tags     map[string]string
+}
+
+// Client manages s3 buckets through a typed api, the json events are an
+// adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates an s3 bucket
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getS3Client()
+
+	req := &s3.CreateBucketInput{
+		Bucket: aws.String(s.Name),
+	}
+
+	if s.ACL != "" {
+		req.ACL = aws.String(s.ACL)
+	}
+
+	if s.Location != "" {
+		req.CreateBucketConfiguration = &s3.CreateBucketConfiguration{
+			LocationConstraint: aws.String(s.Location),
+		}
+	}
+
+	resp, err := svc.CreateBucketWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := Status{
+		Name:     s.Name,
+		Location: s.Location,
+		URI:      aws.StringValue(resp.Location),
+		Grants:   s.Grants,
+		Tags:     s.Tags,
+	}
+
+	if len(s.Grants) < 1 {
+		return st, c.setTags(ctx, svc, s.Name, s.Tags)
+	}
+
+	_, err = c.Update(ctx, s.Name, s)
+
+	return st, err
+}
+
+// Update : sets the canned acl of an s3 bucket, or replaces its grants
+// when there is none, and its tags
+func (c Client) Update(ctx context.Context, name string, s Spec) (Status, error) {
+	svc := c.getS3Client()
+
+	req := &s3.PutBucketAclInput{
+		Bucket: aws.String(name),
+	}
+
+	if s.ACL != "" {
+		req.ACL = aws.String(s.ACL)
+	} else {
+		acl, err := svc.GetBucketAclWithContext(ctx, &s3.GetBucketAclInput{Bucket: aws.String(name)})
+		if err != nil {
+			return Status{}, err
+		}
+
+		req.AccessControlPolicy = &s3.AccessControlPolicy{
+			Grants: mapGrants(s.Grants),
+			Owner:  acl.Owner,
+		}
+	}
+
+	_, err := svc.PutBucketAclWithContext(ctx, req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := Status{
+		Name:     name,
+		Location: s.Location,
+		URI:      bucketURI(name),
+		Grants:   s.Grants,
+		Tags:     s.Tags,
+	}
+
+	return st, c.setTags(ctx, svc, name, s.Tags)
+}
+
+// Delete : deletes an s3 bucket
+func (c Client) Delete(ctx context.Context, name string) error {
+	svc := c.getS3Client()
+
+	req := &s3.DeleteBucketInput{
+		Bucket: aws.String(name),
+	}
+
+	_, err := svc.DeleteBucketWithContext(ctx, req)
+
+	return err
+}
+
+// Find : returns the s3 buckets on the region of the account matching
+// all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getS3Client()
+
+	resp, err := svc.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []Status
+
+	for _, b := range resp.Buckets {
+		location, err := getBucketLocation(ctx, svc, b.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if location == nil || *location != c.Region {
+			continue
+		}
+
+		btags, _ := getBucketTagDescriptions(ctx, svc, b.Name)
+
+		grants, err := getBucketPermissions(ctx, svc, b.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		st := Status{
+			Name:     aws.StringValue(b.Name),
+			Location: *location,
+			URI:      bucketURI(aws.StringValue(b.Name)),
+			Grants:   mapS3Grantees(grants),
+			Tags:     mapS3Tags(btags),
+		}
+
+		if tagsMatch(tags, st.Tags) {
+			buckets = append(buckets, st)
+		}
+	}
+
+	return buckets, nil
+}
+
+func (c Client) getS3Client() *s3.S3 {
+	return s3.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) setTags(ctx context.Context, svc *s3.S3, name string, tags map[string]string) error {
+	if len(tags) < 1 {
+		return nil
+	}
+
+	req := &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(name),
+		Tagging: &s3.Tagging{},
+	}
+
+	for key, val := range tags {
+		req.Tagging.TagSet = append(req.Tagging.TagSet, &s3.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+	}
+
+	_, err := svc.PutBucketTaggingWithContext(ctx, req)
+
+	return err
+}
+
+// mapGrants : builds the s3 grants, the grantee types accept both the
+// ernest and the aws names
+func mapGrants(grants []Grant) []*s3.Grant {
+	var gs []*s3.Grant
+
+	for _, g := range grants {
+		var grantee s3.Grantee
+
+		switch g.Type {
+		case "id", s3.TypeCanonicalUser:
+			grantee.Type = aws.String(s3.TypeCanonicalUser)
+			grantee.ID = aws.String(g.ID)
+		case "emailaddress", s3.TypeAmazonCustomerByEmail:
+			grantee.Type = aws.String(s3.TypeAmazonCustomerByEmail)
+			grantee.EmailAddress = aws.String(g.ID)
+		case "uri", s3.TypeGroup:
+			grantee.Type = aws.String(s3.TypeGroup)
+			grantee.URI = aws.String(g.ID)
+		}
+
+		gs = append(gs, &s3.Grant{
+			Grantee:    &grantee,
+			Permission: aws.String(g.Permission),
+		})
+	}
+
+	return gs
+}
+
+func bucketURI(name string) string {
+	return fmt.Sprintf("https://%s.s3.amazonaws.com", name)
+}
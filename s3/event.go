@@ -5,16 +5,16 @@
 package s3
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -42,6 +42,7 @@ type Event struct {
 	ComponentID      string            `json:"_component_id"`
 	State            string            `json:"_state"`
 	Action           string            `json:"_action"`
+	SchemaVersion    int               `json:"_schema_version"`
 	Name             *string           `json:"name"`
 	ACL              *string           `json:"acl"`
 	BucketLocation   *string           `json:"bucket_location"`
@@ -85,6 +86,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -114,6 +120,10 @@ func (ev *Event) Complete() {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
 	if ev.DatacenterRegion == "" {
 		return ErrDatacenterRegionInvalid
 	}
@@ -136,93 +146,26 @@ func (ev *Event) Find() error {
 
 // Create : Creates a nat object on aws
 func (ev *Event) Create() error {
-	s3client := ev.getS3Client()
-
-	params := &s3.CreateBucketInput{
-		Bucket: ev.Name,
-		ACL:    ev.ACL,
-		CreateBucketConfiguration: &s3.CreateBucketConfiguration{
-			LocationConstraint: ev.BucketLocation,
-		},
-	}
-
-	resp, err := s3client.CreateBucket(params)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
 
-	ev.BucketURI = resp.Location
+	ev.BucketURI = aws.String(st.URI)
 
-	if len(ev.Grantees) < 1 {
-		return ev.setTags()
-	}
-
-	return ev.Update()
+	return nil
 }
 
 // Update : Updates a nat object on aws
 func (ev *Event) Update() error {
-	s3client := ev.getS3Client()
-	params := &s3.PutBucketAclInput{
-		Bucket: ev.Name,
-	}
-
-	if stringEmpty(ev.ACL) != true {
-		params.ACL = ev.ACL
-	}
-
-	var grants []*s3.Grant
-
-	for _, g := range ev.Grantees {
-		var grantee s3.Grantee
-
-		switch *g.Type {
-		case "id", "CanonicalUser":
-			grantee.Type = aws.String(s3.TypeCanonicalUser)
-			grantee.ID = g.ID
-		case "emailaddress", "AmazonCustomerByEmail":
-			grantee.Type = aws.String(s3.TypeAmazonCustomerByEmail)
-			grantee.EmailAddress = g.ID
-		case "uri", "Group":
-			grantee.Type = aws.String(s3.TypeGroup)
-			grantee.URI = g.ID
-		}
-
-		grants = append(grants, &s3.Grant{
-			Grantee:    &grantee,
-			Permission: g.Permissions,
-		})
-	}
-
-	if stringEmpty(ev.ACL) {
-		grt, err := ev.getACL()
-		if err != nil {
-			return err
-		}
-
-		params.AccessControlPolicy = &s3.AccessControlPolicy{
-			Grants: grants,
-			Owner:  grt.Owner,
-		}
-	}
+	_, err := ev.client().Update(context.Background(), aws.StringValue(ev.Name), ev.spec())
 
-	_, err := s3client.PutBucketAcl(params)
-	if err != nil {
-		return err
-	}
-
-	return ev.setTags()
+	return err
 }
 
 // Delete : Deletes a nat object on aws
 func (ev *Event) Delete() error {
-	s3client := ev.getS3Client()
-	params := &s3.DeleteBucketInput{
-		Bucket: ev.Name,
-	}
-	_, err := s3client.DeleteBucket(params)
-
-	return err
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.Name))
 }
 
 // Get : Gets a nat object on aws
@@ -230,64 +173,37 @@ func (ev *Event) Get() error {
 	return errors.New(ev.Subject + " not supported")
 }
 
-func (ev *Event) getS3Client() *s3.S3 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	s3client := s3.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
-	return s3client
-}
-
-func (ev *Event) getACL() (*s3.GetBucketAclOutput, error) {
-	s3client := ev.getS3Client()
-
-	params := &s3.GetBucketAclInput{
-		Bucket: ev.Name,
-	}
-
-	resp, err := s3client.GetBucketAcl(params)
-	if err != nil {
-		return nil, err
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
 	}
-	return resp, nil
 }
 
-func (ev *Event) setTags() error {
-	svc := ev.getS3Client()
-
-	if len(ev.Tags) < 1 {
-		return nil
+func (ev *Event) spec() Spec {
+	s := Spec{
+		Name:     aws.StringValue(ev.Name),
+		ACL:      aws.StringValue(ev.ACL),
+		Location: aws.StringValue(ev.BucketLocation),
+		Tags:     ev.Tags,
 	}
 
-	req := &s3.PutBucketTaggingInput{
-		Bucket: ev.Name,
-	}
-
-	tags := s3.Tagging{}
-
-	for key, val := range ev.Tags {
-		tags.TagSet = append(tags.TagSet, &s3.Tag{
-			Key:   &key,
-			Value: &val,
+	for _, g := range ev.Grantees {
+		s.Grants = append(s.Grants, Grant{
+			ID:         aws.StringValue(g.ID),
+			Type:       aws.StringValue(g.Type),
+			Permission: aws.StringValue(g.Permissions),
 		})
 	}
 
-	req.Tagging = &tags
-
-	_, err := svc.PutBucketTagging(req)
-
-	return err
-}
-
-func stringEmpty(s *string) bool {
-	if s == nil {
-		return true
-	}
-
-	if *s == "" {
-		return true
-	}
-
-	return false
+	return s
 }
@@ -0,0 +1,142 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package s3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "s3.create.aws",
+			body:     `{"name":"assets","acl":"public-read","bucket_location":"us-east-1","tags":{"Name":"assets","env":"test"}}`,
+			expected: "s3.create.aws.done",
+			check: func(res map[string]interface{}) bool {
+				bucket := b.S3.Buckets["assets"]
+				return bucket != nil && len(bucket.Grants) == 2 && len(bucket.Tags) == 2 && *bucket.Tags[0].Key != *bucket.Tags[1].Key
+			},
+		},
+		{
+			name:     "update replaces the grants",
+			subject:  "s3.update.aws",
+			body:     `{"name":"assets","bucket_location":"us-east-1","grantees":[{"id":"http://acs.amazonaws.com/groups/global/AllUsers","type":"uri","permissions":"WRITE"}],"tags":{"Name":"assets"}}`,
+			expected: "s3.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				grants := b.S3.Buckets["assets"].Grants
+				return len(grants) == 1 && *grants[0].Permission == "WRITE"
+			},
+		},
+		{
+			name:     "find",
+			subject:  "s3.find.aws",
+			body:     `{"tags":{"Name":"assets"}}`,
+			expected: "s3.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				bucket := found[0].(map[string]interface{})
+				grantees, _ := bucket["grantees"].([]interface{})
+				return bucket["bucket_uri"] == "https://assets.s3.amazonaws.com" && len(grantees) == 1 && grantees[0].(map[string]interface{})["id"] == "http://acs.amazonaws.com/groups/global/AllUsers"
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "s3.delete.aws",
+			body:     `{"name":"assets"}`,
+			expected: "s3.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.S3.Buckets) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, nil)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails",
+			operation: "CreateBucket",
+			subject:   "s3.create.aws",
+			body:      `{"name":"assets"}`,
+		},
+		{
+			name:      "create fails setting the grants",
+			operation: "PutBucketAcl",
+			subject:   "s3.create.aws",
+			body:      `{"name":"assets","grantees":[{"id":"user","type":"id","permissions":"READ"}]}`,
+		},
+		{
+			name:      "update fails tagging the bucket",
+			operation: "PutBucketTagging",
+			subject:   "s3.update.aws",
+			body:      `{"name":"seed","acl":"private","tags":{"Name":"seed"}}`,
+		},
+		{
+			name:      "find fails reading the grants",
+			operation: "GetBucketAcl",
+			subject:   "s3.find.aws",
+			body:      `{}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteBucket",
+			subject:   "s3.delete.aws",
+			body:      `{"name":"seed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			awsfake.Run(t, New, "s3.create.aws", `{"name":"seed"}`, nil)
+			b.Fail("s3", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, nil)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
@@ -5,15 +5,15 @@
 package s3
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Collection ....
@@ -23,6 +23,7 @@ type Collection struct {
 	ComponentID        string            `json:"_component_id"`
 	State              string            `json:"_state"`
 	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
 	Service            string            `json:"service"`
 	AWSAccessKeyID     string            `json:"aws_access_key_id"`
 	AWSSecretAccessKey string            `json:"aws_secret_access_key"`
@@ -51,6 +52,11 @@ func (col *Collection) GetSubject() string {
 
 // Process : starts processing the current message
 func (col *Collection) Process() (err error) {
+	if col.Body, err = schema.Migrate(col.Subject, col.Body); err != nil {
+		col.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(col.Body, &col); err != nil {
 		col.Error(err)
 		return err
@@ -80,6 +86,10 @@ func (col *Collection) Complete() {
 
 // Validate checks if all criteria are met
 func (col *Collection) Validate() error {
+	if err := schema.Validate(col.Subject, col.Body); err != nil {
+		return err
+	}
+
 	if col.AWSAccessKeyID == "" || col.AWSSecretAccessKey == "" {
 		return ErrDatacenterCredentialsInvalid
 	}
@@ -109,50 +119,30 @@ func (col *Collection) Delete() error {
 
 // Find : Find s3 buckets on aws
 func (col *Collection) Find() error {
-	svc := col.getS3Client()
-
-	resp, err := svc.ListBuckets(nil)
+	buckets, err := col.client().Find(context.Background(), col.Tags)
 	if err != nil {
 		return err
 	}
 
-	for _, b := range resp.Buckets {
-		location, err := getBucketLocation(svc, b.Name)
-		if err != nil {
-			return err
-		}
-
-		if location == nil {
-			continue
-		}
-
-		if *location != col.DatacenterRegion {
-			continue
-		}
-
-		tags, _ := getBucketTagDescriptions(svc, b.Name)
-
-		grants, err := getBucketPermissions(svc, b.Name)
-		if err != nil {
-			return err
-		}
-
-		event := toEvent(b, grants, location, tags)
-
-		if tagsMatch(col.Tags, event.Tags) {
-			col.Results = append(col.Results, event)
-		}
+	for _, st := range buckets {
+		col.Results = append(col.Results, toEvent(st))
 	}
 
 	return nil
 }
 
-func (col *Collection) getS3Client() *s3.S3 {
-	creds, _ := credentials.NewStaticCredentials(col.AWSAccessKeyID, col.AWSSecretAccessKey, col.CryptoKey)
-	return s3.New(session.New(), &aws.Config{
-		Region:      aws.String(col.DatacenterRegion),
-		Credentials: creds,
-	})
+func (col *Collection) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          col.DatacenterRegion,
+			AccessKeyID:     col.AWSAccessKeyID,
+			SecretAccessKey: col.AWSSecretAccessKey,
+			CryptoKey:       col.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject: col.Subject,
+		},
+	}
 }
 
 func mapS3Tags(input []*s3.Tag) map[string]string {
@@ -175,75 +165,80 @@ func tagsMatch(qt, rt map[string]string) bool {
 	return true
 }
 
-func getBucketTagDescriptions(svc *s3.S3, name *string) ([]*s3.Tag, error) {
+func getBucketTagDescriptions(ctx context.Context, svc *s3.S3, name *string) ([]*s3.Tag, error) {
 	treq := &s3.GetBucketTaggingInput{
 		Bucket: name,
 	}
 
-	resp, err := svc.GetBucketTagging(treq)
+	resp, err := svc.GetBucketTaggingWithContext(ctx, treq)
 
 	return resp.TagSet, err
 }
 
-func getBucketPermissions(svc *s3.S3, name *string) ([]*s3.Grant, error) {
+func getBucketPermissions(ctx context.Context, svc *s3.S3, name *string) ([]*s3.Grant, error) {
 	req := &s3.GetBucketAclInput{
 		Bucket: name,
 	}
 
-	resp, err := svc.GetBucketAcl(req)
+	resp, err := svc.GetBucketAclWithContext(ctx, req)
 
 	return resp.Grants, err
 }
 
-func getBucketLocation(svc *s3.S3, name *string) (*string, error) {
+func getBucketLocation(ctx context.Context, svc *s3.S3, name *string) (*string, error) {
 	req := &s3.GetBucketLocationInput{
 		Bucket: name,
 	}
 
-	resp, err := svc.GetBucketLocation(req)
+	resp, err := svc.GetBucketLocationWithContext(ctx, req)
 
 	return resp.LocationConstraint, err
 }
 
-func mapS3Grantees(grantees []*s3.Grant) []Grantee {
-	var gs []Grantee
+// mapS3Grantees : returns the grants of a bucket, identifying each grantee
+// by the field its type uses
+func mapS3Grantees(grants []*s3.Grant) []Grant {
+	var gs []Grant
 
-	for _, g := range grantees {
-		grantee := Grantee{
-			ID:          g.Grantee.ID,
-			Type:        g.Grantee.Type,
-			Permissions: g.Permission,
+	for _, g := range grants {
+		grant := Grant{
+			ID:         aws.StringValue(g.Grantee.ID),
+			Type:       aws.StringValue(g.Grantee.Type),
+			Permission: aws.StringValue(g.Permission),
 		}
 
-		switch *g.Grantee.Type {
-		case "id":
-			grantee.ID = g.Grantee.ID
-		case "emailaddress":
-			grantee.ID = g.Grantee.EmailAddress
-		case "uri":
-			grantee.ID = g.Grantee.URI
+		switch grant.Type {
+		case s3.TypeAmazonCustomerByEmail:
+			grant.ID = aws.StringValue(g.Grantee.EmailAddress)
+		case s3.TypeGroup:
+			grant.ID = aws.StringValue(g.Grantee.URI)
 		}
 
-		gs = append(gs, grantee)
+		gs = append(gs, grant)
 	}
 
 	return gs
 }
 
-// ToEvent converts an s3 bucket object to an ernest event
-func toEvent(b *s3.Bucket, grants []*s3.Grant, location *string, tags []*s3.Tag) *Event {
-	uri := fmt.Sprintf("https://%s.s3.amazonaws.com", *b.Name)
-
+// toEvent converts an s3 bucket status to an ernest event
+func toEvent(st Status) *Event {
 	e := &Event{
 		ProviderType:   "aws",
 		ComponentType:  "s3",
-		ComponentID:    "s3::" + *b.Name,
-		Name:           b.Name,
-		Grantees:       mapS3Grantees(grants),
-		BucketLocation: location,
-		// ACL ???
-		BucketURI: &uri,
-		Tags:      mapS3Tags(tags),
+		ComponentID:    "s3::" + st.Name,
+		Name:           aws.String(st.Name),
+		BucketLocation: aws.String(st.Location),
+		BucketURI:      aws.String(st.URI),
+		Tags:           st.Tags,
 	}
+
+	for _, g := range st.Grants {
+		e.Grantees = append(e.Grantees, Grantee{
+			ID:          aws.String(g.ID),
+			Type:        aws.String(g.Type),
+			Permissions: aws.String(g.Permission),
+		})
+	}
+
 	return e
 }
@@ -0,0 +1,37 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package s3
+
+import "github.com/ernestio/ernestaws/schema"
+
+var grantee = &schema.Field{
+	Type: schema.Object,
+	Fields: []schema.Field{
+		{Name: "id", Type: schema.String, Required: true},
+		{Name: "type", Type: schema.String, Required: true, Enum: []string{"id", "CanonicalUser", "emailaddress", "AmazonCustomerByEmail", "uri", "Group"}},
+		{Name: "permissions", Type: schema.String, Required: true, Enum: []string{"FULL_CONTROL", "WRITE", "WRITE_ACP", "READ", "READ_ACP"}},
+	},
+}
+
+var bucket = []schema.Field{
+	{Name: "name", Type: schema.String, Required: true},
+	{Name: "acl", Type: schema.String, Enum: []string{"private", "public-read", "public-read-write", "aws-exec-read", "authenticated-read", "log-delivery-write"}},
+	{Name: "bucket_location", Type: schema.String, Format: schema.Region},
+	{Name: "grantees", Type: schema.Array, Items: grantee},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("s3", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), bucket),
+		"update": schema.Fields(schema.Datacenter(), bucket),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "name", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
@@ -0,0 +1,36 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package schema
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Field formats
+const (
+	CIDR   = "cidr"
+	IP     = "ip"
+	ARN    = "arn"
+	Region = "region"
+)
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-gov)?-[a-z]+-[0-9]$`)
+
+var formats = map[string]func(string) bool{
+	CIDR: func(s string) bool {
+		_, _, err := net.ParseCIDR(s)
+		return err == nil
+	},
+	IP: func(s string) bool {
+		return net.ParseIP(s) != nil
+	},
+	ARN: func(s string) bool {
+		parts := strings.SplitN(s, ":", 6)
+		return len(parts) == 6 && parts[0] == "arn" && parts[1] != "" && parts[2] != ""
+	},
+	Region: regionPattern.MatchString,
+}
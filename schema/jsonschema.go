@@ -0,0 +1,97 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package schema
+
+const draft = "http://json-schema.org/draft-04/schema#"
+
+// JSONSchema : exports the schema as a json schema document
+func (s *Schema) JSONSchema() map[string]interface{} {
+	doc := objectSchema(s.Fields)
+	doc["$schema"] = draft
+	doc["title"] = s.Component + "." + s.Action + ".aws"
+
+	return doc
+}
+
+// Export : exports all registered schemas as json schema documents, keyed
+// by subject
+func Export() map[string]interface{} {
+	docs := make(map[string]interface{})
+
+	for _, s := range All() {
+		docs[s.Component+"."+s.Action+".aws"] = s.JSONSchema()
+	}
+
+	return docs
+}
+
+func objectSchema(fields []Field) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+
+	for _, f := range fields {
+		props[f.Name] = f.jsonSchema()
+		if f.Required {
+			required = append(required, f.Name)
+		}
+	}
+
+	doc := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+
+	if len(required) > 0 {
+		doc["required"] = required
+	}
+
+	return doc
+}
+
+func (f *Field) jsonSchema() map[string]interface{} {
+	var doc map[string]interface{}
+
+	switch f.Type {
+	case Object:
+		doc = objectSchema(f.Fields)
+	case Map:
+		doc = map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": String},
+		}
+	case Array:
+		doc = map[string]interface{}{"type": Array}
+		if f.Items != nil {
+			doc["items"] = f.Items.jsonSchema()
+		}
+		if f.MinItems > 0 {
+			doc["minItems"] = f.MinItems
+		}
+	default:
+		doc = map[string]interface{}{"type": f.Type}
+	}
+
+	if f.Description != "" {
+		doc["description"] = f.Description
+	}
+
+	if f.Format != "" {
+		doc["format"] = f.Format
+	}
+
+	if len(f.Enum) > 0 {
+		doc["enum"] = f.Enum
+	}
+
+	if f.Minimum != nil {
+		doc["minimum"] = *f.Minimum
+	}
+
+	if f.Maximum != nil {
+		doc["maximum"] = *f.Maximum
+	}
+
+	return doc
+}
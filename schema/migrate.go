@@ -0,0 +1,111 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Version is the current version of the event bodies. Bodies without a
+// _schema_version are considered to be on the first version
+const Version = 2
+
+// VersionField is the body field storing the schema version
+const VersionField = "_schema_version"
+
+// Migration upgrades a decoded body to the next schema version
+type Migration func(body map[string]interface{}) error
+
+// migrations are keyed by component and the version they upgrade from
+var migrations = make(map[string]map[int]Migration)
+
+// RegisterMigration : adds a migration upgrading the bodies of a
+// component from the given version to the next one
+func RegisterMigration(component string, from int, m Migration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if migrations[component] == nil {
+		migrations[component] = make(map[int]Migration)
+	}
+
+	migrations[component][from] = m
+}
+
+// Migrate : upgrades the body to the current schema version, running
+// the migrations registered for the component of the subject in order.
+// Bodies that can't be decoded are returned untouched, so the error is
+// reported when they are processed
+func Migrate(subject string, body []byte) ([]byte, error) {
+	var data map[string]interface{}
+
+	if err := json.Unmarshal(body, &data); err != nil || data == nil {
+		return body, nil
+	}
+
+	version := 1
+
+	if v, ok := data[VersionField]; ok {
+		n, ok := v.(float64)
+		if !ok || n < 1 || n != float64(int(n)) {
+			return body, Errors{Violation{Path: "$." + VersionField, Message: fmt.Sprintf("%v is not a valid schema version", v)}}
+		}
+		version = int(n)
+
+		if version == Version {
+			return body, nil
+		}
+	}
+
+	if version > Version {
+		return body, Errors{Violation{Path: "$." + VersionField, Message: fmt.Sprintf("version %d is newer than the supported version %d", version, Version)}}
+	}
+
+	component := strings.Split(subject, ".")[0]
+
+	mu.RLock()
+	steps := migrations[component]
+	mu.RUnlock()
+
+	for ; version < Version; version++ {
+		m, ok := steps[version]
+		if !ok {
+			continue
+		}
+
+		if err := m(data); err != nil {
+			return body, err
+		}
+	}
+
+	data[VersionField] = Version
+
+	return json.Marshal(data)
+}
+
+// Rename : moves a field of a decoded body to a new name, for use on
+// migrations. Fields already set under the new name are kept
+func Rename(body map[string]interface{}, from, to string) {
+	v, ok := body[from]
+	if !ok {
+		return
+	}
+
+	delete(body, from)
+
+	if _, ok := body[to]; !ok {
+		body[to] = v
+	}
+}
+
+// VpcReference : migration moving the vpc referenced as vpc_aws_id, the
+// name of the field on vpc events, to vpc_id
+func VpcReference(body map[string]interface{}) error {
+	Rename(body, "vpc_aws_id", "vpc_id")
+
+	return nil
+}
@@ -0,0 +1,93 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package schema
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	mu      sync.RWMutex
+	schemas = make(map[string]*Schema)
+)
+
+// Register : adds the schemas of a component, keyed by action
+func Register(component string, actions map[string][]Field) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for action, fields := range actions {
+		schemas[component+"."+action] = &Schema{
+			Component: component,
+			Action:    action,
+			Fields:    fields,
+		}
+	}
+}
+
+// Get : returns the schema for the given subject, if any
+func Get(subject string) *Schema {
+	parts := strings.Split(subject, ".")
+	if len(parts) < 2 {
+		return nil
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return schemas[parts[0]+"."+parts[1]]
+}
+
+// Validate : validates the body against the schema registered for the
+// subject, subjects without a schema are always valid
+func Validate(subject string, body []byte) error {
+	s := Get(subject)
+	if s == nil {
+		return nil
+	}
+
+	return s.Validate(body)
+}
+
+// All : returns all registered schemas sorted by component and action
+func All() []*Schema {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var keys []string
+	for key := range schemas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var all []*Schema
+	for _, key := range keys {
+		all = append(all, schemas[key])
+	}
+
+	return all
+}
+
+// Datacenter : returns the fields shared by all component bodies
+func Datacenter() []Field {
+	return []Field{
+		{Name: "datacenter_region", Type: String, Required: true, Format: Region},
+		{Name: "aws_access_key_id", Type: String, Required: true},
+		{Name: "aws_secret_access_key", Type: String, Required: true},
+	}
+}
+
+// Fields : joins multiple field lists
+func Fields(lists ...[]Field) []Field {
+	var fields []Field
+
+	for _, l := range lists {
+		fields = append(fields, l...)
+	}
+
+	return fields
+}
@@ -0,0 +1,222 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Field types
+const (
+	String  = "string"
+	Integer = "integer"
+	Boolean = "boolean"
+	Array   = "array"
+	Object  = "object"
+	Map     = "map"
+)
+
+// Field describes a single field of an event body
+type Field struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+	Format      string
+	Enum        []string
+	Minimum     *int64
+	Maximum     *int64
+	MinItems    int
+	Items       *Field
+	Fields      []Field
+}
+
+// Schema describes the body of a component action
+type Schema struct {
+	Component string
+	Action    string
+	Fields    []Field
+}
+
+// Violation is a single validation failure on a field
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Errors stores all violations found on a body
+type Errors []Violation
+
+func (e Errors) Error() string {
+	var msgs []string
+
+	for _, v := range e {
+		msgs = append(msgs, v.String())
+	}
+
+	return "Validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Int : returns a pointer to the given value, for ranges
+func Int(v int64) *int64 {
+	return &v
+}
+
+// Validate : checks the body against the schema, returning all the
+// violations found
+func (s *Schema) Validate(body []byte) error {
+	var data map[string]interface{}
+
+	if err := json.Unmarshal(body, &data); err != nil {
+		return Errors{Violation{Path: "$", Message: err.Error()}}
+	}
+
+	errs := validateFields("$", s.Fields, data)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateFields(path string, fields []Field, data map[string]interface{}) Errors {
+	var errs Errors
+
+	for _, f := range fields {
+		errs = append(errs, f.validate(path+"."+f.Name, data[f.Name])...)
+	}
+
+	return errs
+}
+
+func (f *Field) validate(path string, v interface{}) Errors {
+	if isEmpty(v) {
+		if f.Required {
+			return Errors{Violation{Path: path, Message: "is required"}}
+		}
+		return nil
+	}
+
+	switch f.Type {
+	case String:
+		s, ok := v.(string)
+		if !ok {
+			return typeError(path, f.Type)
+		}
+		return f.validateString(path, s)
+	case Integer:
+		n, ok := v.(float64)
+		if !ok || n != float64(int64(n)) {
+			return typeError(path, f.Type)
+		}
+		return f.validateInteger(path, int64(n))
+	case Boolean:
+		if _, ok := v.(bool); !ok {
+			return typeError(path, f.Type)
+		}
+	case Array:
+		items, ok := v.([]interface{})
+		if !ok {
+			return typeError(path, f.Type)
+		}
+		return f.validateArray(path, items)
+	case Object:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return typeError(path, f.Type)
+		}
+		return validateFields(path, f.Fields, m)
+	case Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return typeError(path, "object")
+		}
+		for key, val := range m {
+			if _, ok := val.(string); !ok {
+				return typeError(path+"."+key, String)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *Field) validateString(path, s string) Errors {
+	if len(f.Enum) > 0 && !contains(f.Enum, s) {
+		return Errors{Violation{Path: path, Message: fmt.Sprintf("must be one of [%s]", strings.Join(f.Enum, ", "))}}
+	}
+
+	if f.Format != "" {
+		if check, ok := formats[f.Format]; ok && !check(s) {
+			return Errors{Violation{Path: path, Message: fmt.Sprintf("%q is not a valid %s", s, f.Format)}}
+		}
+	}
+
+	return nil
+}
+
+func (f *Field) validateInteger(path string, n int64) Errors {
+	if f.Minimum != nil && n < *f.Minimum {
+		return Errors{Violation{Path: path, Message: fmt.Sprintf("must be greater than or equal to %d", *f.Minimum)}}
+	}
+
+	if f.Maximum != nil && n > *f.Maximum {
+		return Errors{Violation{Path: path, Message: fmt.Sprintf("must be less than or equal to %d", *f.Maximum)}}
+	}
+
+	return nil
+}
+
+func (f *Field) validateArray(path string, items []interface{}) Errors {
+	var errs Errors
+
+	if len(items) < f.MinItems {
+		errs = append(errs, Violation{Path: path, Message: fmt.Sprintf("must contain at least %d items", f.MinItems)})
+	}
+
+	if f.Items == nil {
+		return errs
+	}
+
+	for i, item := range items {
+		ipath := fmt.Sprintf("%s[%d]", path, i)
+		if isEmpty(item) {
+			errs = append(errs, Violation{Path: ipath, Message: "is required"})
+			continue
+		}
+		errs = append(errs, f.Items.validate(ipath, item)...)
+	}
+
+	return errs
+}
+
+func typeError(path, t string) Errors {
+	return Errors{Violation{Path: path, Message: "must be of type " + t}}
+}
+
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	s, ok := v.(string)
+
+	return ok && s == ""
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
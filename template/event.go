@@ -10,6 +10,7 @@ import (
 	"log"
 
 	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 // Event stores the template data
@@ -19,6 +20,7 @@ type Event struct {
 	ComponentID   string `json:"_component_id"`
 	State         string `json:"_state"`
 	Action        string `json:"_action"`
+	SchemaVersion int    `json:"_schema_version"`
 	ErrorMessage  string `json:"error,omitempty"`
 	Subject       string `json:"-"`
 	Body          []byte `json:"-"`
@@ -48,6 +50,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -0,0 +1,230 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package verify
+
+import (
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// AvailabilityZone : checks the zone is available on the region of the client
+func (c *Check) AvailabilityZone(svc *ec2.EC2, path string, zone *string) {
+	if c.skip() || aws.StringValue(zone) == "" {
+		return
+	}
+
+	resp, err := svc.DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{})
+	if err != nil {
+		c.err = err
+		return
+	}
+
+	var zones []string
+
+	for _, z := range resp.AvailabilityZones {
+		if aws.StringValue(z.ZoneName) == *zone && aws.StringValue(z.State) == ec2.AvailabilityZoneStateAvailable {
+			return
+		}
+		zones = append(zones, aws.StringValue(z.ZoneName))
+	}
+
+	c.fail(path, "availability zone %s is not available on %s, use one of %s", *zone, aws.StringValue(svc.Config.Region), strings.Join(zones, ", "))
+}
+
+// Image : checks the ami exists and is available
+func (c *Check) Image(svc *ec2.EC2, path string, id *string) {
+	if c.skip() || aws.StringValue(id) == "" {
+		return
+	}
+
+	resp, err := svc.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: []*string{id},
+	})
+	if err != nil {
+		if isCode(err, "InvalidAMIID.") {
+			c.fail(path, "image %s does not exist on %s", *id, aws.StringValue(svc.Config.Region))
+			return
+		}
+		c.err = err
+		return
+	}
+
+	if len(resp.Images) < 1 {
+		c.fail(path, "image %s does not exist on %s", *id, aws.StringValue(svc.Config.Region))
+		return
+	}
+
+	if state := aws.StringValue(resp.Images[0].State); state != ec2.ImageStateAvailable {
+		c.fail(path, "image %s is %s", *id, state)
+	}
+}
+
+// KeyPair : checks the key pair exists
+func (c *Check) KeyPair(svc *ec2.EC2, path string, name *string) {
+	if c.skip() || aws.StringValue(name) == "" {
+		return
+	}
+
+	resp, err := svc.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		KeyNames: []*string{name},
+	})
+	if err != nil {
+		if isCode(err, "InvalidKeyPair.") {
+			c.fail(path, "key pair %s does not exist on %s", *name, aws.StringValue(svc.Config.Region))
+			return
+		}
+		c.err = err
+		return
+	}
+
+	if len(resp.KeyPairs) < 1 {
+		c.fail(path, "key pair %s does not exist on %s", *name, aws.StringValue(svc.Config.Region))
+	}
+}
+
+// InstanceType : checks the instance type is offered on the
+// availability zone of the subnet, reporting a missing subnet on the
+// subnet path
+func (c *Check) InstanceType(svc *ec2.EC2, path, subnetPath string, instanceType, subnetID *string) {
+	if c.skip() || aws.StringValue(instanceType) == "" || aws.StringValue(subnetID) == "" {
+		return
+	}
+
+	resp, err := svc.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: []*string{subnetID},
+	})
+	if err != nil {
+		if isCode(err, "InvalidSubnetID.") {
+			c.fail(subnetPath, "network %s not found", *subnetID)
+			return
+		}
+		c.err = err
+		return
+	}
+
+	if len(resp.Subnets) < 1 {
+		c.fail(subnetPath, "network %s not found", *subnetID)
+		return
+	}
+
+	zone := resp.Subnets[0].AvailabilityZone
+
+	offerings, err := svc.DescribeInstanceTypeOfferings(&ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(ec2.LocationTypeAvailabilityZone),
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-type"),
+				Values: []*string{instanceType},
+			},
+			{
+				Name:   aws.String("location"),
+				Values: []*string{zone},
+			},
+		},
+	})
+	if err != nil {
+		c.err = err
+		return
+	}
+
+	if len(offerings.InstanceTypeOfferings) < 1 {
+		c.fail(path, "instance type %s is not offered on %s", *instanceType, aws.StringValue(zone))
+	}
+}
+
+// SubnetCIDR : checks the range is inside the vpc ranges and doesn't
+// overlap any other subnet of the vpc
+func (c *Check) SubnetCIDR(svc *ec2.EC2, path, vpcID string, cidr *string) {
+	if c.skip() || vpcID == "" || aws.StringValue(cidr) == "" {
+		return
+	}
+
+	_, subnet, err := net.ParseCIDR(*cidr)
+	if err != nil {
+		// malformed ranges are reported by the schema validation
+		return
+	}
+
+	vpcs, err := svc.DescribeVpcs(&ec2.DescribeVpcsInput{
+		VpcIds: []*string{aws.String(vpcID)},
+	})
+	if err != nil || len(vpcs.Vpcs) < 1 {
+		c.err = err
+		return
+	}
+
+	var ranges []string
+
+	for _, a := range vpcs.Vpcs[0].CidrBlockAssociationSet {
+		if a.CidrBlockState != nil && aws.StringValue(a.CidrBlockState.State) != ec2.VpcCidrBlockStateCodeAssociated {
+			continue
+		}
+		ranges = append(ranges, aws.StringValue(a.CidrBlock))
+	}
+
+	if len(ranges) < 1 {
+		ranges = append(ranges, aws.StringValue(vpcs.Vpcs[0].CidrBlock))
+	}
+
+	inside := false
+	for _, r := range ranges {
+		if contains(r, subnet) {
+			inside = true
+		}
+	}
+
+	if !inside {
+		c.fail(path, "range %s is outside of the %s ranges (%s)", *cidr, vpcID, strings.Join(ranges, ", "))
+		return
+	}
+
+	subnets, err := svc.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
+	})
+	if err != nil {
+		c.err = err
+		return
+	}
+
+	for _, s := range subnets.Subnets {
+		_, sibling, err := net.ParseCIDR(aws.StringValue(s.CidrBlock))
+		if err != nil {
+			continue
+		}
+
+		if sibling.Contains(subnet.IP) || subnet.Contains(sibling.IP) {
+			c.fail(path, "range %s overlaps subnet %s (%s)", *cidr, aws.StringValue(s.SubnetId), aws.StringValue(s.CidrBlock))
+		}
+	}
+}
+
+// contains : checks the network is fully inside the cidr
+func contains(cidr string, n *net.IPNet) bool {
+	_, outer, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+
+	outerSize, _ := outer.Mask.Size()
+	size, _ := n.Mask.Size()
+
+	return outer.Contains(n.IP) && size >= outerSize
+}
+
+func isCode(err error, prefix string) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return strings.HasPrefix(aerr.Code(), prefix)
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package verify
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// EngineVersion : checks the engine version is offered by rds
+func (c *Check) EngineVersion(svc *rds.RDS, path string, engine, version *string) {
+	if c.skip() || aws.StringValue(engine) == "" || aws.StringValue(version) == "" {
+		return
+	}
+
+	resp, err := svc.DescribeDBEngineVersions(&rds.DescribeDBEngineVersionsInput{
+		Engine:        engine,
+		EngineVersion: version,
+	})
+	if err != nil {
+		if isCode(err, "InvalidParameter") {
+			c.fail(path, "engine %s is not offered by rds", *engine)
+			return
+		}
+		c.err = err
+		return
+	}
+
+	if len(resp.DBEngineVersions) < 1 {
+		c.fail(path, "version %s of %s is not offered on %s", *version, *engine, aws.StringValue(svc.Config.Region))
+	}
+}
@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package verify
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ernestio/ernestaws/schema"
+)
+
+var (
+	mu      sync.RWMutex
+	enabled bool
+)
+
+// Enable : turns the validation against live aws metadata on or off,
+// it is off by default
+func Enable(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	enabled = on
+}
+
+// Enabled : checks if the components should validate their events
+// against live aws metadata
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return enabled
+}
+
+// Check collects the violations found on an event. Once a call to aws
+// fails the remaining checks are skipped
+type Check struct {
+	violations schema.Errors
+	err        error
+}
+
+// Err : returns the aws error that stopped the checks, or the
+// violations found
+func (c *Check) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	if len(c.violations) > 0 {
+		return c.violations
+	}
+
+	return nil
+}
+
+func (c *Check) skip() bool {
+	return c.err != nil
+}
+
+func (c *Check) fail(path, format string, args ...interface{}) {
+	c.violations = append(c.violations, schema.Violation{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,419 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpc
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// Spec describes the desired state of a vpc. Attributes left as nil
+// keep their current value on updates, an empty list of secondary cidrs
+// removes all of them
+type Spec struct {
+	Name               string
+	CIDR               string
+	SecondaryCIDRs     []string
+	IPv6               *bool
+	EnableDNSSupport   *bool
+	EnableDNSHostnames *bool
+	InstanceTenancy    string
+	Tags               map[string]string
+}
+
+// Status describes a vpc as it is on aws
+type Status struct {
+	ID                 string
+	Name               string
+	CIDR               string
+	SecondaryCIDRs     []string
+	IPv6CIDR           string
+	EnableDNSSupport   bool
+	EnableDNSHostnames bool
+	InstanceTenancy    string
+	Tags               map[string]string
+}
+
+// Client manages vpcs through a typed api, the json events are an
+// adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a vpc
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	req := ec2.CreateVpcInput{
+		CidrBlock:                   aws.String(s.CIDR),
+		AmazonProvidedIpv6CidrBlock: s.IPv6,
+	}
+
+	if s.InstanceTenancy != "" {
+		req.InstanceTenancy = aws.String(s.InstanceTenancy)
+	}
+
+	resp, err := svc.CreateVpcWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	id := aws.StringValue(resp.Vpc.VpcId)
+
+	for _, cidr := range s.SecondaryCIDRs {
+		err = c.associateCIDR(ctx, svc, id, cidr)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.setAttributes(ctx, svc, id, s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st, err := c.get(ctx, svc, id)
+	st.Name = s.Name
+
+	return st, err
+}
+
+// Update : reconciles the dns attributes, tenancy, ipv6 block, secondary
+// cidr blocks and tags of a vpc with the spec
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	vpc, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if s.InstanceTenancy != "" && s.InstanceTenancy != aws.StringValue(vpc.InstanceTenancy) {
+		req := ec2.ModifyVpcTenancyInput{
+			VpcId:           aws.String(id),
+			InstanceTenancy: aws.String(s.InstanceTenancy),
+		}
+
+		_, err = svc.ModifyVpcTenancyWithContext(ctx, &req)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.setAttributes(ctx, svc, id, s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if s.SecondaryCIDRs != nil {
+		current := secondaryCIDRs(vpc)
+
+		for _, cidr := range s.SecondaryCIDRs {
+			if _, ok := current[cidr]; !ok {
+				err = c.associateCIDR(ctx, svc, id, cidr)
+				if err != nil {
+					return Status{}, err
+				}
+			}
+		}
+
+		for cidr, association := range current {
+			if !contains(s.SecondaryCIDRs, cidr) {
+				err = c.disassociateCIDR(ctx, svc, association)
+				if err != nil {
+					return Status{}, err
+				}
+			}
+		}
+	}
+
+	if s.IPv6 != nil {
+		association := ipv6Association(vpc)
+
+		switch {
+		case *s.IPv6 && association == nil:
+			req := ec2.AssociateVpcCidrBlockInput{
+				VpcId:                       aws.String(id),
+				AmazonProvidedIpv6CidrBlock: aws.Bool(true),
+			}
+
+			_, err = svc.AssociateVpcCidrBlockWithContext(ctx, &req)
+		case !*s.IPv6 && association != nil:
+			err = c.disassociateCIDR(ctx, svc, aws.StringValue(association.AssociationId))
+		}
+
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st, err := c.get(ctx, svc, id)
+	st.Name = s.Name
+
+	return st, err
+}
+
+// Delete : deletes a vpc
+func (c Client) Delete(ctx context.Context, id string) error {
+	svc := c.getEC2Client()
+
+	req := ec2.DeleteVpcInput{
+		VpcId: aws.String(id),
+	}
+
+	_, err := svc.DeleteVpcWithContext(ctx, &req)
+
+	return err
+}
+
+// Find : returns the vpcs matching all the given tags
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	req := &ec2.DescribeVpcsInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := svc.DescribeVpcsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var vpcs []Status
+
+	for _, v := range resp.Vpcs {
+		st, err := c.status(ctx, svc, v)
+		if err != nil {
+			return nil, err
+		}
+
+		vpcs = append(vpcs, st)
+	}
+
+	return vpcs, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c Client) describe(ctx context.Context, svc *ec2.EC2, id string) (*ec2.Vpc, error) {
+	req := ec2.DescribeVpcsInput{
+		VpcIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeVpcsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Vpcs) == 0 {
+		return nil, ErrVpcNotFound
+	}
+
+	return resp.Vpcs[0], nil
+}
+
+// get : returns the current status of the vpc
+func (c Client) get(ctx context.Context, svc *ec2.EC2, id string) (Status, error) {
+	vpc, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return c.status(ctx, svc, vpc)
+}
+
+// status : maps the vpc, adding the dns attributes that are only
+// returned by DescribeVpcAttribute
+func (c Client) status(ctx context.Context, svc *ec2.EC2, v *ec2.Vpc) (Status, error) {
+	st := toStatus(v)
+
+	req := ec2.DescribeVpcAttributeInput{
+		VpcId:     v.VpcId,
+		Attribute: aws.String(ec2.VpcAttributeNameEnableDnsSupport),
+	}
+
+	resp, err := svc.DescribeVpcAttributeWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if resp.EnableDnsSupport != nil {
+		st.EnableDNSSupport = aws.BoolValue(resp.EnableDnsSupport.Value)
+	}
+
+	req.Attribute = aws.String(ec2.VpcAttributeNameEnableDnsHostnames)
+
+	resp, err = svc.DescribeVpcAttributeWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if resp.EnableDnsHostnames != nil {
+		st.EnableDNSHostnames = aws.BoolValue(resp.EnableDnsHostnames.Value)
+	}
+
+	return st, nil
+}
+
+// setAttributes : sets the dns attributes of the spec. ModifyVpcAttribute
+// only accepts one attribute per call
+func (c Client) setAttributes(ctx context.Context, svc *ec2.EC2, id string, s Spec) error {
+	if s.EnableDNSSupport != nil {
+		req := ec2.ModifyVpcAttributeInput{
+			VpcId:            aws.String(id),
+			EnableDnsSupport: &ec2.AttributeBooleanValue{Value: s.EnableDNSSupport},
+		}
+
+		_, err := svc.ModifyVpcAttributeWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.EnableDNSHostnames != nil {
+		req := ec2.ModifyVpcAttributeInput{
+			VpcId:              aws.String(id),
+			EnableDnsHostnames: &ec2.AttributeBooleanValue{Value: s.EnableDNSHostnames},
+		}
+
+		_, err := svc.ModifyVpcAttributeWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c Client) associateCIDR(ctx context.Context, svc *ec2.EC2, id, cidr string) error {
+	req := ec2.AssociateVpcCidrBlockInput{
+		VpcId:     aws.String(id),
+		CidrBlock: aws.String(cidr),
+	}
+
+	_, err := svc.AssociateVpcCidrBlockWithContext(ctx, &req)
+
+	return err
+}
+
+func (c Client) disassociateCIDR(ctx context.Context, svc *ec2.EC2, association string) error {
+	req := ec2.DisassociateVpcCidrBlockInput{
+		AssociationId: aws.String(association),
+	}
+
+	_, err := svc.DisassociateVpcCidrBlockWithContext(ctx, &req)
+
+	return err
+}
+
+// secondaryCIDRs : returns the association ids of the ipv4 blocks other
+// than the primary one, keyed by cidr
+func secondaryCIDRs(v *ec2.Vpc) map[string]string {
+	cidrs := make(map[string]string)
+
+	for _, a := range v.CidrBlockAssociationSet {
+		if aws.StringValue(a.CidrBlock) == aws.StringValue(v.CidrBlock) || !associated(a.CidrBlockState) {
+			continue
+		}
+
+		cidrs[aws.StringValue(a.CidrBlock)] = aws.StringValue(a.AssociationId)
+	}
+
+	return cidrs
+}
+
+func ipv6Association(v *ec2.Vpc) *ec2.VpcIpv6CidrBlockAssociation {
+	for _, a := range v.Ipv6CidrBlockAssociationSet {
+		if associated(a.Ipv6CidrBlockState) {
+			return a
+		}
+	}
+
+	return nil
+}
+
+func associated(state *ec2.VpcCidrBlockState) bool {
+	if state == nil {
+		return false
+	}
+
+	switch aws.StringValue(state.State) {
+	case ec2.VpcCidrBlockStateCodeAssociated, ec2.VpcCidrBlockStateCodeAssociating:
+		return true
+	}
+
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func toStatus(v *ec2.Vpc) Status {
+	tags := mapEC2Tags(v.Tags)
+
+	st := Status{
+		ID:              aws.StringValue(v.VpcId),
+		Name:            tags["Name"],
+		CIDR:            aws.StringValue(v.CidrBlock),
+		InstanceTenancy: aws.StringValue(v.InstanceTenancy),
+		Tags:            tags,
+	}
+
+	for cidr := range secondaryCIDRs(v) {
+		st.SecondaryCIDRs = append(st.SecondaryCIDRs, cidr)
+	}
+
+	sort.Strings(st.SecondaryCIDRs)
+
+	if a := ipv6Association(v); a != nil {
+		st.IPv6CIDR = aws.StringValue(a.Ipv6CidrBlock)
+	}
+
+	return st
+}
@@ -5,16 +5,17 @@
 package vpc
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ernestio/ernestaws"
-	"github.com/ernestio/ernestaws/credentials"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
 )
 
 var (
@@ -24,30 +25,39 @@ var (
 	ErrDatacenterRegionInvalid = errors.New("Datacenter Region invalid")
 	// ErrDatacenterCredentialsInvalid ...
 	ErrDatacenterCredentialsInvalid = errors.New("Datacenter credentials invalid")
+	// ErrVpcNotFound ...
+	ErrVpcNotFound = errors.New("VPC not found")
 )
 
 // Event stores the template data
 type Event struct {
-	ProviderType     string            `json:"_provider"`
-	ComponentType    string            `json:"_component"`
-	ComponentID      string            `json:"_component_id"`
-	State            string            `json:"_state"`
-	Action           string            `json:"_action"`
-	VpcID            *string           `json:"vpc_aws_id"`
-	Name             string            `json:"name"`
-	Subnet           *string           `json:"subnet"`
-	AutoRemove       bool              `json:"auto_remove"`
-	Tags             map[string]string `json:"tags"`
-	DatacenterType   string            `json:"datacenter_type,omitempty"`
-	DatacenterName   string            `json:"datacenter_name,omitempty"`
-	DatacenterRegion string            `json:"datacenter_region"`
-	AccessKeyID      string            `json:"aws_access_key_id"`
-	SecretAccessKey  string            `json:"aws_secret_access_key"`
-	Service          string            `json:"service"`
-	ErrorMessage     string            `json:"error,omitempty"`
-	Subject          string            `json:"-"`
-	Body             []byte            `json:"-"`
-	CryptoKey        string            `json:"-"`
+	ProviderType       string            `json:"_provider"`
+	ComponentType      string            `json:"_component"`
+	ComponentID        string            `json:"_component_id"`
+	State              string            `json:"_state"`
+	Action             string            `json:"_action"`
+	SchemaVersion      int               `json:"_schema_version"`
+	VpcID              *string           `json:"vpc_aws_id"`
+	Name               string            `json:"name"`
+	Subnet             *string           `json:"subnet"`
+	SecondarySubnets   []string          `json:"secondary_subnets"`
+	AssignIPv6Subnet   *bool             `json:"assign_ipv6_subnet"`
+	IPv6Subnet         *string           `json:"ipv6_subnet"`
+	EnableDNSSupport   *bool             `json:"enable_dns_support"`
+	EnableDNSHostnames *bool             `json:"enable_dns_hostnames"`
+	InstanceTenancy    *string           `json:"instance_tenancy"`
+	AutoRemove         bool              `json:"auto_remove"`
+	Tags               map[string]string `json:"tags"`
+	DatacenterType     string            `json:"datacenter_type,omitempty"`
+	DatacenterName     string            `json:"datacenter_name,omitempty"`
+	DatacenterRegion   string            `json:"datacenter_region"`
+	AccessKeyID        string            `json:"aws_access_key_id"`
+	SecretAccessKey    string            `json:"aws_secret_access_key"`
+	Service            string            `json:"service"`
+	ErrorMessage       string            `json:"error,omitempty"`
+	Subject            string            `json:"-"`
+	Body               []byte            `json:"-"`
+	CryptoKey          string            `json:"-"`
 }
 
 // New : Constructor
@@ -75,6 +85,11 @@ func (ev *Event) GetSubject() string {
 
 // Process : starts processing the current message
 func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	if err := json.Unmarshal(ev.Body, &ev); err != nil {
 		ev.Error(err)
 		return err
@@ -85,6 +100,11 @@ func (ev *Event) Process() (err error) {
 		return err
 	}
 
+	if err := ev.preflight(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
 	return nil
 }
 
@@ -104,7 +124,11 @@ func (ev *Event) Complete() {
 
 // Validate checks if all criteria are met
 func (ev *Event) Validate() error {
-	if ev.Subject == "vpc.delete.aws" {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
+	if ev.Subject == "vpc.update.aws" || ev.Subject == "vpc.delete.aws" {
 		if ev.VpcID == nil {
 			return ErrDatacenterIDInvalid
 		}
@@ -127,34 +151,37 @@ func (ev *Event) Find() error {
 
 // Create : Creates a vpc object on aws
 func (ev *Event) Create() error {
-	svc := ev.getEC2Client()
-
-	req := ec2.CreateVpcInput{
-		CidrBlock: ev.Subnet,
-	}
-	resp, err := svc.CreateVpc(&req)
+	st, err := ev.client().Create(context.Background(), ev.spec())
 	if err != nil {
 		return err
 	}
-	ev.VpcID = resp.Vpc.VpcId
 
-	return ev.setTags()
+	ev.setStatus(st)
+
+	return nil
 }
 
 // Update : Updates a vpc object on aws
 func (ev *Event) Update() error {
-	return errors.New(ev.Subject + " not supported")
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.VpcID), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.setStatus(st)
+
+	return nil
 }
 
-// Delete : Deletes a vpc object on aws
+// Delete : Deletes a vpc object on aws. With auto_remove, the resources
+// left on the vpc are removed first and a failure is reported with the
+// dependencies blocking it
 func (ev *Event) Delete() error {
-	svc := ev.getEC2Client()
-
-	req := ec2.DeleteVpcInput{
-		VpcId: ev.VpcID,
+	if ev.AutoRemove {
+		return ev.client().Teardown(context.Background(), aws.StringValue(ev.VpcID))
 	}
 
-	_, err := svc.DeleteVpc(&req)
+	err := ev.client().Delete(context.Background(), aws.StringValue(ev.VpcID))
 	if err != nil {
 		ev.ErrorMessage = "WARN : Could not remove the vpc - " + err.Error()
 		return nil
@@ -169,33 +196,50 @@ func (ev *Event) Get() error {
 }
 
 func (ev *Event) getEC2Client() *ec2.EC2 {
-	creds, _ := credentials.NewStaticCredentials(ev.AccessKeyID, ev.SecretAccessKey, ev.CryptoKey)
-	return ec2.New(session.New(), &aws.Config{
-		Region:      aws.String(ev.DatacenterRegion),
-		Credentials: creds,
-	})
+	return ev.client().getEC2Client()
 }
 
-func (ev *Event) setTags() error {
-	svc := ev.getEC2Client()
-
-	for key, val := range ev.Tags {
-		req := &ec2.CreateTagsInput{
-			Resources: []*string{ev.VpcID},
-		}
-
-		req.Tags = append(req.Tags, &ec2.Tag{
-			Key:   &key,
-			Value: &val,
-		})
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+}
 
-		_, err := svc.CreateTags(req)
-		if err != nil {
-			return err
-		}
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:               ev.Name,
+		CIDR:               aws.StringValue(ev.Subnet),
+		SecondaryCIDRs:     ev.SecondarySubnets,
+		IPv6:               ev.AssignIPv6Subnet,
+		EnableDNSSupport:   ev.EnableDNSSupport,
+		EnableDNSHostnames: ev.EnableDNSHostnames,
+		InstanceTenancy:    aws.StringValue(ev.InstanceTenancy),
+		Tags:               ev.Tags,
 	}
+}
 
-	return nil
+// setStatus : maps back the values assigned by aws
+func (ev *Event) setStatus(st Status) {
+	ev.VpcID = aws.String(st.ID)
+	ev.Subnet = aws.String(st.CIDR)
+	ev.SecondarySubnets = st.SecondaryCIDRs
+	ev.IPv6Subnet = nil
+	if st.IPv6CIDR != "" {
+		ev.IPv6Subnet = aws.String(st.IPv6CIDR)
+	}
+	ev.EnableDNSSupport = aws.Bool(st.EnableDNSSupport)
+	ev.EnableDNSHostnames = aws.Bool(st.EnableDNSHostnames)
+	ev.InstanceTenancy = aws.String(st.InstanceTenancy)
 }
 
 func mapTags(tags map[string]string) []*ec2.Tag {
@@ -0,0 +1,200 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+// leave : adds a subnet, a security group and an attached internet
+// gateway to the vpc, as a teardown would find them
+func leave(t *testing.T, b *awsfake.Backend, vpc string) {
+	var subnet ec2.CreateSubnetOutput
+	if err := b.EC2.CreateSubnet(&ec2.CreateSubnetInput{VpcId: aws.String(vpc), CidrBlock: aws.String("10.0.1.0/24")}, &subnet); err != nil {
+		t.Fatal(err)
+	}
+
+	var sg ec2.CreateSecurityGroupOutput
+	if err := b.EC2.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{VpcId: aws.String(vpc), GroupName: aws.String("web"), Description: aws.String("web")}, &sg); err != nil {
+		t.Fatal(err)
+	}
+
+	var gw ec2.CreateInternetGatewayOutput
+	if err := b.EC2.CreateInternetGateway(&ec2.CreateInternetGatewayInput{}, &gw); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.EC2.AttachInternetGateway(&ec2.AttachInternetGatewayInput{VpcId: aws.String(vpc), InternetGatewayId: gw.InternetGateway.InternetGatewayId}, &ec2.AttachInternetGatewayOutput{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{}
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "vpc.create.aws",
+			body:     `{"name":"vpc","subnet":"10.0.0.0/16","secondary_subnets":["10.1.0.0/16"],"enable_dns_support":true,"enable_dns_hostnames":true,"tags":{"Name":"vpc"}}`,
+			expected: "vpc.create.aws.done",
+			save:     map[string]string{"id": "vpc_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				secondary, _ := res["secondary_subnets"].([]interface{})
+				return len(secondary) == 1 && b.EC2.VpcAttributes[ids["id"]].EnableDNSHostnames
+			},
+		},
+		{
+			name:     "update",
+			subject:  "vpc.update.aws",
+			body:     `{"vpc_aws_id":"$id","name":"vpc","subnet":"10.0.0.0/16","secondary_subnets":[],"enable_dns_support":true,"enable_dns_hostnames":false}`,
+			expected: "vpc.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				secondary, _ := res["secondary_subnets"].([]interface{})
+				return len(secondary) == 0 && !b.EC2.VpcAttributes[ids["id"]].EnableDNSHostnames
+			},
+		},
+		{
+			name:     "find",
+			subject:  "vpc.find.aws",
+			body:     `{"tags":{"Name":"vpc"}}`,
+			expected: "vpc.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				return len(found) == 1 && found[0].(map[string]interface{})["vpc_aws_id"] == ids["id"]
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "vpc.delete.aws",
+			body:     `{"vpc_aws_id":"$id"}`,
+			expected: "vpc.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.EC2.Vpcs) == 0 && res["error"] == nil
+			},
+		},
+		{
+			name:     "create a vpc to remove",
+			subject:  "vpc.create.aws",
+			body:     `{"name":"removed","subnet":"10.0.0.0/16"}`,
+			expected: "vpc.create.aws.done",
+			save:     map[string]string{"removed": "vpc_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				leave(t, b, ids["removed"])
+				return true
+			},
+		},
+		{
+			name:     "delete leaves a warning when dependencies remain",
+			subject:  "vpc.delete.aws",
+			body:     `{"vpc_aws_id":"$removed"}`,
+			expected: "vpc.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				msg, _ := res["error"].(string)
+				return strings.HasPrefix(msg, "WARN") && len(b.EC2.Vpcs) == 1
+			},
+		},
+		{
+			name:     "delete with auto_remove tears down the dependencies",
+			subject:  "vpc.delete.aws",
+			body:     `{"vpc_aws_id":"$removed","auto_remove":true}`,
+			expected: "vpc.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(b.EC2.Vpcs) == 0 && len(b.EC2.Subnets) == 0 && len(b.EC2.InternetGateways) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+		message   string
+	}{
+		{
+			name:      "create fails creating the vpc",
+			operation: "CreateVpc",
+			subject:   "vpc.create.aws",
+			body:      `{"name":"vpc","subnet":"10.0.0.0/16"}`,
+			message:   "InternalError",
+		},
+		{
+			name:      "update fails modifying the dns attributes",
+			operation: "ModifyVpcAttribute",
+			subject:   "vpc.update.aws",
+			body:      `{"vpc_aws_id":"$vpc","subnet":"10.0.0.0/16","enable_dns_support":false}`,
+			message:   "InternalError",
+		},
+		{
+			name:      "teardown reports the subnet blocking it",
+			operation: "DeleteSubnet",
+			subject:   "vpc.delete.aws",
+			body:      `{"vpc_aws_id":"$vpc","auto_remove":true}`,
+			message:   "Could not remove the vpc $vpc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			var out ec2.CreateVpcOutput
+			if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+				t.Fatal(err)
+			}
+
+			vpc := aws.StringValue(out.Vpc.VpcId)
+			leave(t, b, vpc)
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, map[string]string{"vpc": vpc})
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			msg, _ := res["error"].(string)
+			if !strings.Contains(msg, strings.Replace(tt.message, "$vpc", vpc, -1)) || !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpc
+
+import "github.com/ernestio/ernestaws/schema"
+
+var attributes = []schema.Field{
+	{Name: "secondary_subnets", Type: schema.Array, Items: &schema.Field{Type: schema.String, Format: schema.CIDR}},
+	{Name: "assign_ipv6_subnet", Type: schema.Boolean},
+	{Name: "enable_dns_support", Type: schema.Boolean},
+	{Name: "enable_dns_hostnames", Type: schema.Boolean},
+	{Name: "instance_tenancy", Type: schema.String, Enum: []string{"default", "dedicated"}},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("vpc", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), attributes, []schema.Field{
+			{Name: "name", Type: schema.String},
+			{Name: "subnet", Type: schema.String, Required: true, Format: schema.CIDR},
+			{Name: "auto_remove", Type: schema.Boolean},
+		}),
+		"update": schema.Fields(schema.Datacenter(), attributes, []schema.Field{
+			{Name: "vpc_aws_id", Type: schema.String, Required: true},
+			{Name: "name", Type: schema.String},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_aws_id", Type: schema.String, Required: true},
+			{Name: "auto_remove", Type: schema.Boolean},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+}
@@ -0,0 +1,595 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// StepTimeout is the time each step of a teardown waits for the removed
+// resources to be gone
+var StepTimeout = 5 * time.Minute
+
+// DependencyError is returned when a teardown can't remove the vpc,
+// listing the dependencies that blocked it
+type DependencyError struct {
+	VpcID    string
+	Blocking []string
+	Err      error
+}
+
+func (e *DependencyError) Error() string {
+	msg := "Could not remove the vpc " + e.VpcID + " - " + e.Err.Error()
+
+	if len(e.Blocking) > 0 {
+		msg = msg + " - blocked by: " + strings.Join(e.Blocking, ", ")
+	}
+
+	return msg
+}
+
+// teardown tracks the dependencies that couldn't be removed
+type teardown struct {
+	svc      *ec2.EC2
+	vpc      string
+	blocking []string
+	owned    map[string]bool
+}
+
+// Teardown : removes the vpc with all its dependencies, in order: nat
+// gateways, leftover network interfaces, non main route tables, non
+// default security groups, subnets, non default network acls, internet
+// gateways and egress only internet gateways. Each step waits for its
+// resources to be gone. Dependencies that fail to be removed, and vpc
+// endpoints, which are not removed, don't stop the teardown, they are
+// reported if the vpc can't be removed
+func (c Client) Teardown(ctx context.Context, id string) error {
+	t := teardown{svc: c.getEC2Client(), vpc: id, owned: make(map[string]bool)}
+
+	steps := []func(context.Context) error{
+		t.vpcEndpoints,
+		t.natGateways,
+		t.networkInterfaces,
+		t.routeTables,
+		t.securityGroups,
+		t.subnets,
+		t.networkACLs,
+		t.internetGateways,
+		t.egressOnlyInternetGateways,
+	}
+
+	for _, step := range steps {
+		if err := step(ctx); err != nil {
+			return err
+		}
+	}
+
+	req := ec2.DeleteVpcInput{
+		VpcId: aws.String(id),
+	}
+
+	_, err := t.svc.DeleteVpcWithContext(ctx, &req)
+	if err != nil {
+		return &DependencyError{VpcID: id, Blocking: t.blocking, Err: err}
+	}
+
+	return nil
+}
+
+func (t *teardown) block(id string, err error) {
+	t.blocking = append(t.blocking, id+" ("+err.Error()+")")
+}
+
+func (t *teardown) filter(name string) []*ec2.Filter {
+	return []*ec2.Filter{
+		&ec2.Filter{
+			Name:   aws.String(name),
+			Values: []*string{aws.String(t.vpc)},
+		},
+	}
+}
+
+// wait : polls until the pending function returns no ids. Ids still
+// pending after the step timeout are reported as blocking
+func (t *teardown) wait(ctx context.Context, pending func() ([]string, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, StepTimeout)
+	defer cancel()
+
+	for {
+		ids, err := pending()
+		if err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			for _, id := range ids {
+				t.block(id, ctx.Err())
+			}
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// waitForRemoval : waits until none of the deleted ids is described
+func (t *teardown) waitForRemoval(ctx context.Context, deleted []string, describe func() ([]string, error)) error {
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	return t.wait(ctx, func() ([]string, error) {
+		existing, err := describe()
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, id := range existing {
+			for _, d := range deleted {
+				if id == d {
+					ids = append(ids, id)
+				}
+			}
+		}
+
+		return ids, nil
+	})
+}
+
+// vpcEndpoints : reports the endpoints of the vpc, they block its
+// removal and their network interfaces can only be released by removing
+// them
+func (t *teardown) vpcEndpoints(ctx context.Context) error {
+	req := ec2.DescribeVpcEndpointsInput{
+		Filters: t.filter("vpc-id"),
+	}
+
+	resp, err := t.svc.DescribeVpcEndpointsWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range resp.VpcEndpoints {
+		switch aws.StringValue(e.State) {
+		case "deleted", "deleting", "failed", "rejected", "expired":
+			continue
+		}
+
+		id := aws.StringValue(e.VpcEndpointId)
+		t.blocking = append(t.blocking, id+" (vpc endpoint)")
+
+		for _, ni := range e.NetworkInterfaceIds {
+			t.owned[aws.StringValue(ni)] = true
+		}
+	}
+
+	return nil
+}
+
+func (t *teardown) natGateways(ctx context.Context) error {
+	describe := func() ([]*ec2.NatGateway, error) {
+		req := ec2.DescribeNatGatewaysInput{
+			Filter: t.filter("vpc-id"),
+		}
+
+		resp, err := t.svc.DescribeNatGatewaysWithContext(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		var gateways []*ec2.NatGateway
+		for _, ng := range resp.NatGateways {
+			if aws.StringValue(ng.State) != ec2.NatGatewayStateDeleted && aws.StringValue(ng.State) != ec2.NatGatewayStateFailed {
+				gateways = append(gateways, ng)
+			}
+		}
+
+		return gateways, nil
+	}
+
+	gateways, err := describe()
+	if err != nil {
+		return err
+	}
+
+	deleted := make(map[string]bool)
+
+	for _, ng := range gateways {
+		if aws.StringValue(ng.State) == ec2.NatGatewayStateDeleting {
+			deleted[*ng.NatGatewayId] = true
+			continue
+		}
+
+		req := ec2.DeleteNatGatewayInput{
+			NatGatewayId: ng.NatGatewayId,
+		}
+
+		_, err = t.svc.DeleteNatGatewayWithContext(ctx, &req)
+		if err != nil {
+			t.block(*ng.NatGatewayId, err)
+			continue
+		}
+
+		deleted[*ng.NatGatewayId] = true
+	}
+
+	return t.wait(ctx, func() ([]string, error) {
+		gateways, err := describe()
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, ng := range gateways {
+			if deleted[*ng.NatGatewayId] {
+				ids = append(ids, *ng.NatGatewayId)
+			}
+		}
+
+		return ids, nil
+	})
+}
+
+// networkInterfaces : removes the detached interfaces and waits for the
+// ones managed by aws, like the nat gateway ones, to be released.
+// Interfaces attached to instances are reported straight away, and the
+// ones of vpc endpoints are skipped as the endpoints are reported
+func (t *teardown) networkInterfaces(ctx context.Context) error {
+	attached := make(map[string]bool)
+
+	return t.wait(ctx, func() ([]string, error) {
+		req := ec2.DescribeNetworkInterfacesInput{
+			Filters: t.filter("vpc-id"),
+		}
+
+		resp, err := t.svc.DescribeNetworkInterfacesWithContext(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+
+		for _, ni := range resp.NetworkInterfaces {
+			id := aws.StringValue(ni.NetworkInterfaceId)
+
+			switch {
+			case attached[id], t.owned[id]:
+			case ni.Attachment != nil && aws.StringValue(ni.Attachment.InstanceId) != "":
+				attached[id] = true
+				t.blocking = append(t.blocking, id+" (attached to "+aws.StringValue(ni.Attachment.InstanceId)+")")
+			case aws.StringValue(ni.Status) == ec2.NetworkInterfaceStatusAvailable && !aws.BoolValue(ni.RequesterManaged):
+				_, err = t.svc.DeleteNetworkInterfaceWithContext(ctx, &ec2.DeleteNetworkInterfaceInput{
+					NetworkInterfaceId: ni.NetworkInterfaceId,
+				})
+				if err != nil {
+					attached[id] = true
+					t.block(id, err)
+				}
+			default:
+				ids = append(ids, id)
+			}
+		}
+
+		return ids, nil
+	})
+}
+
+func (t *teardown) routeTables(ctx context.Context) error {
+	describe := func() ([]*ec2.RouteTable, error) {
+		req := ec2.DescribeRouteTablesInput{
+			Filters: t.filter("vpc-id"),
+		}
+
+		resp, err := t.svc.DescribeRouteTablesWithContext(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		return resp.RouteTables, nil
+	}
+
+	tables, err := describe()
+	if err != nil {
+		return err
+	}
+
+	var deleted []string
+
+	for _, rt := range tables {
+		if isMain(rt) {
+			continue
+		}
+
+		for _, a := range rt.Associations {
+			_, err = t.svc.DisassociateRouteTableWithContext(ctx, &ec2.DisassociateRouteTableInput{
+				AssociationId: a.RouteTableAssociationId,
+			})
+			if err != nil {
+				t.block(aws.StringValue(a.RouteTableAssociationId), err)
+			}
+		}
+
+		_, err = t.svc.DeleteRouteTableWithContext(ctx, &ec2.DeleteRouteTableInput{
+			RouteTableId: rt.RouteTableId,
+		})
+		if err != nil {
+			t.block(aws.StringValue(rt.RouteTableId), err)
+			continue
+		}
+
+		deleted = append(deleted, aws.StringValue(rt.RouteTableId))
+	}
+
+	return t.waitForRemoval(ctx, deleted, func() ([]string, error) {
+		tables, err := describe()
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, rt := range tables {
+			ids = append(ids, aws.StringValue(rt.RouteTableId))
+		}
+
+		return ids, nil
+	})
+}
+
+// securityGroups : revokes the rules of every group before removing
+// them, as groups referencing each other can't be removed otherwise
+func (t *teardown) securityGroups(ctx context.Context) error {
+	req := ec2.DescribeSecurityGroupsInput{
+		Filters: t.filter("vpc-id"),
+	}
+
+	resp, err := t.svc.DescribeSecurityGroupsWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	var groups []*ec2.SecurityGroup
+
+	for _, sg := range resp.SecurityGroups {
+		if aws.StringValue(sg.GroupName) == "default" {
+			continue
+		}
+
+		groups = append(groups, sg)
+
+		if len(sg.IpPermissions) > 0 {
+			_, err = t.svc.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+				GroupId:       sg.GroupId,
+				IpPermissions: sg.IpPermissions,
+			})
+			if err != nil {
+				t.block(aws.StringValue(sg.GroupId), err)
+			}
+		}
+
+		if len(sg.IpPermissionsEgress) > 0 {
+			_, err = t.svc.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
+				GroupId:       sg.GroupId,
+				IpPermissions: sg.IpPermissionsEgress,
+			})
+			if err != nil {
+				t.block(aws.StringValue(sg.GroupId), err)
+			}
+		}
+	}
+
+	var deleted []string
+
+	for _, sg := range groups {
+		_, err = t.svc.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{
+			GroupId: sg.GroupId,
+		})
+		if err != nil {
+			t.block(aws.StringValue(sg.GroupId), err)
+			continue
+		}
+
+		deleted = append(deleted, aws.StringValue(sg.GroupId))
+	}
+
+	return t.waitForRemoval(ctx, deleted, func() ([]string, error) {
+		resp, err := t.svc.DescribeSecurityGroupsWithContext(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, sg := range resp.SecurityGroups {
+			ids = append(ids, aws.StringValue(sg.GroupId))
+		}
+
+		return ids, nil
+	})
+}
+
+func (t *teardown) subnets(ctx context.Context) error {
+	req := ec2.DescribeSubnetsInput{
+		Filters: t.filter("vpc-id"),
+	}
+
+	resp, err := t.svc.DescribeSubnetsWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	var deleted []string
+
+	for _, s := range resp.Subnets {
+		_, err = t.svc.DeleteSubnetWithContext(ctx, &ec2.DeleteSubnetInput{
+			SubnetId: s.SubnetId,
+		})
+		if err != nil {
+			t.block(aws.StringValue(s.SubnetId), err)
+			continue
+		}
+
+		deleted = append(deleted, aws.StringValue(s.SubnetId))
+	}
+
+	return t.waitForRemoval(ctx, deleted, func() ([]string, error) {
+		resp, err := t.svc.DescribeSubnetsWithContext(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, s := range resp.Subnets {
+			ids = append(ids, aws.StringValue(s.SubnetId))
+		}
+
+		return ids, nil
+	})
+}
+
+func (t *teardown) networkACLs(ctx context.Context) error {
+	req := ec2.DescribeNetworkAclsInput{
+		Filters: t.filter("vpc-id"),
+	}
+
+	resp, err := t.svc.DescribeNetworkAclsWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	var deleted []string
+
+	for _, acl := range resp.NetworkAcls {
+		if aws.BoolValue(acl.IsDefault) {
+			continue
+		}
+
+		_, err = t.svc.DeleteNetworkAclWithContext(ctx, &ec2.DeleteNetworkAclInput{
+			NetworkAclId: acl.NetworkAclId,
+		})
+		if err != nil {
+			t.block(aws.StringValue(acl.NetworkAclId), err)
+			continue
+		}
+
+		deleted = append(deleted, aws.StringValue(acl.NetworkAclId))
+	}
+
+	return t.waitForRemoval(ctx, deleted, func() ([]string, error) {
+		resp, err := t.svc.DescribeNetworkAclsWithContext(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, acl := range resp.NetworkAcls {
+			ids = append(ids, aws.StringValue(acl.NetworkAclId))
+		}
+
+		return ids, nil
+	})
+}
+
+func (t *teardown) internetGateways(ctx context.Context) error {
+	req := ec2.DescribeInternetGatewaysInput{
+		Filters: t.filter("attachment.vpc-id"),
+	}
+
+	resp, err := t.svc.DescribeInternetGatewaysWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	var deleted []string
+
+	for _, ig := range resp.InternetGateways {
+		_, err = t.svc.DetachInternetGatewayWithContext(ctx, &ec2.DetachInternetGatewayInput{
+			InternetGatewayId: ig.InternetGatewayId,
+			VpcId:             aws.String(t.vpc),
+		})
+		if err != nil {
+			t.block(aws.StringValue(ig.InternetGatewayId), err)
+			continue
+		}
+
+		_, err = t.svc.DeleteInternetGatewayWithContext(ctx, &ec2.DeleteInternetGatewayInput{
+			InternetGatewayId: ig.InternetGatewayId,
+		})
+		if err != nil {
+			t.block(aws.StringValue(ig.InternetGatewayId), err)
+			continue
+		}
+
+		deleted = append(deleted, aws.StringValue(ig.InternetGatewayId))
+	}
+
+	// detached gateways no longer match the vpc, so they are looked up by id
+	return t.waitForRemoval(ctx, deleted, func() ([]string, error) {
+		req := ec2.DescribeInternetGatewaysInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{
+					Name:   aws.String("internet-gateway-id"),
+					Values: aws.StringSlice(deleted),
+				},
+			},
+		}
+
+		resp, err := t.svc.DescribeInternetGatewaysWithContext(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, ig := range resp.InternetGateways {
+			ids = append(ids, aws.StringValue(ig.InternetGatewayId))
+		}
+
+		return ids, nil
+	})
+}
+
+func (t *teardown) egressOnlyInternetGateways(ctx context.Context) error {
+	resp, err := t.svc.DescribeEgressOnlyInternetGatewaysWithContext(ctx, &ec2.DescribeEgressOnlyInternetGatewaysInput{})
+	if err != nil {
+		return err
+	}
+
+	for _, gw := range resp.EgressOnlyInternetGateways {
+		attached := false
+		for _, a := range gw.Attachments {
+			attached = attached || aws.StringValue(a.VpcId) == t.vpc
+		}
+
+		if !attached {
+			continue
+		}
+
+		_, err = t.svc.DeleteEgressOnlyInternetGatewayWithContext(ctx, &ec2.DeleteEgressOnlyInternetGatewayInput{
+			EgressOnlyInternetGatewayId: gw.EgressOnlyInternetGatewayId,
+		})
+		if err != nil {
+			t.block(aws.StringValue(gw.EgressOnlyInternetGatewayId), err)
+		}
+	}
+
+	return nil
+}
+
+func isMain(rt *ec2.RouteTable) bool {
+	for _, a := range rt.Associations {
+		if aws.BoolValue(a.Main) {
+			return true
+		}
+	}
+	return false
+}
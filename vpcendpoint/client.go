@@ -0,0 +1,374 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpcendpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// stateTimeout is the time to wait for an endpoint to become available
+var stateTimeout = 10 * time.Minute
+
+// Spec describes the desired state of a vpc endpoint. Networks, security
+// groups and private dns only apply to interface endpoints, and route
+// tables to gateway ones. A nil private dns flag or an empty policy keep
+// their current value
+type Spec struct {
+	Name              string
+	VpcID             string
+	ServiceName       string
+	EndpointType      string
+	RouteTableIDs     []string
+	NetworkIDs        []string
+	SecurityGroupIDs  []string
+	PrivateDNSEnabled *bool
+	PolicyDocument    string
+	Tags              map[string]string
+}
+
+// Status describes a vpc endpoint as it is on aws
+type Status struct {
+	ID                string
+	Name              string
+	VpcID             string
+	ServiceName       string
+	EndpointType      string
+	State             string
+	RouteTableIDs     []string
+	NetworkIDs        []string
+	SecurityGroupIDs  []string
+	PrivateDNSEnabled bool
+	PolicyDocument    string
+	DNSNames          []string
+	Tags              map[string]string
+}
+
+// Client manages vpc endpoints through a typed api, the json events are
+// an adapter over it
+type Client struct {
+	client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : creates a vpc endpoint and waits for it to be available
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	req := ec2.CreateVpcEndpointInput{
+		VpcId:           aws.String(s.VpcID),
+		ServiceName:     aws.String(s.ServiceName),
+		VpcEndpointType: aws.String(s.EndpointType),
+	}
+
+	if s.PolicyDocument != "" {
+		req.PolicyDocument = aws.String(s.PolicyDocument)
+	}
+
+	if s.EndpointType == ec2.VpcEndpointTypeInterface {
+		req.SubnetIds = aws.StringSlice(s.NetworkIDs)
+		req.SecurityGroupIds = aws.StringSlice(s.SecurityGroupIDs)
+		req.PrivateDnsEnabled = s.PrivateDNSEnabled
+	} else {
+		req.RouteTableIds = aws.StringSlice(s.RouteTableIDs)
+	}
+
+	resp, err := svc.CreateVpcEndpointWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	id := aws.StringValue(resp.VpcEndpoint.VpcEndpointId)
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	e, err := c.waitForAvailable(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(e)
+	st.Name = s.Name
+	st.Tags = s.Tags
+
+	return st, nil
+}
+
+// Update : updates the route tables, networks, security groups and policy
+// of a vpc endpoint. The endpoint type can't be changed, so the one on
+// aws is kept
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	e, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	req := ec2.ModifyVpcEndpointInput{
+		VpcEndpointId: aws.String(id),
+	}
+
+	if aws.StringValue(e.VpcEndpointType) == ec2.VpcEndpointTypeInterface {
+		var groups []string
+		for _, g := range e.Groups {
+			groups = append(groups, aws.StringValue(g.GroupId))
+		}
+
+		subnets := aws.StringValueSlice(e.SubnetIds)
+
+		req.AddSubnetIds = difference(s.NetworkIDs, subnets)
+		req.RemoveSubnetIds = difference(subnets, s.NetworkIDs)
+		req.AddSecurityGroupIds = difference(s.SecurityGroupIDs, groups)
+		req.RemoveSecurityGroupIds = difference(groups, s.SecurityGroupIDs)
+
+		if s.PrivateDNSEnabled != nil && *s.PrivateDNSEnabled != aws.BoolValue(e.PrivateDnsEnabled) {
+			req.PrivateDnsEnabled = s.PrivateDNSEnabled
+		}
+	} else {
+		routeTables := aws.StringValueSlice(e.RouteTableIds)
+
+		req.AddRouteTableIds = difference(s.RouteTableIDs, routeTables)
+		req.RemoveRouteTableIds = difference(routeTables, s.RouteTableIDs)
+	}
+
+	if s.PolicyDocument != "" && !equalPolicies(s.PolicyDocument, aws.StringValue(e.PolicyDocument)) {
+		req.PolicyDocument = aws.String(s.PolicyDocument)
+	}
+
+	if modified(req) {
+		_, err = svc.ModifyVpcEndpointWithContext(ctx, &req)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	e, err = c.waitForAvailable(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := toStatus(e)
+	st.Name = s.Name
+	st.Tags = s.Tags
+
+	return st, nil
+}
+
+// Delete : deletes a vpc endpoint
+func (c Client) Delete(ctx context.Context, id string) error {
+	svc := c.getEC2Client()
+
+	req := ec2.DeleteVpcEndpointsInput{
+		VpcEndpointIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DeleteVpcEndpointsWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range resp.Unsuccessful {
+		if u.Error != nil {
+			return errors.New(aws.StringValue(u.Error.Code) + ": " + aws.StringValue(u.Error.Message))
+		}
+	}
+
+	return nil
+}
+
+// Find : returns the vpc endpoints matching all the given tags, leaving
+// out the ones being deleted
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	svc := c.getEC2Client()
+
+	req := &ec2.DescribeVpcEndpointsInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := svc.DescribeVpcEndpointsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []Status
+
+	for _, e := range resp.VpcEndpoints {
+		switch strings.ToLower(aws.StringValue(e.State)) {
+		case "deleting", "deleted":
+			continue
+		}
+
+		endpoints = append(endpoints, toStatus(e))
+	}
+
+	return endpoints, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+func (c Client) describe(ctx context.Context, svc *ec2.EC2, id string) (*ec2.VpcEndpoint, error) {
+	req := ec2.DescribeVpcEndpointsInput{
+		VpcEndpointIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeVpcEndpointsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.VpcEndpoints) == 0 {
+		return nil, ErrVpcEndpointNotFound
+	}
+
+	return resp.VpcEndpoints[0], nil
+}
+
+// waitForAvailable : polls the endpoint until it is available, failing
+// if it is rejected or fails to be created
+func (c Client) waitForAvailable(ctx context.Context, svc *ec2.EC2, id string) (*ec2.VpcEndpoint, error) {
+	deadline := time.Now().Add(stateTimeout)
+
+	for {
+		e, err := c.describe(ctx, svc, id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(aws.StringValue(e.State)) {
+		case "available":
+			return e, nil
+		case "failed", "rejected", "deleted":
+			return nil, errors.New(ErrVpcEndpointFailed.Error() + ": " + aws.StringValue(e.State))
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("Timed out waiting for the VPC endpoint to be available")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// modified : checks if the modify request changes anything
+func modified(req ec2.ModifyVpcEndpointInput) bool {
+	return len(req.AddRouteTableIds) > 0 || len(req.RemoveRouteTableIds) > 0 ||
+		len(req.AddSubnetIds) > 0 || len(req.RemoveSubnetIds) > 0 ||
+		len(req.AddSecurityGroupIds) > 0 || len(req.RemoveSecurityGroupIds) > 0 ||
+		req.PrivateDnsEnabled != nil || req.PolicyDocument != nil
+}
+
+func dnsNames(e *ec2.VpcEndpoint) []string {
+	var names []string
+
+	for _, d := range e.DnsEntries {
+		names = append(names, aws.StringValue(d.DnsName))
+	}
+
+	return names
+}
+
+// equalPolicies : compares two policy documents ignoring their formatting
+func equalPolicies(a, b string) bool {
+	var pa, pb interface{}
+
+	if json.Unmarshal([]byte(a), &pa) != nil || json.Unmarshal([]byte(b), &pb) != nil {
+		return a == b
+	}
+
+	ja, _ := json.Marshal(pa)
+	jb, _ := json.Marshal(pb)
+
+	return string(ja) == string(jb)
+}
+
+// difference : returns the values of a that are not on b
+func difference(a, b []string) []*string {
+	var d []*string
+
+	for _, v := range a {
+		if !contains(b, v) {
+			d = append(d, aws.String(v))
+		}
+	}
+
+	return d
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func toStatus(e *ec2.VpcEndpoint) Status {
+	tags := mapEC2Tags(e.Tags)
+
+	st := Status{
+		ID:                aws.StringValue(e.VpcEndpointId),
+		Name:              tags["Name"],
+		VpcID:             aws.StringValue(e.VpcId),
+		ServiceName:       aws.StringValue(e.ServiceName),
+		EndpointType:      aws.StringValue(e.VpcEndpointType),
+		State:             aws.StringValue(e.State),
+		RouteTableIDs:     aws.StringValueSlice(e.RouteTableIds),
+		NetworkIDs:        aws.StringValueSlice(e.SubnetIds),
+		PrivateDNSEnabled: aws.BoolValue(e.PrivateDnsEnabled),
+		PolicyDocument:    aws.StringValue(e.PolicyDocument),
+		DNSNames:          dnsNames(e),
+		Tags:              tags,
+	}
+
+	for _, g := range e.Groups {
+		st.SecurityGroupIDs = append(st.SecurityGroupIDs, aws.StringValue(g.GroupId))
+	}
+
+	return st
+}
@@ -0,0 +1,249 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpcendpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
+)
+
+var (
+	// ErrDatacenterIDInvalid ...
+	ErrDatacenterIDInvalid = errors.New("Datacenter VPC ID invalid")
+	// ErrDatacenterRegionInvalid ...
+	ErrDatacenterRegionInvalid = errors.New("Datacenter Region invalid")
+	// ErrDatacenterCredentialsInvalid ...
+	ErrDatacenterCredentialsInvalid = errors.New("Datacenter credentials invalid")
+	// ErrVpcEndpointAWSIDInvalid ...
+	ErrVpcEndpointAWSIDInvalid = errors.New("VPC endpoint aws id invalid")
+	// ErrInterfaceNetworksInvalid ...
+	ErrInterfaceNetworksInvalid = errors.New("VPC endpoint network_aws_ids are required for interface endpoints")
+	// ErrGatewayOptionsInvalid ...
+	ErrGatewayOptionsInvalid = errors.New("VPC endpoint networks, security groups and private dns are only supported on interface endpoints")
+	// ErrVpcEndpointNotFound ...
+	ErrVpcEndpointNotFound = errors.New("VPC endpoint not found")
+	// ErrVpcEndpointFailed ...
+	ErrVpcEndpointFailed = errors.New("VPC endpoint failed")
+)
+
+// Event stores the vpc endpoint data
+type Event struct {
+	ProviderType        string            `json:"_provider"`
+	ComponentType       string            `json:"_component"`
+	ComponentID         string            `json:"_component_id"`
+	State               string            `json:"_state"`
+	Action              string            `json:"_action"`
+	SchemaVersion       int               `json:"_schema_version"`
+	VpcEndpointAWSID    *string           `json:"vpc_endpoint_aws_id"`
+	Name                *string           `json:"name"`
+	ServiceName         string            `json:"service_name"`
+	EndpointType        string            `json:"endpoint_type"`
+	Status              *string           `json:"status"`
+	RouteTableAWSIDs    []*string         `json:"route_table_aws_ids"`
+	NetworkAWSIDs       []*string         `json:"network_aws_ids"`
+	SecurityGroupAWSIDs []*string         `json:"security_group_aws_ids"`
+	PrivateDNSEnabled   *bool             `json:"private_dns_enabled"`
+	PolicyDocument      *string           `json:"policy_document"`
+	DNSNames            []string          `json:"dns_names"`
+	Tags                map[string]string `json:"tags"`
+	DatacenterType      string            `json:"datacenter_type"`
+	DatacenterName      string            `json:"datacenter_name"`
+	DatacenterRegion    string            `json:"datacenter_region"`
+	AccessKeyID         string            `json:"aws_access_key_id"`
+	SecretAccessKey     string            `json:"aws_secret_access_key"`
+	Vpc                 string            `json:"vpc"`
+	VpcID               string            `json:"vpc_id"`
+	Service             string            `json:"service"`
+	ErrorMessage        string            `json:"error,omitempty"`
+	Subject             string            `json:"-"`
+	Body                []byte            `json:"-"`
+	CryptoKey           string            `json:"-"`
+}
+
+// New : Constructor
+func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
+	if strings.Split(subject, ".")[1] == "find" {
+		return &Collection{Subject: subject, Body: body, CryptoKey: cryptoKey}
+	}
+
+	return &Event{Subject: subject, Body: body, CryptoKey: cryptoKey}
+}
+
+// Validate checks if all criteria are met
+func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
+	if ev.VpcID == "" {
+		return ErrDatacenterIDInvalid
+	}
+
+	if ev.DatacenterRegion == "" {
+		return ErrDatacenterRegionInvalid
+	}
+
+	if ev.AccessKeyID == "" || ev.SecretAccessKey == "" {
+		return ErrDatacenterCredentialsInvalid
+	}
+
+	if ev.Subject != "vpc_endpoint.create.aws" && ev.VpcEndpointAWSID == nil {
+		return ErrVpcEndpointAWSIDInvalid
+	}
+
+	if ev.Subject == "vpc_endpoint.delete.aws" {
+		return nil
+	}
+
+	if ev.EndpointType == "" {
+		ev.EndpointType = ec2.VpcEndpointTypeGateway
+	}
+
+	if ev.EndpointType == ec2.VpcEndpointTypeInterface {
+		if len(ev.NetworkAWSIDs) == 0 {
+			return ErrInterfaceNetworksInvalid
+		}
+	} else if len(ev.NetworkAWSIDs) > 0 || len(ev.SecurityGroupAWSIDs) > 0 || ev.PrivateDNSEnabled != nil {
+		return ErrGatewayOptionsInvalid
+	}
+
+	return nil
+}
+
+// Process : starts processing the current message
+func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := json.Unmarshal(ev.Body, &ev); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := ev.Validate(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Error : Will respond the current event with an error
+func (ev *Event) Error(err error) {
+	log.Printf("Error: %s", err.Error())
+	ev.ErrorMessage = err.Error()
+	ev.State = "errored"
+
+	ev.Body, err = json.Marshal(ev)
+}
+
+// Complete : sets the state of the event to completed
+func (ev *Event) Complete() {
+	ev.State = "completed"
+}
+
+// Find : Find an object on aws
+func (ev *Event) Find() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// Create : Creates a vpc endpoint on aws and waits for it to be available
+func (ev *Event) Create() error {
+	st, err := ev.client().Create(context.Background(), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.VpcEndpointAWSID = aws.String(st.ID)
+	ev.setStatus(st)
+
+	return nil
+}
+
+// Update : Updates the route tables, networks, security groups and policy
+// of a vpc endpoint on aws
+func (ev *Event) Update() error {
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.VpcEndpointAWSID), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.EndpointType = st.EndpointType
+	ev.setStatus(st)
+
+	return nil
+}
+
+// Delete : Deletes a vpc endpoint on aws
+func (ev *Event) Delete() error {
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.VpcEndpointAWSID))
+}
+
+// Get : Gets a object on aws
+func (ev *Event) Get() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// GetBody : Gets the body for this event
+func (ev *Event) GetBody() []byte {
+	var err error
+	if ev.Body, err = json.Marshal(ev); err != nil {
+		log.Println(err.Error())
+	}
+	return ev.Body
+}
+
+// GetSubject : Gets the subject for this event
+func (ev *Event) GetSubject() string {
+	return ev.Subject
+}
+
+// client : returns the typed client the event is an adapter for
+func (ev *Event) client() Client {
+	return Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+}
+
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:              aws.StringValue(ev.Name),
+		VpcID:             ev.VpcID,
+		ServiceName:       ev.ServiceName,
+		EndpointType:      ev.EndpointType,
+		RouteTableIDs:     aws.StringValueSlice(ev.RouteTableAWSIDs),
+		NetworkIDs:        aws.StringValueSlice(ev.NetworkAWSIDs),
+		SecurityGroupIDs:  aws.StringValueSlice(ev.SecurityGroupAWSIDs),
+		PrivateDNSEnabled: ev.PrivateDNSEnabled,
+		PolicyDocument:    aws.StringValue(ev.PolicyDocument),
+		Tags:              ev.Tags,
+	}
+}
+
+// setStatus : maps back the state and dns names of the endpoint
+func (ev *Event) setStatus(st Status) {
+	ev.Status = aws.String(st.State)
+	ev.DNSNames = st.DNSNames
+}
@@ -0,0 +1,177 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpcendpoint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String("10.0.0.0/16")}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+func createRouteTable(t *testing.T, b *awsfake.Backend, vpc string) string {
+	var out ec2.CreateRouteTableOutput
+
+	if err := b.EC2.CreateRouteTable(&ec2.CreateRouteTableInput{VpcId: aws.String(vpc)}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.RouteTable.RouteTableId
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := map[string]string{"vpc": createVpc(t, b)}
+	ids["rt1"] = createRouteTable(t, b, ids["vpc"])
+	ids["rt2"] = createRouteTable(t, b, ids["vpc"])
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create",
+			subject:  "vpc_endpoint.create.aws",
+			body:     `{"vpc_id":"$vpc","name":"s3","service_name":"com.amazonaws.us-east-1.s3","route_table_aws_ids":["$rt1"],"tags":{"Name":"s3"}}`,
+			expected: "vpc_endpoint.create.aws.done",
+			save:     map[string]string{"id": "vpc_endpoint_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				e := b.EC2.VpcEndpoints[ids["id"]]
+				return res["status"] == "available" && len(e.RouteTableIds) == 1 && len(e.Tags) == 1
+			},
+		},
+		{
+			name:     "update swaps the route tables",
+			subject:  "vpc_endpoint.update.aws",
+			body:     `{"vpc_id":"$vpc","vpc_endpoint_aws_id":"$id","service_name":"com.amazonaws.us-east-1.s3","route_table_aws_ids":["$rt2"],"policy_document":"{\"Statement\":[]}"}`,
+			expected: "vpc_endpoint.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				e := b.EC2.VpcEndpoints[ids["id"]]
+				return len(e.RouteTableIds) == 1 && *e.RouteTableIds[0] == ids["rt2"] && *e.PolicyDocument == `{"Statement":[]}`
+			},
+		},
+		{
+			name:     "update leaves an unchanged endpoint",
+			subject:  "vpc_endpoint.update.aws",
+			body:     `{"vpc_id":"$vpc","vpc_endpoint_aws_id":"$id","service_name":"com.amazonaws.us-east-1.s3","route_table_aws_ids":["$rt2"],"policy_document":"{ \"Statement\": [] }"}`,
+			expected: "vpc_endpoint.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return b.Called("ec2", "ModifyVpcEndpoint") == 1
+			},
+		},
+		{
+			name:     "find",
+			subject:  "vpc_endpoint.find.aws",
+			body:     `{"tags":{"Name":"s3"}}`,
+			expected: "vpc_endpoint.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				e := found[0].(map[string]interface{})
+				rts, _ := e["route_table_aws_ids"].([]interface{})
+				return e["vpc_endpoint_aws_id"] == ids["id"] && e["endpoint_type"] == "Gateway" && len(rts) == 1 && rts[0] == ids["rt2"]
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "vpc_endpoint.delete.aws",
+			body:     `{"vpc_id":"$vpc","vpc_endpoint_aws_id":"$id"}`,
+			expected: "vpc_endpoint.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				e := b.EC2.VpcEndpoints[ids["id"]]
+				return e == nil || *e.State == "deleted"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails tagging the endpoint",
+			operation: "CreateTags",
+			subject:   "vpc_endpoint.create.aws",
+			body:      `{"vpc_id":"$vpc","service_name":"com.amazonaws.us-east-1.s3","tags":{"Name":"s3"}}`,
+		},
+		{
+			name:      "update fails modifying the endpoint",
+			operation: "ModifyVpcEndpoint",
+			subject:   "vpc_endpoint.update.aws",
+			body:      `{"vpc_id":"$vpc","vpc_endpoint_aws_id":"$id","service_name":"com.amazonaws.us-east-1.s3","route_table_aws_ids":["$rt"]}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteVpcEndpoints",
+			subject:   "vpc_endpoint.delete.aws",
+			body:      `{"vpc_id":"$vpc","vpc_endpoint_aws_id":"$id"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := map[string]string{"vpc": createVpc(t, b)}
+			ids["rt"] = createRouteTable(t, b, ids["vpc"])
+
+			_, res := awsfake.Run(t, New, "vpc_endpoint.create.aws", `{"vpc_id":"$vpc","service_name":"com.amazonaws.us-east-1.s3"}`, ids)
+			ids["id"], _ = res["vpc_endpoint_aws_id"].(string)
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpcendpoint
+
+import "github.com/ernestio/ernestaws/schema"
+
+var options = []schema.Field{
+	{Name: "vpc_id", Type: schema.String, Required: true},
+	{Name: "service_name", Type: schema.String, Required: true},
+	{Name: "endpoint_type", Type: schema.String, Enum: []string{"Gateway", "Interface"}},
+	{Name: "route_table_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "network_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "security_group_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "private_dns_enabled", Type: schema.Boolean},
+	{Name: "policy_document", Type: schema.String},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("vpc_endpoint", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), options, []schema.Field{
+			{Name: "name", Type: schema.String},
+		}),
+		"update": schema.Fields(schema.Datacenter(), options, []schema.Field{
+			{Name: "vpc_endpoint_aws_id", Type: schema.String, Required: true},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "vpc_endpoint_aws_id", Type: schema.String, Required: true},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("vpc_endpoint", 1, schema.VpcReference)
+}
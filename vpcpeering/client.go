@@ -0,0 +1,495 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpcpeering
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/client"
+)
+
+// statusTimeout is the time to wait for a peering connection to reach
+// a status
+var statusTimeout = 5 * time.Minute
+
+// Spec describes the desired state of a vpc peering connection. The dns
+// resolution options left as nil keep their current value
+type Spec struct {
+	Name                         string
+	VpcID                        string
+	PeerVpcID                    string
+	PeerOwnerID                  string
+	PeerRegion                   string
+	AutoAccept                   bool
+	AllowRemoteDNSResolution     *bool
+	PeerAllowRemoteDNSResolution *bool
+	RouteTableIDs                []string
+	PeerRouteTableIDs            []string
+	Tags                         map[string]string
+}
+
+// Status describes a vpc peering connection as it is on aws
+type Status struct {
+	ID                           string
+	Name                         string
+	State                        string
+	VpcID                        string
+	PeerVpcID                    string
+	PeerOwnerID                  string
+	PeerRegion                   string
+	Subnets                      []string
+	PeerSubnets                  []string
+	AllowRemoteDNSResolution     *bool
+	PeerAllowRemoteDNSResolution *bool
+	Tags                         map[string]string
+}
+
+// Client manages vpc peering connections through a typed api, the json
+// events are an adapter over it
+type Client struct {
+	client.Account
+	// Peer is the account of the accepter side, nil when it can't be
+	// managed as it belongs to another account
+	Peer *client.Account
+	// Scope identifies the calls on the session hooks, like the audit
+	Scope client.Scope
+}
+
+// Create : requests a peering connection to the peer vpc, which can be
+// on another account or region. With auto accept, the request is
+// accepted with the peer account
+func (c Client) Create(ctx context.Context, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	req := ec2.CreateVpcPeeringConnectionInput{
+		VpcId:     aws.String(s.VpcID),
+		PeerVpcId: aws.String(s.PeerVpcID),
+	}
+
+	if s.PeerOwnerID != "" {
+		req.PeerOwnerId = aws.String(s.PeerOwnerID)
+	}
+
+	if s.PeerRegion != "" {
+		req.PeerRegion = aws.String(s.PeerRegion)
+	}
+
+	resp, err := svc.CreateVpcPeeringConnectionWithContext(ctx, &req)
+	if err != nil {
+		return Status{}, err
+	}
+
+	id := aws.StringValue(resp.VpcPeeringConnection.VpcPeeringConnectionId)
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	pc, err := c.waitForStatus(ctx, svc, id, ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance, ec2.VpcPeeringConnectionStateReasonCodeActive)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return c.reconcile(ctx, svc, pc, s)
+}
+
+// Update : accepts the peering connection if it is pending and auto
+// accept is set, and updates the dns resolution options and the routes
+// to the peer vpc
+func (c Client) Update(ctx context.Context, id string, s Spec) (Status, error) {
+	svc := c.getEC2Client()
+
+	pc, err := c.describe(ctx, svc, id)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.setTags(ctx, svc, id, s.Tags)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return c.reconcile(ctx, svc, pc, s)
+}
+
+// Delete : deletes the peering connection and the routes using it
+func (c Client) Delete(ctx context.Context, id, vpcID, peerVpcID string) error {
+	svc := c.getEC2Client()
+
+	err := c.deleteRoutes(ctx, svc, id, vpcID, nil)
+	if err != nil {
+		return err
+	}
+
+	if c.Peer != nil {
+		err = c.deleteRoutes(ctx, c.getPeerEC2Client(), id, peerVpcID, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	req := ec2.DeleteVpcPeeringConnectionInput{
+		VpcPeeringConnectionId: aws.String(id),
+	}
+
+	_, err = svc.DeleteVpcPeeringConnectionWithContext(ctx, &req)
+
+	return err
+}
+
+// Find : returns the peering connections matching all the tags, leaving
+// out the deleted and rejected ones
+func (c Client) Find(ctx context.Context, tags map[string]string) ([]Status, error) {
+	req := &ec2.DescribeVpcPeeringConnectionsInput{
+		Filters: mapFilters(tags),
+	}
+
+	resp, err := c.getEC2Client().DescribeVpcPeeringConnectionsWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var connections []Status
+
+	for _, pc := range resp.VpcPeeringConnections {
+		switch status(pc) {
+		case ec2.VpcPeeringConnectionStateReasonCodeDeleted, ec2.VpcPeeringConnectionStateReasonCodeRejected:
+			continue
+		}
+
+		connections = append(connections, toStatus(pc))
+	}
+
+	return connections, nil
+}
+
+func (c Client) getEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Config())
+}
+
+// getPeerEC2Client : returns a client for the accepter side of the
+// peering connection
+func (c Client) getPeerEC2Client() *ec2.EC2 {
+	return ec2.New(client.Session(c.Scope.Subject, c.Scope.ComponentID), c.Peer.Config())
+}
+
+// reconcile : accepts the connection when needed and, once it is active,
+// sets its options and routes
+func (c Client) reconcile(ctx context.Context, svc *ec2.EC2, pc *ec2.VpcPeeringConnection, s Spec) (Status, error) {
+	var err error
+
+	id := aws.StringValue(pc.VpcPeeringConnectionId)
+
+	if status(pc) == ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance && s.AutoAccept && c.Peer != nil {
+		req := ec2.AcceptVpcPeeringConnectionInput{
+			VpcPeeringConnectionId: aws.String(id),
+		}
+
+		_, err = c.getPeerEC2Client().AcceptVpcPeeringConnectionWithContext(ctx, &req)
+		if err != nil {
+			return Status{}, err
+		}
+
+		pc, err = c.waitForStatus(ctx, svc, id, ec2.VpcPeeringConnectionStateReasonCodeActive)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	st := toStatus(pc)
+
+	// options and routes can only be set on active connections, they
+	// are set by a later update once the peer accepts the request
+	if st.State != ec2.VpcPeeringConnectionStateReasonCodeActive {
+		return st, nil
+	}
+
+	err = c.setOptions(ctx, svc, id, s)
+	if err != nil {
+		return Status{}, err
+	}
+
+	err = c.setRoutes(ctx, svc, id, s.VpcID, s.RouteTableIDs, st.PeerSubnets)
+	if err != nil {
+		return Status{}, err
+	}
+
+	if c.Peer != nil {
+		err = c.setRoutes(ctx, c.getPeerEC2Client(), id, s.PeerVpcID, s.PeerRouteTableIDs, st.Subnets)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	return st, nil
+}
+
+func (c Client) setOptions(ctx context.Context, svc *ec2.EC2, id string, s Spec) error {
+	if s.AllowRemoteDNSResolution != nil {
+		req := ec2.ModifyVpcPeeringConnectionOptionsInput{
+			VpcPeeringConnectionId: aws.String(id),
+			RequesterPeeringConnectionOptions: &ec2.PeeringConnectionOptionsRequest{
+				AllowDnsResolutionFromRemoteVpc: s.AllowRemoteDNSResolution,
+			},
+		}
+
+		_, err := svc.ModifyVpcPeeringConnectionOptionsWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.PeerAllowRemoteDNSResolution != nil && c.Peer != nil {
+		req := ec2.ModifyVpcPeeringConnectionOptionsInput{
+			VpcPeeringConnectionId: aws.String(id),
+			AccepterPeeringConnectionOptions: &ec2.PeeringConnectionOptionsRequest{
+				AllowDnsResolutionFromRemoteVpc: s.PeerAllowRemoteDNSResolution,
+			},
+		}
+
+		_, err := c.getPeerEC2Client().ModifyVpcPeeringConnectionOptionsWithContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setRoutes : routes the given cidrs through the peering connection on
+// the route tables, removing the routes from any other table of the vpc
+func (c Client) setRoutes(ctx context.Context, svc *ec2.EC2, id, vpc string, tables, cidrs []string) error {
+	err := c.deleteRoutes(ctx, svc, id, vpc, tables)
+	if err != nil {
+		return err
+	}
+
+	for _, rt := range tables {
+		resp, err := svc.DescribeRouteTablesWithContext(ctx, &ec2.DescribeRouteTablesInput{
+			RouteTableIds: []*string{aws.String(rt)},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, cidr := range cidrs {
+			if len(resp.RouteTables) > 0 && hasRoute(resp.RouteTables[0], cidr, id) {
+				continue
+			}
+
+			req := ec2.CreateRouteInput{
+				RouteTableId:           aws.String(rt),
+				DestinationCidrBlock:   aws.String(cidr),
+				VpcPeeringConnectionId: aws.String(id),
+			}
+
+			_, err = svc.CreateRouteWithContext(ctx, &req)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteRoutes : removes the routes through the peering connection from
+// every route table of the vpc but the ones to keep
+func (c Client) deleteRoutes(ctx context.Context, svc *ec2.EC2, id, vpc string, keep []string) error {
+	req := ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpc)},
+			},
+			&ec2.Filter{
+				Name:   aws.String("route.vpc-peering-connection-id"),
+				Values: []*string{aws.String(id)},
+			},
+		},
+	}
+
+	resp, err := svc.DescribeRouteTablesWithContext(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	for _, rt := range resp.RouteTables {
+		if contains(keep, *rt.RouteTableId) {
+			continue
+		}
+
+		for _, r := range rt.Routes {
+			if aws.StringValue(r.VpcPeeringConnectionId) != id {
+				continue
+			}
+
+			_, err = svc.DeleteRouteWithContext(ctx, &ec2.DeleteRouteInput{
+				RouteTableId:         rt.RouteTableId,
+				DestinationCidrBlock: r.DestinationCidrBlock,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c Client) describe(ctx context.Context, svc *ec2.EC2, id string) (*ec2.VpcPeeringConnection, error) {
+	req := ec2.DescribeVpcPeeringConnectionsInput{
+		VpcPeeringConnectionIds: []*string{aws.String(id)},
+	}
+
+	resp, err := svc.DescribeVpcPeeringConnectionsWithContext(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.VpcPeeringConnections) == 0 {
+		return nil, ErrVpcPeeringNotFound
+	}
+
+	return resp.VpcPeeringConnections[0], nil
+}
+
+// waitForStatus : polls the peering connection until it reaches one of
+// the given status
+func (c Client) waitForStatus(ctx context.Context, svc *ec2.EC2, id string, codes ...string) (*ec2.VpcPeeringConnection, error) {
+	deadline := time.Now().Add(statusTimeout)
+
+	for {
+		pc, err := c.describe(ctx, svc, id)
+		if err != nil && err != ErrVpcPeeringNotFound {
+			return nil, err
+		}
+
+		if pc != nil {
+			for _, code := range codes {
+				if status(pc) == code {
+					return pc, nil
+				}
+			}
+
+			switch status(pc) {
+			case ec2.VpcPeeringConnectionStateReasonCodeFailed, ec2.VpcPeeringConnectionStateReasonCodeRejected:
+				return nil, errors.New(ErrVpcPeeringFailed.Error() + ": " + aws.StringValue(pc.Status.Message))
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("Timed out waiting for the VPC peering connection to be " + strings.Join(codes, " or "))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c Client) setTags(ctx context.Context, svc *ec2.EC2, id string, tags map[string]string) error {
+	for key, val := range tags {
+		req := &ec2.CreateTagsInput{
+			Resources: []*string{aws.String(id)},
+		}
+
+		req.Tags = append(req.Tags, &ec2.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(val),
+		})
+
+		_, err := svc.CreateTagsWithContext(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toStatus(pc *ec2.VpcPeeringConnection) Status {
+	tags := mapEC2Tags(pc.Tags)
+
+	st := Status{
+		ID:          aws.StringValue(pc.VpcPeeringConnectionId),
+		Name:        tags["Name"],
+		State:       status(pc),
+		Subnets:     cidrs(pc.RequesterVpcInfo),
+		PeerSubnets: cidrs(pc.AccepterVpcInfo),
+		Tags:        tags,
+	}
+
+	if r := pc.RequesterVpcInfo; r != nil {
+		st.VpcID = aws.StringValue(r.VpcId)
+
+		if r.PeeringOptions != nil {
+			st.AllowRemoteDNSResolution = r.PeeringOptions.AllowDnsResolutionFromRemoteVpc
+		}
+	}
+
+	if a := pc.AccepterVpcInfo; a != nil {
+		st.PeerVpcID = aws.StringValue(a.VpcId)
+		st.PeerOwnerID = aws.StringValue(a.OwnerId)
+		st.PeerRegion = aws.StringValue(a.Region)
+
+		if a.PeeringOptions != nil {
+			st.PeerAllowRemoteDNSResolution = a.PeeringOptions.AllowDnsResolutionFromRemoteVpc
+		}
+	}
+
+	return st
+}
+
+func status(pc *ec2.VpcPeeringConnection) string {
+	if pc.Status == nil {
+		return ""
+	}
+	return aws.StringValue(pc.Status.Code)
+}
+
+func cidrs(info *ec2.VpcPeeringConnectionVpcInfo) []string {
+	if info == nil {
+		return nil
+	}
+
+	var c []string
+
+	for _, b := range info.CidrBlockSet {
+		c = append(c, aws.StringValue(b.CidrBlock))
+	}
+
+	if len(c) == 0 && info.CidrBlock != nil {
+		c = append(c, *info.CidrBlock)
+	}
+
+	return c
+}
+
+func hasRoute(rt *ec2.RouteTable, cidr, id string) bool {
+	for _, r := range rt.Routes {
+		if aws.StringValue(r.DestinationCidrBlock) == cidr && aws.StringValue(r.VpcPeeringConnectionId) == id {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
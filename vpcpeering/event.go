@@ -0,0 +1,264 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpcpeering
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/ernestio/ernestaws"
+	"github.com/ernestio/ernestaws/client"
+	"github.com/ernestio/ernestaws/schema"
+)
+
+var (
+	// ErrDatacenterIDInvalid ...
+	ErrDatacenterIDInvalid = errors.New("Datacenter VPC ID invalid")
+	// ErrDatacenterRegionInvalid ...
+	ErrDatacenterRegionInvalid = errors.New("Datacenter Region invalid")
+	// ErrDatacenterCredentialsInvalid ...
+	ErrDatacenterCredentialsInvalid = errors.New("Datacenter credentials invalid")
+	// ErrPeerVpcIDInvalid ...
+	ErrPeerVpcIDInvalid = errors.New("Peer VPC ID invalid")
+	// ErrVpcPeeringAWSIDInvalid ...
+	ErrVpcPeeringAWSIDInvalid = errors.New("VPC peering connection ID invalid")
+	// ErrVpcPeeringNotFound ...
+	ErrVpcPeeringNotFound = errors.New("VPC peering connection not found")
+	// ErrVpcPeeringFailed ...
+	ErrVpcPeeringFailed = errors.New("VPC peering connection failed")
+)
+
+// Event stores the vpc peering connection data
+type Event struct {
+	ProviderType                 string            `json:"_provider"`
+	ComponentType                string            `json:"_component"`
+	ComponentID                  string            `json:"_component_id"`
+	State                        string            `json:"_state"`
+	Action                       string            `json:"_action"`
+	SchemaVersion                int               `json:"_schema_version"`
+	VpcPeeringAWSID              *string           `json:"vpc_peering_aws_id"`
+	Name                         *string           `json:"name"`
+	Status                       *string           `json:"status"`
+	PeerVpcID                    string            `json:"peer_vpc_id"`
+	PeerOwnerID                  *string           `json:"peer_owner_id"`
+	PeerRegion                   *string           `json:"peer_region"`
+	PeerAccessKeyID              string            `json:"peer_aws_access_key_id"`
+	PeerSecretAccessKey          string            `json:"peer_aws_secret_access_key"`
+	AutoAccept                   bool              `json:"auto_accept"`
+	AllowRemoteDNSResolution     *bool             `json:"allow_remote_vpc_dns_resolution"`
+	PeerAllowRemoteDNSResolution *bool             `json:"peer_allow_remote_vpc_dns_resolution"`
+	RouteTableAWSIDs             []*string         `json:"route_table_aws_ids"`
+	PeerRouteTableAWSIDs         []*string         `json:"peer_route_table_aws_ids"`
+	Subnets                      []*string         `json:"subnets"`
+	PeerSubnets                  []*string         `json:"peer_subnets"`
+	Tags                         map[string]string `json:"tags"`
+	DatacenterType               string            `json:"datacenter_type"`
+	DatacenterName               string            `json:"datacenter_name"`
+	DatacenterRegion             string            `json:"datacenter_region"`
+	AccessKeyID                  string            `json:"aws_access_key_id"`
+	SecretAccessKey              string            `json:"aws_secret_access_key"`
+	Vpc                          string            `json:"vpc"`
+	VpcID                        string            `json:"vpc_id"`
+	Service                      string            `json:"service"`
+	ErrorMessage                 string            `json:"error,omitempty"`
+	Subject                      string            `json:"-"`
+	Body                         []byte            `json:"-"`
+	CryptoKey                    string            `json:"-"`
+}
+
+// New : Constructor
+func New(subject string, body []byte, cryptoKey string) ernestaws.Event {
+	if strings.Split(subject, ".")[1] == "find" {
+		return &Collection{Subject: subject, Body: body, CryptoKey: cryptoKey}
+	}
+
+	return &Event{Subject: subject, Body: body, CryptoKey: cryptoKey}
+}
+
+// Validate checks if all criteria are met
+func (ev *Event) Validate() error {
+	if err := schema.Validate(ev.Subject, ev.Body); err != nil {
+		return err
+	}
+
+	if ev.VpcID == "" {
+		return ErrDatacenterIDInvalid
+	}
+
+	if ev.DatacenterRegion == "" {
+		return ErrDatacenterRegionInvalid
+	}
+
+	if ev.AccessKeyID == "" || ev.SecretAccessKey == "" {
+		return ErrDatacenterCredentialsInvalid
+	}
+
+	if ev.Subject == "vpc_peering.create.aws" {
+		if ev.PeerVpcID == "" {
+			return ErrPeerVpcIDInvalid
+		}
+	} else if ev.VpcPeeringAWSID == nil {
+		return ErrVpcPeeringAWSIDInvalid
+	}
+
+	return nil
+}
+
+// Process : starts processing the current message
+func (ev *Event) Process() (err error) {
+	if ev.Body, err = schema.Migrate(ev.Subject, ev.Body); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := json.Unmarshal(ev.Body, &ev); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	if err := ev.Validate(); err != nil {
+		ev.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Error : Will respond the current event with an error
+func (ev *Event) Error(err error) {
+	log.Printf("Error: %s", err.Error())
+	ev.ErrorMessage = err.Error()
+	ev.State = "errored"
+
+	ev.Body, err = json.Marshal(ev)
+}
+
+// Complete : sets the state of the event to completed
+func (ev *Event) Complete() {
+	ev.State = "completed"
+}
+
+// Find : Find an object on aws
+func (ev *Event) Find() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// Create : Requests a peering connection to the peer vpc, which can be
+// on another account or region. With auto_accept, the request is
+// accepted with the peer credentials, or the event ones if the peer
+// credentials are not set
+func (ev *Event) Create() error {
+	st, err := ev.client().Create(context.Background(), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.VpcPeeringAWSID = aws.String(st.ID)
+	ev.setStatus(st)
+
+	return nil
+}
+
+// Update : Accepts the peering connection if it is pending and
+// auto_accept is set, and updates the dns resolution options and the
+// routes to the peer vpc
+func (ev *Event) Update() error {
+	st, err := ev.client().Update(context.Background(), aws.StringValue(ev.VpcPeeringAWSID), ev.spec())
+	if err != nil {
+		return err
+	}
+
+	ev.setStatus(st)
+
+	return nil
+}
+
+// Delete : Deletes the peering connection and the routes using it
+func (ev *Event) Delete() error {
+	return ev.client().Delete(context.Background(), aws.StringValue(ev.VpcPeeringAWSID), ev.VpcID, ev.PeerVpcID)
+}
+
+// Get : Gets a object on aws
+func (ev *Event) Get() error {
+	return errors.New(ev.Subject + " not supported")
+}
+
+// GetBody : Gets the body for this event
+func (ev *Event) GetBody() []byte {
+	var err error
+	if ev.Body, err = json.Marshal(ev); err != nil {
+		log.Println(err.Error())
+	}
+	return ev.Body
+}
+
+// GetSubject : Gets the subject for this event
+func (ev *Event) GetSubject() string {
+	return ev.Subject
+}
+
+// client : returns the typed client the event is an adapter for. The
+// accepter side uses the peer credentials, or the event ones if they are
+// not set, and can't be managed when it belongs to another account
+// without its own credentials
+func (ev *Event) client() Client {
+	c := Client{
+		Account: client.Account{
+			Region:          ev.DatacenterRegion,
+			AccessKeyID:     ev.AccessKeyID,
+			SecretAccessKey: ev.SecretAccessKey,
+			CryptoKey:       ev.CryptoKey,
+		},
+		Scope: client.Scope{
+			Subject:     ev.Subject,
+			ComponentID: ev.ComponentID,
+		},
+	}
+
+	if aws.StringValue(ev.PeerOwnerID) != "" && ev.PeerAccessKeyID == "" {
+		return c
+	}
+
+	peer := c.Account
+
+	if ev.PeerAccessKeyID != "" {
+		peer.AccessKeyID, peer.SecretAccessKey = ev.PeerAccessKeyID, ev.PeerSecretAccessKey
+	}
+
+	if aws.StringValue(ev.PeerRegion) != "" {
+		peer.Region = *ev.PeerRegion
+	}
+
+	c.Peer = &peer
+
+	return c
+}
+
+func (ev *Event) spec() Spec {
+	return Spec{
+		Name:                         aws.StringValue(ev.Name),
+		VpcID:                        ev.VpcID,
+		PeerVpcID:                    ev.PeerVpcID,
+		PeerOwnerID:                  aws.StringValue(ev.PeerOwnerID),
+		PeerRegion:                   aws.StringValue(ev.PeerRegion),
+		AutoAccept:                   ev.AutoAccept,
+		AllowRemoteDNSResolution:     ev.AllowRemoteDNSResolution,
+		PeerAllowRemoteDNSResolution: ev.PeerAllowRemoteDNSResolution,
+		RouteTableIDs:                aws.StringValueSlice(ev.RouteTableAWSIDs),
+		PeerRouteTableIDs:            aws.StringValueSlice(ev.PeerRouteTableAWSIDs),
+		Tags:                         ev.Tags,
+	}
+}
+
+// setStatus : maps back the status and the cidrs of both vpcs
+func (ev *Event) setStatus(st Status) {
+	ev.Status = aws.String(st.State)
+	ev.Subnets = aws.StringSlice(st.Subnets)
+	ev.PeerSubnets = aws.StringSlice(st.PeerSubnets)
+}
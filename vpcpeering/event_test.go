@@ -0,0 +1,210 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpcpeering
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/ernestio/ernestaws/awsfake"
+)
+
+func createVpc(t *testing.T, b *awsfake.Backend, cidr string) string {
+	var out ec2.CreateVpcOutput
+
+	if err := b.EC2.CreateVpc(&ec2.CreateVpcInput{CidrBlock: aws.String(cidr)}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.Vpc.VpcId
+}
+
+func createRouteTable(t *testing.T, b *awsfake.Backend, vpc string) string {
+	var out ec2.CreateRouteTableOutput
+
+	if err := b.EC2.CreateRouteTable(&ec2.CreateRouteTableInput{VpcId: aws.String(vpc)}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return *out.RouteTable.RouteTableId
+}
+
+// setup : creates the requester and peer vpcs with a route table each
+func setup(t *testing.T, b *awsfake.Backend) map[string]string {
+	ids := map[string]string{
+		"vpc":  createVpc(t, b, "10.0.0.0/16"),
+		"peer": createVpc(t, b, "10.1.0.0/16"),
+	}
+
+	ids["rt"] = createRouteTable(t, b, ids["vpc"])
+	ids["prt"] = createRouteTable(t, b, ids["peer"])
+
+	return ids
+}
+
+// peeringRoutes : returns the destinations routed through the peering
+// connection on a route table
+func peeringRoutes(b *awsfake.Backend, rt, id string) []string {
+	var cidrs []string
+
+	for _, r := range b.EC2.RouteTables[rt].Routes {
+		if aws.StringValue(r.VpcPeeringConnectionId) == id && aws.StringValue(r.State) != ec2.RouteStateBlackhole {
+			cidrs = append(cidrs, *r.DestinationCidrBlock)
+		}
+	}
+
+	return cidrs
+}
+
+func TestEvents(t *testing.T) {
+	b := awsfake.New()
+	b.Install()
+	defer b.Uninstall()
+
+	ids := setup(t, b)
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		expected string
+		save     map[string]string
+		check    func(res map[string]interface{}) bool
+	}{
+		{
+			name:     "create leaves the request pending",
+			subject:  "vpc_peering.create.aws",
+			body:     `{"vpc_id":"$vpc","peer_vpc_id":"$peer","name":"peer","tags":{"Name":"peer"}}`,
+			expected: "vpc_peering.create.aws.done",
+			save:     map[string]string{"id": "vpc_peering_aws_id"},
+			check: func(res map[string]interface{}) bool {
+				return res["status"] == ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance
+			},
+		},
+		{
+			name:     "update accepts the request and routes both vpcs",
+			subject:  "vpc_peering.update.aws",
+			body:     `{"vpc_id":"$vpc","vpc_peering_aws_id":"$id","peer_vpc_id":"$peer","auto_accept":true,"allow_remote_vpc_dns_resolution":true,"route_table_aws_ids":["$rt"],"peer_route_table_aws_ids":["$prt"]}`,
+			expected: "vpc_peering.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				pc := b.EC2.VpcPeeringConnections[ids["id"]]
+				routes := peeringRoutes(b, ids["rt"], ids["id"])
+				peerRoutes := peeringRoutes(b, ids["prt"], ids["id"])
+				return res["status"] == ec2.VpcPeeringConnectionStateReasonCodeActive &&
+					*pc.RequesterVpcInfo.PeeringOptions.AllowDnsResolutionFromRemoteVpc &&
+					len(routes) == 1 && routes[0] == "10.1.0.0/16" &&
+					len(peerRoutes) == 1 && peerRoutes[0] == "10.0.0.0/16"
+			},
+		},
+		{
+			name:     "update removes the routes of dropped tables",
+			subject:  "vpc_peering.update.aws",
+			body:     `{"vpc_id":"$vpc","vpc_peering_aws_id":"$id","peer_vpc_id":"$peer","peer_route_table_aws_ids":["$prt"]}`,
+			expected: "vpc_peering.update.aws.done",
+			check: func(res map[string]interface{}) bool {
+				return len(peeringRoutes(b, ids["rt"], ids["id"])) == 0 && len(peeringRoutes(b, ids["prt"], ids["id"])) == 1
+			},
+		},
+		{
+			name:     "find",
+			subject:  "vpc_peering.find.aws",
+			body:     `{"tags":{"Name":"peer"}}`,
+			expected: "vpc_peering.find.aws.done",
+			check: func(res map[string]interface{}) bool {
+				found, _ := res["components"].([]interface{})
+				if len(found) != 1 {
+					return false
+				}
+				pc := found[0].(map[string]interface{})
+				return pc["vpc_peering_aws_id"] == ids["id"] && pc["vpc_id"] == ids["vpc"] && pc["peer_vpc_id"] == ids["peer"] && pc["allow_remote_vpc_dns_resolution"] == true
+			},
+		},
+		{
+			name:     "delete",
+			subject:  "vpc_peering.delete.aws",
+			body:     `{"vpc_id":"$vpc","vpc_peering_aws_id":"$id","peer_vpc_id":"$peer"}`,
+			expected: "vpc_peering.delete.aws.done",
+			check: func(res map[string]interface{}) bool {
+				pc := b.EC2.VpcPeeringConnections[ids["id"]]
+				return *pc.Status.Code == ec2.VpcPeeringConnectionStateReasonCodeDeleted && len(b.EC2.RouteTables[ids["prt"]].Routes) == 1
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+		if subject != tt.expected {
+			t.Fatalf("%s: expected %s, got %s: %v", tt.name, tt.expected, subject, res["error"])
+		}
+
+		for k, field := range tt.save {
+			ids[k], _ = res[field].(string)
+		}
+
+		if tt.check != nil && !tt.check(res) {
+			t.Errorf("%s: unexpected result %v", tt.name, res)
+		}
+	}
+}
+
+func TestEventErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		subject   string
+		body      string
+	}{
+		{
+			name:      "create fails",
+			operation: "CreateVpcPeeringConnection",
+			subject:   "vpc_peering.create.aws",
+			body:      `{"vpc_id":"$vpc","peer_vpc_id":"$peer"}`,
+		},
+		{
+			name:      "update fails accepting the request",
+			operation: "AcceptVpcPeeringConnection",
+			subject:   "vpc_peering.update.aws",
+			body:      `{"vpc_id":"$vpc","vpc_peering_aws_id":"$id","auto_accept":true}`,
+		},
+		{
+			name:      "create fails routing the vpc",
+			operation: "CreateRoute",
+			subject:   "vpc_peering.create.aws",
+			body:      `{"vpc_id":"$vpc","peer_vpc_id":"$peer","auto_accept":true,"route_table_aws_ids":["$rt"]}`,
+		},
+		{
+			name:      "delete fails",
+			operation: "DeleteVpcPeeringConnection",
+			subject:   "vpc_peering.delete.aws",
+			body:      `{"vpc_id":"$vpc","vpc_peering_aws_id":"$id","peer_vpc_id":"$peer"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := awsfake.New()
+			b.Install()
+			defer b.Uninstall()
+
+			ids := setup(t, b)
+
+			_, res := awsfake.Run(t, New, "vpc_peering.create.aws", `{"vpc_id":"$vpc","peer_vpc_id":"$peer"}`, ids)
+			ids["id"], _ = res["vpc_peering_aws_id"].(string)
+
+			b.Fail("ec2", tt.operation, "InternalError", 1)
+
+			subject, res := awsfake.Run(t, New, tt.subject, tt.body, ids)
+			if subject != tt.subject+".error" {
+				t.Errorf("expected %s.error, got %s", tt.subject, subject)
+			}
+
+			if msg, _ := res["error"].(string); !strings.Contains(msg, "InternalError") {
+				t.Errorf("expected the injected error, got %q", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package vpcpeering
+
+import "github.com/ernestio/ernestaws/schema"
+
+var options = []schema.Field{
+	{Name: "peer_aws_access_key_id", Type: schema.String},
+	{Name: "peer_aws_secret_access_key", Type: schema.String},
+	{Name: "auto_accept", Type: schema.Boolean},
+	{Name: "allow_remote_vpc_dns_resolution", Type: schema.Boolean},
+	{Name: "peer_allow_remote_vpc_dns_resolution", Type: schema.Boolean},
+	{Name: "route_table_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "peer_route_table_aws_ids", Type: schema.Array, Items: &schema.Field{Type: schema.String}},
+	{Name: "tags", Type: schema.Map},
+}
+
+func init() {
+	schema.Register("vpc_peering", map[string][]schema.Field{
+		"create": schema.Fields(schema.Datacenter(), options, []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "peer_vpc_id", Type: schema.String, Required: true},
+			{Name: "peer_owner_id", Type: schema.String},
+			{Name: "peer_region", Type: schema.String, Format: schema.Region},
+			{Name: "name", Type: schema.String},
+		}),
+		"update": schema.Fields(schema.Datacenter(), options, []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "vpc_peering_aws_id", Type: schema.String, Required: true},
+			{Name: "peer_vpc_id", Type: schema.String},
+			{Name: "peer_owner_id", Type: schema.String},
+			{Name: "peer_region", Type: schema.String, Format: schema.Region},
+		}),
+		"delete": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "vpc_id", Type: schema.String, Required: true},
+			{Name: "vpc_peering_aws_id", Type: schema.String, Required: true},
+			{Name: "peer_vpc_id", Type: schema.String},
+			{Name: "peer_owner_id", Type: schema.String},
+			{Name: "peer_region", Type: schema.String, Format: schema.Region},
+			{Name: "peer_aws_access_key_id", Type: schema.String},
+			{Name: "peer_aws_secret_access_key", Type: schema.String},
+		}),
+		"find": schema.Fields(schema.Datacenter(), []schema.Field{
+			{Name: "tags", Type: schema.Map},
+		}),
+	})
+
+	schema.RegisterMigration("vpc_peering", 1, schema.VpcReference)
+}